@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/provider"
+	"leonardo-cli/internal/service"
+)
+
+// printAccountUsage prints the account subcommands.
+func printAccountUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo account <subcommand> [name]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  add <name>     Store a token read from standard input under name")
+	fmt.Fprintln(stderr, "  list           List stored accounts")
+	fmt.Fprintln(stderr, "  use <name>     Make an account the default")
+	fmt.Fprintln(stderr, "  remove <name>  Delete a stored account")
+}
+
+// runAccount dispatches the account subcommands.
+func runAccount(accounts *service.AccountService, args []string) error {
+	if len(args) == 0 {
+		printAccountUsage()
+		return fmt.Errorf("account subcommand is required")
+	}
+	sub, rest := args[0], args[1:]
+	if sub == "list" {
+		return listAccounts(os.Stdout, accounts)
+	}
+	if len(rest) != 1 {
+		printAccountUsage()
+		return fmt.Errorf("account %s requires exactly one account name", sub)
+	}
+	name := rest[0]
+	switch sub {
+	case "add":
+		if isTerminal(os.Stdin) {
+			fmt.Fprintf(stderr, "API token for %s: ", name)
+		}
+		token, err := readToken(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := accounts.Add(name, token); err != nil {
+			return err
+		}
+		fmt.Println("Stored account:", name)
+	case "use":
+		if err := accounts.Use(name); err != nil {
+			return err
+		}
+		fmt.Println("Default account:", name)
+	case "remove":
+		if err := accounts.Remove(name); err != nil {
+			return err
+		}
+		fmt.Println("Removed account:", name)
+	default:
+		printAccountUsage()
+		return fmt.Errorf("unknown account subcommand: %s", sub)
+	}
+	return nil
+}
+
+// readToken reads a token from the first line of r.  Tokens are read from
+// standard input rather than flags so they stay out of shell history.
+func readToken(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("reading token: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// listAccounts prints the stored accounts with masked tokens, marking the
+// default one.
+func listAccounts(w io.Writer, accounts *service.AccountService) error {
+	list, current, err := accounts.List()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No stored accounts; add one with 'leonardo account add <name>'.")
+		return nil
+	}
+	for _, a := range list {
+		marker := " "
+		if a.Name == current {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\n", marker, a.Name, a.MaskedToken())
+	}
+	return nil
+}
+
+// accountBalance is the outcome of querying one account for --all-accounts.
+type accountBalance struct {
+	name string
+	info domain.UserInfo
+	err  error
+}
+
+// showAllAccounts queries every stored account and prints their balances
+// side by side with a total.  Accounts that fail are reported inline so one
+// revoked token does not hide the others.
+func showAllAccounts(accounts *service.AccountService) error {
+	list, _, err := accounts.List()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("no stored accounts; add one with 'leonardo account add <name>'")
+	}
+	balances := make([]accountBalance, 0, len(list))
+	for _, a := range list {
+		registerSecret(a.Token)
+		client := provider.NewAPIClient(a.Token, nil)
+		client.SetObserver(stats.record)
+		info, err := service.NewGenerationService(client).UserInfo()
+		balances = append(balances, accountBalance{name: a.Name, info: info, err: err})
+	}
+	printAccountBalances(os.Stdout, balances)
+	return nil
+}
+
+// printAccountBalances renders per-account balances as a table.
+func printAccountBalances(w io.Writer, balances []accountBalance) {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "ACCOUNT\tUSER\tSUBSCRIPTION\tPAID\tRENEWAL")
+	var subscription, paid int
+	for _, b := range balances {
+		if b.err != nil {
+			fmt.Fprintf(tw, "%s\terror: %v\t\t\t\n", b.name, b.err)
+			continue
+		}
+		subscription += b.info.APISubscriptionTokens
+		paid += b.info.APIPaidTokens
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", b.name, b.info.Username, b.info.APISubscriptionTokens, b.info.APIPaidTokens, b.info.TokenRenewalDate)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t%d\t%d\t\n", subscription, paid)
+	tw.Flush()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// Exit codes of auth check.  They let setup scripts and CI tell a missing
+// token from one the API rejects.
+const (
+	exitAuthUnverified = 1
+	exitTokenMissing   = 2
+	exitTokenInvalid   = 3
+	exitTokenExpired   = 4
+)
+
+// tokenExitCode maps a token state to the exit code of auth check.
+func tokenExitCode(state domain.TokenState) int {
+	switch state {
+	case domain.TokenValid:
+		return 0
+	case domain.TokenMissing:
+		return exitTokenMissing
+	case domain.TokenInvalid:
+		return exitTokenInvalid
+	case domain.TokenExpired:
+		return exitTokenExpired
+	default:
+		return exitAuthUnverified
+	}
+}
+
+// printAuthUsage prints the auth subcommands.
+func printAuthUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo auth <subcommand>")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  check  Verify the API token and show plan and token balances")
+	fmt.Fprintln(stderr, "Exit codes of check:")
+	fmt.Fprintf(stderr, "  0 valid, %d could not verify, %d missing, %d invalid, %d expired\n",
+		exitAuthUnverified, exitTokenMissing, exitTokenInvalid, exitTokenExpired)
+}
+
+// runAuth dispatches the auth subcommands and returns the exit code.
+func runAuth(svc *service.GenerationService, args []string) int {
+	if len(args) == 0 {
+		printAuthUsage()
+		return 1
+	}
+	switch args[0] {
+	case "check":
+		return checkAuth(svc)
+	default:
+		printAuthUsage()
+		fmt.Fprintln(stderr, "Error: unknown auth subcommand:", args[0])
+		return 1
+	}
+}
+
+// checkAuth verifies the token against /me and prints the account's plan,
+// balances and renewal date.  The returned exit code reflects the token
+// state.
+func checkAuth(svc *service.GenerationService) int {
+	info, err := svc.UserInfo()
+	state := domain.ClassifyTokenError(err)
+	if err != nil {
+		fmt.Fprintf(stderr, "Token: %s (%v)\n", state, err)
+		return tokenExitCode(state)
+	}
+	fmt.Println("Token:", domain.TokenValid)
+	if strings.TrimSpace(info.Username) != "" {
+		fmt.Printf("User: %s (%s)\n", info.Username, info.UserID)
+	}
+	fmt.Println("Plan:", info.PlanType())
+	fmt.Println("API Subscription Tokens:", info.APISubscriptionTokens)
+	fmt.Println("API Paid Tokens:", info.APIPaidTokens)
+	if strings.TrimSpace(info.TokenRenewalDate) != "" {
+		fmt.Println("Token Renewal Date:", info.TokenRenewalDate)
+	}
+	return 0
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// manifestFile is the on-disk representation of a batch manifest.  Request
+// keys mirror the ones written to sidecar metadata files.
+type manifestFile struct {
+	Items []manifestItem `json:"items"`
+}
+
+type manifestItem struct {
+	Request        manifestRequest `json:"request"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	SubmittedAt    string          `json:"submitted_at,omitempty"`
+	GenerationID   string          `json:"generation_id,omitempty"`
+	Attempts       int             `json:"attempts"`
+	Failure        string          `json:"failure,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+type manifestRequest struct {
+	Prompt         string   `json:"prompt"`
+	PromptTemplate string   `json:"prompt_template,omitempty"`
+	NegativePrompt string   `json:"negative_prompt,omitempty"`
+	ModelID        string   `json:"model_id,omitempty"`
+	StyleUUID      string   `json:"style_uuid,omitempty"`
+	NumImages      int      `json:"num_images,omitempty"`
+	Seed           int      `json:"seed,omitempty"`
+	Width          int      `json:"width,omitempty"`
+	Height         int      `json:"height,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Private        bool     `json:"private,omitempty"`
+	Alchemy        bool     `json:"alchemy,omitempty"`
+	Ultra          bool     `json:"ultra,omitempty"`
+	Contrast       float64  `json:"contrast,omitempty"`
+	GuidanceScale  float64  `json:"guidance_scale,omitempty"`
+	HighResolution bool     `json:"high_resolution,omitempty"`
+	ContrastRatio  float64  `json:"contrast_ratio,omitempty"`
+	ExpandedDomain bool     `json:"expanded_domain,omitempty"`
+	HighContrast   bool     `json:"high_contrast,omitempty"`
+	PhotoReal      bool     `json:"photo_real,omitempty"`
+}
+
+// toDomain converts a manifest request into a GenerationRequest.
+func (r manifestRequest) toDomain() domain.GenerationRequest {
+	return domain.GenerationRequest{
+		NumImages: r.NumImages,
+		Private:   r.Private,
+		Metadata: domain.GenerationMetadata{
+			Prompt:         r.Prompt,
+			PromptTemplate: r.PromptTemplate,
+			NegativePrompt: r.NegativePrompt,
+			ModelID:        r.ModelID,
+			StyleUUID:      r.StyleUUID,
+			Seed:           r.Seed,
+			Width:          r.Width,
+			Height:         r.Height,
+			Tags:           r.Tags,
+			Alchemy:        r.Alchemy,
+			Ultra:          r.Ultra,
+			Contrast:       r.Contrast,
+			GuidanceScale:  r.GuidanceScale,
+			HighResolution: r.HighResolution,
+			ContrastRatio:  r.ContrastRatio,
+			ExpandedDomain: r.ExpandedDomain,
+			HighContrast:   r.HighContrast,
+			PhotoReal:      r.PhotoReal,
+		},
+	}
+}
+
+// manifestRequestFromDomain converts a GenerationRequest into its manifest form.
+func manifestRequestFromDomain(req domain.GenerationRequest) manifestRequest {
+	m := req.Metadata
+	return manifestRequest{
+		Prompt:         m.Prompt,
+		PromptTemplate: m.PromptTemplate,
+		NegativePrompt: m.NegativePrompt,
+		ModelID:        m.ModelID,
+		StyleUUID:      m.StyleUUID,
+		NumImages:      req.NumImages,
+		Seed:           m.Seed,
+		Width:          m.Width,
+		Height:         m.Height,
+		Tags:           m.Tags,
+		Private:        req.Private,
+		Alchemy:        m.Alchemy,
+		Ultra:          m.Ultra,
+		Contrast:       m.Contrast,
+		GuidanceScale:  m.GuidanceScale,
+		HighResolution: m.HighResolution,
+		ContrastRatio:  m.ContrastRatio,
+		ExpandedDomain: m.ExpandedDomain,
+		HighContrast:   m.HighContrast,
+		PhotoReal:      m.PhotoReal,
+	}
+}
+
+// readManifest loads a batch manifest from a JSON file.
+func readManifest(path string) (domain.BatchManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return domain.BatchManifest{}, fmt.Errorf("reading manifest: %w", err)
+	}
+	var file manifestFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return domain.BatchManifest{}, fmt.Errorf("parsing manifest: %w", err)
+	}
+	manifest := domain.BatchManifest{}
+	for i, item := range file.Items {
+		var submittedAt time.Time
+		if item.SubmittedAt != "" {
+			if submittedAt, err = time.Parse(time.RFC3339Nano, item.SubmittedAt); err != nil {
+				return domain.BatchManifest{}, fmt.Errorf("parsing manifest: item %d: invalid submitted_at %q", i+1, item.SubmittedAt)
+			}
+		}
+		manifest.Items = append(manifest.Items, domain.BatchItem{
+			Request:        item.Request.toDomain(),
+			IdempotencyKey: item.IdempotencyKey,
+			SubmittedAt:    submittedAt,
+			GenerationID:   item.GenerationID,
+			Attempts:       item.Attempts,
+			Failure:        domain.FailureClass(item.Failure),
+			Error:          item.Error,
+		})
+	}
+	return manifest, nil
+}
+
+// writeManifest stores a batch manifest as indented JSON.  The file is
+// replaced atomically, since a run rewrites it before every submission and
+// may be interrupted at any point.
+func writeManifest(path string, manifest domain.BatchManifest) error {
+	file := manifestFile{Items: []manifestItem{}}
+	for _, item := range manifest.Items {
+		entry := manifestItem{
+			Request:        manifestRequestFromDomain(redactRequest(item.Request)),
+			IdempotencyKey: item.IdempotencyKey,
+			GenerationID:   item.GenerationID,
+			Attempts:       item.Attempts,
+			Failure:        string(item.Failure),
+			Error:          item.Error,
+		}
+		if !item.SubmittedAt.IsZero() {
+			entry.SubmittedAt = item.SubmittedAt.UTC().Format(time.RFC3339Nano)
+		}
+		file.Items = append(file.Items, entry)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
+
+// redactRequest returns req with its prompts redacted when --redact-prompts
+// is in effect.
+func redactRequest(req domain.GenerationRequest) domain.GenerationRequest {
+	req.Metadata.Prompt = redactor.Prompt(req.Metadata.Prompt)
+	req.Metadata.PromptTemplate = redactor.Prompt(req.Metadata.PromptTemplate)
+	req.Metadata.NegativePrompt = redactor.Prompt(req.Metadata.NegativePrompt)
+	return req
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	hintUnknownFlags(fs, args)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// printBatchUsage prints the batch subcommands.
+func printBatchUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo batch --csv <file> [options]")
+	fmt.Fprintln(stderr, "       leonardo batch --stdin [options] < requests.jsonl")
+	fmt.Fprintln(stderr, "       leonardo batch <subcommand> [options]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  resume <manifest>        Finish an interrupted run without resubmitting items that were already created")
+	fmt.Fprintln(stderr, "  retry-failed <manifest>  Resubmit retryable failures recorded in a manifest")
+}
+
+// runBatch dispatches the batch subcommands.  Arguments starting with a
+// flag submit a new batch.
+func runBatch(svc *service.GenerationService, lib *service.LibraryService, models *service.ModelService, preflight *service.PreflightService, args []string) error {
+	if len(args) == 0 {
+		printBatchUsage()
+		return fmt.Errorf("batch subcommand is required")
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return runBatchSubmit(svc, lib, models, preflight, args)
+	}
+	switch args[0] {
+	case "resume":
+		resumeCmd := flag.NewFlagSet("batch resume", flag.ExitOnError)
+		skipPreflight := resumeCmd.Bool("skip-preflight", false, "Start without checking the token and that the balance covers the remaining items")
+		maxConcurrency := resumeCmd.Int("max-concurrency", domain.DefaultBatchConcurrency, "Most submissions in flight at once; fewer are used while the API rate limits")
+		maxPending := resumeCmd.Int("max-pending", 0, "Most generations unfinished on the server at once, e.g. your plan's concurrent job limit; more wait locally (0: no cap)")
+		positional, err := parseInterspersed(resumeCmd, args[1:])
+		if err != nil {
+			return err
+		}
+		applyConfig(resumeCmd)
+		if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+			resumeCmd.Usage()
+			return fmt.Errorf("exactly one manifest path is required")
+		}
+		if err := setBatchConcurrency(svc, *maxConcurrency, *maxPending, 0); err != nil {
+			resumeCmd.Usage()
+			return err
+		}
+		if *skipPreflight {
+			preflight = nil
+		}
+		return resumeBatch(svc, lib, preflight, positional[0])
+	case "retry-failed":
+		retryCmd := flag.NewFlagSet("batch retry-failed", flag.ExitOnError)
+		maxAttempts := retryCmd.Int("max-attempts", domain.DefaultMaxAttempts, "Maximum submissions per item, including the first one")
+		skipPreflight := retryCmd.Bool("skip-preflight", false, "Start without checking the token and that the balance covers the retries")
+		positional, err := parseInterspersed(retryCmd, args[1:])
+		if err != nil {
+			return err
+		}
+		applyConfig(retryCmd)
+		if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+			retryCmd.Usage()
+			return fmt.Errorf("exactly one manifest path is required")
+		}
+		if *skipPreflight {
+			preflight = nil
+		}
+		return retryFailed(svc, preflight, positional[0], *maxAttempts)
+	default:
+		printBatchUsage()
+		return fmt.Errorf("unknown batch subcommand: %s", args[0])
+	}
+}
+
+// runBatchSubmit parses the options of a new batch, submits it and writes
+// its manifest.
+func runBatchSubmit(svc *service.GenerationService, lib *service.LibraryService, models *service.ModelService, preflight *service.PreflightService, args []string) error {
+	submitCmd := flag.NewFlagSet("batch", flag.ExitOnError)
+	csvPath := submitCmd.String("csv", "", "CSV file with a prompt column and optional per-row overrides")
+	stdin := submitCmd.Bool("stdin", false, "Read JSON-lines requests from stdin and write JSON-lines results to stdout")
+	manifestPath := submitCmd.String("manifest", "", "Where to write the batch manifest (default for --csv: next to the input, ending in .manifest.json)")
+	wait := submitCmd.Bool("wait", false, "With --stdin, wait for each generation to finish before reporting it")
+	outputDir := submitCmd.String("output-dir", "", "With --stdin, download finished images to this directory (implies --wait)")
+	pollInterval := submitCmd.Duration("poll-interval", 5*time.Second, "Delay before the first check of a generation while waiting; later checks back off")
+	waitTimeout := submitCmd.Duration("wait-timeout", 10*time.Minute, "Give up waiting for a generation after this long")
+	modelID := submitCmd.String("model-id", "", "Default model ID for rows without one")
+	width := submitCmd.Int("width", 0, "Default width for rows without one")
+	height := submitCmd.Int("height", 0, "Default height for rows without one")
+	numImages := submitCmd.Int("num-images", 1, "Default number of images per row")
+	tags := submitCmd.String("tags", "", "Default comma-separated tags for rows without any")
+	runTags := submitCmd.String("tag", "", "Comma-separated tags added to every request of this run, e.g. campaign-spring-2026")
+	private := submitCmd.Bool("private", false, "Generate private images unless a row says otherwise")
+	skipModelCheck := submitCmd.Bool("skip-model-check", false, "With --csv, submit without checking rows against their model's capabilities")
+	wildcardsDir := submitCmd.String("wildcards-dir", "", "With --csv, directory of wordlists for __wildcards__ in prompts (default ./wildcards, then the state directory)")
+	skipPreflight := submitCmd.Bool("skip-preflight", false, "Start without checking the token and that the balance covers the estimated cost")
+	maxConcurrency := submitCmd.Int("max-concurrency", domain.DefaultBatchConcurrency, "With --csv, most submissions in flight at once; fewer are used while the API rate limits")
+	maxPending := submitCmd.Int("max-pending", 0, "With --csv, most generations unfinished on the server at once, e.g. your plan's concurrent job limit; more wait locally (0: no cap)")
+	spread := submitCmd.Duration("spread", 0, "With --csv, space the submissions evenly over this window, e.g. 2h, instead of sending them at once")
+	at := submitCmd.String("at", "", "With --csv, wait until this time (HH:MM, the next one to come, or RFC 3339) before submitting")
+	parseFlags(submitCmd, args)
+	if *skipPreflight {
+		preflight = nil
+	}
+	if (strings.TrimSpace(*csvPath) == "") == !*stdin {
+		submitCmd.Usage()
+		return fmt.Errorf("exactly one of --csv or --stdin is required")
+	}
+	if *spread < 0 {
+		submitCmd.Usage()
+		return fmt.Errorf("--spread must not be negative")
+	}
+	if err := setBatchConcurrency(svc, *maxConcurrency, *maxPending, *spread); err != nil {
+		submitCmd.Usage()
+		return err
+	}
+	var start time.Time
+	if *at != "" {
+		var err error
+		if start, err = parseStartTime(*at, time.Now()); err != nil {
+			submitCmd.Usage()
+			return err
+		}
+	}
+	defaults := domain.GenerationRequest{
+		NumImages: *numImages,
+		Private:   *private,
+		Metadata: domain.GenerationMetadata{
+			ModelID: *modelID,
+			Width:   *width,
+			Height:  *height,
+			Tags:    parseTags(*tags),
+		},
+	}
+	if *stdin {
+		// A stream's requests are not known up front, so only the token
+		// can be checked.
+		if err := runPreflight(preflight, nil); err != nil {
+			return err
+		}
+		opts := streamOptions{wait: *wait, outputDir: *outputDir, pollInterval: *pollInterval, waitTimeout: *waitTimeout, tags: parseTags(*runTags)}
+		manifest, err := streamBatch(svc, os.Stdin, os.Stdout, defaults, opts)
+		if *manifestPath != "" {
+			if werr := writeManifest(*manifestPath, manifest); werr != nil && err == nil {
+				err = werr
+			}
+		}
+		recordBatch(lib, manifest)
+		return err
+	}
+	requests, unknown, err := readCSVFile(*csvPath, defaults)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		fmt.Fprintln(stderr, "Warning: ignoring unknown CSV columns:", strings.Join(unknown, ", "))
+	}
+	if len(requests) == 0 {
+		return fmt.Errorf("CSV file has no rows")
+	}
+	wildcards := newWildcardService(*wildcardsDir)
+	for i := range requests {
+		requests[i].Metadata.Tags = domain.MergeTags(requests[i].Metadata.Tags, parseTags(*runTags))
+		if requests[i].Metadata, err = wildcards.Resolve(requests[i].Metadata); err != nil {
+			return fmt.Errorf("CSV row %d: %w", i+1, err)
+		}
+	}
+	if !*skipModelCheck {
+		// Check every row before submitting any, so a bad row does not
+		// leave half a batch behind.
+		for i, req := range requests {
+			err := models.Validate(req.Metadata)
+			var invalid *domain.InvalidRequestError
+			if errors.As(err, &invalid) {
+				return fmt.Errorf("CSV row %d: %w", i+1, err)
+			}
+			if err != nil {
+				fmt.Fprintln(stderr, "Warning: skipping model capability check:", err)
+				break
+			}
+		}
+	}
+	if err := runPreflight(preflight, requests); err != nil {
+		return err
+	}
+	if err := waitForStart(start); err != nil {
+		return err
+	}
+	path := *manifestPath
+	if path == "" {
+		path = strings.TrimSuffix(*csvPath, filepath.Ext(*csvPath)) + ".manifest.json"
+	}
+	return submitBatch(svc, lib, requests, path)
+}
+
+// setBatchConcurrency lets batch runs submit up to max items at once, or
+// spaced over spread when positive, with at most maxPending generations
+// unfinished when positive, telling the user when a rate limit or the cap
+// makes them slow down.
+func setBatchConcurrency(svc *service.GenerationService, max, maxPending int, spread time.Duration) error {
+	if max < 1 {
+		return fmt.Errorf("--max-concurrency must be at least 1")
+	}
+	if maxPending < 0 {
+		return fmt.Errorf("--max-pending must not be negative")
+	}
+	svc.SetBatchConcurrency(domain.BatchConcurrency{
+		Max:      max,
+		Cooldown: domain.DefaultRateLimitCooldown,
+		Spread:   spread,
+		OnChange: func(limit int, rateLimited bool) {
+			if rateLimited {
+				fmt.Fprintf(stderr, "Rate limited; submitting %d at a time\n", limit)
+			}
+		},
+		MaxPending: maxPending,
+		OnHold: func(pending int) {
+			fmt.Fprintf(stderr, "%d generations pending (--max-pending %d); waiting for one to finish\n", pending, maxPending)
+		},
+	})
+	return nil
+}
+
+// parseStartTime reads a --at time: HH:MM in local time, meaning the next
+// time the clock shows it, or an RFC 3339 timestamp.
+func parseStartTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	clock, err := time.ParseInLocation("15:04", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --at %q, want HH:MM or an RFC 3339 time", value)
+	}
+	y, m, d := now.Date()
+	t := time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// waitForStart blocks until start, if it is set and still ahead, or until
+// the run is interrupted.
+func waitForStart(start time.Time) error {
+	wait := time.Until(start)
+	if start.IsZero() || wait <= 0 {
+		return nil
+	}
+	fmt.Fprintf(messages(), "Waiting until %s to start...\n", start.Format("2006-01-02 15:04"))
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-runCtx.Done():
+		return fmt.Errorf("batch not started: %w", runCtx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runPreflight checks that the token works and that the balance covers the
+// estimated cost of requests, printing the outcome to stderr.  It returns a
+// *domain.PreflightError when the run cannot complete.  A nil preflight
+// skips the check.
+func runPreflight(preflight *service.PreflightService, requests []domain.GenerationRequest) error {
+	if preflight == nil {
+		return nil
+	}
+	report := preflight.Check(requests)
+	if err := report.Err(); err != nil {
+		return err
+	}
+	if report.Requests == 0 {
+		fmt.Fprintf(stderr, "Preflight: token valid, balance %d tokens\n", report.Balance)
+		return nil
+	}
+	fmt.Fprintln(stderr, "Preflight:", report.Summary())
+	if report.Unpriced > 0 {
+		fmt.Fprintln(stderr, "Warning: the estimate leaves out requests the pricing calculator could not price.")
+	}
+	return nil
+}
+
+// submitBatch submits requests, printing each outcome as it happens, then
+// records the new generations in the local library.  The manifest at path
+// is written before the first submission and kept up to date, so an
+// interrupted run can be finished with batch resume.
+func submitBatch(svc *service.GenerationService, lib *service.LibraryService, requests []domain.GenerationRequest, path string) error {
+	return runManifest(svc, lib, domain.NewBatchManifest(requests), path)
+}
+
+// resumeBatch finishes the run recorded in the manifest at path.  Items
+// already created are left alone and items that may have been created
+// before the interruption are looked up before anything is resubmitted.
+func resumeBatch(svc *service.GenerationService, lib *service.LibraryService, preflight *service.PreflightService, path string) error {
+	manifest, err := readManifest(path)
+	if err != nil {
+		return err
+	}
+	var remaining []domain.GenerationRequest
+	for i, item := range manifest.Items {
+		if !item.Pending() && !item.InDoubt() {
+			continue
+		}
+		if domain.IsRedactedPrompt(item.Request.Metadata.Prompt) {
+			return fmt.Errorf("item %d has a redacted prompt and cannot be resubmitted", i+1)
+		}
+		remaining = append(remaining, item.Request)
+	}
+	if len(remaining) == 0 {
+		fmt.Println("Nothing to resume: every item was submitted.")
+		return nil
+	}
+	if err := runPreflight(preflight, remaining); err != nil {
+		return err
+	}
+	return runManifest(svc, lib, manifest, path)
+}
+
+// runManifest submits the pending items of manifest, checkpointing it to
+// path, and prints a summary.
+func runManifest(svc *service.GenerationService, lib *service.LibraryService, manifest domain.BatchManifest, path string) error {
+	var created domain.BatchManifest
+	manifest, err := svc.RunBatch(manifest, func(i int, item domain.BatchItem) {
+		if !item.Failed() {
+			created.Items = append(created.Items, item)
+		}
+		item.Request = redactRequest(item.Request)
+		if printFormatted(item) {
+			return
+		}
+		if item.Failed() {
+			fmt.Printf("Item %d: %s (%s)\n", i+1, item.Failure, item.Error)
+			return
+		}
+		fmt.Printf("Item %d: %s\n", i+1, colors.id(item.GenerationID))
+	}, func(m domain.BatchManifest) error {
+		return writeManifest(path, m)
+	})
+	recordBatch(lib, created)
+	if err != nil {
+		return fmt.Errorf("%w (resume with: leonardo batch resume %s)", err, path)
+	}
+	failed := 0
+	for _, item := range manifest.Items {
+		if item.Failed() {
+			failed++
+		}
+	}
+	fmt.Fprintf(messages(), "Submitted: %d, failed: %d\n", len(manifest.Items)-failed, failed)
+	fmt.Fprintln(messages(), "Manifest:", path)
+	if failed > 0 {
+		fmt.Fprintf(messages(), "Retry with: leonardo batch retry-failed %s\n", path)
+	}
+	return nil
+}
+
+// recordBatch adds the generations a batch created to the local library.
+// Failures only produce a warning because the batch itself succeeded.
+func recordBatch(lib *service.LibraryService, manifest domain.BatchManifest) {
+	now := time.Now().UTC()
+	for _, item := range manifest.Items {
+		if item.Failed() || item.GenerationID == "" {
+			continue
+		}
+		entry := domain.LibraryEntry{
+			GenerationID: item.GenerationID,
+			Prompt:       item.Request.Metadata.Prompt,
+			ModelID:      item.Request.Metadata.ModelID,
+			CreatedAt:    now,
+			Tags:         item.Request.Metadata.Tags,
+			Width:        item.Request.Metadata.Width,
+			Height:       item.Request.Metadata.Height,
+		}
+		if err := lib.Record(entry); err != nil {
+			fmt.Fprintln(stderr, "Warning: could not record generation in library:", err)
+		}
+	}
+}
+
+// retryFailed resubmits the retryable failures of the manifest at path,
+// writes the updated manifest back and prints a summary.
+func retryFailed(svc *service.GenerationService, preflight *service.PreflightService, path string, maxAttempts int) error {
+	manifest, err := readManifest(path)
+	if err != nil {
+		return err
+	}
+	var retries []domain.GenerationRequest
+	for i, item := range manifest.Items {
+		if item.CanRetry(maxAttempts) && domain.IsRedactedPrompt(item.Request.Metadata.Prompt) {
+			return fmt.Errorf("item %d has a redacted prompt and cannot be resubmitted", i+1)
+		}
+		if item.CanRetry(maxAttempts) {
+			retries = append(retries, item.Request)
+		}
+	}
+	if err := runPreflight(preflight, retries); err != nil {
+		return err
+	}
+	updated, summary := svc.RetryFailed(manifest, maxAttempts)
+	if err := writeManifest(path, updated); err != nil {
+		return err
+	}
+	for i, item := range updated.Items {
+		switch {
+		case item.Failed():
+			fmt.Printf("Item %d: %s (%s, %d attempts)\n", i+1, item.Failure, item.Error, item.Attempts)
+		case item.GenerationID != "":
+			fmt.Printf("Item %d: %s\n", i+1, item.GenerationID)
+		}
+	}
+	fmt.Printf("Retried: %d, succeeded: %d, skipped (not retryable): %d, exhausted: %d\n",
+		summary.Retried, summary.Succeeded, summary.Skipped, summary.Exhausted)
+	return nil
+}
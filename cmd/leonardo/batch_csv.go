@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// csvColumns maps the accepted CSV header names, compared case-insensitively
+// with spaces and dashes treated as underscores, to the request field they
+// set.  Several spellings are accepted because spreadsheets rarely use the
+// CLI's flag names.
+var csvColumns = map[string]string{
+	"prompt":          "prompt",
+	"negative_prompt": "negative_prompt",
+	"model":           "model_id",
+	"model_id":        "model_id",
+	"width":           "width",
+	"height":          "height",
+	"size":            "size",
+	"seed":            "seed",
+	"tags":            "tags",
+	"num_images":      "num_images",
+	"images":          "num_images",
+	"style_uuid":      "style_uuid",
+	"private":         "private",
+	"alchemy":         "alchemy",
+	"ultra":           "ultra",
+	"contrast":        "contrast",
+	"guidance_scale":  "guidance_scale",
+	"high_resolution": "high_resolution",
+	"contrast_ratio":  "contrast_ratio",
+	"expanded_domain": "expanded_domain",
+	"high_contrast":   "high_contrast",
+	"photo_real":      "photo_real",
+	"photoreal":       "photo_real",
+}
+
+// normalizeCSVHeader folds a header cell into the form used by csvColumns.
+func normalizeCSVHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+}
+
+// readCSVRequests reads one generation request per CSV row.  Columns are
+// mapped by header; empty cells keep the value from defaults.  Unknown
+// columns are returned so callers can warn about them, and a prompt column
+// is required.
+func readCSVRequests(r io.Reader, defaults domain.GenerationRequest) ([]domain.GenerationRequest, []string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	fields := make([]string, len(header))
+	var unknown []string
+	hasPrompt := false
+	for i, h := range header {
+		field, ok := csvColumns[normalizeCSVHeader(h)]
+		if !ok {
+			unknown = append(unknown, strings.TrimSpace(h))
+			continue
+		}
+		fields[i] = field
+		hasPrompt = hasPrompt || field == "prompt"
+	}
+	if !hasPrompt {
+		return nil, unknown, fmt.Errorf("CSV header has no prompt column")
+	}
+
+	var requests []domain.GenerationRequest
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, unknown, fmt.Errorf("reading CSV: %w", err)
+		}
+		row, _ := reader.FieldPos(0)
+		req := defaults
+		req.Metadata.Tags = append([]string(nil), defaults.Metadata.Tags...)
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			if i >= len(fields) || fields[i] == "" || cell == "" {
+				continue
+			}
+			if err := applyCSVField(&req, fields[i], cell); err != nil {
+				return nil, unknown, fmt.Errorf("CSV line %d: %w", row, err)
+			}
+		}
+		if strings.TrimSpace(req.Metadata.Prompt) == "" {
+			return nil, unknown, fmt.Errorf("CSV line %d: prompt is empty", row)
+		}
+		if err := req.Metadata.ValidateAlchemy(); err != nil {
+			return nil, unknown, fmt.Errorf("CSV line %d: %w", row, err)
+		}
+		requests = append(requests, req)
+	}
+	return requests, unknown, nil
+}
+
+// applyCSVField sets a request field from a CSV cell.
+func applyCSVField(req *domain.GenerationRequest, field, cell string) error {
+	m := &req.Metadata
+	var err error
+	switch field {
+	case "prompt":
+		m.Prompt = cell
+	case "negative_prompt":
+		m.NegativePrompt = cell
+	case "model_id":
+		m.ModelID = cell
+	case "style_uuid":
+		m.StyleUUID = cell
+	case "tags":
+		// Commas usually separate cells, so tags within a cell may also be
+		// separated by semicolons.
+		m.Tags = parseTags(strings.ReplaceAll(cell, ";", ","))
+	case "size":
+		w, h, ok := strings.Cut(strings.ToLower(cell), "x")
+		if !ok {
+			return fmt.Errorf("invalid size %q (expected WIDTHxHEIGHT)", cell)
+		}
+		if m.Width, err = strconv.Atoi(strings.TrimSpace(w)); err == nil {
+			m.Height, err = strconv.Atoi(strings.TrimSpace(h))
+		}
+		if err != nil {
+			return fmt.Errorf("invalid size %q (expected WIDTHxHEIGHT)", cell)
+		}
+		return nil
+	case "width":
+		m.Width, err = strconv.Atoi(cell)
+	case "height":
+		m.Height, err = strconv.Atoi(cell)
+	case "seed":
+		m.Seed, err = strconv.Atoi(cell)
+	case "num_images":
+		req.NumImages, err = strconv.Atoi(cell)
+	case "private":
+		req.Private, err = strconv.ParseBool(cell)
+	case "alchemy":
+		m.Alchemy, err = strconv.ParseBool(cell)
+	case "ultra":
+		m.Ultra, err = strconv.ParseBool(cell)
+	case "contrast":
+		m.Contrast, err = strconv.ParseFloat(cell, 64)
+	case "guidance_scale":
+		m.GuidanceScale, err = strconv.ParseFloat(cell, 64)
+	case "high_resolution":
+		m.HighResolution, err = strconv.ParseBool(cell)
+	case "contrast_ratio":
+		m.ContrastRatio, err = strconv.ParseFloat(cell, 64)
+	case "expanded_domain":
+		m.ExpandedDomain, err = strconv.ParseBool(cell)
+	case "high_contrast":
+		m.HighContrast, err = strconv.ParseBool(cell)
+	case "photo_real":
+		m.PhotoReal, err = strconv.ParseBool(cell)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", field, cell)
+	}
+	return nil
+}
+
+// readCSVFile opens path and reads its generation requests.
+func readCSVFile(path string, defaults domain.GenerationRequest) ([]domain.GenerationRequest, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening CSV: %w", err)
+	}
+	defer f.Close()
+	return readCSVRequests(f, defaults)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// maxStreamLine bounds the length of a JSONL request line.
+const maxStreamLine = 1 << 20
+
+// streamOptions controls what batch --stdin does after submitting a request.
+type streamOptions struct {
+	wait         bool
+	outputDir    string
+	pollInterval time.Duration
+	waitTimeout  time.Duration
+	// tags are added to every request, on top of its own.
+	tags []string
+}
+
+// streamResult is the JSON line written for every request read by
+// batch --stdin.  Line is the input line number the result belongs to.
+type streamResult struct {
+	Line    int      `json:"line"`
+	ID      string   `json:"id,omitempty"`
+	Status  string   `json:"status"`
+	Files   []string `json:"files"`
+	Failure string   `json:"failure,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Statuses reported by batch --stdin.  SUBMITTED and INVALID are the CLI's
+// own; FAILED matches the API's status for failed generations.
+const (
+	streamStatusSubmitted = "SUBMITTED"
+	streamStatusInvalid   = "INVALID"
+	streamStatusFailed    = "FAILED"
+)
+
+// streamBatch reads one JSON generation request per line from r, submits
+// each one as soon as it is read and writes one JSON result line to w per
+// request, in input order.  Request keys are those of manifest requests;
+// keys a line leaves out take their value from defaults.  A bad line
+// produces an error result instead of stopping the stream.  The returned
+// manifest records every submitted request.
+func streamBatch(svc *service.GenerationService, r io.Reader, w io.Writer, defaults domain.GenerationRequest, opts streamOptions) (domain.BatchManifest, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	enc := json.NewEncoder(w)
+	var manifest domain.BatchManifest
+	line := 0
+	for scanner.Scan() && !interrupted() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		result := streamResult{Line: line, Files: []string{}}
+		req, err := decodeStreamRequest([]byte(text), defaults)
+		if err != nil {
+			result.Status = streamStatusInvalid
+			result.Error = err.Error()
+		} else {
+			req.Metadata.Tags = domain.MergeTags(req.Metadata.Tags, opts.tags)
+			item := svc.SubmitItem(req)
+			manifest.Items = append(manifest.Items, item)
+			completeStreamResult(svc, &result, item, opts)
+		}
+		if err := enc.Encode(result); err != nil {
+			return manifest, fmt.Errorf("writing result: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return manifest, fmt.Errorf("reading requests: %w", err)
+	}
+	return manifest, nil
+}
+
+// decodeStreamRequest parses a JSONL request line on top of defaults.
+func decodeStreamRequest(data []byte, defaults domain.GenerationRequest) (domain.GenerationRequest, error) {
+	r := manifestRequestFromDomain(defaults)
+	// Unmarshal reuses a slice's backing array, so give each line its own tags.
+	r.Tags = append([]string(nil), r.Tags...)
+	if err := json.Unmarshal(data, &r); err != nil {
+		return domain.GenerationRequest{}, fmt.Errorf("parsing request: %w", err)
+	}
+	if strings.TrimSpace(r.Prompt) == "" {
+		return domain.GenerationRequest{}, fmt.Errorf("prompt is required")
+	}
+	req := r.toDomain()
+	if err := req.Metadata.ValidateAlchemy(); err != nil {
+		return domain.GenerationRequest{}, err
+	}
+	return req, nil
+}
+
+// completeStreamResult fills result from a submitted item, waiting for the
+// generation and downloading its images when requested.
+func completeStreamResult(svc *service.GenerationService, result *streamResult, item domain.BatchItem, opts streamOptions) {
+	result.ID = item.GenerationID
+	if item.Failed() {
+		result.Status = streamStatusFailed
+		result.Failure = string(item.Failure)
+		result.Error = item.Error
+		return
+	}
+	result.Status = streamStatusSubmitted
+	if !opts.wait && opts.outputDir == "" {
+		return
+	}
+	status, err := svc.AwaitCompletion(item.GenerationID, opts.pollInterval, opts.waitTimeout)
+	if status.Status != "" {
+		result.Status = status.Status
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	if opts.outputDir == "" || status.Status == streamStatusFailed {
+		return
+	}
+	meta := item.Request.Metadata
+	needed := int64(len(status.Images)) * domain.EstimateImageBytes(meta.Width, meta.Height)
+	if err := ensureDiskSpace(opts.outputDir, needed); err != nil {
+		result.Error = err.Error()
+		return
+	}
+	downloaded, err := svc.Download(item.GenerationID, opts.outputDir)
+	result.Files = downloaded.FilePaths
+	writeThumbnails(downloaded.FilePaths)
+	if err != nil {
+		result.Error = err.Error()
+	}
+}
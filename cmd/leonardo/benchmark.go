@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/imaging"
+	"leonardo-cli/internal/service"
+)
+
+// benchmarkRow is one model of a benchmark report, for --format and the
+// CSV report.
+type benchmarkRow struct {
+	Rank         int
+	ModelID      string
+	ModelName    string
+	GenerationID string
+	Seconds      float64
+	Cost         int
+	File         string
+	Error        string
+}
+
+// benchmarkColumns is the header row of the CSV report.
+var benchmarkColumns = []string{"rank", "model_id", "model_name", "generation_id", "seconds", "cost", "file", "error"}
+
+// runBenchmark submits the same prompt with the same seed to every model
+// of --models, waits for all of them, and reports how long each took and
+// what it cost, ranked fastest first, with a contact sheet of the images
+// captioned with the model names.
+func runBenchmark(svc *service.GenerationService, lib *service.LibraryService, models *service.ModelService, args []string) error {
+	benchmarkCmd := flag.NewFlagSet("benchmark", flag.ExitOnError)
+	modelIDs := benchmarkCmd.String("models", "", fmt.Sprintf("Comma-separated IDs of the models to compare, 2 to %d (required)", domain.MaxBenchmarkModels))
+	prompt := benchmarkCmd.String("prompt", "", "Text prompt shared by every model (required)")
+	negativePrompt := benchmarkCmd.String("negative-prompt", "", "Negative prompt shared by every model")
+	width := benchmarkCmd.Int("width", 0, "Width of the generated images (default each model's own)")
+	height := benchmarkCmd.Int("height", 0, "Height of the generated images (default each model's own)")
+	seed := benchmarkCmd.Int("seed", 0, "Seed shared by every model (default a random one, printed)")
+	style := benchmarkCmd.String("style", "", "Preset style by name or UUID")
+	alchemy := benchmarkCmd.Bool("alchemy", false, "Use Alchemy for every model")
+	name := benchmarkCmd.String("name", "", "Name the generations NAME-mN, N being the model's position in --models")
+	outputDir := benchmarkCmd.String("output-dir", ".", "Directory to save each first image, the contact sheet and the CSV report to")
+	skipModelCheck := benchmarkCmd.Bool("skip-model-check", false, "Submit without checking the request against each model's capabilities")
+	pollInterval := benchmarkCmd.Duration("poll-interval", 2*time.Second, "Delay before the first progress check; later checks back off, which bounds how precise the times are")
+	waitTimeout := benchmarkCmd.Duration("wait-timeout", 10*time.Minute, "Give up waiting for each generation after this long")
+	parseFlags(benchmarkCmd, args)
+	if strings.TrimSpace(*prompt) == "" {
+		benchmarkCmd.Usage()
+		return errors.New("--prompt is required")
+	}
+	var benchmark domain.ModelBenchmark
+	for _, id := range strings.Split(*modelIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			benchmark.ModelIDs = append(benchmark.ModelIDs, id)
+		}
+	}
+	if err := benchmark.Validate(); err != nil {
+		benchmarkCmd.Usage()
+		return err
+	}
+	styleUUID, err := resolveStyle(*style)
+	if err != nil {
+		return err
+	}
+	base := domain.GenerationRequest{NumImages: 1, Metadata: domain.GenerationMetadata{
+		Name:           strings.TrimSpace(*name),
+		Prompt:         *prompt,
+		NegativePrompt: *negativePrompt,
+		StyleUUID:      styleUUID,
+		Seed:           *seed,
+		Width:          *width,
+		Height:         *height,
+		Alchemy:        *alchemy,
+	}}
+	if base.Metadata.Seed == 0 {
+		// A fixed seed is what makes the models comparable.
+		base.Metadata.Seed = rand.Intn(math.MaxInt32-1) + 1
+		fmt.Fprint(messages(), tr.Sprintf("Seed: %d\n", base.Metadata.Seed))
+	}
+	requests := benchmark.Requests(base)
+	if !*skipModelCheck {
+		for _, req := range requests {
+			if err := checkCapabilities(models, req.Metadata); err != nil {
+				return fmt.Errorf("model %s: %w", req.Metadata.ModelID, err)
+			}
+		}
+	}
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return err
+	}
+
+	results := make([]domain.BenchmarkResult, len(requests))
+	submitted := make([]time.Time, len(requests))
+	pending := 0
+	for i, req := range requests {
+		results[i] = domain.BenchmarkResult{ModelID: req.Metadata.ModelID, ModelName: modelLabel(models, req.Metadata.ModelID)}
+		if outputFormat == nil {
+			fmt.Print(tr.Sprintf("Model %s:\n", results[i].Label()))
+		}
+		out, err := createGeneration(svc, lib, req)
+		if err != nil {
+			// Reported with the other models rather than ending the run.
+			results[i].Err = "submitting: " + err.Error()
+			continue
+		}
+		results[i].GenerationID = out.GenerationID
+		submitted[i] = time.Now()
+		pending++
+	}
+
+	// The generations are waited for together so each time is measured
+	// from its own submission, not from when the previous one finished.
+	fmt.Fprint(messages(), tr.Sprintf("Waiting for %d generations to complete...\n", pending))
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].GenerationID == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(r *domain.BenchmarkResult, submitted time.Time) {
+			defer wg.Done()
+			status, err := svc.AwaitCompletion(r.GenerationID, *pollInterval, *waitTimeout)
+			r.Status = status.Status
+			switch {
+			case err != nil:
+				r.Err = err.Error()
+			case status.Status != domain.GenerationComplete:
+				r.Err = "finished with status " + status.Status
+			default:
+				r.Duration = time.Since(submitted)
+			}
+		}(&results[i], submitted[i])
+	}
+	wg.Wait()
+
+	costs, err := lib.Costs()
+	if err != nil {
+		fmt.Fprint(stderr, tr.Sprintf("Warning: could not read costs from the library: %v\n", err))
+	}
+	panels := make([]image.Image, 0, len(results))
+	for i := range results {
+		r := &results[i]
+		r.Cost = costs[r.GenerationID]
+		if !r.OK() {
+			continue
+		}
+		if err := lib.RecordDuration(r.GenerationID, r.Duration); err != nil {
+			fmt.Fprint(stderr, tr.Sprintf("Warning: could not record completion time in library: %v\n", err))
+		}
+		path, err := svc.DownloadRepresentative(r.GenerationID, *outputDir)
+		if err != nil {
+			r.Err = err.Error()
+			continue
+		}
+		r.File = path
+		img, err := imaging.Load(path)
+		if err != nil {
+			r.Err = err.Error()
+			continue
+		}
+		panels = append(panels, imaging.Caption(img, r.Label()))
+	}
+
+	ranked := domain.RankBenchmark(results)
+	rows := benchmarkRows(ranked)
+	if outputFormat != nil {
+		for _, row := range rows {
+			printFormatted(row)
+		}
+	} else {
+		printBenchmark(os.Stdout, rows)
+	}
+	stem := filepath.Join(*outputDir, fmt.Sprintf("benchmark-%d", base.Metadata.Seed))
+	report, err := os.Create(stem + ".csv")
+	if err != nil {
+		return err
+	}
+	if err := writeBenchmarkCSV(report, rows); err != nil {
+		report.Close()
+		return err
+	}
+	if err := report.Close(); err != nil {
+		return err
+	}
+	fmt.Fprint(messages(), tr.Sprintf("Report saved: %s\n", stem+".csv"))
+	if len(panels) > 0 {
+		if err := imaging.SavePNG(stem+".png", imaging.SideBySide(panels...)); err != nil {
+			return err
+		}
+		fmt.Fprint(messages(), tr.Sprintf("Contact sheet saved: %s\n", stem+".png"))
+	}
+	failed := 0
+	for _, r := range results {
+		if !r.OK() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d models did not produce an image", failed, len(results))
+	}
+	return nil
+}
+
+// benchmarkRows converts ranked results into report rows.  Only the
+// models that produced an image are ranked.
+func benchmarkRows(ranked []domain.BenchmarkResult) []benchmarkRow {
+	rows := make([]benchmarkRow, len(ranked))
+	for i, r := range ranked {
+		rows[i] = benchmarkRow{
+			ModelID:      r.ModelID,
+			ModelName:    r.ModelName,
+			GenerationID: r.GenerationID,
+			Cost:         r.Cost,
+			File:         r.File,
+			Error:        r.Err,
+		}
+		if r.OK() {
+			rows[i].Rank = i + 1
+			rows[i].Seconds = math.Round(r.Duration.Seconds()*10) / 10
+		}
+	}
+	return rows
+}
+
+// printBenchmark writes the report as a table.  Unknown costs and the
+// times of failed models show as a dash.
+func printBenchmark(w io.Writer, rows []benchmarkRow) {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "#\tMODEL\tTIME\tCOST\tGENERATION\tRESULT")
+	for _, row := range rows {
+		rank, took, cost, result := "-", "-", "-", row.File
+		if row.Rank > 0 {
+			rank = strconv.Itoa(row.Rank)
+			took = strconv.FormatFloat(row.Seconds, 'f', 1, 64) + "s"
+		}
+		if row.Cost > 0 {
+			cost = strconv.Itoa(row.Cost)
+		}
+		if row.Error != "" {
+			result = "failed: " + row.Error
+		}
+		label := row.ModelName
+		if label == "" {
+			label = row.ModelID
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rank, label, took, cost, colors.id(row.GenerationID), result)
+	}
+	tw.Flush()
+}
+
+// writeBenchmarkCSV writes the report as CSV with a header row.  Unknown
+// costs and the times of failed models are left empty.
+func writeBenchmarkCSV(w io.Writer, rows []benchmarkRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write(benchmarkColumns)
+	for _, row := range rows {
+		rank, seconds, cost := "", "", ""
+		if row.Rank > 0 {
+			rank = strconv.Itoa(row.Rank)
+			seconds = strconv.FormatFloat(row.Seconds, 'f', 1, 64)
+		}
+		if row.Cost > 0 {
+			cost = strconv.Itoa(row.Cost)
+		}
+		cw.Write([]string{rank, row.ModelID, row.ModelName, row.GenerationID, seconds, cost, row.File, row.Error})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// elementsFlag collects repeated --element ID[:WEIGHT] flags.
+type elementsFlag []domain.GenerationElement
+
+func (f *elementsFlag) String() string {
+	specs := make([]string, len(*f))
+	for i, e := range *f {
+		specs[i] = fmt.Sprintf("%s:%g", e.ID, e.Weight)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (f *elementsFlag) Set(value string) error {
+	element, err := domain.ParseElement(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, element)
+	return nil
+}
+
+// printCharacterUsage prints the character subcommands.
+func printCharacterUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo character <subcommand> [args]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  add <name> [options]   Save a subject reference: --image or --init-image-id, --style, --element, --description, --seed")
+	fmt.Fprintln(stderr, "  list                   List saved characters")
+	fmt.Fprintln(stderr, "  show <name>            Show a character's settings")
+	fmt.Fprintln(stderr, "  remove <name>          Forget a character")
+	fmt.Fprintln(stderr, "Generate with one through create --character <name>.")
+}
+
+// runCharacter dispatches the character subcommands.
+func runCharacter(characters *service.CharacterService, images *service.InitImageService, args []string) error {
+	if len(args) == 0 {
+		printCharacterUsage()
+		return fmt.Errorf("character subcommand is required")
+	}
+	sub, rest := args[0], args[1:]
+	switch sub {
+	case "add":
+		return addCharacter(characters, images, rest)
+	case "list":
+		list, err := characters.List()
+		if err != nil {
+			return err
+		}
+		if outputFormat != nil {
+			for _, c := range list {
+				printFormatted(c)
+			}
+			return nil
+		}
+		if len(list) == 0 {
+			fmt.Println("No characters saved; add one with character add <name>.")
+			return nil
+		}
+		return listCharacters(os.Stdout, list)
+	case "show":
+		if len(rest) != 1 {
+			printCharacterUsage()
+			return fmt.Errorf("character show requires exactly one name")
+		}
+		c, err := characters.Get(rest[0])
+		if err != nil {
+			return err
+		}
+		if !printFormatted(c) {
+			showCharacter(os.Stdout, c, time.Now())
+		}
+	case "remove":
+		if len(rest) != 1 {
+			printCharacterUsage()
+			return fmt.Errorf("character remove requires exactly one name")
+		}
+		if err := characters.Remove(rest[0]); err != nil {
+			return err
+		}
+		fmt.Println("Removed character", rest[0])
+	default:
+		printCharacterUsage()
+		return fmt.Errorf("unknown character subcommand %q", sub)
+	}
+	return nil
+}
+
+// addCharacter saves a character from the add subcommand's flags,
+// uploading its reference image first when one is given as a file.
+func addCharacter(characters *service.CharacterService, images *service.InitImageService, args []string) error {
+	addCmd := flag.NewFlagSet("character add", flag.ExitOnError)
+	description := addCmd.String("description", "", "Text describing the subject, put in front of every prompt")
+	image := addCmd.String("image", "", "Reference image file to upload as the character's init image")
+	initImageID := addCmd.String("init-image-id", "", "Already uploaded init image to use as the reference")
+	initStrength := addCmd.Float64("init-strength", 0, "How strongly the reference shapes each image (0.1-0.9)")
+	style := addCmd.String("style", "", "Preset style by name or UUID, e.g. illustration")
+	seed := addCmd.Int("seed", 0, "Seed to generate with, for the most repeatable results")
+	var elements elementsFlag
+	addCmd.Var(&elements, "element", "Element (LoRA) as ID or ID:WEIGHT; repeat for several")
+	positional, err := parseInterspersed(addCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		printCharacterUsage()
+		return fmt.Errorf("character add requires exactly one name")
+	}
+	if *image != "" && *initImageID != "" {
+		return errors.New("use either --image or --init-image-id, not both")
+	}
+	c := domain.Character{
+		Name:         positional[0],
+		Description:  strings.TrimSpace(*description),
+		InitImageID:  *initImageID,
+		InitStrength: *initStrength,
+		Seed:         *seed,
+		Elements:     elements,
+	}
+	if c.StyleUUID, err = resolveStyle(*style); err != nil {
+		return err
+	}
+	if err := domain.ValidateCharacterName(c.Name); err != nil {
+		return err
+	}
+	if *image != "" {
+		uploaded, err := images.Upload(*image)
+		if err != nil {
+			return fmt.Errorf("uploading %s: %w", *image, err)
+		}
+		c.InitImageID = uploaded.ID
+		fmt.Fprintf(messages(), "Uploaded %s: %s\n", *image, colors.id(uploaded.ID))
+	}
+	if err := characters.Save(c); err != nil {
+		return err
+	}
+	if !printFormatted(c) {
+		fmt.Println("Saved character", c.Name)
+	}
+	return nil
+}
+
+// listCharacters writes the characters as a table.
+func listCharacters(w io.Writer, characters []domain.Character) error {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "NAME\tINIT IMAGE\tSTYLE\tELEMENTS\tDESCRIPTION")
+	for _, c := range characters {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", c.Name, orDash(c.InitImageID), orDash(styleName(c.StyleUUID)), len(c.Elements), orDash(c.Description))
+	}
+	return tw.Flush()
+}
+
+// showCharacter writes every setting of a character.
+func showCharacter(w io.Writer, c domain.Character, now time.Time) {
+	fmt.Fprintln(w, "Name:", c.Name)
+	if c.Description != "" {
+		fmt.Fprintln(w, "Description:", c.Description)
+	}
+	if c.InitImageID != "" {
+		fmt.Fprintln(w, "Init image:", colors.id(c.InitImageID))
+	}
+	if c.InitStrength != 0 {
+		fmt.Fprintln(w, "Init strength:", c.InitStrength)
+	}
+	if c.StyleUUID != "" {
+		fmt.Fprintln(w, "Style:", styleName(c.StyleUUID))
+	}
+	if c.Seed != 0 {
+		fmt.Fprintln(w, "Seed:", c.Seed)
+	}
+	for _, e := range c.Elements {
+		fmt.Fprintf(w, "Element: %s (weight %g)\n", e.ID, e.Weight)
+	}
+	if created := formatTimestamp(c.CreatedAt, timestampsRelative, now); created != "" {
+		fmt.Fprintln(w, "Created:", created)
+	}
+}
+
+// orDash returns value, or "-" for an empty table cell.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// cleanupOptions controls what cleanup does with expired files.  With an
+// archive directory files are moved there instead of being deleted.
+type cleanupOptions struct {
+	dir     string
+	policy  domain.RetentionPolicy
+	archive string
+	dryRun  bool
+}
+
+// cleanupSummary counts what cleanup did.
+type cleanupSummary struct {
+	removed   int
+	bytes     int64
+	favorites int
+}
+
+// runCleanup parses the cleanup flags and applies the retention policy.
+func runCleanup(lib *service.LibraryService, args []string) error {
+	cleanupCmd := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	dir := cleanupCmd.String("dir", ".", "Directory holding downloaded images and sidecars, searched recursively")
+	keepDays := cleanupCmd.Int("keep-days", 90, "Keep files modified within this many days")
+	keepFavorites := cleanupCmd.Bool("keep-favorites", true, "Never touch files of favorite generations")
+	archive := cleanupCmd.String("archive", "", "Move expired files into this directory instead of deleting them")
+	dryRun := cleanupCmd.Bool("dry-run", false, "Only list the files that would be cleaned up")
+	parseFlags(cleanupCmd, args)
+	if *keepDays < 1 {
+		return fmt.Errorf("--keep-days must be at least 1")
+	}
+	favorites, err := lib.Favorites()
+	if err != nil {
+		return err
+	}
+	opts := cleanupOptions{
+		dir:     *dir,
+		policy:  domain.RetentionPolicy{KeepDays: *keepDays, KeepFavorites: *keepFavorites},
+		archive: *archive,
+		dryRun:  *dryRun,
+	}
+	summary, err := cleanupFiles(os.Stdout, opts, favorites, time.Now())
+	verb := "Removed"
+	switch {
+	case opts.dryRun:
+		verb = "Would remove"
+	case opts.archive != "":
+		verb = "Archived"
+	}
+	fmt.Printf("%s %d files (%s); kept %d files of favorite generations\n", verb, summary.removed, formatBytes(summary.bytes), summary.favorites)
+	return err
+}
+
+// cleanupFiles walks opts.dir for files written by the CLI and deletes or
+// archives those the policy considers expired, printing each one to w.
+func cleanupFiles(w io.Writer, opts cleanupOptions, favorites map[string]bool, now time.Time) (cleanupSummary, error) {
+	var summary cleanupSummary
+	archive, _ := filepath.Abs(opts.archive)
+	err := filepath.WalkDir(opts.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if abs, _ := filepath.Abs(path); opts.archive != "" && abs == archive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		id, ok := domain.GenerationIDFromFilename(path)
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file := domain.LocalFile{Path: path, GenerationID: id, ModTime: info.ModTime(), Size: info.Size()}
+		if !opts.policy.Expired(file, favorites, now) {
+			if favorites[id] && opts.policy.Expired(file, nil, now) {
+				summary.favorites++
+			}
+			return nil
+		}
+		if err := cleanupFile(w, file, opts); err != nil {
+			return err
+		}
+		summary.removed++
+		summary.bytes += file.Size
+		return nil
+	})
+	return summary, err
+}
+
+// cleanupFile deletes or archives a single expired file.
+func cleanupFile(w io.Writer, file domain.LocalFile, opts cleanupOptions) error {
+	switch {
+	case opts.dryRun:
+		fmt.Fprintln(w, "Would remove:", file.Path)
+		return nil
+	case opts.archive == "":
+		if err := os.Remove(file.Path); err != nil {
+			return fmt.Errorf("removing %s: %w", file.Path, err)
+		}
+		fmt.Fprintln(w, "Removed:", file.Path)
+		return nil
+	}
+	rel, err := filepath.Rel(opts.dir, file.Path)
+	if err != nil {
+		rel = filepath.Base(file.Path)
+	}
+	dest := filepath.Join(opts.archive, rel)
+	if err := moveFile(file.Path, dest); err != nil {
+		return fmt.Errorf("archiving %s: %w", file.Path, err)
+	}
+	fmt.Fprintf(w, "Archived: %s -> %s\n", file.Path, dest)
+	return nil
+}
+
+// moveFile renames src to dest, copying across file systems when a rename
+// is not possible.
+func moveFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%s already exists", dest)
+	}
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return errors.Join(err, os.Remove(dest))
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// formatBytes renders a size in bytes with a binary unit, e.g. "3.2 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runFavorite marks a generation in the local library as a favorite so
+// cleanup never touches its files, or clears the mark with --remove.
+func runFavorite(lib *service.LibraryService, args []string) error {
+	favoriteCmd := flag.NewFlagSet("favorite", flag.ExitOnError)
+	id := favoriteCmd.String("id", "", "Generation ID, ID prefix or name to mark (required)")
+	remove := favoriteCmd.Bool("remove", false, "Clear the favorite mark instead of setting it")
+	parseFlags(favoriteCmd, args)
+	if *id == "" {
+		favoriteCmd.Usage()
+		return fmt.Errorf("--id is required")
+	}
+	genID, err := lib.Resolve(*id)
+	if err != nil {
+		return err
+	}
+	if err := lib.SetFavorite(genID, !*remove); err != nil {
+		return err
+	}
+	if *remove {
+		fmt.Println("Removed from favorites:", colors.id(genID))
+	} else {
+		fmt.Println("Added to favorites:", colors.id(genID))
+	}
+	return nil
+}
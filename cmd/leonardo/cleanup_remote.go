@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// runCleanupRemote deletes generations from the user's API history by
+// status, after listing them and asking for confirmation.
+func runCleanupRemote(svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	cleanupCmd := flag.NewFlagSet("cleanup-remote", flag.ExitOnError)
+	statuses := cleanupCmd.String("status", domain.GenerationFailed, "Comma-separated statuses of the generations to delete: FAILED, PENDING (with --older-than) or FAILED,PENDING")
+	olderThan := cleanupCmd.Duration("older-than", 0, "Only delete generations created longer ago than this, e.g. 2h (required for PENDING)")
+	yes := cleanupCmd.Bool("yes", false, "Delete without asking for confirmation")
+	dryRun := cleanupCmd.Bool("dry-run", false, "Only list the generations that would be deleted")
+	parseFlags(cleanupCmd, args)
+	filter := domain.RemoteCleanupFilter{OlderThan: *olderThan}
+	for _, status := range strings.Split(*statuses, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, strings.ToUpper(status))
+		}
+	}
+	if err := filter.Validate(); err != nil {
+		cleanupCmd.Usage()
+		return err
+	}
+
+	info, err := svc.UserInfo()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	fmt.Fprintf(messages(), "Looking for %s generations...\n", strings.Join(filter.Statuses, " or "))
+	candidates, err := svc.RemoteCleanupCandidates(info.UserID, filter, now)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Println("No matching generations.")
+		return nil
+	}
+	for _, gen := range candidates {
+		printListItem(os.Stdout, gen, timestampsRelative, now)
+	}
+	if *dryRun {
+		fmt.Printf("Would delete %d generations\n", len(candidates))
+		return nil
+	}
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			return errors.New("refusing to delete without confirmation when stdin is not a terminal; pass --yes")
+		}
+		ok, err := confirm(os.Stdin, stderr, fmt.Sprintf("Delete these %d generations? [y/N] ", len(candidates)))
+		if err != nil || !ok {
+			fmt.Println("Nothing deleted.")
+			return err
+		}
+	}
+
+	ids := make([]string, len(candidates))
+	for i, gen := range candidates {
+		ids[i] = gen.ID
+	}
+	return deleteRemoteGenerations(svc, lib, ids)
+}
+
+// deleteRemoteGenerations deletes ids from the API history and the library,
+// reporting each one, and fails when any deletion did.
+func deleteRemoteGenerations(svc *service.GenerationService, lib *service.LibraryService, ids []string) error {
+	deleted := 0
+	failed := svc.DeleteGenerations(ids, func(id string, err error) {
+		if err != nil {
+			reportError("Error deleting "+id, err)
+			return
+		}
+		if ferr := lib.Forget(id); ferr != nil {
+			fmt.Fprintln(stderr, "Warning: could not remove generation from library:", ferr)
+		}
+		deleted++
+		fmt.Println("Deleted:", colors.id(id))
+	})
+	fmt.Printf("Deleted %d of %d generations\n", deleted, len(ids))
+	if failed > 0 {
+		return fmt.Errorf("%d deletions failed", failed)
+	}
+	return nil
+}
+
+// confirm writes question to w and reads the answer from r; only an answer
+// starting with y counts as yes.
+func confirm(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprint(w, question)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y"), nil
+}
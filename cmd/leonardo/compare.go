@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"strings"
+
+	"leonardo-cli/internal/imaging"
+	"leonardo-cli/internal/service"
+)
+
+// idsFlag collects every value of a repeatable --id flag.
+type idsFlag []string
+
+func (f *idsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *idsFlag) Set(v string) error {
+	if strings.TrimSpace(v) == "" {
+		return fmt.Errorf("empty generation reference")
+	}
+	*f = append(*f, strings.TrimSpace(v))
+	return nil
+}
+
+// runCompare downloads the first image of two generations and writes a
+// side-by-side composite, optionally followed by a difference heatmap.
+func runCompare(svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	compareCmd := flag.NewFlagSet("compare", flag.ExitOnError)
+	var ids idsFlag
+	compareCmd.Var(&ids, "id", "Generation ID, ID prefix or name to compare (give exactly two)")
+	output := compareCmd.String("output", "", "Composite image to write (default compare-<A>-<B>.png)")
+	heatmap := compareCmd.Bool("heatmap", false, "Add a third panel highlighting where the images differ")
+	parseFlags(compareCmd, args)
+	if len(ids) != 2 {
+		compareCmd.Usage()
+		return fmt.Errorf("exactly two --id flags are required")
+	}
+	genIDs := []string{resolveGenerationRef(svc, lib, ids[0]), resolveGenerationRef(svc, lib, ids[1])}
+	dir, err := os.MkdirTemp("", "leonardo-compare-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	panels := make([]image.Image, 0, 3)
+	for _, id := range genIDs {
+		path, err := svc.DownloadRepresentative(id, dir)
+		if err != nil {
+			return err
+		}
+		img, err := imaging.Load(path)
+		if err != nil {
+			return err
+		}
+		panels = append(panels, img)
+	}
+	if *heatmap {
+		diff, score := imaging.DiffHeatmap(panels[0], panels[1])
+		panels = append(panels, diff)
+		fmt.Printf("Mean difference: %.1f%%\n", score*100)
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("compare-%s-%s.png", shortID(genIDs[0]), shortID(genIDs[1]))
+	}
+	if err := imaging.SavePNG(*output, imaging.SideBySide(panels...)); err != nil {
+		return err
+	}
+	fmt.Println("Comparison saved:", *output)
+	return nil
+}
+
+// shortID returns the first eight characters of a generation ID, enough to
+// tell generations apart in file names.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/config"
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// Prefixes of the file paths in a configuration bundle: files of the state
+// directory and of the project directory.
+const (
+	bundleHome    = "home/"
+	bundleProject = "project/"
+)
+
+// bundlePassphraseEnv names the variable holding the passphrase that seals
+// the accounts of a bundle, for scripted exports and imports.
+const bundlePassphraseEnv = "LEONARDO_CONFIG_PASSPHRASE"
+
+// homeConfigFiles are the files of the state directory that hold
+// configuration rather than records of past work.
+var homeConfigFiles = []string{"characters.json"}
+
+// printConfigUsage prints the config subcommands.
+func printConfigUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo config <subcommand> [options]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  export [--output FILE] [--include-secrets]  Write characters, wildcards and .leonardo.yaml to one bundle")
+	fmt.Fprintln(stderr, "  import [--force] [--project-dir DIR] FILE   Restore a bundle written by export")
+}
+
+// runConfig dispatches the config subcommands.
+func runConfig(accounts *service.AccountService, args []string) error {
+	if len(args) == 0 {
+		printConfigUsage()
+		return fmt.Errorf("config subcommand is required")
+	}
+	switch sub, rest := args[0], args[1:]; sub {
+	case "export":
+		exportCmd := flag.NewFlagSet("config export", flag.ExitOnError)
+		output := exportCmd.String("output", "-", "File to write the bundle to; - for standard output")
+		includeSecrets := exportCmd.Bool("include-secrets", false, "Also export the stored accounts, encrypted with a passphrase (from "+bundlePassphraseEnv+" or asked for)")
+		noProject := exportCmd.Bool("no-project", false, "Leave out the .leonardo.yaml of this project")
+		parseFlags(exportCmd, rest)
+		projectFile := ""
+		if !*noProject {
+			projectFile, _ = config.FindProjectFile(".")
+		}
+		passphrase := ""
+		if *includeSecrets {
+			var err error
+			if passphrase, err = bundlePassphrase(); err != nil {
+				return err
+			}
+		}
+		bundle, err := exportConfig(leonardoHome(), projectFile, accounts, passphrase, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := writeBundle(*output, bundle); err != nil {
+			return err
+		}
+		fmt.Fprintf(messages(), "Exported %d files", len(bundle.Files))
+		if bundle.Secrets != nil {
+			fmt.Fprint(messages(), " and the stored accounts, encrypted")
+		}
+		fmt.Fprintln(messages())
+		return nil
+	case "import":
+		importCmd := flag.NewFlagSet("config import", flag.ExitOnError)
+		force := importCmd.Bool("force", false, "Replace files and accounts that already exist")
+		projectDir := importCmd.String("project-dir", ".", "Directory to write the bundle's .leonardo.yaml to")
+		positional, err := parseInterspersed(importCmd, rest)
+		if err != nil {
+			return err
+		}
+		applyConfig(importCmd)
+		if len(positional) != 1 {
+			importCmd.Usage()
+			return fmt.Errorf("exactly one bundle file is required")
+		}
+		bundle, err := readBundle(positional[0])
+		if err != nil {
+			return err
+		}
+		passphrase := ""
+		if bundle.Secrets != nil {
+			if passphrase, err = bundlePassphrase(); err != nil {
+				return err
+			}
+		}
+		result, err := importConfig(bundle, leonardoHome(), *projectDir, accounts, passphrase, *force)
+		for _, name := range result.written {
+			fmt.Fprintln(messages(), "Imported", name)
+		}
+		for _, name := range result.skipped {
+			fmt.Fprintln(stderr, "Skipped", name, "(already exists; --force replaces it)")
+		}
+		return err
+	default:
+		printConfigUsage()
+		return fmt.Errorf("unknown config subcommand: %s", sub)
+	}
+}
+
+// bundlePassphrase returns the passphrase sealing a bundle's accounts, from
+// the environment or, failing that, standard input.
+func bundlePassphrase() (string, error) {
+	if passphrase := os.Getenv(bundlePassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if isTerminal(os.Stdin) {
+		fmt.Fprint(stderr, "Bundle passphrase: ")
+	}
+	passphrase, err := readToken(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("a passphrase is required for the stored accounts (set %s)", bundlePassphraseEnv)
+	}
+	return passphrase, nil
+}
+
+// exportConfig collects the configuration files of the state directory home
+// and the project file, when not empty, into a bundle.  With a passphrase
+// the stored accounts are sealed into it too; without one no secret is
+// exported.
+func exportConfig(home, projectFile string, accounts *service.AccountService, passphrase string, now time.Time) (domain.ConfigBundle, error) {
+	bundle := domain.ConfigBundle{
+		Format:     domain.ConfigBundleFormat,
+		Version:    domain.ConfigBundleVersion,
+		ExportedAt: now.UTC(),
+		Files:      map[string]string{},
+	}
+	for _, name := range homeConfigFiles {
+		if err := addBundleFile(bundle.Files, bundleHome+name, filepath.Join(home, name)); err != nil {
+			return bundle, err
+		}
+	}
+	wildcards := filepath.Join(home, "wildcards")
+	err := filepath.WalkDir(wildcards, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(home, p)
+		if err != nil {
+			return err
+		}
+		return addBundleFile(bundle.Files, bundleHome+filepath.ToSlash(rel), p)
+	})
+	if err != nil {
+		return bundle, err
+	}
+	if projectFile != "" {
+		if err := addBundleFile(bundle.Files, bundleProject+config.ProjectFileName, projectFile); err != nil {
+			return bundle, err
+		}
+	}
+	if passphrase == "" {
+		return bundle, nil
+	}
+	list, current, err := accounts.List()
+	if err != nil {
+		return bundle, err
+	}
+	plaintext, err := json.Marshal(domain.BundledAccounts{Accounts: list, Default: current})
+	if err != nil {
+		return bundle, err
+	}
+	sealed, err := domain.SealSecrets(passphrase, plaintext)
+	if err != nil {
+		return bundle, err
+	}
+	bundle.Secrets = &sealed
+	return bundle, nil
+}
+
+// addBundleFile adds the file at path to files under name, skipping files
+// that do not exist.
+func addBundleFile(files map[string]string, name, path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	files[name] = string(data)
+	return nil
+}
+
+// bundleDest returns where the bundle file name is restored, below home or
+// projectDir.  Only the files exportConfig writes are accepted, so a bundle
+// can never replace accounts.json, the library or other state.
+func bundleDest(name, home, projectDir string) (string, bool) {
+	if name == bundleProject+config.ProjectFileName {
+		return filepath.Join(projectDir, config.ProjectFileName), true
+	}
+	rel, ok := strings.CutPrefix(name, bundleHome)
+	if !ok {
+		return "", false
+	}
+	known := strings.HasPrefix(rel, "wildcards/")
+	for _, file := range homeConfigFiles {
+		known = known || rel == file
+	}
+	if !known {
+		return "", false
+	}
+	return filepath.Join(home, filepath.FromSlash(rel)), true
+}
+
+// importResult lists what an import wrote and what it left alone because it
+// already existed.
+type importResult struct {
+	written []string
+	skipped []string
+}
+
+// importConfig writes the files of a bundle below home and projectDir and
+// adds its accounts, opened with passphrase.  Nothing is written unless the
+// whole bundle is valid and opens.  Existing files and accounts are kept
+// unless force is set.
+func importConfig(bundle domain.ConfigBundle, home, projectDir string, accounts *service.AccountService, passphrase string, force bool) (importResult, error) {
+	var result importResult
+	if err := bundle.Validate(); err != nil {
+		return result, err
+	}
+	var bundled domain.BundledAccounts
+	if bundle.Secrets != nil {
+		plaintext, err := bundle.Secrets.Open(passphrase)
+		if err != nil {
+			return result, err
+		}
+		if err := json.Unmarshal(plaintext, &bundled); err != nil {
+			return result, fmt.Errorf("parsing the bundle's accounts: %w", err)
+		}
+	}
+	names := make([]string, 0, len(bundle.Files))
+	dests := map[string]string{}
+	for name := range bundle.Files {
+		dest, ok := bundleDest(name, home, projectDir)
+		if !ok {
+			return result, fmt.Errorf("configuration bundle holds an unknown file %q", name)
+		}
+		names = append(names, name)
+		dests[name] = dest
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dest := dests[name]
+		if _, err := os.Stat(dest); err == nil && !force {
+			result.skipped = append(result.skipped, dest)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return result, err
+		}
+		if err := os.WriteFile(dest, []byte(bundle.Files[name]), 0644); err != nil {
+			return result, err
+		}
+		result.written = append(result.written, dest)
+	}
+	if bundle.Secrets == nil {
+		return result, nil
+	}
+	existing, current, err := accounts.List()
+	if err != nil {
+		return result, err
+	}
+	have := map[string]bool{}
+	for _, a := range existing {
+		have[a.Name] = true
+	}
+	for _, a := range bundled.Accounts {
+		label := path.Join("account", a.Name)
+		if have[a.Name] && !force {
+			result.skipped = append(result.skipped, label)
+			continue
+		}
+		registerSecret(a.Token)
+		if err := accounts.Add(a.Name, a.Token); err != nil {
+			return result, err
+		}
+		result.written = append(result.written, label)
+	}
+	if current == "" && bundled.Default != "" {
+		if err := accounts.Use(bundled.Default); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// writeBundle writes bundle as indented JSON to path, or to standard output
+// for "-".  Bundles with secrets are readable only by their owner.
+func writeBundle(path string, bundle domain.ConfigBundle) error {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// readBundle reads a bundle written by writeBundle; "-" reads standard
+// input.
+func readBundle(path string) (domain.ConfigBundle, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return domain.ConfigBundle{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var bundle domain.ConfigBundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return domain.ConfigBundle{}, fmt.Errorf("parsing configuration bundle: %w", err)
+	}
+	return bundle, nil
+}
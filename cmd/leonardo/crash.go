@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// issueURL is where bugs in the CLI are reported.
+const issueURL = "https://github.com/jcmonteiro/leonardo-cli/issues/new"
+
+// exitCrashed is the exit code of a run that panicked, the code the Go
+// runtime uses for an unrecovered panic.
+const exitCrashed = 2
+
+// crashReportCalls bounds the API calls listed in a crash report.
+const crashReportCalls = 20
+
+// recoverPanic, deferred first thing in main, turns a panic into a crash
+// report saved to a temporary file and a short message saying where to
+// report it, instead of a bare stack dump.  Panics in other goroutines
+// still end the program the usual way.
+func recoverPanic() {
+	v := recover()
+	if v == nil {
+		return
+	}
+	stack := debug.Stack()
+	fmt.Fprint(stderr, tr.Sprintf("leonardo crashed unexpectedly: %v\n", v))
+	path, err := saveCrashReport(newCrashReport(v, stack, os.Args[1:], os.Environ(), time.Now()))
+	if err != nil {
+		fmt.Fprint(stderr, tr.Sprintf("The crash report could not be saved (%v); the stack trace follows.\n", err))
+		fmt.Fprint(stderr, string(stack))
+	} else {
+		fmt.Fprint(stderr, tr.Sprintf("A crash report was saved to %s.\n", path))
+	}
+	fmt.Fprint(stderr, tr.Sprintf("Please report this at %s with the report attached.  Tokens, user IDs and prompts are redacted, but look it over before sharing.\n", issueURL))
+	exit(exitCrashed)
+}
+
+// newCrashReport gathers what a bug report needs about the run: the build,
+// the command line, the project settings and LEONARDO_* variables in
+// environ, the last API calls and the panic with its stack.  Secrets are
+// redacted and prompts always replaced with their hash, whatever
+// --redact-prompts says, since the report is meant to be shared.
+func newCrashReport(v interface{}, stack []byte, args, environ []string, now time.Time) domain.CrashReport {
+	r := domain.Redactor{Secrets: append([]string(nil), redactor.Secrets...), Prompts: true}
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && name == "LEONARDO_API_TOKEN" {
+			r.AddSecret(value)
+		}
+	}
+	b := currentBuild()
+	report := domain.CrashReport{
+		At:        now,
+		Version:   b.Version,
+		Commit:    b.Commit,
+		GoVersion: b.GoVersion,
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Args:      domain.RedactArgs(args, r),
+		Config:    map[string]string{},
+		Env:       map[string]string{},
+		Panic:     r.Text(fmt.Sprint(v)),
+		Stack:     r.Text(string(stack)),
+	}
+	// The project file is only reported when the run had read it; loading
+	// it now could fail in turn.
+	if projectConfigLoaded && projectConfig != nil {
+		report.ConfigFile = projectConfig.Path()
+		for key, value := range projectConfig.Prefixed("") {
+			report.Config[key] = domain.RedactSetting(key, value, r)
+		}
+	}
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, "LEONARDO_") {
+			report.Env[name] = domain.RedactSetting(name, value, r)
+		}
+	}
+	calls := stats.snapshot()
+	if len(calls) > crashReportCalls {
+		calls = calls[len(calls)-crashReportCalls:]
+	}
+	for _, m := range calls {
+		m.Path = redactPath(m.Path)
+		report.Calls = append(report.Calls, m)
+	}
+	return report
+}
+
+// saveCrashReport writes report to a new file in the temporary directory,
+// readable by the user only, and returns its path.
+func saveCrashReport(report domain.CrashReport) (string, error) {
+	f, err := os.CreateTemp("", "leonardo-crash-*.txt")
+	if err != nil {
+		return "", err
+	}
+	if err := report.Render(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
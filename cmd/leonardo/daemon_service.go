@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// printServiceUsage prints the service subcommands.
+func printServiceUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo service install [options] -- <command> [command options]")
+	fmt.Fprintln(stderr, "       leonardo service uninstall [options]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  install    Write a systemd unit or launchd agent running a long-running command")
+	fmt.Fprintln(stderr, "  uninstall  Remove a service written by install")
+	fmt.Fprintln(stderr, "Commands that can run as a service:", strings.Join(domain.DaemonCommands, ", "))
+}
+
+// runService dispatches the service subcommands.  The global --account is
+// passed on to the service, which does not see this run's arguments.
+func runService(args []string, account string) error {
+	if len(args) == 0 {
+		printServiceUsage()
+		return fmt.Errorf("service subcommand is required")
+	}
+	fs := flag.NewFlagSet("service "+args[0], flag.ExitOnError)
+	name := fs.String("name", "", "Name of the service, e.g. inbox for leonardo-inbox (default: the command's name)")
+	manager := fs.String("manager", defaultServiceManager(), "Service manager: "+strings.Join(domain.ServiceManagers, " or "))
+	switch args[0] {
+	case "install":
+		printOnly := fs.Bool("print", false, "Print the service definition instead of writing it")
+		force := fs.Bool("force", false, "Replace an existing service definition")
+		parseFlags(fs, args[1:])
+		command := fs.Args()
+		if len(command) == 0 || !isDaemonCommand(command[0]) {
+			fs.Usage()
+			return fmt.Errorf("give the command to run after --, one of: %s", strings.Join(domain.DaemonCommands, ", "))
+		}
+		if *name == "" {
+			*name = command[0]
+		}
+		spec, err := serviceSpec(*name, command, account)
+		if err != nil {
+			return err
+		}
+		return installService(spec, *manager, *printOnly, *force)
+	case "uninstall":
+		parseFlags(fs, args[1:])
+		if *name == "" {
+			fs.Usage()
+			return fmt.Errorf("--name is required")
+		}
+		return uninstallService(*name, *manager)
+	default:
+		printServiceUsage()
+		return fmt.Errorf("unknown service subcommand: %s", args[0])
+	}
+}
+
+// defaultServiceManager returns the service manager of this machine.
+func defaultServiceManager() string {
+	if runtime.GOOS == "darwin" {
+		return domain.ServiceLaunchd
+	}
+	return domain.ServiceSystemd
+}
+
+// isDaemonCommand reports whether command keeps running and so can be a
+// service.
+func isDaemonCommand(command string) bool {
+	for _, c := range domain.DaemonCommands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceSpec describes the service running command from the current
+// directory with this run's LEONARDO_* settings.  The API token is never
+// copied into the definition: the service uses a stored account.
+func serviceSpec(name string, command []string, account string) (domain.ServiceSpec, error) {
+	if err := domain.ValidateServiceName(name); err != nil {
+		return domain.ServiceSpec{}, err
+	}
+	program, err := os.Executable()
+	if err != nil {
+		return domain.ServiceSpec{}, fmt.Errorf("locating the leonardo executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(program); err == nil {
+		program = resolved
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return domain.ServiceSpec{}, err
+	}
+	spec := domain.ServiceSpec{Name: name, Program: program, Args: command, WorkDir: workDir, Env: serviceEnv(os.Environ(), account)}
+	if home, err := os.UserHomeDir(); err == nil {
+		spec.LogDir = filepath.Join(home, "Library", "Logs", "leonardo-cli")
+	}
+	return spec, nil
+}
+
+// serviceEnv returns the LEONARDO_* variables of environ for a service,
+// leaving out tokens, passphrases and other secrets judged by name, with
+// LEONARDO_ACCOUNT set to account when given.  LEONARDO_API_TOKEN_FILE is
+// kept: it names a file, not the token.
+func serviceEnv(environ []string, account string) map[string]string {
+	env := map[string]string{}
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, "LEONARDO_") {
+			continue
+		}
+		if domain.IsSecretName(key) {
+			continue
+		}
+		env[key] = value
+	}
+	if account != "" {
+		env["LEONARDO_ACCOUNT"] = account
+	}
+	return env
+}
+
+// servicePath returns where manager's definition of the service name is
+// installed for the current user.
+func servicePath(name, manager string) (string, error) {
+	spec := domain.ServiceSpec{Name: name}
+	switch manager {
+	case domain.ServiceSystemd:
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "systemd", "user", spec.UnitName()), nil
+	case domain.ServiceLaunchd:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "LaunchAgents", spec.Label()+".plist"), nil
+	}
+	return "", fmt.Errorf("unknown service manager %q (use %s)", manager, strings.Join(domain.ServiceManagers, " or "))
+}
+
+// installService writes the definition of spec for manager, or prints it,
+// and tells the user how to start it.
+func installService(spec domain.ServiceSpec, manager string, printOnly, force bool) error {
+	definition, err := spec.Render(manager)
+	if err != nil {
+		return err
+	}
+	if printOnly {
+		fmt.Print(definition)
+		return nil
+	}
+	path, err := servicePath(spec.Name, manager)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists; pass --force to replace it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if manager == domain.ServiceLaunchd && spec.LogDir != "" {
+		if err := os.MkdirAll(spec.LogDir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, []byte(definition), 0644); err != nil {
+		return err
+	}
+	fmt.Println("Service written:", path)
+	if _, ok := spec.Env["LEONARDO_ACCOUNT"]; !ok && os.Getenv("LEONARDO_API_TOKEN") != "" {
+		fmt.Fprintln(stderr, "Note: LEONARDO_API_TOKEN is not copied into the service; it will use the default stored account (see 'leonardo account add').")
+	}
+	fmt.Fprintln(messages(), "Start it with:")
+	if manager == domain.ServiceSystemd {
+		fmt.Fprintln(messages(), "  systemctl --user daemon-reload && systemctl --user enable --now", spec.UnitName())
+	} else {
+		fmt.Fprintln(messages(), "  launchctl load -w", path)
+	}
+	return nil
+}
+
+// uninstallService removes the definition of the service name, telling the
+// user to stop it first.
+func uninstallService(name, manager string) error {
+	if err := domain.ValidateServiceName(name); err != nil {
+		return err
+	}
+	path, err := servicePath(name, manager)
+	if err != nil {
+		return err
+	}
+	spec := domain.ServiceSpec{Name: name}
+	if manager == domain.ServiceSystemd {
+		fmt.Fprintln(messages(), "Stop it first with: systemctl --user disable --now", spec.UnitName())
+	} else {
+		fmt.Fprintln(messages(), "Stop it first with: launchctl unload -w", path)
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no service named %s at %s", name, path)
+		}
+		return err
+	}
+	fmt.Println("Service removed:", path)
+	return nil
+}
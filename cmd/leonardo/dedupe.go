@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"leonardo-cli/internal/service"
+)
+
+// dedupeFiles records the downloaded files for download --dedupe, replacing
+// each one identical to an earlier download with a hard link to it.  A file
+// that cannot be recorded only produces a warning; the download itself
+// succeeded.
+func dedupeFiles(dedupe *service.DedupeService, paths []string) {
+	for _, path := range paths {
+		to, err := dedupe.Add(path)
+		if err != nil {
+			fmt.Fprint(stderr, tr.Sprintf("Warning: could not deduplicate %s: %v\n", path, err))
+			continue
+		}
+		if to != "" {
+			fmt.Fprint(messages(), tr.Sprintf("%s is identical to %s; linked instead of kept as a copy\n", path, to))
+		}
+	}
+}
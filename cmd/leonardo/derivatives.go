@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"leonardo-cli/internal/imaging"
+)
+
+// derivativeSuffix is added to the file name of a web-ready copy, before
+// its extension; draftSuffix replaces it when the copy is watermarked.
+const (
+	derivativeSuffix = "_web"
+	draftSuffix      = "_draft"
+)
+
+// derivativeOptions describe the web-ready copies download makes of every
+// saved image, next to the original.
+type derivativeOptions struct {
+	size         image.Point // exact size from --resize
+	maxDimension int         // longest side from --max-dimension
+	quality      int         // JPEG quality from --quality; 0 keeps PNG
+	watermark    *imaging.Watermark
+}
+
+// parseDerivativeOptions validates the --resize, --max-dimension and
+// --quality flags of download.
+func parseDerivativeOptions(resize string, maxDimension, quality int) (derivativeOptions, error) {
+	var opts derivativeOptions
+	if resize != "" {
+		size, err := imaging.ParseSize(resize)
+		if err != nil {
+			return opts, fmt.Errorf("--resize: %w", err)
+		}
+		opts.size = size
+	}
+	if maxDimension < 0 {
+		return opts, errors.New("--max-dimension must be positive")
+	}
+	if maxDimension > 0 && resize != "" {
+		return opts, errors.New("--resize and --max-dimension cannot be combined")
+	}
+	if quality < 0 || quality > 100 {
+		return opts, errors.New("--quality must be between 1 and 100")
+	}
+	opts.maxDimension = maxDimension
+	opts.quality = quality
+	return opts, nil
+}
+
+// parseWatermark validates the --watermark, --watermark-image,
+// --watermark-corner and --watermark-opacity flags of download.  It returns
+// nil when neither text nor an image was given.
+func parseWatermark(text, imagePath, corner string, opacity float64) (*imaging.Watermark, error) {
+	if strings.TrimSpace(text) == "" && strings.TrimSpace(imagePath) == "" {
+		return nil, nil
+	}
+	if text != "" && imagePath != "" {
+		return nil, errors.New("--watermark and --watermark-image cannot be combined")
+	}
+	w := &imaging.Watermark{Text: text, Corner: strings.ToLower(strings.TrimSpace(corner)), Opacity: opacity}
+	if imagePath != "" {
+		overlay, err := imaging.Load(imagePath)
+		if err != nil {
+			return nil, fmt.Errorf("--watermark-image: %w", err)
+		}
+		w.Image = overlay
+	}
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// enabled reports whether any derivative was requested.
+func (o derivativeOptions) enabled() bool {
+	return o.size != (image.Point{}) || o.maxDimension > 0 || o.quality > 0 || o.watermark != nil
+}
+
+// label names the copies in download's output.
+func (o derivativeOptions) label() string {
+	if o.watermark != nil {
+		return "Draft"
+	}
+	return "Web copy"
+}
+
+// path returns where the derivative of the image at original is written:
+// {name}_web.jpg when a quality was given, {name}_web.png otherwise, with
+// _draft instead of _web for a watermarked copy.
+func (o derivativeOptions) path(original string) string {
+	ext := ".png"
+	if o.quality > 0 {
+		ext = ".jpg"
+	}
+	suffix := derivativeSuffix
+	if o.watermark != nil {
+		suffix = draftSuffix
+	}
+	return strings.TrimSuffix(original, filepath.Ext(original)) + suffix + ext
+}
+
+// makeDerivative writes the web-ready copy of the image at original,
+// resized and then watermarked as requested, and returns its path.  The
+// original is left untouched.
+func makeDerivative(original string, o derivativeOptions) (string, error) {
+	img, err := imaging.Load(original)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case o.size != (image.Point{}):
+		img = imaging.Resize(img, o.size)
+	case o.maxDimension > 0:
+		if size := imaging.FitWithin(img.Bounds().Size(), o.maxDimension); size != img.Bounds().Size() {
+			img = imaging.Resize(img, size)
+		}
+	}
+	if o.watermark != nil {
+		img = imaging.ApplyWatermark(img, *o.watermark)
+	}
+	dest := o.path(original)
+	if o.quality > 0 {
+		err = imaging.SaveJPEG(dest, img, o.quality)
+	} else {
+		err = imaging.SavePNG(dest, img)
+	}
+	if err != nil {
+		return "", err
+	}
+	return dest, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"leonardo-cli/internal/service"
+)
+
+// ensureDiskSpace fails when the file system holding dir has less than
+// needed bytes free.  dir may not exist yet, in which case its nearest
+// existing parent is checked.  Platforms that cannot report free space pass.
+func ensureDiskSpace(dir string, needed int64) error {
+	if needed <= 0 {
+		return nil
+	}
+	dir = existingParent(dir)
+	free, ok := freeDiskSpace(dir)
+	if !ok || uint64(needed) <= free {
+		return nil
+	}
+	return fmt.Errorf("not enough disk space in %s: about %s needed, %s available", dir, formatBytes(needed), formatBytes(int64(free)))
+}
+
+// existingParent returns dir, or its nearest parent that exists.
+func existingParent(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "."
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// checkDownloadSpace estimates the size of downloading the given generations
+// and fails before anything is written when outputDir cannot hold them.  An
+// estimate that cannot be made is reported and the download goes ahead.
+func checkDownloadSpace(svc *service.GenerationService, ids []string, outputDir string, includeVariations bool) error {
+	needed, err := svc.EstimateDownload(ids, includeVariations)
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: skipping disk space check:", err)
+		return nil
+	}
+	return ensureDiskSpace(outputDir, needed)
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+// freeDiskSpace cannot query free space on this platform, so disk space
+// checks are skipped.
+func freeDiskSpace(dir string) (uint64, bool) {
+	return 0, false
+}
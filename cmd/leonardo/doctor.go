@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/config"
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/provider"
+)
+
+// doctorEnv is what doctor checks beyond the local files: how to find the
+// token, a description of where it comes from, and a call to /me with it.
+type doctorEnv struct {
+	token       func() (string, error)
+	tokenSource string
+	probe       func(token string) (domain.RawResponse, error)
+}
+
+// runDoctor checks the environment the CLI runs in and prints a checklist.
+// It returns 1 when any check failed.
+func runDoctor(args []string, env doctorEnv) int {
+	doctorCmd := flag.NewFlagSet("doctor", flag.ExitOnError)
+	outputDir := doctorCmd.String("output-dir", ".", "Directory images and sidecars are written to")
+	parseFlags(doctorCmd, args)
+	checks := []domain.DoctorCheck{
+		checkConfigFile("."),
+		checkWritableDir("Output directory", *outputDir, false),
+		checkWritableDir("State directory", leonardoHome(), true),
+		checkCredentialStore(accountsPath(), runtime.GOOS),
+	}
+	checks = append(checks, checkAPI(env, time.Now)...)
+	if outputFormat != nil {
+		for _, c := range checks {
+			printFormatted(c)
+		}
+	} else {
+		printDoctorChecks(os.Stdout, checks)
+	}
+	if !domain.Healthy(checks) {
+		fmt.Fprintln(stderr, "Some checks failed; include this output when asking for help.")
+		return 1
+	}
+	return 0
+}
+
+// checkConfigFile reports whether the project configuration found from dir,
+// if any, can be read.
+func checkConfigFile(dir string) domain.DoctorCheck {
+	check := domain.DoctorCheck{Name: "Config file", Status: domain.CheckPass}
+	path, ok := config.FindProjectFile(dir)
+	if !ok {
+		check.Detail = "no .leonardo.yaml found; defaults and LEONARDO_* variables apply"
+		return check
+	}
+	if _, err := config.LoadFile(path); err != nil {
+		check.Status, check.Detail = domain.CheckFail, err.Error()
+		return check
+	}
+	check.Detail = path
+	return check
+}
+
+// checkWritableDir reports whether a file can be created in dir.  With
+// create, a missing directory is created first, as the CLI does for its
+// state directory.
+func checkWritableDir(name, dir string, create bool) domain.DoctorCheck {
+	check := domain.DoctorCheck{Name: name, Status: domain.CheckFail, Detail: dir}
+	if create {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			check.Detail = err.Error()
+			return check
+		}
+	}
+	f, err := os.CreateTemp(dir, ".leonardo-doctor-*")
+	if err != nil {
+		check.Detail = fmt.Sprintf("%s is not writable: %v", dir, err)
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+	check.Status = domain.CheckPass
+	return check
+}
+
+// checkCredentialStore reports where stored account tokens live and warns
+// when other users can read them.  Tokens are kept in a file readable only
+// by its owner; the OS keychain is not used.
+func checkCredentialStore(path, goos string) domain.DoctorCheck {
+	check := domain.DoctorCheck{Name: "Credential store", Status: domain.CheckPass}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		check.Detail = "no stored accounts; tokens would be kept in " + path + " (no OS keychain support)"
+	case err != nil:
+		check.Status, check.Detail = domain.CheckFail, err.Error()
+	case goos != "windows" && info.Mode().Perm()&0077 != 0:
+		check.Status = domain.CheckWarn
+		check.Detail = fmt.Sprintf("%s is readable by other users; run chmod 600 %s", path, path)
+	default:
+		check.Detail = path + " (no OS keychain support)"
+	}
+	return check
+}
+
+// checkAPI checks the token, that the API can be reached with it and that
+// the local clock agrees with the API's.  The later checks are skipped when
+// an earlier one fails.
+func checkAPI(env doctorEnv, now func() time.Time) []domain.DoctorCheck {
+	token := domain.DoctorCheck{Name: "API token", Status: domain.CheckFail}
+	key, err := env.token()
+	if err != nil {
+		token.Detail = err.Error()
+		return []domain.DoctorCheck{token}
+	}
+	network := domain.DoctorCheck{Name: "Network", Status: domain.CheckFail}
+	resp, err := env.probe(key)
+	var apiErr *domain.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		token.Status, token.Detail = domain.CheckWarn, "found in "+env.tokenSource+" but not verified"
+		network.Detail = fmt.Sprintf("cannot reach %s: %v", apiHost(), err)
+		return []domain.DoctorCheck{token, network}
+	}
+	network.Status, network.Detail = domain.CheckPass, "reached "+apiHost()
+	switch state := domain.ClassifyTokenError(err); state {
+	case domain.TokenValid:
+		token.Status, token.Detail = domain.CheckPass, "valid, from "+env.tokenSource
+	case domain.TokenUnverified:
+		token.Status, token.Detail = domain.CheckWarn, fmt.Sprintf("could not be verified (from %s): %v", env.tokenSource, err)
+	default:
+		token.Detail = fmt.Sprintf("%s (from %s): %v", state, env.tokenSource, err)
+	}
+	checks := []domain.DoctorCheck{token, network}
+	if server, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		checks = append(checks, domain.CheckClockSkew(now(), server))
+	} else {
+		checks = append(checks, domain.DoctorCheck{Name: "Clock", Status: domain.CheckWarn, Detail: "the API sent no Date header to compare with"})
+	}
+	return checks
+}
+
+// apiHost returns the host name of the API.
+func apiHost() string {
+	host := strings.TrimPrefix(provider.APIBaseURL, "https://")
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// printDoctorChecks writes the checklist, one check per line.
+func printDoctorChecks(w io.Writer, checks []domain.DoctorCheck) {
+	tw := newTable(w)
+	for _, c := range checks {
+		label := strings.ToUpper(c.Status)
+		switch c.Status {
+		case domain.CheckPass:
+			label = colors.wrap(ansiGreen, label)
+		case domain.CheckWarn:
+			label = colors.wrap(ansiYellow, label)
+		default:
+			label = colors.wrap(ansiRed, label)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", label, c.Name, c.Detail)
+	}
+	tw.Flush()
+}
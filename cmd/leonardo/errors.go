@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"leonardo-cli/internal/domain"
+)
+
+// jsonErrors is set by --format json: errors are then written to stderr as
+// one JSON object per line instead of free text.
+var jsonErrors bool
+
+// errorOutput is the JSON form of an error.  HTTPStatus and RequestID are
+// set for errors returned by the API.
+type errorOutput struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// newErrorOutput describes err, reported under label such as "Error
+// creating generation".
+func newErrorOutput(label string, err error) errorOutput {
+	out := errorOutput{Code: domain.ErrorCode(err), Message: err.Error()}
+	if label != "" {
+		out.Message = label + ": " + out.Message
+	}
+	var apiErr *domain.APIError
+	if errors.As(err, &apiErr) {
+		out.HTTPStatus, out.RequestID = apiErr.StatusCode, apiErr.RequestID
+	}
+	return out
+}
+
+// reportError writes err to stderr under label, as JSON with --format json
+// and as "label: err" otherwise, the label in the user's language.
+func reportError(label string, err error) {
+	if !jsonErrors {
+		if label == "" {
+			fmt.Fprintln(stderr, err)
+		} else {
+			fmt.Fprintln(stderr, tr.Sprintf(label)+":", err)
+		}
+		return
+	}
+	data, merr := json.Marshal(newErrorOutput(label, err))
+	if merr != nil {
+		fmt.Fprintln(stderr, label+":", err)
+		return
+	}
+	fmt.Fprintln(stderr, string(data))
+}
+
+// fail reports err under label and exits with 1.
+func fail(label string, err error) {
+	reportError(label, err)
+	exit(1)
+}
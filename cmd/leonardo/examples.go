@@ -0,0 +1,116 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// examplesCatalog holds the recipes printed by the examples command.
+//
+//go:embed examples.json
+var examplesCatalog []byte
+
+// example is a recipe: command lines to run one after the other for a
+// common workflow, grouped by topic.
+type example struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Commands []string `json:"commands"`
+	Note     string   `json:"note,omitempty"`
+}
+
+// loadExamples parses the embedded catalog.
+func loadExamples() ([]example, error) {
+	var examples []example
+	if err := json.Unmarshal(examplesCatalog, &examples); err != nil {
+		return nil, fmt.Errorf("parsing the examples catalog: %w", err)
+	}
+	return examples, nil
+}
+
+// exampleTopics returns the topics of examples in alphabetical order.
+func exampleTopics(examples []example) []string {
+	seen := map[string]bool{}
+	var topics []string
+	for _, e := range examples {
+		if !seen[e.Topic] {
+			seen[e.Topic] = true
+			topics = append(topics, e.Topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// runExamples prints the recipes of the catalog, those of one topic when
+// one is given, or with --topics just the topics.
+func runExamples(args []string) error {
+	examplesCmd := flag.NewFlagSet("examples", flag.ExitOnError)
+	listTopics := examplesCmd.Bool("topics", false, "List the topics instead of the recipes")
+	positional, err := parseInterspersed(examplesCmd, args)
+	if err != nil {
+		return err
+	}
+	applyConfig(examplesCmd)
+	examples, err := loadExamples()
+	if err != nil {
+		return err
+	}
+	topics := exampleTopics(examples)
+	if *listTopics {
+		for _, topic := range topics {
+			fmt.Println(topic)
+		}
+		return nil
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("give at most one topic")
+	}
+	if len(positional) == 1 {
+		topic := strings.ToLower(positional[0])
+		var matched []example
+		for _, e := range examples {
+			if e.Topic == topic {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) == 0 {
+			if near, ok := closest(topic, topics); ok {
+				return fmt.Errorf("no examples for %q; did you mean %q?", topic, near)
+			}
+			return fmt.Errorf("no examples for %q; topics are %s", topic, strings.Join(topics, ", "))
+		}
+		examples = matched
+	}
+	if outputFormat != nil {
+		for _, e := range examples {
+			printFormatted(e)
+		}
+		return nil
+	}
+	printExamples(os.Stdout, examples)
+	return nil
+}
+
+// printExamples writes each recipe as a comment line naming it followed by
+// its command lines, so a block can be pasted into a shell as it is.
+func printExamples(w io.Writer, examples []example) {
+	for i, e := range examples {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# [%s] %s\n", e.Topic, e.Title)
+		if e.Note != "" {
+			fmt.Fprintf(w, "# %s\n", e.Note)
+		}
+		for _, c := range e.Commands {
+			fmt.Fprintln(w, c)
+		}
+	}
+}
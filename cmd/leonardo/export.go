@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// exportExtensions is the file extension of each export format, added to
+// the sidecar's name when writing to --output-dir.
+var exportExtensions = map[string]string{
+	domain.ExportA1111: ".a1111.txt",
+	domain.ExportComfy: ".comfy.json",
+}
+
+// runExport converts sidecar metadata files into the formats of Stable
+// Diffusion UIs.  A single sidecar is written to stdout; several need
+// --output-dir.
+func runExport(args []string) error {
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	to := exportCmd.String("to", "", "Format to export to: "+strings.Join(domain.ExportFormats, " or ")+" (required)")
+	outputDir := exportCmd.String("output-dir", "", "Write <sidecar>"+exportExtensions[domain.ExportA1111]+" or <sidecar>"+exportExtensions[domain.ExportComfy]+" files here instead of printing")
+	parseFlags(exportCmd, args)
+	if _, ok := exportExtensions[*to]; !ok {
+		exportCmd.Usage()
+		return fmt.Errorf("--to must be one of %s", strings.Join(domain.ExportFormats, ", "))
+	}
+	files := exportCmd.Args()
+	switch {
+	case len(files) == 0:
+		exportCmd.Usage()
+		return fmt.Errorf("at least one sidecar file is required")
+	case len(files) > 1 && *outputDir == "":
+		return fmt.Errorf("exporting %d sidecars needs --output-dir", len(files))
+	}
+	for _, path := range files {
+		out, err := exportSidecar(path, *to)
+		if err != nil {
+			return err
+		}
+		if *outputDir == "" {
+			fmt.Print(string(out))
+			if !strings.HasSuffix(string(out), "\n") {
+				fmt.Println()
+			}
+			continue
+		}
+		dest := filepath.Join(*outputDir, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+exportExtensions[*to])
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, out, 0644); err != nil {
+			return fmt.Errorf("writing export: %w", err)
+		}
+		fmt.Println("Exported:", dest)
+	}
+	return nil
+}
+
+// exportSidecar reads the sidecar at path and converts it to format.
+func exportSidecar(path, format string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading sidecar metadata: %w", err)
+	}
+	sidecar, err := domain.ParseSidecar(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sidecar.Export(format)
+}
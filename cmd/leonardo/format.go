@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// outputFormat is the Go template given with --format.  When set, commands
+// write one line per record through it instead of their usual output, and
+// progress messages move to stderr so stdout holds only the records.
+var outputFormat *template.Template
+
+// formatJSON is the --format value that prints every record as a JSON
+// object and reports errors as JSON on stderr.
+const formatJSON = "json"
+
+// formatFuncs are the functions available to --format templates besides
+// the text/template builtins.
+var formatFuncs = template.FuncMap{
+	"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"rfc3339": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	},
+}
+
+// parseFormat compiles a --format template.  The escapes \t and \n are
+// expanded so tab-separated output can be asked for from any shell.
+func parseFormat(text string) (*template.Template, error) {
+	text = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(text)
+	tmpl, err := template.New("format").Funcs(formatFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// writeFormatted executes tmpl for record and ends the line.
+func writeFormatted(w io.Writer, tmpl *template.Template, record interface{}) error {
+	if err := tmpl.Execute(w, record); err != nil {
+		return fmt.Errorf("--format: %w", err)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// printFormatted writes record through --format and reports whether it did;
+// without --format it does nothing, so callers fall back to their usual
+// output.  A template that does not fit the record ends the program.
+func printFormatted(record interface{}) bool {
+	if outputFormat == nil {
+		return false
+	}
+	if err := writeFormatted(os.Stdout, outputFormat, record); err != nil {
+		fail("Error", err)
+	}
+	return true
+}
+
+// messages is where commands write progress and informational lines: stdout
+// normally, stderr when --format reserves stdout for records.
+func messages() io.Writer {
+	if outputFormat != nil {
+		return stderr
+	}
+	return os.Stdout
+}
+
+// generationOutput is the record --format sees for commands acting on a
+// generation.  Fields a command does not know are left empty.
+type generationOutput struct {
+	GenerationID string
+	Name         string
+	Status       string
+	CreatedAt    time.Time
+	Prompt       string
+	ModelID      string
+	Style        string
+	Images       []string
+	SidecarPath  string
+	Files        []string
+	Note         string
+}
+
+// detailOutput converts a generation record for --format.
+func detailOutput(d domain.GenerationDetail) generationOutput {
+	out := generationOutput{GenerationID: d.ID, Status: d.Status, CreatedAt: d.CreatedAt, Prompt: redactor.Prompt(d.Prompt), ModelID: d.ModelID}
+	for _, img := range d.Images {
+		out.Images = append(out.Images, img.URL)
+	}
+	return out
+}
+
+// listItemOutput converts a generation summary for --format.
+func listItemOutput(gen domain.GenerationListItem) generationOutput {
+	return generationOutput{GenerationID: gen.ID, Status: gen.Status, CreatedAt: gen.CreatedAt, Prompt: redactor.Prompt(gen.Prompt), Images: gen.Images}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+	"leonardo-cli/internal/storage"
+)
+
+// historyPath returns the location of the invocation history log.
+func historyPath() string {
+	return filepath.Join(leonardoHome(), "history.jsonl")
+}
+
+// invocationRecord is what the current run will add to the history when it
+// exits.
+type invocationRecord struct {
+	args         []string
+	started      time.Time
+	generationID string
+}
+
+// invocation is the run being recorded, or nil when history is off or the
+// command is history itself.
+var invocation *invocationRecord
+
+// historyEnabled reports whether invocations are recorded.  It is on unless
+// LEONARDO_HISTORY is set to a false value.
+func historyEnabled() bool {
+	value := strings.TrimSpace(os.Getenv("LEONARDO_HISTORY"))
+	if value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err != nil || enabled
+}
+
+// startHistory begins recording this run.  Invocations of history are not
+// recorded, so rerunning does not bury the entries being rerun.
+func startHistory(args []string, cmd string) {
+	if cmd == "history" || !historyEnabled() {
+		return
+	}
+	invocation = &invocationRecord{args: args, started: time.Now().UTC()}
+}
+
+// noteGenerationID records the generation this run created.
+func noteGenerationID(id string) {
+	if invocation != nil {
+		invocation.generationID = id
+	}
+}
+
+// recordHistory appends the run to the history.  Arguments are redacted at
+// this point, when every secret of the run is known to the redactor.  A
+// history that cannot be written must not fail the command, so errors only
+// show with --verbose.
+func recordHistory(code int) {
+	if invocation == nil {
+		return
+	}
+	dir, _ := os.Getwd()
+	entry := domain.HistoryEntry{
+		At:           invocation.started,
+		Dir:          dir,
+		Args:         domain.RedactArgs(invocation.args, redactor),
+		ExitCode:     code,
+		GenerationID: invocation.generationID,
+	}
+	invocation = nil
+	if err := service.NewHistoryService(storage.NewFileHistory(historyPath())).Record(entry); err != nil && stats.verbose {
+		fmt.Fprintln(stderr, "Warning: could not record history:", err)
+	}
+}
+
+// printHistoryUsage prints the history subcommands.
+func printHistoryUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo history [--limit N] | leonardo history rerun [--here] <N>")
+	fmt.Fprintln(stderr, "  history        List recent invocations with their number")
+	fmt.Fprintln(stderr, "  history rerun  Run invocation N again, in the directory it ran in")
+}
+
+// runHistory lists or reruns recorded invocations and returns the exit
+// code of the program.  A rerun exits with the code of the rerun command.
+func runHistory(args []string) int {
+	history := service.NewHistoryService(storage.NewFileHistory(historyPath()))
+	if len(args) > 0 && args[0] == "rerun" {
+		code, err := rerunHistory(history, args[1:])
+		if err != nil {
+			reportError("Error", err)
+			return 1
+		}
+		return code
+	}
+	historyCmd := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := historyCmd.Int("limit", 20, "Show this many recent invocations (0 for all)")
+	parseFlags(historyCmd, args)
+	if historyCmd.NArg() > 0 {
+		printHistoryUsage()
+		return 1
+	}
+	entries, err := history.Recent(*limit)
+	if err != nil {
+		reportError("Error reading history", err)
+		return 1
+	}
+	if outputFormat != nil {
+		for _, e := range entries {
+			printFormatted(e)
+		}
+		return 0
+	}
+	if err := printHistory(os.Stdout, entries); err != nil {
+		reportError("Error", err)
+		return 1
+	}
+	return 0
+}
+
+// printHistory writes invocations as a table.
+func printHistory(w io.Writer, entries []domain.HistoryEntry) error {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No invocations recorded yet.")
+		return nil
+	}
+	tw := newTable(w)
+	fmt.Fprintln(tw, "#\tWHEN\tEXIT\tGENERATION\tCOMMAND")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", e.Number, e.At.Local().Format(time.RFC3339), e.ExitCode, e.GenerationID, e.CommandLine())
+	}
+	return tw.Flush()
+}
+
+// rerunHistory runs a recorded invocation again as a new process, so it is
+// recorded in the history like any other run.
+func rerunHistory(history *service.HistoryService, args []string) (int, error) {
+	rerunCmd := flag.NewFlagSet("history rerun", flag.ExitOnError)
+	here := rerunCmd.Bool("here", false, "Run in the current directory instead of the one the invocation ran in")
+	rest, err := parseInterspersed(rerunCmd, args)
+	if err != nil {
+		return 1, err
+	}
+	if len(rest) != 1 {
+		printHistoryUsage()
+		return 1, fmt.Errorf("history rerun needs the number of one invocation")
+	}
+	n, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return 1, fmt.Errorf("invalid invocation number %q", rest[0])
+	}
+	entry, err := history.Get(n)
+	if err != nil {
+		return 1, err
+	}
+	if err := entry.Replayable(); err != nil {
+		return 1, err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return 1, fmt.Errorf("locating the leonardo binary: %w", err)
+	}
+	cmd := exec.Command(self, entry.Args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if !*here && entry.Dir != "" {
+		if info, err := os.Stat(entry.Dir); err == nil && info.IsDir() {
+			cmd.Dir = entry.Dir
+		} else {
+			fmt.Fprintf(stderr, "Warning: %s no longer exists; running in the current directory\n", entry.Dir)
+		}
+	}
+	fmt.Fprintf(stderr, "Re-running #%d: leonardo %s\n", entry.Number, entry.CommandLine())
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, fmt.Errorf("running invocation %d: %w", n, err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"leonardo-cli/internal/i18n"
+)
+
+// tr renders user-facing messages in the user's language.  It stays
+// English until main loads the catalogs.
+var tr = i18n.New(i18n.SourceLocale, nil)
+
+// loadTranslator returns a translator for the locale chosen by the
+// environment, using the shipped catalogs and any in the locales directory
+// of the state directory, which can add a language or fix a translation.
+func loadTranslator(getenv func(string) string) *i18n.Translator {
+	catalogs, err := i18n.Builtin()
+	if err == nil {
+		err = i18n.LoadDir(catalogs, filepath.Join(leonardoHome(), "locales"))
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: messages stay in English:", err)
+		return i18n.New(i18n.SourceLocale, nil)
+	}
+	return i18n.New(i18n.Detect(getenv), catalogs)
+}
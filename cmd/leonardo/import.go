@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/imaging"
+	"leonardo-cli/internal/service"
+)
+
+// a1111TextKey is the PNG text chunk AUTOMATIC1111 stores its settings in.
+const a1111TextKey = "parameters"
+
+// runImport reads a prompt and its settings from another tool's metadata
+// and prints it as a batch --stdin request line, or submits it.
+func runImport(svc *service.GenerationService, lib *service.LibraryService, models *service.ModelService, args []string) error {
+	importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+	modelID := importCmd.String("model-id", "", "Model ID to generate with; the source's checkpoint cannot be used (can be set with LEONARDO_MODEL_ID)")
+	numImages := importCmd.Int("num-images", 1, "Number of images to generate (1-8)")
+	name := importCmd.String("name", "", "Optional human-friendly name for the submitted generation")
+	submit := importCmd.Bool("submit", false, "Submit the imported request instead of printing it")
+	parseFlags(importCmd, args)
+	if importCmd.NArg() != 1 {
+		importCmd.Usage()
+		return fmt.Errorf("exactly one PNG, parameters text or civitai JSON file is required")
+	}
+	imported, err := importPrompt(importCmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(imported.Ignored) > 0 {
+		fmt.Fprintln(stderr, "Ignored settings without a Leonardo equivalent:", strings.Join(imported.Ignored, ", "))
+	}
+	req := domain.GenerationRequest{NumImages: *numImages, Metadata: imported.Metadata}
+	if *modelID != "" {
+		req.Metadata.ModelID = *modelID
+	}
+	req.Metadata.Name = strings.TrimSpace(*name)
+	if !*submit {
+		line, err := json.Marshal(manifestRequestFromDomain(redactRequest(req)))
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(line))
+		return nil
+	}
+	if err := checkCapabilities(models, req.Metadata); err != nil {
+		return err
+	}
+	created, err := createGeneration(svc, lib, req)
+	if err != nil {
+		return err
+	}
+	printFormatted(created)
+	return nil
+}
+
+// importPrompt reads the file at path by its extension: the parameters
+// text chunk of a PNG, a civitai JSON file, or AUTOMATIC1111 parameters
+// text.
+func importPrompt(path string) (domain.ImportedPrompt, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		text, err := imaging.PNGText(path)
+		if err != nil {
+			return domain.ImportedPrompt{}, err
+		}
+		params, ok := text[a1111TextKey]
+		if !ok {
+			return domain.ImportedPrompt{}, fmt.Errorf("%s has no %q text chunk with generation settings", path, a1111TextKey)
+		}
+		return domain.ParseA1111Parameters(params)
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return domain.ImportedPrompt{}, err
+		}
+		return domain.ParseCivitaiJSON(data)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return domain.ImportedPrompt{}, err
+	}
+	return domain.ParseA1111Parameters(string(data))
+}
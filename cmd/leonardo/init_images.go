@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// printInitImagesUsage prints the init-images subcommands.
+func printInitImagesUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo init-images <subcommand> [args]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  list               List init images uploaded from this machine")
+	fmt.Fprintln(stderr, "  upload <file>...   Upload reference images (png, jpg, jpeg or webp)")
+	fmt.Fprintln(stderr, "  show <id>          Show an init image as stored by Leonardo")
+	fmt.Fprintln(stderr, "  delete <id>...     Delete init images from Leonardo")
+}
+
+// runInitImages dispatches the init-images subcommands.
+func runInitImages(images *service.InitImageService, args []string) error {
+	if len(args) == 0 {
+		printInitImagesUsage()
+		return fmt.Errorf("init-images subcommand is required")
+	}
+	sub, rest := args[0], args[1:]
+	switch sub {
+	case "list":
+		list, err := images.List()
+		if err != nil {
+			return err
+		}
+		if outputFormat != nil {
+			for _, image := range list {
+				printFormatted(image)
+			}
+			return nil
+		}
+		return listInitImages(os.Stdout, list, time.Now())
+	case "upload":
+		if len(rest) == 0 {
+			printInitImagesUsage()
+			return fmt.Errorf("init-images upload requires at least one file")
+		}
+		for _, path := range rest {
+			image, err := images.Upload(path)
+			if err != nil {
+				return fmt.Errorf("uploading %s: %w", path, err)
+			}
+			if !printFormatted(image) {
+				fmt.Printf("Uploaded %s: %s\n", path, colors.id(image.ID))
+			}
+		}
+	case "show":
+		if len(rest) != 1 {
+			printInitImagesUsage()
+			return fmt.Errorf("init-images show requires exactly one ID")
+		}
+		image, err := images.Show(rest[0])
+		if err != nil {
+			return err
+		}
+		if printFormatted(image) || printQueried(image.Raw) {
+			return nil
+		}
+		fmt.Println("ID:", colors.id(image.ID))
+		fmt.Println("URL:", image.URL)
+		if created := formatTimestamp(image.CreatedAt, timestampsRelative, time.Now()); created != "" {
+			fmt.Println("Created:", created)
+		}
+		prettyPrintJSON(image.Raw)
+	case "delete":
+		if len(rest) == 0 {
+			printInitImagesUsage()
+			return fmt.Errorf("init-images delete requires at least one ID")
+		}
+		for _, id := range rest {
+			if _, err := images.Delete(id); err != nil {
+				return fmt.Errorf("deleting %s: %w", id, err)
+			}
+			fmt.Println("Deleted init image:", colors.id(id))
+		}
+	default:
+		printInitImagesUsage()
+		return fmt.Errorf("unknown init-images subcommand: %s", sub)
+	}
+	return nil
+}
+
+// listInitImages prints the recorded init images as a table, oldest first.
+func listInitImages(w io.Writer, images []domain.InitImage, now time.Time) error {
+	if len(images) == 0 {
+		fmt.Fprintln(w, "No init images uploaded from this machine yet.")
+		return nil
+	}
+	tw := newTable(w)
+	fmt.Fprintln(tw, "ID\tFILE\tUPLOADED\tURL")
+	for _, image := range images {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", image.ID, image.FileName, formatTimestamp(image.CreatedAt, timestampsRelative, now), image.URL)
+	}
+	return tw.Flush()
+}
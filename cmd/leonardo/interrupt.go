@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// exitInterrupted is the exit code of a run stopped by SIGINT or SIGTERM,
+// following the shell convention of 128 plus the signal number.
+const exitInterrupted = 130
+
+// interruptGrace bounds how long a stopped run may spend saving manifests
+// and reporting before the program exits anyway.
+const interruptGrace = 5 * time.Second
+
+// exitTimedOut is the exit code of a run stopped by --timeout, the code
+// timeout(1) uses.
+const exitTimedOut = 124
+
+// interruptCtx is cancelled on the first interrupt.
+var interruptCtx, cancelRun = context.WithCancel(context.Background())
+
+// runCtx is interruptCtx, bounded by --timeout when given; the API client
+// and services run under it, so requests in flight and waits stop at once.
+var runCtx = interruptCtx
+
+// runTimeout is the --timeout the run is bounded by, zero for none;
+// cancelTimeout releases its timer.
+var (
+	runTimeout    time.Duration
+	cancelTimeout context.CancelFunc
+)
+
+// limitRun bounds the rest of the run to timeout.  It must be called before
+// any client or service is given runCtx.
+func limitRun(timeout time.Duration) {
+	runTimeout = timeout
+	runCtx, cancelTimeout = context.WithTimeout(interruptCtx, timeout)
+}
+
+// unfinished lists the generations this run created that are still
+// pending.  It is set once the generation service exists.
+var unfinished func() []string
+
+// handleInterrupts cancels interruptCtx, and with it runCtx, on SIGINT or
+// SIGTERM so the command can save its state and return.  A second signal, or a command that does not
+// return within interruptGrace, exits straight away.
+func handleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancelRun()
+		fmt.Fprintln(stderr, "Interrupted; cleaning up (press Ctrl-C again to quit at once)...")
+		select {
+		case <-signals:
+		case <-time.After(interruptGrace):
+		}
+		exit(exitInterrupted)
+	}()
+}
+
+// interrupted reports whether the run has been interrupted.
+func interrupted() bool {
+	return interruptCtx.Err() != nil
+}
+
+// timedOut reports whether the run was stopped by --timeout.
+func timedOut() bool {
+	return !interrupted() && runCtx.Err() != nil
+}
+
+// reportUnfinished lists the generations left pending by an interrupted
+// run so they can be picked up later.
+func reportUnfinished() {
+	if unfinished == nil {
+		return
+	}
+	ids := unfinished()
+	if len(ids) == 0 {
+		return
+	}
+	fmt.Fprintln(stderr, "Generations still pending:")
+	for _, id := range ids {
+		fmt.Fprintln(stderr, " ", id)
+	}
+	fmt.Fprintln(stderr, "Check on them with: leonardo status <generation-id>")
+}
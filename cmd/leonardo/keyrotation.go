@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/provider"
+	"leonardo-cli/internal/service"
+)
+
+// keyRotation spreads the run over the keys of several stored accounts.  It
+// is set when --account names more than one.
+var keyRotation *service.KeyRotation
+
+// newKeyRotation builds a rotation over the keys of the stored accounts
+// names, in that order, announcing each move from one key to the next.
+func newKeyRotation(accounts *service.AccountService, names []string) (*service.KeyRotation, error) {
+	keys := make([]service.RotationKey, 0, len(names))
+	for _, name := range names {
+		token, _, err := accounts.Token(name)
+		if err != nil {
+			return nil, err
+		}
+		registerSecret(token)
+		client := provider.NewAPIClient(token, nil)
+		client.SetObserver(stats.record)
+		client.SetContext(runCtx)
+		keys = append(keys, service.RotationKey{Account: name, Client: client})
+	}
+	rotation := service.NewKeyRotation(keys)
+	rotation.SetOnRotate(func(from, to, reason string) {
+		fmt.Fprintf(stderr, "Account %s %s; switching to %s\n", from, reason, to)
+	})
+	return rotation, nil
+}
+
+// printKeyUsage renders what each key of a rotation was used for, once any
+// was used.
+func printKeyUsage(w io.Writer, usage []domain.KeyUsage) {
+	used := false
+	for _, u := range usage {
+		used = used || u.Requests > 0
+	}
+	if !used {
+		return
+	}
+	tw := newTable(w)
+	fmt.Fprintln(tw, "ACCOUNT\tSUBMITTED\tCREATED\tCOST\tRATE LIMITED\tSTATUS")
+	for _, u := range usage {
+		status := "ok"
+		if u.OutOfTokens {
+			status = "out of tokens"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", u.Account, u.Requests, u.Generations, u.Cost, u.RateLimited, status)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// loadKinds returns the built-in generation templates with the kind.*
+// settings of the project configuration applied.
+func loadKinds() ([]domain.Kind, error) {
+	overrides := map[string]string{}
+	if project := loadProjectConfig(); project != nil {
+		overrides = project.Prefixed(domain.KindConfigPrefix)
+	}
+	return domain.Kinds(overrides)
+}
+
+// applyKind sets the flags of fs that were not given on the command line
+// from the kind selected with --kind, or with LEONARDO_KIND or the project
+// configuration.  It must be called after fs is parsed and before the
+// configuration is applied, so a kind's settings win over general
+// defaults while flags on the command line win over the kind.
+func applyKind(fs *flag.FlagSet) error {
+	name := fs.Lookup("kind").Value.String()
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if !explicit["kind"] {
+		for _, source := range configSources() {
+			if value, _, ok := source.Lookup("kind"); ok {
+				name = value
+				break
+			}
+		}
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	kinds, err := loadKinds()
+	if err != nil {
+		return err
+	}
+	kind, err := domain.FindKind(kinds, name)
+	if err != nil {
+		return err
+	}
+	for _, setting := range kind.SettingNames() {
+		// A style given either way replaces the kind's.
+		if explicit[setting] || (setting == "style" && (explicit["style-uuid"] || explicit["style-name"])) || (setting == "style-uuid" && (explicit["style"] || explicit["style-name"])) {
+			continue
+		}
+		if fs.Lookup(setting) == nil {
+			return fmt.Errorf("kind %s sets %q, which is not a create option", kind.Name, setting)
+		}
+		if err := fs.Set(setting, kind.Settings[setting]); err != nil {
+			return fmt.Errorf("kind %s: invalid %s %q: %w", kind.Name, setting, kind.Settings[setting], err)
+		}
+	}
+	return nil
+}
+
+// runKinds lists the generation templates usable with create --kind.
+func runKinds(args []string) error {
+	kindsCmd := flag.NewFlagSet("kinds", flag.ExitOnError)
+	parseFlags(kindsCmd, args)
+	kinds, err := loadKinds()
+	if err != nil {
+		return err
+	}
+	if outputFormat != nil {
+		for _, k := range kinds {
+			printFormatted(k)
+		}
+		return nil
+	}
+	return printKinds(os.Stdout, kinds)
+}
+
+// printKinds writes each kind with its description and then its settings,
+// one per line.
+func printKinds(w io.Writer, kinds []domain.Kind) error {
+	tw := newTable(w)
+	for i, k := range kinds {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", colors.id(k.Name), k.Description)
+		for _, name := range k.SettingNames() {
+			fmt.Fprintf(tw, "  %s:\t%s\n", name, k.Settings[name])
+		}
+	}
+	return tw.Flush()
+}
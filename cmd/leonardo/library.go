@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+	"leonardo-cli/internal/storage"
+)
+
+// printLibraryUsage prints the library subcommands.
+func printLibraryUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo library <subcommand> [options]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  search [--tag <tag>] [--note <text>]  List the generations carrying a tag or with text in their note, newest first")
+	fmt.Fprintln(stderr, "  backfill [--dir DIR] [--dry-run]  Write the missing sidecars of images downloaded before sidecars existed")
+	fmt.Fprintln(stderr, "  gc [--dry-run]  Hard-link identical images kept by download --dedupe, forget deleted ones and report the space saved")
+}
+
+// runLibrary dispatches the library subcommands that only read the local
+// library and need no API token; library backfill is run with the API
+// commands.
+func runLibrary(lib *service.LibraryService, args []string) error {
+	if len(args) == 0 {
+		printLibraryUsage()
+		return fmt.Errorf("library subcommand is required")
+	}
+	switch sub, rest := args[0], args[1:]; sub {
+	case "search":
+		searchCmd := flag.NewFlagSet("library search", flag.ExitOnError)
+		tag := searchCmd.String("tag", "", "Tag to look for, ignoring case")
+		note := searchCmd.String("note", "", "Text to look for in the notes given with create --note, ignoring case")
+		parseFlags(searchCmd, rest)
+		if strings.TrimSpace(*tag) == "" && strings.TrimSpace(*note) == "" {
+			searchCmd.Usage()
+			return fmt.Errorf("--tag or --note is required")
+		}
+		entries, err := lib.Search(strings.TrimSpace(*tag), strings.TrimSpace(*note))
+		if err != nil {
+			return err
+		}
+		if outputFormat != nil {
+			for _, e := range entries {
+				e.Prompt = redactor.Prompt(e.Prompt)
+				printFormatted(e)
+			}
+			return nil
+		}
+		return printLibraryEntries(os.Stdout, entries)
+	case "gc":
+		return runLibraryGC(service.NewDedupeService(storage.NewFileDownloadStore(downloadsPath())), rest)
+	default:
+		printLibraryUsage()
+		return fmt.Errorf("unknown library subcommand: %s", sub)
+	}
+}
+
+// printLibraryEntries writes library entries as a table.
+func printLibraryEntries(w io.Writer, entries []domain.LibraryEntry) error {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No matching generations.")
+		return nil
+	}
+	tw := newTable(w)
+	fmt.Fprintln(tw, "ID\tCREATED\tNAME\tTAGS\tNOTE\tPROMPT")
+	for _, e := range entries {
+		created := ""
+		if !e.CreatedAt.IsZero() {
+			created = e.CreatedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.GenerationID, created, e.Name, strings.Join(e.Tags, ","), e.Note, redactor.Prompt(e.Prompt))
+	}
+	return tw.Flush()
+}
+
+// runLibraryGC checks the downloads recorded by download --dedupe, linking
+// the identical files that do not share their data yet, and reports the
+// space the links save.
+func runLibraryGC(dedupe *service.DedupeService, args []string) error {
+	gcCmd := flag.NewFlagSet("library gc", flag.ExitOnError)
+	dryRun := gcCmd.Bool("dry-run", false, "Report what would be linked and forgotten without changing anything")
+	parseFlags(gcCmd, args)
+	report, err := dedupe.GC(*dryRun)
+	if err != nil {
+		return err
+	}
+	if printFormatted(report) {
+		return nil
+	}
+	printDedupeReport(os.Stdout, report, *dryRun)
+	return nil
+}
+
+// printDedupeReport writes the outcome of library gc.
+func printDedupeReport(w io.Writer, report domain.DedupeReport, dryRun bool) {
+	fmt.Fprint(w, tr.Sprintf("%d downloaded files, %d distinct images\n", report.Files, report.Distinct))
+	if dryRun {
+		fmt.Fprint(w, tr.Sprintf("Would link %d copies; would forget %d deleted files\n", report.Linked, report.Forgotten))
+		fmt.Fprint(w, tr.Sprintf("Space the links would save: %s\n", formatBytes(report.SavedBytes)))
+		return
+	}
+	fmt.Fprint(w, tr.Sprintf("Linked %d copies; forgot %d deleted files\n", report.Linked, report.Forgotten))
+	fmt.Fprint(w, tr.Sprintf("Space saved by links: %s\n", formatBytes(report.SavedBytes)))
+}
+
+// runLibraryBackfill writes the sidecars missing for the images in a
+// directory, matching each file to its generation and fetching the
+// generation's record.
+func runLibraryBackfill(svc *service.GenerationService, backfill *service.BackfillService, args []string) error {
+	backfillCmd := flag.NewFlagSet("library backfill", flag.ExitOnError)
+	dir := backfillCmd.String("dir", ".", "Directory of downloaded images to scan")
+	dryRun := backfillCmd.Bool("dry-run", false, "Report the matches without fetching metadata or writing sidecars")
+	scan := backfillCmd.Int("scan", service.DefaultBackfillScan, "Recent generations to search for file names not matched otherwise; 0 skips the search")
+	parseFlags(backfillCmd, args)
+	entries, err := os.ReadDir(*dir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && domain.IsBackfillImage(e.Name()) {
+			files = append(files, e.Name())
+		}
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(messages(), "No images found in", *dir)
+		return nil
+	}
+	userID := ""
+	if *scan > 0 {
+		info, err := svc.UserInfo()
+		if err != nil {
+			return err
+		}
+		userID = info.UserID
+	}
+	matches, err := backfill.Match(files, userID, *scan)
+	if err != nil {
+		return err
+	}
+	// Sidecars are per generation, so the files of one are grouped.
+	var ids []string
+	filesOf := map[string][]string{}
+	for _, m := range matches {
+		formatted := printFormatted(m)
+		if m.GenerationID == "" {
+			if !formatted {
+				fmt.Printf("%s: no matching generation\n", m.File)
+			}
+			continue
+		}
+		if filesOf[m.GenerationID] == nil {
+			ids = append(ids, m.GenerationID)
+		}
+		filesOf[m.GenerationID] = append(filesOf[m.GenerationID], m.File)
+	}
+	written, existing, failed := 0, 0, 0
+	for _, id := range ids {
+		path := filepath.Join(*dir, id+".json")
+		if _, err := os.Stat(path); err == nil {
+			existing++
+			continue
+		}
+		if *dryRun {
+			fmt.Fprintf(messages(), "Would write %s for %s\n", path, strings.Join(filesOf[id], ", "))
+			continue
+		}
+		detail, err := svc.Show(id)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: fetching generation %s: %v\n", id, err)
+			failed++
+			continue
+		}
+		extra := map[string]interface{}{"backfilled": true, "files": filesOf[id]}
+		if _, err := writeSidecarFile(*dir, domain.BackfillRequest(detail), id, detail.CreatedAt, extra); err != nil {
+			return err
+		}
+		fmt.Fprintf(messages(), "Wrote %s for %s\n", path, strings.Join(filesOf[id], ", "))
+		written++
+	}
+	fmt.Fprintf(messages(), "Sidecars written: %d, already present: %d, failed: %d\n", written, existing, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d generations could not be fetched", failed)
+	}
+	return nil
+}
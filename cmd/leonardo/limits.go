@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// showLimits makes a cheap API call and prints the rate limit reported on
+// its response.  The limit is printed even when the call itself was
+// rejected, since that is when it matters most.
+func showLimits(svc *service.GenerationService) error {
+	_, err := svc.UserInfo()
+	limit, ok := stats.latestRateLimit()
+	switch {
+	case ok:
+		if !printFormatted(limit) {
+			printRateLimit(os.Stdout, limit, time.Now())
+		}
+	case err == nil:
+		fmt.Println("The API did not report a rate limit on its response.")
+	}
+	return err
+}
+
+// printRateLimit writes the remaining requests and when they reset.
+func printRateLimit(w io.Writer, limit domain.RateLimit, now time.Time) {
+	fmt.Fprintf(w, "Requests left: %d of %d\n", limit.Remaining, limit.Limit)
+	if !limit.Reset.IsZero() {
+		wait := limit.Reset.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		fmt.Fprintf(w, "Resets: in %s (%s)\n", wait.Round(time.Second), limit.Reset.Local().Format(time.RFC3339))
+	}
+	if limit.Low() {
+		fmt.Fprintln(w, "Nearly exhausted: space out batch runs, lower list --all --concurrency or wait for the reset.")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// webhookSignatureHeader carries the signature of a delivery, "sha256="
+// followed by the hex HMAC of the body, when a secret is in use.
+const webhookSignatureHeader = "X-Leonardo-Signature"
+
+// webhookSimulatedHeader marks the deliveries made by listen --simulate, so
+// a receiver can tell them from real ones.
+const webhookSimulatedHeader = "X-Leonardo-Simulated"
+
+// maxWebhookBody bounds the size of a received delivery.
+const maxWebhookBody = 10 << 20
+
+// webhookRelay passes deliveries on: each body as one JSON line on out, its
+// prompts redacted with --redact-prompts, and, when forward is set, POSTed
+// there as it is, signed with secret.  Received deliveries are tracked on
+// board, when set, as jobs that fail when rejected or not forwarded.  It
+// is safe for concurrent use.
+type webhookRelay struct {
+	mu      sync.Mutex
+	out     io.Writer
+	forward string
+	secret  string
+	client  *http.Client
+	board   *statusBoard
+}
+
+// deliver relays body, marked as simulated when it was fabricated.
+func (r *webhookRelay) deliver(ctx context.Context, body []byte, simulated bool) error {
+	var line bytes.Buffer
+	if err := json.Compact(&line, body); err != nil {
+		return fmt.Errorf("delivery is not JSON: %w", err)
+	}
+	r.mu.Lock()
+	fmt.Fprintln(r.out, string(redactJSONPrompts(line.Bytes())))
+	r.mu.Unlock()
+	if r.forward == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.forward, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.secret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+domain.WebhookSignature(r.secret, body))
+	}
+	if simulated {
+		req.Header.Set(webhookSimulatedHeader, "true")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("forwarding to %s: %w", r.forward, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookBody))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("forwarding to %s: receiver answered %s", r.forward, resp.Status)
+	}
+	return nil
+}
+
+// ServeHTTP accepts a POSTed delivery, checking its signature when a
+// secret is set, and relays it.  A delivery that cannot be forwarded is
+// answered with 502 so the sender retries it.
+func (r *webhookRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a webhook delivery", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if r.board != nil {
+		job := "delivery from " + req.RemoteAddr
+		r.board.begin(job, 0)
+		defer func() { r.board.end(job, 0, 0, err) }()
+	}
+	if r.secret != "" {
+		if err = domain.VerifyWebhookSignature(r.secret, body, req.Header.Get(webhookSignatureHeader)); err != nil {
+			fmt.Fprint(stderr, tr.Sprintf("Warning: rejected a delivery from %s: %v\n", req.RemoteAddr, err))
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	if err = r.deliver(req.Context(), body, req.Header.Get(webhookSimulatedHeader) != ""); err != nil {
+		fmt.Fprint(stderr, tr.Sprintf("Warning: could not relay a delivery: %v\n", err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runListen receives webhook deliveries on --addr until interrupted, or
+// with --simulate fabricates the delivery of each generation named by --id
+// or --last from its record in the API, and relays them.  While listening,
+// --status-socket and --metrics-addr report the deliveries the way they do
+// for watch-folder.
+func runListen(svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	listenCmd := flag.NewFlagSet("listen", flag.ExitOnError)
+	addr := listenCmd.String("addr", "127.0.0.1:8787", "Address to receive webhook deliveries on")
+	secret := listenCmd.String("secret", "", "Webhook secret: received deliveries must be signed with it and forwarded ones are (can be set with LEONARDO_SECRET)")
+	forward := listenCmd.String("forward", "", "URL to POST each delivery to, e.g. the endpoint of your automation")
+	simulate := listenCmd.Bool("simulate", false, "Fabricate deliveries for --id or --last from API data instead of listening")
+	id := listenCmd.String("id", "", "With --simulate, the generation ID, ID prefix or name to deliver")
+	var last lastFlag
+	listenCmd.Var(&last, "last", "With --simulate, deliver the N most recently created generations recorded locally (default 1)")
+	statusSocket := listenCmd.String("status-socket", "", "Serve the deliveries in flight, delivered and failed counts and recent errors as JSON on this unix socket, for supervisors")
+	metricsAddr := listenCmd.String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address, e.g. localhost:9464")
+	parseWithLast(listenCmd, args, &last)
+	if *secret != "" {
+		registerSecret(*secret)
+	}
+	relay := &webhookRelay{out: os.Stdout, forward: *forward, secret: *secret, client: &http.Client{Timeout: 30 * time.Second}}
+	if *simulate {
+		return simulateDeliveries(relay, svc, targetGenerations(listenCmd, svc, lib, *id, last), time.Now())
+	}
+	if *id != "" || last > 0 {
+		listenCmd.Usage()
+		return errors.New("--id and --last only apply with --simulate")
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	relay.board = newStatusBoard("listen")
+	if *statusSocket != "" {
+		stop, err := serveStatusSocket(*statusSocket, relay.board)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		defer stop()
+	}
+	if *metricsAddr != "" {
+		stop, err := serveMetrics(*metricsAddr, relay.board)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		defer stop()
+	}
+	fmt.Fprint(stderr, tr.Sprintf("Listening for webhook deliveries on http://%s\n", listener.Addr()))
+	server := &http.Server{Handler: relay, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-runCtx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// simulateDeliveries relays a fabricated delivery for each generation in
+// ids, stamped with now.
+func simulateDeliveries(relay *webhookRelay, svc *service.GenerationService, ids []string, now time.Time) error {
+	for _, genID := range ids {
+		detail, err := svc.Show(genID)
+		if err != nil {
+			return err
+		}
+		event, err := domain.SimulatedWebhookEvent(detail, now)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := relay.deliver(runCtx, body, true); err != nil {
+			return err
+		}
+		if relay.forward != "" {
+			fmt.Fprint(stderr, tr.Sprintf("Delivered a simulated %s for %s to %s\n", event.Type, genID, relay.forward))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// apiAccessURL is the page of the Leonardo.Ai web app where API keys are
+// created.
+const apiAccessURL = "https://app.leonardo.ai/api-access"
+
+// loginSteps walks through creating an API key, since Leonardo.Ai issues
+// keys from its web app only.
+var loginSteps = []string{
+	"Open " + apiAccessURL + " on any device and sign in.",
+	"Choose Create New Key, name it after this machine and copy the key.",
+	"Paste the key below.  It is checked against the API before it is stored.",
+}
+
+// runLogin guides the user through creating an API key, reads it from in,
+// verifies it with verify and stores it as the default account.  Leonardo.Ai
+// offers no OAuth or device-code sign-in for API keys, so --device explains
+// that and falls back to the same guided steps; nothing needs a browser on
+// this machine.
+func runLogin(accounts *service.AccountService, args []string, in io.Reader, interactive bool, verify func(token string) (domain.UserInfo, error)) error {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	name := fs.String("name", "default", "Name to store the account under")
+	device := fs.Bool("device", false, "Ask for a device-code sign-in (Leonardo.Ai has none; shows the guided steps)")
+	parseFlags(fs, args)
+	if *device {
+		fmt.Fprintln(stderr, tr.Sprintf("Leonardo.Ai does not offer OAuth or device-code sign-in for API keys; create a key in the web app instead."))
+	}
+	if interactive {
+		for i, step := range loginSteps {
+			fmt.Fprintf(stderr, "%d. %s\n", i+1, tr.Sprintf(step))
+		}
+		fmt.Fprint(stderr, tr.Sprintf("API key: "))
+	}
+	token, err := readToken(in)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("no API key given")
+	}
+	registerSecret(token)
+	info, err := verify(token)
+	if err != nil {
+		return fmt.Errorf("the API did not accept the key (%s): %w", domain.ClassifyTokenError(err), err)
+	}
+	if err := accounts.Add(*name, token); err != nil {
+		return err
+	}
+	if err := accounts.Use(*name); err != nil {
+		return err
+	}
+	user := strings.TrimSpace(info.Username)
+	if user == "" {
+		user = info.UserID
+	}
+	fmt.Print(tr.Sprintf("Logged in as %s; stored account %s as the default\n", user, *name))
+	return nil
+}
@@ -1,93 +1,651 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"leonardo-cli/internal/config"
 	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/imaging"
 	"leonardo-cli/internal/provider"
 	"leonardo-cli/internal/service"
+	"leonardo-cli/internal/storage"
 )
 
-// printUsage prints the top level usage instructions.
+// commands lists the built-in commands in the order usage shows them.  A
+// plugin never shadows a command listed here.
+var commands = []struct{ name, summary string }{
+	{"create", "Create a new image generation"},
+	{"status", "Check the status of an existing generation"},
+	{"show", "Show the complete record of a generation"},
+	{"delete", "Delete an existing generation"},
+	{"me", "Show account info and token balances"},
+	{"list", "List recent generations"},
+	{"models", "List available platform models"},
+	{"limits", "Show the API rate limit: requests left and when they reset"},
+	{"usage", "Report tokens consumed per day, e.g. usage remote --days 7"},
+	{"pricing", "Print the token cost of common sizes, Alchemy and image counts for a model"},
+	{"styles", "List preset styles usable with create --style"},
+	{"kinds", "List the content templates usable with create --kind"},
+	{"examples", "Print copy-paste command lines for common workflows, e.g. examples img2img"},
+	{"project", "Track which generations produced a project's asset files"},
+	{"download", "Download images for a completed generation"},
+	{"urls", "Print the CDN URLs of a generation's images, one per line, without downloading"},
+	{"variations", "Upscale, remove the background of or unzoom images in parallel"},
+	{"inspect", "Inspect a sidecar metadata JSON file; --verify checks its signature"},
+	{"keygen", "Create an ed25519 key pair for signing sidecars with create --sign-key"},
+	{"export", "Convert sidecar metadata to AUTOMATIC1111 or ComfyUI settings"},
+	{"import", "Read a prompt and settings from AUTOMATIC1111 PNG info or civitai JSON"},
+	{"compare", "Build a side-by-side image comparing two generations"},
+	{"sweep", "Generate a series varying an Element's weight with the prompt and seed fixed"},
+	{"benchmark", "Compare models on the same prompt and seed: time, cost and a contact sheet"},
+	{"share", "Write a read-only HTML and JSON bundle of a generation for people without an account"},
+	{"preview", "Show the images of a generation inline in the terminal"},
+	{"listen", "Receive webhook deliveries, or simulate them for a generation with --simulate"},
+	{"mask", "Draw an inpainting mask for a local image from shapes or its alpha channel"},
+	{"init-images", "Upload, list and delete reference images for generations"},
+	{"character", "Save a subject reference under a name for create --character"},
+	{"models3d", "Upload OBJ models for texture generation"},
+	{"watch-folder", "Restyle every new image in a directory with an image-to-image preset"},
+	{"service", "Install watch-folder as an always-on systemd or launchd service"},
+	{"batch", "Submit prompts from a CSV file and manage batch manifests"},
+	{"auth", "Check that the API token is valid"},
+	{"raw", "Send an arbitrary request to the API, e.g. raw GET /me"},
+	{"login", "Create an API key step by step, verify it and store it as the default account"},
+	{"setup", "Set up a key, default model, output directory and sidecars, written to .leonardo.yaml"},
+	{"config", "Export the configuration to a portable bundle or import one"},
+	{"account", "Manage stored API credentials for several accounts"},
+	{"library", "Search the local record of created generations"},
+	{"favorite", "Mark a generation as a favorite so cleanup keeps its files"},
+	{"cleanup", "Delete or archive old local images and sidecars"},
+	{"cleanup-remote", "Delete failed or stuck generations from the API history in bulk"},
+	{"history", "List previous invocations and rerun one with history rerun N"},
+	{"webhook", "Sign or verify recorded webhook payloads"},
+	{"doctor", "Check the token, network, configuration, directories and clock, printing a checklist"},
+	{"version", "Print the build and the API it targets; --check-api probes the API"},
+}
+
+// globalFlagUsage describes the global options in usage.
+var globalFlagUsage = []struct{ name, summary string }{
+	{"--no-color", "Disable colored output (also honours NO_COLOR)"},
+	{"--verbose", "Log every API call with its status, latency and request ID"},
+	{"--stats", "Print a summary of API calls and their latency when done"},
+	{"--account", "Use a stored account, or rotate between several: a,b (also LEONARDO_ACCOUNT)"},
+	{"--token-file", "Read the API token from this file, e.g. a mounted secret (also LEONARDO_API_TOKEN_FILE)"},
+	{"--no-config-file", "Ignore .leonardo.yaml; take settings from flags and LEONARDO_* variables only"},
+	{"--redact-prompts", "Replace prompts with a hash in output, sidecars and manifests (also LEONARDO_REDACT_PROMPTS)"},
+	{"--plain", "Linear output for screen readers: no colors, images or aligned tables (also LEONARDO_PLAIN)"},
+	{"--progress-json", "Emit progress events (submitted, polling, image-downloaded, done) as JSON lines on stderr"},
+	{"--format", "Print each result through a Go template, e.g. '{{.GenerationID}} {{.Status}}' or json (also LEONARDO_FORMAT)"},
+	{"--query", "Print only the field of the raw API response at a path, e.g. 'generations_by_pk.generated_images[0].id'"},
+	{"--timeout", "Stop the whole command after this long, e.g. 90s or 5m, exiting with 124 (also LEONARDO_TIMEOUT)"},
+	{"--stuck-after", "Warn about generations PENDING for longer than this, default 30m (also LEONARDO_STUCK_AFTER)"},
+}
+
+// printUsage prints the top level usage instructions in the user's
+// language.
 func printUsage() {
 	program := os.Args[0]
-	fmt.Fprintf(os.Stderr, "Usage: %s <command> [options]\n", program)
-	fmt.Fprintln(os.Stderr, "Commands:")
-	fmt.Fprintln(os.Stderr, "  create   Create a new image generation")
-	fmt.Fprintln(os.Stderr, "  status   Check the status of an existing generation")
-	fmt.Fprintln(os.Stderr, "  delete   Delete an existing generation")
-	fmt.Fprintln(os.Stderr, "  me       Show account info and token balances")
-	fmt.Fprintln(os.Stderr, "  list     List recent generations")
-	fmt.Fprintln(os.Stderr, "  models   List available platform models")
-	fmt.Fprintln(os.Stderr, "  download Download images for a completed generation")
-	fmt.Fprintln(os.Stderr, "  inspect  Inspect a sidecar metadata JSON file")
-	fmt.Fprintln(os.Stderr, "Use \"", program, " <command> -h\" for more information about a command.")
-}
-
-// ensureAPIKey retrieves the API key from the environment and returns it.
-func ensureAPIKey() (string, error) {
-	key := os.Getenv("LEONARDO_API_TOKEN")
-	if strings.TrimSpace(key) == "" {
-		return "", fmt.Errorf("environment variable LEONARDO_API_TOKEN is not set")
+	fmt.Fprint(stderr, tr.Sprintf("Usage: %s <command> [options]\n", program))
+	fmt.Fprintln(stderr, tr.Sprintf("Commands:"))
+	tw := newTable(stderr)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, tr.Sprintf(c.summary))
+	}
+	tw.Flush()
+	if plugins := findPlugins(); len(plugins) > 0 {
+		fmt.Fprintln(stderr, tr.Sprintf("Plugins (leonardo-<name> on PATH):"))
+		for _, name := range plugins {
+			fmt.Fprintln(stderr, " ", name)
+		}
+	}
+	fmt.Fprintln(stderr, tr.Sprintf("Global options:"))
+	for _, f := range globalFlagUsage {
+		fmt.Fprintf(tw, "  %s\t%s\n", f.name, tr.Sprintf(f.summary))
+	}
+	tw.Flush()
+	fmt.Fprintln(stderr, tr.Sprintf("Every flag can also be set with a LEONARDO_* environment variable named after it,"))
+	fmt.Fprintln(stderr, tr.Sprintf("e.g. LEONARDO_MODEL_ID for --model-id; flags given on the command line take precedence."))
+	fmt.Fprint(stderr, tr.Sprintf("Use \"%s <command> -h\" for more information about a command.\n", program))
+	fmt.Fprintln(stderr, tr.Sprintf("Messages follow LEONARDO_LANG or the system locale (LC_ALL, LC_MESSAGES, LANG)."))
+}
+
+// globalOptions holds the flags accepted by every command.
+type globalOptions struct {
+	noColor       bool
+	plain         bool
+	verbose       bool
+	stats         bool
+	account       string
+	tokenFile     string
+	noConfigFile  bool
+	format        string
+	query         string
+	timeout       string
+	stuckAfter    string
+	redactPrompts bool
+	progressJSON  bool
+}
+
+// extractGlobalFlags removes global flags from args wherever they appear,
+// before or after the command name, and returns them alongside the
+// remaining arguments.  Flags taking a value accept it as --flag=value or
+// as the next argument.  Like every flag, global flags default to their
+// LEONARDO_* environment variable.
+func extractGlobalFlags(args []string) (globalOptions, []string) {
+	var opts globalOptions
+	for name, target := range map[string]*bool{
+		"no-color":       &opts.noColor,
+		"plain":          &opts.plain,
+		"verbose":        &opts.verbose,
+		"stats":          &opts.stats,
+		"redact-prompts": &opts.redactPrompts,
+		"progress-json":  &opts.progressJSON,
+		"no-config-file": &opts.noConfigFile,
+	} {
+		if value, ok := globalFromEnv(name); ok {
+			*target = globalBool(value, true)
+		}
+	}
+	opts.account, _ = globalFromEnv("account")
+	opts.tokenFile = strings.TrimSpace(os.Getenv(tokenFileEnv))
+	opts.format, _ = globalFromEnv("format")
+	opts.query, _ = globalFromEnv("query")
+	opts.timeout, _ = globalFromEnv("timeout")
+	opts.stuckAfter, _ = globalFromEnv("stuck-after")
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch name {
+		case "no-color":
+			opts.noColor = globalBool(value, hasValue)
+		case "plain":
+			opts.plain = globalBool(value, hasValue)
+		case "verbose":
+			opts.verbose = globalBool(value, hasValue)
+		case "stats":
+			opts.stats = globalBool(value, hasValue)
+		case "redact-prompts":
+			opts.redactPrompts = globalBool(value, hasValue)
+		case "progress-json":
+			opts.progressJSON = globalBool(value, hasValue)
+		case "no-config-file":
+			opts.noConfigFile = globalBool(value, hasValue)
+		case "account", "token-file", "format", "query", "timeout", "stuck-after":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			switch name {
+			case "account":
+				opts.account = strings.TrimSpace(value)
+			case "token-file":
+				opts.tokenFile = strings.TrimSpace(value)
+			case "format":
+				opts.format = value
+			case "timeout":
+				opts.timeout = strings.TrimSpace(value)
+			case "stuck-after":
+				opts.stuckAfter = strings.TrimSpace(value)
+			default:
+				opts.query = value
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest
+}
+
+// globalBool interprets the value of a boolean global flag: a bare flag is
+// true, otherwise the value must parse as true.
+func globalBool(value string, hasValue bool) bool {
+	enabled, err := strconv.ParseBool(value)
+	return !hasValue || (err == nil && enabled)
+}
+
+// printStats is set by --stats to print the API call summary on exit.
+var printStats bool
+
+// exitMu makes sure only one goroutine ever runs exit.
+var exitMu sync.Mutex
+
+// exit terminates the program with code, printing the API call summary
+// first so --stats also reports on runs that fail part way.  With
+// --progress-json the done event is always the last line written.  An
+// interrupted run exits with exitInterrupted and lists its pending
+// generations; one stopped by --timeout exits with exitTimedOut.
+func exit(code int) {
+	exitMu.Lock()
+	if interrupted() {
+		code = exitInterrupted
+		reportUnfinished()
+	} else if timedOut() {
+		code = exitTimedOut
+		fmt.Fprintf(stderr, "Timed out after %s (--timeout)\n", runTimeout)
+		reportUnfinished()
+	}
+	recordHistory(code)
+	if printStats {
+		stats.summary(stderr)
+	}
+	if keyRotation != nil {
+		printKeyUsage(stderr, keyRotation.Usage())
+	}
+	if progress != nil {
+		progress.done(code)
+	}
+	os.Exit(code)
+}
+
+// tokenFileEnv names the file holding the API token, as Docker and
+// Kubernetes mount secrets, when --token-file is not given.
+const tokenFileEnv = "LEONARDO_API_TOKEN_FILE"
+
+// ensureAPIKey returns the API key for this run.  A stored account named by
+// --account (or LEONARDO_ACCOUNT) wins, the first one when several are
+// rotated; then the file named by --token-file (or LEONARDO_API_TOKEN_FILE),
+// then LEONARDO_API_TOKEN, falling back to the default stored account.
+func ensureAPIKey(accounts *service.AccountService, opts globalOptions) (string, error) {
+	if names := domain.ParseAccountList(opts.account); len(names) > 0 {
+		key, _, err := accounts.Token(names[0])
+		return key, err
+	}
+	if opts.tokenFile != "" {
+		return readTokenFile(opts.tokenFile)
+	}
+	if key := os.Getenv("LEONARDO_API_TOKEN"); strings.TrimSpace(key) != "" {
+		return key, nil
+	}
+	key, ok, err := accounts.Token("")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New(tr.Sprintf("environment variable LEONARDO_API_TOKEN is not set (or use --token-file, or add an account with 'leonardo account add')"))
 	}
 	return key, nil
 }
 
-// defaultPrivateFromEnv returns whether image generations should default to private.
-func defaultPrivateFromEnv() bool {
-	privateValue := strings.TrimSpace(os.Getenv("LEONARDO_PRIVATE"))
-	if privateValue == "" {
-		return false
+// tokenService returns a service calling the API with token, for commands
+// that check a key before it is stored.
+func tokenService(token string) *service.GenerationService {
+	client := provider.NewAPIClient(token, nil)
+	client.SetObserver(stats.record)
+	client.SetContext(runCtx)
+	return service.NewGenerationService(client)
+}
+
+// readTokenFile reads an API token from the first line of the file at path,
+// ignoring surrounding whitespace such as the trailing newline of a secret.
+func readTokenFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("reading token file: %w", err)
 	}
-	private, err := strconv.ParseBool(privateValue)
+	defer f.Close()
+	token, err := readToken(f)
 	if err != nil {
-		return false
+		return "", err
+	}
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
 	}
-	return private
+	return token, nil
 }
 
-// defaultModelIDFromEnv returns the default model ID from the environment.
-func defaultModelIDFromEnv() string {
-	return strings.TrimSpace(os.Getenv("LEONARDO_MODEL_ID"))
+// tokenSource describes where ensureAPIKey takes the token from.
+func tokenSource(opts globalOptions) string {
+	switch {
+	case opts.account != "":
+		return "account " + opts.account
+	case opts.tokenFile != "":
+		return "token file " + opts.tokenFile
+	case strings.TrimSpace(os.Getenv("LEONARDO_API_TOKEN")) != "":
+		return "LEONARDO_API_TOKEN"
+	}
+	return "the default stored account"
+}
+
+// projectConfig is the .leonardo.yaml found for the working directory, if
+// any.  It is looked up once, on first use.
+var (
+	projectConfig       *config.FileSource
+	projectConfigLoaded bool
+)
+
+// loadProjectConfig finds and parses the project configuration file,
+// exiting when it exists but cannot be read.
+func loadProjectConfig() *config.FileSource {
+	if projectConfigLoaded {
+		return projectConfig
+	}
+	projectConfigLoaded = true
+	path, ok := config.FindProjectFile(".")
+	if !ok {
+		return nil
+	}
+	source, err := config.LoadFile(path)
+	if err != nil {
+		fail("Error", err)
+	}
+	if stats.verbose {
+		fmt.Fprintln(stderr, "Using project settings from", path)
+	}
+	projectConfig = source
+	return projectConfig
+}
+
+// configSources lists where flags missing from the command line are read
+// from, in order of precedence.
+func configSources() []config.Source {
+	sources := []config.Source{config.NewEnvSource()}
+	if project := loadProjectConfig(); project != nil {
+		sources = append(sources, project)
+	}
+	return sources
+}
+
+// applyConfig fills the flags of fs that were not given on the command line
+// from the configuration sources, exiting on invalid values.
+func applyConfig(fs *flag.FlagSet) {
+	if err := config.Apply(fs, configSources()...); err != nil {
+		fail("Error", err)
+	}
+}
+
+// parseFlags parses args into fs and applies the configuration sources.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	hintUnknownFlags(fs, args)
+	fs.Parse(args)
+	applyConfig(fs)
+}
+
+// globalFromEnv returns the value of the environment variable mapped to a
+// global flag.
+func globalFromEnv(name string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(config.EnvVar(name)))
+	return value, value != ""
+}
+
+// leonardoHome returns the directory holding local CLI state such as the
+// generation library.  LEONARDO_HOME overrides the per-user config directory.
+func leonardoHome() string {
+	if home := strings.TrimSpace(os.Getenv("LEONARDO_HOME")); home != "" {
+		return home
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".leonardo"
+	}
+	return filepath.Join(dir, "leonardo-cli")
+}
+
+// libraryPath returns the location of the local generation library file.
+func libraryPath() string {
+	return filepath.Join(leonardoHome(), "library.json")
+}
+
+// downloadsPath returns the location of the record of downloads kept by
+// download --dedupe.
+func downloadsPath() string {
+	return filepath.Join(leonardoHome(), "downloads.json")
+}
+
+// initImagesPath returns the location of the record of uploaded init images.
+func initImagesPath() string {
+	return filepath.Join(leonardoHome(), "init-images.json")
+}
+
+// charactersPath returns the location of the saved characters.
+func charactersPath() string {
+	return filepath.Join(leonardoHome(), "characters.json")
+}
+
+// modelsPath returns the location of the cached platform model list.
+func modelsPath() string {
+	return filepath.Join(leonardoHome(), "models.json")
+}
+
+// accountsPath returns the location of the stored account credentials.
+func accountsPath() string {
+	return filepath.Join(leonardoHome(), "accounts.json")
+}
+
+// lastFlag implements --last, which may be given bare (meaning 1) or with a
+// count as --last=N or --last N.
+type lastFlag int
+
+func (f *lastFlag) String() string { return strconv.Itoa(int(*f)) }
+
+// IsBoolFlag lets --last be used without a value.
+func (f *lastFlag) IsBoolFlag() bool { return true }
+
+func (f *lastFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*f = 1
+	case "false":
+		*f = 0
+	default:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("expected a positive count, got %q", value)
+		}
+		*f = lastFlag(n)
+	}
+	return nil
+}
+
+// parseWithLast parses args into fs, accepting a count given as a separate
+// argument right after a bare --last, and returns the names of the flags
+// given on the command line.  Those are recorded before the configuration
+// sources are applied, since Apply marks the flags it sets as visited too.
+func parseWithLast(fs *flag.FlagSet, args []string, last *lastFlag) map[string]bool {
+	hintUnknownFlags(fs, args)
+	fs.Parse(args)
+	if *last > 0 && fs.NArg() > 0 {
+		if n, err := strconv.Atoi(fs.Arg(0)); err == nil && n >= 1 {
+			*last = lastFlag(n)
+			fs.Parse(fs.Args()[1:])
+		}
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	applyConfig(fs)
+	return given
+}
+
+// targetGenerations returns the generation IDs a command should act on,
+// taken either from --id (a name, ID or ID prefix) or from --last.  It exits
+// with usage information when neither or both are given.
+func targetGenerations(fs *flag.FlagSet, svc *service.GenerationService, lib *service.LibraryService, ref string, last lastFlag) []string {
+	hasRef := strings.TrimSpace(ref) != ""
+	if hasRef == (last > 0) {
+		reportError("Error", errors.New("exactly one of --id or --last is required"))
+		fs.Usage()
+		exit(1)
+	}
+	if hasRef {
+		return []string{resolveGenerationRef(svc, lib, ref)}
+	}
+	ids, err := lib.Last(int(last))
+	if err != nil {
+		fail("Error resolving generation", err)
+	}
+	return ids
 }
 
 // createGeneration wraps the service call to create a generation and outputs
 // relevant information to the user.  It accepts a GenerationService and a
-// GenerationRequest built from CLI flags.
-func createGeneration(svc *service.GenerationService, req domain.GenerationRequest) error {
+// GenerationRequest built from CLI flags.  The new generation is recorded in
+// the local library so it can later be referred to by name.  It returns the
+// new generation's ID.
+func createGeneration(svc *service.GenerationService, lib *service.LibraryService, req domain.GenerationRequest) (generationOutput, error) {
+	if err := lib.CheckName(req.Metadata.Name); err != nil {
+		return generationOutput{}, err
+	}
 	res, err := svc.Create(req)
 	if err != nil {
-		return err
+		return generationOutput{}, err
 	}
 	sidecarPath, err := writeSidecarMetadata(req, res.GenerationID)
 	if err != nil {
-		return err
+		return generationOutput{}, err
+	}
+	created := generationOutput{
+		GenerationID: res.GenerationID,
+		Name:         req.Metadata.Name,
+		Prompt:       redactor.Prompt(req.Metadata.Prompt),
+		ModelID:      req.Metadata.ModelID,
+		SidecarPath:  sidecarPath,
+		Note:         req.Metadata.Note,
+	}
+	if req.Metadata.HasStyleUUID() {
+		created.Style = styleName(req.Metadata.StyleUUID)
 	}
 	if strings.TrimSpace(res.GenerationID) != "" {
-		fmt.Println("Generation ID:", res.GenerationID)
+		noteGenerationID(res.GenerationID)
 	}
-	fmt.Println("Sidecar metadata:", sidecarPath)
-	prettyPrintJSON(res.Raw)
-	return nil
+	if outputFormat == nil && outputQuery == "" {
+		if strings.TrimSpace(res.GenerationID) != "" {
+			fmt.Println(tr.Sprintf("Generation ID:"), colors.id(res.GenerationID))
+		}
+		if req.Metadata.HasName() {
+			fmt.Println(tr.Sprintf("Name:"), req.Metadata.Name)
+		}
+		if created.Style != "" {
+			fmt.Println(tr.Sprintf("Style:"), created.Style)
+		}
+		if created.Note != "" {
+			fmt.Println(tr.Sprintf("Note:"), created.Note)
+		}
+		if sidecarPath != "" {
+			fmt.Println(tr.Sprintf("Sidecar metadata:"), sidecarPath)
+		}
+	}
+	entry := domain.LibraryEntry{
+		GenerationID: res.GenerationID,
+		Name:         req.Metadata.Name,
+		Prompt:       req.Metadata.Prompt,
+		ModelID:      req.Metadata.ModelID,
+		CreatedAt:    time.Now().UTC(),
+		SidecarPath:  sidecarPath,
+		Tags:         req.Metadata.Tags,
+		Width:        req.Metadata.Width,
+		Height:       req.Metadata.Height,
+		Cost:         res.Cost,
+		Note:         req.Metadata.Note,
+	}
+	if err := lib.Record(entry); err != nil {
+		fmt.Fprintln(stderr, "Warning: could not record generation in library:", err)
+	}
+	if !printQueried(res.Raw) && outputFormat == nil {
+		prettyPrintJSON(res.Raw)
+	}
+	return created, nil
+}
+
+// autoUpscale waits for a new generation, upscales every image and reports
+// where the upscaled files were saved.
+func autoUpscale(svc *service.GenerationService, id, outputDir string, interval, timeout time.Duration) ([]string, error) {
+	fmt.Fprintln(messages(), "Upscaling every image...")
+	result, err := svc.AutoUpscale(id, outputDir, interval, timeout)
+	for i, fp := range result.FilePaths {
+		fmt.Fprintf(messages(), "Upscaled image %d saved: %s\n", i+1, fp)
+	}
+	return result.FilePaths, err
+}
+
+// awaitGeneration waits for a generation submitted at submitted to finish,
+// telling the user how long generations like it usually take.  The time it
+// took is recorded in the library to improve later estimates.
+func awaitGeneration(svc *service.GenerationService, lib *service.LibraryService, models *service.ModelService, id string, meta domain.GenerationMetadata, submitted time.Time, interval, timeout time.Duration) (domain.GenerationStatus, error) {
+	if estimate, ok := lib.EstimateWait(meta.ModelID, meta.Width, meta.Height); ok {
+		fmt.Fprintf(messages(), "Waiting for generation to complete (%s)...\n", estimate.Describe(modelLabel(models, meta.ModelID)))
+	} else {
+		fmt.Fprintln(messages(), "Waiting for generation to complete...")
+	}
+	status, err := svc.AwaitCompletion(id, interval, timeout)
+	if err != nil {
+		return status, err
+	}
+	fmt.Fprintln(messages(), "Status:", colors.status(status.Status))
+	if status.Status != "COMPLETE" {
+		return status, fmt.Errorf("generation %s finished with status %s", id, status.Status)
+	}
+	took := time.Since(submitted)
+	fmt.Fprintln(messages(), "Completed in", took.Round(time.Second))
+	if err := lib.RecordDuration(id, took); err != nil {
+		fmt.Fprintln(stderr, "Warning: could not record completion time in library:", err)
+	}
+	return status, nil
+}
+
+// modelLabel returns the name of a model from the cached model list, or
+// its ID when the name is not known.
+func modelLabel(models *service.ModelService, id string) string {
+	if id == "" || models == nil {
+		return id
+	}
+	catalog, err := models.Catalog(false)
+	if err != nil {
+		return id
+	}
+	if model, ok := catalog.Find(id); ok && model.Name != "" {
+		return model.Name
+	}
+	return id
+}
+
+// resolveGenerationRef resolves a generation name, ID or ID prefix given on
+// the command line into a generation ID.  The local library is consulted
+// first; prefixes it does not know are matched against recent generations.
+func resolveGenerationRef(svc *service.GenerationService, lib *service.LibraryService, ref string) string {
+	id, err := lib.Resolve(ref)
+	if err == nil && !domain.IsFullGenerationID(id) {
+		id, err = svc.ResolveRecent(id)
+	}
+	if err != nil {
+		fail("Error resolving generation", err)
+	}
+	return id
 }
 
 // checkGenerationStatus wraps the service call to obtain the status of a
 // generation and outputs relevant information to the user.
-func checkGenerationStatus(svc *service.GenerationService, id string) error {
+func checkGenerationStatus(svc *service.GenerationService, id, timestamps string) error {
 	status, err := svc.Status(id)
 	if err != nil {
 		return err
 	}
+	if pending, stuck := domain.StuckFor(status.Status, status.CreatedAt, time.Now(), stuckAfter); stuck {
+		warnStuck(id, pending)
+	}
+	if printFormatted(generationOutput{GenerationID: id, Status: status.Status, CreatedAt: status.CreatedAt, Images: status.Images}) || printQueried(status.Raw) {
+		return nil
+	}
 	if strings.TrimSpace(status.Status) != "" {
-		fmt.Println("Status:", status.Status)
+		fmt.Println("Status:", colors.status(status.Status))
+	}
+	if created := formatTimestamp(status.CreatedAt, timestamps, time.Now()); created != "" {
+		fmt.Println("Created:", created)
 	}
 	for i, url := range status.Images {
 		fmt.Printf("Image %d URL: %s\n", i+1, url)
@@ -96,15 +654,113 @@ func checkGenerationStatus(svc *service.GenerationService, id string) error {
 	return nil
 }
 
+// showGeneration wraps the service call to retrieve the complete record of a
+// generation and renders its parameters, elements and images.
+func showGeneration(svc *service.GenerationService, lib *service.LibraryService, id, timestamps string) error {
+	detail, err := svc.Show(id)
+	if err != nil {
+		return err
+	}
+	note, err := lib.Note(id)
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: could not read the note from the library:", err)
+	}
+	out := detailOutput(detail)
+	out.Note = note
+	if printFormatted(out) || printQueried(detail.Raw) {
+		return nil
+	}
+	printGenerationDetail(os.Stdout, detail, note, timestamps, time.Now())
+	return nil
+}
+
+// printGenerationDetail renders a generation record as aligned fields, with
+// the note recorded locally at create time.  Fields the API left empty are
+// omitted.
+func printGenerationDetail(w io.Writer, d domain.GenerationDetail, note, timestamps string, now time.Time) {
+	tw := newTable(w)
+	field := func(label, value string) {
+		if strings.TrimSpace(value) != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", label, value)
+		}
+	}
+	field("Generation", colors.id(d.ID))
+	field("Status", colors.status(d.Status))
+	field("Created", formatTimestamp(d.CreatedAt, timestamps, now))
+	field("Prompt", redactor.Prompt(d.Prompt))
+	field("Negative prompt", redactor.Prompt(d.NegativePrompt))
+	field("Note", note)
+	field("Model", d.ModelID)
+	if d.Width > 0 && d.Height > 0 {
+		field("Size", fmt.Sprintf("%dx%d", d.Width, d.Height))
+	}
+	if d.Seed > 0 {
+		field("Seed", strconv.Itoa(d.Seed))
+	}
+	field("Scheduler", d.Scheduler)
+	field("Preset style", d.PresetStyle)
+	field("SD version", d.SDVersion)
+	if d.InferenceSteps > 0 {
+		field("Inference steps", strconv.Itoa(d.InferenceSteps))
+	}
+	if d.GuidanceScale != 0 {
+		field("Guidance scale", strconv.FormatFloat(d.GuidanceScale, 'g', -1, 64))
+	}
+	field("Init image", d.InitImageID)
+	if d.InitStrength != 0 {
+		field("Init strength", strconv.FormatFloat(d.InitStrength, 'g', -1, 64))
+	}
+	var options []string
+	for _, opt := range []struct {
+		on   bool
+		name string
+	}{{d.Public, "public"}, {d.PhotoReal, "photoReal"}, {d.Alchemy, "alchemy"}, {d.Ultra, "ultra"}, {d.PromptMagic, "promptMagic"}} {
+		if opt.on {
+			options = append(options, opt.name)
+		}
+	}
+	field("Options", strings.Join(options, ", "))
+	tw.Flush()
+	if len(d.Elements) > 0 {
+		fmt.Fprintln(w, "Elements:")
+		for _, e := range d.Elements {
+			fmt.Fprintf(w, "  - %s (%s) weight %s\n", e.Name, e.ID, strconv.FormatFloat(e.Weight, 'g', -1, 64))
+		}
+	}
+	if len(d.Images) > 0 {
+		fmt.Fprintln(w, "Images:")
+		for i, img := range d.Images {
+			line := fmt.Sprintf("  %d. %s %s", i+1, colors.id(img.ID), img.URL)
+			if img.NSFW {
+				line += " [nsfw]"
+			}
+			if img.LikeCount > 0 {
+				line += fmt.Sprintf(" (%d likes)", img.LikeCount)
+			}
+			fmt.Fprintln(w, line)
+			for _, v := range img.Variations {
+				fmt.Fprintf(w, "     %s [%s] %s\n", v.TransformType, colors.status(v.Status), v.URL)
+			}
+		}
+	}
+}
+
 // deleteGeneration wraps the service call to delete a generation and outputs
-// the result to the user.
-func deleteGeneration(svc *service.GenerationService, id string) error {
+// the result to the user.  The generation is also dropped from the local
+// library.
+func deleteGeneration(svc *service.GenerationService, lib *service.LibraryService, id string) error {
 	resp, err := svc.Delete(id)
 	if err != nil {
 		return err
 	}
+	if err := lib.Forget(id); err != nil {
+		fmt.Fprintln(stderr, "Warning: could not remove generation from library:", err)
+	}
+	if printFormatted(generationOutput{GenerationID: id, Status: "DELETED"}) || printQueried(resp.Raw) {
+		return nil
+	}
 	if strings.TrimSpace(resp.ID) != "" {
-		fmt.Println("Deleted generation:", resp.ID)
+		fmt.Println("Deleted generation:", colors.id(resp.ID))
 	}
 	prettyPrintJSON(resp.Raw)
 	return nil
@@ -117,6 +773,9 @@ func showUserInfo(svc *service.GenerationService) error {
 	if err != nil {
 		return err
 	}
+	if printFormatted(info) || printQueried(info.Raw) {
+		return nil
+	}
 	if strings.TrimSpace(info.UserID) != "" {
 		fmt.Println("User ID:", info.UserID)
 	}
@@ -134,35 +793,245 @@ func showUserInfo(svc *service.GenerationService) error {
 
 // listGenerations wraps the service call to list user generations and outputs
 // a summary to the user.
-func listGenerations(svc *service.GenerationService, userID string, offset, limit int) error {
+func listGenerations(svc *service.GenerationService, userID string, offset, limit int, timestamps string) error {
 	resp, err := svc.ListGenerations(userID, offset, limit)
 	if err != nil {
 		return err
 	}
+	if printQueried(resp.Raw) {
+		return nil
+	}
+	now := time.Now()
 	for _, gen := range resp.Generations {
-		fmt.Printf("[%s] %s — %s", gen.Status, gen.ID, gen.Prompt)
-		if len(gen.Images) > 0 {
-			fmt.Printf(" (%d images)", len(gen.Images))
+		if !printFormatted(listItemOutput(gen)) {
+			printListItem(os.Stdout, gen, timestamps, now)
 		}
-		fmt.Println()
 	}
-	prettyPrintJSON(resp.Raw)
+	if outputFormat == nil {
+		prettyPrintJSON(resp.Raw)
+	}
 	return nil
 }
 
-// downloadImages wraps the service call to download all generated images for a
-// generation and outputs the saved file paths to the user.
-func downloadImages(svc *service.GenerationService, id, outputDir string) error {
-	result, err := svc.Download(id, outputDir)
+// listAllGenerations walks every page of a user's generations, printing each
+// one as soon as it arrives.  The raw JSON is not printed because it spans
+// many responses.
+func listAllGenerations(svc *service.GenerationService, userID string, concurrency int, timestamps string) error {
+	now := time.Now()
+	count := 0
+	err := svc.ListAllGenerations(userID, service.DefaultListPageSize, concurrency, func(gen domain.GenerationListItem) {
+		if !printFormatted(listItemOutput(gen)) {
+			printListItem(os.Stdout, gen, timestamps, now)
+		}
+		count++
+	})
 	if err != nil {
 		return err
 	}
-	for i, fp := range result.FilePaths {
-		fmt.Printf("Image %d saved: %s\n", i+1, fp)
+	fmt.Fprintf(messages(), "%d generations\n", count)
+	return nil
+}
+
+// printListItem writes the one-line summary of a generation used by list.
+func printListItem(w io.Writer, gen domain.GenerationListItem, timestamps string, now time.Time) {
+	fmt.Fprintf(w, "[%s] %s — %s", colors.status(gen.Status), colors.id(gen.ID), redactor.Prompt(gen.Prompt))
+	if len(gen.Images) > 0 {
+		fmt.Fprintf(w, " (%d images)", len(gen.Images))
+	}
+	if created := formatTimestamp(gen.CreatedAt, timestamps, now); created != "" {
+		fmt.Fprintf(w, " · %s", created)
+	}
+	fmt.Fprintln(w)
+}
+
+// streamConflicts are the download flags that only apply to saved files.
+var streamConflicts = []string{"include-variations", "dedupe", "resize", "max-dimension", "quality", "watermark", "watermark-image"}
+
+// checkStream validates download --stdout, which writes the bytes of a
+// single original image: one generation, no variations, copies or
+// deduplication of saved files, and stdout not a terminal, where the bytes
+// would only garble the screen.  given names the flags given on the
+// command line; those set by the environment or .leonardo.yaml for
+// downloads that save files are ignored.
+func checkStream(ids []string, given map[string]bool, terminal bool) error {
+	for _, name := range streamConflicts {
+		if given[name] {
+			return fmt.Errorf("--stdout writes the original image only and saves no file; it cannot be combined with --%s", name)
+		}
+	}
+	switch {
+	case len(ids) != 1:
+		return fmt.Errorf("--stdout writes a single image, but %d generations were selected", len(ids))
+	case outputFormat != nil:
+		return errors.New("--stdout cannot be combined with --format")
+	case terminal:
+		return errors.New("refusing to write image bytes to a terminal; pipe or redirect stdout")
 	}
 	return nil
 }
 
+// downloadImages wraps the service call to download all generated images for a
+// generation and outputs a summary of what was saved, skipped or failed.
+func downloadImages(svc *service.GenerationService, dedupe *service.DedupeService, id, outputDir string, includeVariations, thumbnails bool, derivatives derivativeOptions) error {
+	download := svc.Download
+	if includeVariations {
+		download = svc.DownloadWithVariations
+	}
+	result, err := download(id, outputDir)
+	if len(result.Outcomes) == 0 {
+		return err
+	}
+	files := append([]string(nil), result.FilePaths...)
+	for _, v := range result.Variations {
+		files = append(files, v.Path)
+	}
+	if dedupe != nil {
+		dedupeFiles(dedupe, files)
+	}
+	if thumbnails {
+		writeThumbnails(files)
+	}
+	var copies []string
+	if derivatives.enabled() {
+		for _, original := range files {
+			path, derr := makeDerivative(original, derivatives)
+			if derr != nil {
+				fmt.Fprintf(stderr, "Warning: could not make a %s of %s: %v\n", strings.ToLower(derivatives.label()), original, derr)
+				if err == nil {
+					err = fmt.Errorf("making %ss: %w", strings.ToLower(derivatives.label()), derr)
+				}
+				continue
+			}
+			copies = append(copies, path)
+		}
+	}
+	if !printFormatted(generationOutput{GenerationID: id, Files: append(files, copies...)}) {
+		printDownloadSummary(os.Stdout, result)
+		for _, path := range copies {
+			fmt.Println(derivatives.label()+" saved:", path)
+		}
+	}
+	if len(result.Variations) > 0 {
+		path, serr := recordVariations(id, outputDir, result.Variations)
+		if serr != nil {
+			fmt.Fprintln(stderr, "Warning: could not record variations in sidecar:", serr)
+		} else {
+			fmt.Fprintln(messages(), tr.Sprintf("Sidecar metadata:"), path)
+		}
+	}
+	return err
+}
+
+// printDownloadSummary renders the outcome of every file of a download as a
+// table, followed by the totals.
+func printDownloadSummary(w io.Writer, result domain.DownloadResult) {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "IMAGE\tFILE\tRESULT")
+	for _, o := range result.Outcomes {
+		image := strconv.Itoa(o.Image)
+		if o.Variation != "" {
+			image += " " + o.Variation
+		}
+		outcome := string(o.Outcome)
+		if o.Err != nil {
+			outcome += ": " + o.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", image, o.Path, outcome)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d saved, %d skipped, %d failed\n",
+		result.Count(domain.DownloadSaved), result.Count(domain.DownloadSkipped), result.Count(domain.DownloadFailed))
+}
+
+// recordVariations adds the downloaded variations to the generation's
+// sidecar.  The sidecar written by create in the current directory is
+// updated when present; otherwise one is written next to the images.
+func recordVariations(id, outputDir string, variations []domain.DownloadedVariation) (string, error) {
+	path := filepath.Join(".", id+".json")
+	sidecar := map[string]interface{}{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		path = filepath.Join(outputDir, id+".json")
+		data, err = os.ReadFile(path)
+	}
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		sidecar["generation_id"] = id
+	case err != nil:
+		return "", fmt.Errorf("reading sidecar metadata: %w", err)
+	default:
+		if err := json.Unmarshal(data, &sidecar); err != nil {
+			return "", fmt.Errorf("parsing sidecar metadata: %w", err)
+		}
+	}
+	records := make([]map[string]interface{}, 0, len(variations))
+	for _, v := range variations {
+		records = append(records, map[string]interface{}{
+			"image":          v.Image,
+			"id":             v.Variation.ID,
+			"transform_type": v.Variation.TransformType,
+			"status":         v.Variation.Status,
+			"file":           filepath.Base(v.Path),
+		})
+	}
+	sidecar["variations"] = records
+	data, err = json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding sidecar metadata: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("writing sidecar metadata: %w", err)
+	}
+	return path, nil
+}
+
+// defaultSize fills in the width and height meta omits with its model's
+// native resolution from the cached model list, saying what was chosen.
+func defaultSize(models *service.ModelService, meta domain.GenerationMetadata) domain.GenerationMetadata {
+	meta, model, filled := models.DefaultSize(meta)
+	if filled {
+		name := model.Name
+		if name == "" {
+			name = model.ID
+		}
+		fmt.Fprintf(messages(), "Size: %dx%d, the native resolution of %s (set --width and --height to choose another)\n", meta.Width, meta.Height, name)
+	}
+	return meta
+}
+
+// checkCapabilities validates meta against the capabilities of its model.
+// Only unsupported settings are fatal: when the model list cannot be fetched
+// the check is skipped with a warning and the API has the final say.
+func checkCapabilities(models *service.ModelService, meta domain.GenerationMetadata) error {
+	err := models.Validate(meta)
+	var invalid *domain.InvalidRequestError
+	if err != nil && !errors.As(err, &invalid) {
+		fmt.Fprintln(stderr, "Warning: skipping model capability check:", err)
+		return nil
+	}
+	return err
+}
+
+// checkPromptLength reports the approximate token count of a prompt and
+// warns when the model would ignore part of it.  With truncate set, the
+// prompt is cut to fit instead and the shortened prompt returned.
+func checkPromptLength(prompt string, limit int, truncate bool) string {
+	tokens := domain.EstimatePromptTokens(prompt)
+	if stats.verbose {
+		fmt.Fprintf(stderr, "Prompt is about %d tokens; the model reads %d\n", tokens, limit)
+	}
+	if tokens <= limit {
+		return prompt
+	}
+	if !truncate {
+		fmt.Fprintf(stderr, "Warning: prompt is about %d tokens but the model only reads the first %d; the rest is ignored (use --truncate-prompt to cut it)\n", tokens, limit)
+		return prompt
+	}
+	truncated := domain.TruncatePrompt(prompt, limit)
+	fmt.Fprintf(stderr, "Prompt truncated from about %d to %d tokens: %s\n", tokens, domain.EstimatePromptTokens(truncated), redactor.Prompt(truncated))
+	return truncated
+}
+
 // listPlatformModels wraps the service call to retrieve available platform
 // models and outputs a summary to the user.
 func listPlatformModels(svc *service.GenerationService) error {
@@ -170,27 +1039,49 @@ func listPlatformModels(svc *service.GenerationService) error {
 	if err != nil {
 		return err
 	}
+	if printQueried(resp.Raw) {
+		return nil
+	}
 	for _, model := range resp.Models {
-		fmt.Printf("[%s] %s", model.ID, model.Name)
+		if printFormatted(model) {
+			continue
+		}
+		fmt.Printf("[%s] %s", colors.id(model.ID), model.Name)
 		if model.Description != "" {
 			fmt.Printf(" — %s", model.Description)
 		}
 		fmt.Println()
 	}
-	prettyPrintJSON(resp.Raw)
+	if outputFormat == nil {
+		prettyPrintJSON(resp.Raw)
+	}
 	return nil
 }
 
+// skipSidecars stops writeSidecarMetadata from writing sidecars, set by
+// create --sidecar=false.
+var skipSidecars bool
+
 // writeSidecarMetadata writes a JSON metadata sidecar file named
-// {generationID}.json in the current directory.
+// {generationID}.json in the current directory.  With sidecars turned off
+// it writes nothing and returns an empty path.
 func writeSidecarMetadata(req domain.GenerationRequest, generationID string) (string, error) {
+	if skipSidecars {
+		return "", nil
+	}
+	return writeSidecarFile(".", req, generationID, time.Now(), nil)
+}
+
+// writeSidecarFile writes the sidecar of a generation created at created
+// as {generationID}.json in dir, with extra fields added.
+func writeSidecarFile(dir string, req domain.GenerationRequest, generationID string, created time.Time, extra map[string]interface{}) (string, error) {
 	if strings.TrimSpace(generationID) == "" {
 		return "", fmt.Errorf("generation ID is empty; cannot write sidecar metadata")
 	}
 	metadata := req.Metadata
-	timestamp := time.Now().UTC().Format(time.RFC3339)
+	timestamp := created.UTC().Format(time.RFC3339)
 	sidecar := map[string]interface{}{
-		"prompt":        metadata.Prompt,
+		"prompt":        redactor.Prompt(metadata.Prompt),
 		"num_images":    req.NumImages,
 		"generation_id": generationID,
 		"timestamp":     timestamp,
@@ -198,14 +1089,26 @@ func writeSidecarMetadata(req domain.GenerationRequest, generationID string) (st
 		"alchemy":       metadata.Alchemy,
 		"ultra":         metadata.Ultra,
 	}
+	if metadata.HasName() {
+		sidecar["name"] = metadata.Name
+	}
+	if metadata.Note != "" {
+		sidecar["note"] = metadata.Note
+	}
+	if metadata.HasPromptTemplate() {
+		sidecar["prompt_template"] = redactor.Prompt(metadata.PromptTemplate)
+	}
 	if metadata.HasNegativePrompt() {
-		sidecar["negative_prompt"] = metadata.NegativePrompt
+		sidecar["negative_prompt"] = redactor.Prompt(metadata.NegativePrompt)
 	}
 	if metadata.HasModelID() {
 		sidecar["model_id"] = metadata.ModelID
 	}
 	if metadata.HasStyleUUID() {
 		sidecar["style_uuid"] = metadata.StyleUUID
+		if name := styleName(metadata.StyleUUID); name != metadata.StyleUUID {
+			sidecar["style_name"] = name
+		}
 	}
 	if metadata.HasSeed() {
 		sidecar["seed"] = metadata.Seed
@@ -225,11 +1128,50 @@ func writeSidecarMetadata(req domain.GenerationRequest, generationID string) (st
 	if metadata.HasGuidanceScale() {
 		sidecar["guidance_scale"] = metadata.GuidanceScale
 	}
+	if metadata.HasHighResolution() {
+		sidecar["high_resolution"] = true
+	}
+	if metadata.HasContrastRatio() {
+		sidecar["contrast_ratio"] = metadata.ContrastRatio
+	}
+	if metadata.HasExpandedDomain() {
+		sidecar["expanded_domain"] = true
+	}
+	if metadata.HasHighContrast() {
+		sidecar["high_contrast"] = true
+	}
+	if metadata.HasPhotoReal() {
+		sidecar["photo_real"] = true
+	}
+	if metadata.HasInitImageID() {
+		sidecar["init_image_id"] = metadata.InitImageID
+	}
+	if metadata.HasInitStrength() {
+		sidecar["init_strength"] = metadata.InitStrength
+	}
+	if metadata.HasElements() {
+		elements := make([]map[string]interface{}, len(metadata.Elements))
+		for i, e := range metadata.Elements {
+			elements[i] = map[string]interface{}{"id": e.ID, "weight": e.Weight}
+		}
+		sidecar["elements"] = elements
+	}
+	if len(req.Params) > 0 {
+		sidecar["params"] = req.Params
+	}
+	for key, value := range extra {
+		sidecar[key] = value
+	}
 	data, err := json.MarshalIndent(sidecar, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("encoding sidecar metadata: %w", err)
 	}
-	path := filepath.Join(".", fmt.Sprintf("%s.json", generationID))
+	if sidecarSigner != nil {
+		if data, err = domain.SignSidecar(data, sidecarSigner); err != nil {
+			return "", fmt.Errorf("signing sidecar metadata: %w", err)
+		}
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s.json", generationID))
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return "", fmt.Errorf("writing sidecar metadata: %w", err)
 	}
@@ -268,6 +1210,7 @@ func parseTags(raw string) []string {
 
 // prettyPrintJSON takes a raw JSON byte slice and prints it indented.
 func prettyPrintJSON(data []byte) {
+	data = redactJSONPrompts(data)
 	var out bytes.Buffer
 	if err := json.Indent(&out, data, "", "  "); err != nil {
 		// If indentation fails, print raw data
@@ -278,48 +1221,367 @@ func prettyPrintJSON(data []byte) {
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	defer recoverPanic()
+	tr = loadTranslator(os.Getenv)
+	opts, args := extractGlobalFlags(os.Args[1:])
+	if len(args) < 1 {
 		printUsage()
-		os.Exit(1)
+		exit(1)
+	}
+	cmd, cmdArgs := args[0], args[1:]
+	if opts.noConfigFile {
+		// Settings come from flags and the environment only.
+		projectConfigLoaded = true
+	}
+	handleInterrupts()
+	startHistory(os.Args[1:], cmd)
+	plainOutput = opts.plain
+	colors = palette{enabled: colorEnabled(opts.noColor || opts.plain, isTerminal(os.Stdout))}
+	stats.verbose, stats.log = opts.verbose, stderr
+	redactor.Prompts = opts.redactPrompts
+	printStats = opts.stats
+	if opts.progressJSON {
+		progress = newProgressReporter(stderr)
+	}
+	if opts.format == formatJSON {
+		// JSON mode: every record as a JSON object, errors too.
+		opts.format, jsonErrors = "{{json .}}", true
+	}
+	if opts.format != "" {
+		tmpl, err := parseFormat(opts.format)
+		if err != nil {
+			fail("Error", err)
+		}
+		outputFormat = tmpl
+	}
+	if opts.query != "" {
+		if opts.format != "" {
+			reportError("Error", errors.New("use either --format or --query, not both"))
+			exit(1)
+		}
+		if _, err := parseQuery(opts.query); err != nil {
+			fail("Error", err)
+		}
+		outputQuery = opts.query
+	}
+	if opts.timeout != "" {
+		timeout, err := time.ParseDuration(opts.timeout)
+		if err != nil || timeout <= 0 {
+			reportError("Error", fmt.Errorf("invalid --timeout %q, want a positive duration such as 90s or 5m", opts.timeout))
+			exit(1)
+		}
+		limitRun(timeout)
+	}
+	if opts.stuckAfter != "" {
+		after, err := time.ParseDuration(opts.stuckAfter)
+		if err != nil || after <= 0 {
+			reportError("Error", fmt.Errorf("invalid --stuck-after %q, want a positive duration such as 30m or 2h", opts.stuckAfter))
+			exit(1)
+		}
+		stuckAfter = after
+	}
+	accounts := service.NewAccountService(storage.NewFileAccountStore(accountsPath()))
+	// Managing accounts does not need a token.
+	if cmd == "account" {
+		if err := runAccount(accounts, cmdArgs); err != nil {
+			fail("Error managing accounts", err)
+		}
+		exit(0)
+	}
+	if cmd == "login" {
+		err := runLogin(accounts, cmdArgs, os.Stdin, isTerminal(os.Stdin), func(token string) (domain.UserInfo, error) {
+			return tokenService(token).UserInfo()
+		})
+		if err != nil {
+			fail("Error logging in", err)
+		}
+		exit(0)
+	}
+	setup := setupEnv{
+		in:  bufio.NewReader(os.Stdin),
+		out: stderr,
+		token: func() (string, bool) {
+			key, err := ensureAPIKey(accounts, opts)
+			return key, err == nil
+		},
+		verify: func(token string) (domain.UserInfo, error) {
+			return tokenService(token).UserInfo()
+		},
+		models: func(token string) ([]domain.PlatformModel, error) {
+			resp, err := tokenService(token).ListPlatformModels()
+			return resp.Models, err
+		},
+	}
+	if cmd == "setup" {
+		if err := runSetup(accounts, cmdArgs, setup); err != nil {
+			fail("Error setting up", err)
+		}
+		exit(0)
+	}
+	if cmd == "config" {
+		if err := runConfig(accounts, cmdArgs); err != nil {
+			fail("Error managing configuration", err)
+		}
+		exit(0)
+	}
+	// Local housekeeping does not need a token either.
+	switch cmd {
+	case "styles":
+		if err := runStyles(cmdArgs); err != nil {
+			fail("Error listing styles", err)
+		}
+		exit(0)
+	case "kinds":
+		if err := runKinds(cmdArgs); err != nil {
+			fail("Error listing kinds", err)
+		}
+		exit(0)
+	case "examples":
+		if err := runExamples(cmdArgs); err != nil {
+			fail("Error listing examples", err)
+		}
+		exit(0)
+	case "project":
+		if err := runProject(cmdArgs); err != nil {
+			fail("Error", err)
+		}
+		exit(0)
+	case "favorite":
+		if err := runFavorite(service.NewLibraryService(storage.NewFileLibrary(libraryPath())), cmdArgs); err != nil {
+			fail("Error marking favorite", err)
+		}
+		exit(0)
+	case "cleanup":
+		if err := runCleanup(service.NewLibraryService(storage.NewFileLibrary(libraryPath())), cmdArgs); err != nil {
+			fail("Error cleaning up", err)
+		}
+		exit(0)
+	case "library":
+		// Only library backfill reads the API.
+		if len(cmdArgs) > 0 && cmdArgs[0] == "backfill" {
+			break
+		}
+		if err := runLibrary(service.NewLibraryService(storage.NewFileLibrary(libraryPath())), cmdArgs); err != nil {
+			fail("Error", err)
+		}
+		exit(0)
+	case "usage":
+		// Only usage remote reads the API.
+		if len(cmdArgs) > 0 && cmdArgs[0] == "budget" {
+			if err := runUsageBudget(service.NewLibraryService(storage.NewFileLibrary(libraryPath())), cmdArgs[1:]); err != nil {
+				fail("Error reporting budget", err)
+			}
+			exit(0)
+		}
+	case "history":
+		exit(runHistory(cmdArgs))
+	case "webhook":
+		if err := runWebhook(cmdArgs); err != nil {
+			fail("Error", err)
+		}
+		exit(0)
+	case "mask":
+		if err := runMask(cmdArgs); err != nil {
+			fail("Error creating mask", err)
+		}
+		exit(0)
+	case "export":
+		if err := runExport(cmdArgs); err != nil {
+			fail("Error exporting", err)
+		}
+		exit(0)
+	case "doctor":
+		exit(runDoctor(cmdArgs, doctorEnv{
+			token: func() (string, error) {
+				apiKey, err := ensureAPIKey(accounts, opts)
+				if err == nil {
+					registerSecret(apiKey)
+				}
+				return apiKey, err
+			},
+			tokenSource: tokenSource(opts),
+			probe: func(token string) (domain.RawResponse, error) {
+				client := provider.NewAPIClient(token, nil)
+				client.SetObserver(stats.record)
+				client.SetContext(runCtx)
+				return client.SendRaw(domain.RawRequest{Method: "GET", Path: "/me"})
+			},
+		}))
+	case "inspect":
+		inspectCmd := flag.NewFlagSet("inspect", flag.ExitOnError)
+		filePath := inspectCmd.String("file", "", "Path to a sidecar metadata JSON file (required)")
+		verify := inspectCmd.Bool("verify", false, "Check the sidecar's signature instead of printing it")
+		publicKey := inspectCmd.String("public-key", "", "With --verify, also require the signature to be by this ed25519 public key (PEM)")
+		parseFlags(inspectCmd, cmdArgs)
+		if strings.TrimSpace(*filePath) == "" {
+			reportError("Error", errors.New("--file is required"))
+			inspectCmd.Usage()
+			exit(1)
+		}
+		if *verify {
+			if err := verifySidecarFile(*filePath, *publicKey); err != nil {
+				fail("Error verifying sidecar", err)
+			}
+			exit(0)
+		}
+		if err := inspectSidecar(*filePath); err != nil {
+			fail("Error inspecting sidecar", err)
+		}
+		exit(0)
+	case "keygen":
+		if err := runKeygen(cmdArgs); err != nil {
+			fail("Error generating key", err)
+		}
+		exit(0)
+	case "service":
+		if err := runService(cmdArgs, opts.account); err != nil {
+			fail("Error managing service", err)
+		}
+		exit(0)
+	case "version":
+		// Only --check-api needs a token.
+		exit(runVersion(cmdArgs, func() ([]domain.EndpointCheck, error) {
+			apiKey, err := ensureAPIKey(accounts, opts)
+			if err != nil {
+				return nil, err
+			}
+			registerSecret(apiKey)
+			client := provider.NewAPIClient(apiKey, nil)
+			client.SetObserver(stats.record)
+			client.SetContext(runCtx)
+			return client.CheckCompatibility(), nil
+		}))
+	}
+	// Anything else may be a plugin: leonardo-<cmd> on PATH.
+	if path, ok := findPlugin(cmd); ok {
+		exit(runPlugin(cmd, path, cmdArgs, opts, accounts))
+	}
+	if !isBuiltin(cmd) && cmd != "--help" && cmd != "-h" {
+		// Say so before asking for a token the command would not use.
+		unknownCommand(cmd)
+		exit(1)
+	}
+	apiKey, err := ensureAPIKey(accounts, opts)
+	if err != nil && cmd != "auth" && firstRun(opts) && offerSetup(accounts, setup) {
+		apiKey, err = ensureAPIKey(accounts, opts)
 	}
-	cmd := os.Args[1]
-	apiKey, err := ensureAPIKey()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		reportError("", err)
+		if cmd == "auth" {
+			exit(tokenExitCode(domain.TokenMissing))
+		}
+		exit(1)
 	}
+	registerSecret(apiKey)
 	// Construct the adapter and service once at program start.
 	client := provider.NewAPIClient(apiKey, nil)
+	client.SetObserver(stats.record)
+	client.SetContext(runCtx)
 	svc := service.NewGenerationService(client)
+	if names := domain.ParseAccountList(opts.account); len(names) > 1 {
+		if keyRotation, err = newKeyRotation(accounts, names); err != nil {
+			fail("Error", err)
+		}
+		svc = service.NewGenerationService(keyRotation)
+	}
+	svc.SetContext(runCtx)
+	unfinished = svc.Unfinished
+	if progress != nil {
+		svc.SetProgress(progress.report)
+	}
+	svc.SetStuckAlert(stuckAfter, warnStuck)
+	lib := service.NewLibraryService(storage.NewFileLibrary(libraryPath()))
+	models := service.NewModelService(client, storage.NewFileModelCache(modelsPath()))
 	switch cmd {
 	case "create":
 		createCmd := flag.NewFlagSet("create", flag.ExitOnError)
 		prompt := createCmd.String("prompt", "", "Text prompt for image generation (required)")
+		name := createCmd.String("name", "", "Optional human-friendly name usable instead of the generation ID")
 		negativePrompt := createCmd.String("negative-prompt", "", "Negative prompt to avoid undesired traits")
-		modelId := createCmd.String("model-id", defaultModelIDFromEnv(), "Model ID to use for generation (can be set with LEONARDO_MODEL_ID)")
+		modelId := createCmd.String("model-id", "", "Model ID to use for generation (can be set with LEONARDO_MODEL_ID)")
 		width := createCmd.Int("width", 0, "Width of the generated image")
 		height := createCmd.Int("height", 0, "Height of the generated image")
 		numImages := createCmd.Int("num-images", 1, "Number of images to generate (1-8)")
 		seed := createCmd.Int("seed", 0, "Optional generation seed")
 		tags := createCmd.String("tags", "", "Optional comma-separated metadata tags")
-		private := createCmd.Bool("private", defaultPrivateFromEnv(), "Generate private images (can be set with LEONARDO_PRIVATE)")
+		note := createCmd.String("note", "", "Context to keep with the generation, e.g. what the client asked for; stored locally, never sent to the API")
+		private := createCmd.Bool("private", false, "Generate private images (can be set with LEONARDO_PRIVATE)")
 		alchemy := createCmd.Bool("alchemy", false, "Enable Alchemy for advanced generation")
 		ultra := createCmd.Bool("ultra", false, "Enable ultra mode for high fidelity generation")
 		styleUUID := createCmd.String("style-uuid", "", "Optional style UUID to influence generation")
+		style := createCmd.String("style", "", "Preset style by name, e.g. cinematic (see the styles command)")
+		createCmd.StringVar(style, "style-name", "", "Same as --style")
+		sidecar := createCmd.Bool("sidecar", true, "Write a JSON sidecar with the request to the current directory (sidecar: false in .leonardo.yaml turns it off)")
+		signKey := createCmd.String("sign-key", "", "Sign the sidecar with this ed25519 private key (PEM, see keygen)")
 		contrast := createCmd.Float64("contrast", 0.0, "Optional contrast adjustment (0-5)")
 		guidanceScale := createCmd.Float64("guidance-scale", 0.0, "Optional guidance scale, typically between 1 and 10")
-		// Parse flags
-		createCmd.Parse(os.Args[2:])
+		highResolution := createCmd.Bool("high-resolution", false, "Alchemy: add a high resolution pass (requires --alchemy)")
+		contrastRatio := createCmd.Float64("contrast-ratio", 0.0, "Alchemy: contrast ratio between 0 and 1 (requires --alchemy)")
+		expandedDomain := createCmd.Bool("expanded-domain", false, "Alchemy: enable expanded domain (requires --alchemy)")
+		highContrast := createCmd.Bool("high-contrast", false, "Alchemy: enable high contrast (requires --alchemy)")
+		photoReal := createCmd.Bool("photo-real", false, "Enable PhotoReal (requires --alchemy and a supporting model)")
+		initImageID := createCmd.String("init-image-id", "", "Start from an uploaded init image (see init-images upload)")
+		initStrength := createCmd.Float64("init-strength", 0.0, "How strongly the init image shapes the result (0.1-0.9)")
+		skipModelCheck := createCmd.Bool("skip-model-check", false, "Submit without checking the request against the model's capabilities")
+		wildcardsDir := createCmd.String("wildcards-dir", "", "Directory of wordlists for __wildcards__ in the prompt (default ./wildcards, then the state directory)")
+		truncatePrompt := createCmd.Bool("truncate-prompt", false, "Cut a prompt longer than the model reads at the last sentence that fits")
+		wait := createCmd.Bool("wait", false, "Wait for the generation to complete, showing how long similar generations usually take")
+		autoUpscaleImages := createCmd.Bool("auto-upscale", false, "Wait for the generation, upscale every image and download the upscaled results")
+		outputDir := createCmd.String("output-dir", ".", "Directory to save upscaled images with --auto-upscale")
+		pollInterval := createCmd.Duration("poll-interval", 5*time.Second, "Delay before the first progress check with --wait or --auto-upscale; later checks back off")
+		waitTimeout := createCmd.Duration("wait-timeout", 10*time.Minute, "Give up waiting for each job after this long with --wait or --auto-upscale")
+		character := createCmd.String("character", "", "Apply a saved subject reference: its description, init image, style, seed and Elements (see the character command)")
+		createCmd.String("kind", "", "Start from a content template: size, model, Alchemy, style and negative prompt (see the kinds command)")
+		params := paramsFlag{}
+		createCmd.Var(params, "param", "Extra request field as KEY=VALUE, repeatable, for API parameters without an option yet; JSON values such as 3 or true are sent as they are")
+		dryRun := createCmd.Bool("dry-run", false, "Print the JSON body that would be sent, with every template, recipe and default applied, without submitting it")
+		fromURL := createCmd.String("from-url", "", "Start from a recipe shared at a URL: a share bundle's generation.json, a sidecar or AUTOMATIC1111 parameters text")
+		// Parse flags; a recipe and then a kind fill in what the command
+		// line leaves out before the configured defaults do.
+		hintUnknownFlags(createCmd, cmdArgs)
+		createCmd.Parse(cmdArgs)
+		recipe, err := loadRecipe(createCmd, service.NewRecipeService(client), *fromURL)
+		if err != nil {
+			fail("Error", err)
+		}
+		if err := applyKind(createCmd); err != nil {
+			fail("Error", err)
+		}
+		applyConfig(createCmd)
 		if strings.TrimSpace(*prompt) == "" {
-			fmt.Fprintln(os.Stderr, "Error: --prompt is required")
+			reportError("Error", errors.New("--prompt is required"))
 			createCmd.Usage()
-			os.Exit(1)
+			exit(1)
+		}
+		skipSidecars = !*sidecar
+		if *signKey != "" {
+			if skipSidecars {
+				reportError("Error", errors.New("--sign-key signs the sidecar; it cannot be used with --sidecar=false"))
+				exit(1)
+			}
+			key, err := loadSigningKey(*signKey)
+			if err != nil {
+				fail("Error", err)
+			}
+			sidecarSigner = key
+		}
+		if *style != "" {
+			if *styleUUID != "" {
+				reportError("Error", errors.New("use either --style (or --style-name) or --style-uuid, not both"))
+				exit(1)
+			}
+			resolved, err := resolveStyle(*style)
+			if err != nil {
+				fail("Error", err)
+			}
+			*styleUUID = resolved
 		}
 		// Build a domain request object.
 		req := domain.GenerationRequest{
 			NumImages: *numImages,
 			Private:   *private,
 			Metadata: domain.GenerationMetadata{
+				Name:           strings.TrimSpace(*name),
 				Prompt:         *prompt,
 				NegativePrompt: *negativePrompt,
 				ModelID:        *modelId,
@@ -332,95 +1594,359 @@ func main() {
 				Ultra:          *ultra,
 				Contrast:       *contrast,
 				GuidanceScale:  *guidanceScale,
+				HighResolution: *highResolution,
+				ContrastRatio:  *contrastRatio,
+				ExpandedDomain: *expandedDomain,
+				HighContrast:   *highContrast,
+				PhotoReal:      *photoReal,
+				InitImageID:    *initImageID,
+				InitStrength:   *initStrength,
+				Note:           strings.TrimSpace(*note),
 			},
+			Params: requestParams(params),
+		}
+		if *character != "" {
+			c, err := service.NewCharacterService(storage.NewFileCharacterStore(charactersPath())).Get(*character)
+			if err != nil {
+				fail("Error", err)
+			}
+			req.Metadata = c.Apply(req.Metadata)
+		}
+		req.Metadata = withRecipeElements(req.Metadata, recipe)
+		if warning := styleWarning(req.Metadata.ModelID, req.Metadata.StyleUUID); warning != "" {
+			fmt.Fprintln(stderr, warning)
+		}
+		if err := req.Metadata.ValidateAlchemy(); err != nil {
+			reportError("Error", err)
+			createCmd.Usage()
+			exit(1)
+		}
+		if req.Metadata, err = newWildcardService(*wildcardsDir).Resolve(req.Metadata); err != nil {
+			fail("Error", err)
 		}
-		if err := createGeneration(svc, req); err != nil {
-			fmt.Fprintln(os.Stderr, "Error creating generation:", err)
-			os.Exit(1)
+		if req.Metadata.HasPromptTemplate() {
+			fmt.Fprintln(messages(), "Prompt:", redactor.Prompt(req.Metadata.Prompt))
 		}
+		if err := req.Metadata.ValidateInitImage(); err != nil {
+			fail("Error", err)
+		}
+		if !*skipModelCheck {
+			req.Metadata = defaultSize(models, req.Metadata)
+			if err := checkCapabilities(models, req.Metadata); err != nil {
+				fail("Error", err)
+			}
+		}
+		limit := domain.DefaultPromptTokenLimit
+		if !*skipModelCheck {
+			limit = models.PromptTokenLimit(req.Metadata.ModelID)
+		}
+		req.Metadata.Prompt = checkPromptLength(req.Metadata.Prompt, limit, *truncatePrompt)
+		if *dryRun {
+			payload, err := provider.GenerationPayload(req)
+			if err != nil {
+				fail("Error", err)
+			}
+			fmt.Fprintln(messages(), "Dry run: would POST", provider.APIBaseURL+"/generations")
+			prettyPrintJSON(payload)
+			break
+		}
+		submitted := time.Now()
+		created, err := createGeneration(svc, lib, req)
+		if err != nil {
+			fail("Error creating generation", err)
+		}
+		if *wait || *autoUpscaleImages {
+			status, err := awaitGeneration(svc, lib, models, created.GenerationID, req.Metadata, submitted, *pollInterval, *waitTimeout)
+			if err != nil {
+				fail("Error waiting for generation", err)
+			}
+			created.Status, created.CreatedAt, created.Images = status.Status, status.CreatedAt, status.Images
+		}
+		if *autoUpscaleImages {
+			if created.Files, err = autoUpscale(svc, created.GenerationID, *outputDir, *pollInterval, *waitTimeout); err != nil {
+				fail("Error upscaling generation", err)
+			}
+		}
+		printFormatted(created)
 	case "status":
 		statusCmd := flag.NewFlagSet("status", flag.ExitOnError)
-		id := statusCmd.String("id", "", "Generation ID to check (required)")
-		statusCmd.Parse(os.Args[2:])
-		if strings.TrimSpace(*id) == "" {
-			fmt.Fprintln(os.Stderr, "Error: --id is required")
-			statusCmd.Usage()
-			os.Exit(1)
+		id := statusCmd.String("id", "", "Generation ID, ID prefix or name to check")
+		var last lastFlag
+		statusCmd.Var(&last, "last", "Check the N most recently created generations recorded locally (default 1)")
+		timestamps := statusCmd.String("timestamps", timestampsRelative, "Timestamp format: relative or rfc3339")
+		parseWithLast(statusCmd, cmdArgs, &last)
+		if err := validateTimestampMode(*timestamps); err != nil {
+			fail("Error", err)
+		}
+		ids := targetGenerations(statusCmd, svc, lib, *id, last)
+		for _, genID := range ids {
+			if len(ids) > 1 && outputFormat == nil && outputQuery == "" {
+				fmt.Println(colors.bold("Generation: " + genID))
+			}
+			if err := checkGenerationStatus(svc, genID, *timestamps); err != nil {
+				fail("Error checking status", err)
+			}
+		}
+	case "show":
+		showCmd := flag.NewFlagSet("show", flag.ExitOnError)
+		id := showCmd.String("id", "", "Generation ID, ID prefix or name to show")
+		var last lastFlag
+		showCmd.Var(&last, "last", "Show the N most recently created generations recorded locally (default 1)")
+		timestamps := showCmd.String("timestamps", timestampsRelative, "Timestamp format: relative or rfc3339")
+		parseWithLast(showCmd, cmdArgs, &last)
+		if err := validateTimestampMode(*timestamps); err != nil {
+			fail("Error", err)
 		}
-		if err := checkGenerationStatus(svc, *id); err != nil {
-			fmt.Fprintln(os.Stderr, "Error checking status:", err)
-			os.Exit(1)
+		for i, genID := range targetGenerations(showCmd, svc, lib, *id, last) {
+			if i > 0 && outputFormat == nil && outputQuery == "" {
+				fmt.Println()
+			}
+			if err := showGeneration(svc, lib, genID, *timestamps); err != nil {
+				fail("Error showing generation", err)
+			}
 		}
 	case "delete":
 		deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
-		id := deleteCmd.String("id", "", "Generation ID to delete (required)")
-		deleteCmd.Parse(os.Args[2:])
-		if strings.TrimSpace(*id) == "" {
-			fmt.Fprintln(os.Stderr, "Error: --id is required")
-			deleteCmd.Usage()
-			os.Exit(1)
+		id := deleteCmd.String("id", "", "Generation ID, ID prefix or name to delete")
+		var last lastFlag
+		deleteCmd.Var(&last, "last", "Delete the N most recently created generations recorded locally (default 1)")
+		parseWithLast(deleteCmd, cmdArgs, &last)
+		for _, genID := range targetGenerations(deleteCmd, svc, lib, *id, last) {
+			if err := deleteGeneration(svc, lib, genID); err != nil {
+				fail("Error deleting generation", err)
+			}
 		}
-		if err := deleteGeneration(svc, *id); err != nil {
-			fmt.Fprintln(os.Stderr, "Error deleting generation:", err)
-			os.Exit(1)
+	case "cleanup-remote":
+		if err := runCleanupRemote(svc, lib, cmdArgs); err != nil {
+			fail("Error cleaning up generations", err)
 		}
 	case "me":
+		meCmd := flag.NewFlagSet("me", flag.ExitOnError)
+		allAccounts := meCmd.Bool("all-accounts", false, "Summarize token balances across all stored accounts")
+		parseFlags(meCmd, cmdArgs)
+		if *allAccounts {
+			if err := showAllAccounts(accounts); err != nil {
+				fail("Error getting user info", err)
+			}
+			break
+		}
 		if err := showUserInfo(svc); err != nil {
-			fmt.Fprintln(os.Stderr, "Error getting user info:", err)
-			os.Exit(1)
+			fail("Error getting user info", err)
 		}
 	case "list":
 		listCmd := flag.NewFlagSet("list", flag.ExitOnError)
 		userID := listCmd.String("user-id", "", "User ID to list generations for (required, use 'me' command to find your ID)")
 		offset := listCmd.Int("offset", 0, "Pagination offset")
 		limit := listCmd.Int("limit", 10, "Number of generations to return")
-		listCmd.Parse(os.Args[2:])
+		timestamps := listCmd.String("timestamps", timestampsRelative, "Timestamp format: relative or rfc3339")
+		all := listCmd.Bool("all", false, "List every generation, fetching pages concurrently (ignores --offset and --limit)")
+		concurrency := listCmd.Int("concurrency", service.DefaultListConcurrency, "Number of pages fetched at once with --all")
+		stuck := listCmd.Bool("stuck", false, "List only generations PENDING for longer than --stuck-after (default 30m), across every page")
+		resubmit := listCmd.Bool("resubmit", false, "With --stuck, submit each stuck generation again as a new one")
+		deleteStuck := listCmd.Bool("delete", false, "With --stuck, delete the stuck generations (after --resubmit, if given)")
+		yes := listCmd.Bool("yes", false, "With --resubmit or --delete, do not ask for confirmation")
+		parseFlags(listCmd, cmdArgs)
+		if err := validateTimestampMode(*timestamps); err != nil {
+			fail("Error", err)
+		}
 		if strings.TrimSpace(*userID) == "" {
-			fmt.Fprintln(os.Stderr, "Error: --user-id is required (use 'me' command to find your user ID)")
+			reportError("Error", errors.New("--user-id is required (use 'me' command to find your user ID)"))
 			listCmd.Usage()
-			os.Exit(1)
+			exit(1)
+		}
+		registerSecret(*userID)
+		if (*resubmit || *deleteStuck) && !*stuck {
+			reportError("Error", errors.New("--resubmit and --delete need --stuck"))
+			exit(1)
 		}
-		if err := listGenerations(svc, *userID, *offset, *limit); err != nil {
-			fmt.Fprintln(os.Stderr, "Error listing generations:", err)
-			os.Exit(1)
+		if *stuck {
+			if err := listStuck(svc, lib, *userID, *timestamps, stuckActions{resubmit: *resubmit, remove: *deleteStuck, yes: *yes}); err != nil {
+				fail("Error handling stuck generations", err)
+			}
+			break
+		}
+		if *all {
+			if outputQuery != "" {
+				reportError("Error", errors.New("--query needs a single response; drop --all or use --format"))
+				exit(1)
+			}
+			if *concurrency < 1 {
+				reportError("Error", errors.New("--concurrency must be at least 1"))
+				exit(1)
+			}
+			if err := listAllGenerations(svc, *userID, *concurrency, *timestamps); err != nil {
+				fail("Error listing generations", err)
+			}
+			break
+		}
+		if err := listGenerations(svc, *userID, *offset, *limit, *timestamps); err != nil {
+			fail("Error listing generations", err)
+		}
+	case "limits":
+		if err := showLimits(svc); err != nil {
+			fail("Error checking rate limits", err)
 		}
 	case "models":
 		if err := listPlatformModels(svc); err != nil {
-			fmt.Fprintln(os.Stderr, "Error listing platform models:", err)
-			os.Exit(1)
+			fail("Error listing platform models", err)
 		}
 	case "download":
 		downloadCmd := flag.NewFlagSet("download", flag.ExitOnError)
-		id := downloadCmd.String("id", "", "Generation ID to download images for (required)")
+		id := downloadCmd.String("id", "", "Generation ID, ID prefix or name to download images for")
+		var last lastFlag
+		downloadCmd.Var(&last, "last", "Download the N most recently created generations recorded locally (default 1)")
 		outputDir := downloadCmd.String("output-dir", ".", "Directory to save downloaded images")
-		downloadCmd.Parse(os.Args[2:])
-		if strings.TrimSpace(*id) == "" {
-			fmt.Fprintln(os.Stderr, "Error: --id is required")
+		includeVariations := downloadCmd.Bool("include-variations", false, "Also download upscaled, background-removed and other variations of each image")
+		failFast := downloadCmd.Bool("fail-fast", false, "Stop at the first file that fails to download instead of attempting the rest")
+		resize := downloadCmd.String("resize", "", "Also save a copy of each image resized to WIDTHxHEIGHT, e.g. 512x512")
+		maxDimension := downloadCmd.Int("max-dimension", 0, "Also save a copy of each image scaled down so its longest side is at most this many pixels")
+		quality := downloadCmd.Int("quality", 0, "Save the copies as JPEG with this quality (1-100) instead of PNG")
+		watermark := downloadCmd.String("watermark", "", "Also save a draft copy of each image marked with this text, keeping the original clean")
+		watermarkImage := downloadCmd.String("watermark-image", "", "Also save a draft copy of each image with this image (e.g. a logo) overlaid")
+		watermarkCorner := downloadCmd.String("watermark-corner", imaging.CornerBottomRight, "Where to place the watermark: "+strings.Join(imaging.Corners, ", "))
+		watermarkOpacity := downloadCmd.Float64("watermark-opacity", 0.6, "Opacity of the watermark, above 0 and at most 1")
+		noThumbnails := downloadCmd.Bool("no-thumbnails", false, "Do not cache 256px thumbnails of the images in .thumbnails")
+		dedupeFlag := downloadCmd.Bool("dedupe", false, "Replace images identical to ones downloaded before with hard links to them; see library gc")
+		toStdout := downloadCmd.Bool("stdout", false, "Write the bytes of one image to stdout, for piping, instead of saving files")
+		imageNumber := downloadCmd.Int("image", 1, "With --stdout, the number of the image to write, starting at 1")
+		given := parseWithLast(downloadCmd, cmdArgs, &last)
+		derivatives, err := parseDerivativeOptions(*resize, *maxDimension, *quality)
+		if err == nil {
+			derivatives.watermark, err = parseWatermark(*watermark, *watermarkImage, *watermarkCorner, *watermarkOpacity)
+		}
+		if err != nil {
+			reportError("Error", err)
 			downloadCmd.Usage()
-			os.Exit(1)
+			exit(1)
 		}
-		if err := downloadImages(svc, *id, *outputDir); err != nil {
-			fmt.Fprintln(os.Stderr, "Error downloading images:", err)
-			os.Exit(1)
+		ids := targetGenerations(downloadCmd, svc, lib, *id, last)
+		if *toStdout {
+			if err := checkStream(ids, given, isTerminal(os.Stdout)); err != nil {
+				reportError("Error", err)
+				downloadCmd.Usage()
+				exit(1)
+			}
+			if err := svc.StreamImage(ids[0], *imageNumber, os.Stdout); err != nil {
+				fail("Error downloading images", err)
+			}
+			break
 		}
-	case "inspect":
-		inspectCmd := flag.NewFlagSet("inspect", flag.ExitOnError)
-		filePath := inspectCmd.String("file", "", "Path to a sidecar metadata JSON file (required)")
-		inspectCmd.Parse(os.Args[2:])
-		if strings.TrimSpace(*filePath) == "" {
-			fmt.Fprintln(os.Stderr, "Error: --file is required")
-			inspectCmd.Usage()
-			os.Exit(1)
+		if given["image"] {
+			reportError("Error", errors.New("--image only applies with --stdout"))
+			downloadCmd.Usage()
+			exit(1)
 		}
-		if err := inspectSidecar(*filePath); err != nil {
-			fmt.Fprintln(os.Stderr, "Error inspecting sidecar:", err)
-			os.Exit(1)
+		if err := checkDownloadSpace(svc, ids, *outputDir, *includeVariations); err != nil {
+			fail("Error", err)
 		}
+		svc.SetFailFast(*failFast)
+		var dedupe *service.DedupeService
+		if *dedupeFlag {
+			dedupe = service.NewDedupeService(storage.NewFileDownloadStore(downloadsPath()))
+		}
+		failed := false
+		for _, genID := range ids {
+			if err := downloadImages(svc, dedupe, genID, *outputDir, *includeVariations, !*noThumbnails, derivatives); err != nil {
+				if *failFast {
+					fail("Error downloading images", err)
+				}
+				reportError("Error downloading images", err)
+				failed = true
+			}
+		}
+		if failed {
+			exit(1)
+		}
+	case "urls":
+		if err := runURLs(svc, lib, cmdArgs); err != nil {
+			fail("Error listing image URLs", err)
+		}
+	case "variations":
+		if err := runVariations(svc, lib, cmdArgs); err != nil {
+			fail("Error creating variations", err)
+		}
+	case "compare":
+		if err := runCompare(svc, lib, cmdArgs); err != nil {
+			fail("Error comparing generations", err)
+		}
+	case "sweep":
+		if err := runSweep(svc, lib, models, cmdArgs); err != nil {
+			fail("Error running sweep", err)
+		}
+	case "benchmark":
+		if err := runBenchmark(svc, lib, models, cmdArgs); err != nil {
+			fail("Error running benchmark", err)
+		}
+	case "import":
+		if err := runImport(svc, lib, models, cmdArgs); err != nil {
+			fail("Error importing prompt", err)
+		}
+	case "share":
+		if err := runShare(svc, lib, cmdArgs); err != nil {
+			fail("Error sharing generation", err)
+		}
+	case "listen":
+		if err := runListen(svc, lib, cmdArgs); err != nil {
+			fail("Error relaying webhooks", err)
+		}
+	case "preview":
+		if err := runPreview(svc, lib, cmdArgs); err != nil {
+			fail("Error previewing images", err)
+		}
+	case "init-images":
+		images := service.NewInitImageService(client, storage.NewFileInitImageStore(initImagesPath()))
+		if err := runInitImages(images, cmdArgs); err != nil {
+			fail("Error managing init images", err)
+		}
+	case "character":
+		images := service.NewInitImageService(client, storage.NewFileInitImageStore(initImagesPath()))
+		if err := runCharacter(service.NewCharacterService(storage.NewFileCharacterStore(charactersPath())), images, cmdArgs); err != nil {
+			fail("Error managing characters", err)
+		}
+	case "pricing":
+		if err := runPricing(service.NewPricingService(client), models, cmdArgs); err != nil {
+			fail("Error pricing", err)
+		}
+	case "library":
+		backfill := service.NewBackfillService(svc, lib)
+		if err := runLibraryBackfill(svc, backfill, cmdArgs[1:]); err != nil {
+			fail("Error backfilling sidecars", err)
+		}
+	case "usage":
+		usage := service.NewUsageService(svc, client, models)
+		if err := runUsage(usage, svc, lib, cmdArgs); err != nil {
+			fail("Error reporting usage", err)
+		}
+	case "models3d":
+		if err := runModels3D(service.NewModel3DService(client), cmdArgs); err != nil {
+			fail("Error managing 3D models", err)
+		}
+	case "watch-folder":
+		images := service.NewInitImageService(client, storage.NewFileInitImageStore(initImagesPath()))
+		if err := runWatchFolder(svc, lib, models, images, cmdArgs); err != nil {
+			fail("Error watching folder", err)
+		}
+	case "batch":
+		preflight := service.NewPreflightService(client, client, models)
+		if keyRotation != nil {
+			preflight = service.NewPreflightService(keyRotation, client, models)
+		}
+		if err := runBatch(svc, lib, models, preflight, cmdArgs); err != nil {
+			fail("Error running batch", err)
+		}
+	case "raw":
+		raw := service.NewRawService(client)
+		raw.SetContext(runCtx)
+		if err := runRaw(raw, cmdArgs); err != nil {
+			fail("Error sending request", err)
+		}
+	case "auth":
+		exit(runAuth(svc, cmdArgs))
 	case "help", "--help", "-h":
 		printUsage()
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", cmd)
-		printUsage()
-		os.Exit(1)
+		unknownCommand(cmd)
+		exit(1)
 	}
+	exit(0)
 }
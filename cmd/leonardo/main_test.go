@@ -1,15 +1,36 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"image"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"leonardo-cli/internal/config"
 	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/i18n"
+	"leonardo-cli/internal/imaging"
+	"leonardo-cli/internal/service"
+	"leonardo-cli/internal/storage"
 )
 
 func TestWriteSidecarMetadata_WritesExpectedJSON(t *testing.T) {
@@ -27,6 +48,7 @@ func TestWriteSidecarMetadata_WritesExpectedJSON(t *testing.T) {
 		NumImages: 2,
 		Private:   true,
 		Metadata: domain.GenerationMetadata{
+			Name:           "lighthouse-v1",
 			Prompt:         "a lighthouse at dusk",
 			NegativePrompt: "low quality",
 			ModelID:        "model-123",
@@ -66,6 +88,9 @@ func TestWriteSidecarMetadata_WritesExpectedJSON(t *testing.T) {
 	if got["negative_prompt"] != req.Metadata.NegativePrompt {
 		t.Errorf("expected negative_prompt %q, got %v", req.Metadata.NegativePrompt, got["negative_prompt"])
 	}
+	if got["name"] != req.Metadata.Name {
+		t.Errorf("expected name %q, got %v", req.Metadata.Name, got["name"])
+	}
 	if got["generation_id"] != "gen-abc" {
 		t.Errorf("expected generation_id %q, got %v", "gen-abc", got["generation_id"])
 	}
@@ -130,26 +155,3086 @@ func TestParseTags_ParsesAndTrimsCommaSeparatedValues(t *testing.T) {
 	}
 }
 
-func TestDefaultModelIDFromEnv_ReturnsValueWhenSet(t *testing.T) {
+func TestParseFlags_ModelIDFromEnvWhenSet(t *testing.T) {
 	t.Setenv("LEONARDO_MODEL_ID", "model-abc-123")
-	got := defaultModelIDFromEnv()
-	if got != "model-abc-123" {
-		t.Errorf("expected %q, got %q", "model-abc-123", got)
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	model := fs.String("model-id", "", "")
+	parseFlags(fs, nil)
+	if *model != "model-abc-123" {
+		t.Errorf("expected %q, got %q", "model-abc-123", *model)
 	}
 }
 
-func TestDefaultModelIDFromEnv_ReturnsEmptyWhenUnset(t *testing.T) {
+func TestParseFlags_ModelIDEmptyWhenUnset(t *testing.T) {
 	t.Setenv("LEONARDO_MODEL_ID", "")
-	got := defaultModelIDFromEnv()
-	if got != "" {
-		t.Errorf("expected empty string, got %q", got)
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	model := fs.String("model-id", "", "")
+	parseFlags(fs, nil)
+	if *model != "" {
+		t.Errorf("expected empty string, got %q", *model)
 	}
 }
 
-func TestDefaultModelIDFromEnv_TrimsWhitespace(t *testing.T) {
+func TestParseFlags_ModelIDFromEnvTrimsWhitespace(t *testing.T) {
 	t.Setenv("LEONARDO_MODEL_ID", "  model-xyz  ")
-	got := defaultModelIDFromEnv()
-	if got != "model-xyz" {
-		t.Errorf("expected %q, got %q", "model-xyz", got)
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	model := fs.String("model-id", "", "")
+	parseFlags(fs, nil)
+	if *model != "model-xyz" {
+		t.Errorf("expected %q, got %q", "model-xyz", *model)
+	}
+}
+
+func TestParseFlags_FlagOverridesEnv(t *testing.T) {
+	t.Setenv("LEONARDO_OUTPUT_DIR", "/from/env")
+	t.Setenv("LEONARDO_PRIVATE", "true")
+	fs := flag.NewFlagSet("download", flag.ContinueOnError)
+	outputDir := fs.String("output-dir", ".", "")
+	private := fs.Bool("private", false, "")
+	parseFlags(fs, []string{"--output-dir", "/from/flag", "--private=false"})
+	if *outputDir != "/from/flag" {
+		t.Errorf("expected %q, got %q", "/from/flag", *outputDir)
+	}
+	if *private {
+		t.Error("expected --private=false to override LEONARDO_PRIVATE")
+	}
+}
+
+func TestExtractGlobalFlags_DefaultsFromEnv(t *testing.T) {
+	t.Setenv("LEONARDO_STATS", "true")
+	t.Setenv("LEONARDO_ACCOUNT", "work")
+	opts, _ := extractGlobalFlags([]string{"me", "--stats=false"})
+	if opts.stats {
+		t.Error("expected --stats=false to override LEONARDO_STATS")
+	}
+	if opts.account != "work" {
+		t.Errorf("expected account %q, got %q", "work", opts.account)
+	}
+}
+
+func TestWriteManifest_RoundTripsBatchItems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest := domain.BatchManifest{Items: []domain.BatchItem{
+		{
+			Request: domain.GenerationRequest{
+				NumImages: 2,
+				Metadata:  domain.GenerationMetadata{Prompt: "a red fox", ModelID: "model-1", Tags: []string{"fox"}},
+			},
+			IdempotencyKey: "key-1",
+			SubmittedAt:    time.Date(2026, 3, 1, 12, 0, 0, 500, time.UTC),
+			GenerationID:   "gen-1",
+			Attempts:       1,
+		},
+		{
+			Request:  domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "a blue whale"}},
+			Attempts: 2,
+			Failure:  domain.FailureRateLimit,
+			Error:    "API returned status 429",
+		},
+	}}
+
+	if err := writeManifest(path, manifest); err != nil {
+		t.Fatalf("unexpected error writing manifest: %v", err)
+	}
+	got, err := readManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading manifest: %v", err)
+	}
+
+	if len(got.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(got.Items))
+	}
+	if got.Items[0].Request.Metadata.Prompt != "a red fox" || got.Items[0].Request.Metadata.ModelID != "model-1" {
+		t.Errorf("unexpected first request: %+v", got.Items[0].Request)
+	}
+	if got.Items[0].Request.NumImages != 2 {
+		t.Errorf("expected num images 2, got %d", got.Items[0].Request.NumImages)
+	}
+	if got.Items[0].IdempotencyKey != "key-1" || !got.Items[0].SubmittedAt.Equal(manifest.Items[0].SubmittedAt) {
+		t.Errorf("expected key and submission time to round-trip, got %q at %v", got.Items[0].IdempotencyKey, got.Items[0].SubmittedAt)
+	}
+	if !got.Items[1].SubmittedAt.IsZero() {
+		t.Errorf("expected no submission time, got %v", got.Items[1].SubmittedAt)
+	}
+	if got.Items[1].Failure != domain.FailureRateLimit {
+		t.Errorf("expected failure %q, got %q", domain.FailureRateLimit, got.Items[1].Failure)
+	}
+	if got.Items[1].Attempts != 2 {
+		t.Errorf("expected attempts 2, got %d", got.Items[1].Attempts)
+	}
+}
+
+func TestParseWithLast_AcceptsBareEqualsAndSeparateCount(t *testing.T) {
+	cases := []struct {
+		args []string
+		want lastFlag
+	}{
+		{[]string{"--last"}, 1},
+		{[]string{"--last=3"}, 3},
+		{[]string{"--last", "2", "--output-dir", "out"}, 2},
+		{[]string{"--output-dir", "out"}, 0},
+	}
+	for _, tc := range cases {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		var last lastFlag
+		fs.Var(&last, "last", "")
+		outputDir := fs.String("output-dir", ".", "")
+
+		parseWithLast(fs, tc.args, &last)
+
+		if last != tc.want {
+			t.Errorf("args %v: expected last %d, got %d", tc.args, tc.want, last)
+		}
+		if len(tc.args) > 1 && tc.args[len(tc.args)-1] == "out" && *outputDir != "out" {
+			t.Errorf("args %v: expected output dir %q, got %q", tc.args, "out", *outputDir)
+		}
+	}
+}
+
+func TestFormatTimestamp_RendersRelativeAndRFC3339(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		t    time.Time
+		mode string
+		want string
+	}{
+		{now.Add(-30 * time.Second), timestampsRelative, "30s ago"},
+		{now.Add(-4 * time.Minute), timestampsRelative, "4m ago"},
+		{now.Add(-5 * time.Hour), timestampsRelative, "5h ago"},
+		{now.Add(-49 * time.Hour), timestampsRelative, "2d ago"},
+		{now.Add(time.Minute), timestampsRelative, "just now"},
+		{now.Add(-4 * time.Minute), timestampsRFC3339, "2026-03-10T11:56:00Z"},
+		{time.Time{}, timestampsRelative, ""},
+	}
+	for _, tc := range cases {
+		if got := formatTimestamp(tc.t, tc.mode, now); got != tc.want {
+			t.Errorf("formatTimestamp(%v, %q): expected %q, got %q", tc.t, tc.mode, tc.want, got)
+		}
+	}
+}
+
+func TestValidateTimestampMode_RejectsUnknownModes(t *testing.T) {
+	if err := validateTimestampMode("rfc3339"); err != nil {
+		t.Errorf("expected rfc3339 to be valid, got %v", err)
+	}
+	if err := validateTimestampMode("unix"); err == nil {
+		t.Error("expected error for unknown mode, got nil")
+	}
+}
+
+func TestExtractGlobalFlags_RemovesNoColorAnywhere(t *testing.T) {
+	opts, rest := extractGlobalFlags([]string{"status", "--id", "abc", "--no-color"})
+	if !opts.noColor {
+		t.Error("expected noColor to be set")
+	}
+	if strings.Join(rest, " ") != "status --id abc" {
+		t.Errorf("expected remaining args %q, got %q", "status --id abc", strings.Join(rest, " "))
+	}
+
+	opts, rest = extractGlobalFlags([]string{"--no-color=false", "list"})
+	if opts.noColor {
+		t.Error("expected --no-color=false to leave color enabled")
+	}
+	if len(rest) != 1 || rest[0] != "list" {
+		t.Errorf("expected remaining args [list], got %v", rest)
+	}
+}
+
+func TestColorEnabled_HonoursFlagEnvironmentAndTerminal(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm-256color")
+	if !colorEnabled(false, true) {
+		t.Error("expected color on a terminal by default")
+	}
+	if colorEnabled(true, true) {
+		t.Error("expected --no-color to disable color")
+	}
+	if colorEnabled(false, false) {
+		t.Error("expected color to be disabled when not writing to a terminal")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if colorEnabled(false, true) {
+		t.Error("expected NO_COLOR to disable color")
+	}
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "dumb")
+	if colorEnabled(false, true) {
+		t.Error("expected TERM=dumb to disable color")
+	}
+}
+
+func TestPalette_ColorsStatusesOnlyWhenEnabled(t *testing.T) {
+	if got := (palette{}).status("COMPLETE"); got != "COMPLETE" {
+		t.Errorf("expected disabled palette to return plain text, got %q", got)
+	}
+	on := palette{enabled: true}
+	if got := on.status("COMPLETE"); got != ansiGreen+"COMPLETE"+ansiReset {
+		t.Errorf("expected green COMPLETE, got %q", got)
+	}
+	if got := on.status("FAILED"); got != ansiRed+"FAILED"+ansiReset {
+		t.Errorf("expected red FAILED, got %q", got)
+	}
+	if got := on.id(""); got != "" {
+		t.Errorf("expected empty input to stay empty, got %q", got)
+	}
+}
+
+func TestPrintGenerationDetail_RendersParametersElementsAndImages(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	detail := domain.GenerationDetail{
+		ID:          "gen-1",
+		Status:      "COMPLETE",
+		CreatedAt:   now.Add(-2 * time.Hour),
+		Prompt:      "a castle",
+		ModelID:     "model-1",
+		Width:       1024,
+		Height:      768,
+		Scheduler:   "LEONARDO",
+		PresetStyle: "CINEMATIC",
+		PhotoReal:   true,
+		Elements:    []domain.GenerationElement{{ID: "el-1", Name: "Crystal", Weight: 0.8}},
+		Images: []domain.GeneratedImage{{
+			ID:         "img-1",
+			URL:        "https://cdn.leonardo.ai/1.png",
+			Variations: []domain.ImageVariation{{TransformType: "UPSCALE", Status: "COMPLETE", URL: "https://cdn.leonardo.ai/1-up.png"}},
+		}},
+	}
+	var buf bytes.Buffer
+
+	printGenerationDetail(&buf, detail, "client asked for warmer colors", timestampsRelative, now)
+
+	out := buf.String()
+	for _, want := range []string{"gen-1", "2h ago", "1024x768", "LEONARDO", "CINEMATIC", "photoReal", "Crystal (el-1) weight 0.8", "https://cdn.leonardo.ai/1.png", "UPSCALE [COMPLETE]", "Note:", "warmer colors"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Negative prompt") {
+		t.Errorf("expected empty fields to be omitted, got:\n%s", out)
+	}
+}
+
+func TestExtractGlobalFlags_ParsesVerboseAndStats(t *testing.T) {
+	opts, rest := extractGlobalFlags([]string{"--stats", "list", "--verbose", "--limit", "5"})
+	if !opts.verbose || !opts.stats {
+		t.Errorf("expected verbose and stats to be set, got %+v", opts)
+	}
+	if strings.Join(rest, " ") != "list --limit 5" {
+		t.Errorf("expected remaining args %q, got %q", "list --limit 5", strings.Join(rest, " "))
+	}
+}
+
+func TestCallStats_LogsCallsAndSummarisesLatency(t *testing.T) {
+	var log bytes.Buffer
+	s := &callStats{verbose: true, log: &log}
+	s.record(domain.CallMetric{Method: "GET", Path: "/me", StatusCode: 200, Duration: 100 * time.Millisecond})
+	s.record(domain.CallMetric{Method: "GET", Path: "/generations/abc", StatusCode: 500, RequestID: "req-9", Duration: 300 * time.Millisecond})
+	s.record(domain.CallMetric{Method: "DELETE", Path: "/generations/abc", Duration: 200 * time.Millisecond})
+
+	expectedLog := "GET /me -> 200 in 100ms\n" +
+		"GET /generations/abc -> 500 in 300ms (request ID req-9)\n" +
+		"DELETE /generations/abc -> no response in 200ms\n"
+	if log.String() != expectedLog {
+		t.Errorf("expected log %q, got %q", expectedLog, log.String())
+	}
+
+	var out bytes.Buffer
+	s.summary(&out)
+	expected := "API calls: 3 (2 failed)\n" +
+		"Latency: total 600ms, avg 200ms, p50 200ms, p95 300ms, max 300ms\n" +
+		"Slowest: GET /generations/abc -> 500 in 300ms (request ID req-9)\n"
+	if out.String() != expected {
+		t.Errorf("expected summary %q, got %q", expected, out.String())
+	}
+}
+
+func TestCallStats_SummaryIsEmptyWithoutCalls(t *testing.T) {
+	var out bytes.Buffer
+	(&callStats{}).summary(&out)
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+}
+
+func TestPrintListItem_RendersSummaryLine(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	gen := domain.GenerationListItem{
+		ID:        "gen-1",
+		Status:    "COMPLETE",
+		Prompt:    "a lighthouse",
+		Images:    []string{"a.png", "b.png"},
+		CreatedAt: now.Add(-5 * time.Minute),
+	}
+	var out bytes.Buffer
+	printListItem(&out, gen, timestampsRelative, now)
+	expected := "[COMPLETE] gen-1 — a lighthouse (2 images) · 5m ago\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestTokenExitCode_DistinguishesTokenProblems(t *testing.T) {
+	codes := map[domain.TokenState]int{
+		domain.TokenValid:      0,
+		domain.TokenUnverified: 1,
+		domain.TokenMissing:    2,
+		domain.TokenInvalid:    3,
+		domain.TokenExpired:    4,
+	}
+	for state, expected := range codes {
+		if got := tokenExitCode(state); got != expected {
+			t.Errorf("expected exit code %d for %q, got %d", expected, state, got)
+		}
+	}
+}
+
+func TestUserInfoPlanType_InfersSubscriptionFromRenewalDate(t *testing.T) {
+	if got := (domain.UserInfo{TokenRenewalDate: "2024-06-01"}).PlanType(); got != "subscription" {
+		t.Errorf("expected %q, got %q", "subscription", got)
+	}
+	if got := (domain.UserInfo{APIPaidTokens: 100}).PlanType(); got != "pay-as-you-go" {
+		t.Errorf("expected %q, got %q", "pay-as-you-go", got)
+	}
+}
+
+func TestExtractGlobalFlags_AcceptsAccountWithSeparateValue(t *testing.T) {
+	opts, rest := extractGlobalFlags([]string{"me", "--account", "work"})
+	if opts.account != "work" {
+		t.Errorf("expected account %q, got %q", "work", opts.account)
+	}
+	if strings.Join(rest, " ") != "me" {
+		t.Errorf("expected remaining args %q, got %q", "me", strings.Join(rest, " "))
+	}
+	opts, _ = extractGlobalFlags([]string{"--account=personal", "me"})
+	if opts.account != "personal" {
+		t.Errorf("expected account %q, got %q", "personal", opts.account)
+	}
+}
+
+func TestPrintAccountBalances_TotalsSuccessfulAccounts(t *testing.T) {
+	var out bytes.Buffer
+	printAccountBalances(&out, []accountBalance{
+		{name: "work", info: domain.UserInfo{Username: "ana", APISubscriptionTokens: 100, APIPaidTokens: 20}},
+		{name: "personal", err: errors.New("API returned status 401")},
+		{name: "team", info: domain.UserInfo{Username: "ops", APISubscriptionTokens: 50, APIPaidTokens: 5}},
+	})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[2], "error: API returned status 401") {
+		t.Errorf("expected inline error, got %q", lines[2])
+	}
+	if fields := strings.Fields(lines[4]); len(fields) != 3 || fields[1] != "150" || fields[2] != "25" {
+		t.Errorf("expected totals 150 and 25, got %q", lines[4])
+	}
+}
+
+func TestRedactingWriter_HidesRegisteredSecrets(t *testing.T) {
+	t.Setenv("LEONARDO_REDACT", "")
+	saved := redactor
+	defer func() { redactor = saved }()
+	redactor = domain.Redactor{}
+	registerSecret("sk-secret-token")
+	registerSecret("ab") // too short to redact safely
+
+	var out bytes.Buffer
+	fmt.Fprintln(redactingWriter{w: &out}, "Error: token sk-secret-token rejected for ab")
+	expected := "Error: token [redacted] rejected for ab\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRegisterSecret_DisabledByLeonardoRedact(t *testing.T) {
+	t.Setenv("LEONARDO_REDACT", "false")
+	saved := redactor
+	defer func() { redactor = saved }()
+	redactor = domain.Redactor{}
+	registerSecret("sk-secret-token")
+
+	if got := redactor.Text("sk-secret-token"); got != "sk-secret-token" {
+		t.Errorf("expected secret to be kept, got %q", got)
+	}
+	if got := redactPath("/generations/user/u-1"); got != "/generations/user/u-1" {
+		t.Errorf("expected path to be kept, got %q", got)
+	}
+}
+
+func TestRedactPath_HidesUserID(t *testing.T) {
+	t.Setenv("LEONARDO_REDACT", "")
+	got := redactPath("/api/rest/v1/generations/user/3fa2c1d0-aaaa")
+	if got != "/api/rest/v1/generations/user/[redacted]" {
+		t.Errorf("expected user ID to be redacted, got %q", got)
+	}
+}
+
+func TestRedactJSONPrompts_ReplacesNestedPrompts(t *testing.T) {
+	saved := redactor
+	defer func() { redactor = saved }()
+	redactor = domain.Redactor{Prompts: true}
+
+	data := []byte(`{"generations":[{"id":"g1","prompt":"secret launch","negativePrompt":"blur","imageWidth":1024}]}`)
+	out := string(redactJSONPrompts(data))
+	if strings.Contains(out, "secret launch") || strings.Contains(out, "blur") {
+		t.Errorf("expected prompts to be redacted, got %s", out)
+	}
+	if !strings.Contains(out, `"imageWidth":1024`) || !strings.Contains(out, `"id":"g1"`) {
+		t.Errorf("expected other fields to be kept, got %s", out)
+	}
+	if !domain.IsRedactedPrompt(redactor.Prompt("secret launch")) {
+		t.Error("expected redacted prompt to be recognised")
+	}
+	if redactor.Prompt("secret launch") != redactor.Prompt("secret launch") {
+		t.Error("expected redaction to be stable for identical prompts")
+	}
+}
+
+func TestWriteSidecarMetadata_RedactsPromptsWhenRequested(t *testing.T) {
+	saved := redactor
+	defer func() { redactor = saved }()
+	redactor = domain.Redactor{Prompts: true}
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	req := domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "confidential product"}}
+	path, err := writeSidecarMetadata(req, "gen-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, path))
+	if strings.Contains(string(data), "confidential product") {
+		t.Errorf("expected prompt to be redacted, got %s", data)
+	}
+}
+
+func TestCatalogStyles_AddsAndReplacesCustomStyles(t *testing.T) {
+	custom := map[string]string{
+		"brand-noir": "0b5c7f7e-1111-4a3e-9a11-5f3c2c8d9e01",
+		"cinematic":  "0b5c7f7e-2222-4a3e-9a11-5f3c2c8d9e02",
+	}
+	styles, err := domain.CatalogStyles(custom)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(styles) != len(domain.Styles())+1 {
+		t.Errorf("expected one style added, got %d styles", len(styles))
+	}
+	if uuid, err := domain.ResolveStyleIn(styles, "Brand Noir"); err != nil || uuid != custom["brand-noir"] {
+		t.Errorf("expected the custom style to resolve, got %q (%v)", uuid, err)
+	}
+	if uuid, _ := domain.ResolveStyleIn(styles, "cinematic"); uuid != custom["cinematic"] {
+		t.Errorf("expected the custom style to replace the built-in one, got %q", uuid)
+	}
+	if name := domain.StyleName(styles, custom["brand-noir"]); name != "brand-noir" {
+		t.Errorf("expected the custom style's name, got %q", name)
+	}
+	if _, err := domain.CatalogStyles(map[string]string{"broken": "not-a-uuid"}); err == nil {
+		t.Error("expected an error for a custom style without a UUID")
+	}
+}
+
+func TestWriteSidecarMetadata_NamesStyleFromProjectCatalog(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	uuid := "0b5c7f7e-1111-4a3e-9a11-5f3c2c8d9e01"
+	if err := os.WriteFile(".leonardo.yaml", []byte("style.brand-noir: "+uuid+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	projectConfig, projectConfigLoaded = nil, false
+	defer func() { projectConfig, projectConfigLoaded = nil, false }()
+
+	resolved, err := resolveStyle("brand-noir")
+	if err != nil || resolved != uuid {
+		t.Fatalf("expected --style-name brand-noir to resolve to %s, got %q (%v)", uuid, resolved, err)
+	}
+	req := domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "poster", StyleUUID: resolved}}
+	path, err := writeSidecarMetadata(req, "gen-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, path))
+	sidecar, err := domain.ParseSidecar(data)
+	if err != nil || sidecar.StyleName != "brand-noir" || sidecar.StyleUUID != uuid {
+		t.Errorf("expected the sidecar to name the style, got %+v (%v)", sidecar, err)
+	}
+}
+
+func TestWriteSidecarFile_BackfillsFromGenerationRecord(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	detail := domain.GenerationDetail{ID: "gen-old", Prompt: "a red fox", ModelID: "model-1", Width: 1024, Height: 768, Seed: 42, CreatedAt: created, Images: make([]domain.GeneratedImage, 2)}
+
+	path, err := writeSidecarFile(dir, domain.BackfillRequest(detail), detail.ID, detail.CreatedAt, map[string]interface{}{"backfilled": true})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, "gen-old.json") {
+		t.Errorf("expected the sidecar in the scanned directory, got %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	var sidecar map[string]interface{}
+	json.Unmarshal(data, &sidecar)
+	if sidecar["prompt"] != "a red fox" || sidecar["seed"] != float64(42) || sidecar["num_images"] != float64(2) {
+		t.Errorf("expected the generation's settings, got %s", data)
+	}
+	if sidecar["timestamp"] != "2025-03-04T05:06:07Z" || sidecar["backfilled"] != true {
+		t.Errorf("expected the creation time and a backfilled mark, got %s", data)
+	}
+}
+
+func TestSignedSidecar_VerifiesUntilChanged(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	if err := runKeygen([]string{"--output", "signer"}); err != nil {
+		t.Fatalf("keygen: %v", err)
+	}
+	if info, err := os.Stat("signer.pem"); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected a private key readable only by its owner, got %v (%v)", info.Mode(), err)
+	}
+	key, err := loadSigningKey("signer.pem")
+	if err != nil {
+		t.Fatalf("loading key: %v", err)
+	}
+	sidecarSigner = key
+	defer func() { sidecarSigner = nil }()
+	req := domain.GenerationRequest{NumImages: 1, Metadata: domain.GenerationMetadata{Prompt: "a lighthouse", Seed: 1234567, GuidanceScale: 7.5}}
+	path, err := writeSidecarMetadata(req, "gen-signed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := verifySidecarFile(path, "signer.pub.pem"); err != nil {
+		t.Errorf("expected the signature to verify, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var compact bytes.Buffer
+	json.Compact(&compact, data)
+	os.WriteFile(path, compact.Bytes(), 0644)
+	if err := verifySidecarFile(path, ""); err != nil {
+		t.Errorf("expected reformatting to keep the signature valid, got %v", err)
+	}
+	os.WriteFile(path, bytes.Replace(data, []byte("1234567"), []byte("7654321"), 1), 0644)
+	if err := verifySidecarFile(path, ""); err == nil {
+		t.Error("expected a changed seed to break the signature")
+	}
+
+	runKeygen([]string{"--output", "other"})
+	os.WriteFile(path, data, 0644)
+	if err := verifySidecarFile(path, "other.pub.pem"); err == nil || !strings.Contains(err.Error(), "not the trusted key") {
+		t.Errorf("expected a signature by another key to be rejected, got %v", err)
+	}
+	if _, err := domain.VerifySidecar([]byte(`{"prompt":"x"}`), nil); !errors.Is(err, domain.ErrUnsignedSidecar) {
+		t.Errorf("expected an unsigned sidecar to be reported, got %v", err)
+	}
+}
+
+func TestReadCSVRequests_MapsColumnsByHeaderAndKeepsDefaults(t *testing.T) {
+	input := "Prompt,Model,Size,Seed,Tags,Notes\n" +
+		"a red fox,model-2,1024x768,42,animal;red,first\n" +
+		"\"a city, at night\",,,,,\n"
+	defaults := domain.GenerationRequest{NumImages: 2, Metadata: domain.GenerationMetadata{ModelID: "model-1", Width: 512, Height: 512, Tags: []string{"batch"}}}
+
+	requests, unknown, err := readCSVRequests(strings.NewReader(input), defaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unknown) != 1 || unknown[0] != "Notes" {
+		t.Errorf("expected Notes to be reported as unknown, got %v", unknown)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	first := requests[0]
+	if first.Metadata.Prompt != "a red fox" || first.Metadata.ModelID != "model-2" || first.Metadata.Seed != 42 {
+		t.Errorf("unexpected first request: %+v", first.Metadata)
+	}
+	if first.Metadata.Width != 1024 || first.Metadata.Height != 768 {
+		t.Errorf("expected size 1024x768, got %dx%d", first.Metadata.Width, first.Metadata.Height)
+	}
+	if strings.Join(first.Metadata.Tags, ",") != "animal,red" {
+		t.Errorf("expected tags animal,red, got %v", first.Metadata.Tags)
+	}
+	second := requests[1]
+	if second.Metadata.Prompt != "a city, at night" || second.Metadata.ModelID != "model-1" || second.Metadata.Width != 512 || second.NumImages != 2 {
+		t.Errorf("expected defaults for empty cells, got %+v", second)
+	}
+	if strings.Join(second.Metadata.Tags, ",") != "batch" {
+		t.Errorf("expected default tags, got %v", second.Metadata.Tags)
+	}
+}
+
+func TestReadCSVRequests_ReportsBadCellsWithLineNumbers(t *testing.T) {
+	input := "prompt,seed\nok,1\nbroken,abc\n"
+	_, _, err := readCSVRequests(strings.NewReader(input), domain.GenerationRequest{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "seed") {
+		t.Errorf("expected error to name line 3 and the seed column, got %q", err.Error())
+	}
+}
+
+func TestReadCSVRequests_RequiresPromptColumn(t *testing.T) {
+	_, _, err := readCSVRequests(strings.NewReader("model,seed\nm,1\n"), domain.GenerationRequest{})
+	if err == nil || !strings.Contains(err.Error(), "prompt column") {
+		t.Errorf("expected missing prompt column error, got %v", err)
+	}
+}
+
+func TestDecodeStreamRequest_OverridesDefaultsPerLine(t *testing.T) {
+	defaults := domain.GenerationRequest{NumImages: 1, Metadata: domain.GenerationMetadata{ModelID: "model-1", Width: 512, Tags: []string{"batch"}}}
+
+	req, err := decodeStreamRequest([]byte(`{"prompt":"a red fox","width":1024,"tags":["fox"]}`), defaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Metadata.Prompt != "a red fox" || req.Metadata.Width != 1024 || req.Metadata.ModelID != "model-1" || req.NumImages != 1 {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if strings.Join(req.Metadata.Tags, ",") != "fox" {
+		t.Errorf("expected tags fox, got %v", req.Metadata.Tags)
+	}
+	if strings.Join(defaults.Metadata.Tags, ",") != "batch" {
+		t.Errorf("expected defaults to be left untouched, got %v", defaults.Metadata.Tags)
+	}
+}
+
+func TestDecodeStreamRequest_RejectsBadLines(t *testing.T) {
+	for _, line := range []string{`{"prompt":`, `{"width":512}`, `{"prompt":"  "}`} {
+		if _, err := decodeStreamRequest([]byte(line), domain.GenerationRequest{}); err == nil {
+			t.Errorf("expected error for %s, got nil", line)
+		}
+	}
+}
+
+func TestProgressReporter_WritesOneJSONLinePerEvent(t *testing.T) {
+	var out bytes.Buffer
+	p := newProgressReporter(&out)
+	p.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	p.report(domain.ProgressEvent{Kind: domain.ProgressPolling, GenerationID: "gen-1", Status: "PENDING", Attempt: 2})
+	p.report(domain.ProgressEvent{Kind: domain.ProgressImageDownloaded, GenerationID: "gen-1", Image: 1, Images: 2, Path: "out/gen-1_1.png"})
+	p.done(0)
+
+	expected := `{"event":"polling","time":"2024-05-01T12:00:00Z","id":"gen-1","status":"PENDING","attempt":2}` + "\n" +
+		`{"event":"image-downloaded","time":"2024-05-01T12:00:00Z","id":"gen-1","image":1,"images":2,"path":"out/gen-1_1.png"}` + "\n" +
+		`{"event":"done","time":"2024-05-01T12:00:00Z","exit_code":0}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestExtractGlobalFlags_ParsesProgressJSON(t *testing.T) {
+	opts, rest := extractGlobalFlags([]string{"download", "--progress-json", "--id", "abc"})
+	if !opts.progressJSON {
+		t.Errorf("expected progressJSON to be set, got %+v", opts)
+	}
+	if strings.Join(rest, " ") != "download --id abc" {
+		t.Errorf("expected remaining args %q, got %q", "download --id abc", strings.Join(rest, " "))
+	}
+}
+
+func TestCleanupFiles_RemovesOldFilesButKeepsFavoritesAndOthers(t *testing.T) {
+	dir := t.TempDir()
+	const oldID = "3fa2c1d0-5e4b-4f5a-9c1e-0123456789ab"
+	const favID = "9d01aa2e-1111-4f5a-9c1e-0123456789ab"
+	const newID = "77ab0c3d-2222-4f5a-9c1e-0123456789ab"
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -100)
+	files := map[string]time.Time{
+		oldID + "_1.png":           old,
+		oldID + ".json":            old,
+		favID + "_1.png":           old,
+		newID + "_1.png":           now.AddDate(0, 0, -10),
+		"notes.txt":                old,
+		oldID + "_final.png":       old,
+		oldID + "_1_upscaled.png":  old,
+		"sub/" + oldID + "_2.webp": old,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(path, mtime, mtime)
+	}
+	opts := cleanupOptions{dir: dir, policy: domain.RetentionPolicy{KeepDays: 90, KeepFavorites: true}}
+
+	var out bytes.Buffer
+	summary, err := cleanupFiles(&out, opts, map[string]bool{favID: true}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.removed != 4 || summary.favorites != 1 || summary.bytes != 16 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	for name := range files {
+		_, statErr := os.Stat(filepath.Join(dir, name))
+		removed := errors.Is(statErr, os.ErrNotExist)
+		shouldRemove := name == oldID+"_1.png" || name == oldID+".json" || name == "sub/"+oldID+"_2.webp" || name == oldID+"_1_upscaled.png"
+		if removed != shouldRemove {
+			t.Errorf("%s: expected removed=%v, got %v", name, shouldRemove, removed)
+		}
+	}
+}
+
+func TestCleanupFiles_DryRunAndArchiveLeaveNothingDeleted(t *testing.T) {
+	dir := t.TempDir()
+	name := "3fa2c1d0-5e4b-4f5a-9c1e-0123456789ab_1.png"
+	path := filepath.Join(dir, name)
+	_ = os.WriteFile(path, []byte("data"), 0644)
+	now := time.Now()
+	old := now.AddDate(0, 0, -30)
+	_ = os.Chtimes(path, old, old)
+	policy := domain.RetentionPolicy{KeepDays: 7, KeepFavorites: true}
+
+	var out bytes.Buffer
+	if _, err := cleanupFiles(&out, cleanupOptions{dir: dir, policy: policy, dryRun: true}, nil, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected dry run to keep the file, got %v", err)
+	}
+
+	archive := filepath.Join(dir, "archive")
+	if _, err := cleanupFiles(&out, cleanupOptions{dir: dir, policy: policy, archive: archive}, nil, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(archive, name)); err != nil {
+		t.Errorf("expected file to be archived, got %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected original to be moved, got %v", err)
+	}
+}
+
+func TestResolveStyle_AcceptsNamesSlugsAndUUIDs(t *testing.T) {
+	cases := map[string]string{
+		"cinematic":                            "a5632c7c-ddbb-4e2f-ba34-8456ab3ac436",
+		"Pro B&W Photography":                  "22a9a7d2-2166-4d86-80ff-22e2643adbcf",
+		"pro_bw-photography":                   "22a9a7d2-2166-4d86-80ff-22e2643adbcf",
+		"3d-render":                            "debdf72a-91a4-467b-bf61-cc02bdeb69c6",
+		"111DC692-D470-4EEC-B791-3475ABAC4C46": "111dc692-d470-4eec-b791-3475abac4c46",
+		// Unlisted UUIDs pass through so new styles can be used right away.
+		"00000000-1111-2222-3333-444444444444": "00000000-1111-2222-3333-444444444444",
+		"":                                     "",
+	}
+	for name, want := range cases {
+		got, err := domain.ResolveStyle(name)
+		if err != nil {
+			t.Errorf("ResolveStyle(%q): unexpected error: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ResolveStyle(%q): expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestResolveStyle_RejectsUnknownName(t *testing.T) {
+	_, err := domain.ResolveStyle("watercolour")
+	var unknown *domain.UnknownStyleError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownStyleError, got %v", err)
+	}
+	if unknown.Name != "watercolour" {
+		t.Errorf("expected name %q, got %q", "watercolour", unknown.Name)
+	}
+}
+
+func TestPrintStyles_ListsCatalogAndModelSupport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printStyles(&buf, domain.Styles(), "b24e16ff-06e3-43eb-8d33-4416c2d75876"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "cinematic") || !strings.Contains(out, "a5632c7c-ddbb-4e2f-ba34-8456ab3ac436") {
+		t.Errorf("expected cinematic style in output, got:\n%s", out)
+	}
+	if !strings.Contains(out, "not documented to accept style UUIDs") {
+		t.Errorf("expected unsupported model note, got:\n%s", out)
+	}
+	if w := styleWarning("de7d3faf-762f-48e0-b3b7-9d0ac3a3fcf3", "a5632c7c-ddbb-4e2f-ba34-8456ab3ac436"); w != "" {
+		t.Errorf("expected no warning for Phoenix, got %q", w)
+	}
+}
+
+func TestKinds_ConfigOverridesAndAddsKinds(t *testing.T) {
+	kinds, err := domain.Kinds(map[string]string{
+		"icon.model":         "model-mine",
+		"icon.size":          "256x256",
+		"poster.size":        "768x1024",
+		"poster.description": "Portrait poster",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	icon, err := domain.FindKind(kinds, "icon")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if icon.Settings["model-id"] != "model-mine" || icon.Settings["width"] != "256" || icon.Settings["alchemy"] != "true" {
+		t.Errorf("expected overridden icon settings, got %v", icon.Settings)
+	}
+	poster, err := domain.FindKind(kinds, "Poster")
+	if err != nil || poster.Description != "Portrait poster" || poster.Settings["height"] != "1024" {
+		t.Errorf("expected the configured poster kind, got %+v, %v", poster, err)
+	}
+	builtin, _ := domain.Kinds(nil)
+	if icon, _ := domain.FindKind(builtin, "icon"); icon.Settings["width"] != "512" {
+		t.Errorf("expected overrides to leave the built-in kinds alone, got %v", icon.Settings)
+	}
+	if _, err := domain.FindKind(builtin, "poster"); err == nil || !strings.Contains(err.Error(), "seamless-texture") {
+		t.Errorf("expected unknown kind error listing the kinds, got %v", err)
+	}
+	if _, err := domain.Kinds(map[string]string{"icon": "x"}); err == nil {
+		t.Error("expected a key without a setting to be rejected")
+	}
+}
+
+func TestApplyKind_FillsFlagsTheCommandLineLeavesOut(t *testing.T) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.String("kind", "", "")
+	width := fs.Int("width", 0, "")
+	height := fs.Int("height", 0, "")
+	alchemy := fs.Bool("alchemy", false, "")
+	style := fs.String("style", "", "")
+	fs.String("style-uuid", "", "")
+	fs.String("model-id", "", "")
+	negative := fs.String("negative-prompt", "", "")
+	if err := fs.Parse([]string{"--kind", "icon", "--width", "640", "--style-uuid", "s-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := applyKind(fs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *width != 640 || *height != 512 || !*alchemy || !strings.Contains(*negative, "watermark") {
+		t.Errorf("expected kind defaults under the explicit width, got %dx%d alchemy=%v negative=%q", *width, *height, *alchemy, *negative)
+	}
+	if *style != "" {
+		t.Errorf("expected --style-uuid to replace the kind's style, got %q", *style)
+	}
+}
+
+func TestCharacterApply_FillsWhatTheRequestLeavesOut(t *testing.T) {
+	c := domain.Character{
+		Name:         "mira",
+		Description:  "Mira, a red-haired courier",
+		InitImageID:  "init-1",
+		InitStrength: 0.4,
+		StyleUUID:    "style-1",
+		Seed:         42,
+		Elements:     []domain.GenerationElement{{ID: "el-1", Weight: 0.7}, {ID: "el-2", Weight: 1}},
+	}
+	meta := domain.GenerationMetadata{
+		Prompt:    "riding through rain",
+		StyleUUID: "style-mine",
+		Elements:  []domain.GenerationElement{{ID: "el-2", Weight: 0.3}},
+		Tags:      []string{"series"},
+	}
+
+	got := c.Apply(meta)
+
+	if got.Prompt != "Mira, a red-haired courier, riding through rain" {
+		t.Errorf("unexpected prompt %q", got.Prompt)
+	}
+	if got.InitImageID != "init-1" || got.InitStrength != 0.4 || got.Seed != 42 || got.StyleUUID != "style-mine" {
+		t.Errorf("expected the reference under the request's own style, got %+v", got)
+	}
+	if len(got.Elements) != 2 || got.Elements[0].Weight != 0.3 || got.Elements[1].ID != "el-1" {
+		t.Errorf("expected the request's Element weight to win, got %+v", got.Elements)
+	}
+	if strings.Join(got.Tags, ",") != "series,character:mira" || len(meta.Tags) != 1 {
+		t.Errorf("expected a character tag without changing the input, got %v", got.Tags)
+	}
+	if again := c.Apply(got); again.Prompt != got.Prompt || len(again.Tags) != 2 {
+		t.Errorf("expected applying twice to change nothing, got %+v", again)
+	}
+}
+
+func TestParseElement_AcceptsOptionalWeight(t *testing.T) {
+	if e, err := domain.ParseElement("el-1"); err != nil || e.Weight != domain.DefaultElementWeight {
+		t.Errorf("expected the default weight, got %+v, %v", e, err)
+	}
+	if e, err := domain.ParseElement("el-1:-0.5"); err != nil || e.ID != "el-1" || e.Weight != -0.5 {
+		t.Errorf("expected weight -0.5, got %+v, %v", e, err)
+	}
+	for _, spec := range []string{"", ":1", "el-1:heavy"} {
+		if _, err := domain.ParseElement(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestWeightSweep_VariesOnlyTheElementWeight(t *testing.T) {
+	sweep := domain.WeightSweep{ElementID: "el-1", From: 0, To: 1, Steps: 4}
+	if err := sweep.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	base := domain.GenerationRequest{NumImages: 1, Metadata: domain.GenerationMetadata{
+		Name:     "mira",
+		Prompt:   "a courier",
+		Seed:     42,
+		Tags:     []string{"series"},
+		Elements: []domain.GenerationElement{{ID: "el-2", Weight: 0.8}, {ID: "el-1", Weight: 0.3}},
+	}}
+
+	requests := sweep.Requests(base)
+
+	labels := []string{"0", "0.333", "0.667", "1"}
+	if len(requests) != len(labels) {
+		t.Fatalf("expected %d requests, got %d", len(labels), len(requests))
+	}
+	for i, req := range requests {
+		meta := req.Metadata
+		if meta.Prompt != "a courier" || meta.Seed != 42 {
+			t.Errorf("step %d: expected prompt and seed kept, got %+v", i, meta)
+		}
+		if len(meta.Elements) != 2 || meta.Elements[0].ID != "el-2" || domain.SweepLabel(meta.Elements[1].Weight) != labels[i] {
+			t.Errorf("step %d: expected el-1 at %s, got %+v", i, labels[i], meta.Elements)
+		}
+		if meta.Name != "mira-w"+labels[i] || strings.Join(meta.Tags, ",") != "series,weight:"+labels[i] {
+			t.Errorf("step %d: unexpected name %q and tags %v", i, meta.Name, meta.Tags)
+		}
+	}
+	if len(base.Metadata.Tags) != 1 || base.Metadata.Elements[1].Weight != 0.3 {
+		t.Error("expected the base request to be left alone")
+	}
+	for _, bad := range []domain.WeightSweep{
+		{From: 0, To: 1, Steps: 3},
+		{ElementID: "el-1", From: 0, To: 1, Steps: 1},
+		{ElementID: "el-1", From: 0, To: 1, Steps: domain.MaxSweepSteps + 1},
+		{ElementID: "el-1", From: 0.5, To: 0.5, Steps: 3},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestUsageSince_CountsTodayAsOneOfTheDays(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	since, err := usageSince("", 7, now)
+	if err != nil {
+		t.Fatalf("usageSince: %v", err)
+	}
+	if want := time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC); !since.Equal(want) {
+		t.Errorf("since = %v, want %v", since, want)
+	}
+	if _, err := usageSince("", 0, now); err == nil {
+		t.Error("expected an error for --days 0")
+	}
+	if _, err := usageSince("10/05/2024", 7, now); err == nil {
+		t.Error("expected an error for a malformed --since")
+	}
+}
+
+func TestPrintUsageReport_MarksEstimatedDaysAndTotals(t *testing.T) {
+	var report domain.UsageReport
+	report.Add("2024-05-02", 4, 48, true)
+	report.Add("2024-05-01", 2, 30, false)
+	report.Add("2024-05-01", 1, 15, false)
+	var buf bytes.Buffer
+	if err := printUsageReport(&buf, report); err != nil {
+		t.Fatalf("printUsageReport: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5:\n%s", len(lines), buf.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "2024-05-01 2 3 45" {
+		t.Errorf("first day = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "2024-05-02 1 4 48*" {
+		t.Errorf("second day = %q", lines[2])
+	}
+	if fields := strings.Fields(lines[3]); strings.Join(fields, " ") != "total 3 7 93*" {
+		t.Errorf("total = %q", lines[3])
+	}
+	if !strings.Contains(lines[4], "1 generations priced") {
+		t.Errorf("missing estimate note: %q", lines[4])
+	}
+}
+
+func TestWriteBudgetCSV_WritesHeaderAndOneRowPerGroup(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeBudgetCSV(&buf, []domain.BudgetLine{
+		{Month: "2026-05", Group: "acme, inc", Generations: 3, Tokens: 90, Uncosted: 1},
+	})
+	if err != nil {
+		t.Fatalf("writeBudgetCSV: %v", err)
+	}
+	want := "month,group,generations,tokens,uncosted_generations\n2026-05,\"acme, inc\",3,90,1\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteSharePage_EscapesPromptAndListsImages(t *testing.T) {
+	bundle := newShareBundle(domain.GenerationDetail{ID: "gen-1", Prompt: "a <b>bold</b> fox", Width: 512, Height: 768, Seed: 42}, false)
+	var buf bytes.Buffer
+	if err := writeSharePage(&buf, bundle, []string{"gen-1_1.png", "data:image/png;base64,AAAA"}); err != nil {
+		t.Fatalf("writeSharePage: %v", err)
+	}
+	page := buf.String()
+	for _, want := range []string{`src="gen-1_1.png"`, `src="data:image/png;base64,AAAA"`, "a &lt;b&gt;bold&lt;/b&gt; fox", "512×768", "<dd>42</dd>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page is missing %q:\n%s", want, page)
+		}
+	}
+	if err := writeSharePage(io.Discard, bundle, []string{"javascript:alert(1)"}); err == nil {
+		t.Error("expected an error for a source that is neither a file name nor an image data URL")
+	}
+}
+
+func TestNewShareBundle_LeavesPromptsOutOnRequest(t *testing.T) {
+	bundle := newShareBundle(domain.GenerationDetail{ID: "gen-1", Prompt: "secret", NegativePrompt: "blurry"}, true)
+	if bundle.Prompt != "" || bundle.NegativePrompt != "" {
+		t.Errorf("expected no prompts, got %+v", bundle)
+	}
+}
+
+func TestParseRecipe_ReadsShareBundle(t *testing.T) {
+	recipe, err := domain.ParseRecipe([]byte(`{"generation_id":"gen-1","prompt":"a fox","model_id":"m-1","width":512,"height":768,"seed":42,"preset_style":"Cinematic","alchemy":true,"elements":[{"id":"el-1","weight":0.5}],"images":["gen-1_1.png"]}`))
+	if err != nil {
+		t.Fatalf("ParseRecipe: %v", err)
+	}
+	want := map[string]string{"prompt": "a fox", "model-id": "m-1", "width": "512", "height": "768", "seed": "42", "alchemy": "true"}
+	for name, value := range want {
+		if recipe.Settings[name] != value {
+			t.Errorf("%s = %q, want %q", name, recipe.Settings[name], value)
+		}
+	}
+	if recipe.Settings["style-uuid"] == "" {
+		t.Error("expected the preset style to be resolved to a style UUID")
+	}
+	if len(recipe.Elements) != 1 || recipe.Elements[0].Weight != 0.5 {
+		t.Errorf("unexpected elements %+v", recipe.Elements)
+	}
+}
+
+func TestParseRecipe_ReadsParametersTextAndRefusesRedactedPrompts(t *testing.T) {
+	recipe, err := domain.ParseRecipe([]byte("a fox\nNegative prompt: blurry\nSteps: 30, Seed: 7, Size: 512x512"))
+	if err != nil {
+		t.Fatalf("ParseRecipe: %v", err)
+	}
+	if recipe.Settings["negative-prompt"] != "blurry" || recipe.Settings["seed"] != "7" {
+		t.Errorf("unexpected settings %v", recipe.Settings)
+	}
+	redacted := domain.Redactor{Prompts: true}.Prompt("a fox")
+	if _, err := domain.ParseRecipe([]byte(`{"prompt":"` + redacted + `"}`)); err == nil {
+		t.Error("expected a redacted prompt to be refused")
+	}
+}
+
+func TestApplyRecipe_LeavesExplicitFlagsAlone(t *testing.T) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	prompt := fs.String("prompt", "", "")
+	seed := fs.Int("seed", 0, "")
+	width := fs.Int("width", 0, "")
+	if err := fs.Parse([]string{"--seed", "9"}); err != nil {
+		t.Fatal(err)
+	}
+	recipe := domain.Recipe{Settings: map[string]string{"prompt": "a fox", "seed": "42", "width": "768", "ultra": "true"}}
+	if err := applyRecipe(fs, recipe); err != nil {
+		t.Fatalf("applyRecipe: %v", err)
+	}
+	if *prompt != "a fox" || *seed != 9 || *width != 768 {
+		t.Errorf("got prompt %q, seed %d, width %d", *prompt, *seed, *width)
+	}
+}
+
+func TestParamsFlag_SendsJSONValuesAsTheyAreAndTheRestAsStrings(t *testing.T) {
+	params := paramsFlag{}
+	for _, spec := range []string{"steps=30", "enhance=true", "transparency=foreground_only", `controlnets=[{"weight":0.5}]`, "note= two words"} {
+		if err := params.Set(spec); err != nil {
+			t.Fatalf("Set(%q): %v", spec, err)
+		}
+	}
+	want := map[string]string{
+		"steps":        "30",
+		"enhance":      "true",
+		"transparency": `"foreground_only"`,
+		"controlnets":  `[{"weight":0.5}]`,
+		"note":         `" two words"`,
+	}
+	for key, value := range want {
+		if string(params[key]) != value {
+			t.Errorf("%s = %s, want %s", key, params[key], value)
+		}
+	}
+	for _, spec := range []string{"novalue", "=3", "two words=1"} {
+		if err := params.Set(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestCheckAPI_ReportsTokenNetworkAndClock(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	env := doctorEnv{
+		token:       func() (string, error) { return "key", nil },
+		tokenSource: "LEONARDO_API_TOKEN",
+		probe: func(string) (domain.RawResponse, error) {
+			header := http.Header{}
+			header.Set("Date", now.Add(-2*time.Minute).Format(http.TimeFormat))
+			return domain.RawResponse{StatusCode: 200, Header: header}, nil
+		},
+	}
+
+	checks := checkAPI(env, func() time.Time { return now })
+
+	if len(checks) != 3 {
+		t.Fatalf("expected token, network and clock checks, got %+v", checks)
+	}
+	if checks[0].Status != domain.CheckPass || checks[1].Status != domain.CheckPass {
+		t.Errorf("expected token and network to pass, got %+v", checks[:2])
+	}
+	if checks[2].Status != domain.CheckWarn || !strings.Contains(checks[2].Detail, "2m0s ahead of") {
+		t.Errorf("expected a clock warning, got %+v", checks[2])
+	}
+}
+
+func TestCheckAPI_FailsOnRejectedTokenAndUnreachableAPI(t *testing.T) {
+	rejected := doctorEnv{
+		token: func() (string, error) { return "key", nil },
+		probe: func(string) (domain.RawResponse, error) {
+			return domain.RawResponse{StatusCode: 401}, &domain.APIError{StatusCode: 401, Body: []byte(`{"error":"invalid token"}`)}
+		},
+	}
+	checks := checkAPI(rejected, time.Now)
+	if checks[0].Status != domain.CheckFail || checks[1].Status != domain.CheckPass {
+		t.Errorf("expected a failed token on a reachable API, got %+v", checks)
+	}
+
+	offline := doctorEnv{
+		token: func() (string, error) { return "key", nil },
+		probe: func(string) (domain.RawResponse, error) {
+			return domain.RawResponse{}, errors.New("dial tcp: no route to host")
+		},
+	}
+	checks = checkAPI(offline, time.Now)
+	if len(checks) != 2 || checks[1].Status != domain.CheckFail || domain.Healthy(checks) {
+		t.Errorf("expected the network check to fail, got %+v", checks)
+	}
+
+	missing := doctorEnv{token: func() (string, error) { return "", errors.New("LEONARDO_API_TOKEN is not set") }}
+	if checks = checkAPI(missing, time.Now); len(checks) != 1 || checks[0].Status != domain.CheckFail {
+		t.Errorf("expected only a failed token check, got %+v", checks)
+	}
+}
+
+func TestCheckCredentialStore_WarnsWhenOthersCanRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if check := checkCredentialStore(path, "linux"); check.Status != domain.CheckPass {
+		t.Errorf("expected a private file to pass, got %+v", check)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if check := checkCredentialStore(path, "linux"); check.Status != domain.CheckWarn {
+		t.Errorf("expected a world-readable file to warn, got %+v", check)
+	}
+}
+
+func TestConcurrencyLimit_IncreasesAdditivelyAndHalvesOnRateLimit(t *testing.T) {
+	limit := domain.NewConcurrencyLimit(4)
+	var seen []int
+	for i := 0; i < 10; i++ {
+		limit.Succeeded()
+		seen = append(seen, limit.Limit())
+	}
+	// One success raises 1 to 2, two more raise it to 3, three more to 4.
+	if want := "[2 2 3 3 3 4 4 4 4 4]"; fmt.Sprint(seen) != want {
+		t.Errorf("expected limits %s, got %v", want, seen)
+	}
+	if !limit.RateLimited() || limit.Limit() != 2 {
+		t.Errorf("expected a rate limit to halve 4 to 2, got %d", limit.Limit())
+	}
+	limit.RateLimited()
+	if limit.RateLimited() || limit.Limit() != 1 {
+		t.Errorf("expected the limit to stop at 1, got %d", limit.Limit())
+	}
+}
+
+func TestParseStartTime_TakesNextClockTimeOrTimestamp(t *testing.T) {
+	now := time.Date(2026, 6, 1, 22, 30, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"23:15":                time.Date(2026, 6, 1, 23, 15, 0, 0, time.UTC),
+		"02:00":                time.Date(2026, 6, 2, 2, 0, 0, 0, time.UTC),
+		"22:30":                time.Date(2026, 6, 2, 22, 30, 0, 0, time.UTC),
+		"2026-06-03T04:00:00Z": time.Date(2026, 6, 3, 4, 0, 0, 0, time.UTC),
+	}
+	for value, want := range cases {
+		got, err := parseStartTime(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%s: expected %s, got %s (%v)", value, want, got, err)
+		}
+	}
+	if _, err := parseStartTime("tonight", now); err == nil {
+		t.Error("expected an error for an unrecognised time")
+	}
+}
+
+func TestFolderWatcher_ReportsNewImagesOnceTheyStopChanging(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "old.png"), []byte("old"), 0644)
+	w, err := newFolderWatcher(dir, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fresh := filepath.Join(dir, "new.png")
+	os.WriteFile(fresh, []byte("part"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+	if ready, _ := w.scan(); len(ready) != 0 {
+		t.Fatalf("expected nothing ready on first sight, got %v", ready)
+	}
+	os.WriteFile(fresh, []byte("partial write"), 0644)
+	if ready, _ := w.scan(); len(ready) != 0 {
+		t.Fatalf("expected a growing file to wait, got %v", ready)
+	}
+	ready, _ := w.scan()
+	if len(ready) != 1 || ready[0] != fresh {
+		t.Fatalf("expected only %s once stable, got %v", fresh, ready)
+	}
+	if ready, _ := w.scan(); len(ready) != 0 || !w.settled() {
+		t.Errorf("expected the file to be reported once, got %v", ready)
+	}
+}
+
+func TestFolderWatcher_ProcessesExistingImagesWhenAsked(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.jpg"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(dir, "a.webp"), []byte("a"), 0644)
+	w, _ := newFolderWatcher(dir, true)
+	ready, _ := w.scan()
+	if len(ready) != 2 || filepath.Base(ready[0]) != "a.webp" {
+		t.Errorf("expected both existing images sorted by name, got %v", ready)
+	}
+}
+
+func TestRecordVariations_UpdatesExistingSidecarOrWritesOne(t *testing.T) {
+	tempDir := t.TempDir()
+	origWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting current working directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("changing working directory: %v", err)
+	}
+	defer os.Chdir(origWD)
+
+	vars := []domain.DownloadedVariation{{
+		Image:     1,
+		Variation: domain.ImageVariation{ID: "v1", Status: "COMPLETE", TransformType: "UPSCALE"},
+		Path:      filepath.Join("out", "gen-a_1_upscaled.png"),
+	}}
+	os.WriteFile("gen-a.json", []byte(`{"prompt":"a fox","generation_id":"gen-a"}`), 0644)
+	path, err := recordVariations("gen-a", "out", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Clean(path) != "gen-a.json" {
+		t.Errorf("expected the existing sidecar to be updated, got %s", path)
+	}
+	var got struct {
+		Prompt     string `json:"prompt"`
+		Variations []struct {
+			Image         int    `json:"image"`
+			TransformType string `json:"transform_type"`
+			File          string `json:"file"`
+		} `json:"variations"`
+	}
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("parsing sidecar: %v", err)
+	}
+	if got.Prompt != "a fox" || len(got.Variations) != 1 || got.Variations[0].File != "gen-a_1_upscaled.png" || got.Variations[0].TransformType != "UPSCALE" {
+		t.Errorf("unexpected sidecar: %s", data)
+	}
+
+	os.Mkdir("out", 0755)
+	path, err = recordVariations("gen-b", "out", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join("out", "gen-b.json") {
+		t.Errorf("expected a new sidecar next to the images, got %s", path)
+	}
+}
+
+func TestEnsureDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	if err := ensureDiskSpace(dir, 0); err != nil {
+		t.Errorf("expected nothing needed to pass, got %v", err)
+	}
+	if err := ensureDiskSpace(filepath.Join(dir, "new", "deeper"), 1); err != nil {
+		t.Errorf("expected a missing directory to be checked via its parent, got %v", err)
+	}
+	if _, ok := freeDiskSpace(dir); !ok {
+		t.Skip("free disk space is not available on this platform")
+	}
+	err := ensureDiskSpace(dir, 1<<62)
+	if err == nil || !strings.Contains(err.Error(), "not enough disk space") {
+		t.Errorf("expected a disk space error, got %v", err)
+	}
+}
+
+func TestWebhookSignatureRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payload.json")
+	os.WriteFile(path, []byte(`{"type":"image_generation.complete","data":{"object":{"id":"gen-1"}}}`+"\n"), 0644)
+	payload, err := readPayload(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signature := domain.WebhookSignature("s3cret", payload)
+
+	for _, sig := range []string{signature, "sha256=" + signature, strings.ToUpper(signature)} {
+		if err := domain.VerifyWebhookSignature("s3cret", payload, sig); err != nil {
+			t.Errorf("expected %q to verify, got %v", sig, err)
+		}
+	}
+	if err := domain.VerifyWebhookSignature("other", payload, signature); !errors.Is(err, domain.ErrWebhookSignatureMismatch) {
+		t.Errorf("expected a mismatch with the wrong secret, got %v", err)
+	}
+	trimmed := bytes.TrimSpace(payload)
+	if err := domain.VerifyWebhookSignature("s3cret", trimmed, signature); !errors.Is(err, domain.ErrWebhookSignatureMismatch) {
+		t.Errorf("expected a mismatch with an altered body, got %v", err)
+	}
+	if err := domain.VerifyWebhookSignature("s3cret", payload, "not-hex"); !errors.Is(err, domain.ErrWebhookSignatureMismatch) {
+		t.Errorf("expected a mismatch with a malformed signature, got %v", err)
+	}
+}
+
+func TestCheckPromptLength_WarnsOrTruncatesAtSentence(t *testing.T) {
+	var buf bytes.Buffer
+	original := stderr
+	stderr = &buf
+	defer func() { stderr = original }()
+	prompt := "A lighthouse on a cliff at dusk. Waves crash below in version 1.5 style. " + strings.Repeat("Gulls circle overhead, ", 30)
+
+	if got := checkPromptLength("a fox", 75, true); got != "a fox" || buf.Len() != 0 {
+		t.Errorf("expected a short prompt to pass untouched, got %q with output %q", got, buf.String())
+	}
+	if got := checkPromptLength(prompt, 20, false); got != prompt || !strings.Contains(buf.String(), "Warning: prompt is about") {
+		t.Errorf("expected a warning and the prompt unchanged, got %q with output %q", got, buf.String())
+	}
+	want := "A lighthouse on a cliff at dusk. Waves crash below in version 1.5 style."
+	if got := checkPromptLength(prompt, 20, true); got != want {
+		t.Errorf("expected the prompt cut after the last whole sentence, got %q", got)
+	}
+	if got := domain.TruncatePrompt(prompt, 5); got != "A lighthouse on a" {
+		t.Errorf("expected a word boundary cut when no sentence fits, got %q", got)
+	}
+}
+
+func TestRedactArgs_HidesSecretsAndRedactedPrompts(t *testing.T) {
+	r := domain.Redactor{}
+	r.AddSecret("tok-123")
+	args := []string{"webhook", "verify", "--secret", "s3cret", "--signature=abc", "--payload", "body.json", "--verbose", "tok-123"}
+
+	got := domain.RedactArgs(args, r)
+
+	want := []string{"webhook", "verify", "--secret", domain.RedactedArg, "--signature=abc", "--payload", "body.json", "--verbose", domain.RedactedArg}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	entry := domain.HistoryEntry{Number: 7, Args: got}
+	if err := entry.Replayable(); err == nil {
+		t.Error("expected an invocation with redacted values not to be replayable")
+	}
+
+	r.Prompts = true
+	got = domain.RedactArgs([]string{"create", "--prompt=a red fox", "--negative-prompt", "blur"}, r)
+	if !domain.IsRedactedPrompt(strings.TrimPrefix(got[1], "--prompt=")) || !domain.IsRedactedPrompt(got[3]) {
+		t.Errorf("expected prompts redacted, got %v", got)
+	}
+}
+
+func TestHistoryEntry_CommandLineQuotesArguments(t *testing.T) {
+	entry := domain.HistoryEntry{Args: []string{"create", "--prompt", "a red fox", "--width", "1024"}}
+	if got := entry.CommandLine(); got != `create --prompt "a red fox" --width 1024` {
+		t.Errorf("unexpected command line %s", got)
+	}
+	if err := entry.Replayable(); err != nil {
+		t.Errorf("expected a plain invocation to be replayable, got %v", err)
+	}
+}
+
+func TestParseVariationKinds(t *testing.T) {
+	kinds, err := domain.ParseVariationKinds(" Upscale,nobg,upscale ,unzoom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kinds) != 3 || kinds[0] != domain.VariationUpscale || kinds[0].FileSuffix() != "upscaled" || kinds[2] != domain.VariationUnzoom {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+	if _, err := domain.ParseVariationKinds("upscale,sharpen"); err == nil || !strings.Contains(err.Error(), "supported: upscale, nobg, unzoom") {
+		t.Errorf("expected an error listing the supported types, got %v", err)
+	}
+}
+
+func TestPricingGrid_SkipsUnsupportedSizesAndAlchemy(t *testing.T) {
+	flux := domain.PlatformModel{ID: "flux", SDVersion: "FLUX_DEV"}
+	sizes := []domain.Size{{Width: 512, Height: 512}, {Width: 2048, Height: 2048}}
+
+	queries, skipped := domain.PricingGrid(flux, false, sizes, []int{1, 4})
+
+	if len(skipped) != 1 || skipped[0].String() != "2048x2048" {
+		t.Errorf("expected 2048x2048 to be skipped, got %v", skipped)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 queries without Alchemy, got %+v", queries)
+	}
+	for _, q := range queries {
+		if q.Alchemy || q.Width != 512 {
+			t.Errorf("unexpected query %+v", q)
+		}
+	}
+	sdxl := domain.PlatformModel{ID: "sdxl", SDVersion: "SDXL_1_0"}
+	queries, _ = domain.PricingGrid(sdxl, false, sizes[:1], []int{1})
+	if len(queries) != 2 || !queries[1].Alchemy || !queries[1].SDXL() {
+		t.Errorf("expected SDXL queries with and without Alchemy, got %+v", queries)
+	}
+}
+
+func TestPrintPricingTable_SortsByCostPerImage(t *testing.T) {
+	quotes := []domain.PriceQuote{
+		{PriceQuery: domain.PriceQuery{Width: 1024, Height: 1024, NumImages: 1}, Cost: 12},
+		{PriceQuery: domain.PriceQuery{Width: 1024, Height: 1024, NumImages: 4, Alchemy: true}, Cost: 30},
+		{PriceQuery: domain.PriceQuery{Width: 512, Height: 512, NumImages: 2}, Cost: 9},
+	}
+	domain.SortQuotesByCost(quotes)
+	var out bytes.Buffer
+	if err := printPricingTable(&out, quotes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := [][]string{
+		{"SIZE", "ALCHEMY", "IMAGES", "TOKENS", "PER", "IMAGE"},
+		{"512x512", "no", "2", "9", "4.5"},
+		{"1024x1024", "yes", "4", "30", "7.5"},
+		{"1024x1024", "no", "1", "12", "12"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+	for i, line := range lines {
+		if got := strings.Fields(line); strings.Join(got, " ") != strings.Join(want[i], " ") {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	if size, err := domain.ParseSize(" 1024X768 "); err != nil || size != (domain.Size{Width: 1024, Height: 768}) {
+		t.Errorf("got %v, %v", size, err)
+	}
+	for _, bad := range []string{"1024", "x768", "0x512", "axb"} {
+		if _, err := domain.ParseSize(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestWaitOptions_BacksOffWithJitterAndHonoursAdvice(t *testing.T) {
+	opts := domain.WaitOptions{Interval: 2 * time.Second}
+	var got []time.Duration
+	var interval time.Duration
+	for i := 0; i < 6; i++ {
+		interval = opts.NextInterval(interval)
+		got = append(got, interval)
+	}
+	want := []time.Duration{2 * time.Second, 3 * time.Second, 4500 * time.Millisecond, 6750 * time.Millisecond, 8 * time.Second, 8 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("intervals %v, want %v", got, want)
+		}
+	}
+	if d := opts.Delay(10*time.Second, 0, 0); d != 8*time.Second {
+		t.Errorf("expected the lowest jitter to shorten 10s to 8s, got %s", d)
+	}
+	if d := opts.Delay(10*time.Second, 0, 0.5); d != 10*time.Second {
+		t.Errorf("expected no jitter at 0.5, got %s", d)
+	}
+	if d := opts.Delay(time.Second, 30*time.Second, 0.9); d != 30*time.Second {
+		t.Errorf("expected the server's advice to win, got %s", d)
+	}
+	if d := opts.Delay(time.Second, time.Hour, 0.9); d != 5*time.Minute {
+		t.Errorf("expected advice to be capped, got %s", d)
+	}
+}
+
+func TestPrintVersion_ShowsBuildAndTargetedAPI(t *testing.T) {
+	var out bytes.Buffer
+	printVersion(&out, buildInfo{Version: "v1.4.0", Commit: "abc123", Modified: true, GoVersion: "go1.20"})
+	text := out.String()
+	for _, want := range []string{"leonardo v1.4.0\n", "abc123 (modified)", "built:   unknown", "API:     v1 (https://cloud.leonardo.ai/api/rest/v1)", "  POST /generations\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestCompatible_RequiresEveryCheckToPass(t *testing.T) {
+	ok := domain.EndpointCheck{Endpoint: "GET /me"}
+	failed := domain.EndpointCheck{Endpoint: "GET /platformModels", Err: errors.New("status 500")}
+	if !domain.Compatible([]domain.EndpointCheck{ok}) {
+		t.Error("expected a passing check to be compatible")
+	}
+	if domain.Compatible([]domain.EndpointCheck{ok, failed}) || domain.Compatible(nil) {
+		t.Error("expected a failed or missing check to be incompatible")
+	}
+}
+
+// writePlugin creates an executable shell script called leonardo-<name>
+// in dir.
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, pluginPrefix+name), []byte("#!/bin/sh\n"+script), mode); err != nil {
+		t.Fatalf("writing plugin: %v", err)
+	}
+}
+
+func TestFindPlugins_ListsExecutablesNotShadowedByBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "hello", "exit 0\n", 0755)
+	writePlugin(t, dir, "create", "exit 0\n", 0755)
+	writePlugin(t, dir, "notes", "exit 0\n", 0644)
+	t.Setenv("PATH", dir)
+
+	if got := strings.Join(findPlugins(), ","); got != "hello" {
+		t.Errorf("expected only the hello plugin, got %q", got)
+	}
+	if _, ok := findPlugin("create"); ok {
+		t.Error("expected a built-in command to win over a plugin")
+	}
+	if _, ok := findPlugin("../hello"); ok {
+		t.Error("expected names with path separators to be rejected")
+	}
+}
+
+func TestRunPlugin_PassesArgsEnvironmentAndExitCode(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	writePlugin(t, dir, "echo", `printf '%s|%s|%s|%s' "$1" "$LEONARDO_API_TOKEN" "$LEONARDO_HOME" "$LEONARDO_VERBOSE" > "$OUT"; exit 3`+"\n", 0755)
+	t.Setenv("PATH", dir)
+	t.Setenv("OUT", out)
+	t.Setenv("LEONARDO_API_TOKEN", "plugin-token")
+	t.Setenv("LEONARDO_HOME", filepath.Join(dir, "state"))
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(dir, "accounts.json")))
+	path, ok := findPlugin("echo")
+	if !ok {
+		t.Fatal("expected the plugin to be found")
+	}
+
+	code := runPlugin("echo", path, []string{"arg one"}, globalOptions{verbose: true}, accounts)
+
+	if code != 3 {
+		t.Errorf("expected the plugin's exit code 3, got %d", code)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("reading plugin output: %v", err)
+	}
+	if want := "arg one|plugin-token|" + filepath.Join(dir, "state") + "|true"; string(data) != want {
+		t.Errorf("expected %q, got %q", want, data)
+	}
+}
+
+func TestExtractGlobalFlags_TakesFormatTemplate(t *testing.T) {
+	opts, rest := extractGlobalFlags([]string{"status", "--format", "{{.GenerationID}} {{.Status}}", "--id", "abc"})
+	if opts.format != "{{.GenerationID}} {{.Status}}" {
+		t.Errorf("unexpected format %q", opts.format)
+	}
+	if strings.Join(rest, " ") != "status --id abc" {
+		t.Errorf("expected remaining args %q, got %q", "status --id abc", strings.Join(rest, " "))
+	}
+}
+
+func TestExtractGlobalFlags_TakesTimeout(t *testing.T) {
+	t.Setenv("LEONARDO_TIMEOUT", "10m")
+	opts, rest := extractGlobalFlags([]string{"create", "--timeout", "90s", "--prompt", "x"})
+	if opts.timeout != "90s" {
+		t.Errorf("expected timeout 90s, got %q", opts.timeout)
+	}
+	if strings.Join(rest, " ") != "create --prompt x" {
+		t.Errorf("expected remaining args %q, got %q", "create --prompt x", strings.Join(rest, " "))
+	}
+	if opts, _ := extractGlobalFlags([]string{"list"}); opts.timeout != "10m" {
+		t.Errorf("expected LEONARDO_TIMEOUT to apply, got %q", opts.timeout)
+	}
+}
+
+func TestLimitRun_TimesOutWithoutCountingAsInterrupt(t *testing.T) {
+	saved := runCtx
+	defer func() { runCtx, runTimeout = saved, 0 }()
+	limitRun(time.Millisecond)
+	defer cancelTimeout()
+	<-runCtx.Done()
+	if !timedOut() {
+		t.Error("expected the run to have timed out")
+	}
+	if interrupted() {
+		t.Error("a timeout must not be reported as an interrupt")
+	}
+}
+
+func TestWriteFormatted_RendersRecordsThroughTemplate(t *testing.T) {
+	tmpl, err := parseFormat(`{{.GenerationID}}\t{{.Status}}\t{{join "," .Images}}\t{{lower .Status}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out bytes.Buffer
+	record := generationOutput{GenerationID: "gen-1", Status: "COMPLETE", Images: []string{"a.png", "b.png"}}
+	if err := writeFormatted(&out, tmpl, record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "gen-1\tCOMPLETE\ta.png,b.png\tcomplete\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+
+	tmpl, _ = parseFormat("{{.PlanType}} {{json .APIPaidTokens}}")
+	out.Reset()
+	if err := writeFormatted(&out, tmpl, domain.UserInfo{APIPaidTokens: 5}); err != nil || out.String() != "pay-as-you-go 5\n" {
+		t.Errorf("expected methods and json to work, got %q, %v", out.String(), err)
+	}
+}
+
+func TestWriteFormatted_RejectsUnknownFields(t *testing.T) {
+	if _, err := parseFormat("{{.GenerationID"); err == nil {
+		t.Error("expected a malformed template to be rejected")
+	}
+	tmpl, err := parseFormat("{{.Nope}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeFormatted(io.Discard, tmpl, generationOutput{}); err == nil || !strings.Contains(err.Error(), "Nope") {
+		t.Errorf("expected an error naming the unknown field, got %v", err)
+	}
+}
+
+func TestQueryJSON_SelectsFieldsIndexesAndWildcards(t *testing.T) {
+	raw := []byte(`{"generations_by_pk":{"status":"COMPLETE","seed":42,"generated_images":[{"id":"img-1","url":"a.png"},{"id":"img-2","url":"b.png"}]}}`)
+	cases := map[string]string{
+		"generations_by_pk.generated_images[0].id":   "img-1",
+		".generations_by_pk.generated_images[-1].id": "img-2",
+		"generations_by_pk.seed":                     "42",
+		"generations_by_pk.generated_images.#":       "2",
+		"generations_by_pk.generated_images[*].url":  "[\n  \"a.png\",\n  \"b.png\"\n]",
+	}
+	for path, want := range cases {
+		v, err := queryJSON(raw, path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+			continue
+		}
+		if got, _ := formatQueryResult(v); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestQueryJSON_ReportsWhereThePathStopsMatching(t *testing.T) {
+	raw := []byte(`{"generations_by_pk":{"generated_images":[{"id":"img-1"}]}}`)
+	cases := map[string]string{
+		"generations_by_pk.nope":                `no field "nope" at generations_by_pk`,
+		"generations_by_pk.generated_images[3]": "index 3 out of range at generations_by_pk.generated_images (length 1)",
+		"generations_by_pk.generated_images.id": "generations_by_pk.generated_images is not an object",
+		"generations_by_pk.generated_images[x]": "is not a number",
+		"generations_by_pk..generated_images":   "empty field name",
+		"generations_by_pk.generated_images[0":  "unclosed [",
+	}
+	for path, want := range cases {
+		if _, err := queryJSON(raw, path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", path, want, err)
+		}
+	}
+}
+
+func TestReportError_WritesStructuredJSONInJSONMode(t *testing.T) {
+	var buf bytes.Buffer
+	original := stderr
+	stderr = &buf
+	defer func() { stderr = original; jsonErrors = false }()
+	err := fmt.Errorf("creating: %w", &domain.APIError{StatusCode: 429, RequestID: "req-9"})
+
+	reportError("Error creating generation", err)
+	if want := "Error creating generation: creating: API returned status 429 (request ID req-9)\n"; buf.String() != want {
+		t.Errorf("expected text %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	jsonErrors = true
+	reportError("Error creating generation", err)
+	var got errorOutput
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	want := errorOutput{Code: "rate_limit", Message: "Error creating generation: creating: API returned status 429 (request ID req-9)", HTTPStatus: 429, RequestID: "req-9"}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestErrorCode_NamesEachKindOfFailure(t *testing.T) {
+	cases := map[string]error{
+		"":                nil,
+		"interrupted":     fmt.Errorf("batch stopped: %w", context.Canceled),
+		"invalid_request": &domain.InvalidRequestError{Reason: "no prompt"},
+		"preflight":       &domain.PreflightError{},
+		"permanent":       &domain.APIError{StatusCode: 400},
+		"transient":       &domain.APIError{StatusCode: 503},
+		"error":           errors.New("disk full"),
+	}
+	for want, err := range cases {
+		if got := domain.ErrorCode(err); got != want {
+			t.Errorf("ErrorCode(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestCallStats_WarnsOnceWhenRateLimitRunsLow(t *testing.T) {
+	var log bytes.Buffer
+	s := &callStats{log: &log}
+	s.record(domain.CallMetric{RateLimit: domain.RateLimit{Limit: 100, Remaining: 50}})
+	if log.Len() != 0 {
+		t.Errorf("expected no warning with half the allowance left, got %q", log.String())
+	}
+	s.record(domain.CallMetric{RateLimit: domain.RateLimit{Limit: 100, Remaining: 10}})
+	s.record(domain.CallMetric{RateLimit: domain.RateLimit{Limit: 100, Remaining: 9}})
+	s.record(domain.CallMetric{})
+	if got := strings.Count(log.String(), "rate limit nearly exhausted (10 of 100 requests left)"); got != 1 {
+		t.Errorf("expected one warning, got %q", log.String())
+	}
+	if limit, ok := s.latestRateLimit(); !ok || limit.Remaining != 9 {
+		t.Errorf("expected the latest reported limit, got %+v", limit)
+	}
+}
+
+func TestRateLimit_LowAndDescribe(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	if (domain.RateLimit{Limit: 5, Remaining: 2}).Low() || !(domain.RateLimit{Limit: 5, Remaining: 1}).Low() {
+		t.Error("expected a small limit to be low only at its last request")
+	}
+	if (domain.RateLimit{}).Low() {
+		t.Error("expected an unknown limit never to be low")
+	}
+	limit := domain.RateLimit{Limit: 100, Remaining: 7, Reset: now.Add(42 * time.Second)}
+	if got := limit.Describe(now); got != "7 of 100 requests left, resets in 42s" {
+		t.Errorf("unexpected description %q", got)
+	}
+}
+
+func TestPrintDownloadSummary_ListsEveryOutcomeAndTotals(t *testing.T) {
+	result := domain.DownloadResult{Outcomes: []domain.ImageDownload{
+		{Image: 1, Path: "gen_1.png", Outcome: domain.DownloadSaved},
+		{Image: 2, Path: "gen_2.png", Outcome: domain.DownloadFailed, Err: errors.New("downloading image 2: API returned status 403")},
+		{Image: 1, Variation: "upscaled", Path: "gen_1_upscaled.png", Outcome: domain.DownloadSkipped},
+	}}
+
+	var buf bytes.Buffer
+	printDownloadSummary(&buf, result)
+
+	out := buf.String()
+	for _, want := range []string{
+		"IMAGE", "gen_1.png", "saved",
+		"failed: downloading image 2: API returned status 403",
+		"1 upscaled", "skipped",
+		"1 saved, 1 skipped, 1 failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestPrintDownloadSummary_PlainNamesEveryCellOnOneLinePerRow(t *testing.T) {
+	plainOutput = true
+	defer func() { plainOutput = false }()
+	result := domain.DownloadResult{Outcomes: []domain.ImageDownload{
+		{Image: 1, Path: "gen_1.png", Outcome: domain.DownloadSaved},
+		{Image: 1, Variation: "upscaled", Path: "gen_1_upscaled.png", Outcome: domain.DownloadSkipped},
+	}}
+
+	var buf bytes.Buffer
+	printDownloadSummary(&buf, result)
+
+	want := "Image: 1, File: gen_1.png, Result: saved\n" +
+		"Image: 1 upscaled, File: gen_1_upscaled.png, Result: skipped\n" +
+		"1 saved, 1 skipped, 0 failed\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPlainTable_JoinsLabelsAndSkipsEmptyCells(t *testing.T) {
+	plainOutput = true
+	defer func() { plainOutput = false }()
+	var buf bytes.Buffer
+	tw := newTable(&buf)
+	fmt.Fprintf(tw, "Status:\t%s\n", "COMPLETE")
+	fmt.Fprintf(tw, "  %s\t%s\t%s\n", "create", "-", "Create a new image generation")
+	tw.Flush()
+
+	want := "Status: COMPLETE\n  create, Create a new image generation\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if got := headerLabel("PER IMAGE"); got != "Per image" {
+		t.Errorf("headerLabel(PER IMAGE) = %q", got)
+	}
+	if got := headerLabel("ID"); got != "ID" {
+		t.Errorf("headerLabel(ID) = %q", got)
+	}
+}
+
+func TestMakeDerivative_WritesAResizedJPEGNextToTheOriginal(t *testing.T) {
+	original := filepath.Join(t.TempDir(), "gen_1.png")
+	if err := imaging.SavePNG(original, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := parseDerivativeOptions("", 10, 80)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path, err := makeDerivative(original, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := strings.TrimSuffix(original, ".png") + "_web.jpg"; path != want {
+		t.Errorf("expected %s, got %s", want, path)
+	}
+	img, err := imaging.Load(path)
+	if err != nil {
+		t.Fatalf("expected a readable JPEG: %v", err)
+	}
+	if img.Bounds().Size() != image.Pt(10, 5) {
+		t.Errorf("expected 10x5, got %v", img.Bounds().Size())
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("expected the original to be kept: %v", err)
+	}
+}
+
+func TestParseDerivativeOptions_RejectsConflictingFlags(t *testing.T) {
+	if _, err := parseDerivativeOptions("512x512", 256, 0); err == nil {
+		t.Error("expected --resize with --max-dimension to be rejected")
+	}
+	if _, err := parseDerivativeOptions("", 0, 101); err == nil {
+		t.Error("expected --quality above 100 to be rejected")
+	}
+}
+
+func TestMakeDerivative_WritesAWatermarkedDraftAndKeepsTheOriginalClean(t *testing.T) {
+	original := filepath.Join(t.TempDir(), "gen_1.png")
+	if err := imaging.SavePNG(original, image.NewRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatal(err)
+	}
+	var opts derivativeOptions
+	var err error
+	if opts.watermark, err = parseWatermark("Draft", "", "Top-Left", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path, err := makeDerivative(original, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := strings.TrimSuffix(original, ".png") + "_draft.png"; path != want {
+		t.Errorf("expected %s, got %s", want, path)
+	}
+	draft, err := imaging.Load(path)
+	if err != nil {
+		t.Fatalf("expected a readable draft: %v", err)
+	}
+	if _, _, _, a := draft.At(3, 3).RGBA(); a == 0 {
+		t.Error("expected the watermark in the top left corner of the draft")
+	}
+	clean, err := imaging.Load(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := clean.At(3, 3).RGBA(); a != 0 {
+		t.Error("expected the original to stay clean")
+	}
+}
+
+func TestParseWatermark_RejectsTextWithAnImage(t *testing.T) {
+	if w, err := parseWatermark("", "", imaging.CornerCenter, 0.5); w != nil || err != nil {
+		t.Errorf("expected no watermark without text or image, got %+v (%v)", w, err)
+	}
+	if _, err := parseWatermark("Draft", "logo.png", imaging.CornerCenter, 0.5); err == nil {
+		t.Error("expected --watermark with --watermark-image to be rejected")
+	}
+	if _, err := parseWatermark("Draft", "", "middle", 0.5); err == nil {
+		t.Error("expected an unknown corner to be rejected")
+	}
+}
+
+func TestPrintKeyUsage_ListsEachKeyOnceAnyWasUsed(t *testing.T) {
+	var quiet bytes.Buffer
+	printKeyUsage(&quiet, []domain.KeyUsage{{Account: "work"}, {Account: "backup"}})
+	if quiet.Len() != 0 {
+		t.Errorf("expected nothing before any submission, got:\n%s", quiet.String())
+	}
+
+	var buf bytes.Buffer
+	printKeyUsage(&buf, []domain.KeyUsage{
+		{Account: "work", Requests: 3, Generations: 2, Cost: 16, OutOfTokens: true},
+		{Account: "backup", Requests: 4, Generations: 4, Cost: 32, RateLimited: 1},
+	})
+
+	out := buf.String()
+	for _, want := range []string{"ACCOUNT", "work", "out of tokens", "backup", "32"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected usage to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestSanitizeFileName_FollowsTheRulesOfEachFileSystem(t *testing.T) {
+	for _, tc := range []struct {
+		fs   domain.FileSystem
+		name string
+		want string
+	}{
+		{domain.FileSystemLinux, `a:b<c>?.`, `a:b<c>?.`},
+		{domain.FileSystemLinux, "a/b", "a_b"},
+		{domain.FileSystemMacOS, "a:b/c", "a_b_c"},
+		{domain.FileSystemWindows, `a:b*c|d. `, "a_b_c_d"},
+		{domain.FileSystemWindows, "con", "con_"},
+		{domain.FileSystemPortable, "LPT1.final", "LPT1_.final"},
+		{domain.FileSystemPortable, "console", "console"},
+		{domain.FileSystemPortable, "tab\there", "tab_here"},
+		{domain.FileSystemPortable, `a\b`, "a_b"},
+		{domain.FileSystemLinux, "..", "_"},
+		{domain.FileSystemWindows, "...", "_"},
+	} {
+		if got := tc.fs.SanitizeFileName(tc.name, 0); got != tc.want {
+			t.Errorf("%s: %q became %q, expected %q", tc.fs, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSanitizeFileName_LeavesRoomForASuffixWithoutSplittingCharacters(t *testing.T) {
+	name := strings.Repeat("é", 200) // 400 bytes
+
+	got := domain.FileSystemLinux.SanitizeFileName(name, 10)
+
+	if len(got) != 244 || !utf8.ValidString(got) {
+		t.Errorf("expected 122 whole characters (244 bytes), got %d bytes (valid UTF-8: %v)", len(got), utf8.ValidString(got))
+	}
+}
+
+func TestTargetFileSystem_DefaultsToThisMachine(t *testing.T) {
+	if fs, err := targetFileSystem(""); err != nil || fs != domain.HostFileSystem(runtime.GOOS) {
+		t.Errorf("expected this machine's file system, got %q (%v)", fs, err)
+	}
+	if fs, err := targetFileSystem("Portable"); err != nil || fs != domain.FileSystemPortable {
+		t.Errorf("expected portable, got %q (%v)", fs, err)
+	}
+	if _, err := targetFileSystem("fat12"); err == nil {
+		t.Error("expected an unknown file system to be rejected")
+	}
+}
+
+func TestStatusSocket_ReportsJobsQueueAndRecentErrors(t *testing.T) {
+	// Unix socket paths are limited to about 100 bytes, which a test's
+	// temporary directory may exceed.
+	dir, err := os.MkdirTemp("", "lsock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "status.sock")
+	board := newStatusBoard("watch-folder")
+	stop, err := serveStatusSocket(path, board)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stop()
+	board.begin("a.png", 2)
+	board.end("a.png", 0, 0, errors.New("moderated"))
+	board.begin("b.png", 1)
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}}}
+
+	resp, err := client.Get("http://leonardo/status")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var status domain.DaemonStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("expected JSON: %v", err)
+	}
+
+	if status.Command != "watch-folder" || status.PID != os.Getpid() {
+		t.Errorf("unexpected process fields: %+v", status)
+	}
+	if len(status.InFlight) != 1 || status.InFlight[0].Name != "b.png" || status.QueueDepth != 1 {
+		t.Errorf("expected b.png in flight with 1 queued, got %+v", status)
+	}
+	if status.Failed != 1 || len(status.RecentErrors) != 1 || status.RecentErrors[0].Error != "moderated" {
+		t.Errorf("expected the failure of a.png, got %+v", status)
+	}
+	if _, err := serveStatusSocket(path, newStatusBoard("watch-folder")); err == nil {
+		t.Error("expected a socket in use to be refused")
+	}
+}
+
+func TestWriteMetrics_RendersPrometheusCountersAndLatencyHistogram(t *testing.T) {
+	calls := []domain.CallMetric{
+		{Method: "POST", Path: "/generations", StatusCode: 200, Duration: 300 * time.Millisecond},
+		{Method: "GET", Path: "/generations/abc", StatusCode: 200, Duration: 50 * time.Millisecond},
+		{Method: "GET", Path: "/generations/def", StatusCode: 429, Duration: 2 * time.Second},
+	}
+	status := domain.DaemonStatus{Started: time.Unix(1700000000, 0), Completed: 1, TokensSpent: 24, ImagesDownloaded: 4, QueueDepth: 2}
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, calls, status)
+
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE leonardo_api_requests_total counter\n",
+		`leonardo_api_requests_total{method="GET",code="200"} 1`,
+		`leonardo_api_requests_total{method="GET",code="429"} 1`,
+		"leonardo_api_request_errors_total 1\n",
+		`leonardo_api_request_duration_seconds_bucket{le="0.1"} 1`,
+		`leonardo_api_request_duration_seconds_bucket{le="0.5"} 2`,
+		`leonardo_api_request_duration_seconds_bucket{le="+Inf"} 3`,
+		"leonardo_api_request_duration_seconds_sum 2.35\n",
+		"leonardo_tokens_consumed_total 24\n",
+		"leonardo_images_downloaded_total 4\n",
+		"leonardo_queue_depth 2\n",
+		"leonardo_start_time_seconds 1700000000\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDaemonStatus_KeepsOnlyTheLatestErrors(t *testing.T) {
+	var status domain.DaemonStatus
+	now := time.Now()
+	for i := 0; i < domain.MaxRecentErrors+3; i++ {
+		job := fmt.Sprintf("%d.png", i)
+		status.Begin(job, 0, now)
+		status.End(job, errors.New("failed"), now)
+	}
+
+	if len(status.RecentErrors) != domain.MaxRecentErrors || status.RecentErrors[0].Job != "3.png" {
+		t.Errorf("expected the last %d errors from 3.png, got %+v", domain.MaxRecentErrors, status.RecentErrors)
+	}
+	if status.Failed != domain.MaxRecentErrors+3 || len(status.InFlight) != 0 {
+		t.Errorf("unexpected totals: %+v", status)
+	}
+}
+
+func TestServiceSpec_RendersASystemdUnitWithQuotedArguments(t *testing.T) {
+	spec := domain.ServiceSpec{
+		Name:    "inbox",
+		Program: "/usr/local/bin/leonardo",
+		Args:    []string{"watch-folder", "--dir", "/srv/in box", "--prompt", `oil "painting" 100%`},
+		WorkDir: "/srv/project",
+		Env:     map[string]string{"LEONARDO_MODEL_ID": "abc"},
+	}
+
+	unit, err := spec.Render(domain.ServiceSystemd)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"WorkingDirectory=/srv/project\n",
+		"Environment=LEONARDO_MODEL_ID=abc\n",
+		`ExecStart=/usr/local/bin/leonardo watch-folder --dir "/srv/in box" --prompt "oil \"painting\" 100%%"` + "\n",
+		"Restart=on-failure\n",
+		"WantedBy=default.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("expected the unit to contain %q, got:\n%s", want, unit)
+		}
+	}
+}
+
+func TestServiceSpec_RendersALaunchdAgentWithEscapedStrings(t *testing.T) {
+	spec := domain.ServiceSpec{Name: "inbox", Program: "/opt/leonardo", Args: []string{"watch-folder", "--prompt", "cats & dogs"}, WorkDir: "/Users/me/p", LogDir: "/Users/me/Library/Logs/leonardo-cli"}
+
+	plist, err := spec.Render(domain.ServiceLaunchd)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"<string>com.leonardo-cli.inbox</string>",
+		"<string>cats &amp; dogs</string>",
+		"<key>RunAtLoad</key>",
+		"<string>/Users/me/Library/Logs/leonardo-cli/com.leonardo-cli.inbox.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("expected the plist to contain %q, got:\n%s", want, plist)
+		}
+	}
+}
+
+func TestServiceEnv_KeepsSettingsButNotCredentials(t *testing.T) {
+	env := serviceEnv([]string{"LEONARDO_MODEL_ID=abc", "LEONARDO_API_TOKEN=secret", "HOME=/home/me", "LEONARDO_WEBHOOK_SECRET=x", "LEONARDO_CONFIG_PASSPHRASE=hunter2", "LEONARDO_API_TOKEN_FILE=/run/secrets/token"}, "team")
+
+	want := map[string]string{"LEONARDO_MODEL_ID": "abc", "LEONARDO_ACCOUNT": "team", "LEONARDO_API_TOKEN_FILE": "/run/secrets/token"}
+	if len(env) != len(want) {
+		t.Fatalf("expected %v, got %v", want, env)
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("expected %s=%s, got %q", k, v, env[k])
+		}
+	}
+}
+
+func TestInstallService_WritesTheUnitOnceUnlessForced(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the systemd user directory follows XDG_CONFIG_HOME on Linux only")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	spec := domain.ServiceSpec{Name: "inbox", Program: "/bin/leonardo", Args: []string{"watch-folder"}, WorkDir: "/srv"}
+
+	if err := installService(spec, domain.ServiceSystemd, false, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "systemd", "user", "leonardo-inbox.service")
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), "ExecStart=/bin/leonardo watch-folder") {
+		t.Fatalf("expected the unit at %s, got %q (%v)", path, data, err)
+	}
+	if err := installService(spec, domain.ServiceSystemd, false, false); err == nil {
+		t.Error("expected an existing unit to be kept without --force")
+	}
+	if err := uninstallService("inbox", domain.ServiceSystemd); err != nil {
+		t.Errorf("unexpected error removing the unit: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected the unit to be removed")
+	}
+}
+
+func TestExtractGlobalFlags_TakesTokenFileAndNoConfigFile(t *testing.T) {
+	t.Setenv("LEONARDO_API_TOKEN_FILE", "/run/secrets/leonardo")
+	t.Setenv("LEONARDO_NO_CONFIG_FILE", "1")
+	opts, _ := extractGlobalFlags([]string{"list"})
+	if opts.tokenFile != "/run/secrets/leonardo" || !opts.noConfigFile {
+		t.Errorf("expected the environment to set both, got %q %v", opts.tokenFile, opts.noConfigFile)
+	}
+
+	opts, rest := extractGlobalFlags([]string{"list", "--token-file", "/tmp/token", "--no-config-file=false"})
+	if opts.tokenFile != "/tmp/token" || opts.noConfigFile {
+		t.Errorf("expected the flags to win, got %q %v", opts.tokenFile, opts.noConfigFile)
+	}
+	if strings.Join(rest, " ") != "list" {
+		t.Errorf("expected remaining args %q, got %q", "list", strings.Join(rest, " "))
+	}
+}
+
+func TestEnsureAPIKey_ReadsTheTokenFileBeforeTheEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(dir, "accounts.json")))
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("  file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LEONARDO_API_TOKEN", "env-token")
+
+	key, err := ensureAPIKey(accounts, globalOptions{tokenFile: path})
+	if err != nil || key != "file-token" {
+		t.Fatalf("expected the trimmed token from the file, got %q, %v", key, err)
+	}
+	if src := tokenSource(globalOptions{tokenFile: path}); src != "token file "+path {
+		t.Errorf("unexpected token source %q", src)
+	}
+	if key, _ := ensureAPIKey(accounts, globalOptions{}); key != "env-token" {
+		t.Errorf("expected LEONARDO_API_TOKEN without a token file, got %q", key)
+	}
+}
+
+func TestEnsureAPIKey_FailsOnAMissingOrEmptyTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(dir, "accounts.json")))
+	t.Setenv("LEONARDO_API_TOKEN", "env-token")
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing"), empty} {
+		if key, err := ensureAPIKey(accounts, globalOptions{tokenFile: path}); err == nil {
+			t.Errorf("expected %s to be refused rather than fall back, got %q", path, key)
+		}
+	}
+}
+
+func TestRunLogin_StoresAVerifiedKeyAsTheDefault(t *testing.T) {
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(t.TempDir(), "accounts.json")))
+	if err := accounts.Add("old", "old-token"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	original := stderr
+	stderr = &buf
+	defer func() { stderr = original }()
+
+	var checked string
+	err := runLogin(accounts, []string{"--device", "--name", "laptop"}, strings.NewReader("new-token\n"), true, func(token string) (domain.UserInfo, error) {
+		checked = token
+		return domain.UserInfo{Username: "ada"}, nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != "new-token" {
+		t.Errorf("expected the pasted key to be verified, got %q", checked)
+	}
+	if key, ok, _ := accounts.Token(""); !ok || key != "new-token" {
+		t.Errorf("expected the new account to be the default, got %q", key)
+	}
+	if !strings.Contains(buf.String(), "device-code") || !strings.Contains(buf.String(), apiAccessURL) {
+		t.Errorf("expected the device-code notice and the guided steps, got %q", buf.String())
+	}
+}
+
+func TestRunLogin_DoesNotStoreARejectedKey(t *testing.T) {
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(t.TempDir(), "accounts.json")))
+
+	err := runLogin(accounts, nil, strings.NewReader("bad-token"), false, func(string) (domain.UserInfo, error) {
+		return domain.UserInfo{}, &domain.APIError{StatusCode: 401, Body: []byte("unauthorized")}
+	})
+
+	if err == nil {
+		t.Fatal("expected a rejected key to fail")
+	}
+	if list, _, _ := accounts.List(); len(list) != 0 {
+		t.Errorf("expected nothing stored, got %v", list)
+	}
+}
+
+func TestConfigBundle_RoundTripsFilesAndSealedAccounts(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "wildcards"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(src, "characters.json"), []byte(`{"ava":{}}`), 0644)
+	os.WriteFile(filepath.Join(src, "wildcards", "color.txt"), []byte("red\nblue\n"), 0644)
+	os.WriteFile(filepath.Join(src, "library.json"), []byte("[]"), 0644)
+	project := filepath.Join(src, ".leonardo.yaml")
+	os.WriteFile(project, []byte("styles:\n  noir: dark\n"), 0644)
+	from := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(src, "accounts.json")))
+	if err := from.Add("work", "secret-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := exportConfig(src, project, from, "pass phrase", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := bundle.Files["home/library.json"]; ok {
+		t.Error("expected the library to be left out")
+	}
+	if bundle.Secrets == nil || strings.Contains(string(bundle.Secrets.Ciphertext), "secret-token") {
+		t.Fatalf("expected the accounts sealed, got %+v", bundle.Secrets)
+	}
+
+	dest, projectDir := t.TempDir(), t.TempDir()
+	to := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(dest, "accounts.json")))
+	if _, err := importConfig(bundle, dest, projectDir, to, "wrong", false); !errors.Is(err, domain.ErrWrongPassphrase) {
+		t.Errorf("expected a wrong passphrase to fail, got %v", err)
+	}
+	result, err := importConfig(bundle, dest, projectDir, to, "pass phrase", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "wildcards", "color.txt")); string(data) != "red\nblue\n" {
+		t.Errorf("expected the wildcard file copied, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(projectDir, ".leonardo.yaml")); !strings.Contains(string(data), "noir") {
+		t.Errorf("expected the project file copied, got %q", data)
+	}
+	if token, ok, _ := to.Token(""); !ok || token != "secret-token" {
+		t.Errorf("expected the account imported as the default, got %q", token)
+	}
+	if len(result.skipped) != 0 {
+		t.Errorf("expected nothing skipped, got %v", result.skipped)
+	}
+
+	os.WriteFile(filepath.Join(dest, "characters.json"), []byte("{}"), 0644)
+	result, err = importConfig(bundle, dest, projectDir, to, "pass phrase", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.written) != 0 || len(result.skipped) != 4 {
+		t.Errorf("expected existing files and accounts kept, got written %v skipped %v", result.written, result.skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "characters.json")); string(data) != "{}" {
+		t.Errorf("expected the local characters kept without --force, got %q", data)
+	}
+}
+
+func TestConfigBundle_RejectsPathsOutsideTheTarget(t *testing.T) {
+	bundle := domain.ConfigBundle{
+		Format:  domain.ConfigBundleFormat,
+		Version: domain.ConfigBundleVersion,
+		Files:   map[string]string{"home/../../evil": "x"},
+	}
+	dest := t.TempDir()
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(dest, "accounts.json")))
+
+	if _, err := importConfig(bundle, dest, dest, accounts, "", false); err == nil {
+		t.Fatal("expected an unsafe path to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(dest)), "evil")); err == nil {
+		t.Error("expected nothing written outside the target")
+	}
+}
+
+func TestConfigBundle_RejectsFilesExportDoesNotWrite(t *testing.T) {
+	dest := t.TempDir()
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(dest, "accounts.json")))
+	for _, name := range []string{"home/accounts.json", "home/library.json", "project/leonardo.lock"} {
+		bundle := domain.ConfigBundle{
+			Format:  domain.ConfigBundleFormat,
+			Version: domain.ConfigBundleVersion,
+			Files:   map[string]string{"home/characters.json": "{}", name: "x"},
+		}
+		if _, err := importConfig(bundle, dest, dest, accounts, "", true); err == nil {
+			t.Errorf("expected %s to be rejected", name)
+		}
+	}
+	entries, _ := os.ReadDir(dest)
+	if len(entries) != 0 {
+		t.Errorf("expected nothing written, got %v", entries)
+	}
+}
+
+func TestRunSetup_StoresTheKeyAndWritesTheAnswers(t *testing.T) {
+	dir := t.TempDir()
+	accounts := service.NewAccountService(storage.NewFileAccountStore(filepath.Join(dir, "accounts.json")))
+	var out bytes.Buffer
+	env := setupEnv{
+		in:    bufio.NewReader(strings.NewReader("new-token\n7\n2\nrenders\nn\n")),
+		out:   &out,
+		token: func() (string, bool) { return "", false },
+		verify: func(token string) (domain.UserInfo, error) {
+			if token != "new-token" {
+				t.Errorf("expected the pasted key verified, got %q", token)
+			}
+			return domain.UserInfo{Username: "ada"}, nil
+		},
+		models: func(string) ([]domain.PlatformModel, error) {
+			return []domain.PlatformModel{{ID: "m-1", Name: "Phoenix"}, {ID: "m-2", Name: "Kino XL"}}, nil
+		},
+	}
+
+	if err := runSetup(accounts, []string{"--project-dir", dir}, env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if key, ok, _ := accounts.Token(""); !ok || key != "new-token" {
+		t.Errorf("expected the key stored as the default account, got %q", key)
+	}
+	if !strings.Contains(out.String(), "Enter a number from 1 to 2") {
+		t.Errorf("expected an out-of-range model number asked again, got %q", out.String())
+	}
+	if info, err := os.Stat(filepath.Join(dir, "renders")); err != nil || !info.IsDir() {
+		t.Errorf("expected the output directory created, got %v", err)
+	}
+	source, err := config.LoadFile(filepath.Join(dir, config.ProjectFileName))
+	if err != nil {
+		t.Fatalf("expected a readable config file, got %v", err)
+	}
+	for key, want := range map[string]string{"model-id": "m-2", "output-dir": filepath.Join(dir, "renders"), "sidecar": "false"} {
+		if got, _, _ := source.Lookup(key); got != want {
+			t.Errorf("expected %s %q, got %q", key, want, got)
+		}
+	}
+
+	if err := runSetup(accounts, []string{"--project-dir", dir}, env); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected an existing file kept without --force, got %v", err)
+	}
+}
+
+func TestWriteSidecarMetadata_WritesNothingWhenTurnedOff(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	skipSidecars = true
+	defer func() { skipSidecars = false }()
+
+	path, err := writeSidecarMetadata(domain.GenerationRequest{}, "gen-1")
+
+	if err != nil || path != "" {
+		t.Fatalf("expected no sidecar, got %q, %v", path, err)
+	}
+	if _, err := os.Stat("gen-1.json"); err == nil {
+		t.Error("expected no sidecar file")
+	}
+}
+
+func TestClosest_SuggestsCommandsForTypos(t *testing.T) {
+	names := []string{"create", "status", "download", "variations", "models", "me"}
+	cases := map[string]string{"creat": "create", "stauts": "status", "downlod": "download", "var": "variations", "modle": "models", "xyz": "", "qq": ""}
+	for typo, want := range cases {
+		got, ok := closest(typo, names)
+		if want == "" && ok {
+			t.Errorf("%s: expected no suggestion, got %q", typo, got)
+		}
+		if want != "" && got != want {
+			t.Errorf("%s: expected %q, got %q", typo, want, got)
+		}
+	}
+}
+
+func TestHintUnknownFlags_EndsTheUsageWithTheFlagMeant(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--prompt", "a fox", "--model", "m-1"}, "Did you mean --model-id?"},
+		{[]string{"--promt=a fox"}, "Did you mean --prompt?"},
+		{[]string{"--token", "abc"}, "LEONARDO_API_TOKEN"},
+	}
+	for _, c := range cases {
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		var out bytes.Buffer
+		fs.SetOutput(&out)
+		fs.String("prompt", "", "")
+		fs.String("model-id", "", "")
+		fs.Bool("alchemy", false, "")
+
+		hintUnknownFlags(fs, c.args)
+		if err := fs.Parse(c.args); err == nil {
+			t.Fatalf("%v: expected a parse error", c.args)
+		}
+		if !strings.Contains(out.String(), c.want) {
+			t.Errorf("%v: expected %q in the usage, got %q", c.args, c.want, out.String())
+		}
+	}
+}
+
+func TestUnknownFlag_SkipsFlagValues(t *testing.T) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.String("prompt", "", "")
+	fs.Bool("alchemy", false, "")
+
+	if name, ok := unknownFlag(fs, []string{"--prompt", "--not-a-flag", "--alchemy", "pos", "-h"}); ok {
+		t.Errorf("expected every flag known, got %q", name)
+	}
+	if name, _ := unknownFlag(fs, []string{"--alchemy", "--sed", "1"}); name != "sed" {
+		t.Errorf("expected sed reported, got %q", name)
+	}
+}
+
+func TestExamples_CatalogRunsBuiltInCommands(t *testing.T) {
+	examples, err := loadExamples()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	topics := strings.Join(exampleTopics(examples), ",")
+	for _, want := range []string{"img2img", "batch", "sweep", "sync"} {
+		if !strings.Contains(topics, want) {
+			t.Errorf("expected a %s topic, got %s", want, topics)
+		}
+	}
+	for _, e := range examples {
+		if e.Topic == "" || e.Title == "" || len(e.Commands) == 0 {
+			t.Errorf("incomplete example %+v", e)
+		}
+		for _, c := range e.Commands {
+			fields := strings.Fields(c)
+			if fields[0] == "leonardo" && !isBuiltin(fields[1]) {
+				t.Errorf("%q: %s is not a command", e.Title, fields[1])
+			}
+		}
+	}
+}
+
+func TestPrintExamples_WritesPasteableBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	printExamples(&buf, []example{
+		{Topic: "sync", Title: "Sync to S3", Commands: []string{"leonardo download --last 1", "aws s3 sync . s3://b"}, Note: "Needs the AWS CLI."},
+	})
+
+	want := "# [sync] Sync to S3\n# Needs the AWS CLI.\nleonardo download --last 1\naws s3 sync . s3://b\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestPrintUsage_IsFullyTranslatedToPortuguese(t *testing.T) {
+	catalogs, err := i18n.Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range commands {
+		if catalogs["pt-BR"][c.summary] == "" {
+			t.Errorf("command %s has no pt-BR summary", c.name)
+		}
+	}
+	for _, f := range globalFlagUsage {
+		if catalogs["pt-BR"][f.summary] == "" {
+			t.Errorf("global option %s has no pt-BR summary", f.name)
+		}
+	}
+
+	saved, savedErr := tr, stderr
+	defer func() { tr, stderr = saved, savedErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+	tr = loadTranslator(func(name string) string {
+		if name == "LEONARDO_LANG" {
+			return "pt_BR.UTF-8"
+		}
+		return ""
+	})
+	printUsage()
+	if !strings.Contains(buf.String(), "Comandos:") || !strings.Contains(buf.String(), "Cria uma nova geração de imagens") {
+		t.Errorf("expected the usage in Portuguese, got %q", buf.String())
+	}
+}
+
+func TestCrashReport_IsSavedWithSecretsAndPromptsRedacted(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	savedStats := stats
+	defer func() { stats = savedStats }()
+	stats = &callStats{}
+	stats.record(domain.CallMetric{Method: "GET", Path: "/api/rest/v1/generations/user/user-123", StatusCode: 200, RequestID: "req-1"})
+
+	report := newCrashReport("index out of range", []byte("goroutine 1 [running]:\nmain.main()\n"),
+		[]string{"create", "--prompt", "a secret castle", "--token=tok-abcdef"},
+		[]string{"LEONARDO_API_TOKEN=tok-abcdef", "LEONARDO_WIDTH=512", "LEONARDO_CONFIG_PASSPHRASE=hunter22", "HOME=/root"},
+		time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	path, err := saveCrashReport(report)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Dir(path) != os.Getenv("TMPDIR") {
+		t.Errorf("expected the report in the temporary directory, got %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{"Panic: index out of range", "main.main()", "LEONARDO_WIDTH: 512", "/generations/user/[redacted] -> 200", "request ID req-1", "[redacted prompt sha256:"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, secret := range []string{"tok-abcdef", "hunter22", "a secret castle", "HOME"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected the report not to contain %q, got:\n%s", secret, out)
+		}
+	}
+}
+
+func TestWebhookRelay_ForwardsSimulatedDeliveriesToAReceiverThatChecksThem(t *testing.T) {
+	detail := domain.GenerationDetail{ID: "gen-1", Status: "COMPLETE", Prompt: "a lighthouse", Width: 512, Height: 768,
+		Images: []domain.GeneratedImage{{ID: "img-1", URL: "https://cdn.example/img-1.png"}}}
+	if _, err := domain.SimulatedWebhookEvent(domain.GenerationDetail{ID: "gen-2", Status: "PENDING"}, time.Now()); err == nil {
+		t.Error("expected a pending generation to have no delivery")
+	}
+	event, err := domain.SimulatedWebhookEvent(detail, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := json.Marshal(event)
+
+	// The receiver is itself a listen relay with the same secret, which
+	// checks the signature before passing the delivery on.
+	var received bytes.Buffer
+	receiver := httptest.NewServer(&webhookRelay{out: &received, secret: "s3cret"})
+	defer receiver.Close()
+	var printed bytes.Buffer
+	relay := &webhookRelay{out: &printed, forward: receiver.URL, secret: "s3cret", client: receiver.Client()}
+	if err := relay.deliver(context.Background(), body, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, out := range []string{printed.String(), received.String()} {
+		for _, want := range []string{`"type":"image_generation.complete"`, `"timestamp":1700000000`, `"id":"gen-1"`, `"imageHeight":768`, `"generationId":"gen-1"`} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in %s", want, out)
+			}
+		}
+	}
+
+	relay.secret = "wrong"
+	savedErr := stderr
+	defer func() { stderr = savedErr }()
+	stderr = io.Discard
+	if err := relay.deliver(context.Background(), body, true); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the receiver to reject a badly signed delivery, got %v", err)
+	}
+}
+
+func TestWebhookRelay_TracksDeliveriesOnItsStatusBoard(t *testing.T) {
+	savedErr := stderr
+	defer func() { stderr = savedErr }()
+	stderr = io.Discard
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+	board := newStatusBoard("listen")
+	relay := &webhookRelay{out: io.Discard, secret: "s3cret", client: receiver.Client(), board: board}
+	server := httptest.NewServer(relay)
+	defer server.Close()
+	body := []byte(`{"type":"image_generation.complete"}`)
+	post := func() {
+		req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+		req.Header.Set(webhookSignatureHeader, "sha256="+domain.WebhookSignature("s3cret", body))
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	post()
+	resp, err := server.Client().Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	relay.forward = receiver.URL
+	post()
+
+	status := board.snapshot()
+	if status.Completed != 1 || status.Failed != 2 || len(status.InFlight) != 0 {
+		t.Errorf("expected 1 delivered, an unsigned and an unforwarded one failed, none in flight, got %+v", status)
+	}
+	var metrics bytes.Buffer
+	writeMetrics(&metrics, nil, status)
+	if !strings.Contains(metrics.String(), "leonardo_jobs_failed_total 2") {
+		t.Errorf("expected the failures in the metrics, got:\n%s", metrics.String())
+	}
+}
+
+func TestStuckFor_OnlyFlagsPendingGenerationsPastTheThreshold(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		status    string
+		submitted time.Time
+		want      bool
+	}{
+		{"PENDING", now.Add(-45 * time.Minute), true},
+		{"pending", now.Add(-45 * time.Minute), true},
+		{"PENDING", now.Add(-10 * time.Minute), false},
+		{"COMPLETE", now.Add(-45 * time.Minute), false},
+		{"PENDING", time.Time{}, false},
+	}
+	for _, c := range cases {
+		if _, got := domain.StuckFor(c.status, c.submitted, now, 30*time.Minute); got != c.want {
+			t.Errorf("StuckFor(%s, %s) = %v, want %v", c.status, now.Sub(c.submitted), got, c.want)
+		}
+	}
+
+	detail := domain.GenerationDetail{ID: "gen-1", Status: "PENDING", Prompt: "a lighthouse", ModelID: "model-1", Width: 512, Height: 768, Seed: 42, Alchemy: true,
+		Elements: []domain.GenerationElement{{ID: "el-1", Weight: 0.5}}}
+	req := detail.ResubmitRequest()
+	if req.Metadata.Prompt != "a lighthouse" || req.Metadata.ModelID != "model-1" || req.Metadata.Width != 512 || req.Metadata.Seed != 42 || !req.Metadata.Alchemy || len(req.Metadata.Elements) != 1 {
+		t.Errorf("expected the record's settings in the request, got %+v", req.Metadata)
+	}
+}
+
+func TestModelBenchmark_RanksModelsByTimeAndReportsFailures(t *testing.T) {
+	if err := (domain.ModelBenchmark{ModelIDs: []string{"m1"}}).Validate(); err == nil {
+		t.Error("expected a single model to be rejected")
+	}
+	if err := (domain.ModelBenchmark{ModelIDs: []string{"m1", "m1"}}).Validate(); err == nil {
+		t.Error("expected a repeated model to be rejected")
+	}
+	benchmark := domain.ModelBenchmark{ModelIDs: []string{"m1", "m2", "m3"}}
+	requests := benchmark.Requests(domain.GenerationRequest{NumImages: 1, Metadata: domain.GenerationMetadata{Name: "cat", Prompt: "a cat", Seed: 7}})
+	if len(requests) != 3 || requests[1].Metadata.ModelID != "m2" || requests[1].Metadata.Name != "cat-m2" || requests[1].Metadata.Seed != 7 || strings.Join(requests[1].Metadata.Tags, ",") != "benchmark:7" {
+		t.Errorf("unexpected requests %+v", requests)
+	}
+
+	ranked := domain.RankBenchmark([]domain.BenchmarkResult{
+		{ModelID: "m1", ModelName: "Slow", GenerationID: "g1", Duration: 40 * time.Second, Cost: 8, File: "g1_1.png"},
+		{ModelID: "m2", GenerationID: "g2", Err: "finished with status FAILED"},
+		{ModelID: "m3", ModelName: "Fast", GenerationID: "g3", Duration: 12 * time.Second, File: "g3_1.png"},
+	})
+	var buf bytes.Buffer
+	if err := writeBenchmarkCSV(&buf, benchmarkRows(ranked)); err != nil {
+		t.Fatal(err)
+	}
+	want := "rank,model_id,model_name,generation_id,seconds,cost,file,error\n" +
+		"1,m3,Fast,g3,12.0,,g3_1.png,\n" +
+		"2,m1,Slow,g1,40.0,8,g1_1.png,\n" +
+		",m2,,g2,,,,finished with status FAILED\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCheckStream_AllowsOneOriginalImageToAPipe(t *testing.T) {
+	if err := checkStream([]string{"gen-1"}, map[string]bool{"stdout": true, "image": true}, false); err != nil {
+		t.Errorf("expected one generation piped to be accepted, got %v", err)
+	}
+	cases := map[string]error{
+		"two generations": checkStream([]string{"gen-1", "gen-2"}, nil, false),
+		"variations":      checkStream([]string{"gen-1"}, map[string]bool{"include-variations": true}, false),
+		"--dedupe":        checkStream([]string{"gen-1"}, map[string]bool{"dedupe": true}, false),
+		"copies":          checkStream([]string{"gen-1"}, map[string]bool{"quality": true}, false),
+		"a terminal":      checkStream([]string{"gen-1"}, nil, true),
+	}
+	for name, err := range cases {
+		if err == nil {
+			t.Errorf("expected --stdout with %s to be refused", name)
+		}
+	}
+}
+
+func TestParseWithLast_ReportsOnlyCommandLineFlagsAsGiven(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	if err := os.WriteFile(".leonardo.yaml", []byte("dedupe: true\ninclude-variations: true\nresize: 512x512\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	projectConfig, projectConfigLoaded = nil, false
+	defer func() { projectConfig, projectConfigLoaded = nil, false }()
+	t.Setenv("LEONARDO_IMAGE", "2")
+
+	fs := flag.NewFlagSet("download", flag.ContinueOnError)
+	var last lastFlag
+	fs.Var(&last, "last", "")
+	fs.Bool("stdout", false, "")
+	image := fs.Int("image", 1, "")
+	dedupe := fs.Bool("dedupe", false, "")
+	fs.Bool("include-variations", false, "")
+	fs.String("resize", "", "")
+	given := parseWithLast(fs, []string{"--last", "--stdout"}, &last)
+	if *image != 2 || !*dedupe {
+		t.Fatalf("expected LEONARDO_IMAGE and .leonardo.yaml to apply, got --image %d --dedupe %t", *image, *dedupe)
+	}
+	if given["image"] || given["dedupe"] || !given["stdout"] || !given["last"] {
+		t.Errorf("expected only --last and --stdout reported as given, got %v", given)
+	}
+	if err := checkStream([]string{"gen-1"}, given, false); err != nil {
+		t.Errorf("expected --stdout to ignore configured download flags, got %v", err)
+	}
+}
+
+func TestImageURLs_ListsImagesAndFinishedVariationsInOrder(t *testing.T) {
+	detail := domain.GenerationDetail{ID: "gen-1", Status: domain.GenerationComplete, Images: []domain.GeneratedImage{
+		{URL: "https://cdn/1.png", Variations: []domain.ImageVariation{
+			{URL: "https://cdn/1-up.png", Status: "COMPLETE", TransformType: "UPSCALE"},
+			{Status: "PENDING", TransformType: "NOBG"},
+		}},
+		{URL: "https://cdn/2.png"},
+	}}
+
+	plain, err := detail.ImageURLs(false)
+	if err != nil || len(plain) != 2 || plain[1].URL != "https://cdn/2.png" || plain[1].Image != 2 {
+		t.Fatalf("expected the two image URLs, got %+v, %v", plain, err)
+	}
+	all, _ := detail.ImageURLs(true)
+	if len(all) != 3 || all[1].URL != "https://cdn/1-up.png" || all[1].Variation != "upscaled" {
+		t.Errorf("expected the finished upscale after its image, got %+v", all)
+	}
+
+	detail.Status = domain.GenerationPending
+	if _, err := detail.ImageURLs(false); err == nil || !strings.Contains(err.Error(), "PENDING") {
+		t.Errorf("expected a pending generation to be refused, got %v", err)
+	}
+}
+
+func TestPrintImageTag_EscapesTheURLAndAltText(t *testing.T) {
+	var out strings.Builder
+	printImageTag(&out, domain.ImageURL{URL: "https://cdn/a.png?x=1&y=2"}, `a "red" fox`)
+	want := "<img src=\"https://cdn/a.png?x=1&amp;y=2\" alt=\"a &#34;red&#34; fox\">\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestPrintDedupeReport_SaysWhatWasAndWouldBeDone(t *testing.T) {
+	report := domain.DedupeReport{Files: 5, Distinct: 3, Linked: 1, Forgotten: 2, SavedBytes: 3 << 20}
+	var done, preview strings.Builder
+	printDedupeReport(&done, report, false)
+	printDedupeReport(&preview, report, true)
+
+	want := "5 downloaded files, 3 distinct images\nLinked 1 copies; forgot 2 deleted files\nSpace saved by links: 3.0 MiB\n"
+	if done.String() != want {
+		t.Errorf("expected %q, got %q", want, done.String())
+	}
+	if !strings.Contains(preview.String(), "Would link 1 copies; would forget 2") || !strings.Contains(preview.String(), "would save: 3.0 MiB") {
+		t.Errorf("expected the dry run to be worded conditionally, got %q", preview.String())
+	}
+}
+
+// safeguardUsage matches the usage of boolean flags that skip a
+// confirmation, replace what exists, only report what would be done or
+// protect files: flags a stray LEONARDO_* variable or a checked-in
+// .leonardo.yaml must never be able to set.
+var safeguardUsage = regexp.MustCompile(`(?i)without asking|confirmation|replace (an )?existing|already exist|would be|without changing|never touch`)
+
+func TestSafeguardFlags_AreCommandLineOnly(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	checked := 0
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 3 {
+				return true
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Bool" {
+				return true
+			}
+			flagName, ok1 := call.Args[0].(*ast.BasicLit)
+			usage, ok2 := call.Args[2].(*ast.BasicLit)
+			if !ok1 || !ok2 || flagName.Kind != token.STRING || usage.Kind != token.STRING {
+				return true
+			}
+			if !safeguardUsage.MatchString(usage.Value) {
+				return true
+			}
+			checked++
+			if flag := strings.Trim(flagName.Value, "`\""); !config.CommandLineOnly(flag) {
+				t.Errorf("%s: --%s (%s) confirms or guards a destructive action; add it to targetFlags in internal/config", fset.Position(call.Pos()), flag, usage.Value)
+			}
+			return true
+		})
+	}
+	if checked == 0 {
+		t.Error("expected to find the safeguard flags, found none")
+	}
+}
+
+func TestThumbnail_CachesASmallCopyUntilTheOriginalChanges(t *testing.T) {
+	original := filepath.Join(t.TempDir(), "gen_1.png")
+	if err := imaging.SavePNG(original, image.NewRGBA(image.Rect(0, 0, 1024, 512))); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := thumbnail(original)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(original), ".thumbnails", "gen_1.png"); path != want {
+		t.Errorf("expected %s, got %s", want, path)
+	}
+	img, err := imaging.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Size() != image.Pt(256, 128) {
+		t.Errorf("expected a 256x128 thumbnail, got %v", img.Bounds().Size())
+	}
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := imaging.SavePNG(original, image.NewRGBA(image.Rect(0, 0, 100, 100))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := thumbnail(original); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img, _ := imaging.Load(path); img.Bounds().Size() != image.Pt(100, 100) {
+		t.Errorf("expected a stale thumbnail to be rewritten, got %v", img.Bounds().Size())
+	}
+}
+
+func TestDetectProtocol_PicksTheTerminalsProtocol(t *testing.T) {
+	cases := []struct {
+		env   map[string]string
+		color bool
+		want  string
+	}{
+		{env: map[string]string{"TERM_PROGRAM": "iTerm.app"}, color: true, want: "iterm"},
+		{env: map[string]string{"TERM": "xterm-kitty"}, color: true, want: "kitty"},
+		{env: map[string]string{"TERM": "foot"}, color: true, want: "sixel"},
+		{env: map[string]string{"TERM": "xterm-256color"}, color: true, want: "blocks"},
+		{env: map[string]string{"TERM": "xterm-256color"}, color: false, want: "ascii"},
+	}
+	for _, c := range cases {
+		getenv := func(key string) string { return c.env[key] }
+		if got := detectProtocol(getenv, c.color); got != c.want {
+			t.Errorf("%v (color %v): expected %s, got %s", c.env, c.color, c.want, got)
+		}
+	}
+}
+
+func TestLocalImages_ListsOriginalsInImageOrder(t *testing.T) {
+	dir := t.TempDir()
+	id := "11111111-2222-3333-4444-555555555555"
+	for _, name := range []string{id + "_10.png", id + "_2.png", id + "_1_upscaled.png", id + "_1.png", id + ".json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	for _, path := range localImages(dir, id) {
+		names = append(names, strings.TrimPrefix(filepath.Base(path), id))
+	}
+
+	if got := strings.Join(names, ","); got != "_1.png,_2.png,_10.png" {
+		t.Errorf("expected _1, _2 and _10, got %s", got)
+	}
+}
+
+func TestConfirm_OnlyYesConfirms(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "Yes\n": true, "n\n": false, "\n": false, "": false} {
+		var prompt bytes.Buffer
+		got, err := confirm(strings.NewReader(answer), &prompt, "Delete? [y/N] ")
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", answer, err)
+		}
+		if got != want {
+			t.Errorf("%q: expected %v, got %v", answer, want, got)
+		}
+		if prompt.String() != "Delete? [y/N] " {
+			t.Errorf("expected the question to be asked, got %q", prompt.String())
+		}
+	}
+}
+
+func TestSidecarExport_A1111ParametersCarryPromptAndSettings(t *testing.T) {
+	sidecar, err := domain.ParseSidecar([]byte(`{"generation_id":"gen-1","prompt":"a red fox","negative_prompt":"blurry","seed":42,"width":832,"height":1216,"guidance_scale":7.5,"model_id":"model-9"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := sidecar.Export(domain.ExportA1111)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a red fox\nNegative prompt: blurry\nCFG scale: 7.5, Seed: 42, Size: 832x1216, Model: model-9, Leonardo generation: gen-1\n"
+	if string(out) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestSidecarExport_ComfyWorkflowWiresTheDefaultGraph(t *testing.T) {
+	sidecar := domain.Sidecar{Prompt: "a red fox", Seed: 42, Width: 512, Height: 768, NumImages: 2}
+
+	out, err := sidecar.Export(domain.ExportComfy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var workflow map[string]struct {
+		ClassType string                 `json:"class_type"`
+		Inputs    map[string]interface{} `json:"inputs"`
+	}
+	if err := json.Unmarshal(out, &workflow); err != nil {
+		t.Fatalf("expected JSON, got %v", err)
+	}
+	if workflow["6"].ClassType != "CLIPTextEncode" || workflow["6"].Inputs["text"] != "a red fox" {
+		t.Errorf("expected the prompt in node 6, got %+v", workflow["6"])
+	}
+	latent := workflow["5"].Inputs
+	if latent["width"] != 512.0 || latent["height"] != 768.0 || latent["batch_size"] != 2.0 {
+		t.Errorf("expected a 512x768 latent batch of 2, got %v", latent)
+	}
+	if workflow["3"].Inputs["seed"] != 42.0 || workflow["3"].Inputs["cfg"] != 7.0 {
+		t.Errorf("expected seed 42 and the default CFG, got %v", workflow["3"].Inputs)
+	}
+}
+
+func TestParseSidecar_RejectsOtherJSON(t *testing.T) {
+	if _, err := domain.ParseSidecar([]byte(`{"items":[]}`)); err == nil {
+		t.Error("expected a JSON file without prompt or generation_id to be rejected")
+	}
+}
+
+func TestParseA1111Parameters_MapsPromptAndSettings(t *testing.T) {
+	text := "a red fox, forest\nat dawn\nNegative prompt: blurry,\nlowres\nSteps: 28, Sampler: DPM++ 2M Karras, CFG scale: 6.5, Seed: 1234, Size: 832x1216, Model hash: abc123, Model: juggernautXL, Lora hashes: \"fox: 1a2b, dawn: 3c4d\""
+
+	imported, err := domain.ParseA1111Parameters(text)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := imported.Metadata
+	if m.Prompt != "a red fox, forest\nat dawn" || m.NegativePrompt != "blurry,\nlowres" {
+		t.Errorf("unexpected prompts: %q / %q", m.Prompt, m.NegativePrompt)
+	}
+	if m.Seed != 1234 || m.Width != 832 || m.Height != 1216 || m.GuidanceScale != 6.5 || m.ModelID != "" {
+		t.Errorf("unexpected settings: %+v", m)
+	}
+	if got := strings.Join(imported.Ignored, ","); got != "Steps,Sampler,Model hash,Model,Lora hashes" {
+		t.Errorf("unexpected ignored settings: %s", got)
+	}
+}
+
+func TestParseA1111Parameters_RoundTripsExport(t *testing.T) {
+	sidecar := domain.Sidecar{GenerationID: "gen-1", Prompt: "a red fox", NegativePrompt: "blurry", Seed: 42, Width: 512, Height: 768, GuidanceScale: 7, ModelID: "aa2b9e34-5e6f-4a1b-9c3d-112233445566", StyleUUID: "style-1"}
+	text, err := sidecar.Export(domain.ExportA1111)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := domain.ParseA1111Parameters(string(text))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := imported.Metadata
+	if m.Prompt != "a red fox" || m.Seed != 42 || m.ModelID != sidecar.ModelID || m.StyleUUID != "style-1" || len(imported.Ignored) != 0 {
+		t.Errorf("expected the exported settings back, got %+v ignoring %v", m, imported.Ignored)
+	}
+}
+
+func TestParseCivitaiJSON_ReadsNestedMeta(t *testing.T) {
+	data := []byte(`{"id": 99, "meta": {"prompt": "a red fox", "negativePrompt": "blurry", "seed": 314159265, "cfgScale": 5, "Size": "1024x1024", "steps": 30}}`)
+
+	imported, err := domain.ParseCivitaiJSON(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := imported.Metadata
+	if m.Prompt != "a red fox" || m.NegativePrompt != "blurry" || m.Width != 1024 || m.GuidanceScale != 5 || m.Seed != 314159265 {
+		t.Errorf("unexpected metadata: %+v", m)
+	}
+	if strings.Join(imported.Ignored, ",") != "steps" {
+		t.Errorf("expected steps to be ignored, got %v", imported.Ignored)
 	}
 }
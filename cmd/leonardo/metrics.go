@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"leonardo-cli/internal/domain"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the API latency
+// histogram.
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// writeMetrics renders the API calls of a long-running command and the
+// work it has done in the Prometheus text exposition format.  Calls are
+// labelled by method and status code only, since paths carry generation
+// IDs.
+func writeMetrics(w io.Writer, calls []domain.CallMetric, status domain.DaemonStatus) {
+	type key struct{ method, code string }
+	counts := map[key]int{}
+	errs := 0
+	buckets := make([]int, len(latencyBuckets))
+	var seconds float64
+	for _, m := range calls {
+		code := "none"
+		if m.StatusCode != 0 {
+			code = strconv.Itoa(m.StatusCode)
+		}
+		counts[key{m.Method, code}]++
+		if m.Failed() {
+			errs++
+		}
+		d := m.Duration.Seconds()
+		seconds += d
+		for i, bound := range latencyBuckets {
+			if d <= bound {
+				buckets[i]++
+			}
+		}
+	}
+	keys := make([]key, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+
+	metricHeader(w, "leonardo_api_requests_total", "counter", "API requests made, by method and response status code.")
+	for _, k := range keys {
+		fmt.Fprintf(w, "leonardo_api_requests_total{method=%q,code=%q} %d\n", k.method, k.code, counts[k])
+	}
+	metric(w, "leonardo_api_request_errors_total", "counter", "API requests that got no response or a status of 300 or above.", errs)
+	metricHeader(w, "leonardo_api_request_duration_seconds", "histogram", "Latency of API requests.")
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(w, "leonardo_api_request_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(bound, 'g', -1, 64), buckets[i])
+	}
+	fmt.Fprintf(w, "leonardo_api_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", len(calls))
+	fmt.Fprintf(w, "leonardo_api_request_duration_seconds_sum %s\n", strconv.FormatFloat(seconds, 'f', -1, 64))
+	fmt.Fprintf(w, "leonardo_api_request_duration_seconds_count %d\n", len(calls))
+	metric(w, "leonardo_tokens_consumed_total", "counter", "API tokens charged for the generations created, when the API reported it.", status.TokensSpent)
+	metric(w, "leonardo_images_downloaded_total", "counter", "Images saved to disk.", status.ImagesDownloaded)
+	metric(w, "leonardo_jobs_completed_total", "counter", "Jobs that finished successfully.", status.Completed)
+	metric(w, "leonardo_jobs_failed_total", "counter", "Jobs that failed.", status.Failed)
+	metric(w, "leonardo_jobs_in_flight", "gauge", "Jobs being worked on.", len(status.InFlight))
+	metric(w, "leonardo_queue_depth", "gauge", "Jobs ready and waiting behind the ones in flight.", status.QueueDepth)
+	metricHeader(w, "leonardo_start_time_seconds", "gauge", "When the process started, in seconds since the Unix epoch.")
+	fmt.Fprintf(w, "leonardo_start_time_seconds %d\n", status.Started.Unix())
+}
+
+// metricHeader writes the HELP and TYPE lines of a metric.
+func metricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// metric writes a metric with a single unlabelled value.
+func metric(w io.Writer, name, kind, help string, value int) {
+	metricHeader(w, name, kind, help)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
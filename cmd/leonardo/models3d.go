@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"leonardo-cli/internal/service"
+)
+
+// printModels3DUsage prints the models3d subcommands.
+func printModels3DUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo models3d <subcommand> [args]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  upload [--name <name>] <file.obj>   Upload an OBJ model and print its model ID")
+}
+
+// runModels3D dispatches the models3d subcommands.
+func runModels3D(models3d *service.Model3DService, args []string) error {
+	if len(args) == 0 {
+		printModels3DUsage()
+		return fmt.Errorf("models3d subcommand is required")
+	}
+	sub, rest := args[0], args[1:]
+	switch sub {
+	case "upload":
+		uploadCmd := flag.NewFlagSet("models3d upload", flag.ExitOnError)
+		name := uploadCmd.String("name", "", "Name for the model on Leonardo (default the file name)")
+		files, err := parseInterspersed(uploadCmd, rest)
+		if err != nil {
+			return err
+		}
+		if len(files) != 1 {
+			printModels3DUsage()
+			return fmt.Errorf("models3d upload requires exactly one file")
+		}
+		model, err := models3d.Upload(files[0], *name)
+		if err != nil {
+			return fmt.Errorf("uploading %s: %w", files[0], err)
+		}
+		if !printFormatted(model) {
+			fmt.Printf("Uploaded %s as %q: %s\n", files[0], model.Name, colors.id(model.ID))
+		}
+	default:
+		printModels3DUsage()
+		return fmt.Errorf("unknown models3d subcommand: %s", sub)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+// ANSI escape sequences used by the palette.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// palette colors terminal output.  A disabled palette returns its input
+// unchanged so callers never need to check whether color is on.
+type palette struct {
+	enabled bool
+}
+
+// colors is the palette used by all command output.  It is configured once
+// in main from the global flags and the environment.
+var colors = palette{}
+
+// wrap surrounds s with the given escape sequence when color is enabled.
+func (p palette) wrap(code, s string) string {
+	if !p.enabled || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// status colors a generation status by outcome: green when complete, red
+// when failed and yellow while still in progress.
+func (p palette) status(s string) string {
+	switch strings.ToUpper(s) {
+	case "COMPLETE":
+		return p.wrap(ansiGreen, s)
+	case "FAILED":
+		return p.wrap(ansiRed, s)
+	default:
+		return p.wrap(ansiYellow, s)
+	}
+}
+
+// id highlights an identifier such as a generation ID.
+func (p palette) id(s string) string {
+	return p.wrap(ansiCyan, s)
+}
+
+// bold emphasises s.
+func (p palette) bold(s string) string {
+	return p.wrap(ansiBold, s)
+}
+
+// colorEnabled decides whether output should be colored.  Color is used only
+// on terminals, and is turned off by --no-color, a non-empty NO_COLOR (see
+// https://no-color.org) or TERM=dumb.
+func colorEnabled(noColorFlag bool, isTerminal bool) bool {
+	if noColorFlag || !isTerminal {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return os.Getenv("TERM") != "dumb"
+}
+
+// isTerminal reports whether f is attached to a character device.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Timestamp display modes accepted by --timestamps.
+const (
+	timestampsRelative = "relative"
+	timestampsRFC3339  = "rfc3339"
+)
+
+// validateTimestampMode checks a --timestamps value.
+func validateTimestampMode(mode string) error {
+	switch mode {
+	case timestampsRelative, timestampsRFC3339:
+		return nil
+	default:
+		return fmt.Errorf("invalid --timestamps value %q (expected %q or %q)", mode, timestampsRelative, timestampsRFC3339)
+	}
+}
+
+// formatTimestamp renders t according to mode.  Relative times are computed
+// against now; unknown (zero) times render as an empty string.
+func formatTimestamp(t time.Time, mode string, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if mode == timestampsRFC3339 {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return relativeTime(t, now)
+}
+
+// relativeTime renders the time elapsed between t and now in its largest
+// whole unit, e.g. "4m ago" or "2d ago".  Times in the future, which only
+// happen with clock skew, are shown as "just now".
+func relativeTime(t, now time.Time) string {
+	elapsed := now.Sub(t)
+	switch {
+	case elapsed < time.Minute:
+		if elapsed < time.Second {
+			return "just now"
+		}
+		return fmt.Sprintf("%ds ago", int(elapsed/time.Second))
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed/time.Minute))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(elapsed/(24*time.Hour)))
+	}
+}
+
+// plainOutput is set by --plain: output is kept linear for screen readers,
+// so tables are written as one sentence-like line per row and images are
+// described rather than drawn.
+var plainOutput bool
+
+// table lays out tab-separated cells written to it as aligned columns when
+// flushed.
+type table interface {
+	io.Writer
+	Flush() error
+}
+
+// newTable returns the table commands write their columns to: aligned with
+// a tabwriter, or linearised by --plain.
+func newTable(w io.Writer) table {
+	if plainOutput {
+		return &plainTable{w: w}
+	}
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// plainTable writes each row as one line without alignment padding.  When
+// the first row is a header of upper-case column names, every later row
+// names its cells, e.g. "Image: 1, File: a.png, Result: saved", and the
+// header itself is not written.  Otherwise a cell ending in a colon is
+// followed by the next with a space and other cells are separated by
+// commas.  Empty cells and dashes standing for nothing are left out.
+type plainTable struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (t *plainTable) Write(p []byte) (int, error) {
+	return t.buf.Write(p)
+}
+
+func (t *plainTable) Flush() error {
+	text := t.buf.String()
+	t.buf.Reset()
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	var header []string
+	if cells := strings.Split(lines[0], "\t"); len(lines) > 1 && isHeaderRow(cells) {
+		for _, c := range cells {
+			header = append(header, headerLabel(strings.TrimSpace(c)))
+		}
+		lines = lines[1:]
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		b.WriteString(indent)
+		b.WriteString(plainRow(strings.Split(line, "\t"), header))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
+
+// plainRow joins the cells of a row, naming each after header when there
+// is one.
+func plainRow(cells, header []string) string {
+	var b strings.Builder
+	previous := ""
+	for i, cell := range cells {
+		cell = strings.TrimSpace(cell)
+		if cell == "" || cell == "-" {
+			continue
+		}
+		switch {
+		case previous == "":
+		case strings.HasSuffix(previous, ":"):
+			b.WriteByte(' ')
+		default:
+			b.WriteString(", ")
+		}
+		previous = cell
+		if i < len(header) && header[i] != "" {
+			b.WriteString(header[i] + ": ")
+		}
+		b.WriteString(cell)
+	}
+	return b.String()
+}
+
+// isHeaderRow reports whether cells look like column names: at least two,
+// with letters and none of them lower case.
+func isHeaderRow(cells []string) bool {
+	if len(cells) < 2 {
+		return false
+	}
+	letters := false
+	for _, r := range strings.Join(cells, "") {
+		if unicode.IsLower(r) {
+			return false
+		}
+		letters = letters || unicode.IsLetter(r)
+	}
+	return letters
+}
+
+// headerLabel turns a column name such as "PER IMAGE" into "Per image",
+// leaving abbreviations such as ID and URL as they are and "#" as "Number".
+func headerLabel(name string) string {
+	if name == "#" {
+		return "Number"
+	}
+	words := strings.Fields(name)
+	for i, w := range words {
+		switch w {
+		case "ID", "URL", "UUID", "SHA256", "API":
+			continue
+		}
+		w = strings.ToLower(w)
+		if i == 0 {
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		words[i] = w
+	}
+	return strings.Join(words, " ")
+}
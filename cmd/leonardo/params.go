@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// paramsFlag collects repeated --param KEY=VALUE flags.
+type paramsFlag map[string]json.RawMessage
+
+func (f paramsFlag) String() string {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	specs := make([]string, len(keys))
+	for i, key := range keys {
+		specs[i] = key + "=" + string(f[key])
+	}
+	return strings.Join(specs, ",")
+}
+
+func (f paramsFlag) Set(value string) error {
+	key, raw, err := domain.ParseParam(value)
+	if err != nil {
+		return err
+	}
+	f[key] = raw
+	return nil
+}
+
+// requestParams returns the extra request fields: the project
+// configuration's param.KEY values, replaced by those given with --param.
+func requestParams(flagged paramsFlag) map[string]json.RawMessage {
+	params := map[string]json.RawMessage{}
+	if project := loadProjectConfig(); project != nil {
+		for key, value := range project.Prefixed(domain.ParamConfigPrefix) {
+			params[key] = domain.ParamValue(value)
+		}
+	}
+	for key, value := range flagged {
+		params[key] = value
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
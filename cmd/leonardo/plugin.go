@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+
+	"leonardo-cli/internal/config"
+	"leonardo-cli/internal/service"
+)
+
+// pluginPrefix starts the name of every plugin executable: running
+// "leonardo foo" runs leonardo-foo from PATH, the way git finds its
+// subcommands.
+const pluginPrefix = "leonardo-"
+
+// isBuiltin reports whether name is a built-in command.
+func isBuiltin(name string) bool {
+	if name == "help" {
+		return true
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findPlugin returns the executable implementing the plugin command name.
+// Built-in commands are never looked up.
+func findPlugin(name string) (string, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) || isBuiltin(name) {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// findPlugins lists the plugin commands available on PATH, sorted and
+// without the ones built-in commands shadow.
+func findPlugins() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, pluginPrefix) || entry.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			name = strings.TrimPrefix(name, pluginPrefix)
+			if seen[name] {
+				continue
+			}
+			if _, ok := findPlugin(name); ok {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pluginEnv returns the environment a plugin runs with: the CLI's own plus
+// the resolved state directory, project settings, global options and, when
+// one can be found, the API token, so a plugin behaves like a built-in
+// command without resolving them itself.
+func pluginEnv(name string, opts globalOptions, apiKey string) []string {
+	env := os.Environ()
+	if project := loadProjectConfig(); project != nil {
+		env = project.Environ(env)
+		env = append(env, "LEONARDO_CONFIG_FILE="+project.Path())
+	}
+	env = append(env, "LEONARDO_HOME="+leonardoHome(), "LEONARDO_PLUGIN="+name, "LEONARDO_CLI_VERSION="+currentBuild().Version)
+	if self, err := os.Executable(); err == nil {
+		env = append(env, "LEONARDO_CLI="+self)
+	}
+	if apiKey != "" {
+		env = append(env, "LEONARDO_API_TOKEN="+apiKey)
+	}
+	globals := []struct {
+		flag string
+		set  bool
+	}{{"no-color", opts.noColor}, {"plain", opts.plain}, {"verbose", opts.verbose}, {"redact-prompts", opts.redactPrompts}}
+	for _, g := range globals {
+		if g.set {
+			env = append(env, config.EnvVar(g.flag)+"=true")
+		}
+	}
+	if opts.account != "" {
+		env = append(env, "LEONARDO_ACCOUNT="+opts.account)
+	}
+	return env
+}
+
+// runPlugin runs the plugin at path with args and returns its exit code.
+// The token is optional unless --account names a stored account or
+// --token-file a file, so plugins that work offline run without one.
+// Ctrl-C and SIGTERM are passed on to the plugin, which decides how to stop.
+func runPlugin(name, path string, args []string, opts globalOptions, accounts *service.AccountService) int {
+	apiKey, err := ensureAPIKey(accounts, opts)
+	if err != nil && (opts.account != "" || opts.tokenFile != "") {
+		reportError("", err)
+		return 1
+	}
+	if apiKey != "" {
+		registerSecret(apiKey)
+	}
+	plugin := exec.Command(path, args...)
+	plugin.Env = pluginEnv(name, opts, apiKey)
+	plugin.Stdin, plugin.Stdout, plugin.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := plugin.Start(); err != nil {
+		reportError("Error running plugin "+name, err)
+		return 1
+	}
+	signals := make(chan os.Signal, 2)
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			plugin.Process.Signal(sig)
+		}
+	}()
+	err = plugin.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code
+		}
+		return exitInterrupted
+	}
+	if err != nil {
+		reportError("Error running plugin "+name, err)
+		return 1
+	}
+	return 0
+}
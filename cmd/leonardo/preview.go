@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"leonardo-cli/internal/imaging"
+	"leonardo-cli/internal/service"
+)
+
+// protocolAuto picks the terminal image protocol from the environment.
+const protocolAuto = "auto"
+
+// runPreview shows the images of generations inline in the terminal.  Images
+// already downloaded to --dir are used as they are; otherwise they are
+// downloaded to a temporary directory first.
+func runPreview(svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	previewCmd := flag.NewFlagSet("preview", flag.ExitOnError)
+	id := previewCmd.String("id", "", "Generation ID, ID prefix or name to preview")
+	var last lastFlag
+	previewCmd.Var(&last, "last", "Preview the N most recently created generations recorded locally (default 1)")
+	dir := previewCmd.String("dir", ".", "Directory the images were downloaded to")
+	protocol := previewCmd.String("protocol", protocolAuto, "Terminal image protocol: auto, "+strings.Join(imaging.Protocols, ", "))
+	width := previewCmd.Int("width", 32, "Width of each preview in terminal columns")
+	parseWithLast(previewCmd, args, &last)
+	if *protocol == protocolAuto {
+		*protocol = detectProtocol(os.Getenv, colors.enabled)
+	}
+	if !knownProtocol(*protocol) {
+		previewCmd.Usage()
+		return fmt.Errorf("unknown --protocol %q", *protocol)
+	}
+	ids := targetGenerations(previewCmd, svc, lib, *id, last)
+	for _, genID := range ids {
+		paths, cleanup, err := previewImages(svc, genID, *dir)
+		if err != nil {
+			return err
+		}
+		err = renderPreviews(os.Stdout, genID, paths, *protocol, *width)
+		cleanup()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detectProtocol picks the image protocol the terminal supports from the
+// variables terminals set, falling back to colored blocks, or to plain
+// characters when color is off.
+func detectProtocol(getenv func(string) string, color bool) string {
+	term := getenv("TERM")
+	switch {
+	case getenv("TERM_PROGRAM") == "iTerm.app" || getenv("TERM_PROGRAM") == "WezTerm" || getenv("LC_TERMINAL") == "iTerm2":
+		return imaging.ProtocolITerm
+	case getenv("KITTY_WINDOW_ID") != "" || strings.Contains(term, "kitty"):
+		return imaging.ProtocolKitty
+	case strings.Contains(term, "sixel") || term == "foot" || strings.HasPrefix(term, "mlterm"):
+		return imaging.ProtocolSixel
+	case color:
+		return imaging.ProtocolBlocks
+	}
+	return imaging.ProtocolASCII
+}
+
+// knownProtocol reports whether protocol is a supported image protocol.
+func knownProtocol(protocol string) bool {
+	for _, p := range imaging.Protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// localImages returns the images of a generation saved in dir, as
+// {id}_{n}.png, in image order.
+func localImages(dir, id string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dir, id+"_*.png"))
+	var images []string
+	for _, path := range matches {
+		stem := strings.TrimSuffix(filepath.Base(path), ".png")
+		index := strings.TrimPrefix(stem, id+"_")
+		if index != "" && strings.Trim(index, "0123456789") == "" {
+			images = append(images, path)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return len(images[i]) < len(images[j]) || len(images[i]) == len(images[j]) && images[i] < images[j]
+	})
+	return images
+}
+
+// previewImages returns the image files to preview for a generation and a
+// function removing any that were downloaded just for the preview.
+func previewImages(svc *service.GenerationService, id, dir string) ([]string, func(), error) {
+	if images := localImages(dir, id); len(images) > 0 {
+		return images, func() {}, nil
+	}
+	tmp, err := os.MkdirTemp("", "leonardo-preview-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	result, err := svc.Download(id, tmp)
+	if len(result.FilePaths) == 0 {
+		cleanup()
+		return nil, nil, err
+	}
+	return result.FilePaths, cleanup, nil
+}
+
+// previewSource returns the file to decode for a preview columns wide: the
+// cached thumbnail when it is large enough, which is much faster to decode,
+// or else the original.
+func previewSource(original string, columns int) string {
+	if columns*imaging.CellWidth > thumbnailSize {
+		return original
+	}
+	if _, err := os.Stat(thumbnailPath(original)); err == nil {
+		return thumbnailPath(original)
+	}
+	return original
+}
+
+// renderPreviews writes each image to w with a caption naming it.  With
+// --plain only the caption is written, giving the image's size and where it
+// is saved instead of drawing it.
+func renderPreviews(w io.Writer, id string, paths []string, protocol string, columns int) error {
+	for i, path := range paths {
+		if plainOutput {
+			img, err := imaging.Load(path)
+			if err != nil {
+				return err
+			}
+			size := img.Bounds().Size()
+			fmt.Fprintf(w, "%s image %d: %d by %d pixels, saved as %s\n", id, i+1, size.X, size.Y, path)
+			continue
+		}
+		img, err := imaging.Load(previewSource(path, columns))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s image %d (%s)\n", colors.id(id), i+1, filepath.Base(path))
+		if err := imaging.RenderTerminal(w, img, protocol, columns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// runPricing prices common configurations of a model with the pricing
+// calculator and prints them as a table.
+func runPricing(pricing *service.PricingService, models *service.ModelService, args []string) error {
+	pricingCmd := flag.NewFlagSet("pricing", flag.ExitOnError)
+	modelID := pricingCmd.String("model-id", "", "Model ID to price (required; can be set with LEONARDO_MODEL_ID)")
+	sizesFlag := pricingCmd.String("sizes", "", "Comma-separated sizes to price, e.g. 512x512,1024x768 (default common sizes)")
+	countsFlag := pricingCmd.String("num-images", "", "Comma-separated image counts to price (default 1,2,4)")
+	sortBy := pricingCmd.String("sort", "size", "Order rows by size or by cost per image (size or cost)")
+	parseFlags(pricingCmd, args)
+	if *modelID == "" {
+		pricingCmd.Usage()
+		return fmt.Errorf("--model-id is required")
+	}
+	if *sortBy != "size" && *sortBy != "cost" {
+		return fmt.Errorf("invalid --sort %q (use size or cost)", *sortBy)
+	}
+	sizes := domain.DefaultPricingSizes
+	if *sizesFlag != "" {
+		sizes = nil
+		for _, field := range strings.Split(*sizesFlag, ",") {
+			size, err := domain.ParseSize(field)
+			if err != nil {
+				return err
+			}
+			sizes = append(sizes, size)
+		}
+	}
+	counts := domain.DefaultPricingImageCounts
+	if *countsFlag != "" {
+		counts = nil
+		for _, field := range strings.Split(*countsFlag, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || n < 1 || n > 8 {
+				return fmt.Errorf("invalid image count %q (use 1-8)", field)
+			}
+			counts = append(counts, n)
+		}
+	}
+	catalog, err := models.Catalog(false)
+	if err != nil {
+		return fmt.Errorf("fetching models: %w", err)
+	}
+	model, found := catalog.Find(*modelID)
+	if !found {
+		model = domain.PlatformModel{ID: *modelID}
+		fmt.Fprintln(stderr, "Warning: model not in the platform list; pricing it as a custom model.")
+	}
+	queries, skipped := domain.PricingGrid(model, !found, sizes, counts)
+	for _, size := range skipped {
+		fmt.Fprintf(stderr, "Skipping %s: not supported by the model.\n", size)
+	}
+	if len(queries) == 0 {
+		return fmt.Errorf("no configuration to price")
+	}
+	quotes, err := pricing.Table(queries)
+	if err != nil {
+		return err
+	}
+	if *sortBy == "cost" {
+		domain.SortQuotesByCost(quotes)
+	}
+	if outputFormat != nil {
+		for _, q := range quotes {
+			printFormatted(q)
+		}
+		return nil
+	}
+	name := model.Name
+	if name == "" {
+		name = model.ID
+	}
+	fmt.Printf("Token cost for %s:\n", name)
+	return printPricingTable(os.Stdout, quotes)
+}
+
+// printPricingTable prints quotes with their total and per-image cost.
+func printPricingTable(w io.Writer, quotes []domain.PriceQuote) error {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "SIZE\tALCHEMY\tIMAGES\tTOKENS\tPER IMAGE")
+	for _, q := range quotes {
+		alchemy := "no"
+		if q.Alchemy {
+			alchemy = "yes"
+		}
+		size := domain.Size{Width: q.Width, Height: q.Height}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", size, alchemy, q.NumImages, q.Cost, strconv.FormatFloat(q.PerImage(), 'f', -1, 64))
+	}
+	return tw.Flush()
+}
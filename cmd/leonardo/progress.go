@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// progressLine is the JSON form of a progress event written by
+// --progress-json.  ExitCode is only set on the final done event.
+type progressLine struct {
+	Event    domain.ProgressKind `json:"event"`
+	Time     string              `json:"time"`
+	ID       string              `json:"id,omitempty"`
+	Status   string              `json:"status,omitempty"`
+	Attempt  int                 `json:"attempt,omitempty"`
+	Image    int                 `json:"image,omitempty"`
+	Images   int                 `json:"images,omitempty"`
+	Path     string              `json:"path,omitempty"`
+	ExitCode *int                `json:"exit_code,omitempty"`
+}
+
+// progressReporter writes progress events as JSON lines so tools wrapping
+// the CLI can follow a run.  Writes are serialised because events may come
+// from concurrent work.
+type progressReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now func() time.Time
+}
+
+// progress is set by --progress-json; it stays nil otherwise.
+var progress *progressReporter
+
+// newProgressReporter returns a reporter writing to w.
+func newProgressReporter(w io.Writer) *progressReporter {
+	return &progressReporter{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// report writes ev as a single JSON line.
+func (p *progressReporter) report(ev domain.ProgressEvent) {
+	p.write(progressLine{
+		Event:   ev.Kind,
+		ID:      ev.GenerationID,
+		Status:  ev.Status,
+		Attempt: ev.Attempt,
+		Image:   ev.Image,
+		Images:  ev.Images,
+		Path:    ev.Path,
+	})
+}
+
+// done writes the final event of a run with the exit code it ends with.
+func (p *progressReporter) done(code int) {
+	p.write(progressLine{Event: domain.ProgressDone, ExitCode: &code})
+}
+
+func (p *progressReporter) write(line progressLine) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	line.Time = p.now().UTC().Format(time.RFC3339Nano)
+	// A consumer that went away must not stop the run.
+	_ = p.enc.Encode(line)
+}
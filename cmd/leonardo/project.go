@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"leonardo-cli/internal/config"
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+	"leonardo-cli/internal/storage"
+)
+
+// printProjectUsage prints the project subcommands.
+func printProjectUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo project <subcommand> [args]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  init                    Create "+domain.ProjectManifestName+" in the current directory")
+	fmt.Fprintln(stderr, "  add --id <ref> <file>...  Record the generation that produced asset files")
+	fmt.Fprintln(stderr, "  list                    List the recorded assets")
+}
+
+// runProject dispatches the project subcommands.  Commands other than init
+// use the nearest manifest found in the working directory or its parents.
+func runProject(args []string) error {
+	if len(args) == 0 {
+		printProjectUsage()
+		return fmt.Errorf("project subcommand is required")
+	}
+	sub, rest := args[0], args[1:]
+	library := storage.NewFileLibrary(libraryPath())
+	lib := service.NewLibraryService(library)
+	switch sub {
+	case "init":
+		path, err := filepath.Abs(domain.ProjectManifestName)
+		if err != nil {
+			return err
+		}
+		if err := service.NewProjectService(storage.NewFileProjectManifest(path), library).Init(); err != nil {
+			return err
+		}
+		fmt.Println("Created", path)
+	case "add":
+		addCmd := flag.NewFlagSet("project add", flag.ExitOnError)
+		id := addCmd.String("id", "", "Generation ID, ID prefix or name that produced the files (required)")
+		files, err := parseInterspersed(addCmd, rest)
+		if err != nil {
+			return err
+		}
+		applyConfig(addCmd)
+		if strings.TrimSpace(*id) == "" || len(files) == 0 {
+			printProjectUsage()
+			return fmt.Errorf("project add requires --id and at least one file")
+		}
+		path, err := findProjectManifest()
+		if err != nil {
+			return err
+		}
+		genID, err := lib.Resolve(*id)
+		if err != nil {
+			return err
+		}
+		projects := service.NewProjectService(storage.NewFileProjectManifest(path), library)
+		projects.SetPromptFilter(redactor.Prompt)
+		for _, file := range files {
+			asset, err := projectAsset(filepath.Dir(path), file)
+			if err != nil {
+				return err
+			}
+			asset.GenerationID = genID
+			if _, err := projects.Add(asset); err != nil {
+				return fmt.Errorf("recording %s: %w", file, err)
+			}
+			fmt.Printf("Recorded %s from %s\n", asset.Path, colors.id(genID))
+		}
+	case "list":
+		path, err := findProjectManifest()
+		if err != nil {
+			return err
+		}
+		assets, err := service.NewProjectService(storage.NewFileProjectManifest(path), library).Assets()
+		if err != nil {
+			return err
+		}
+		return listProjectAssets(os.Stdout, assets)
+	default:
+		printProjectUsage()
+		return fmt.Errorf("unknown project subcommand: %s", sub)
+	}
+	return nil
+}
+
+// findProjectManifest returns the nearest project manifest.
+func findProjectManifest() (string, error) {
+	path, ok := config.FindUp(".", domain.ProjectManifestName)
+	if !ok {
+		return "", fmt.Errorf("no %s found in this directory or its parents; run project init first", domain.ProjectManifestName)
+	}
+	return path, nil
+}
+
+// projectAsset describes file relative to the project root, with the
+// SHA-256 of its content.
+func projectAsset(root, file string) (domain.ProjectAsset, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return domain.ProjectAsset{}, err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return domain.ProjectAsset{}, fmt.Errorf("%s is outside the project", file)
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return domain.ProjectAsset{}, fmt.Errorf("reading asset: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return domain.ProjectAsset{}, fmt.Errorf("reading asset: %w", err)
+	}
+	return domain.ProjectAsset{Path: filepath.ToSlash(rel), SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// listProjectAssets prints the recorded assets as a table.
+func listProjectAssets(w io.Writer, assets []domain.ProjectAsset) error {
+	if len(assets) == 0 {
+		fmt.Fprintln(w, "No assets recorded yet.")
+		return nil
+	}
+	tw := newTable(w)
+	fmt.Fprintln(tw, "PATH\tGENERATION\tSHA256\tPROMPT")
+	for _, a := range assets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", a.Path, a.GenerationID, shortID(a.SHA256), a.Prompt)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// sidecarSigner signs every sidecar written when set, by create --sign-key.
+var sidecarSigner ed25519.PrivateKey
+
+// loadSigningKey reads the ed25519 private key sidecars are signed with.
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	return domain.ParseSigningKey(data)
+}
+
+// runKeygen writes a new ed25519 key pair for signing sidecars: the private
+// key to <output>.pem, readable only by its owner, and the public key to
+// <output>.pub.pem for publishing.
+func runKeygen(args []string) error {
+	keygenCmd := flag.NewFlagSet("keygen", flag.ExitOnError)
+	output := keygenCmd.String("output", "leonardo-signing", "Path prefix of the key files")
+	parseFlags(keygenCmd, args)
+	privatePath, publicPath := *output+".pem", *output+".pub.pem"
+	for _, path := range []string{privatePath, publicPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists; choose another --output", path)
+		}
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	private, public, err := domain.EncodeKeyPair(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(privatePath, private, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(publicPath, public, 0644); err != nil {
+		return err
+	}
+	fmt.Println("Private key:", privatePath)
+	fmt.Println("Public key: ", publicPath)
+	fmt.Println("Key ID:     ", domain.KeyID(key.Public().(ed25519.PublicKey)))
+	fmt.Fprintln(messages(), "Keep the private key secret; sign sidecars with create --sign-key", privatePath)
+	return nil
+}
+
+// verifySidecarFile checks the signature of the sidecar at path, against
+// the public key at publicKeyPath when given, and reports who signed it.
+func verifySidecarFile(path, publicKeyPath string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading sidecar metadata: %w", err)
+	}
+	var trusted ed25519.PublicKey
+	if strings.TrimSpace(publicKeyPath) != "" {
+		keyData, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return fmt.Errorf("reading public key: %w", err)
+		}
+		if trusted, err = domain.ParsePublicKey(keyData); err != nil {
+			return err
+		}
+	}
+	sig, err := domain.VerifySidecar(data, trusted)
+	if errors.Is(err, domain.ErrUnsignedSidecar) {
+		return fmt.Errorf("%s is not signed", path)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: signature valid, key %s\n", colors.wrap(ansiGreen, "OK"), sig.KeyID)
+	if trusted == nil {
+		fmt.Fprintln(messages(), "The sidecar is unchanged since it was signed; pass --public-key to check who signed it.")
+	}
+	return nil
+}
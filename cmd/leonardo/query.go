@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// outputQuery is the path given with --query.  When set, commands that
+// receive a raw API response print only the value it selects.
+var outputQuery string
+
+// queryStep is one step of a --query path.
+type queryStep struct {
+	key   string // object field, when index and all are unset
+	index int    // array index; negative counts from the end
+	isIdx bool
+	all   bool // [*]: apply the rest of the path to every element
+	count bool // #: the length of an array or object
+}
+
+// parseQuery splits a path such as
+// generations_by_pk.generated_images[0].id into steps.  Fields are
+// separated by dots, [n] indexes an array (negative from the end), [*]
+// maps the rest of the path over every element and # is the length.  A
+// leading dot is allowed, as in jq.
+func parseQuery(path string) ([]queryStep, error) {
+	var steps []queryStep
+	rest := strings.TrimPrefix(strings.TrimSpace(path), ".")
+	if rest == "" {
+		return nil, fmt.Errorf("empty --query")
+	}
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid --query %q: unclosed [", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			if inner == "*" {
+				steps = append(steps, queryStep{all: true})
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid --query %q: index %q is not a number", path, inner)
+				}
+				steps = append(steps, queryStep{index: n, isIdx: true})
+			}
+			rest = rest[end+1:]
+		case rest[0] == '.':
+			rest = rest[1:]
+			if rest == "" || rest[0] == '.' || rest[0] == '[' {
+				return nil, fmt.Errorf("invalid --query %q: empty field name", path)
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if key := rest[:end]; key == "#" {
+				steps = append(steps, queryStep{count: true})
+			} else {
+				steps = append(steps, queryStep{key: key})
+			}
+			rest = rest[end:]
+		}
+	}
+	return steps, nil
+}
+
+// queryJSON returns the value path selects in the JSON document data.
+func queryJSON(data []byte, path string) (interface{}, error) {
+	steps, err := parseQuery(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("response is not JSON: %w", err)
+	}
+	return applyQuery(doc, steps, "")
+}
+
+// applyQuery walks steps from v; at names the position for error messages.
+func applyQuery(v interface{}, steps []queryStep, at string) (interface{}, error) {
+	for i, step := range steps {
+		switch {
+		case step.count:
+			switch t := v.(type) {
+			case []interface{}:
+				v = len(t)
+			case map[string]interface{}:
+				v = len(t)
+			default:
+				return nil, fmt.Errorf("%s is not an array or object", describeAt(at))
+			}
+			at += ".#"
+		case step.all:
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array", describeAt(at))
+			}
+			out := make([]interface{}, 0, len(list))
+			for j, item := range list {
+				selected, err := applyQuery(item, steps[i+1:], fmt.Sprintf("%s[%d]", at, j))
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, selected)
+			}
+			return out, nil
+		case step.isIdx:
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array", describeAt(at))
+			}
+			n := step.index
+			if n < 0 {
+				n += len(list)
+			}
+			if n < 0 || n >= len(list) {
+				return nil, fmt.Errorf("index %d out of range at %s (length %d)", step.index, describeAt(at), len(list))
+			}
+			v = list[n]
+			at += fmt.Sprintf("[%d]", step.index)
+		default:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an object", describeAt(at))
+			}
+			field, ok := obj[step.key]
+			if !ok {
+				return nil, fmt.Errorf("no field %q at %s", step.key, describeAt(at))
+			}
+			v = field
+			at += "." + step.key
+		}
+	}
+	return v, nil
+}
+
+// describeAt names a position in the document for error messages.
+func describeAt(at string) string {
+	if at == "" {
+		return "the top level"
+	}
+	return strings.TrimPrefix(at, ".")
+}
+
+// formatQueryResult renders a selected value: strings bare, so they can be
+// used directly in shell scripts, and everything else as indented JSON.
+func formatQueryResult(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// printQueried prints the value --query selects from raw and reports
+// whether it did; without --query it does nothing, so callers fall back to
+// their usual output.  A path that does not match ends the program.
+func printQueried(raw []byte) bool {
+	if outputQuery == "" {
+		return false
+	}
+	v, err := queryJSON(redactJSONPrompts(raw), outputQuery)
+	if err == nil {
+		var out string
+		if out, err = formatQueryResult(v); err == nil {
+			fmt.Fprintln(os.Stdout, out)
+			return true
+		}
+	}
+	fail("Error", fmt.Errorf("--query: %w", err))
+	return true
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// runRaw sends an arbitrary request to the API, for endpoints the CLI does
+// not wrap, and prints the response body.
+func runRaw(raw *service.RawService, args []string) error {
+	rawCmd := flag.NewFlagSet("raw", flag.ExitOnError)
+	rawCmd.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: leonardo raw METHOD PATH [-d JSON|@file|@-] [options]")
+		fmt.Fprintln(stderr, "PATH is relative to the API base URL, e.g. /me or /generations/user/ID?limit=5.")
+		rawCmd.PrintDefaults()
+	}
+	var data string
+	rawCmd.StringVar(&data, "d", "", "JSON request body, @file to read it from a file or @- to read it from stdin")
+	rawCmd.StringVar(&data, "data", "", "Same as -d")
+	retries := rawCmd.Int("retries", 2, "Times to retry rate limits, and server errors of idempotent requests")
+	include := rawCmd.Bool("include", false, "Print the response status and headers to stderr")
+	positional, err := parseInterspersed(rawCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		rawCmd.Usage()
+		return fmt.Errorf("a METHOD and a PATH are required")
+	}
+	if *retries < 0 {
+		return fmt.Errorf("--retries must not be negative")
+	}
+	req := domain.RawRequest{Method: strings.ToUpper(positional[0]), Path: positional[1]}
+	if data != "" {
+		if req.Body, err = readRawBody(data, os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	resp, err := raw.Send(req, *retries)
+	if *include && resp.StatusCode != 0 {
+		printRawHeader(stderr, resp)
+	}
+	if err != nil {
+		if len(resp.Body) > 0 {
+			prettyPrintJSON(resp.Body)
+		}
+		return err
+	}
+	if len(resp.Body) == 0 || printQueried(resp.Body) {
+		return nil
+	}
+	prettyPrintJSON(resp.Body)
+	return nil
+}
+
+// readRawBody returns the request body -d gave: the JSON itself, or the
+// contents of the file after @, or of stdin for @-.
+func readRawBody(data string, stdin io.Reader) ([]byte, error) {
+	body := []byte(data)
+	var err error
+	switch {
+	case data == "@-":
+		body, err = io.ReadAll(stdin)
+	case strings.HasPrefix(data, "@"):
+		body, err = os.ReadFile(data[1:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("request body is not valid JSON")
+	}
+	return body, nil
+}
+
+// printRawHeader writes the status line and headers of resp, sorted by name.
+func printRawHeader(w io.Writer, resp domain.RawResponse) {
+	fmt.Fprintf(w, "HTTP %d\n", resp.StatusCode)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+}
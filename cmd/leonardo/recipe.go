@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// loadRecipe fetches the recipe given with --from-url, if any, and sets
+// the flags of fs that were not given on the command line from it.  It
+// must be called after fs is parsed and before applyKind, so that flags on
+// the command line win over the recipe and the recipe over a kind.
+func loadRecipe(fs *flag.FlagSet, recipes *service.RecipeService, url string) (domain.Recipe, error) {
+	if strings.TrimSpace(url) == "" {
+		return domain.Recipe{}, nil
+	}
+	recipe, err := recipes.Load(url)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+	if err := applyRecipe(fs, recipe); err != nil {
+		return domain.Recipe{}, err
+	}
+	if len(recipe.Ignored) > 0 {
+		fmt.Fprintln(stderr, "Ignored recipe settings that cannot be reused:", strings.Join(recipe.Ignored, ", "))
+	}
+	return recipe, nil
+}
+
+// applyRecipe sets the flags of fs that were not given on the command line
+// from recipe.
+func applyRecipe(fs *flag.FlagSet, recipe domain.Recipe) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, setting := range recipe.SettingNames() {
+		// A style given either way replaces the recipe's.
+		if explicit[setting] || (setting == "style-uuid" && explicit["style"]) || fs.Lookup(setting) == nil {
+			continue
+		}
+		if err := fs.Set(setting, recipe.Settings[setting]); err != nil {
+			return fmt.Errorf("recipe: invalid %s %q: %w", setting, recipe.Settings[setting], err)
+		}
+	}
+	return nil
+}
+
+// withRecipeElements adds the recipe's Elements the request does not
+// already use.
+func withRecipeElements(meta domain.GenerationMetadata, recipe domain.Recipe) domain.GenerationMetadata {
+	for _, e := range recipe.Elements {
+		used := false
+		for _, have := range meta.Elements {
+			used = used || have.ID == e.ID
+		}
+		if !used {
+			meta.Elements = append(meta.Elements, e)
+		}
+	}
+	return meta
+}
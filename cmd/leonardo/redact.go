@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// redactor hides API tokens and user IDs in diagnostics and, with
+// --redact-prompts, prompts in everything the CLI prints or writes.  It is
+// configured once in main.
+var redactor = domain.Redactor{}
+
+// redactingWriter passes writes through the redactor.  Each fmt.Fprint call
+// is a single write, so secrets are never split across calls.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redactor.Text(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// stderr receives all diagnostics: errors, warnings and verbose logs.
+var stderr io.Writer = redactingWriter{w: os.Stderr}
+
+// redactSecretsFromEnv reports whether tokens and user IDs should be
+// redacted.  It is on unless LEONARDO_REDACT is set to a false value, which
+// is occasionally useful when debugging with a throwaway token.
+func redactSecretsFromEnv() bool {
+	value := strings.TrimSpace(os.Getenv("LEONARDO_REDACT"))
+	if value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err != nil || enabled
+}
+
+// registerSecret hides value from diagnostics when redaction is enabled.
+func registerSecret(value string) {
+	if redactSecretsFromEnv() {
+		redactor.AddSecret(value)
+	}
+}
+
+// redactPath hides the user ID in API paths such as
+// /api/rest/v1/generations/user/{userId}.
+func redactPath(path string) string {
+	if !redactSecretsFromEnv() {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "user" && segments[i] != "" {
+			segments[i] = "[redacted]"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// promptKeys are the JSON keys holding prompts in API responses and sidecars.
+var promptKeys = map[string]bool{"prompt": true, "negativePrompt": true, "negative_prompt": true}
+
+// redactJSONPrompts replaces the prompts found anywhere in a JSON document.
+// Documents that cannot be parsed, and all documents when prompts are not
+// being redacted, are returned unchanged.
+func redactJSONPrompts(data []byte) []byte {
+	if !redactor.Prompts {
+		return data
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return data
+	}
+	redacted, err := json.Marshal(redactPromptValues(doc))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func redactPromptValues(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if s, ok := child.(string); ok && promptKeys[k] {
+				value[k] = redactor.Prompt(s)
+				continue
+			}
+			value[k] = redactPromptValues(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = redactPromptValues(child)
+		}
+	}
+	return v
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"leonardo-cli/internal/config"
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// setupEnv is what the setup wizard talks to beyond the account store: the
+// answers, where the questions go, the token already configured if any, and
+// the API calls that check a new key and list the models.
+type setupEnv struct {
+	in     *bufio.Reader
+	out    io.Writer
+	token  func() (string, bool)
+	verify func(token string) (domain.UserInfo, error)
+	models func(token string) ([]domain.PlatformModel, error)
+}
+
+// setupSettings are the answers of the wizard written to .leonardo.yaml.
+type setupSettings struct {
+	modelID   string
+	outputDir string
+	sidecar   bool
+}
+
+// runSetup asks for an API key unless one is configured, a default model
+// from the live list, an output directory and whether to write sidecars,
+// then writes the answers to .leonardo.yaml in --project-dir.  An existing file is
+// kept unless --force is given.
+func runSetup(accounts *service.AccountService, args []string, env setupEnv) error {
+	setupCmd := flag.NewFlagSet("setup", flag.ExitOnError)
+	dir := setupCmd.String("project-dir", ".", "Directory to write .leonardo.yaml to")
+	force := setupCmd.Bool("force", false, "Replace an existing .leonardo.yaml")
+	parseFlags(setupCmd, args)
+	path := filepath.Join(*dir, config.ProjectFileName)
+	if _, err := os.Stat(path); err == nil && !*force {
+		return fmt.Errorf("%s already exists; pass --force to replace it", path)
+	}
+
+	fmt.Fprintln(env.out, tr.Sprintf("Step 1 of 4: API key"))
+	token, ok := env.token()
+	if ok {
+		fmt.Fprintln(env.out, tr.Sprintf("An API key is already set up; keeping it."))
+	} else {
+		for i, step := range loginSteps {
+			fmt.Fprintf(env.out, "  %d. %s\n", i+1, tr.Sprintf(step))
+		}
+		token = ask(env, "API key: ")
+		if token == "" {
+			return fmt.Errorf("no API key given")
+		}
+		registerSecret(token)
+		if _, err := env.verify(token); err != nil {
+			return fmt.Errorf("the API did not accept the key (%s): %w", domain.ClassifyTokenError(err), err)
+		}
+		if err := accounts.Add("default", token); err != nil {
+			return err
+		}
+		if err := accounts.Use("default"); err != nil {
+			return err
+		}
+		fmt.Fprintln(env.out, tr.Sprintf("Key checked and stored as the default account."))
+	}
+
+	settings := setupSettings{sidecar: true}
+	fmt.Fprintln(env.out, tr.Sprintf("Step 2 of 4: default model"))
+	models, err := env.models(token)
+	if err != nil {
+		return fmt.Errorf("listing models: %w", err)
+	}
+	settings.modelID = chooseModel(env, models)
+
+	fmt.Fprintln(env.out, tr.Sprintf("Step 3 of 4: output directory"))
+	settings.outputDir = ask(env, "Save downloaded images to [.]: ")
+	if settings.outputDir == "" {
+		settings.outputDir = "."
+	}
+	outputDir := settings.outputDir
+	if !filepath.IsAbs(outputDir) {
+		outputDir = filepath.Join(*dir, outputDir)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(env.out, tr.Sprintf("Step 4 of 4: sidecars"))
+	answer := ask(env, "Write a JSON sidecar with each generation's prompt and settings? [Y/n]: ")
+	settings.sidecar = !strings.HasPrefix(strings.ToLower(answer), "n")
+
+	if err := os.WriteFile(path, []byte(setupConfigFile(settings)), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintln(env.out, tr.Sprintf("Settings written to"), path)
+	fmt.Fprintln(env.out, tr.Sprintf(`Try: leonardo create --prompt "a lighthouse at dusk" --wait`))
+	return nil
+}
+
+// ask prints question, translated, and returns the answer, trimmed; an empty answer at
+// the end of the input.
+func ask(env setupEnv, question string) string {
+	fmt.Fprint(env.out, tr.Sprintf(question))
+	answer, _ := readToken(env.in)
+	return answer
+}
+
+// chooseModel lists models and returns the ID of the one picked by number,
+// or an empty string when the question is skipped.
+func chooseModel(env setupEnv, models []domain.PlatformModel) string {
+	if len(models) == 0 {
+		fmt.Fprintln(env.out, tr.Sprintf("No models are available; skipping."))
+		return ""
+	}
+	for i, m := range models {
+		fmt.Fprintf(env.out, "  %2d. %s\n", i+1, m.Name)
+	}
+	for {
+		answer := ask(env, "Model number, or Enter to pick one per command: ")
+		if answer == "" {
+			return ""
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(models) {
+			return models[n-1].ID
+		}
+		fmt.Fprint(env.out, tr.Sprintf("Enter a number from 1 to %d.\n", len(models)))
+	}
+}
+
+// setupConfigFile renders the wizard's answers as a .leonardo.yaml.
+func setupConfigFile(s setupSettings) string {
+	var b strings.Builder
+	b.WriteString("# Written by leonardo setup; every key is a flag name (see README).\n")
+	if s.modelID != "" {
+		fmt.Fprintf(&b, "model-id: %s\n", s.modelID)
+	}
+	fmt.Fprintf(&b, "output-dir: %s\n", yamlScalar(s.outputDir))
+	fmt.Fprintf(&b, "sidecar: %t\n", s.sidecar)
+	return b.String()
+}
+
+// yamlScalar quotes value when the configuration file would otherwise read
+// it differently, e.g. because of a " #" or surrounding spaces.
+func yamlScalar(value string) string {
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "#:\"'[") {
+		if !strings.Contains(value, "'") {
+			return "'" + value + "'"
+		}
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// firstRun reports whether this looks like the first use of the CLI: no
+// token was named, no project settings exist and someone is at the
+// terminal to answer the wizard.
+func firstRun(opts globalOptions) bool {
+	return opts.account == "" && opts.tokenFile == "" && !opts.noConfigFile && isTerminal(os.Stdin) && loadProjectConfig() == nil
+}
+
+// offerSetup asks whether to run the setup wizard and runs it when the
+// answer is yes.  It reports whether setup completed.
+func offerSetup(accounts *service.AccountService, env setupEnv) bool {
+	fmt.Fprintln(env.out, tr.Sprintf("No API key is set up yet."))
+	answer := ask(env, "Run the first-time setup now? [Y/n]: ")
+	if strings.HasPrefix(strings.ToLower(answer), "n") {
+		return false
+	}
+	if err := runSetup(accounts, nil, env); err != nil {
+		reportError("Error setting up", err)
+		return false
+	}
+	// Pick up the settings just written.
+	projectConfigLoaded = false
+	return true
+}
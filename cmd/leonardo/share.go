@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// shareBundle is the generation.json written into a share bundle: what a
+// client needs to see how an image was made, without the account it was
+// made with.  It doubles as a recipe for create --from-url.
+type shareBundle struct {
+	GenerationID   string         `json:"generation_id"`
+	CreatedAt      time.Time      `json:"created_at,omitempty"`
+	Prompt         string         `json:"prompt,omitempty"`
+	NegativePrompt string         `json:"negative_prompt,omitempty"`
+	ModelID        string         `json:"model_id,omitempty"`
+	Width          int            `json:"width,omitempty"`
+	Height         int            `json:"height,omitempty"`
+	Seed           int            `json:"seed,omitempty"`
+	PresetStyle    string         `json:"preset_style,omitempty"`
+	GuidanceScale  float64        `json:"guidance_scale,omitempty"`
+	Alchemy        bool           `json:"alchemy,omitempty"`
+	Ultra          bool           `json:"ultra,omitempty"`
+	PhotoReal      bool           `json:"photo_real,omitempty"`
+	Elements       []shareElement `json:"elements,omitempty"`
+	Images         []string       `json:"images"`
+}
+
+// shareElement is an Element in generation.json, written as in sidecars.
+type shareElement struct {
+	ID     string  `json:"id"`
+	Weight float64 `json:"weight"`
+}
+
+// shareBundleFile and sharePageFile are the files of a share bundle besides
+// the images.
+const (
+	shareBundleFile = "generation.json"
+	sharePageFile   = "index.html"
+)
+
+// sharePage renders a bundle as a self-contained page.  Image sources are
+// file names next to the page or, with --single-file, data URLs.
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Generation {{.GenerationID}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 72rem; padding: 0 1rem; color: #222; }
+.images { display: grid; grid-template-columns: repeat(auto-fill, minmax(20rem, 1fr)); gap: 1rem; }
+.images img { width: 100%; height: auto; border-radius: 4px; }
+dl { display: grid; grid-template-columns: max-content 1fr; gap: .25rem 1rem; }
+dt { font-weight: 600; }
+dd { margin: 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<div class="images">
+{{range .Sources}}<a href="{{.}}"><img src="{{.}}" alt="Generated image"></a>
+{{end}}</div>
+<dl>
+{{with .Prompt}}<dt>Prompt</dt><dd>{{.}}</dd>
+{{end}}{{with .NegativePrompt}}<dt>Negative prompt</dt><dd>{{.}}</dd>
+{{end}}{{with .ModelID}}<dt>Model</dt><dd>{{.}}</dd>
+{{end}}{{if .Width}}<dt>Size</dt><dd>{{.Width}}×{{.Height}}</dd>
+{{end}}{{with .Seed}}<dt>Seed</dt><dd>{{.}}</dd>
+{{end}}{{with .PresetStyle}}<dt>Style</dt><dd>{{.}}</dd>
+{{end}}<dt>Generation</dt><dd>{{.GenerationID}}</dd>
+</dl>
+</body>
+</html>
+`))
+
+// runShare writes a read-only bundle of a generation — its images, an
+// index.html showing them with the prompt and settings, and
+// generation.json — that can be sent to someone without a Leonardo account.
+func runShare(svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	shareCmd := flag.NewFlagSet("share", flag.ExitOnError)
+	id := shareCmd.String("id", "", "Generation ID, ID prefix or name to share")
+	var last lastFlag
+	shareCmd.Var(&last, "last", "Share the N most recently created generations recorded locally (default 1)")
+	outputDir := shareCmd.String("output-dir", "", "Directory to write the bundle to (default share-<generation ID>)")
+	singleFile := shareCmd.Bool("single-file", false, "Write only index.html, with the images embedded in it")
+	noPrompt := shareCmd.Bool("no-prompt", false, "Leave the prompts out of the bundle")
+	parseWithLast(shareCmd, args, &last)
+	ids := targetGenerations(shareCmd, svc, lib, *id, last)
+	if *outputDir != "" && len(ids) > 1 {
+		shareCmd.Usage()
+		return fmt.Errorf("--output-dir can only be used when sharing one generation")
+	}
+	for _, genID := range ids {
+		dir := *outputDir
+		if dir == "" {
+			dir = "share-" + genID
+		}
+		if err := shareGeneration(svc, genID, dir, *singleFile, *noPrompt); err != nil {
+			return err
+		}
+		fmt.Println("Wrote", filepath.Join(dir, sharePageFile))
+	}
+	return nil
+}
+
+// shareGeneration downloads a completed generation into dir and writes its
+// page and, unless single is set, generation.json next to the images.
+func shareGeneration(svc *service.GenerationService, id, dir string, single, noPrompt bool) error {
+	detail, err := svc.Show(id)
+	if err != nil {
+		return err
+	}
+	if detail.Status != domain.GenerationComplete {
+		return fmt.Errorf("generation %s is %s; only completed generations can be shared", id, detail.Status)
+	}
+	bundle := newShareBundle(detail, noPrompt)
+	imageDir := dir
+	if single {
+		tmp, err := os.MkdirTemp("", "leonardo-share-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		imageDir = tmp
+	}
+	result, err := svc.Download(id, imageDir)
+	if err != nil {
+		return err
+	}
+	var sources []string
+	for _, path := range result.FilePaths {
+		if !single {
+			bundle.Images = append(bundle.Images, filepath.Base(path))
+			sources = append(sources, filepath.Base(path))
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, "data:image/png;base64,"+base64.StdEncoding.EncodeToString(data))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, sharePageFile))
+	if err != nil {
+		return err
+	}
+	if err := writeSharePage(f, bundle, sources); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if single {
+		return nil
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, shareBundleFile), append(data, '\n'), 0644)
+}
+
+// newShareBundle picks the fields of a generation that are shared, with
+// prompts redacted as configured or left out with noPrompt.
+func newShareBundle(d domain.GenerationDetail, noPrompt bool) shareBundle {
+	bundle := shareBundle{
+		GenerationID:  d.ID,
+		CreatedAt:     d.CreatedAt,
+		ModelID:       d.ModelID,
+		Width:         d.Width,
+		Height:        d.Height,
+		Seed:          d.Seed,
+		PresetStyle:   d.PresetStyle,
+		GuidanceScale: d.GuidanceScale,
+		Alchemy:       d.Alchemy,
+		Ultra:         d.Ultra,
+		PhotoReal:     d.PhotoReal,
+		Images:        []string{},
+	}
+	for _, e := range d.Elements {
+		bundle.Elements = append(bundle.Elements, shareElement{ID: e.ID, Weight: e.Weight})
+	}
+	if !noPrompt {
+		bundle.Prompt = redactor.Prompt(d.Prompt)
+		bundle.NegativePrompt = redactor.Prompt(d.NegativePrompt)
+	}
+	return bundle
+}
+
+// writeSharePage renders the share page for bundle with the given image
+// sources.
+func writeSharePage(w io.Writer, bundle shareBundle, sources []string) error {
+	urls := make([]template.URL, len(sources))
+	for i, src := range sources {
+		// Sources are file names written by the CLI or data URLs it
+		// encoded, which html/template would otherwise reject.
+		if !strings.HasPrefix(src, "data:image/") && strings.ContainsAny(src, ":/\\") {
+			return fmt.Errorf("unexpected image source %q", src)
+		}
+		urls[i] = template.URL(src)
+	}
+	return sharePage.Execute(w, struct {
+		shareBundle
+		Sources []template.URL
+	}{bundle, urls})
+}
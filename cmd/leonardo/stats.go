@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// callStats collects the metrics reported by the API client during a run.
+// With verbose set every call is logged as it completes; the aggregate is
+// printed by summary when --stats is given.  The latest rate limit the API
+// reported is kept, and a warning is logged once when it runs low.
+type callStats struct {
+	mu        sync.Mutex
+	calls     []domain.CallMetric
+	verbose   bool
+	log       io.Writer
+	rateLimit domain.RateLimit
+	warnedLow bool
+}
+
+// stats receives API call metrics for the current run.  It is configured
+// once in main from the global flags.
+var stats = &callStats{}
+
+// record stores a call and logs it when verbose output is on.
+func (s *callStats) record(m domain.CallMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, m)
+	if s.verbose && s.log != nil {
+		fmt.Fprintln(s.log, formatCall(m))
+	}
+	if !m.RateLimit.Known() {
+		return
+	}
+	s.rateLimit = m.RateLimit
+	if m.RateLimit.Low() && !s.warnedLow && s.log != nil {
+		s.warnedLow = true
+		fmt.Fprintf(s.log, "Warning: API rate limit nearly exhausted (%s); space out runs or lower --concurrency.\n", m.RateLimit.Describe(time.Now()))
+	}
+}
+
+// snapshot returns the calls recorded so far.
+func (s *callStats) snapshot() []domain.CallMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]domain.CallMetric(nil), s.calls...)
+}
+
+// latestRateLimit returns the most recent rate limit the API reported.
+func (s *callStats) latestRateLimit() (domain.RateLimit, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rateLimit, s.rateLimit.Known()
+}
+
+// formatCall renders a call as a single log line, e.g.
+// "GET /api/rest/v1/me -> 200 in 212ms (request ID abc123)".
+func formatCall(m domain.CallMetric) string {
+	outcome := "no response"
+	if m.StatusCode != 0 {
+		outcome = fmt.Sprintf("%d", m.StatusCode)
+	}
+	line := fmt.Sprintf("%s %s -> %s in %s", m.Method, redactPath(m.Path), outcome, m.Duration.Round(time.Millisecond))
+	if m.RequestID != "" {
+		line += fmt.Sprintf(" (request ID %s)", m.RequestID)
+	}
+	return line
+}
+
+// summary writes the number of calls, their failure count and latency
+// distribution to w.  Nothing is written when no call was made.
+func (s *callStats) summary(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.calls) == 0 {
+		return
+	}
+	durations := make([]time.Duration, 0, len(s.calls))
+	var total time.Duration
+	failed := 0
+	slowest := s.calls[0]
+	for _, m := range s.calls {
+		durations = append(durations, m.Duration)
+		total += m.Duration
+		if m.Failed() {
+			failed++
+		}
+		if m.Duration > slowest.Duration {
+			slowest = m
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	avg := total / time.Duration(len(durations))
+	fmt.Fprintf(w, "API calls: %d (%d failed)\n", len(s.calls), failed)
+	fmt.Fprintf(w, "Latency: total %s, avg %s, p50 %s, p95 %s, max %s\n",
+		total.Round(time.Millisecond), avg.Round(time.Millisecond),
+		percentile(durations, 50).Round(time.Millisecond),
+		percentile(durations, 95).Round(time.Millisecond),
+		durations[len(durations)-1].Round(time.Millisecond))
+	fmt.Fprintln(w, "Slowest:", formatCall(slowest))
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
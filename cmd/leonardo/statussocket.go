@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// statusBoard tracks the work of a long-running command for its status
+// socket and metrics endpoint.  It is safe for concurrent use.
+type statusBoard struct {
+	mu     sync.Mutex
+	status domain.DaemonStatus
+	// calls returns the API calls made so far, for the metrics.
+	calls func() []domain.CallMetric
+}
+
+// newStatusBoard returns a board for command, started now.
+func newStatusBoard(command string) *statusBoard {
+	return &statusBoard{status: domain.DaemonStatus{
+		Command:      command,
+		PID:          os.Getpid(),
+		Started:      time.Now().UTC(),
+		InFlight:     []domain.DaemonJob{},
+		RecentErrors: []domain.DaemonError{},
+	}, calls: stats.snapshot}
+}
+
+// begin records that job started with queued jobs behind it.
+func (b *statusBoard) begin(job string, queued int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status.Begin(job, queued, time.Now().UTC())
+}
+
+// end records that job finished after saving images and being charged
+// tokens, failing with err when it is not nil.
+func (b *statusBoard) end(job string, images, tokens int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status.Produced(images, tokens)
+	b.status.End(job, err, time.Now().UTC())
+}
+
+// snapshot returns a copy of the current status.
+func (b *statusBoard) snapshot() domain.DaemonStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.status
+	s.InFlight = append([]domain.DaemonJob{}, s.InFlight...)
+	s.RecentErrors = append([]domain.DaemonError{}, s.RecentErrors...)
+	return s
+}
+
+// ServeHTTP answers GET /status with the status as JSON, GET /metrics with
+// Prometheus metrics and GET /healthz with "ok" while the process is
+// running.
+func (b *statusBoard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/healthz":
+		fmt.Fprintln(w, "ok")
+	case "/metrics":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, b.calls(), b.snapshot())
+	case "/", "/status":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(b.snapshot())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveMetrics serves board over HTTP on addr, such as localhost:9464, for
+// Prometheus to scrape, until the returned function is called.
+func serveMetrics(addr string, board *statusBoard) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("opening metrics address: %w", err)
+	}
+	return serveBoard(listener, board), nil
+}
+
+// serveBoard serves board on listener in the background and returns the
+// function that stops it.
+func serveBoard(listener net.Listener, board *statusBoard) func() {
+	server := &http.Server{Handler: board, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(stderr, "Warning: status endpoint stopped:", err)
+		}
+	}()
+	return func() { server.Close() }
+}
+
+// serveStatusSocket serves board on a unix socket at path until the
+// returned function is called, which also removes the socket.  A socket
+// left behind by a process that died is replaced; one still answering is
+// not, so two commands never share a path.
+func serveStatusSocket(path string, board *statusBoard) (func(), error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("status socket %s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("status socket %s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale status socket: %w", err)
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening status socket: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("restricting status socket: %w", err)
+	}
+	stop := serveBoard(listener, board)
+	return func() {
+		stop()
+		os.Remove(path)
+	}, nil
+}
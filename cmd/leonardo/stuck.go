@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// stuckAfter is how long a generation may stay PENDING before status,
+// waits and list --stuck report it as stuck.  It is set by --stuck-after.
+var stuckAfter = domain.DefaultStuckAfter
+
+// warnStuck tells the user that generation id has been pending for too
+// long, and how to deal with it.
+func warnStuck(id string, pending time.Duration) {
+	fmt.Fprint(stderr, tr.Sprintf("Warning: generation %s has been PENDING for %s (--stuck-after %s) and may be stuck; 'leonardo list --stuck' finds such generations and can resubmit or delete them.\n",
+		id, pending.Round(time.Minute), stuckAfter))
+}
+
+// stuckActions are what list --stuck does with the generations it finds
+// besides listing them.
+type stuckActions struct {
+	resubmit bool
+	remove   bool
+	yes      bool
+}
+
+// listStuck lists the user's generations PENDING for longer than
+// stuckAfter.  When asked, and after confirmation, each one is submitted
+// again as a new generation from its record and then deleted.
+func listStuck(svc *service.GenerationService, lib *service.LibraryService, userID, timestamps string, actions stuckActions) error {
+	now := time.Now()
+	filter := domain.RemoteCleanupFilter{Statuses: []string{domain.GenerationPending}, OlderThan: stuckAfter}
+	candidates, err := svc.RemoteCleanupCandidates(userID, filter, now)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Fprintf(messages(), "No generations have been PENDING for longer than %s.\n", stuckAfter)
+		return nil
+	}
+	for _, gen := range candidates {
+		if !printFormatted(listItemOutput(gen)) {
+			printListItem(os.Stdout, gen, timestamps, now)
+		}
+	}
+	if !actions.resubmit && !actions.remove {
+		return nil
+	}
+
+	var question string
+	switch {
+	case actions.resubmit && actions.remove:
+		question = fmt.Sprintf("Resubmit and then delete these %d generations? [y/N] ", len(candidates))
+	case actions.resubmit:
+		question = fmt.Sprintf("Resubmit these %d generations as new ones? [y/N] ", len(candidates))
+	default:
+		question = fmt.Sprintf("Delete these %d generations? [y/N] ", len(candidates))
+	}
+	if !actions.yes {
+		if !isTerminal(os.Stdin) {
+			return errors.New("refusing to change generations without confirmation when stdin is not a terminal; pass --yes")
+		}
+		ok, err := confirm(os.Stdin, stderr, question)
+		if err != nil || !ok {
+			fmt.Fprintln(messages(), "Nothing changed.")
+			return err
+		}
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, gen := range candidates {
+		if actions.resubmit {
+			detail, err := svc.Show(gen.ID)
+			if err != nil {
+				return fmt.Errorf("reading %s to resubmit it: %w", gen.ID, err)
+			}
+			created, err := createGeneration(svc, lib, detail.ResubmitRequest())
+			if err != nil {
+				return fmt.Errorf("resubmitting %s: %w", gen.ID, err)
+			}
+			fmt.Fprintf(messages(), "Resubmitted %s as %s\n", colors.id(gen.ID), colors.id(created.GenerationID))
+		}
+		ids = append(ids, gen.ID)
+	}
+	if !actions.remove {
+		return nil
+	}
+	return deleteRemoteGenerations(svc, lib, ids)
+}
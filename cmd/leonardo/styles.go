@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// loadStyles returns the preset style catalog with the style.* settings of
+// the project configuration added.
+func loadStyles() ([]domain.Style, error) {
+	custom := map[string]string{}
+	if project := loadProjectConfig(); project != nil {
+		custom = project.Prefixed(domain.StyleConfigPrefix)
+	}
+	return domain.CatalogStyles(custom)
+}
+
+// resolveStyle returns the UUID of a style given by name, slug or UUID,
+// looking custom styles up as well as built-in ones.
+func resolveStyle(name string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", nil
+	}
+	styles, err := loadStyles()
+	if err != nil {
+		return "", err
+	}
+	return domain.ResolveStyleIn(styles, name)
+}
+
+// styleName returns the name of the style with the given UUID, or the UUID
+// itself when it is not in the catalog.
+func styleName(uuid string) string {
+	styles, err := loadStyles()
+	if err != nil {
+		styles = domain.Styles()
+	}
+	if name := domain.StyleName(styles, uuid); name != "" {
+		return name
+	}
+	return uuid
+}
+
+// runStyles lists the preset style catalog and, when a model is given,
+// whether that model is documented to accept style UUIDs.
+func runStyles(args []string) error {
+	stylesCmd := flag.NewFlagSet("styles", flag.ExitOnError)
+	modelID := stylesCmd.String("model-id", "", "Report whether this model accepts style UUIDs (can be set with LEONARDO_MODEL_ID)")
+	parseFlags(stylesCmd, args)
+	styles, err := loadStyles()
+	if err != nil {
+		return err
+	}
+	if outputFormat != nil {
+		for _, s := range styles {
+			printFormatted(s)
+		}
+		return nil
+	}
+	return printStyles(os.Stdout, styles, *modelID)
+}
+
+// printStyles writes the style catalog as a table followed by the models
+// the styles apply to.
+func printStyles(w io.Writer, styles []domain.Style, modelID string) error {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "NAME\tSTYLE\tUUID")
+	for _, s := range styles {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Slug(), s.UUID)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	if modelID = strings.TrimSpace(modelID); modelID != "" {
+		if name, ok := domain.StyleModels[modelID]; ok {
+			fmt.Fprintf(w, "Model %s (%s) accepts these styles.\n", modelID, name)
+		} else {
+			fmt.Fprintf(w, "Model %s is not documented to accept style UUIDs; they may be ignored.\n", modelID)
+		}
+		return nil
+	}
+	names := make([]string, 0, len(domain.StyleModels))
+	for _, name := range domain.StyleModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Styles apply to:", strings.Join(names, ", "))
+	return nil
+}
+
+// styleWarning returns a warning when a style is requested for a model that
+// is not documented to accept style UUIDs, or an empty string.
+func styleWarning(modelID, styleUUID string) string {
+	if styleUUID == "" || modelID == "" {
+		return ""
+	}
+	if _, ok := domain.StyleModels[modelID]; ok {
+		return ""
+	}
+	return fmt.Sprintf("Warning: model %s is not documented to accept style UUIDs; the style may be ignored", modelID)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// confusedFlags map flags people often type to a hint naming the one they
+// mean.  A hint naming a flag is only given when the command has it.
+var confusedFlags = map[string]struct{ flag, hint string }{
+	"model":      {"model-id", "Did you mean --model-id?  Models are chosen by ID; list them with the models command."},
+	"model-name": {"model-id", "Did you mean --model-id?  Models are chosen by ID; list them with the models command."},
+	"size":       {"width", "Did you mean --width and --height?  size: WxH is only understood in .leonardo.yaml."},
+	"n":          {"num-images", "Did you mean --num-images?"},
+	"count":      {"num-images", "Did you mean --num-images?"},
+	"images":     {"num-images", "Did you mean --num-images?"},
+	"negative":   {"negative-prompt", "Did you mean --negative-prompt?"},
+	"tag":        {"tags", "Did you mean --tags?  It takes a comma-separated list."},
+	"out":        {"output-dir", "Did you mean --output-dir?"},
+	"output":     {"output-dir", "Did you mean --output-dir?"},
+	"style-id":   {"style-uuid", "Did you mean --style-uuid, or --style with a preset name?"},
+	"token":      {"", "The API key is not a flag: set LEONARDO_API_TOKEN, pass the global --token-file or store it with login."},
+	"api-key":    {"", "The API key is not a flag: set LEONARDO_API_TOKEN, pass the global --token-file or store it with login."},
+	"json":       {"", "Did you mean the global --format json?"},
+}
+
+// unknownCommand reports a command that is neither built in nor a plugin,
+// suggesting the closest one when there is one.
+func unknownCommand(cmd string) {
+	fmt.Fprint(stderr, tr.Sprintf("Unknown command: %s\n", cmd))
+	names := []string{"help"}
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	names = append(names, findPlugins()...)
+	if name, ok := closest(cmd, names); ok {
+		fmt.Fprint(stderr, tr.Sprintf("Did you mean %q?  Run 'leonardo help' to list every command.\n", name))
+		return
+	}
+	printUsage()
+}
+
+// hintUnknownFlags arranges for the usage fs prints when parsing args fails
+// on a flag fs does not define to end with a hint at the flag meant.  It
+// must be called before fs is parsed.
+func hintUnknownFlags(fs *flag.FlagSet, args []string) {
+	name, ok := unknownFlag(fs, args)
+	if !ok {
+		return
+	}
+	hint := flagHint(fs, name)
+	if hint == "" {
+		return
+	}
+	usage := fs.Usage
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintln(fs.Output(), hint)
+	}
+}
+
+// unknownFlag returns the first flag in args that fs does not define,
+// skipping the values of the flags it does.
+func unknownFlag(fs *flag.FlagSet, args []string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if name == "h" || name == "help" {
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			return name, true
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && b.IsBoolFlag()) {
+			i++
+		}
+	}
+	return "", false
+}
+
+// flagHint returns a hint at the flag of fs meant by name: a targeted one
+// for commonly confused flags, otherwise the closest flag by spelling.
+func flagHint(fs *flag.FlagSet, name string) string {
+	if c, ok := confusedFlags[strings.ToLower(name)]; ok && (c.flag == "" || fs.Lookup(c.flag) != nil) {
+		return tr.Sprintf(c.hint)
+	}
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	if match, ok := closest(name, names); ok {
+		return tr.Sprintf("Did you mean --%s?", match)
+	}
+	return ""
+}
+
+// closest returns the candidate most like name: one that starts with it,
+// or failing that the nearest by edit distance when that is small for the
+// length of name.
+func closest(name string, candidates []string) (string, bool) {
+	name = strings.ToLower(name)
+	if len(name) >= 3 {
+		for _, c := range candidates {
+			if strings.HasPrefix(c, name) {
+				return c, true
+			}
+		}
+	}
+	best, bestDistance := "", -1
+	for _, c := range candidates {
+		if d := editDistance(name, c); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	limit := len(name) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if bestDistance < 0 || bestDistance > limit || bestDistance >= len(name) {
+		return "", false
+	}
+	return best, true
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps
+// of adjacent characters turning a into b (optimal string alignment).
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// min3 returns the smallest of three ints.
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/imaging"
+	"leonardo-cli/internal/service"
+	"leonardo-cli/internal/storage"
+)
+
+// sweepStep is one generation of a sweep, for --format.
+type sweepStep struct {
+	Weight float64
+	generationOutput
+}
+
+// runSweep submits one generation per step of an Element weight sweep with
+// the prompt and seed fixed and, with --output-dir, waits for them and
+// writes a contact sheet captioned with each weight.
+func runSweep(svc *service.GenerationService, lib *service.LibraryService, models *service.ModelService, args []string) error {
+	sweepCmd := flag.NewFlagSet("sweep", flag.ExitOnError)
+	prompt := sweepCmd.String("prompt", "", "Text prompt shared by every step (required)")
+	negativePrompt := sweepCmd.String("negative-prompt", "", "Negative prompt shared by every step")
+	modelID := sweepCmd.String("model-id", "", "Model ID to use (can be set with LEONARDO_MODEL_ID)")
+	width := sweepCmd.Int("width", 0, "Width of the generated images")
+	height := sweepCmd.Int("height", 0, "Height of the generated images")
+	seed := sweepCmd.Int("seed", 0, "Seed shared by every step (default a random one, printed)")
+	style := sweepCmd.String("style", "", "Preset style by name or UUID")
+	character := sweepCmd.String("character", "", "Apply a saved subject reference to every step (see the character command)")
+	name := sweepCmd.String("name", "", "Name the generations NAME-wWEIGHT")
+	elementID := sweepCmd.String("element", "", "ID of the Element whose weight is swept (required)")
+	from := sweepCmd.Float64("from", 0, "Weight of the first step")
+	to := sweepCmd.Float64("to", 1, "Weight of the last step")
+	steps := sweepCmd.Int("steps", 5, fmt.Sprintf("Number of evenly spaced weights, at most %d", domain.MaxSweepSteps))
+	outputDir := sweepCmd.String("output-dir", "", "Wait for the series and save each first image and a captioned contact sheet here")
+	skipModelCheck := sweepCmd.Bool("skip-model-check", false, "Submit without checking the request against the model's capabilities")
+	pollInterval := sweepCmd.Duration("poll-interval", 5*time.Second, "Delay before the first progress check with --output-dir; later checks back off")
+	waitTimeout := sweepCmd.Duration("wait-timeout", 10*time.Minute, "Give up waiting for each generation after this long with --output-dir")
+	parseFlags(sweepCmd, args)
+	if strings.TrimSpace(*prompt) == "" {
+		sweepCmd.Usage()
+		return errors.New("--prompt is required")
+	}
+	sweep := domain.WeightSweep{ElementID: strings.TrimSpace(*elementID), From: *from, To: *to, Steps: *steps}
+	if err := sweep.Validate(); err != nil {
+		sweepCmd.Usage()
+		return err
+	}
+	styleUUID, err := resolveStyle(*style)
+	if err != nil {
+		return err
+	}
+	base := domain.GenerationRequest{NumImages: 1, Metadata: domain.GenerationMetadata{
+		Name:           strings.TrimSpace(*name),
+		Prompt:         *prompt,
+		NegativePrompt: *negativePrompt,
+		ModelID:        *modelID,
+		StyleUUID:      styleUUID,
+		Seed:           *seed,
+		Width:          *width,
+		Height:         *height,
+	}}
+	if *character != "" {
+		c, err := service.NewCharacterService(storage.NewFileCharacterStore(charactersPath())).Get(*character)
+		if err != nil {
+			return err
+		}
+		base.Metadata = c.Apply(base.Metadata)
+	}
+	if base.Metadata.Seed == 0 {
+		// A fixed seed is what makes the steps comparable.
+		base.Metadata.Seed = rand.Intn(math.MaxInt32-1) + 1
+		fmt.Fprintln(messages(), "Seed:", base.Metadata.Seed)
+	}
+	if err := base.Metadata.ValidateInitImage(); err != nil {
+		return err
+	}
+	if !*skipModelCheck {
+		if err := checkCapabilities(models, base.Metadata); err != nil {
+			return err
+		}
+	}
+
+	requests := sweep.Requests(base)
+	weights := sweep.Weights()
+	created := make([]sweepStep, 0, len(requests))
+	submitted := time.Now()
+	for i, req := range requests {
+		weight := weights[i]
+		if outputFormat == nil {
+			fmt.Printf("Weight %s:\n", domain.SweepLabel(weight))
+		}
+		out, err := createGeneration(svc, lib, req)
+		if err != nil {
+			return fmt.Errorf("submitting weight %s: %w", domain.SweepLabel(weight), err)
+		}
+		created = append(created, sweepStep{Weight: weight, generationOutput: out})
+	}
+	if *outputDir == "" {
+		for _, step := range created {
+			printFormatted(step)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return err
+	}
+	panels := make([]image.Image, 0, len(created))
+	for i, step := range created {
+		if _, err := awaitGeneration(svc, lib, models, step.GenerationID, requests[i].Metadata, submitted, *pollInterval, *waitTimeout); err != nil {
+			return err
+		}
+		path, err := svc.DownloadRepresentative(step.GenerationID, *outputDir)
+		if err != nil {
+			return err
+		}
+		created[i].Files = []string{path}
+		img, err := imaging.Load(path)
+		if err != nil {
+			return err
+		}
+		panels = append(panels, imaging.Caption(img, domain.SweepLabel(step.Weight)))
+	}
+	for _, step := range created {
+		printFormatted(step)
+	}
+	sheet := filepath.Join(*outputDir, fmt.Sprintf("sweep-%s-%d.png", shortID(sweep.ElementID), base.Metadata.Seed))
+	if err := imaging.SavePNG(sheet, imaging.SideBySide(panels...)); err != nil {
+		return err
+	}
+	fmt.Fprintln(messages(), "Contact sheet saved:", sheet)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"leonardo-cli/internal/imaging"
+)
+
+// Thumbnails of downloaded images are cached in a hidden directory next to
+// them, so anything that shows many images at once can load small files
+// instead of decoding the full-size originals.
+const (
+	thumbnailDir  = ".thumbnails"
+	thumbnailSize = 256 // longest side, in pixels
+)
+
+// thumbnailPath returns where the thumbnail of the image at original is
+// cached: {dir}/.thumbnails/{name}.png.
+func thumbnailPath(original string) string {
+	name := strings.TrimSuffix(filepath.Base(original), filepath.Ext(original)) + ".png"
+	return filepath.Join(filepath.Dir(original), thumbnailDir, name)
+}
+
+// thumbnail returns the path of the cached thumbnail of original, writing
+// it first when it is missing or older than the original.
+func thumbnail(original string) (string, error) {
+	path := thumbnailPath(original)
+	src, err := os.Stat(original)
+	if err != nil {
+		return "", err
+	}
+	if cached, err := os.Stat(path); err == nil && !cached.ModTime().Before(src.ModTime()) {
+		return path, nil
+	}
+	img, err := imaging.Load(original)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("creating thumbnail directory: %w", err)
+	}
+	if err := imaging.SavePNG(path, imaging.Thumbnail(img, thumbnailSize)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writeThumbnails caches a thumbnail of every image in paths.  A thumbnail
+// that cannot be written only produces a warning; the download itself
+// succeeded.
+func writeThumbnails(paths []string) {
+	for _, original := range paths {
+		if _, err := thumbnail(original); err != nil {
+			fmt.Fprintf(stderr, "Warning: could not write a thumbnail of %s: %v\n", original, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// urlOutput is the record --format sees for each URL printed by urls.
+type urlOutput struct {
+	GenerationID string
+	Image        int
+	Variation    string
+	URL          string
+}
+
+// runURLs prints the CDN URLs of the images of the generations named by
+// --id or --last, one per line, without downloading anything, for external
+// downloaders such as aria2 or wget.  With --html each URL is printed as an
+// img tag instead.
+func runURLs(svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	urlsCmd := flag.NewFlagSet("urls", flag.ExitOnError)
+	id := urlsCmd.String("id", "", "Generation ID, ID prefix or name to list the image URLs of")
+	var last lastFlag
+	urlsCmd.Var(&last, "last", "List the URLs of the N most recently created generations recorded locally (default 1)")
+	includeVariations := urlsCmd.Bool("include-variations", false, "Also list upscaled, background-removed and other finished variations of each image")
+	asHTML := urlsCmd.Bool("html", false, "Print an <img> tag for each image, with the prompt as alt text, instead of the bare URL")
+	parseWithLast(urlsCmd, args, &last)
+	for _, genID := range targetGenerations(urlsCmd, svc, lib, *id, last) {
+		detail, err := svc.Show(genID)
+		if err != nil {
+			return err
+		}
+		urls, err := detail.ImageURLs(*includeVariations)
+		if err != nil {
+			return err
+		}
+		for _, u := range urls {
+			if printFormatted(urlOutput{GenerationID: genID, Image: u.Image, Variation: u.Variation, URL: u.URL}) {
+				continue
+			}
+			if *asHTML {
+				printImageTag(os.Stdout, u, redactor.Prompt(detail.Prompt))
+			} else {
+				fmt.Println(u.URL)
+			}
+		}
+	}
+	return nil
+}
+
+// printImageTag writes u as an img tag with alt as its alternative text,
+// both escaped for HTML.
+func printImageTag(w io.Writer, u domain.ImageURL, alt string) {
+	fmt.Fprintf(w, "<img src=\"%s\" alt=\"%s\">\n", html.EscapeString(u.URL), html.EscapeString(alt))
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// printUsageUsage prints the usage subcommands.
+func printUsageUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo usage <subcommand> [options]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  remote [--days N | --since YYYY-MM-DD]   Tokens consumed per day, derived from the generation history")
+	fmt.Fprintln(stderr, "  budget [--by project|tag] [--month YYYY-MM] [--output FILE]   Recorded spend per month and project or tag as CSV")
+}
+
+// runUsage dispatches the usage subcommands that read the API; usage
+// budget only reads the library and is run before a token is required.
+func runUsage(usage *service.UsageService, svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	if len(args) == 0 {
+		printUsageUsage()
+		return fmt.Errorf("usage subcommand is required")
+	}
+	switch args[0] {
+	case "remote":
+		return runUsageRemote(usage, svc, lib, args[1:])
+	}
+	printUsageUsage()
+	return fmt.Errorf("unknown usage subcommand %q", args[0])
+}
+
+// runUsageRemote prints the tokens consumed per day since --since or over
+// the last --days days.
+func runUsageRemote(usage *service.UsageService, svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	remoteCmd := flag.NewFlagSet("usage remote", flag.ExitOnError)
+	days := remoteCmd.Int("days", 30, "Number of days to report, today included")
+	sinceFlag := remoteCmd.String("since", "", "Report from this date (YYYY-MM-DD) instead of --days")
+	parseFlags(remoteCmd, args)
+	since, err := usageSince(*sinceFlag, *days, time.Now())
+	if err != nil {
+		remoteCmd.Usage()
+		return err
+	}
+
+	info, err := svc.UserInfo()
+	if err != nil {
+		return err
+	}
+	recorded, err := lib.Costs()
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: could not read recorded costs from library:", err)
+	}
+	fmt.Fprintf(messages(), "Reading generations since %s...\n", since.Format("2006-01-02"))
+	report, err := usage.Remote(runCtx, info.UserID, since, recorded, time.Local)
+	if err != nil {
+		return err
+	}
+	if outputFormat != nil {
+		for _, day := range report.Days {
+			printFormatted(day)
+		}
+		return nil
+	}
+	if len(report.Days) == 0 {
+		fmt.Println("No completed generations in this period.")
+	} else if err := printUsageReport(os.Stdout, report); err != nil {
+		return err
+	}
+	if report.Unpriced > 0 {
+		fmt.Fprintf(stderr, "Warning: %d generations could not be priced; their tokens are missing from the totals\n", report.Unpriced)
+	}
+	return nil
+}
+
+// usageSince returns the start of the reporting period: midnight local
+// time on since, or days days ago counting today.
+func usageSince(since string, days int, now time.Time) (time.Time, error) {
+	if since != "" {
+		t, err := time.ParseInLocation("2006-01-02", since, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --since %q, want YYYY-MM-DD", since)
+		}
+		return t, nil
+	}
+	if days < 1 {
+		return time.Time{}, fmt.Errorf("--days must be at least 1")
+	}
+	y, m, d := now.Date()
+	return time.Date(y, m, d-days+1, 0, 0, 0, 0, now.Location()), nil
+}
+
+// printUsageReport writes one row per day and a total, marking days whose
+// tokens were partly estimated with the pricing calculator.
+func printUsageReport(w io.Writer, report domain.UsageReport) error {
+	tw := newTable(w)
+	fmt.Fprintln(tw, "DATE\tGENERATIONS\tIMAGES\tTOKENS")
+	for _, day := range append(report.Days, report.Total()) {
+		mark := ""
+		if day.Estimated > 0 {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d%s\n", day.Date, day.Generations, day.Images, day.Tokens, mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if total := report.Total(); total.Estimated > 0 {
+		fmt.Fprintf(w, "* includes %d generations priced with the pricing calculator because their cost was not recorded\n", total.Estimated)
+	}
+	return nil
+}
+
+// budgetColumns is the header of the budget CSV.
+var budgetColumns = []string{"month", "group", "generations", "tokens", "uncosted_generations"}
+
+// runUsageBudget writes the token spend recorded in the local library per
+// month and per project or tag as CSV, for charging it back.
+func runUsageBudget(lib *service.LibraryService, args []string) error {
+	budgetCmd := flag.NewFlagSet("usage budget", flag.ExitOnError)
+	by := budgetCmd.String("by", domain.BudgetByProject, "Group spend by project (project:NAME tags) or by tag")
+	month := budgetCmd.String("month", "", "Only report this month, as YYYY-MM")
+	output := budgetCmd.String("output", "", "Write the CSV to this file instead of stdout")
+	parseFlags(budgetCmd, args)
+	if *month != "" {
+		if _, err := time.Parse("2006-01", *month); err != nil {
+			budgetCmd.Usage()
+			return fmt.Errorf("invalid --month %q, want YYYY-MM", *month)
+		}
+	}
+	lines, err := lib.Budget(*by, *month, time.Local)
+	if err != nil {
+		budgetCmd.Usage()
+		return err
+	}
+	if *output == "" {
+		return writeBudgetCSV(os.Stdout, lines)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := writeBudgetCSV(f, lines); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(messages(), "Wrote %d rows to %s\n", len(lines), *output)
+	return nil
+}
+
+// writeBudgetCSV writes budget lines as CSV with a header row.
+func writeBudgetCSV(w io.Writer, lines []domain.BudgetLine) error {
+	cw := csv.NewWriter(w)
+	cw.Write(budgetColumns)
+	for _, line := range lines {
+		cw.Write([]string{
+			line.Month,
+			line.Group,
+			strconv.Itoa(line.Generations),
+			strconv.Itoa(line.Tokens),
+			strconv.Itoa(line.Uncosted),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// runVariations requests several variations of a generation's images at
+// once and downloads them into one folder per image.
+func runVariations(svc *service.GenerationService, lib *service.LibraryService, args []string) error {
+	variationsCmd := flag.NewFlagSet("variations", flag.ExitOnError)
+	id := variationsCmd.String("id", "", "Generation ID, ID prefix or name whose images to process")
+	var last lastFlag
+	variationsCmd.Var(&last, "last", "Process the N most recently created generations recorded locally (default 1)")
+	types := variationsCmd.String("types", "", "Comma-separated variation types: upscale, nobg, unzoom (required)")
+	image := variationsCmd.Int("image", 0, "Only process image N of the generation (default all)")
+	outputDir := variationsCmd.String("output-dir", ".", "Directory to create the <generation-id>/image-<n>/ folders in")
+	pollInterval := variationsCmd.Duration("poll-interval", 5*time.Second, "How often to check the variation jobs")
+	waitTimeout := variationsCmd.Duration("wait-timeout", 10*time.Minute, "Give up waiting for a variation after this long")
+	targetWidth := variationsCmd.Int("target-width", 0, "Upscale to this width, keeping the aspect ratio, with the universal upscaler")
+	targetHeight := variationsCmd.Int("target-height", 0, "Upscale to this height, keeping the aspect ratio, with the universal upscaler")
+	parseWithLast(variationsCmd, args, &last)
+	if strings.TrimSpace(*types) == "" {
+		variationsCmd.Usage()
+		return fmt.Errorf("--types is required")
+	}
+	kinds, err := domain.ParseVariationKinds(*types)
+	if err != nil {
+		return err
+	}
+	target := domain.UpscaleTarget{Width: *targetWidth, Height: *targetHeight}
+	if *targetWidth < 0 || *targetHeight < 0 {
+		return fmt.Errorf("--target-width and --target-height must not be negative")
+	}
+	if !target.IsZero() {
+		if !domain.HasVariationKind(kinds, domain.VariationUpscale) {
+			return fmt.Errorf("--target-width and --target-height need --types upscale")
+		}
+		svc.SetUpscaleTarget(target)
+	}
+	var failed int
+	for _, genID := range targetGenerations(variationsCmd, svc, lib, *id, last) {
+		fmt.Printf("Requesting %s for generation %s...\n", *types, colors.id(genID))
+		outcomes, err := svc.FanOutVariations(genID, *image, kinds, *outputDir, *pollInterval, *waitTimeout)
+		if err != nil {
+			return err
+		}
+		for _, o := range outcomes {
+			if o.Err != nil {
+				failed++
+				fmt.Fprintf(stderr, "Image %d %s failed: %v\n", o.Image, o.Kind, o.Err)
+				continue
+			}
+			fmt.Printf("Image %d %s saved: %s\n", o.Image, o.Kind, o.Path)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d variations failed", failed)
+	}
+	return nil
+}
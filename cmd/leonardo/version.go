@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/provider"
+)
+
+// version, commit and buildDate describe the build.  Release builds set
+// them with -ldflags "-X main.version=... -X main.commit=... -X
+// main.buildDate=..."; otherwise they come from the VCS stamp Go records.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// buildInfo describes this binary.
+type buildInfo struct {
+	Version   string
+	Commit    string
+	Modified  bool
+	BuildDate string
+	GoVersion string
+}
+
+// currentBuild returns the build description, falling back to the module
+// version and VCS settings embedded by the Go toolchain for values not set
+// at link time.
+func currentBuild() buildInfo {
+	b := buildInfo{Version: version, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	if b.Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		b.Version = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if b.Commit == "" {
+				b.Commit = s.Value
+			}
+		case "vcs.time":
+			if b.BuildDate == "" {
+				b.BuildDate = s.Value
+			}
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
+
+// runVersion prints the build and the API it targets.  With --check-api
+// it also probes the API through check and returns 1 unless every endpoint
+// passed.
+func runVersion(args []string, check func() ([]domain.EndpointCheck, error)) int {
+	versionCmd := flag.NewFlagSet("version", flag.ExitOnError)
+	checkAPI := versionCmd.Bool("check-api", false, "Probe the API to confirm it still answers as this version expects (needs a token)")
+	parseFlags(versionCmd, args)
+	if !printFormatted(currentBuild()) {
+		printVersion(os.Stdout, currentBuild())
+	}
+	if !*checkAPI {
+		return 0
+	}
+	checks, err := check()
+	if err != nil {
+		reportError("Error", err)
+		return 1
+	}
+	fmt.Println()
+	printChecks(os.Stdout, checks)
+	if !domain.Compatible(checks) {
+		fmt.Fprintln(stderr, "The API did not answer as expected; this version may need updating.")
+		return 1
+	}
+	fmt.Println("API compatible.")
+	return 0
+}
+
+// printVersion writes the build description and the API endpoints used.
+func printVersion(w io.Writer, b buildInfo) {
+	fmt.Fprintln(w, "leonardo", b.Version)
+	tw := newTable(w)
+	commit := b.Commit
+	if commit == "" {
+		commit = "unknown"
+	} else if b.Modified {
+		commit += " (modified)"
+	}
+	built := b.BuildDate
+	if built == "" {
+		built = "unknown"
+	}
+	fmt.Fprintf(tw, "commit:\t%s\n", commit)
+	fmt.Fprintf(tw, "built:\t%s\n", built)
+	fmt.Fprintf(tw, "go:\t%s\n", b.GoVersion)
+	fmt.Fprintf(tw, "API:\t%s (%s)\n", provider.APIVersion, provider.APIBaseURL)
+	tw.Flush()
+	fmt.Fprintln(w, "Endpoints:")
+	for _, endpoint := range provider.Endpoints {
+		fmt.Fprintln(w, " ", endpoint)
+	}
+}
+
+// printChecks writes one line per probed endpoint.
+func printChecks(w io.Writer, checks []domain.EndpointCheck) {
+	tw := newTable(w)
+	for _, c := range checks {
+		result := "ok"
+		if !c.OK() {
+			result = "FAIL: " + c.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Endpoint, c.Duration.Round(time.Millisecond), result)
+	}
+	tw.Flush()
+}
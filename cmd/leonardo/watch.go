@@ -0,0 +1,246 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fileState is what the watcher remembers about a file between scans.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	done    bool
+}
+
+// folderWatcher polls a directory for new images.  A file is reported once
+// its size and modification time are unchanged between two scans, so files
+// still being copied in are not picked up half written.
+type folderWatcher struct {
+	dir   string
+	files map[string]*fileState
+}
+
+// newFolderWatcher returns a watcher for dir.  Unless existing is set, the
+// images already in dir are treated as handled.
+func newFolderWatcher(dir string, existing bool) (*folderWatcher, error) {
+	w := &folderWatcher{dir: dir, files: map[string]*fileState{}}
+	if _, err := w.scan(); err != nil {
+		return nil, err
+	}
+	for _, state := range w.files {
+		state.done = !existing
+	}
+	return w, nil
+}
+
+// scan lists the images in the directory and returns the ones that became
+// ready since the last scan, sorted by name.  Subdirectories are not
+// searched.
+func (w *folderWatcher) scan() ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading watched directory: %w", err)
+	}
+	var ready []string
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if _, err := domain.InitImageExtension(entry.Name()); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(w.dir, entry.Name())
+		seen[path] = true
+		state, ok := w.files[path]
+		if !ok {
+			w.files[path] = &fileState{size: info.Size(), modTime: info.ModTime()}
+			continue
+		}
+		if state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			// Replaced or still being written: wait for it to settle.
+			state.size, state.modTime, state.done = info.Size(), info.ModTime(), false
+			continue
+		}
+		if !state.done {
+			state.done = true
+			ready = append(ready, path)
+		}
+	}
+	for path := range w.files {
+		if !seen[path] {
+			delete(w.files, path)
+		}
+	}
+	sort.Strings(ready)
+	return ready, nil
+}
+
+// settled reports whether every file seen has been handled.
+func (w *folderWatcher) settled() bool {
+	for _, state := range w.files {
+		if !state.done {
+			return false
+		}
+	}
+	return true
+}
+
+// targetFileSystem returns the file system named by a --filesystem flag,
+// or this machine's when it is empty.
+func targetFileSystem(name string) (domain.FileSystem, error) {
+	if strings.TrimSpace(name) == "" {
+		return domain.HostFileSystem(runtime.GOOS), nil
+	}
+	return domain.ParseFileSystem(name)
+}
+
+// runWatchFolder restyles every image that appears in a directory with an
+// image-to-image preset, writing the results to an output directory.
+func runWatchFolder(svc *service.GenerationService, lib *service.LibraryService, models *service.ModelService, images *service.InitImageService, args []string) error {
+	watchCmd := flag.NewFlagSet("watch-folder", flag.ExitOnError)
+	dir := watchCmd.String("dir", "", "Directory to watch for new images (required)")
+	outputDir := watchCmd.String("output-dir", "", "Directory to write restyled images to (required)")
+	prompt := watchCmd.String("prompt", "", "Prompt of the image-to-image preset (required)")
+	negativePrompt := watchCmd.String("negative-prompt", "", "Optional negative prompt")
+	modelID := watchCmd.String("model-id", "", "Model ID to use (can be set with LEONARDO_MODEL_ID)")
+	style := watchCmd.String("style", "", "Preset style by name, e.g. cinematic")
+	initStrength := watchCmd.Float64("init-strength", 0.5, "How strongly the source image shapes the result (0.1-0.9)")
+	width := watchCmd.Int("width", 0, "Width of the generated images")
+	height := watchCmd.Int("height", 0, "Height of the generated images")
+	numImages := watchCmd.Int("num-images", 1, "Number of images per source image")
+	alchemy := watchCmd.Bool("alchemy", false, "Enable Alchemy")
+	interval := watchCmd.Duration("interval", 2*time.Second, "How often to look for new images")
+	pollInterval := watchCmd.Duration("poll-interval", 5*time.Second, "Delay before the first check of a generation while waiting; later checks back off")
+	waitTimeout := watchCmd.Duration("wait-timeout", 10*time.Minute, "Give up waiting for a generation after this long")
+	existing := watchCmd.Bool("existing", false, "Also process the images already in the directory")
+	once := watchCmd.Bool("once", false, "Process the images currently in the directory and exit")
+	statusSocket := watchCmd.String("status-socket", "", "Serve the images in flight, queue depth and recent errors as JSON on this unix socket, for supervisors")
+	metricsAddr := watchCmd.String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address, e.g. localhost:9464")
+	fileSystem := watchCmd.String("filesystem", "", "File system the result names must be valid on: portable, windows, macos or linux (default this machine's; use portable for network shares)")
+	parseFlags(watchCmd, args)
+	if strings.TrimSpace(*dir) == "" || strings.TrimSpace(*outputDir) == "" || strings.TrimSpace(*prompt) == "" {
+		watchCmd.Usage()
+		return fmt.Errorf("--dir, --output-dir and --prompt are required")
+	}
+	if filepath.Clean(*dir) == filepath.Clean(*outputDir) {
+		return fmt.Errorf("--output-dir must differ from --dir, or results would be restyled again")
+	}
+	fs, err := targetFileSystem(*fileSystem)
+	if err != nil {
+		return err
+	}
+	svc.SetFileSystem(fs)
+	styleUUID, err := resolveStyle(*style)
+	if err != nil {
+		return err
+	}
+	preset := domain.GenerationRequest{
+		NumImages: *numImages,
+		Metadata: domain.GenerationMetadata{
+			Prompt:         *prompt,
+			NegativePrompt: *negativePrompt,
+			ModelID:        *modelID,
+			StyleUUID:      styleUUID,
+			Width:          *width,
+			Height:         *height,
+			Alchemy:        *alchemy,
+			InitStrength:   *initStrength,
+		},
+	}
+	if err := domain.ValidateInitStrength(*initStrength); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	restyler := service.NewRestyleService(svc, images)
+	watcher, err := newFolderWatcher(*dir, *existing || *once)
+	if err != nil {
+		return err
+	}
+	board := newStatusBoard("watch-folder")
+	if *statusSocket != "" {
+		stop, err := serveStatusSocket(*statusSocket, board)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	if *metricsAddr != "" {
+		stop, err := serveMetrics(*metricsAddr, board)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	if !*once {
+		fmt.Printf("Watching %s for new images (Ctrl-C to stop)...\n", *dir)
+	}
+	for {
+		select {
+		case <-time.After(*interval):
+		case <-runCtx.Done():
+			return nil
+		}
+		ready, err := watcher.scan()
+		if err != nil {
+			return err
+		}
+		for i, path := range ready {
+			board.begin(path, len(ready)-i-1)
+			result, err := restyle(restyler, lib, models, path, preset, *outputDir, *pollInterval, *waitTimeout)
+			board.end(path, len(result.FilePaths), result.Cost, err)
+		}
+		if *once && len(ready) == 0 && watcher.settled() {
+			return nil
+		}
+	}
+}
+
+// restyle runs one image through the preset, reports the outcome and
+// returns it.  Failures are reported without stopping the watcher.
+func restyle(restyler *service.RestyleService, lib *service.LibraryService, models *service.ModelService, path string, preset domain.GenerationRequest, outputDir string, interval, timeout time.Duration) (service.RestyleResult, error) {
+	meta := preset.Metadata
+	if estimate, ok := lib.EstimateWait(meta.ModelID, meta.Width, meta.Height); ok {
+		fmt.Printf("Restyling %s (%s)\n", path, estimate.Describe(modelLabel(models, meta.ModelID)))
+	} else {
+		fmt.Println("Restyling", path)
+	}
+	result, err := restyler.Restyle(path, preset, outputDir, interval, timeout)
+	if result.GenerationID != "" {
+		entry := domain.LibraryEntry{
+			GenerationID: result.GenerationID,
+			Prompt:       meta.Prompt,
+			ModelID:      meta.ModelID,
+			CreatedAt:    time.Now().UTC(),
+			Width:        meta.Width,
+			Height:       meta.Height,
+			Duration:     result.Duration,
+		}
+		if lerr := lib.Record(entry); lerr != nil {
+			fmt.Fprintln(stderr, "Warning: could not record generation in library:", lerr)
+		}
+	}
+	for _, fp := range result.FilePaths {
+		fmt.Println("  saved", fp)
+	}
+	writeThumbnails(result.FilePaths)
+	if err != nil {
+		reportError("Error restyling "+path, err)
+	}
+	return result, err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+)
+
+// printWebhookUsage prints the webhook subcommands.
+func printWebhookUsage() {
+	fmt.Fprintln(stderr, "Usage: leonardo webhook <subcommand> [options]")
+	fmt.Fprintln(stderr, "Subcommands:")
+	fmt.Fprintln(stderr, "  verify --secret <s> --signature <sig> --payload <file>  Check a recorded payload's signature")
+	fmt.Fprintln(stderr, "  sign --secret <s> --payload <file>                      Print the signature of a payload")
+}
+
+// runWebhook dispatches the webhook subcommands.  They work on recorded
+// payloads and need no API token.
+func runWebhook(args []string) error {
+	if len(args) == 0 {
+		printWebhookUsage()
+		return fmt.Errorf("webhook subcommand is required")
+	}
+	sub, rest := args[0], args[1:]
+	fs := flag.NewFlagSet("webhook "+sub, flag.ExitOnError)
+	secret := fs.String("secret", "", "Webhook secret (can be set with LEONARDO_SECRET)")
+	payloadPath := fs.String("payload", "-", "File holding the raw request body, or - for standard input")
+	var signature *string
+	switch sub {
+	case "verify":
+		signature = fs.String("signature", "", "Signature sent with the payload, with or without a sha256= prefix (required)")
+	case "sign":
+	default:
+		printWebhookUsage()
+		return fmt.Errorf("unknown webhook subcommand: %s", sub)
+	}
+	parseFlags(fs, rest)
+	if *secret == "" {
+		fs.Usage()
+		return fmt.Errorf("--secret is required")
+	}
+	registerSecret(*secret)
+	payload, err := readPayload(*payloadPath)
+	if err != nil {
+		return err
+	}
+	if sub == "sign" {
+		fmt.Println(domain.WebhookSignature(*secret, payload))
+		return nil
+	}
+	if strings.TrimSpace(*signature) == "" {
+		fs.Usage()
+		return fmt.Errorf("--signature is required")
+	}
+	if err := domain.VerifyWebhookSignature(*secret, payload, *signature); err != nil {
+		return err
+	}
+	fmt.Println("Signature is valid")
+	return nil
+}
+
+// readPayload reads a recorded webhook body from path, or from standard
+// input when path is "-".  The bytes are returned unchanged, since any
+// difference, even trailing whitespace, changes the signature.
+func readPayload(path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading payload from standard input: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading payload: %w", err)
+	}
+	return data, nil
+}
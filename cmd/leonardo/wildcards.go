@@ -0,0 +1,25 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"leonardo-cli/internal/service"
+	"leonardo-cli/internal/storage"
+)
+
+// wildcardDirs returns where wordlists are looked up: dir when given,
+// otherwise ./wildcards followed by the wildcards directory under the CLI's
+// state directory.
+func wildcardDirs(dir string) []string {
+	if dir != "" {
+		return []string{dir}
+	}
+	return []string{"wildcards", filepath.Join(leonardoHome(), "wildcards")}
+}
+
+// newWildcardService returns a service resolving prompt wildcards from the
+// wordlists in dir, or the default directories when dir is empty.
+func newWildcardService(dir string) *service.WildcardService {
+	return service.NewWildcardService(storage.NewDirWordlists(wildcardDirs(dir)...), time.Now().UnixNano())
+}
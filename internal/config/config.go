@@ -0,0 +1,91 @@
+// Package config resolves CLI settings from sources other than the command
+// line.  Every flag can be set through an environment variable named after
+// it or in a project-local .leonardo.yaml.  Values are resolved with the
+// precedence flag > environment > configuration file.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment variable read by the CLI.
+const EnvPrefix = "LEONARDO_"
+
+// Source provides values for flags that were not given on the command line.
+type Source interface {
+	// Lookup returns the value configured for the named flag, and a
+	// description of where it came from for error messages.
+	Lookup(flagName string) (value, origin string, ok bool)
+}
+
+// EnvVar returns the environment variable mapped to a flag, e.g.
+// LEONARDO_MODEL_ID for --model-id.
+func EnvVar(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// EnvSource reads flag values from LEONARDO_* environment variables.  Blank
+// variables are treated as unset.
+type EnvSource struct{}
+
+// NewEnvSource constructs an EnvSource reading the process environment.
+func NewEnvSource() *EnvSource {
+	return &EnvSource{}
+}
+
+// Lookup implements the Source interface.
+func (s *EnvSource) Lookup(flagName string) (string, string, bool) {
+	name := EnvVar(flagName)
+	value, ok := os.LookupEnv(name)
+	value = strings.TrimSpace(value)
+	if !ok || value == "" {
+		return "", "", false
+	}
+	return value, "environment variable " + name, true
+}
+
+// targetFlags name the generation or file a command acts on, actions
+// such as list --stuck --delete that change generations in bulk, and the
+// confirmations and safeguards of destructive commands: --yes, --force,
+// --keep-favorites and --dry-run.  They are never taken from the
+// environment or a configuration file, so a stray LEONARDO_ID can never
+// make delete act on the wrong generation, nor a checked-in .leonardo.yaml
+// skip a confirmation or turn a safeguard off.
+var targetFlags = map[string]bool{
+	"id": true, "last": true, "name": true, "file": true, "delete": true, "resubmit": true,
+	"yes": true, "force": true, "keep-favorites": true, "dry-run": true,
+}
+
+// CommandLineOnly reports whether the flag name is one of targetFlags,
+// which Apply never sets and plugins never receive.
+func CommandLineOnly(name string) bool {
+	return targetFlags[name]
+}
+
+// Apply sets every flag in fs that was not given on the command line from
+// the first source holding a value for it.  It must be called after fs has
+// been parsed.
+func Apply(fs *flag.FlagSet, sources ...Source) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] || targetFlags[f.Name] {
+			return
+		}
+		for _, source := range sources {
+			value, origin, ok := source.Lookup(f.Name)
+			if !ok {
+				continue
+			}
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for -%s from %s: %w", value, f.Name, origin, setErr)
+			}
+			return
+		}
+	})
+	return err
+}
@@ -0,0 +1,253 @@
+package config_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"leonardo-cli/internal/config"
+)
+
+// mapSource is a Source backed by a map, standing in for a config file.
+type mapSource map[string]string
+
+func (m mapSource) Lookup(flagName string) (string, string, bool) {
+	value, ok := m[flagName]
+	return value, "test config", ok
+}
+
+// setEnv sets environment variables for the duration of the test.
+func setEnv(t *testing.T, env map[string]string) {
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+func TestEnvVar_MapsFlagNames(t *testing.T) {
+	cases := map[string]string{
+		"model-id":   "LEONARDO_MODEL_ID",
+		"width":      "LEONARDO_WIDTH",
+		"output-dir": "LEONARDO_OUTPUT_DIR",
+	}
+	for flagName, expected := range cases {
+		if got := config.EnvVar(flagName); got != expected {
+			t.Errorf("expected %q for %q, got %q", expected, flagName, got)
+		}
+	}
+}
+
+func TestApply_PrefersFlagThenEnvThenConfig(t *testing.T) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	model := fs.String("model-id", "", "")
+	width := fs.Int("width", 0, "")
+	height := fs.Int("height", 0, "")
+	private := fs.Bool("private", false, "")
+	fs.Parse([]string{"--width", "512"})
+
+	setEnv(t, map[string]string{"LEONARDO_WIDTH": "768", "LEONARDO_MODEL_ID": " model-env ", "LEONARDO_PRIVATE": "true", "LEONARDO_HEIGHT": ""})
+	file := mapSource{"model-id": "model-file", "height": "640"}
+	if err := config.Apply(fs, config.NewEnvSource(), file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *width != 512 {
+		t.Errorf("expected flag value 512 to win, got %d", *width)
+	}
+	if *model != "model-env" {
+		t.Errorf("expected environment to win over config, got %q", *model)
+	}
+	if *height != 640 {
+		t.Errorf("expected config value 640, got %d", *height)
+	}
+	if !*private {
+		t.Error("expected private to be enabled from the environment")
+	}
+}
+
+func TestApply_IgnoresBlankEnvAndTargetFlags(t *testing.T) {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	id := fs.String("id", "", "")
+	model := fs.String("model-id", "default", "")
+	remove := fs.Bool("delete", false, "")
+	yes := fs.Bool("yes", false, "")
+	fs.Parse(nil)
+
+	setEnv(t, map[string]string{"LEONARDO_ID": "gen-1", "LEONARDO_MODEL_ID": "  ", "LEONARDO_DELETE": "true", "LEONARDO_YES": "true"})
+	if err := config.Apply(fs, config.NewEnvSource()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *id != "" {
+		t.Errorf("expected --id to ignore the environment, got %q", *id)
+	}
+	if *model != "default" {
+		t.Errorf("expected blank variable to be ignored, got %q", *model)
+	}
+	if *remove {
+		t.Error("expected --delete to ignore the environment")
+	}
+	if *yes {
+		t.Error("expected --yes to ignore the environment")
+	}
+}
+
+func TestApply_IgnoresSafeguardFlags(t *testing.T) {
+	cases := []struct {
+		flag, env, value string
+		def              bool
+	}{
+		{"force", "LEONARDO_FORCE", "true", false},
+		{"keep-favorites", "LEONARDO_KEEP_FAVORITES", "false", true},
+		{"dry-run", "LEONARDO_DRY_RUN", "false", true},
+	}
+	for _, c := range cases {
+		t.Run(c.flag, func(t *testing.T) {
+			fs := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+			value := fs.Bool(c.flag, c.def, "")
+			fs.Parse(nil)
+
+			setEnv(t, map[string]string{c.env: c.value})
+			if err := config.Apply(fs, config.NewEnvSource()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *value != c.def {
+				t.Errorf("expected --%s to ignore %s=%s", c.flag, c.env, c.value)
+			}
+		})
+	}
+}
+
+func TestApply_ReportsInvalidValuesWithTheirOrigin(t *testing.T) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.Int("width", 0, "")
+	fs.Parse(nil)
+
+	setEnv(t, map[string]string{"LEONARDO_WIDTH": "wide"})
+	err := config.Apply(fs, config.NewEnvSource())
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "LEONARDO_WIDTH") {
+		t.Errorf("expected error to name the variable, got %q", err.Error())
+	}
+}
+
+func TestFindProjectFile_WalksUpParentDirectories(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "shots", "day1")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("creating directories: %v", err)
+	}
+	if _, ok := config.FindProjectFile(nested); ok {
+		t.Fatal("expected no project file yet")
+	}
+	want := filepath.Join(root, config.ProjectFileName)
+	if err := os.WriteFile(want, []byte("model: m\n"), 0644); err != nil {
+		t.Fatalf("writing project file: %v", err)
+	}
+	got, ok := config.FindProjectFile(nested)
+	if !ok || got != want {
+		t.Errorf("expected %q, got %q (found %v)", want, got, ok)
+	}
+}
+
+func TestLoadFile_ParsesFlatSettings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, config.ProjectFileName)
+	content := `# project defaults
+model: "model-project"   # Phoenix
+size: 1024x768
+output_dir: renders
+tags:
+  - harbour
+  - 'night shots'
+private: true
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing project file: %v", err)
+	}
+	source, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"model-id":   "model-project",
+		"width":      "1024",
+		"height":     "768",
+		"output-dir": filepath.Join(dir, "renders"),
+		"tags":       "harbour,night shots",
+		"private":    "true",
+	}
+	for flagName, want := range expected {
+		got, origin, ok := source.Lookup(flagName)
+		if !ok || got != want {
+			t.Errorf("expected %s=%q, got %q (found %v)", flagName, want, got, ok)
+		}
+		if !strings.Contains(origin, path) {
+			t.Errorf("expected origin to name the file, got %q", origin)
+		}
+	}
+	if _, _, ok := source.Lookup("seed"); ok {
+		t.Error("expected unset keys to be missing")
+	}
+}
+
+func TestLoadFile_RejectsUnsupportedSyntax(t *testing.T) {
+	for name, content := range map[string]string{
+		"nested": "defaults:\n  model: m\n",
+		"no key": "just text\n",
+		"size":   "size: large\n",
+	} {
+		path := filepath.Join(t.TempDir(), config.ProjectFileName)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing project file: %v", err)
+		}
+		if _, err := config.LoadFile(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFileSource_EnvironAddsSettingsTheEnvironmentLacks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.ProjectFileName)
+	content := "model: model-project\nprivate: true\nseed: 7\nid: gen-1\nkind.icon.width: 256\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing project file: %v", err)
+	}
+	source, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env := source.Environ([]string{"HOME=/home/me", "LEONARDO_SEED=42", "LEONARDO_PRIVATE="})
+
+	want := "HOME=/home/me LEONARDO_SEED=42 LEONARDO_PRIVATE= LEONARDO_MODEL_ID=model-project LEONARDO_PRIVATE=true"
+	if got := strings.Join(env, " "); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFileSource_PrefixedReturnsDottedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.ProjectFileName)
+	content := "model: model-project\nkind.icon.model: model-icon\nkind.icon.negative_prompt: text\nkind.poster.size: 768x1024\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing project file: %v", err)
+	}
+	source, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := source.Prefixed("kind.")
+
+	want := map[string]string{"icon.model": "model-icon", "icon.negative-prompt": "text", "poster.size": "768x1024"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, got[key])
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProjectFileName is the name of the project-local configuration file.
+const ProjectFileName = ".leonardo.yaml"
+
+// keyAliases map the friendlier keys accepted in a configuration file to the
+// flag they set.
+var keyAliases = map[string]string{
+	"model": "model-id",
+	"tag":   "tags",
+}
+
+// pathKeys hold directories.  Relative values are resolved against the
+// directory containing the file, so a project file works from any of the
+// project's subdirectories.
+var pathKeys = map[string]bool{"output-dir": true, "dir": true, "archive": true, "wildcards-dir": true}
+
+// FileSource reads flag values from a configuration file.  Only the subset
+// of YAML needed for flat settings is understood: "key: value" pairs,
+// comments, quoted strings and lists of scalars (either "[a, b]" or one
+// "- item" per line), which are joined with commas.
+type FileSource struct {
+	path   string
+	values map[string]string
+}
+
+// FindProjectFile looks for ProjectFileName in dir and each of its parents,
+// the way git looks for its repository, and returns the first one found.
+func FindProjectFile(dir string) (string, bool) {
+	return FindUp(dir, ProjectFileName)
+}
+
+// FindUp looks for a file called name in dir and each of its parents and
+// returns the path of the first one found.
+func FindUp(dir, name string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// LoadFile reads and parses the configuration file at path.
+func LoadFile(path string) (*FileSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	values, err := parseFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for key, value := range values {
+		if pathKeys[key] && value != "" && !filepath.IsAbs(value) {
+			values[key] = filepath.Join(filepath.Dir(path), value)
+		}
+	}
+	return &FileSource{path: path, values: values}, nil
+}
+
+// Path returns the file the values were read from.
+func (s *FileSource) Path() string {
+	return s.path
+}
+
+// Environ returns environ with the file's settings added as the LEONARDO_*
+// variables mapped to their flags, so child processes see the same
+// configuration.  Variables environ already sets win, matching the
+// precedence of Apply, and target flags and dotted keys such as
+// "kind.icon.width", which map to no flag, are left out.
+func (s *FileSource) Environ(environ []string) []string {
+	set := map[string]bool{}
+	for _, kv := range environ {
+		if i := strings.IndexByte(kv, '='); i > 0 && strings.TrimSpace(kv[i+1:]) != "" {
+			set[kv[:i]] = true
+		}
+	}
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := append([]string(nil), environ...)
+	for _, key := range keys {
+		if name := EnvVar(key); !targetFlags[key] && !set[name] && !strings.Contains(key, ".") {
+			out = append(out, name+"="+s.values[key])
+		}
+	}
+	return out
+}
+
+// Prefixed returns the values whose keys start with prefix, such as
+// "kind.", keyed by the rest of the key.
+func (s *FileSource) Prefixed(prefix string) map[string]string {
+	values := map[string]string{}
+	for key, value := range s.values {
+		if rest := strings.TrimPrefix(key, prefix); rest != key {
+			values[rest] = value
+		}
+	}
+	return values
+}
+
+// Lookup implements the Source interface.
+func (s *FileSource) Lookup(flagName string) (string, string, bool) {
+	value, ok := s.values[flagName]
+	if !ok {
+		return "", "", false
+	}
+	return value, fmt.Sprintf("%s (%s)", s.path, flagName), true
+}
+
+// parseFile parses the flat YAML subset described on FileSource into values
+// keyed by flag name.  A "size" such as 1024x768 sets width and height.
+func parseFile(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	var listKey string
+	var list []string
+	flush := func() {
+		if listKey != "" {
+			values[listKey] = strings.Join(list, ",")
+		}
+		listKey, list = "", nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item without a key", n)
+			}
+			list = append(list, unquote(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+		flush()
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested settings are not supported", n)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		key = normalizeKey(key)
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			listKey = key
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			var items []string
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = unquote(strings.TrimSpace(item)); item != "" {
+					items = append(items, item)
+				}
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = unquote(value)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if size, ok := values["size"]; ok {
+		w, h, ok := strings.Cut(strings.ToLower(size), "x")
+		if !ok {
+			return nil, errors.New("size must look like 1024x768")
+		}
+		values["width"], values["height"] = strings.TrimSpace(w), strings.TrimSpace(h)
+		delete(values, "size")
+	}
+	return values, nil
+}
+
+// normalizeKey maps a file key such as "model_id" or "Model" to its flag name.
+func normalizeKey(key string) string {
+	key = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(key), "_", "-"))
+	if alias, ok := keyAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// stripComment removes a trailing "# comment" outside of quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes matching single or double quotes around a value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
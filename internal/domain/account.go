@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Account is a named Leonardo API credential, such as "work" or "personal".
+type Account struct {
+	Name  string
+	Token string
+}
+
+// MaskedToken returns the token with all but its last four characters
+// hidden, suitable for display.
+func (a Account) MaskedToken() string {
+	if len(a.Token) <= 4 {
+		return strings.Repeat("*", len(a.Token))
+	}
+	return strings.Repeat("*", 8) + a.Token[len(a.Token)-4:]
+}
+
+// UnknownAccountError is returned when an account name does not match any
+// stored account.
+type UnknownAccountError struct {
+	Name string
+}
+
+// Error implements the error interface.
+func (e *UnknownAccountError) Error() string {
+	return fmt.Sprintf("no stored account named %q", e.Name)
+}
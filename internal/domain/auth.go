@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TokenState describes the outcome of validating an API token.
+type TokenState string
+
+const (
+	// TokenValid means the API accepted the token.
+	TokenValid TokenState = "valid"
+	// TokenMissing means no token was configured.
+	TokenMissing TokenState = "missing"
+	// TokenInvalid means the API rejected the token, for example because it
+	// was revoked or mistyped.
+	TokenInvalid TokenState = "invalid"
+	// TokenExpired means the API rejected the token as expired.
+	TokenExpired TokenState = "expired"
+	// TokenUnverified means the token could not be checked, for example
+	// because the API was unreachable.
+	TokenUnverified TokenState = "unverified"
+)
+
+// ClassifyTokenError reports what an error returned while calling the API
+// with a token says about that token.  Authentication failures are told
+// apart by status code (401 and 403) or by an error body mentioning the
+// token; everything else leaves the token unverified.
+func ClassifyTokenError(err error) TokenState {
+	if err == nil {
+		return TokenValid
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return TokenUnverified
+	}
+	body := strings.ToLower(string(apiErr.Body))
+	authStatus := apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	if !authStatus && !(apiErr.StatusCode < 500 && strings.Contains(body, "token")) {
+		return TokenUnverified
+	}
+	if strings.Contains(body, "expired") {
+		return TokenExpired
+	}
+	return TokenInvalid
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// How a backfilled image file was matched to its generation.
+const (
+	// MatchedByID means the file name starts with the generation ID, as
+	// download names files.
+	MatchedByID = "id"
+	// MatchedByName means the file name starts with a name or ID prefix
+	// recorded in the local library.
+	MatchedByName = "library"
+	// MatchedByRemoteFile means the file name is that of an image in the
+	// user's generation history, as saved from the web app.
+	MatchedByRemoteFile = "remote"
+)
+
+// BackfillMatch pairs an image file with the generation it came from.
+// GenerationID is empty when no heuristic matched.
+type BackfillMatch struct {
+	File         string
+	GenerationID string
+	By           string
+}
+
+// backfillExtensions are the image files backfill considers.
+var backfillExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".webp": true}
+
+// IsBackfillImage reports whether name is an image file backfill considers.
+func IsBackfillImage(name string) bool {
+	return backfillExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// BackfillReference returns the generation reference an image file name
+// starts with: the generation ID of {id}_{n}.png and its variations, or the
+// name of {name}_{n}.png.  It returns an empty string when the name does not
+// follow the download pattern.
+func BackfillReference(name string) string {
+	stem := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	if len(stem) >= 36 && IsFullGenerationID(stem[:36]) && (len(stem) == 36 || stem[36] == '_') {
+		return stem[:36]
+	}
+	parts := strings.Split(stem, "_")
+	for i := len(parts) - 1; i > 0; i-- {
+		if isDigits(parts[i]) {
+			return strings.Join(parts[:i], "_")
+		}
+	}
+	return ""
+}
+
+// RemoteImageName returns the file name of an image URL, which the web app
+// also uses when an image is saved from it.
+func RemoteImageName(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	return path.Base(url)
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// BackfillRequest rebuilds the request a completed generation was created
+// with from its record, for writing the sidecar it is missing.
+func BackfillRequest(d GenerationDetail) GenerationRequest {
+	return GenerationRequest{
+		NumImages: len(d.Images),
+		Private:   !d.Public,
+		Metadata: GenerationMetadata{
+			Prompt:         d.Prompt,
+			NegativePrompt: d.NegativePrompt,
+			ModelID:        d.ModelID,
+			Seed:           d.Seed,
+			Width:          d.Width,
+			Height:         d.Height,
+			GuidanceScale:  d.GuidanceScale,
+			Alchemy:        d.Alchemy,
+			Ultra:          d.Ultra,
+			PhotoReal:      d.PhotoReal,
+			InitImageID:    d.InitImageID,
+			InitStrength:   d.InitStrength,
+			Elements:       d.Elements,
+		},
+	}
+}
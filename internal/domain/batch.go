@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// DefaultMaxAttempts is the number of submissions allowed per batch item
+// before it is left alone by retry operations.
+const DefaultMaxAttempts = 3
+
+// InDoubtClockSkew is how much earlier than an in-doubt item's submission
+// time a generation may be stamped by the API and still be matched to it.
+const InDoubtClockSkew = 2 * time.Minute
+
+// BatchItem tracks a single request within a batch run together with the
+// outcome of its most recent submission.  IdempotencyKey identifies the item
+// across runs of the same manifest and SubmittedAt records when its latest
+// create request was sent, so a run that crashed can tell items that were
+// never sent from items whose response was lost.
+type BatchItem struct {
+	Request        GenerationRequest
+	IdempotencyKey string
+	SubmittedAt    time.Time
+	GenerationID   string
+	Attempts       int
+	Failure        FailureClass
+	Error          string
+}
+
+// Pending indicates whether the item has not been submitted yet.
+func (i BatchItem) Pending() bool {
+	return i.SubmittedAt.IsZero() && i.GenerationID == "" && !i.Failed()
+}
+
+// InDoubt indicates whether the item's create request was sent but its
+// outcome never recorded, so a generation may or may not exist for it.
+func (i BatchItem) InDoubt() bool {
+	return !i.SubmittedAt.IsZero() && i.GenerationID == "" && !i.Failed()
+}
+
+// MatchInDoubt looks for the generation an in-doubt item created among
+// recent generations: the earliest one with the item's prompt created no
+// earlier than its submission, allowing for InDoubtClockSkew.  Generations
+// in claimed already belong to other items and are skipped.
+func (i BatchItem) MatchInDoubt(recent []GenerationListItem, claimed map[string]bool) (string, bool) {
+	var best GenerationListItem
+	found := false
+	for _, g := range recent {
+		if claimed[g.ID] || g.Prompt != i.Request.Metadata.Prompt || g.CreatedAt.Before(i.SubmittedAt.Add(-InDoubtClockSkew)) {
+			continue
+		}
+		if !found || g.CreatedAt.Before(best.CreatedAt) {
+			best, found = g, true
+		}
+	}
+	return best.ID, found
+}
+
+// NewIdempotencyKey returns a random key identifying a batch item.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms; fall back
+		// to the clock so a key is still unique in practice.
+		return hex.EncodeToString([]byte(time.Now().UTC().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewBatchManifest returns a manifest of pending items for requests, each
+// with its own idempotency key.
+func NewBatchManifest(requests []GenerationRequest) BatchManifest {
+	manifest := BatchManifest{Items: make([]BatchItem, 0, len(requests))}
+	for _, req := range requests {
+		manifest.Items = append(manifest.Items, BatchItem{Request: req, IdempotencyKey: NewIdempotencyKey()})
+	}
+	return manifest
+}
+
+// Failed indicates whether the most recent submission of the item failed.
+func (i BatchItem) Failed() bool {
+	return i.Failure != ""
+}
+
+// CanRetry indicates whether the item failed with a retryable class and still
+// has attempts left under maxAttempts.
+func (i BatchItem) CanRetry(maxAttempts int) bool {
+	return i.Failed() && i.Failure.Retryable() && i.Attempts < maxAttempts
+}
+
+// BatchManifest records every item of a batch run so the run can be
+// inspected or resumed later.
+type BatchManifest struct {
+	Items []BatchItem
+}
+
+// BatchRetrySummary reports what a retry pass did with the items of a
+// manifest.
+type BatchRetrySummary struct {
+	Retried   int
+	Succeeded int
+	Skipped   int
+	Exhausted int
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxBenchmarkModels caps the generations one benchmark submits, since each
+// one costs tokens.
+const MaxBenchmarkModels = 10
+
+// ModelBenchmark generates the same prompt with the same seed on each of
+// ModelIDs, keeping every other setting fixed, to compare the models'
+// output, speed and cost.
+type ModelBenchmark struct {
+	ModelIDs []string
+}
+
+// Validate checks that the benchmark has between two and
+// MaxBenchmarkModels distinct models.
+func (b ModelBenchmark) Validate() error {
+	if len(b.ModelIDs) < 2 || len(b.ModelIDs) > MaxBenchmarkModels {
+		return &InvalidRequestError{Reason: fmt.Sprintf("a benchmark needs between 2 and %d models, not %d", MaxBenchmarkModels, len(b.ModelIDs))}
+	}
+	seen := map[string]bool{}
+	for _, id := range b.ModelIDs {
+		if seen[id] {
+			return &InvalidRequestError{Reason: fmt.Sprintf("model %s is listed twice", id)}
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Requests returns one request per model: base with that model.
+// Generations are tagged "benchmark:SEED" and, when base is named, named
+// after it with the position of the model appended so the series is easy
+// to find in the library.
+func (b ModelBenchmark) Requests(base GenerationRequest) []GenerationRequest {
+	requests := make([]GenerationRequest, len(b.ModelIDs))
+	for i, id := range b.ModelIDs {
+		req := base
+		meta := base.Metadata
+		meta.ModelID = id
+		meta.Tags = append(append([]string(nil), meta.Tags...), fmt.Sprintf("benchmark:%d", meta.Seed))
+		if meta.Name != "" {
+			meta.Name += fmt.Sprintf("-m%d", i+1)
+		}
+		req.Metadata = meta
+		requests[i] = req
+	}
+	return requests
+}
+
+// BenchmarkResult is the outcome of one model of a benchmark.  Duration is
+// the time from submission until the generation was seen complete, and
+// Cost the tokens it was charged, zero when the API did not say.  Err is
+// set when the generation failed or could not be waited for.
+type BenchmarkResult struct {
+	ModelID      string
+	ModelName    string
+	GenerationID string
+	Status       string
+	Duration     time.Duration
+	Cost         int
+	File         string
+	Err          string
+}
+
+// OK reports whether the model produced an image.
+func (r BenchmarkResult) OK() bool {
+	return r.Err == ""
+}
+
+// Label returns the model's name, or its ID when the name is not known.
+func (r BenchmarkResult) Label() string {
+	if strings.TrimSpace(r.ModelName) != "" {
+		return r.ModelName
+	}
+	return r.ModelID
+}
+
+// RankBenchmark returns the successful results fastest first, with the
+// cheaper model first on a tie, followed by the failed ones in their
+// original order.
+func RankBenchmark(results []BenchmarkResult) []BenchmarkResult {
+	ranked := append([]BenchmarkResult(nil), results...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.OK() != b.OK() {
+			return a.OK()
+		}
+		if !a.OK() {
+			return false
+		}
+		if a.Duration != b.Duration {
+			return a.Duration < b.Duration
+		}
+		return a.Cost < b.Cost
+	})
+	return ranked
+}
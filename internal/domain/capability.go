@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ModelCapabilities describes the generation settings a model accepts.
+// Width and height must lie between MinDimension and MaxDimension and be a
+// multiple of DimensionStep.  PromptTokens is how much of a prompt the
+// model's text encoder reads.
+type ModelCapabilities struct {
+	MinDimension  int
+	MaxDimension  int
+	DimensionStep int
+	Alchemy       bool
+	PhotoReal     bool
+	PromptTokens  int
+}
+
+// String lists the supported options for error messages.
+func (c ModelCapabilities) String() string {
+	parts := []string{fmt.Sprintf("width and height %d-%d in steps of %d", c.MinDimension, c.MaxDimension, c.DimensionStep)}
+	if c.Alchemy {
+		parts = append(parts, "Alchemy")
+	}
+	if c.PhotoReal {
+		parts = append(parts, "PhotoReal")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fits reports whether a width or height is accepted.
+func (c ModelCapabilities) fits(n int) bool {
+	return n >= c.MinDimension && n <= c.MaxDimension && n%c.DimensionStep == 0
+}
+
+// Capabilities returns what the model accepts, derived from its base
+// architecture.  The API does not publish capabilities directly; models with
+// an unrecognised SDVersion get the limits every model shares and are
+// assumed to support Alchemy, so new models are not rejected outright.
+func (m PlatformModel) Capabilities() ModelCapabilities {
+	caps := ModelCapabilities{MinDimension: 32, MaxDimension: 1536, DimensionStep: 8, Alchemy: true, PromptTokens: DefaultPromptTokenLimit}
+	switch strings.ToUpper(m.SDVersion) {
+	case "V1_5", "V2":
+		caps.MaxDimension = 1024
+	case "SDXL_0_8", "SDXL_0_9", "SDXL_1_0", "SDXL_LIGHTNING":
+		caps.PhotoReal = true
+	case "FLUX", "FLUX_DEV", "FLUX_SCHNELL":
+		caps.Alchemy = false
+		caps.PromptTokens = t5PromptTokenLimit
+	}
+	return caps
+}
+
+// NativeSize returns the resolution the model was trained at, the size it
+// renders best without upscaling.  Models with an unrecognised SDVersion
+// have none, leaving the size to the API.
+func (m PlatformModel) NativeSize() (width, height int, ok bool) {
+	switch strings.ToUpper(m.SDVersion) {
+	case "V1_5":
+		return 512, 512, true
+	case "V2":
+		return 768, 768, true
+	case "SDXL_0_8", "SDXL_0_9", "SDXL_1_0", "SDXL_LIGHTNING", "PHOENIX", "FLUX", "FLUX_DEV", "FLUX_SCHNELL":
+		return 1024, 1024, true
+	}
+	return 0, 0, false
+}
+
+// Validate checks the size, Alchemy and PhotoReal settings of a request
+// against the model's capabilities.  The error names every unsupported
+// setting together with the options the model does support.
+func (m PlatformModel) Validate(meta GenerationMetadata) error {
+	caps := m.Capabilities()
+	var unsupported []string
+	if meta.HasWidth() && !caps.fits(meta.Width) {
+		unsupported = append(unsupported, fmt.Sprintf("width %d", meta.Width))
+	}
+	if meta.HasHeight() && !caps.fits(meta.Height) {
+		unsupported = append(unsupported, fmt.Sprintf("height %d", meta.Height))
+	}
+	if meta.HasAlchemy() && !caps.Alchemy {
+		unsupported = append(unsupported, "Alchemy")
+	}
+	if meta.HasPhotoReal() && !caps.PhotoReal {
+		unsupported = append(unsupported, "PhotoReal")
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	name := m.Name
+	if name == "" {
+		name = m.ID
+	}
+	return &InvalidRequestError{Reason: fmt.Sprintf("model %s does not support %s (supported: %s)",
+		name, strings.Join(unsupported, ", "), caps)}
+}
+
+// ModelCatalog is a cached copy of the platform model list.
+type ModelCatalog struct {
+	Models    []PlatformModel
+	FetchedAt time.Time
+}
+
+// Fresh reports whether the catalog was fetched less than ttl before now.
+func (c ModelCatalog) Fresh(now time.Time, ttl time.Duration) bool {
+	return !c.FetchedAt.IsZero() && now.Sub(c.FetchedAt) < ttl
+}
+
+// Find returns the model with the given ID.
+func (c ModelCatalog) Find(id string) (PlatformModel, bool) {
+	for _, m := range c.Models {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return PlatformModel{}, false
+}
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Character is a named subject reference reused across generations so a
+// series keeps the same subject: a description put in front of each prompt,
+// a reference init image, a style, a seed and Elements.  Every part is
+// optional, but a character needs at least one.
+type Character struct {
+	Name         string
+	Description  string
+	InitImageID  string
+	InitStrength float64
+	StyleUUID    string
+	Seed         int
+	Elements     []GenerationElement
+	CreatedAt    time.Time
+}
+
+// CharacterTagPrefix starts the tag added to generations made with a
+// character, so the library can find a series, e.g. "character:mira".
+const CharacterTagPrefix = "character:"
+
+// ValidateCharacterName checks that name can be typed as a command-line
+// argument: letters, digits, dashes, underscores and dots.
+func ValidateCharacterName(name string) error {
+	if name == "" {
+		return &InvalidRequestError{Reason: "a character name is required"}
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return &InvalidRequestError{Reason: fmt.Sprintf("character name %q may only contain letters, digits, '-', '_' and '.'", name)}
+		}
+	}
+	return nil
+}
+
+// Validate checks the character's name and that it holds a usable
+// reference.
+func (c Character) Validate() error {
+	if err := ValidateCharacterName(c.Name); err != nil {
+		return err
+	}
+	if c.Description == "" && c.InitImageID == "" && c.StyleUUID == "" && c.Seed == 0 && len(c.Elements) == 0 {
+		return &InvalidRequestError{Reason: fmt.Sprintf("character %s needs a description, init image, style, seed or Element", c.Name)}
+	}
+	if c.InitStrength != 0 {
+		if c.InitImageID == "" {
+			return &InvalidRequestError{Reason: "init strength requires an init image"}
+		}
+		return ValidateInitStrength(c.InitStrength)
+	}
+	return nil
+}
+
+// Apply returns meta with the character filled in: the description goes
+// in front of the prompt, and the init image, style and seed are used
+// unless meta sets its own.  The character's Elements are added to any
+// meta already has, and the generation is tagged with the character's
+// name.
+func (c Character) Apply(meta GenerationMetadata) GenerationMetadata {
+	if c.Description != "" && !strings.HasPrefix(meta.Prompt, c.Description) {
+		meta.Prompt = c.Description + ", " + meta.Prompt
+	}
+	if meta.InitImageID == "" && c.InitImageID != "" {
+		meta.InitImageID = c.InitImageID
+		if meta.InitStrength == 0 {
+			meta.InitStrength = c.InitStrength
+		}
+	}
+	if meta.StyleUUID == "" {
+		meta.StyleUUID = c.StyleUUID
+	}
+	if meta.Seed == 0 {
+		meta.Seed = c.Seed
+	}
+	elements := append([]GenerationElement(nil), meta.Elements...)
+	for _, e := range c.Elements {
+		if !hasElement(elements, e.ID) {
+			elements = append(elements, e)
+		}
+	}
+	meta.Elements = elements
+	tag := CharacterTagPrefix + c.Name
+	for _, t := range meta.Tags {
+		if t == tag {
+			return meta
+		}
+	}
+	meta.Tags = append(append([]string(nil), meta.Tags...), tag)
+	return meta
+}
+
+// hasElement reports whether elements include the Element with id.
+func hasElement(elements []GenerationElement, id string) bool {
+	for _, e := range elements {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultElementWeight is the weight of an Element given without one.
+const DefaultElementWeight = 1.0
+
+// ParseElement parses an Element given as ID or ID:WEIGHT, such as
+// "b3a1...:0.6".
+func ParseElement(spec string) (GenerationElement, error) {
+	id, weight, hasWeight := strings.Cut(strings.TrimSpace(spec), ":")
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return GenerationElement{}, fmt.Errorf("element %q must be ID or ID:WEIGHT", spec)
+	}
+	element := GenerationElement{ID: id, Weight: DefaultElementWeight}
+	if hasWeight {
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil {
+			return GenerationElement{}, fmt.Errorf("element %q: weight %q is not a number", spec, weight)
+		}
+		element.Weight = w
+	}
+	return element, nil
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// EndpointCheck is the outcome of probing one API endpoint for
+// compatibility.  A nil Err means the endpoint answered with the shape the
+// CLI expects.
+type EndpointCheck struct {
+	Endpoint string
+	Duration time.Duration
+	Err      error
+}
+
+// OK reports whether the endpoint passed the check.
+func (c EndpointCheck) OK() bool {
+	return c.Err == nil
+}
+
+// Compatible reports whether every probed endpoint passed.
+func Compatible(checks []EndpointCheck) bool {
+	for _, c := range checks {
+		if !c.OK() {
+			return false
+		}
+	}
+	return len(checks) > 0
+}
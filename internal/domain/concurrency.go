@@ -0,0 +1,90 @@
+package domain
+
+import "time"
+
+// Batch concurrency defaults.
+const (
+	// DefaultBatchConcurrency is the most batch submissions in flight at
+	// once unless configured otherwise.
+	DefaultBatchConcurrency = 4
+	// DefaultRateLimitCooldown is how long a batch holds back new
+	// submissions after the API answers 429.
+	DefaultRateLimitCooldown = 2 * time.Second
+	// DefaultPendingPoll is how often generations are checked while a
+	// pending cap holds submissions back.
+	DefaultPendingPoll = 5 * time.Second
+)
+
+// BatchConcurrency configures how many batch submissions run at once.  The
+// limit starts at one and adapts up to Max, see ConcurrencyLimit.  After a
+// rate limit no new submission starts for Cooldown.  A positive Spread
+// spaces the submissions evenly over that window, trickling them out
+// rather than sending them as fast as the limit allows.  OnChange, when
+// set, is called whenever the limit changes, with whether a rate limit
+// lowered it.
+//
+// A positive MaxPending caps the generations created but not yet finished
+// on the server, as plan tiers limit concurrent jobs: at the cap, further
+// submissions queue locally and the pending ones are checked every Poll
+// (DefaultPendingPoll when zero) until one finishes.  OnHold, when set, is
+// called each time the cap starts holding submissions back.
+type BatchConcurrency struct {
+	Max        int
+	Cooldown   time.Duration
+	Spread     time.Duration
+	OnChange   func(limit int, rateLimited bool)
+	MaxPending int
+	Poll       time.Duration
+	OnHold     func(pending int)
+}
+
+// ConcurrencyLimit is an additive-increase, multiplicative-decrease limit
+// on requests in flight, as TCP uses for its congestion window: every rate
+// limit halves it, and each run of as many successes in a row as the limit
+// raises it by one, up to a maximum.  A run therefore settles just under
+// the rate the API allows without being tuned by hand.
+type ConcurrencyLimit struct {
+	max    int
+	limit  int
+	streak int
+}
+
+// NewConcurrencyLimit returns a limit starting at one and never exceeding
+// max, which is taken as one when lower.
+func NewConcurrencyLimit(max int) *ConcurrencyLimit {
+	if max < 1 {
+		max = 1
+	}
+	return &ConcurrencyLimit{max: max, limit: 1}
+}
+
+// Limit returns how many requests may be in flight.
+func (l *ConcurrencyLimit) Limit() int {
+	return l.limit
+}
+
+// Succeeded records a request that was not rate limited and reports whether
+// the limit was raised.
+func (l *ConcurrencyLimit) Succeeded() bool {
+	if l.limit >= l.max {
+		return false
+	}
+	l.streak++
+	if l.streak < l.limit {
+		return false
+	}
+	l.limit++
+	l.streak = 0
+	return true
+}
+
+// RateLimited records a rate-limited request and reports whether the limit
+// was lowered.
+func (l *ConcurrencyLimit) RateLimited() bool {
+	l.streak = 0
+	if l.limit == 1 {
+		return false
+	}
+	l.limit /= 2
+	return true
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ConfigBundleFormat and ConfigBundleVersion identify a configuration
+// bundle written by config export.
+const (
+	ConfigBundleFormat  = "leonardo-cli-config"
+	ConfigBundleVersion = 1
+)
+
+// ConfigBundle is a portable copy of the CLI's configuration, for setting up
+// another machine the same way: the files, keyed by their slash-separated
+// path, and the stored accounts when they were exported, sealed with a
+// passphrase.  Records of past work such as the library and the history are
+// not configuration and are left out.
+type ConfigBundle struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Files      map[string]string `json:"files"`
+	Secrets    *SealedSecrets    `json:"secrets,omitempty"`
+}
+
+// BundledAccounts are the stored accounts carried, sealed, by a bundle.
+type BundledAccounts struct {
+	Accounts []Account `json:"accounts"`
+	Default  string    `json:"default,omitempty"`
+}
+
+// Validate checks the bundle was written by a version of config export
+// this one reads, and that every file path stays inside the directory it is
+// imported into.
+func (b ConfigBundle) Validate() error {
+	if b.Format != ConfigBundleFormat {
+		return fmt.Errorf("not a leonardo-cli configuration bundle")
+	}
+	if b.Version < 1 || b.Version > ConfigBundleVersion {
+		return fmt.Errorf("configuration bundle version %d is not supported (this CLI reads up to %d)", b.Version, ConfigBundleVersion)
+	}
+	for name := range b.Files {
+		if err := ValidateBundlePath(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateBundlePath checks that name is a relative, slash-separated path
+// that cannot climb out of the directory it is written to.
+func ValidateBundlePath(name string) error {
+	clean := path.Clean(name)
+	if name == "" || clean != name || path.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(name, `\`) {
+		return fmt.Errorf("configuration bundle holds an unsafe file path %q", name)
+	}
+	return nil
+}
+
+// SealedSecrets are secrets encrypted with AES-256-GCM under a key derived
+// from a passphrase with PBKDF2-HMAC-SHA256.
+type SealedSecrets struct {
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// sealIterations is the PBKDF2 work factor for new bundles.
+const sealIterations = 600000
+
+// ErrWrongPassphrase reports sealed secrets that could not be opened, either
+// because the passphrase differs or because they were altered.
+var ErrWrongPassphrase = errors.New("wrong passphrase, or the bundle's secrets were altered")
+
+// SealSecrets encrypts plaintext with passphrase.
+func SealSecrets(passphrase string, plaintext []byte) (SealedSecrets, error) {
+	if passphrase == "" {
+		return SealedSecrets{}, fmt.Errorf("a passphrase is required to export secrets")
+	}
+	s := SealedSecrets{Iterations: sealIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(s.Salt); err != nil {
+		return SealedSecrets{}, err
+	}
+	gcm, err := s.cipher(passphrase)
+	if err != nil {
+		return SealedSecrets{}, err
+	}
+	s.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(s.Nonce); err != nil {
+		return SealedSecrets{}, err
+	}
+	s.Ciphertext = gcm.Seal(nil, s.Nonce, plaintext, nil)
+	return s, nil
+}
+
+// Open decrypts the secrets with passphrase.
+func (s SealedSecrets) Open(passphrase string) ([]byte, error) {
+	if s.Iterations < 1 {
+		return nil, fmt.Errorf("sealed secrets have no key derivation settings")
+	}
+	gcm, err := s.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Nonce) != gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	plaintext, err := gcm.Open(nil, s.Nonce, s.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// cipher returns the AES-GCM cipher keyed from passphrase.
+func (s SealedSecrets) cipher(passphrase string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(passphrase), s.Salt, s.Iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key of keyLen bytes from password and salt, as in
+// RFC 8018 with HMAC-SHA256.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
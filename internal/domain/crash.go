@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CrashReport describes a run that panicked, for attaching to a bug report.
+// Everything in it must already be redacted; see RedactSetting.
+type CrashReport struct {
+	At        time.Time
+	Version   string
+	Commit    string
+	GoVersion string
+	Platform  string
+	// Args is the command line, after the program name.
+	Args []string
+	// ConfigFile is the project configuration file in use, if any, and
+	// Config its settings.
+	ConfigFile string
+	Config     map[string]string
+	// Env holds the LEONARDO_* variables set for the run.
+	Env   map[string]string
+	Calls []CallMetric
+	Panic string
+	Stack string
+}
+
+// secretNameParts mark configuration keys and environment variables whose
+// values are never included in a crash report.
+var secretNameParts = []string{"token", "secret", "password", "passphrase", "api-key", "api_key"}
+
+// IsSecretName reports whether the setting or variable name holds a secret
+// such as a token, password or passphrase.  Names of files holding a
+// secret, such as LEONARDO_API_TOKEN_FILE, are not secrets themselves.
+func IsSecretName(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, "file") {
+		return false
+	}
+	for _, part := range secretNameParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSetting returns the value of the setting or variable name as it may
+// appear in a crash report: secrets, judged by name, become a marker,
+// prompts go through r.Prompt and everything through r.Text.  Names of
+// files holding a secret, such as LEONARDO_API_TOKEN_FILE, are kept.
+func RedactSetting(name, value string, r Redactor) string {
+	if IsSecretName(name) {
+		return redactedMarker
+	}
+	lower := strings.ToLower(name)
+	key := strings.ReplaceAll(strings.TrimPrefix(lower, "leonardo_"), "_", "-")
+	if promptFlags[key] {
+		value = r.Prompt(value)
+	}
+	return r.Text(value)
+}
+
+// Render writes the report as plain text, one section per heading.
+func (c CrashReport) Render(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "leonardo crash report, %s\n\n", c.At.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Version:  %s\n", c.Version)
+	if c.Commit != "" {
+		fmt.Fprintf(&b, "Commit:   %s\n", c.Commit)
+	}
+	fmt.Fprintf(&b, "Go:       %s\n", c.GoVersion)
+	fmt.Fprintf(&b, "Platform: %s\n", c.Platform)
+	fmt.Fprintf(&b, "Command:  %s\n", HistoryEntry{Args: append([]string{"leonardo"}, c.Args...)}.CommandLine())
+
+	fmt.Fprintf(&b, "\nPanic: %s\n", c.Panic)
+
+	b.WriteString("\nConfiguration")
+	if c.ConfigFile != "" {
+		fmt.Fprintf(&b, " (%s)", c.ConfigFile)
+	}
+	b.WriteString(":\n")
+	writeSettings(&b, c.Config)
+
+	b.WriteString("\nEnvironment:\n")
+	writeSettings(&b, c.Env)
+
+	fmt.Fprintf(&b, "\nRecent API calls (%d):\n", len(c.Calls))
+	if len(c.Calls) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, m := range c.Calls {
+		status := "no response"
+		if m.StatusCode != 0 {
+			status = fmt.Sprint(m.StatusCode)
+		}
+		fmt.Fprintf(&b, "  %s %s -> %s in %s", m.Method, m.Path, status, m.Duration.Round(time.Millisecond))
+		if m.RequestID != "" {
+			fmt.Fprintf(&b, " (request ID %s)", m.RequestID)
+		}
+		b.WriteByte('\n')
+	}
+
+	fmt.Fprintf(&b, "\nStack trace:\n%s", c.Stack)
+	if !strings.HasSuffix(c.Stack, "\n") {
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeSettings writes settings sorted by name, or "none".
+func writeSettings(b *strings.Builder, settings map[string]string) {
+	if len(settings) == 0 {
+		b.WriteString("  none\n")
+		return
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "  %s: %s\n", name, settings[name])
+	}
+}
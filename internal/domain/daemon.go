@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+// MaxRecentErrors is how many of its latest failures a long-running command
+// reports on its status endpoint.
+const MaxRecentErrors = 10
+
+// DaemonJob is a unit of work a long-running command has in flight, such
+// as an image watch-folder is restyling.
+type DaemonJob struct {
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+}
+
+// DaemonError is a job that failed.
+type DaemonError struct {
+	Time  time.Time `json:"time"`
+	Job   string    `json:"job"`
+	Error string    `json:"error"`
+}
+
+// DaemonStatus is what a long-running command reports to supervisors on its
+// status endpoint: the jobs in flight, how many are waiting behind them and
+// the most recent failures, newest last.
+type DaemonStatus struct {
+	Command    string      `json:"command"`
+	PID        int         `json:"pid"`
+	Started    time.Time   `json:"started"`
+	InFlight   []DaemonJob `json:"in_flight"`
+	QueueDepth int         `json:"queue_depth"`
+	Completed  int         `json:"completed"`
+	Failed     int         `json:"failed"`
+	// TokensSpent and ImagesDownloaded add up what the finished jobs
+	// were charged and saved.
+	TokensSpent      int           `json:"tokens_spent"`
+	ImagesDownloaded int           `json:"images_downloaded"`
+	RecentErrors     []DaemonError `json:"recent_errors"`
+}
+
+// Begin records that job started at now with queued jobs waiting behind it.
+func (s *DaemonStatus) Begin(job string, queued int, now time.Time) {
+	s.InFlight = append(s.InFlight, DaemonJob{Name: job, Started: now})
+	s.QueueDepth = queued
+}
+
+// Produced records that a job saved images and was charged tokens.
+func (s *DaemonStatus) Produced(images, tokens int) {
+	s.ImagesDownloaded += images
+	s.TokensSpent += tokens
+}
+
+// End records that job finished at now, failing with err when it is not
+// nil.  Only the last MaxRecentErrors failures are kept.
+func (s *DaemonStatus) End(job string, err error, now time.Time) {
+	for i, j := range s.InFlight {
+		if j.Name == job {
+			s.InFlight = append(s.InFlight[:i], s.InFlight[i+1:]...)
+			break
+		}
+	}
+	if err == nil {
+		s.Completed++
+		return
+	}
+	s.Failed++
+	s.RecentErrors = append(s.RecentErrors, DaemonError{Time: now, Job: job, Error: err.Error()})
+	if extra := len(s.RecentErrors) - MaxRecentErrors; extra > 0 {
+		s.RecentErrors = append([]DaemonError(nil), s.RecentErrors[extra:]...)
+	}
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// Service managers a long-running command can be installed under.
+const (
+	ServiceSystemd = "systemd" // a systemd user unit, on Linux
+	ServiceLaunchd = "launchd" // a launchd user agent, on macOS
+)
+
+// ServiceManagers lists the supported service managers.
+var ServiceManagers = []string{ServiceSystemd, ServiceLaunchd}
+
+// DaemonCommands are the commands that keep running and so can be
+// installed as a service.
+var DaemonCommands = []string{"watch-folder"}
+
+// ServiceSpec describes a command to run as an always-on service.
+type ServiceSpec struct {
+	// Name identifies the service, e.g. "inbox" for leonardo-inbox.
+	Name string
+	// Program is the absolute path of the executable and Args its
+	// arguments.
+	Program string
+	Args    []string
+	// WorkDir is where the command runs, so it finds .leonardo.yaml.
+	WorkDir string
+	// Env holds environment variables to set, such as LEONARDO_* options.
+	Env map[string]string
+	// LogDir is where launchd writes the agent's output; systemd keeps it
+	// in the journal.
+	LogDir string
+}
+
+// Label returns the launchd label of the service.
+func (s ServiceSpec) Label() string {
+	return "com.leonardo-cli." + s.Name
+}
+
+// UnitName returns the systemd unit name of the service.
+func (s ServiceSpec) UnitName() string {
+	return "leonardo-" + s.Name + ".service"
+}
+
+// ValidateServiceName checks a service name is usable in unit file names
+// and launchd labels: letters, digits, '-', '_' and '.'.
+func ValidateServiceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("service name must not be empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return fmt.Errorf("service name %q may only contain letters, digits, '-', '_' and '.'", name)
+		}
+	}
+	return nil
+}
+
+// Render returns the service definition for manager: a systemd unit or a
+// launchd property list.
+func (s ServiceSpec) Render(manager string) (string, error) {
+	switch manager {
+	case ServiceSystemd:
+		return s.systemdUnit(), nil
+	case ServiceLaunchd:
+		return s.launchdPlist(), nil
+	}
+	return "", fmt.Errorf("unknown service manager %q (use %s)", manager, strings.Join(ServiceManagers, " or "))
+}
+
+// sortedEnv returns the names of the environment variables in order, so
+// definitions are stable.
+func (s ServiceSpec) sortedEnv() []string {
+	names := make([]string, 0, len(s.Env))
+	for name := range s.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// systemdUnit renders a systemd user unit that restarts the command when
+// it stops.
+func (s ServiceSpec) systemdUnit() string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=leonardo-cli service %s\n", s.Name)
+	b.WriteString("After=network-online.target\nWants=network-online.target\n\n")
+	b.WriteString("[Service]\nType=simple\n")
+	fmt.Fprintf(&b, "WorkingDirectory=%s\n", systemdQuote(s.WorkDir))
+	for _, name := range s.sortedEnv() {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(name+"="+s.Env[name]))
+	}
+	words := []string{systemdQuote(s.Program)}
+	for _, arg := range s.Args {
+		words = append(words, systemdQuote(arg))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(words, " "))
+	b.WriteString("Restart=on-failure\nRestartSec=10\n\n")
+	b.WriteString("[Install]\nWantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdQuote quotes a word of a unit file setting when it needs it and
+// escapes the specifiers and variables systemd would expand.
+func systemdQuote(word string) string {
+	word = strings.NewReplacer("%", "%%", "$", "$$").Replace(word)
+	if word != "" && !strings.ContainsAny(word, " \t\"'\\;") {
+		return word
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(word) + `"`
+}
+
+// launchdPlist renders a launchd user agent that starts at login and is
+// restarted when it stops.
+func (s ServiceSpec) launchdPlist() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	plistString(&b, "Label", s.Label())
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{s.Program}, s.Args...) {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", html.EscapeString(arg))
+	}
+	b.WriteString("\t</array>\n")
+	plistString(&b, "WorkingDirectory", s.WorkDir)
+	if len(s.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, name := range s.sortedEnv() {
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", html.EscapeString(name), html.EscapeString(s.Env[name]))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	if s.LogDir != "" {
+		plistString(&b, "StandardOutPath", strings.TrimSuffix(s.LogDir, "/")+"/"+s.Label()+".log")
+		plistString(&b, "StandardErrorPath", strings.TrimSuffix(s.LogDir, "/")+"/"+s.Label()+".err.log")
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// plistString writes a string entry of a property list dictionary.
+func plistString(b *strings.Builder, key, value string) {
+	fmt.Fprintf(b, "\t<key>%s</key>\n\t<string>%s</string>\n", key, html.EscapeString(value))
+}
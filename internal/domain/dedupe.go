@@ -0,0 +1,24 @@
+package domain
+
+// StoredImage is a downloaded file recorded by its content, so that a later
+// download of the same bytes can become a hard link to it instead of a
+// second copy.
+type StoredImage struct {
+	// Path is absolute.
+	Path   string
+	SHA256 string
+	Size   int64
+}
+
+// DedupeReport is what library gc found among the recorded downloads.
+// Files and Distinct count the files still present and their different
+// contents; Linked the copies replaced with a hard link during the run and
+// Forgotten the records of files that no longer exist.  SavedBytes is the
+// space taken by files sharing their data with another recorded file.
+type DedupeReport struct {
+	Files      int
+	Distinct   int
+	Linked     int
+	Forgotten  int
+	SavedBytes int64
+}
@@ -0,0 +1,49 @@
+package domain
+
+// Download sizes are not known until the files arrive, so disk space checks
+// work from an estimate.  Generated PNGs average well under two bytes per
+// pixel; using two keeps the estimate on the safe side.
+const (
+	estimatedBytesPerPixel = 2
+	defaultImageSide       = 1024
+	// upscaleAreaFactor is how much larger an upscaled image is than its
+	// original: upscales double the width and the height.
+	upscaleAreaFactor = 4
+)
+
+// EstimateImageBytes returns the expected size of a downloaded image of the
+// given dimensions.  Unknown dimensions count as 1024 pixels.
+func EstimateImageBytes(width, height int) int64 {
+	if width <= 0 {
+		width = defaultImageSide
+	}
+	if height <= 0 {
+		height = defaultImageSide
+	}
+	return int64(width) * int64(height) * estimatedBytesPerPixel
+}
+
+// EstimatedDownloadBytes returns the expected size of downloading every image
+// of the generation and, when includeVariations is set, every finished
+// variation as well.
+func (d GenerationDetail) EstimatedDownloadBytes(includeVariations bool) int64 {
+	image := EstimateImageBytes(d.Width, d.Height)
+	var total int64
+	for _, img := range d.Images {
+		total += image
+		if !includeVariations {
+			continue
+		}
+		for _, v := range img.Variations {
+			if v.Status != "COMPLETE" {
+				continue
+			}
+			if v.FileSuffix() == "upscaled" {
+				total += image * upscaleAreaFactor
+			} else {
+				total += image
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Outcomes of a doctor check.  A warning points at something worth fixing
+// that does not stop the CLI from working.
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// DoctorCheck is the outcome of one environment check run by doctor.
+type DoctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// Clock skew thresholds.  A skew past ClockSkewWarn makes relative times and
+// wait estimates misleading; past ClockSkewFail, TLS certificates and
+// signed webhook timestamps start to be rejected.
+const (
+	ClockSkewWarn = 30 * time.Second
+	ClockSkewFail = 5 * time.Minute
+)
+
+// CheckClockSkew compares the local clock with the time a server reported
+// at the same moment.
+func CheckClockSkew(local, server time.Time) DoctorCheck {
+	skew := local.Sub(server)
+	direction := "ahead of"
+	if skew < 0 {
+		skew, direction = -skew, "behind"
+	}
+	check := DoctorCheck{Name: "Clock", Status: CheckPass, Detail: fmt.Sprintf("%s %s the API's clock", skew.Round(time.Second), direction)}
+	switch {
+	case skew > ClockSkewFail:
+		check.Status = CheckFail
+	case skew > ClockSkewWarn:
+		check.Status = CheckWarn
+	default:
+		check.Detail = "in sync with the API's clock"
+	}
+	if check.Status != CheckPass {
+		check.Detail += "; sync it with NTP"
+	}
+	return check
+}
+
+// Healthy reports whether no check failed.
+func Healthy(checks []DoctorCheck) bool {
+	for _, c := range checks {
+		if c.Status == CheckFail {
+			return false
+		}
+	}
+	return true
+}
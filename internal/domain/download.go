@@ -0,0 +1,50 @@
+package domain
+
+import "fmt"
+
+// DownloadOutcome says what happened to one file of a download.
+type DownloadOutcome string
+
+// Outcomes of downloading one image or variation.
+const (
+	DownloadSaved   DownloadOutcome = "saved"
+	DownloadSkipped DownloadOutcome = "skipped" // not attempted after an earlier failure
+	DownloadFailed  DownloadOutcome = "failed"
+)
+
+// ImageDownload is the outcome of downloading one image of a generation, or
+// one variation of it.  Image is the 1-based position of the image and
+// Variation the file suffix of the variation, empty for the image itself.
+type ImageDownload struct {
+	Image     int
+	Variation string
+	Path      string
+	Outcome   DownloadOutcome
+	Err       error
+}
+
+// Count returns how many files of the download ended with outcome.
+func (r DownloadResult) Count(outcome DownloadOutcome) int {
+	n := 0
+	for _, o := range r.Outcomes {
+		if o.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// Err summarizes the files that failed to download, wrapping the first
+// failure, or returns nil when none did.
+func (r DownloadResult) Err() error {
+	failed := r.Count(DownloadFailed)
+	if failed == 0 {
+		return nil
+	}
+	for _, o := range r.Outcomes {
+		if o.Outcome == DownloadFailed {
+			return fmt.Errorf("%d of %d files failed to download: %w", failed, len(r.Outcomes), o.Err)
+		}
+	}
+	return nil
+}
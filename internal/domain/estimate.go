@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// WaitEstimate is the typical time generations with the same model and size
+// took to complete, derived from past generations in the local library.
+type WaitEstimate struct {
+	ModelID string
+	Width   int
+	Height  int
+	Typical time.Duration
+	Samples int
+}
+
+// EstimateWait returns the median completion time of the entries created
+// with modelID at width×height.  Entries without a recorded duration are
+// ignored; ok is false when none is left.
+func EstimateWait(entries []LibraryEntry, modelID string, width, height int) (WaitEstimate, bool) {
+	var durations []time.Duration
+	for _, e := range entries {
+		if e.Duration > 0 && e.ModelID == modelID && e.Width == width && e.Height == height {
+			durations = append(durations, e.Duration)
+		}
+	}
+	if len(durations) == 0 {
+		return WaitEstimate{}, false
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	typical := durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		typical = (durations[len(durations)/2-1] + typical) / 2
+	}
+	return WaitEstimate{ModelID: modelID, Width: width, Height: height, Typical: typical, Samples: len(durations)}, true
+}
+
+// Describe renders the estimate for people, e.g. "usually ~35s for Phoenix
+// 1024×1024".  modelName labels the model; the default model is used when
+// both it and the estimate's model ID are empty.
+func (e WaitEstimate) Describe(modelName string) string {
+	if modelName == "" {
+		modelName = e.ModelID
+	}
+	if modelName == "" {
+		modelName = "the default model"
+	}
+	size := "the default size"
+	if e.Width > 0 || e.Height > 0 {
+		size = fmt.Sprintf("%d×%d", e.Width, e.Height)
+	}
+	typical := e.Typical.Round(time.Second)
+	if typical < time.Second {
+		typical = time.Second
+	}
+	return fmt.Sprintf("usually ~%s for %s %s", typical, modelName, size)
+}
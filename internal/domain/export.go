@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Formats a sidecar can be exported to.
+const (
+	ExportA1111 = "a1111" // AUTOMATIC1111 "parameters" text
+	ExportComfy = "comfy" // ComfyUI workflow in API format
+)
+
+// ExportFormats lists the supported export formats.
+var ExportFormats = []string{ExportA1111, ExportComfy}
+
+// Settings assumed when a sidecar does not record them, matching the
+// defaults of the Stable Diffusion UIs.
+const (
+	exportDefaultSize     = 1024
+	exportDefaultCFG      = 7
+	exportDefaultSteps    = 30
+	exportDefaultSampler  = "euler"
+	exportDefaultSchedule = "normal"
+)
+
+// Export converts s to the given format.
+func (s Sidecar) Export(format string) ([]byte, error) {
+	switch format {
+	case ExportA1111:
+		return []byte(s.A1111Parameters()), nil
+	case ExportComfy:
+		return json.MarshalIndent(s.ComfyWorkflow(), "", "  ")
+	}
+	return nil, fmt.Errorf("unknown export format %q (want one of %s)", format, strings.Join(ExportFormats, ", "))
+}
+
+// A1111Parameters renders the sidecar as the "parameters" text AUTOMATIC1111
+// stores in its images and reads back with PNG Info: the prompt, the
+// negative prompt, and a line of comma-separated settings.  Only settings
+// the sidecar records are written.
+func (s Sidecar) A1111Parameters() string {
+	var b strings.Builder
+	b.WriteString(s.Prompt)
+	b.WriteString("\n")
+	if s.NegativePrompt != "" {
+		b.WriteString("Negative prompt: " + s.NegativePrompt + "\n")
+	}
+	var settings []string
+	add := func(key, value string) {
+		if strings.ContainsAny(value, ",:\n") {
+			value = strconv.Quote(value)
+		}
+		settings = append(settings, key+": "+value)
+	}
+	if s.GuidanceScale > 0 {
+		add("CFG scale", strconv.FormatFloat(s.GuidanceScale, 'f', -1, 64))
+	}
+	if s.Seed != 0 {
+		add("Seed", strconv.Itoa(s.Seed))
+	}
+	if s.Width > 0 && s.Height > 0 {
+		add("Size", fmt.Sprintf("%dx%d", s.Width, s.Height))
+	}
+	if s.ModelID != "" {
+		add("Model", s.ModelID)
+	}
+	if s.InitStrength > 0 {
+		add("Denoising strength", strconv.FormatFloat(1-s.InitStrength, 'f', -1, 64))
+	}
+	if s.GenerationID != "" {
+		add("Leonardo generation", s.GenerationID)
+	}
+	if s.StyleUUID != "" {
+		add("Leonardo style", s.StyleUUID)
+	}
+	b.WriteString(strings.Join(settings, ", "))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// ComfyNode is one node of a ComfyUI workflow in API format.
+type ComfyNode struct {
+	ClassType string                 `json:"class_type"`
+	Inputs    map[string]interface{} `json:"inputs"`
+}
+
+// ComfyWorkflow renders the sidecar as ComfyUI's default text-to-image
+// workflow in API format, which ComfyUI loads from a file or accepts on its
+// /prompt endpoint.  The checkpoint is named after the Leonardo model, so
+// it usually needs pointing at a local checkpoint before queueing.
+func (s Sidecar) ComfyWorkflow() map[string]ComfyNode {
+	width, height := s.Width, s.Height
+	if width <= 0 {
+		width = exportDefaultSize
+	}
+	if height <= 0 {
+		height = exportDefaultSize
+	}
+	cfg := s.GuidanceScale
+	if cfg <= 0 {
+		cfg = exportDefaultCFG
+	}
+	batch := s.NumImages
+	if batch <= 0 {
+		batch = 1
+	}
+	checkpoint := s.ModelID
+	if checkpoint == "" {
+		checkpoint = "model.safetensors"
+	}
+	prefix := s.Name
+	if prefix == "" {
+		prefix = s.GenerationID
+	}
+	if prefix == "" {
+		prefix = "leonardo"
+	}
+	return map[string]ComfyNode{
+		"3": {ClassType: "KSampler", Inputs: map[string]interface{}{
+			"seed":         s.Seed,
+			"steps":        exportDefaultSteps,
+			"cfg":          cfg,
+			"sampler_name": exportDefaultSampler,
+			"scheduler":    exportDefaultSchedule,
+			"denoise":      1,
+			"model":        []interface{}{"4", 0},
+			"positive":     []interface{}{"6", 0},
+			"negative":     []interface{}{"7", 0},
+			"latent_image": []interface{}{"5", 0},
+		}},
+		"4": {ClassType: "CheckpointLoaderSimple", Inputs: map[string]interface{}{"ckpt_name": checkpoint}},
+		"5": {ClassType: "EmptyLatentImage", Inputs: map[string]interface{}{"width": width, "height": height, "batch_size": batch}},
+		"6": {ClassType: "CLIPTextEncode", Inputs: map[string]interface{}{"text": s.Prompt, "clip": []interface{}{"4", 1}}},
+		"7": {ClassType: "CLIPTextEncode", Inputs: map[string]interface{}{"text": s.NegativePrompt, "clip": []interface{}{"4", 1}}},
+		"8": {ClassType: "VAEDecode", Inputs: map[string]interface{}{"samples": []interface{}{"3", 0}, "vae": []interface{}{"4", 2}}},
+		"9": {ClassType: "SaveImage", Inputs: map[string]interface{}{"filename_prefix": prefix, "images": []interface{}{"8", 0}}},
+	}
+}
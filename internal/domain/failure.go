@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError describes a non-2xx response returned by the Leonardo API.  The
+// status code and raw body are kept so callers can decide how to react to a
+// failure without parsing error strings.  RequestID is the identifier the API
+// assigned to the request, when it sent one, and is what support will ask for.
+type APIError struct {
+	StatusCode int
+	Body       []byte
+	RequestID  string
+}
+
+// Error implements the error interface using the historical
+// "API returned status N" message, followed by the request ID when known.
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("API returned status %d (request ID %s)", e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("API returned status %d", e.StatusCode)
+}
+
+// InvalidRequestError reports a generation request rejected locally, before
+// it was sent to the API.
+type InvalidRequestError struct {
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *InvalidRequestError) Error() string {
+	return "invalid request: " + e.Reason
+}
+
+// FailureClass groups failures by how a caller should react to them.
+type FailureClass string
+
+const (
+	// FailureModeration means the request was rejected by content moderation.
+	// Resubmitting the same prompt will fail again.
+	FailureModeration FailureClass = "moderation"
+	// FailureRateLimit means the API asked the caller to slow down.
+	FailureRateLimit FailureClass = "rate_limit"
+	// FailureTransient covers network errors, server errors and generations
+	// that failed on the server side; a later attempt may succeed.
+	FailureTransient FailureClass = "transient"
+	// FailurePermanent covers everything else, such as invalid parameters or
+	// authentication problems.
+	FailurePermanent FailureClass = "permanent"
+)
+
+// Retryable indicates whether resubmitting a request that failed with this
+// class can reasonably be expected to succeed.
+func (c FailureClass) Retryable() bool {
+	return c == FailureRateLimit || c == FailureTransient
+}
+
+// moderationMarkers are lowercase fragments found in Leonardo error bodies
+// when a prompt is rejected by content filters.
+var moderationMarkers = []string{"moderation", "nsfw", "content policy", "inappropriate"}
+
+// ClassifyFailure inspects an error returned by a LeonardoClient and reports
+// its FailureClass.  Requests rejected locally are permanent, and other errors
+// that carry no HTTP status (for example connection resets) are treated as
+// transient.
+func ClassifyFailure(err error) FailureClass {
+	if err == nil {
+		return ""
+	}
+	var invalid *InvalidRequestError
+	if errors.As(err, &invalid) {
+		return FailurePermanent
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return FailureTransient
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return FailureRateLimit
+	case apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode >= 500:
+		return FailureTransient
+	}
+	body := strings.ToLower(string(apiErr.Body))
+	for _, marker := range moderationMarkers {
+		if strings.Contains(body, marker) {
+			return FailureModeration
+		}
+	}
+	return FailurePermanent
+}
+
+// ErrorCode returns a short, stable name for the kind of err, for tools
+// that handle failures programmatically: the FailureClass of API errors,
+// "invalid_request" for requests rejected locally, "preflight" for failed
+// preflight checks, "interrupted" for cancelled runs and "error" for
+// anything else.
+func ErrorCode(err error) string {
+	var invalid *InvalidRequestError
+	var preflight *PreflightError
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.As(err, &invalid):
+		return "invalid_request"
+	case errors.As(err, &preflight):
+		return "preflight"
+	case errors.As(err, &apiErr):
+		return string(ClassifyFailure(apiErr))
+	}
+	return "error"
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// FileSystem is a file system that generated file names must be valid on.
+// Names written to a network share must suit the file system serving it,
+// which may not be the local one.
+type FileSystem string
+
+// Supported file systems.
+const (
+	// FileSystemPortable accepts only names valid on every other one, for
+	// shares whose server is unknown.
+	FileSystemPortable FileSystem = "portable"
+	FileSystemWindows  FileSystem = "windows"
+	FileSystemMacOS    FileSystem = "macos"
+	FileSystemLinux    FileSystem = "linux"
+)
+
+// FileSystems lists the supported file systems.
+var FileSystems = []FileSystem{FileSystemPortable, FileSystemWindows, FileSystemMacOS, FileSystemLinux}
+
+// maxFileNameBytes is the longest file name, in bytes, that the supported
+// file systems all store.
+const maxFileNameBytes = 255
+
+// windowsReserved are the device names Windows refuses as file names, with
+// or without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ParseFileSystem returns the file system named s, case-insensitively.
+func ParseFileSystem(s string) (FileSystem, error) {
+	for _, fs := range FileSystems {
+		if strings.EqualFold(strings.TrimSpace(s), string(fs)) {
+			return fs, nil
+		}
+	}
+	names := make([]string, len(FileSystems))
+	for i, fs := range FileSystems {
+		names[i] = string(fs)
+	}
+	return "", fmt.Errorf("unknown file system %q (use %s)", s, strings.Join(names, ", "))
+}
+
+// HostFileSystem returns the file system of the operating system goos, as
+// in runtime.GOOS; unknown systems get FileSystemPortable.
+func HostFileSystem(goos string) FileSystem {
+	switch goos {
+	case "windows":
+		return FileSystemWindows
+	case "darwin", "ios":
+		return FileSystemMacOS
+	case "linux", "android", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return FileSystemLinux
+	}
+	return FileSystemPortable
+}
+
+// windowsRules reports whether names must also be valid on Windows.  The
+// zero value is treated as FileSystemPortable.
+func (fs FileSystem) windowsRules() bool {
+	return fs != FileSystemMacOS && fs != FileSystemLinux
+}
+
+// illegal reports whether r cannot appear in a file name on fs.
+func (fs FileSystem) illegal(r rune) bool {
+	switch {
+	case r == '/' || r == 0 || r == utf8.RuneError:
+		return true
+	case fs == FileSystemMacOS:
+		// Finder shows ':' as '/', and older APIs treat it as the separator.
+		return r == ':'
+	case fs == FileSystemLinux:
+		return false
+	}
+	return r < 32 || strings.ContainsRune(`<>:"\|?*`, r)
+}
+
+// SanitizeFileName makes name, a file name without its directory, valid on
+// fs: illegal characters become '_', Windows device names such as CON get
+// a '_' appended and Windows' trailing dots and spaces are dropped.  The
+// result is cut at a character boundary so that reserve more bytes, such as
+// a suffix and extension added later, still fit the length limit.  An empty
+// result becomes "_".
+func (fs FileSystem) SanitizeFileName(name string, reserve int) string {
+	var b strings.Builder
+	for _, r := range name {
+		if fs.illegal(r) {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	clean := b.String()
+	limit := maxFileNameBytes - reserve
+	if limit < 1 {
+		limit = 1
+	}
+	for len(clean) > limit {
+		_, size := utf8.DecodeLastRuneInString(clean)
+		clean = clean[:len(clean)-size]
+	}
+	if fs.windowsRules() {
+		clean = strings.TrimRight(clean, ". ")
+		stem, _, _ := strings.Cut(clean, ".")
+		if windowsReserved[strings.ToUpper(strings.TrimSpace(stem))] {
+			clean = stem + "_" + strings.TrimPrefix(clean, stem)
+			if len(clean) > limit {
+				clean = stem + "_"
+			}
+		}
+	}
+	if clean == "" || clean == "." || clean == ".." {
+		return "_"
+	}
+	return clean
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedactedArg replaces the value of a secret flag in the history log.  It
+// is the marker that replaces registered secrets everywhere else.
+const RedactedArg = redactedMarker
+
+// secretFlags name the flags whose values are never written to the history.
+var secretFlags = map[string]bool{"secret": true, "token": true, "api-key": true, "api-token": true, "password": true}
+
+// promptFlags name the flags holding prompts, which are redacted in the
+// history like everywhere else when prompts are being redacted.
+var promptFlags = map[string]bool{"prompt": true, "negative-prompt": true}
+
+// HistoryEntry is one recorded invocation of the CLI.  Number is its
+// position in the history, counting from 1.  Dir is the working directory
+// it ran in, which matters for commands that write relative paths.
+type HistoryEntry struct {
+	Number       int
+	At           time.Time
+	Dir          string
+	Args         []string
+	ExitCode     int
+	GenerationID string
+}
+
+// CommandLine returns the arguments as they could be typed in a shell.
+func (e HistoryEntry) CommandLine() string {
+	quoted := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`*?[]{}()<>|&;#~") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Replayable returns an error when the entry lost information to redaction
+// and running it again would not repeat the original invocation.
+func (e HistoryEntry) Replayable() error {
+	for _, arg := range e.Args {
+		if strings.Contains(arg, RedactedArg) || IsRedactedPrompt(arg) {
+			return fmt.Errorf("invocation %d was recorded with redacted values and cannot be replayed", e.Number)
+		}
+	}
+	return nil
+}
+
+// RedactArgs returns a copy of command-line arguments safe to keep in the
+// history: values of secret flags are replaced with RedactedArg, prompts go
+// through r.Prompt and every argument through r.Text.  Flags may be written
+// as --flag=value or --flag value.
+func RedactArgs(args []string, r Redactor) []string {
+	out := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		out[i] = r.Text(arg)
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var redact func(string) string
+		switch {
+		case secretFlags[name]:
+			redact = func(string) string { return RedactedArg }
+		case promptFlags[name]:
+			redact = func(v string) string { return r.Text(r.Prompt(v)) }
+		default:
+			continue
+		}
+		if hasValue {
+			out[i] = arg[:len(arg)-len(value)] + redact(value)
+		} else if i+1 < len(args) {
+			i++
+			out[i] = redact(args[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportedPrompt is a prompt and its settings read from another tool's
+// metadata.  Metadata holds what maps onto a Leonardo request; Ignored
+// names the settings that have no Leonardo equivalent, such as the sampler
+// or step count, so callers can say what was left behind.
+type ImportedPrompt struct {
+	Metadata GenerationMetadata
+	Ignored  []string
+}
+
+// a1111NegativePrefix starts the negative prompt in AUTOMATIC1111
+// parameters text.
+const a1111NegativePrefix = "Negative prompt:"
+
+// ParseA1111Parameters reads AUTOMATIC1111 "parameters" text: the prompt,
+// an optional "Negative prompt:" section, and a last line of
+// comma-separated "Key: value" settings.  Prompt, negative prompt, seed,
+// size and CFG scale are mapped; the model is kept only when it is a
+// Leonardo model ID, as written by export, since checkpoint names mean
+// nothing to Leonardo.
+func ParseA1111Parameters(text string) (ImportedPrompt, error) {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n")), "\n")
+	var settings map[string]string
+	var order []string
+	if parsed, keys := parseA1111Settings(lines[len(lines)-1]); parsed != nil {
+		settings, order = parsed, keys
+		lines = lines[:len(lines)-1]
+	}
+	var prompt, negative []string
+	inNegative := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, a1111NegativePrefix) {
+			inNegative = true
+			line = strings.TrimSpace(strings.TrimPrefix(line, a1111NegativePrefix))
+		}
+		if inNegative {
+			negative = append(negative, line)
+		} else {
+			prompt = append(prompt, line)
+		}
+	}
+	var imported ImportedPrompt
+	imported.Metadata.Prompt = strings.TrimSpace(strings.Join(prompt, "\n"))
+	imported.Metadata.NegativePrompt = strings.TrimSpace(strings.Join(negative, "\n"))
+	if imported.Metadata.Prompt == "" {
+		return ImportedPrompt{}, fmt.Errorf("no prompt found in parameters text")
+	}
+	for _, key := range order {
+		if err := imported.apply(key, settings[key]); err != nil {
+			return ImportedPrompt{}, err
+		}
+	}
+	return imported, nil
+}
+
+// parseA1111Settings parses a line of "Key: value" pairs separated by
+// commas, where values containing commas are quoted.  It returns nil when
+// the line is not a settings line.
+func parseA1111Settings(line string) (map[string]string, []string) {
+	settings := map[string]string{}
+	var order []string
+	for rest := strings.TrimSpace(line); rest != ""; {
+		key, after, ok := strings.Cut(rest, ": ")
+		if !ok || key == "" || strings.ContainsAny(key, ",\"") {
+			return nil, nil
+		}
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			quoted, err := strconv.QuotedPrefix(after)
+			if err != nil {
+				return nil, nil
+			}
+			value, _ = strconv.Unquote(quoted)
+			after = after[len(quoted):]
+		} else {
+			end := strings.Index(after, ",")
+			if end < 0 {
+				end = len(after)
+			}
+			value, after = after[:end], after[end:]
+		}
+		settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		order = append(order, strings.TrimSpace(key))
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(after), ","))
+	}
+	for _, key := range []string{"Steps", "Seed", "Size", "CFG scale", "Sampler"} {
+		if _, ok := settings[key]; ok {
+			return settings, order
+		}
+	}
+	return nil, nil
+}
+
+// civitaiMeta is the generation metadata civitai shows for an image, either
+// on its own or under "meta".
+type civitaiMeta struct {
+	Prompt         string          `json:"prompt"`
+	NegativePrompt string          `json:"negativePrompt"`
+	Seed           json.Number     `json:"seed"`
+	CFGScale       json.Number     `json:"cfgScale"`
+	Size           string          `json:"Size"`
+	Width          int             `json:"width"`
+	Height         int             `json:"height"`
+	Meta           json.RawMessage `json:"meta"`
+}
+
+// ParseCivitaiJSON reads the generation metadata civitai publishes for an
+// image and maps prompt, negative prompt, seed, size and CFG scale.  The
+// remaining keys are reported as ignored.
+func ParseCivitaiJSON(data []byte) (ImportedPrompt, error) {
+	var meta civitaiMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return ImportedPrompt{}, fmt.Errorf("parsing civitai metadata: %w", err)
+	}
+	if meta.Prompt == "" && len(meta.Meta) > 0 && string(meta.Meta) != "null" {
+		return ParseCivitaiJSON(meta.Meta)
+	}
+	if strings.TrimSpace(meta.Prompt) == "" {
+		return ImportedPrompt{}, fmt.Errorf("no prompt found in civitai metadata")
+	}
+	imported := ImportedPrompt{Metadata: GenerationMetadata{
+		Prompt:         strings.TrimSpace(meta.Prompt),
+		NegativePrompt: strings.TrimSpace(meta.NegativePrompt),
+		Width:          meta.Width,
+		Height:         meta.Height,
+	}}
+	if meta.Seed != "" {
+		if err := imported.apply("Seed", meta.Seed.String()); err != nil {
+			return ImportedPrompt{}, err
+		}
+	}
+	if meta.CFGScale != "" {
+		if err := imported.apply("CFG scale", meta.CFGScale.String()); err != nil {
+			return ImportedPrompt{}, err
+		}
+	}
+	if meta.Size != "" {
+		if err := imported.apply("Size", meta.Size); err != nil {
+			return ImportedPrompt{}, err
+		}
+	}
+	var all map[string]json.RawMessage
+	json.Unmarshal(data, &all)
+	for key := range all {
+		switch key {
+		case "prompt", "negativePrompt", "seed", "cfgScale", "Size", "width", "height", "meta":
+		default:
+			imported.Ignored = append(imported.Ignored, key)
+		}
+	}
+	sort.Strings(imported.Ignored)
+	return imported, nil
+}
+
+// apply maps one setting onto the imported metadata, or records it as
+// ignored.
+func (p *ImportedPrompt) apply(key, value string) error {
+	var err error
+	switch key {
+	case "Seed":
+		var seed int64
+		seed, err = strconv.ParseInt(value, 10, 64)
+		// Leonardo seeds are signed 32-bit; -1 (random) and larger
+		// seeds cannot be reproduced.
+		if err == nil && (seed < 0 || seed > math.MaxInt32) {
+			p.Ignored = append(p.Ignored, key)
+			return nil
+		}
+		p.Metadata.Seed = int(seed)
+	case "CFG scale":
+		p.Metadata.GuidanceScale, err = strconv.ParseFloat(value, 64)
+	case "Size":
+		w, h, ok := strings.Cut(value, "x")
+		if !ok {
+			return fmt.Errorf("size %q is not WIDTHxHEIGHT", value)
+		}
+		if p.Metadata.Width, err = strconv.Atoi(w); err == nil {
+			p.Metadata.Height, err = strconv.Atoi(h)
+		}
+	case "Model":
+		// Leonardo model IDs are UUIDs, like generation IDs.
+		if !IsFullGenerationID(value) {
+			p.Ignored = append(p.Ignored, key)
+			return nil
+		}
+		p.Metadata.ModelID = value
+	case "Leonardo style":
+		p.Metadata.StyleUUID = value
+	case "Leonardo generation":
+		// Identifies the original; a new request gets its own.
+	default:
+		p.Ignored = append(p.Ignored, key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", key, value)
+	}
+	return nil
+}
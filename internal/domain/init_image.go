@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// initImageExtensions lists the file types accepted as init images.
+var initImageExtensions = map[string]bool{"png": true, "jpg": true, "jpeg": true, "webp": true}
+
+// InitImage is a reference image uploaded to Leonardo so generations can
+// start from it.  FileName is the local file it was uploaded from, when
+// known.
+type InitImage struct {
+	ID        string
+	URL       string
+	FileName  string
+	CreatedAt time.Time
+	Raw       []byte
+}
+
+// InitImageExtension returns the lower-case extension of path, without the
+// dot, or an error when the file type cannot be used as an init image.
+func InitImageExtension(path string) (string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if !initImageExtensions[ext] {
+		return "", fmt.Errorf("unsupported init image type %q (use png, jpg, jpeg or webp)", filepath.Ext(path))
+	}
+	return ext, nil
+}
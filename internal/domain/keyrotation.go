@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// KeyUsage accounts for what one API key of a rotation was used for during
+// a run.
+type KeyUsage struct {
+	Account string
+	// Requests counts the generations submitted with the key, including
+	// the ones that failed.
+	Requests int
+	// Generations counts the generations the key created, and Cost the API
+	// tokens they were charged when the API reported it.
+	Generations int
+	Cost        int
+	// RateLimited counts the submissions the API rate limited.
+	RateLimited int
+	// OutOfTokens is set once the API reported the key's balance could not
+	// pay for a generation; the key is not used again in the run.
+	OutOfTokens bool
+}
+
+// ParseAccountList splits a comma-separated list of stored account names,
+// dropping empty entries, as given to --account to rotate between keys.
+func ParseAccountList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// outOfTokensMarkers are lowercase fragments of Leonardo error bodies sent
+// when the account cannot pay for a request.
+var outOfTokensMarkers = []string{"not enough api tokens", "not enough tokens", "insufficient tokens", "insufficient balance", "insufficient credit"}
+
+// IsOutOfTokens reports whether err is the API refusing a request because
+// the key's token balance cannot pay for it.
+func IsOutOfTokens(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusPaymentRequired {
+		return true
+	}
+	body := strings.ToLower(string(apiErr.Body))
+	for _, marker := range outOfTokensMarkers {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kind is a generation template for a type of content, such as icons or
+// stickers.  Settings holds the create options that suit it keyed by flag
+// name, e.g. "width" or "negative-prompt", with their values as they would
+// be given on the command line.
+type Kind struct {
+	Name        string
+	Description string
+	Settings    map[string]string
+}
+
+// KindConfigPrefix starts the configuration keys that change a kind's
+// settings or add a kind, e.g. "kind.icon.model-id".
+const KindConfigPrefix = "kind."
+
+// kindModel is the model the built-in kinds generate with: it supports
+// Alchemy and preset styles, and handles the wide and square sizes used.
+const kindModel = "de7d3faf-762f-48e0-b3b7-9d0ac3a3fcf3" // Leonardo Phoenix 1.0
+
+// builtinKinds are the templates shipped with the CLI.
+var builtinKinds = []Kind{
+	{Name: "icon", Description: "Square app or UI icon on a plain background", Settings: map[string]string{
+		"width": "512", "height": "512", "model-id": kindModel, "alchemy": "true", "style": "graphic-design-vector",
+		"negative-prompt": "text, letters, watermark, photo, busy background, frame, border, blurry",
+	}},
+	{Name: "thumbnail", Description: "16:9 video or article thumbnail with a clear subject", Settings: map[string]string{
+		"width": "1280", "height": "720", "model-id": kindModel, "alchemy": "true", "style": "dynamic",
+		"negative-prompt": "watermark, text, blurry, low contrast, cropped subject",
+	}},
+	{Name: "hero-banner", Description: "Wide website header with room for a headline", Settings: map[string]string{
+		"width": "1536", "height": "640", "model-id": kindModel, "alchemy": "true", "style": "cinematic",
+		"negative-prompt": "text, watermark, logo, cluttered, busy composition, cropped",
+	}},
+	{Name: "sticker", Description: "Die-cut sticker illustration on a plain background", Settings: map[string]string{
+		"width": "1024", "height": "1024", "model-id": kindModel, "alchemy": "true", "style": "illustration",
+		"negative-prompt": "photo, realistic, background scenery, cast shadow, text, watermark",
+	}},
+	{Name: "seamless-texture", Description: "Flat, evenly lit material texture for tiling", Settings: map[string]string{
+		"width": "1024", "height": "1024", "model-id": kindModel, "alchemy": "false", "style": "none",
+		"negative-prompt": "seams, border, vignette, perspective, objects, shadows, uneven lighting, text, watermark",
+	}},
+}
+
+// kindSettingAliases map the friendlier setting names accepted in a
+// configuration file to the flag they set, as for top-level keys.
+var kindSettingAliases = map[string]string{
+	"model": "model-id",
+	"tag":   "tags",
+}
+
+// Kinds returns the built-in kinds with overrides applied, sorted by name.
+// overrides maps "NAME.SETTING" keys, the configuration keys without
+// KindConfigPrefix, to values: a setting replaces the built-in one, and a
+// name that is not built in adds a kind.  "NAME.description" describes a
+// kind and "NAME.size" such as 512x512 sets its width and height.
+func Kinds(overrides map[string]string) ([]Kind, error) {
+	byName := map[string]*Kind{}
+	for _, k := range builtinKinds {
+		k := Kind{Name: k.Name, Description: k.Description, Settings: copySettings(k.Settings)}
+		byName[k.Name] = &k
+	}
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, setting, ok := strings.Cut(key, ".")
+		if !ok || name == "" || setting == "" {
+			return nil, fmt.Errorf("kind setting %q must look like %sNAME.SETTING, e.g. %sicon.width", KindConfigPrefix+key, KindConfigPrefix, KindConfigPrefix)
+		}
+		k := byName[name]
+		if k == nil {
+			k = &Kind{Name: name, Settings: map[string]string{}}
+			byName[name] = k
+		}
+		value := overrides[key]
+		if alias, ok := kindSettingAliases[setting]; ok {
+			setting = alias
+		}
+		switch setting {
+		case "description":
+			k.Description = value
+		case "size":
+			w, h, ok := strings.Cut(strings.ToLower(value), "x")
+			if !ok {
+				return nil, fmt.Errorf("%s%s must look like 1024x768", KindConfigPrefix, key)
+			}
+			k.Settings["width"], k.Settings["height"] = strings.TrimSpace(w), strings.TrimSpace(h)
+		default:
+			k.Settings[setting] = value
+		}
+	}
+	kinds := make([]Kind, 0, len(byName))
+	for _, k := range byName {
+		kinds = append(kinds, *k)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Name < kinds[j].Name })
+	return kinds, nil
+}
+
+// FindKind returns the kind called name from kinds.
+func FindKind(kinds []Kind, name string) (Kind, error) {
+	names := make([]string, len(kinds))
+	for i, k := range kinds {
+		if strings.EqualFold(k.Name, strings.TrimSpace(name)) {
+			return k, nil
+		}
+		names[i] = k.Name
+	}
+	return Kind{}, fmt.Errorf("unknown kind %q (choose from %s)", name, strings.Join(names, ", "))
+}
+
+// SettingNames returns the names of the kind's settings in order.
+func (k Kind) SettingNames() []string {
+	names := make([]string, 0, len(k.Settings))
+	for name := range k.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copySettings returns a copy of settings, so overrides never change the
+// built-in kinds.
+func copySettings(settings map[string]string) map[string]string {
+	out := make(map[string]string, len(settings))
+	for key, value := range settings {
+		out[key] = value
+	}
+	return out
+}
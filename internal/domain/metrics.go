@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// CallMetric describes a single HTTP request made by a LeonardoClient.  A
+// StatusCode of zero means no response was received.
+type CallMetric struct {
+	Method     string
+	Path       string
+	StatusCode int
+	RequestID  string
+	Duration   time.Duration
+	// RateLimit is the allowance the response reported, if any.
+	RateLimit RateLimit
+}
+
+// Failed reports whether the call did not produce a successful response.
+func (m CallMetric) Failed() bool {
+	return m.StatusCode == 0 || m.StatusCode >= 300
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Model3D is a 3D model uploaded to Leonardo so textures can be generated
+// for it.  FileName is the local file it was uploaded from, when known.
+type Model3D struct {
+	ID       string
+	Name     string
+	URL      string
+	FileName string
+	Raw      []byte
+}
+
+// Model3DExtension returns the lower-case extension of path, without the
+// dot, or an error when the file type cannot be uploaded as a 3D model.
+// Leonardo accepts Wavefront OBJ files only.
+func Model3DExtension(path string) (string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext != "obj" {
+		return "", fmt.Errorf("unsupported 3D model type %q (use obj)", filepath.Ext(path))
+	}
+	return ext, nil
+}
+
+// Model3DName returns the name a 3D model uploaded from path is given when
+// none is chosen: the file name without its extension.
+func Model3DName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
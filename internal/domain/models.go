@@ -1,13 +1,25 @@
 package domain
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // GenerationRequest defines the parameters necessary to start an image generation.
 // Only a subset of Leonardo’s many parameters are exposed here; additional fields
 // can be added as required.  Fields with zero values will be omitted from the
 // request body by the provider layer.
 type GenerationRequest struct {
-	NumImages int  // optional number of images (default 1)
-	Private   bool // when true, request private images; false keeps API default visibility
-	Metadata  GenerationMetadata
+	NumImages      int    // optional number of images (default 1)
+	Private        bool   // when true, request private images; false keeps API default visibility
+	IdempotencyKey string // optional client-chosen key identifying the request across retries
+	Metadata       GenerationMetadata
+	// Params are extra fields added to the request body as given, for API
+	// parameters the CLI has no option for yet.  They replace fields of the
+	// same name the CLI sets.
+	Params map[string]json.RawMessage
 }
 
 // HasNumImages indicates whether request includes an explicit number of images.
@@ -30,7 +42,11 @@ func (r GenerationRequest) HasPrivate() bool {
 
 // GenerationMetadata captures generation details stored in a local sidecar file. It is written when a generation request is created.
 type GenerationMetadata struct {
-	Prompt         string
+	Name   string
+	Prompt string
+	// PromptTemplate is the prompt as written, before its wildcards were
+	// resolved into Prompt.  It is empty for prompts without wildcards.
+	PromptTemplate string
 	NegativePrompt string
 	ModelID        string
 	StyleUUID      string
@@ -43,6 +59,33 @@ type GenerationMetadata struct {
 	Ultra          bool
 	Contrast       float64
 	GuidanceScale  float64
+	// Alchemy-only refinements; see ValidateAlchemy.
+	HighResolution bool
+	ContrastRatio  float64
+	ExpandedDomain bool
+	HighContrast   bool
+	// PhotoReal asks for photographic output; it needs Alchemy and a model
+	// that supports it (see PlatformModel.Validate).
+	PhotoReal bool
+	// InitImageID starts the generation from an uploaded init image
+	// (image-to-image); InitStrength sets how closely it is followed.
+	InitImageID  string
+	InitStrength float64
+	// Elements are trained Elements (LoRAs) applied with their weights.
+	Elements []GenerationElement
+	// Note is context for people, such as what a client asked for, kept
+	// in the sidecar and the library; it is never sent to the API.
+	Note string
+}
+
+// HasName indicates whether metadata contains a human-friendly generation name.
+func (m GenerationMetadata) HasName() bool {
+	return m.Name != ""
+}
+
+// HasPromptTemplate indicates whether the prompt was resolved from wildcards.
+func (m GenerationMetadata) HasPromptTemplate() bool {
+	return m.PromptTemplate != ""
 }
 
 // HasNegativePrompt indicates whether metadata contains a negative prompt value.
@@ -100,19 +143,198 @@ func (m GenerationMetadata) HasGuidanceScale() bool {
 	return m.GuidanceScale != 0
 }
 
+// HasHighResolution indicates whether metadata asks for Alchemy's high resolution pass.
+func (m GenerationMetadata) HasHighResolution() bool {
+	return m.HighResolution
+}
+
+// HasContrastRatio indicates whether metadata contains an Alchemy contrast ratio.
+func (m GenerationMetadata) HasContrastRatio() bool {
+	return m.ContrastRatio != 0
+}
+
+// HasExpandedDomain indicates whether metadata enables Alchemy's expanded domain.
+func (m GenerationMetadata) HasExpandedDomain() bool {
+	return m.ExpandedDomain
+}
+
+// HasHighContrast indicates whether metadata enables Alchemy's high contrast.
+func (m GenerationMetadata) HasHighContrast() bool {
+	return m.HighContrast
+}
+
+// HasPhotoReal indicates whether metadata enables PhotoReal.
+func (m GenerationMetadata) HasPhotoReal() bool {
+	return m.PhotoReal
+}
+
+// HasInitImageID indicates whether metadata starts from an init image.
+func (m GenerationMetadata) HasInitImageID() bool {
+	return m.InitImageID != ""
+}
+
+// HasInitStrength indicates whether metadata contains an init strength.
+func (m GenerationMetadata) HasInitStrength() bool {
+	return m.InitStrength != 0
+}
+
+// HasElements indicates whether metadata applies any Elements.
+func (m GenerationMetadata) HasElements() bool {
+	return len(m.Elements) > 0
+}
+
+// ValidateInitImage checks the image-to-image fields: a strength needs an
+// init image and must lie within the 0.1 to 0.9 range the API accepts.
+func (m GenerationMetadata) ValidateInitImage() error {
+	if !m.HasInitStrength() {
+		return nil
+	}
+	if !m.HasInitImageID() {
+		return &InvalidRequestError{Reason: "init strength requires an init image"}
+	}
+	return ValidateInitStrength(m.InitStrength)
+}
+
+// ValidateInitStrength checks that an init strength lies within the 0.1 to
+// 0.9 range the API accepts.
+func ValidateInitStrength(strength float64) error {
+	if strength < 0.1 || strength > 0.9 {
+		return &InvalidRequestError{Reason: fmt.Sprintf("init strength %g must be between 0.1 and 0.9", strength)}
+	}
+	return nil
+}
+
+// ValidateAlchemy checks the Alchemy-only fields.  The API ignores them, or
+// rejects the request, when Alchemy is off, so setting any of them without
+// Alchemy is an error rather than a silent no-op.
+func (m GenerationMetadata) ValidateAlchemy() error {
+	var set []string
+	if m.HasHighResolution() {
+		set = append(set, "high resolution")
+	}
+	if m.HasContrastRatio() {
+		set = append(set, "contrast ratio")
+	}
+	if m.HasExpandedDomain() {
+		set = append(set, "expanded domain")
+	}
+	if m.HasHighContrast() {
+		set = append(set, "high contrast")
+	}
+	if m.HasPhotoReal() {
+		set = append(set, "PhotoReal")
+	}
+	if len(set) > 0 && !m.Alchemy {
+		return &InvalidRequestError{Reason: strings.Join(set, ", ") + " require Alchemy to be enabled"}
+	}
+	if m.ContrastRatio < 0 || m.ContrastRatio > 1 {
+		return &InvalidRequestError{Reason: fmt.Sprintf("contrast ratio %g must be between 0 and 1", m.ContrastRatio)}
+	}
+	return nil
+}
+
 // GenerationResponse represents the response returned after creating a generation.
 // It exposes the generation ID (if present) along with the raw JSON returned by the API.
 type GenerationResponse struct {
 	GenerationID string
-	Raw          []byte
+	// Cost is the API tokens the generation was charged, when the API
+	// reports it.
+	Cost int
+	Raw  []byte
 }
 
 // GenerationStatus represents the status of a generation and any generated image URLs.
 // The Raw field contains the full JSON payload returned by the API for transparency.
 type GenerationStatus struct {
-	Status string
-	Images []string
-	Raw    []byte
+	Status    string
+	Images    []string
+	CreatedAt time.Time
+	Raw       []byte
+}
+
+// GenerationDetail is the complete record the API stores for a generation,
+// including its parameters and per-image data.
+type GenerationDetail struct {
+	ID             string
+	Status         string
+	CreatedAt      time.Time
+	Prompt         string
+	NegativePrompt string
+	ModelID        string
+	Scheduler      string
+	PresetStyle    string
+	SDVersion      string
+	Width          int
+	Height         int
+	InferenceSteps int
+	Seed           int
+	GuidanceScale  float64
+	InitStrength   float64
+	InitImageID    string
+	Public         bool
+	PhotoReal      bool
+	Alchemy        bool
+	Ultra          bool
+	PromptMagic    bool
+	Elements       []GenerationElement
+	Images         []GeneratedImage
+	Raw            []byte
+}
+
+// GenerationElement is an Element (LoRA) applied to a generation.
+type GenerationElement struct {
+	ID     string
+	Name   string
+	Weight float64
+}
+
+// GeneratedImage is a single image produced by a generation together with
+// any variations (upscales, background removal, ...) derived from it.
+type GeneratedImage struct {
+	ID         string
+	URL        string
+	NSFW       bool
+	LikeCount  int
+	Variations []ImageVariation
+}
+
+// ImageVariation is a processed copy of a generated image.
+type ImageVariation struct {
+	ID            string
+	URL           string
+	Status        string
+	TransformType string
+}
+
+// variationSuffixes name the files of the common variation types; other
+// types use their lower-cased name.
+var variationSuffixes = map[string]string{"UPSCALE": "upscaled", "NOBG": "nobg", "UNZOOM": "unzoom"}
+
+// FileSuffix returns the suffix added to the file name of a downloaded
+// variation, e.g. "upscaled" for an upscale.
+func (v ImageVariation) FileSuffix() string {
+	if suffix, ok := variationSuffixes[strings.ToUpper(v.TransformType)]; ok {
+		return suffix
+	}
+	if v.TransformType == "" {
+		return "variation"
+	}
+	return strings.ToLower(v.TransformType)
+}
+
+// DownloadedVariation records a variation saved next to the original
+// images.  Image is the 1-based position of the image it was derived from.
+type DownloadedVariation struct {
+	Image     int
+	Variation ImageVariation
+	Path      string
+}
+
+// VariationJob is a processing job, such as an upscale, started on a
+// generated image.  Its result is retrieved as an ImageVariation.
+type VariationJob struct {
+	ID  string
+	Raw []byte
 }
 
 // DeleteResponse represents the result of deleting a generation.
@@ -133,14 +355,29 @@ type UserInfo struct {
 	Raw                   []byte
 }
 
+// PlanType describes how the account pays for API usage.  The API does not
+// name the plan, so it is inferred: only API subscriptions have a token
+// renewal date.
+func (u UserInfo) PlanType() string {
+	if strings.TrimSpace(u.TokenRenewalDate) != "" {
+		return "subscription"
+	}
+	return "pay-as-you-go"
+}
+
 // GenerationListItem represents a single generation in a list response.
 // It contains a subset of generation metadata along with any image URLs.
 type GenerationListItem struct {
 	ID        string
 	Status    string
-	CreatedAt string
+	CreatedAt time.Time
 	Prompt    string
 	Images    []string
+	// The settings that decide what a generation costs.
+	ModelID string
+	Width   int
+	Height  int
+	Alchemy bool
 }
 
 // GenerationListResponse represents a paginated list of user generations.
@@ -151,9 +388,11 @@ type GenerationListResponse struct {
 
 // DownloadResult represents the outcome of downloading generated images
 // for a single generation.  It contains the list of file paths where images
-// were saved.
+// were saved, and the outcome of every image and variation attempted.
 type DownloadResult struct {
-	FilePaths []string
+	FilePaths  []string
+	Variations []DownloadedVariation
+	Outcomes   []ImageDownload
 }
 
 // PlatformModel represents a single platform model available for generation.
@@ -161,6 +400,7 @@ type PlatformModel struct {
 	ID          string
 	Name        string
 	Description string
+	SDVersion   string // base architecture, e.g. SDXL_1_0 or PHOENIX
 }
 
 // PlatformModelResponse represents the response from listing platform models.
@@ -168,3 +408,49 @@ type PlatformModelResponse struct {
 	Models []PlatformModel
 	Raw    []byte
 }
+
+// LibraryEntry records a generation created from this machine so it can be
+// referred to later by name instead of by its UUID.  Favorite generations are
+// never removed by local cleanup.
+type LibraryEntry struct {
+	GenerationID string
+	Name         string
+	Prompt       string
+	ModelID      string
+	CreatedAt    time.Time
+	SidecarPath  string
+	Favorite     bool
+	Tags         []string
+	// Width and Height are the requested size, zero for the model default.
+	Width  int
+	Height int
+	// Duration is how long the generation took to complete, when the CLI
+	// waited for it; zero otherwise.
+	Duration time.Duration
+	// Cost is the API tokens the generation was charged, when known.
+	Cost int
+	// Note is the note given at create time.
+	Note string
+}
+
+// HasTag reports whether the entry carries tag, ignoring case.
+func (e LibraryEntry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeTags returns tags followed by the extra tags it does not already
+// contain, ignoring case.
+func MergeTags(tags, extra []string) []string {
+	merged := append([]string(nil), tags...)
+	for _, tag := range extra {
+		if !(LibraryEntry{Tags: merged}).HasTag(tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
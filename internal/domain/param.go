@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParamConfigPrefix starts the configuration keys adding extra fields to
+// every create request, e.g. "param.transparency: foreground_only".
+const ParamConfigPrefix = "param."
+
+// ParseParam parses a KEY=VALUE extra request field.  A value that is valid
+// JSON, such as 3, true, null or {"a":1}, is sent as it is; anything else
+// is sent as a string, so transparency=foreground_only needs no quoting.
+func ParseParam(spec string) (string, json.RawMessage, error) {
+	key, value, ok := strings.Cut(spec, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", nil, fmt.Errorf("param %q must be KEY=VALUE", spec)
+	}
+	if strings.ContainsAny(key, " \t\"") {
+		return "", nil, fmt.Errorf("param key %q must not contain spaces or quotes", key)
+	}
+	return key, ParamValue(value), nil
+}
+
+// ParamValue returns value as a JSON field value: unchanged when it is
+// valid JSON, as a JSON string otherwise.
+func ParamValue(value string) json.RawMessage {
+	if trimmed := strings.TrimSpace(value); trimmed != "" && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	encoded, _ := json.Marshal(value)
+	return encoded
+}
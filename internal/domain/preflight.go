@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requests without a size are priced at the largest default size of the
+// platform models, so an estimate errs on the expensive side.
+const (
+	preflightDefaultWidth  = 1024
+	preflightDefaultHeight = 1024
+)
+
+// PriceQueryFor returns the pricing query for a request to the given model.
+// custom marks models missing from the platform list.
+func PriceQueryFor(req GenerationRequest, model PlatformModel, custom bool) PriceQuery {
+	q := PriceQuery{
+		Width:       req.Metadata.Width,
+		Height:      req.Metadata.Height,
+		NumImages:   req.NumImagesOrDefault(),
+		Alchemy:     req.Metadata.HasAlchemy(),
+		SDVersion:   model.SDVersion,
+		CustomModel: custom,
+	}
+	if !req.Metadata.HasWidth() {
+		q.Width = preflightDefaultWidth
+	}
+	if !req.Metadata.HasHeight() {
+		q.Height = preflightDefaultHeight
+	}
+	return q
+}
+
+// PreflightReport is the outcome of checking, before a run starts, that it
+// can complete: the token is valid and the account holds enough tokens for
+// the estimated cost.  Requests whose cost could not be estimated are
+// counted in Unpriced and left out of EstimatedCost.
+type PreflightReport struct {
+	Requests      int
+	Token         TokenState
+	TokenError    error
+	Balance       int
+	EstimatedCost int
+	Unpriced      int
+}
+
+// Err returns a *PreflightError when the run cannot complete, or nil.
+func (r PreflightReport) Err() error {
+	if r.Token != TokenValid || r.EstimatedCost > r.Balance {
+		return &PreflightError{Report: r}
+	}
+	return nil
+}
+
+// Summary describes the report in one line.
+func (r PreflightReport) Summary() string {
+	if r.Token != TokenValid {
+		if r.TokenError != nil {
+			return fmt.Sprintf("token %s (%v)", r.Token, r.TokenError)
+		}
+		return fmt.Sprintf("token %s", r.Token)
+	}
+	parts := []string{
+		fmt.Sprintf("%d requests", r.Requests),
+		fmt.Sprintf("estimated cost %d tokens", r.EstimatedCost),
+		fmt.Sprintf("balance %d tokens", r.Balance),
+	}
+	if r.Unpriced > 0 {
+		parts = append(parts, fmt.Sprintf("%d not priced", r.Unpriced))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PreflightError reports a run stopped before it started because it could
+// not possibly complete.
+type PreflightError struct {
+	Report PreflightReport
+}
+
+// Error implements the error interface.
+func (e *PreflightError) Error() string {
+	r := e.Report
+	if r.Token != TokenValid {
+		return "preflight failed: " + r.Summary()
+	}
+	return fmt.Sprintf("preflight failed: not enough tokens, short by %d (%s)", r.EstimatedCost-r.Balance, r.Summary())
+}
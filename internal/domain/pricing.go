@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PriceQuery describes a generation configuration to price.  SDVersion is
+// the base architecture of the model, which the pricing calculator charges
+// by, and CustomModel marks models missing from the platform list.
+type PriceQuery struct {
+	Width       int
+	Height      int
+	NumImages   int
+	Alchemy     bool
+	SDVersion   string
+	CustomModel bool
+}
+
+// SDXL reports whether the query is for an SDXL based model.
+func (q PriceQuery) SDXL() bool {
+	return strings.HasPrefix(strings.ToUpper(q.SDVersion), "SDXL")
+}
+
+// Phoenix reports whether the query is for a Phoenix model.
+func (q PriceQuery) Phoenix() bool {
+	return strings.EqualFold(q.SDVersion, "PHOENIX")
+}
+
+// PriceQuote is the token cost of a configuration.
+type PriceQuote struct {
+	PriceQuery
+	Cost int
+}
+
+// PerImage returns the cost of each image in the configuration.
+func (q PriceQuote) PerImage() float64 {
+	if q.NumImages < 1 {
+		return float64(q.Cost)
+	}
+	return float64(q.Cost) / float64(q.NumImages)
+}
+
+// SortQuotesByCost orders quotes cheapest per image first.  Quotes costing
+// the same per image keep their order.
+func SortQuotesByCost(quotes []PriceQuote) {
+	sort.SliceStable(quotes, func(i, j int) bool {
+		return quotes[i].PerImage() < quotes[j].PerImage()
+	})
+}
+
+// Size is an image width and height.
+type Size struct {
+	Width  int
+	Height int
+}
+
+// String formats the size as WIDTHxHEIGHT.
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// DefaultPricingSizes are the common sizes priced when none are chosen.
+var DefaultPricingSizes = []Size{{512, 512}, {768, 768}, {1024, 768}, {768, 1024}, {1024, 1024}, {1536, 1536}}
+
+// DefaultPricingImageCounts are the image counts priced when none are chosen.
+var DefaultPricingImageCounts = []int{1, 2, 4}
+
+// ParseSize parses a size written as WIDTHxHEIGHT, e.g. 1024x768.
+func ParseSize(s string) (Size, error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	width, errW := strconv.Atoi(strings.TrimSpace(w))
+	height, errH := strconv.Atoi(strings.TrimSpace(h))
+	if !ok || errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return Size{}, fmt.Errorf("invalid size %q (use WIDTHxHEIGHT, e.g. 1024x768)", s)
+	}
+	return Size{Width: width, Height: height}, nil
+}
+
+// PricingGrid lists the configurations to price for a model: every size it
+// accepts times every image count, with and without Alchemy when the model
+// supports it.  Sizes the model rejects are returned separately so they can
+// be reported.
+func PricingGrid(model PlatformModel, custom bool, sizes []Size, counts []int) (queries []PriceQuery, skipped []Size) {
+	caps := model.Capabilities()
+	alchemy := []bool{false}
+	if caps.Alchemy {
+		alchemy = append(alchemy, true)
+	}
+	for _, size := range sizes {
+		if !caps.fits(size.Width) || !caps.fits(size.Height) {
+			skipped = append(skipped, size)
+			continue
+		}
+		for _, a := range alchemy {
+			for _, n := range counts {
+				queries = append(queries, PriceQuery{
+					Width:       size.Width,
+					Height:      size.Height,
+					NumImages:   n,
+					Alchemy:     a,
+					SDVersion:   model.SDVersion,
+					CustomModel: custom,
+				})
+			}
+		}
+	}
+	return queries, skipped
+}
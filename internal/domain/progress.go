@@ -0,0 +1,27 @@
+package domain
+
+// ProgressKind names a step in the life of a generation as seen by the CLI.
+type ProgressKind string
+
+const (
+	// ProgressSubmitted is reported once a generation has been accepted.
+	ProgressSubmitted ProgressKind = "submitted"
+	// ProgressPolling is reported after each status check while waiting.
+	ProgressPolling ProgressKind = "polling"
+	// ProgressImageDownloaded is reported after each image is saved.
+	ProgressImageDownloaded ProgressKind = "image-downloaded"
+	// ProgressDone is reported once when the command finishes.
+	ProgressDone ProgressKind = "done"
+)
+
+// ProgressEvent describes a progress step.  Only the fields relevant to its
+// kind are set: Attempt for polling, Image, Images and Path for downloads.
+type ProgressEvent struct {
+	Kind         ProgressKind
+	GenerationID string
+	Status       string
+	Attempt      int
+	Image        int
+	Images       int
+	Path         string
+}
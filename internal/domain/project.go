@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// ProjectManifestName is the file recording asset provenance at the root of
+// a project.  Like a lock file it is meant to be committed.
+const ProjectManifestName = "leonardo.lock"
+
+// ProjectAsset records which generation produced a file committed to a
+// project.  Path is relative to the manifest, with forward slashes, so the
+// manifest reads the same on every platform.  SHA256 identifies the exact
+// file content recorded.
+type ProjectAsset struct {
+	Path         string
+	GenerationID string
+	Prompt       string
+	ModelID      string
+	SHA256       string
+	AddedAt      time.Time
+}
+
+// CleanAssetPath normalises a project-relative path and reports whether it
+// stays inside the project.
+func CleanAssetPath(p string) (string, bool) {
+	p = path.Clean(strings.ReplaceAll(p, "\\", "/"))
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") || path.IsAbs(p) {
+		return "", false
+	}
+	return p, true
+}
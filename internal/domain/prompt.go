@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultPromptTokenLimit is how many prompt tokens a Stable Diffusion model
+// reads.  Its CLIP text encoder takes 77 tokens, two of which mark the start
+// and end of the prompt; anything past the limit is silently ignored.
+const DefaultPromptTokenLimit = 75
+
+// t5PromptTokenLimit is the limit of models with a T5 text encoder, such as
+// FLUX.
+const t5PromptTokenLimit = 512
+
+// charsPerToken approximates how many letters of an uncommon word the
+// tokenizer packs into one token.  Common words are a single token.
+const charsPerToken = 5
+
+// EstimatePromptTokens approximates the number of tokens a prompt is split
+// into.  Words count one token per few letters and punctuation one token per
+// mark; the estimate is usually within ten percent of the real tokenizer.
+func EstimatePromptTokens(prompt string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		if word > 0 {
+			tokens += (word + charsPerToken - 1) / charsPerToken
+			word = 0
+		}
+	}
+	for _, r := range prompt {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// sentenceEnds end a sentence; clauseEnds end a part of a prompt that can be
+// dropped on its own when no sentence fits.
+const (
+	sentenceEnds = ".!?;\n"
+	clauseEnds   = ","
+)
+
+// TruncatePrompt shortens prompt to at most limit estimated tokens.  It cuts
+// after the last whole sentence that fits, failing that after the last
+// comma-separated clause, and failing that after the last whole word.  A
+// prompt that already fits is returned unchanged.
+func TruncatePrompt(prompt string, limit int) string {
+	if limit <= 0 || EstimatePromptTokens(prompt) <= limit {
+		return prompt
+	}
+	for _, ends := range []string{sentenceEnds, clauseEnds, " \t"} {
+		if cut := lastFittingCut(prompt, limit, ends); cut != "" {
+			return cut
+		}
+	}
+	return ""
+}
+
+// lastFittingCut returns the longest prefix of prompt ending in one of ends
+// that fits in limit tokens, trimmed of trailing separators.
+func lastFittingCut(prompt string, limit int, ends string) string {
+	best := ""
+	for i, r := range prompt {
+		if !strings.ContainsRune(ends, r) {
+			continue
+		}
+		if r == '.' && i+1 < len(prompt) && !unicode.IsSpace(rune(prompt[i+1])) {
+			// A decimal point or abbreviation, not the end of a sentence.
+			continue
+		}
+		prefix := prompt[:i]
+		if strings.ContainsRune(sentenceEnds, r) && r != '\n' {
+			prefix = prompt[:i+1]
+		}
+		prefix = strings.TrimRight(prefix, " \t\n,")
+		if EstimatePromptTokens(prefix) > limit {
+			break
+		}
+		best = prefix
+	}
+	return best
+}
@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// SignatureAlgorithm is the only algorithm sidecars are signed with.
+const SignatureAlgorithm = "ed25519"
+
+// sidecarSignatureField is the sidecar key holding its signature.
+const sidecarSignatureField = "signature"
+
+// SidecarSignature is the signature stored in a signed sidecar.  The public
+// key travels with it, so anyone can check the sidecar was not changed
+// since it was signed; KeyID identifies the key for comparing it with one
+// the signer published.
+type SidecarSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Value     string `json:"value"`
+}
+
+// ErrUnsignedSidecar is returned when verifying a sidecar that carries no
+// signature.
+var ErrUnsignedSidecar = errors.New("sidecar is not signed")
+
+// KeyID returns a short fingerprint of a public key: the first 16 hex
+// digits of its SHA-256.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseSigningKey reads an ed25519 private key from a PEM "PRIVATE KEY"
+// block, as written by keygen or openssl genpkey -algorithm ed25519.
+func ParseSigningKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("signing key is not a PEM PRIVATE KEY")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key is not an ed25519 key")
+	}
+	return key, nil
+}
+
+// ParsePublicKey reads an ed25519 public key from a PEM "PUBLIC KEY" block.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("public key is not a PEM PUBLIC KEY")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an ed25519 key")
+	}
+	return key, nil
+}
+
+// EncodeKeyPair returns the PEM encodings of an ed25519 key pair, private
+// key first.
+func EncodeKeyPair(key ed25519.PrivateKey) ([]byte, []byte, error) {
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	public, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), nil
+}
+
+// canonicalSidecar returns the bytes a sidecar's signature covers: its
+// fields other than the signature as compact JSON with sorted keys, so
+// re-indenting the file does not break the signature.
+func canonicalSidecar(fields map[string]interface{}) ([]byte, error) {
+	unsigned := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if key != sidecarSignatureField {
+			unsigned[key] = value
+		}
+	}
+	return json.Marshal(unsigned)
+}
+
+// decodeSidecarFields decodes a sidecar keeping numbers as written.
+func decodeSidecarFields(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("parsing sidecar metadata: %w", err)
+	}
+	return fields, nil
+}
+
+// SignSidecar returns the sidecar data with a signature by key added,
+// replacing any previous one, indented as sidecars are written.
+func SignSidecar(data []byte, key ed25519.PrivateKey) ([]byte, error) {
+	fields, err := decodeSidecarFields(data)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := canonicalSidecar(fields)
+	if err != nil {
+		return nil, err
+	}
+	public := key.Public().(ed25519.PublicKey)
+	fields[sidecarSignatureField] = SidecarSignature{
+		Algorithm: SignatureAlgorithm,
+		KeyID:     KeyID(public),
+		PublicKey: base64.StdEncoding.EncodeToString(public),
+		Value:     base64.StdEncoding.EncodeToString(ed25519.Sign(key, canonical)),
+	}
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// VerifySidecar checks the signature of a sidecar against the public key it
+// carries and returns the signature.  When trusted is not nil the sidecar
+// must also have been signed with that key.
+func VerifySidecar(data []byte, trusted ed25519.PublicKey) (SidecarSignature, error) {
+	fields, err := decodeSidecarFields(data)
+	if err != nil {
+		return SidecarSignature{}, err
+	}
+	raw, ok := fields[sidecarSignatureField]
+	if !ok {
+		return SidecarSignature{}, ErrUnsignedSidecar
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return SidecarSignature{}, err
+	}
+	var sig SidecarSignature
+	if err := json.Unmarshal(encoded, &sig); err != nil {
+		return SidecarSignature{}, fmt.Errorf("parsing sidecar signature: %w", err)
+	}
+	if sig.Algorithm != SignatureAlgorithm {
+		return sig, fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	public, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+	if err != nil || len(public) != ed25519.PublicKeySize {
+		return sig, fmt.Errorf("sidecar signature has an invalid public key")
+	}
+	value, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return sig, fmt.Errorf("sidecar signature is not valid base64")
+	}
+	canonical, err := canonicalSidecar(fields)
+	if err != nil {
+		return sig, err
+	}
+	if !ed25519.Verify(public, canonical, value) {
+		return sig, fmt.Errorf("signature does not match: the sidecar was changed after it was signed")
+	}
+	if trusted != nil && !bytes.Equal(trusted, public) {
+		return sig, fmt.Errorf("sidecar was signed with key %s, not the trusted key %s", sig.KeyID, KeyID(trusted))
+	}
+	return sig, nil
+}
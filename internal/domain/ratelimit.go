@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// lowRateLimitFraction is the share of the request allowance below which
+// the remaining requests count as nearly exhausted.
+const lowRateLimitFraction = 0.1
+
+// RateLimit is the request allowance the API reported on a response.  The
+// zero value means the response carried no rate-limit headers.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	// Reset is when the allowance refills; zero when not reported.
+	Reset time.Time
+}
+
+// Known reports whether the API reported a rate limit.
+func (r RateLimit) Known() bool {
+	return r.Limit > 0
+}
+
+// Low reports whether the remaining requests are nearly exhausted: at most
+// a tenth of the limit, and never fewer than one left.
+func (r RateLimit) Low() bool {
+	if !r.Known() {
+		return false
+	}
+	threshold := int(float64(r.Limit) * lowRateLimitFraction)
+	if threshold < 1 {
+		threshold = 1
+	}
+	return r.Remaining <= threshold
+}
+
+// Describe summarises the allowance, e.g. "7 of 100 requests left, resets
+// in 42s".
+func (r RateLimit) Describe(now time.Time) string {
+	text := fmt.Sprintf("%d of %d requests left", r.Remaining, r.Limit)
+	if !r.Reset.IsZero() {
+		wait := r.Reset.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		text += fmt.Sprintf(", resets in %s", wait.Round(time.Second))
+	}
+	return text
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RawRequest is an arbitrary request against the Leonardo API, for
+// endpoints the CLI does not wrap.  Path is relative to the API base URL,
+// such as "/generations" or "/me", and may carry a query string.  Body is
+// sent as JSON when it is not nil.
+type RawRequest struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// Idempotent reports whether repeating the request has the same effect as
+// sending it once, so that it is safe to retry after any transient failure.
+// Other requests, such as POST, are only retried when the API rejected
+// them before doing anything, as with rate limits.
+func (r RawRequest) Idempotent() bool {
+	switch strings.ToUpper(r.Method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// RawResponse is the API's answer to a RawRequest.  RetryAfter is the delay
+// a Retry-After header asked for, or zero.
+type RawResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	RetryAfter time.Duration
+}
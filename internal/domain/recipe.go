@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxRecipeSize is the largest recipe accepted from a URL.  Recipes are a
+// few hundred bytes; anything much larger is not one.
+const MaxRecipeSize = 1 << 20
+
+// Recipe is the settings of a generation shared for others to reproduce:
+// a sidecar written by create, the generation.json of a share bundle, or
+// AUTOMATIC1111 parameters text such as export writes.  Settings maps
+// create options to values, like a Kind's; Elements are applied to the
+// request as they are.  Ignored names the settings that cannot be reused,
+// such as an init image, which belongs to the account that uploaded it.
+type Recipe struct {
+	Settings map[string]string
+	Elements []GenerationElement
+	Ignored  []string
+}
+
+// recipeFile is the JSON form of a recipe: the fields sidecars and share
+// bundles have in common, plus each one's own.
+type recipeFile struct {
+	Prompt         string  `json:"prompt"`
+	NegativePrompt string  `json:"negative_prompt"`
+	ModelID        string  `json:"model_id"`
+	StyleUUID      string  `json:"style_uuid"`
+	PresetStyle    string  `json:"preset_style"`
+	Seed           int     `json:"seed"`
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	NumImages      int     `json:"num_images"`
+	GuidanceScale  float64 `json:"guidance_scale"`
+	Contrast       float64 `json:"contrast"`
+	Alchemy        bool    `json:"alchemy"`
+	Ultra          bool    `json:"ultra"`
+	PhotoReal      bool    `json:"photo_real"`
+	InitImageID    string  `json:"init_image_id"`
+	Elements       []struct {
+		ID     string  `json:"id"`
+		Weight float64 `json:"weight"`
+	} `json:"elements"`
+}
+
+// ParseRecipe reads a recipe, as JSON when it is an object and as
+// AUTOMATIC1111 parameters text otherwise.  A recipe whose prompt was
+// redacted cannot be reproduced and is refused.
+func ParseRecipe(data []byte) (Recipe, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		imported, err := ParseA1111Parameters(string(data))
+		if err != nil {
+			return Recipe{}, err
+		}
+		return recipeFromMetadata(imported.Metadata, imported.Ignored)
+	}
+	var file recipeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Recipe{}, fmt.Errorf("parsing recipe: %w", err)
+	}
+	meta := GenerationMetadata{
+		Prompt:         file.Prompt,
+		NegativePrompt: file.NegativePrompt,
+		ModelID:        file.ModelID,
+		StyleUUID:      file.StyleUUID,
+		Seed:           file.Seed,
+		Width:          file.Width,
+		Height:         file.Height,
+		GuidanceScale:  file.GuidanceScale,
+		Contrast:       file.Contrast,
+		Alchemy:        file.Alchemy,
+		Ultra:          file.Ultra,
+		PhotoReal:      file.PhotoReal,
+	}
+	var ignored []string
+	if file.InitImageID != "" {
+		ignored = append(ignored, "init_image_id")
+	}
+	if file.PresetStyle != "" && file.StyleUUID == "" {
+		if uuid, err := ResolveStyle(file.PresetStyle); err == nil {
+			meta.StyleUUID = uuid
+		} else {
+			ignored = append(ignored, "preset_style")
+		}
+	}
+	for _, e := range file.Elements {
+		meta.Elements = append(meta.Elements, GenerationElement{ID: e.ID, Weight: e.Weight})
+	}
+	recipe, err := recipeFromMetadata(meta, ignored)
+	if err == nil && file.NumImages > 0 {
+		recipe.Settings["num-images"] = strconv.Itoa(file.NumImages)
+	}
+	return recipe, err
+}
+
+// recipeFromMetadata turns the settings of meta into create options.
+func recipeFromMetadata(meta GenerationMetadata, ignored []string) (Recipe, error) {
+	if strings.TrimSpace(meta.Prompt) == "" {
+		return Recipe{}, fmt.Errorf("recipe has no prompt")
+	}
+	if IsRedactedPrompt(meta.Prompt) {
+		return Recipe{}, fmt.Errorf("recipe's prompt was redacted when it was written and cannot be reused")
+	}
+	recipe := Recipe{Settings: map[string]string{"prompt": meta.Prompt}, Elements: meta.Elements, Ignored: ignored}
+	set := func(name string, present bool, value string) {
+		if present {
+			recipe.Settings[name] = value
+		}
+	}
+	float := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	set("negative-prompt", meta.HasNegativePrompt() && !IsRedactedPrompt(meta.NegativePrompt), meta.NegativePrompt)
+	set("model-id", meta.HasModelID(), meta.ModelID)
+	set("style-uuid", meta.HasStyleUUID(), meta.StyleUUID)
+	set("seed", meta.HasSeed(), strconv.Itoa(meta.Seed))
+	set("width", meta.HasWidth(), strconv.Itoa(meta.Width))
+	set("height", meta.HasHeight(), strconv.Itoa(meta.Height))
+	set("guidance-scale", meta.HasGuidanceScale(), float(meta.GuidanceScale))
+	set("contrast", meta.HasContrast(), float(meta.Contrast))
+	set("alchemy", meta.Alchemy, "true")
+	set("ultra", meta.Ultra, "true")
+	set("photo-real", meta.HasPhotoReal(), "true")
+	sort.Strings(recipe.Ignored)
+	return recipe, nil
+}
+
+// SettingNames returns the create options the recipe sets, sorted.
+func (r Recipe) SettingNames() []string {
+	names := make([]string, 0, len(r.Settings))
+	for name := range r.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
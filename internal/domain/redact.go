@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// redactedMarker replaces secrets removed by a Redactor.
+const redactedMarker = "[redacted]"
+
+// redactedPromptPrefix starts every redacted prompt.  The prompt's hash is
+// kept so identical prompts can still be matched across shared files.
+const redactedPromptPrefix = "[redacted prompt sha256:"
+
+// minSecretLength is the shortest value a Redactor will hide; shorter values
+// would match ordinary text.
+const minSecretLength = 4
+
+// Redactor removes sensitive values, such as API tokens and user IDs, from
+// text before it is logged or written to shared files.  When Prompts is set,
+// prompts are replaced by a short hash as well.  The zero value redacts
+// nothing.
+type Redactor struct {
+	Secrets []string
+	Prompts bool
+}
+
+// AddSecret registers a value that must never appear in output.  Blank and
+// very short values are ignored.
+func (r *Redactor) AddSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+	for _, s := range r.Secrets {
+		if s == value {
+			return
+		}
+	}
+	r.Secrets = append(r.Secrets, value)
+}
+
+// Text returns s with every registered secret replaced.
+func (r Redactor) Text(s string) string {
+	for _, secret := range r.Secrets {
+		s = strings.ReplaceAll(s, secret, redactedMarker)
+	}
+	return s
+}
+
+// Prompt returns p unchanged, or its redacted form when prompts are being
+// redacted.  Empty prompts stay empty.
+func (r Redactor) Prompt(p string) string {
+	if !r.Prompts || p == "" || IsRedactedPrompt(p) {
+		return p
+	}
+	sum := sha256.Sum256([]byte(p))
+	return redactedPromptPrefix + hex.EncodeToString(sum[:])[:12] + "]"
+}
+
+// IsRedactedPrompt reports whether p was produced by Redactor.Prompt.  Such
+// prompts cannot be resubmitted.
+func IsRedactedPrompt(p string) bool {
+	return strings.HasPrefix(p, redactedPromptPrefix)
+}
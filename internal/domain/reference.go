@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MinIDPrefixLength is the shortest generation ID prefix accepted in place of
+// a full ID.  Shorter prefixes match too many generations to be useful.
+const MinIDPrefixLength = 4
+
+// AmbiguousReferenceError is returned when a reference typed by the user
+// matches more than one generation.
+type AmbiguousReferenceError struct {
+	Ref     string
+	Matches []string
+}
+
+// Error implements the error interface.
+func (e *AmbiguousReferenceError) Error() string {
+	return fmt.Sprintf("%q matches %d generations: %s", e.Ref, len(e.Matches), strings.Join(e.Matches, ", "))
+}
+
+// IsFullGenerationID reports whether s has the shape of a complete generation
+// ID (a UUID such as 3fa2c1d0-5e4b-4f5a-9c1e-0123456789ab).
+func IsFullGenerationID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MatchIDPrefix looks for the single ID in ids starting with prefix.  It
+// reports whether a match was found, and returns an AmbiguousReferenceError
+// when several IDs share the prefix.  Prefixes shorter than
+// MinIDPrefixLength never match.
+func MatchIDPrefix(prefix string, ids []string) (string, bool, error) {
+	if len(prefix) < MinIDPrefixLength {
+		return "", false, nil
+	}
+	lower := strings.ToLower(prefix)
+	var matches []string
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if strings.HasPrefix(strings.ToLower(id), lower) && !seen[id] {
+			seen[id] = true
+			matches = append(matches, id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return matches[0], true, nil
+	default:
+		return "", false, &AmbiguousReferenceError{Ref: prefix, Matches: matches}
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RemoteCleanupFilter selects generations in the user's API history for
+// deletion.  Statuses lists the statuses to match, ignoring case.  A
+// positive OlderThan only matches generations created longer ago than that,
+// which tells generations stuck in progress from ones still running.
+type RemoteCleanupFilter struct {
+	Statuses  []string
+	OlderThan time.Duration
+}
+
+// Validate limits a filter to failed and stuck generations: FAILED, and
+// PENDING with OlderThan so generations still running are kept.  Any other
+// status, COMPLETE in particular, is rejected, since it would delete
+// generations that worked.
+func (f RemoteCleanupFilter) Validate() error {
+	if len(f.Statuses) == 0 {
+		return fmt.Errorf("at least one status is required")
+	}
+	for _, status := range f.Statuses {
+		switch status = strings.ToUpper(status); status {
+		case GenerationFailed:
+		case GenerationPending:
+			if f.OlderThan <= 0 {
+				return fmt.Errorf("matching %s generations needs an age limit, so generations still running are kept", status)
+			}
+		case GenerationComplete:
+			return fmt.Errorf("refusing to delete %s generations; only FAILED and, with an age limit, PENDING ones can be cleaned up", status)
+		default:
+			return fmt.Errorf("unsupported status %s; only FAILED and, with an age limit, PENDING generations can be cleaned up", status)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the filter selects item at time now.
+func (f RemoteCleanupFilter) Matches(item GenerationListItem, now time.Time) bool {
+	if f.OlderThan > 0 && (item.CreatedAt.IsZero() || now.Sub(item.CreatedAt) < f.OlderThan) {
+		return false
+	}
+	for _, status := range f.Statuses {
+		if strings.EqualFold(item.Status, status) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localFileExtensions lists the extensions of files the CLI writes for a
+// generation: downloaded images and JSON sidecars.
+var localFileExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".webp": true,
+	".json": true,
+}
+
+// LocalFile is an image or sidecar on disk belonging to a generation.
+type LocalFile struct {
+	Path         string
+	GenerationID string
+	ModTime      time.Time
+	Size         int64
+}
+
+// GenerationIDFromFilename returns the generation a file written by the CLI
+// belongs to.  Images are named {id}_{n}, variations {id}_{n}_{suffix} and
+// sidecars {id}, all followed by a known extension; any other name is not
+// recognised, so unrelated files are never mistaken for generation output.
+func GenerationIDFromFilename(name string) (string, bool) {
+	base := filepath.Base(name)
+	ext := filepath.Ext(base)
+	if !localFileExtensions[strings.ToLower(ext)] {
+		return "", false
+	}
+	stem := strings.TrimSuffix(base, ext)
+	id, rest, hasIndex := strings.Cut(stem, "_")
+	index, suffix, hasSuffix := strings.Cut(rest, "_")
+	if hasIndex && (index == "" || strings.Trim(index, "0123456789") != "") {
+		return "", false
+	}
+	if hasSuffix && (suffix == "" || strings.Trim(suffix, "abcdefghijklmnopqrstuvwxyz0123456789_") != "") {
+		return "", false
+	}
+	if !IsFullGenerationID(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// RetentionPolicy decides which local files are old enough to clean up.
+type RetentionPolicy struct {
+	KeepDays      int
+	KeepFavorites bool
+}
+
+// Expired reports whether f is older than the policy allows at now.  Files
+// of favorite generations never expire while KeepFavorites is set.
+func (p RetentionPolicy) Expired(f LocalFile, favorites map[string]bool, now time.Time) bool {
+	if p.KeepFavorites && favorites[f.GenerationID] {
+		return false
+	}
+	return f.ModTime.Before(now.AddDate(0, 0, -p.KeepDays))
+}
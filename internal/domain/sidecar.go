@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Sidecar is the metadata JSON file written next to a generation by
+// create, read back for tools that port a generation's settings elsewhere.
+// Fields the sidecar leaves out are zero.
+type Sidecar struct {
+	GenerationID   string  `json:"generation_id"`
+	Name           string  `json:"name"`
+	Timestamp      string  `json:"timestamp"`
+	Prompt         string  `json:"prompt"`
+	NegativePrompt string  `json:"negative_prompt"`
+	ModelID        string  `json:"model_id"`
+	StyleUUID      string  `json:"style_uuid"`
+	StyleName      string  `json:"style_name"`
+	Seed           int     `json:"seed"`
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	NumImages      int     `json:"num_images"`
+	GuidanceScale  float64 `json:"guidance_scale"`
+	Alchemy        bool    `json:"alchemy"`
+	InitImageID    string  `json:"init_image_id"`
+	InitStrength   float64 `json:"init_strength"`
+}
+
+// ParseSidecar decodes a sidecar metadata file.
+func ParseSidecar(data []byte) (Sidecar, error) {
+	var s Sidecar
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Sidecar{}, fmt.Errorf("parsing sidecar metadata: %w", err)
+	}
+	if s.Prompt == "" && s.GenerationID == "" {
+		return Sidecar{}, fmt.Errorf("not a sidecar metadata file: no prompt or generation_id")
+	}
+	return s, nil
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultStuckAfter is how long a generation may stay PENDING before it is
+// reported as stuck.  Most finish within a couple of minutes.
+const DefaultStuckAfter = 30 * time.Minute
+
+// StuckFor returns how long a generation with status, submitted at
+// submitted, has been pending at now, and whether that is longer than
+// after.  Generations that are not pending, or whose submission time is
+// unknown, are never stuck.
+func StuckFor(status string, submitted, now time.Time, after time.Duration) (time.Duration, bool) {
+	if !strings.EqualFold(status, GenerationPending) || submitted.IsZero() {
+		return 0, false
+	}
+	pending := now.Sub(submitted)
+	return pending, pending > after
+}
+
+// ResubmitRequest rebuilds the request that created the generation from
+// its record, so a stuck generation can be submitted again.  The record
+// does not keep the number of images asked for while none exist, so a
+// pending generation is resubmitted with the API's default count.
+func (d GenerationDetail) ResubmitRequest() GenerationRequest {
+	return GenerationRequest{
+		NumImages: len(d.Images),
+		Metadata: GenerationMetadata{
+			Prompt:         d.Prompt,
+			NegativePrompt: d.NegativePrompt,
+			ModelID:        d.ModelID,
+			Seed:           d.Seed,
+			Width:          d.Width,
+			Height:         d.Height,
+			GuidanceScale:  d.GuidanceScale,
+			Alchemy:        d.Alchemy,
+			Ultra:          d.Ultra,
+			PhotoReal:      d.PhotoReal,
+			InitImageID:    d.InitImageID,
+			InitStrength:   d.InitStrength,
+			Elements:       append([]GenerationElement(nil), d.Elements...),
+		},
+	}
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StyleConfigPrefix starts the configuration keys that add styles to the
+// catalog, e.g. "style.brand-noir: <uuid>".
+const StyleConfigPrefix = "style."
+
+// Style is a preset style that can be applied to a generation through its
+// style UUID.
+type Style struct {
+	Name string
+	UUID string
+}
+
+// Slug returns the style's name in the form accepted on the command line,
+// e.g. "pro-bw-photography" for "Pro B&W Photography".
+func (s Style) Slug() string {
+	return styleSlug(s.Name)
+}
+
+// styleCatalog lists the style UUIDs documented by Leonardo.  The API has no
+// endpoint to enumerate them, so they are embedded here.
+var styleCatalog = []Style{
+	{Name: "3D Render", UUID: "debdf72a-91a4-467b-bf61-cc02bdeb69c6"},
+	{Name: "Bokeh", UUID: "9fdc5e8c-4d13-49b4-9ce6-5a74cbb19177"},
+	{Name: "Cinematic", UUID: "a5632c7c-ddbb-4e2f-ba34-8456ab3ac436"},
+	{Name: "Cinematic Concept", UUID: "33abbb99-03b9-4dd7-9761-ee98650b2c88"},
+	{Name: "Creative", UUID: "6fedbf1f-4a17-45ec-84fb-92fe524a29ef"},
+	{Name: "Dynamic", UUID: "111dc692-d470-4eec-b791-3475abac4c46"},
+	{Name: "Fashion", UUID: "594c4a08-a522-4e0e-b7ff-e4dac4b6b622"},
+	{Name: "Graphic Design Pop Art", UUID: "2e74ec31-f3a4-4825-b08b-2894f6d13941"},
+	{Name: "Graphic Design Vector", UUID: "1fbb6a68-9319-44d2-8d56-2957ca0ece6a"},
+	{Name: "HDR", UUID: "97c20e5c-1af6-4d42-b227-54d03d8f0727"},
+	{Name: "Illustration", UUID: "645e4195-f63d-4715-a3f2-3fb1e6eb8c70"},
+	{Name: "Macro", UUID: "30c1d34f-e3a9-479a-b56f-c018bbc9c02a"},
+	{Name: "Minimalist", UUID: "cadc8cd6-7838-4c99-b645-df76be8ba8d8"},
+	{Name: "Moody", UUID: "621e1c9a-6319-4bee-a12d-ae40659162fa"},
+	{Name: "None", UUID: "556c1ee5-ec38-42e8-955a-1e82dad0ffa1"},
+	{Name: "Portrait", UUID: "8e2bc543-6ee2-45f9-bcd9-594b6ce84dcd"},
+	{Name: "Portrait Fashion", UUID: "0d34f8e1-46d4-428f-8ddd-4b11811fa7c9"},
+	{Name: "Pro B&W Photography", UUID: "22a9a7d2-2166-4d86-80ff-22e2643adbcf"},
+	{Name: "Pro Color Photography", UUID: "7c3f932b-a572-47cb-9b9b-f20211e63b5b"},
+	{Name: "Pro Film Photography", UUID: "581ba6d6-5aac-4492-bebe-54c424a0d46e"},
+	{Name: "Ray Traced", UUID: "b504f83c-3326-4947-82e1-7fe9e839ec0f"},
+	{Name: "Sketch B&W", UUID: "be8c6b58-739c-4d44-b9c1-b032ed308b61"},
+	{Name: "Sketch Color", UUID: "20d05f7b-0e57-4bb2-ad65-47b1e9c0c6fd"},
+	{Name: "Stock Photo", UUID: "5bdc3f2a-1be6-4d1c-8e77-992a30824a2c"},
+	{Name: "Vibrant", UUID: "dee282d3-891f-4f73-ba02-7f8131e5541b"},
+}
+
+// StyleModels maps the IDs of the platform models documented to accept
+// style UUIDs to their names.  Other models may ignore or reject them.
+var StyleModels = map[string]string{
+	"de7d3faf-762f-48e0-b3b7-9d0ac3a3fcf3": "Leonardo Phoenix 1.0",
+	"6b645e3a-d64f-4341-a6d8-7a3690fbf042": "Leonardo Phoenix 0.9",
+	"b2614463-296c-462a-9586-aafdb8f00e36": "Flux Dev",
+	"1dd50843-d653-4516-a8e3-f0238ee453ff": "Flux Schnell",
+}
+
+// Styles returns the style catalog sorted by name.
+func Styles() []Style {
+	styles := append([]Style(nil), styleCatalog...)
+	sort.Slice(styles, func(i, j int) bool { return styles[i].Name < styles[j].Name })
+	return styles
+}
+
+// CatalogStyles returns the style catalog extended with custom styles,
+// which map names to UUIDs, sorted by name.  A custom style whose name
+// matches a built-in one replaces it.
+func CatalogStyles(custom map[string]string) ([]Style, error) {
+	styles := append([]Style(nil), styleCatalog...)
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		uuid := strings.TrimSpace(custom[name])
+		if styleSlug(name) == "" {
+			return nil, fmt.Errorf("style %q: name has no letters or digits", name)
+		}
+		if !IsFullGenerationID(uuid) {
+			return nil, fmt.Errorf("style %s: %q is not a style UUID", name, uuid)
+		}
+		style := Style{Name: name, UUID: uuid}
+		replaced := false
+		for i, s := range styles {
+			if s.Slug() == style.Slug() {
+				styles[i], replaced = style, true
+			}
+		}
+		if !replaced {
+			styles = append(styles, style)
+		}
+	}
+	sort.Slice(styles, func(i, j int) bool { return styles[i].Name < styles[j].Name })
+	return styles, nil
+}
+
+// StyleName returns the name of the style with the given UUID in styles, or
+// an empty string when it is not listed.
+func StyleName(styles []Style, uuid string) string {
+	for _, s := range styles {
+		if strings.EqualFold(s.UUID, uuid) {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// UnknownStyleError is returned when a style name matches no catalog entry.
+type UnknownStyleError struct {
+	Name string
+}
+
+// Error implements the error interface.
+func (e *UnknownStyleError) Error() string {
+	return fmt.Sprintf("unknown style %q (run the styles command to list them)", e.Name)
+}
+
+// ResolveStyle returns the style UUID for a style given by name, slug or
+// UUID.  Names are compared case-insensitively, ignoring spaces, dashes and
+// underscores, so "Pro B&W Photography" and "pro-bw-photography" match.  A
+// full UUID not in the catalog is passed through unchanged, since new styles
+// may be published before the catalog is updated.
+func ResolveStyle(name string) (string, error) {
+	return ResolveStyleIn(styleCatalog, name)
+}
+
+// ResolveStyleIn resolves a style as ResolveStyle does, looking it up in
+// styles, such as a catalog returned by CatalogStyles.
+func ResolveStyleIn(styles []Style, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	slug := styleSlug(name)
+	for _, s := range styles {
+		if s.Slug() == slug || strings.EqualFold(s.UUID, name) {
+			return s.UUID, nil
+		}
+	}
+	if IsFullGenerationID(name) {
+		return name, nil
+	}
+	return "", &UnknownStyleError{Name: name}
+}
+
+// styleSlug folds a style name into lower-case words joined by dashes,
+// dropping punctuation such as "&".
+func styleSlug(name string) string {
+	var words []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}) {
+		word := strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return -1
+		}, field)
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MaxSweepSteps caps the generations one sweep submits, since each one
+// costs tokens.
+const MaxSweepSteps = 10
+
+// WeightSweep varies the weight of one Element over evenly spaced steps
+// from From to To, inclusive, keeping every other setting fixed, to show
+// the Element's influence at each strength.
+type WeightSweep struct {
+	ElementID string
+	From      float64
+	To        float64
+	Steps     int
+}
+
+// Validate checks that the sweep names an Element and has between two and
+// MaxSweepSteps distinct steps.
+func (s WeightSweep) Validate() error {
+	if s.ElementID == "" {
+		return &InvalidRequestError{Reason: "a sweep needs an Element ID"}
+	}
+	if s.Steps < 2 || s.Steps > MaxSweepSteps {
+		return &InvalidRequestError{Reason: fmt.Sprintf("a sweep needs between 2 and %d steps, not %d", MaxSweepSteps, s.Steps)}
+	}
+	if s.From == s.To {
+		return &InvalidRequestError{Reason: "a sweep needs different start and end weights"}
+	}
+	return nil
+}
+
+// Weights returns the weight of each step, rounded to three decimals so
+// labels stay short.
+func (s WeightSweep) Weights() []float64 {
+	weights := make([]float64, s.Steps)
+	for i := range weights {
+		w := s.From + (s.To-s.From)*float64(i)/float64(s.Steps-1)
+		weights[i] = math.Round(w*1000) / 1000
+	}
+	return weights
+}
+
+// SweepLabel returns the label of a step's weight, e.g. "0.25".
+func SweepLabel(weight float64) string {
+	return strconv.FormatFloat(weight, 'f', -1, 64)
+}
+
+// Requests returns one request per step: base with the swept Element at
+// that step's weight, replacing any weight base gives it.  Generations are
+// tagged "weight:LABEL" and, when base is named, named after it with the
+// label appended so the series is easy to find in the library.
+func (s WeightSweep) Requests(base GenerationRequest) []GenerationRequest {
+	weights := s.Weights()
+	requests := make([]GenerationRequest, len(weights))
+	for i, w := range weights {
+		req := base
+		meta := base.Metadata
+		label := SweepLabel(w)
+		elements := make([]GenerationElement, 0, len(meta.Elements)+1)
+		for _, e := range meta.Elements {
+			if e.ID != s.ElementID {
+				elements = append(elements, e)
+			}
+		}
+		meta.Elements = append(elements, GenerationElement{ID: s.ElementID, Weight: w})
+		meta.Tags = append(append([]string(nil), meta.Tags...), "weight:"+label)
+		if meta.Name != "" {
+			meta.Name += "-w" + label
+		}
+		req.Metadata = meta
+		requests[i] = req
+	}
+	return requests
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "fmt"
+
+// ImageURL is where one file of a generation can be fetched from: an image,
+// numbered from 1, or one of its variations.  The URLs are public CDN links;
+// the API does not sign them or make them expire.
+type ImageURL struct {
+	Image     int
+	Variation string
+	URL       string
+}
+
+// ImageURLs returns the URLs of the images of a completed generation in
+// order, each followed by its finished variations when includeVariations is
+// set.
+func (d GenerationDetail) ImageURLs(includeVariations bool) ([]ImageURL, error) {
+	if d.Status != GenerationComplete {
+		return nil, fmt.Errorf("generation %s is not complete, current status: %s", d.ID, d.Status)
+	}
+	if len(d.Images) == 0 {
+		return nil, fmt.Errorf("no images available for generation %s", d.ID)
+	}
+	var urls []ImageURL
+	for i, img := range d.Images {
+		urls = append(urls, ImageURL{Image: i + 1, URL: img.URL})
+		if !includeVariations {
+			continue
+		}
+		for _, v := range img.Variations {
+			if v.Status == GenerationComplete && v.URL != "" {
+				urls = append(urls, ImageURL{Image: i + 1, Variation: v.FileSuffix(), URL: v.URL})
+			}
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UsageDay is the token consumption of one day: the completed generations
+// created that day, their images and the tokens they cost.  Estimated
+// counts the generations whose cost was priced with the pricing calculator
+// from their settings because it was not recorded when they were created.
+type UsageDay struct {
+	Date        string
+	Generations int
+	Images      int
+	Tokens      int
+	Estimated   int
+}
+
+// UsageReport is the token consumption per day, oldest first.  Unpriced
+// counts the generations whose cost could not be found or estimated; their
+// tokens are missing from the totals.
+type UsageReport struct {
+	Days     []UsageDay
+	Unpriced int
+}
+
+// Add counts a generation created on date with images images that cost
+// tokens.  estimated marks a cost priced rather than recorded.
+func (r *UsageReport) Add(date string, images, tokens int, estimated bool) {
+	i := sort.Search(len(r.Days), func(i int) bool { return r.Days[i].Date >= date })
+	if i == len(r.Days) || r.Days[i].Date != date {
+		r.Days = append(r.Days, UsageDay{})
+		copy(r.Days[i+1:], r.Days[i:])
+		r.Days[i] = UsageDay{Date: date}
+	}
+	day := &r.Days[i]
+	day.Generations++
+	day.Images += images
+	day.Tokens += tokens
+	if estimated {
+		day.Estimated++
+	}
+}
+
+// Total returns the sum of every day, with Date set to "total".
+func (r UsageReport) Total() UsageDay {
+	total := UsageDay{Date: "total"}
+	for _, d := range r.Days {
+		total.Generations += d.Generations
+		total.Images += d.Images
+		total.Tokens += d.Tokens
+		total.Estimated += d.Estimated
+	}
+	return total
+}
+
+// ProjectTagPrefix starts the tag attributing a generation to a project,
+// e.g. project:acme.  Setting "tags: project:acme" in a project's
+// .leonardo.yaml tags every generation created there.
+const ProjectTagPrefix = "project:"
+
+// Budget groupings: by every tag of a generation, or by its project tag.
+const (
+	BudgetByTag     = "tag"
+	BudgetByProject = "project"
+)
+
+// BudgetUnassigned is the group of generations without a tag, or without a
+// project tag when grouping by project.
+const BudgetUnassigned = "(none)"
+
+// BudgetLine is the token spend of one group in one month.  Uncosted counts
+// the generations whose cost was not recorded; their tokens are missing
+// from Tokens.
+type BudgetLine struct {
+	Month       string
+	Group       string
+	Generations int
+	Tokens      int
+	Uncosted    int
+}
+
+// Budget totals the recorded spend of entries per month in loc and per
+// group, sorted by month and then group.  Grouped by tag, a generation with
+// several tags counts towards each of them, so the groups of a month can add
+// up to more than was spent; grouped by project, it counts towards its
+// first project tag only.
+func Budget(entries []LibraryEntry, by string, loc *time.Location) ([]BudgetLine, error) {
+	if by != BudgetByTag && by != BudgetByProject {
+		return nil, fmt.Errorf("unknown budget grouping %q (want %s or %s)", by, BudgetByTag, BudgetByProject)
+	}
+	type key struct{ month, group string }
+	lines := map[key]*BudgetLine{}
+	for _, e := range entries {
+		month := e.CreatedAt.In(loc).Format("2006-01")
+		for _, group := range budgetGroups(e, by) {
+			k := key{month, group}
+			line, ok := lines[k]
+			if !ok {
+				line = &BudgetLine{Month: month, Group: group}
+				lines[k] = line
+			}
+			line.Generations++
+			if e.Cost > 0 {
+				line.Tokens += e.Cost
+			} else {
+				line.Uncosted++
+			}
+		}
+	}
+	sorted := make([]BudgetLine, 0, len(lines))
+	for _, line := range lines {
+		sorted = append(sorted, *line)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Month != sorted[j].Month {
+			return sorted[i].Month < sorted[j].Month
+		}
+		return sorted[i].Group < sorted[j].Group
+	})
+	return sorted, nil
+}
+
+// budgetGroups returns the groups an entry's spend is attributed to.
+func budgetGroups(e LibraryEntry, by string) []string {
+	var groups []string
+	for _, tag := range e.Tags {
+		if by == BudgetByTag {
+			groups = append(groups, tag)
+		} else if name := strings.TrimPrefix(tag, ProjectTagPrefix); name != tag && name != "" {
+			return []string{name}
+		}
+	}
+	if len(groups) == 0 {
+		return []string{BudgetUnassigned}
+	}
+	return groups
+}
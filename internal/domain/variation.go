@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// VariationKind is a type of processed copy that can be requested for a
+// generated image.
+type VariationKind string
+
+// Variation kinds supported by the API's /variations endpoints.
+const (
+	VariationUpscale      VariationKind = "upscale"
+	VariationNoBackground VariationKind = "nobg"
+	VariationUnzoom       VariationKind = "unzoom"
+)
+
+// variationKinds lists the supported kinds in the order they are shown.
+var variationKinds = []VariationKind{VariationUpscale, VariationNoBackground, VariationUnzoom}
+
+// FileSuffix returns the suffix of the file a variation of this kind is
+// saved as, matching ImageVariation.FileSuffix, e.g. "upscaled".
+func (k VariationKind) FileSuffix() string {
+	return ImageVariation{TransformType: strings.ToUpper(string(k))}.FileSuffix()
+}
+
+// HasVariationKind reports whether kinds includes kind.
+func HasVariationKind(kinds []VariationKind, kind VariationKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds of the multiplier the universal upscaler accepts.
+const (
+	MinUpscaleMultiplier = 1.0
+	MaxUpscaleMultiplier = 2.0
+)
+
+// UpscaleTarget is a size to upscale an image to.  A zero dimension follows
+// from the other one and the image's aspect ratio.
+type UpscaleTarget struct {
+	Width  int
+	Height int
+}
+
+// IsZero reports whether no target size is set.
+func (t UpscaleTarget) IsZero() bool {
+	return t.Width <= 0 && t.Height <= 0
+}
+
+// Multiplier returns the upscale multiplier that takes a width x height
+// image to the target without changing its aspect ratio, rounded up to two
+// decimals so the result is never smaller than asked.  When both dimensions
+// are set they must have the image's aspect ratio, give or take a pixel.
+// Targets smaller than the image or beyond MaxUpscaleMultiplier are errors.
+func (t UpscaleTarget) Multiplier(width, height int) (float64, error) {
+	if width <= 0 || height <= 0 {
+		return 0, fmt.Errorf("the size of the image is unknown")
+	}
+	var m float64
+	switch {
+	case t.Width > 0:
+		m = float64(t.Width) / float64(width)
+		if t.Height > 0 && math.Abs(float64(height)*m-float64(t.Height)) > 1 {
+			return 0, fmt.Errorf("target %dx%d would change the %dx%d image's aspect ratio; give only the target width or height", t.Width, t.Height, width, height)
+		}
+	case t.Height > 0:
+		m = float64(t.Height) / float64(height)
+	default:
+		return 0, fmt.Errorf("no target size given")
+	}
+	m = math.Ceil(m*100-1e-9) / 100
+	if m < MinUpscaleMultiplier {
+		return 0, fmt.Errorf("target is smaller than the %dx%d image", width, height)
+	}
+	if m > MaxUpscaleMultiplier {
+		return 0, fmt.Errorf("the %dx%d image needs a %.2fx upscale to reach the target; the API allows at most %.0fx (%dx%d)",
+			width, height, m, MaxUpscaleMultiplier, width*int(MaxUpscaleMultiplier), height*int(MaxUpscaleMultiplier))
+	}
+	return m, nil
+}
+
+// ParseVariationKinds parses a comma-separated list of variation kinds such
+// as "upscale,nobg".  Kinds are matched regardless of case and repeated
+// kinds are requested once.
+func ParseVariationKinds(list string) ([]VariationKind, error) {
+	var kinds []VariationKind
+	seen := map[VariationKind]bool{}
+	for _, part := range strings.Split(list, ",") {
+		kind := VariationKind(strings.ToLower(strings.TrimSpace(part)))
+		if kind == "" || seen[kind] {
+			continue
+		}
+		if !kind.valid() {
+			names := make([]string, len(variationKinds))
+			for i, k := range variationKinds {
+				names[i] = string(k)
+			}
+			return nil, fmt.Errorf("unknown variation type %q (supported: %s)", part, strings.Join(names, ", "))
+		}
+		seen[kind] = true
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("no variation types given")
+	}
+	return kinds, nil
+}
+
+func (k VariationKind) valid() bool {
+	for _, known := range variationKinds {
+		if k == known {
+			return true
+		}
+	}
+	return false
+}
+
+// VariationOutcome is the result of one variation job of a fan-out: the
+// kind requested for an image, the job started for it and where the result
+// was saved, or the error that stopped it.
+type VariationOutcome struct {
+	Image   int
+	ImageID string
+	Kind    VariationKind
+	JobID   string
+	Path    string
+	Err     error
+}
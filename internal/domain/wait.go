@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// Generation statuses that end a wait.
+const (
+	GenerationComplete = "COMPLETE"
+	GenerationFailed   = "FAILED"
+)
+
+// GenerationPending is the status of a generation still being worked on.
+const GenerationPending = "PENDING"
+
+// Finished reports whether the generation has stopped running, successfully
+// or not.
+func (s GenerationStatus) Finished() bool {
+	return s.Status == GenerationComplete || s.Status == GenerationFailed
+}
+
+// Backoff settings shared by every wait for a generation.
+const (
+	// waitBackoffFactor is how much longer each delay is than the last.
+	waitBackoffFactor = 1.5
+	// waitJitter is the fraction by which each delay is randomly stretched
+	// or shortened, so many waiting clients do not poll in lockstep.
+	waitJitter = 0.2
+	// waitMaxAdvised caps a server-advised delay.
+	waitMaxAdvised = 5 * time.Minute
+	// WaitMaxErrors is how many status checks in a row may fail with a
+	// retryable error before a wait gives up.
+	WaitMaxErrors = 3
+)
+
+// WaitOptions configures a wait for a generation to finish.  Checks start
+// Interval apart and back off towards MaxInterval, which defaults to four
+// times Interval.  A positive Timeout bounds the wait.  OnPoll, when set,
+// is called with every status seen and its check number.
+type WaitOptions struct {
+	Interval    time.Duration
+	MaxInterval time.Duration
+	Timeout     time.Duration
+	OnPoll      func(status GenerationStatus, attempt int)
+}
+
+// NextInterval returns the delay to back off to after previous, before
+// jitter.  A zero previous delay starts at Interval.
+func (o WaitOptions) NextInterval(previous time.Duration) time.Duration {
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	max := o.MaxInterval
+	if max < o.Interval {
+		max = 4 * o.Interval
+	}
+	if previous <= 0 {
+		return o.Interval
+	}
+	next := time.Duration(float64(previous) * waitBackoffFactor)
+	if next > max {
+		next = max
+	}
+	return next
+}
+
+// Delay returns how long to wait before the next check: interval stretched
+// or shortened by up to waitJitter according to r, a random number in
+// [0, 1), or the server-advised delay when that is longer.
+func (o WaitOptions) Delay(interval, advised time.Duration, r float64) time.Duration {
+	delay := time.Duration(float64(interval) * (1 + waitJitter*(2*r-1)))
+	if advised > waitMaxAdvised {
+		advised = waitMaxAdvised
+	}
+	if advised > delay {
+		return advised
+	}
+	return delay
+}
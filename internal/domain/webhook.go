@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// webhookSignaturePrefix may precede the hex digest in a signature header.
+const webhookSignaturePrefix = "sha256="
+
+// ErrWebhookSignatureMismatch reports a webhook payload whose signature was
+// not produced with the expected secret, or whose body was altered.
+var ErrWebhookSignatureMismatch = errors.New("webhook signature does not match the payload")
+
+// WebhookSignature returns the signature of a webhook payload: the hex
+// encoded HMAC-SHA256 of the raw request body keyed with the secret.
+func WebhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a signature received with a webhook
+// payload.  The signature may carry a "sha256=" prefix and is compared in
+// constant time.
+func VerifyWebhookSignature(secret string, payload []byte, signature string) error {
+	if secret == "" {
+		return errors.New("webhook secret is empty")
+	}
+	signature = strings.TrimSpace(signature)
+	if len(signature) > len(webhookSignaturePrefix) && strings.EqualFold(signature[:len(webhookSignaturePrefix)], webhookSignaturePrefix) {
+		signature = signature[len(webhookSignaturePrefix):]
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrWebhookSignatureMismatch
+	}
+	want, _ := hex.DecodeString(WebhookSignature(secret, payload))
+	if !hmac.Equal(got, want) {
+		return ErrWebhookSignatureMismatch
+	}
+	return nil
+}
+
+// WebhookEventComplete is the type of the delivery announcing a finished
+// generation.
+const WebhookEventComplete = "image_generation.complete"
+
+// WebhookEvent is the body of a webhook delivery, shaped like those the API
+// sends to a callback URL.
+type WebhookEvent struct {
+	Type       string           `json:"type"`
+	Object     string           `json:"object"`
+	Timestamp  int64            `json:"timestamp"`
+	APIVersion string           `json:"api_version"`
+	Data       WebhookEventData `json:"data"`
+}
+
+// WebhookEventData wraps the generation a delivery is about.
+type WebhookEventData struct {
+	Object WebhookGeneration `json:"object"`
+}
+
+// WebhookGeneration is a generation as described in a webhook delivery.
+type WebhookGeneration struct {
+	ID             string         `json:"id"`
+	CreatedAt      string         `json:"createdAt,omitempty"`
+	Status         string         `json:"status"`
+	Prompt         string         `json:"prompt"`
+	NegativePrompt string         `json:"negativePrompt,omitempty"`
+	ModelID        string         `json:"modelId,omitempty"`
+	ImageWidth     int            `json:"imageWidth"`
+	ImageHeight    int            `json:"imageHeight"`
+	InferenceSteps int            `json:"inferenceSteps,omitempty"`
+	Seed           int            `json:"seed,omitempty"`
+	GuidanceScale  float64        `json:"guidanceScale,omitempty"`
+	InitStrength   float64        `json:"initStrength,omitempty"`
+	Scheduler      string         `json:"scheduler,omitempty"`
+	SDVersion      string         `json:"sdVersion,omitempty"`
+	PresetStyle    string         `json:"presetStyle,omitempty"`
+	Public         bool           `json:"public"`
+	PhotoReal      bool           `json:"photoReal"`
+	Alchemy        bool           `json:"alchemy"`
+	Images         []WebhookImage `json:"images"`
+}
+
+// WebhookImage is an image of a generation in a webhook delivery.
+type WebhookImage struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	NSFW         bool   `json:"nsfw"`
+	LikeCount    int    `json:"likeCount"`
+	GenerationID string `json:"generationId"`
+}
+
+// SimulatedWebhookEvent fabricates the delivery the API would send when
+// generation d completed, stamped with now, for testing receivers without
+// waiting for a real generation.  Only complete generations have one.
+func SimulatedWebhookEvent(d GenerationDetail, now time.Time) (WebhookEvent, error) {
+	if !strings.EqualFold(d.Status, "COMPLETE") {
+		return WebhookEvent{}, fmt.Errorf("generation %s is %s; only complete generations can be simulated", d.ID, d.Status)
+	}
+	g := WebhookGeneration{
+		ID:             d.ID,
+		Status:         d.Status,
+		Prompt:         d.Prompt,
+		NegativePrompt: d.NegativePrompt,
+		ModelID:        d.ModelID,
+		ImageWidth:     d.Width,
+		ImageHeight:    d.Height,
+		InferenceSteps: d.InferenceSteps,
+		Seed:           d.Seed,
+		GuidanceScale:  d.GuidanceScale,
+		InitStrength:   d.InitStrength,
+		Scheduler:      d.Scheduler,
+		SDVersion:      d.SDVersion,
+		PresetStyle:    d.PresetStyle,
+		Public:         d.Public,
+		PhotoReal:      d.PhotoReal,
+		Alchemy:        d.Alchemy,
+		Images:         []WebhookImage{},
+	}
+	if !d.CreatedAt.IsZero() {
+		g.CreatedAt = d.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	for _, img := range d.Images {
+		g.Images = append(g.Images, WebhookImage{ID: img.ID, URL: img.URL, NSFW: img.NSFW, LikeCount: img.LikeCount, GenerationID: d.ID})
+	}
+	return WebhookEvent{
+		Type:       WebhookEventComplete,
+		Object:     "generation",
+		Timestamp:  now.Unix(),
+		APIVersion: "v1",
+		Data:       WebhookEventData{Object: g},
+	}, nil
+}
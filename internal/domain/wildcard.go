@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// wildcardPattern matches a wildcard such as __artists__ or
+// __styles/lighting__ in a prompt.
+var wildcardPattern = regexp.MustCompile(`__([A-Za-z0-9][A-Za-z0-9_\-/]*?)__`)
+
+// maxWildcardDepth bounds how deeply wildcards may expand to lines that
+// contain further wildcards, so a wordlist that refers to itself fails
+// instead of looping forever.
+const maxWildcardDepth = 10
+
+// UnknownWildcardError reports a wildcard without a wordlist, or with an
+// empty one.
+type UnknownWildcardError struct {
+	Name string
+}
+
+// Error implements the error interface.
+func (e *UnknownWildcardError) Error() string {
+	return fmt.Sprintf("no wordlist for wildcard __%s__ (expected a non-empty %s.txt)", e.Name, e.Name)
+}
+
+// HasWildcards reports whether prompt contains a wildcard.
+func HasWildcards(prompt string) bool {
+	return wildcardPattern.MatchString(prompt)
+}
+
+// ExpandWildcards replaces every wildcard in prompt with a line picked from
+// its wordlist.  lines returns the wordlist of a wildcard name and pick
+// chooses an index below n.  Picked lines may contain wildcards of their
+// own, which are expanded in turn.
+func ExpandWildcards(prompt string, lines func(name string) ([]string, error), pick func(n int) int) (string, error) {
+	for depth := 0; HasWildcards(prompt); depth++ {
+		if depth == maxWildcardDepth {
+			return "", fmt.Errorf("wildcards nested more than %d deep; does a wordlist refer to itself?", maxWildcardDepth)
+		}
+		var err error
+		prompt = wildcardPattern.ReplaceAllStringFunc(prompt, func(match string) string {
+			if err != nil {
+				return match
+			}
+			name := wildcardPattern.FindStringSubmatch(match)[1]
+			var words []string
+			if words, err = lines(name); err != nil {
+				return match
+			}
+			if len(words) == 0 {
+				err = &UnknownWildcardError{Name: name}
+				return match
+			}
+			return words[pick(len(words))]
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return prompt, nil
+}
@@ -0,0 +1,162 @@
+// Package i18n translates the CLI's user-facing messages.  Messages are
+// keyed by their English text, in the fmt format the CLI passes, so English
+// needs no catalog and a message missing from a catalog is shown in English.
+// JSON output, flag names and other machine-read text are never translated.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SourceLocale is the language messages are written in.
+const SourceLocale = "en"
+
+// LocaleEnv names the variable that chooses the language ahead of the
+// usual locale variables.
+const LocaleEnv = "LEONARDO_LANG"
+
+// Catalog maps English messages to their translation.
+type Catalog map[string]string
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+// Builtin returns the catalogs shipped with the CLI, keyed by locale.
+func Builtin() (map[string]Catalog, error) {
+	catalogs := map[string]Catalog{}
+	entries, err := fs.ReadDir(builtin, "locales")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := builtin.ReadFile("locales/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := addCatalog(catalogs, e.Name(), data); err != nil {
+			return nil, err
+		}
+	}
+	return catalogs, nil
+}
+
+// LoadDir adds the catalogs in dir, files named after their locale such as
+// pt-BR.json, to catalogs.  Their messages win over those already there, so
+// a file can fix a shipped translation or add a language.  A missing
+// directory adds nothing.
+func LoadDir(catalogs map[string]Catalog, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := addCatalog(catalogs, e.Name(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addCatalog merges the catalog in data, read from the file name, into
+// catalogs.
+func addCatalog(catalogs map[string]Catalog, name string, data []byte) error {
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return fmt.Errorf("parsing message catalog %s: %w", name, err)
+	}
+	locale := Normalize(strings.TrimSuffix(name, filepath.Ext(name)))
+	if catalogs[locale] == nil {
+		catalogs[locale] = Catalog{}
+	}
+	for key, value := range catalog {
+		catalogs[locale][key] = value
+	}
+	return nil
+}
+
+// Detect returns the locale the user asked for through LEONARDO_LANG,
+// LC_ALL, LC_MESSAGES or LANG, in that order, normalized; the source
+// locale when none is set.
+func Detect(getenv func(string) string) string {
+	for _, name := range []string{LocaleEnv, "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := strings.TrimSpace(getenv(name)); value != "" {
+			return Normalize(value)
+		}
+	}
+	return SourceLocale
+}
+
+// Normalize turns a POSIX locale such as pt_BR.UTF-8 into a language tag
+// such as pt-BR.  The C and POSIX locales are English.
+func Normalize(locale string) string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return SourceLocale
+	}
+	lang, region, ok := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	if !ok {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "-" + strings.ToUpper(region)
+}
+
+// Translator renders messages in one locale.
+type Translator struct {
+	locale  string
+	catalog Catalog
+}
+
+// New returns a translator for the catalog matching locale: the same tag,
+// or failing that the same language, so pt and pt-PT fall back to pt-BR
+// when it is the only Portuguese catalog.  With no match messages stay in
+// English.
+func New(locale string, catalogs map[string]Catalog) *Translator {
+	locale = Normalize(locale)
+	if catalog, ok := catalogs[locale]; ok {
+		return &Translator{locale: locale, catalog: catalog}
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	for tag, catalog := range catalogs {
+		if l, _, _ := strings.Cut(tag, "-"); l == lang {
+			return &Translator{locale: tag, catalog: catalog}
+		}
+	}
+	return &Translator{locale: SourceLocale}
+}
+
+// Locale returns the locale messages are rendered in.
+func (t *Translator) Locale() string {
+	return t.locale
+}
+
+// Sprintf formats the translation of format with args, or format itself
+// when it has no translation.  Without args the message is returned as it
+// is, so messages need not escape a literal %.
+func (t *Translator) Sprintf(format string, args ...interface{}) string {
+	if translated, ok := t.catalog[format]; ok && translated != "" {
+		format = translated
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
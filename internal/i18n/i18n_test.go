@@ -0,0 +1,113 @@
+package i18n_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"leonardo-cli/internal/i18n"
+)
+
+func TestNormalize_TurnsPOSIXLocalesIntoTags(t *testing.T) {
+	cases := map[string]string{
+		"pt_BR.UTF-8":     "pt-BR",
+		"pt-br":           "pt-BR",
+		"en_US.UTF-8@x":   "en-US",
+		"C":               "en",
+		"POSIX":           "en",
+		"de":              "de",
+		"C.UTF-8":         "en",
+		"fr_CA.ISO8859-1": "fr-CA",
+	}
+	for in, want := range cases {
+		if got := i18n.Normalize(in); got != want {
+			t.Errorf("%q: expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestDetect_PrefersLeonardoLangThenTheLocaleVariables(t *testing.T) {
+	env := map[string]string{"LANG": "de_DE.UTF-8", "LC_MESSAGES": "pt_BR.UTF-8"}
+	getenv := func(name string) string { return env[name] }
+
+	if got := i18n.Detect(getenv); got != "pt-BR" {
+		t.Errorf("expected LC_MESSAGES to win over LANG, got %q", got)
+	}
+	env[i18n.LocaleEnv] = "en"
+	if got := i18n.Detect(getenv); got != "en" {
+		t.Errorf("expected %s to win, got %q", i18n.LocaleEnv, got)
+	}
+	if got := i18n.Detect(func(string) string { return "" }); got != i18n.SourceLocale {
+		t.Errorf("expected English without a locale, got %q", got)
+	}
+}
+
+func TestNew_FallsBackToTheLanguageAndThenToEnglish(t *testing.T) {
+	catalogs := map[string]i18n.Catalog{"pt-BR": {"Name:": "Nome:"}}
+
+	if got := i18n.New("pt_PT.UTF-8", catalogs); got.Locale() != "pt-BR" || got.Sprintf("Name:") != "Nome:" {
+		t.Errorf("expected pt-PT to use the pt-BR catalog, got %s %q", got.Locale(), got.Sprintf("Name:"))
+	}
+	if got := i18n.New("fr_FR", catalogs); got.Locale() != i18n.SourceLocale || got.Sprintf("Name:") != "Name:" {
+		t.Errorf("expected English for a language without a catalog, got %s %q", got.Locale(), got.Sprintf("Name:"))
+	}
+}
+
+func TestSprintf_FormatsTheTranslationOrTheMessage(t *testing.T) {
+	tr := i18n.New("pt-BR", map[string]i18n.Catalog{"pt-BR": {"Unknown command: %s": "Comando desconhecido: %s"}})
+
+	if got := tr.Sprintf("Unknown command: %s", "x"); got != "Comando desconhecido: x" {
+		t.Errorf("expected the translation formatted, got %q", got)
+	}
+	if got := tr.Sprintf("Stopped at %d%%", 50); got != "Stopped at 50%" {
+		t.Errorf("expected an untranslated message formatted, got %q", got)
+	}
+	if got := tr.Sprintf("100% done"); got != "100% done" {
+		t.Errorf("expected a message without args left as it is, got %q", got)
+	}
+}
+
+func TestLoadDir_AddsAndOverridesCatalogs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pt-BR.json"), []byte(`{"Name:": "Nome do trabalho:"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "es.json"), []byte(`{"Name:": "Nombre:"}`), 0644)
+	catalogs, err := i18n.Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i18n.LoadDir(catalogs, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := i18n.New("pt-BR", catalogs).Sprintf("Name:"); got != "Nome do trabalho:" {
+		t.Errorf("expected the user catalog to win, got %q", got)
+	}
+	if got := i18n.New("es_ES", catalogs).Sprintf("Name:"); got != "Nombre:" {
+		t.Errorf("expected a new language added, got %q", got)
+	}
+	if err := i18n.LoadDir(catalogs, filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("expected a missing directory ignored, got %v", err)
+	}
+}
+
+func TestBuiltin_TranslationsKeepTheFormatVerbs(t *testing.T) {
+	catalogs, err := i18n.Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalogs["pt-BR"]) == 0 {
+		t.Fatal("expected a pt-BR catalog")
+	}
+	verbs := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+	for locale, catalog := range catalogs {
+		for key, value := range catalog {
+			got, want := verbs.FindAllString(value, -1), verbs.FindAllString(key, -1)
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("%s: %q has verbs %v, want %v", locale, value, got, want)
+			}
+		}
+	}
+}
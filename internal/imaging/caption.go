@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"unicode"
+)
+
+// glyphs is a 3×5 pixel font covering digits, capital letters and the
+// punctuation of labels, one row per string with '#' for a lit pixel.
+// Lower case letters are drawn as capitals; other characters as blanks.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	'-': {"...", "...", "###", "...", "..."},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'!': {".#.", ".#.", ".#.", "...", ".#."},
+	'/': {"..#", "..#", ".#.", "#..", "#.."},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+}
+
+// captionColor draws caption text.
+var captionColor = color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+// Caption returns img with a dark band below it showing text centered, in
+// a blocky font scaled to the image width.  Characters the font lacks
+// leave a gap, so captions are meant for short labels such as weights.
+func Caption(img image.Image, text string) *image.RGBA {
+	b := img.Bounds()
+	scale := b.Dx() / 80
+	if scale < 2 {
+		scale = 2
+	}
+	band := 7 * scale
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()+band))
+	draw.Draw(out, out.Bounds(), &image.Uniform{C: gapColor}, image.Point{}, draw.Src)
+	draw.Draw(out, image.Rect(0, 0, b.Dx(), b.Dy()), img, b.Min, draw.Src)
+	x := (b.Dx() - textWidth(text, scale)) / 2
+	drawText(out, text, image.Pt(x, b.Dy()+scale), scale, captionColor)
+	return out
+}
+
+// textWidth is the width in pixels of text drawn at scale.
+func textWidth(text string, scale int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (4*n - 1) * scale
+}
+
+// drawText draws text onto dst with its top left corner at at, each font
+// pixel a scale×scale square.
+func drawText(dst draw.Image, text string, at image.Point, scale int, c color.Color) {
+	x := at.X
+	for _, r := range text {
+		glyph := glyphs[unicode.ToUpper(r)]
+		for row, line := range glyph {
+			for col, pixel := range line {
+				if pixel == '#' {
+					cell := image.Rect(x+col*scale, at.Y+row*scale, x+(col+1)*scale, at.Y+(row+1)*scale)
+					draw.Draw(dst, cell, &image.Uniform{C: c}, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
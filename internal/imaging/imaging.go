@@ -0,0 +1,131 @@
+// Package imaging holds the local image processing used by commands that
+// work on downloaded images.  It relies only on the standard library, so
+// it decodes PNG and JPEG files.
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+
+	// Registers the JPEG decoder with image.Decode.
+	_ "image/jpeg"
+)
+
+// panelGap is the width in pixels of the separator between panels.
+const panelGap = 8
+
+// gapColor fills the separator and any area not covered by a panel.
+var gapColor = color.RGBA{R: 32, G: 32, B: 32, A: 255}
+
+// Load decodes the image stored at path.
+func Load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening image: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// SavePNG encodes img as a PNG file at path.
+func SavePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating image: %w", err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// SideBySide places panels next to each other, left to right and aligned at
+// the top, separated by a narrow dark gap.
+func SideBySide(panels ...image.Image) *image.RGBA {
+	width, height := 0, 0
+	for i, p := range panels {
+		b := p.Bounds()
+		if i > 0 {
+			width += panelGap
+		}
+		width += b.Dx()
+		if b.Dy() > height {
+			height = b.Dy()
+		}
+	}
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(out, out.Bounds(), &image.Uniform{C: gapColor}, image.Point{}, draw.Src)
+	x := 0
+	for _, p := range panels {
+		b := p.Bounds()
+		draw.Draw(out, image.Rect(x, 0, x+b.Dx(), b.Dy()), p, b.Min, draw.Src)
+		x += b.Dx() + panelGap
+	}
+	return out
+}
+
+// DiffHeatmap compares a and b pixel by pixel over the area they share and
+// renders the difference as a heatmap: black where the pixels match, through
+// red to yellow where they differ most.  It also returns the mean difference
+// as a fraction between 0 (identical) and 1.
+func DiffHeatmap(a, b image.Image) (*image.RGBA, float64) {
+	ab, bb := a.Bounds(), b.Bounds()
+	w, h := min(ab.Dx(), bb.Dx()), min(ab.Dy(), bb.Dy())
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	var total float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			d := pixelDiff(a.At(ab.Min.X+x, ab.Min.Y+y), b.At(bb.Min.X+x, bb.Min.Y+y))
+			total += d
+			out.SetRGBA(x, y, heat(d))
+		}
+	}
+	if w == 0 || h == 0 {
+		return out, 0
+	}
+	return out, total / float64(w*h)
+}
+
+// pixelDiff returns the mean absolute difference of two colors' RGB
+// channels as a fraction between 0 and 1.
+func pixelDiff(c1, c2 color.Color) float64 {
+	r1, g1, b1, _ := c1.RGBA()
+	r2, g2, b2, _ := c2.RGBA()
+	sum := absDiff(r1, r2) + absDiff(g1, g2) + absDiff(b1, b2)
+	return float64(sum) / (3 * 0xffff)
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// heat maps a difference in [0, 1] onto a black-red-yellow ramp.
+func heat(d float64) color.RGBA {
+	v := int(d * 510)
+	if v > 510 {
+		v = 510
+	}
+	if v <= 255 {
+		return color.RGBA{R: uint8(v), A: 255}
+	}
+	return color.RGBA{R: 255, G: uint8(v - 255), A: 255}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,110 @@
+package imaging_test
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/imaging"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestSideBySide_PlacesPanelsLeftToRight(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	out := imaging.SideBySide(solid(4, 4, red), solid(6, 2, blue))
+
+	if out.Bounds().Dx() != 4+8+6 || out.Bounds().Dy() != 4 {
+		t.Fatalf("expected 18x4 composite, got %v", out.Bounds())
+	}
+	if got := out.RGBAAt(0, 0); got != red {
+		t.Errorf("expected first panel at the left, got %v", got)
+	}
+	if got := out.RGBAAt(12, 1); got != blue {
+		t.Errorf("expected second panel after the gap, got %v", got)
+	}
+	if got := out.RGBAAt(12, 3); got == blue {
+		t.Errorf("expected area below the shorter panel to be background, got %v", got)
+	}
+}
+
+func TestDiffHeatmap_ScoresIdenticalAndOppositeImages(t *testing.T) {
+	black := solid(3, 3, color.Black)
+	white := solid(3, 3, color.White)
+
+	_, same := imaging.DiffHeatmap(black, black)
+	if same != 0 {
+		t.Errorf("expected identical images to score 0, got %v", same)
+	}
+	heat, opposite := imaging.DiffHeatmap(black, white)
+	if opposite != 1 {
+		t.Errorf("expected opposite images to score 1, got %v", opposite)
+	}
+	if got := heat.RGBAAt(1, 1); got != (color.RGBA{R: 255, G: 255, A: 255}) {
+		t.Errorf("expected maximal difference to be yellow, got %v", got)
+	}
+}
+
+func TestDiffHeatmap_ComparesOnlyTheSharedArea(t *testing.T) {
+	heat, _ := imaging.DiffHeatmap(solid(5, 2, color.Black), solid(3, 4, color.Black))
+	if heat.Bounds().Dx() != 3 || heat.Bounds().Dy() != 2 {
+		t.Errorf("expected 3x2 heatmap, got %v", heat.Bounds())
+	}
+}
+
+func TestSavePNG_RoundTripsThroughLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.png")
+	if err := imaging.SavePNG(path, solid(2, 2, color.White)); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	img, err := imaging.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if img.Bounds().Dx() != 2 {
+		t.Errorf("expected 2px wide image, got %v", img.Bounds())
+	}
+}
+
+func TestCaption_AddsBandWithCenteredText(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	img := solid(160, 100, red)
+
+	out := imaging.Caption(img, "0.5")
+
+	// 160px wide gives a scale of 2: a 14px band with 3 glyphs 22px wide.
+	if out.Bounds().Dx() != 160 || out.Bounds().Dy() != 114 {
+		t.Fatalf("expected 160x114, got %v", out.Bounds())
+	}
+	if out.RGBAAt(80, 50) != red {
+		t.Errorf("expected the image to be kept above the band")
+	}
+	lit, litLeft := 0, 0
+	for y := 100; y < 114; y++ {
+		for x := 0; x < 160; x++ {
+			if c := out.RGBAAt(x, y); c.R > 200 && c.G > 200 {
+				lit++
+				if x < 69 {
+					litLeft++
+				}
+			}
+		}
+	}
+	if lit == 0 {
+		t.Error("expected caption pixels in the band")
+	}
+	if litLeft != 0 {
+		t.Errorf("expected the caption centered, found %d pixels left of it", litLeft)
+	}
+}
@@ -0,0 +1,179 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Inpainting masks cover the whole image.  Leonardo's canvas repaints the
+// black area of a mask and keeps the white area.
+var (
+	maskRepaint = color.Gray{Y: 0}
+	maskKeep    = color.Gray{Y: 255}
+)
+
+// alphaThreshold is the opacity below which a pixel counts as transparent
+// when a mask is taken from an image's alpha channel.
+const alphaThreshold = 0x8000
+
+// Shape is an area of an image to repaint.
+type Shape interface {
+	// Contains reports whether the pixel at x, y lies inside the shape.
+	Contains(x, y int) bool
+}
+
+// Rect is an axis-aligned rectangle.
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Contains implements Shape, testing the pixel's centre.
+func (r Rect) Contains(x, y int) bool {
+	px, py := float64(x)+0.5, float64(y)+0.5
+	return px >= r.X && px < r.X+r.W && py >= r.Y && py < r.Y+r.H
+}
+
+// Ellipse is the ellipse inscribed in a bounding rectangle.
+type Ellipse struct {
+	X, Y, W, H float64
+}
+
+// Contains implements Shape, testing the pixel's centre.
+func (e Ellipse) Contains(x, y int) bool {
+	if e.W <= 0 || e.H <= 0 {
+		return false
+	}
+	rx, ry := e.W/2, e.H/2
+	dx := (float64(x) + 0.5 - (e.X + rx)) / rx
+	dy := (float64(y) + 0.5 - (e.Y + ry)) / ry
+	return dx*dx+dy*dy <= 1
+}
+
+// Polygon is a freeform closed outline through its points, filled with the
+// even-odd rule.
+type Polygon []image.Point
+
+// Contains implements Shape, testing the pixel's centre.
+func (p Polygon) Contains(x, y int) bool {
+	px, py := float64(x)+0.5, float64(y)+0.5
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		xi, yi := float64(p[i].X), float64(p[i].Y)
+		xj, yj := float64(p[j].X), float64(p[j].Y)
+		if (yi > py) != (yj > py) && px < (xj-xi)*(py-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ParseShape parses a shape specification for an image of the given size:
+// "rect:X,Y,W,H", "ellipse:X,Y,W,H" (the ellipse filling that rectangle) or
+// "polygon:X1,Y1,X2,Y2,X3,Y3,...".  Numbers are pixels, or percentages of
+// the image's width (X and W) or height (Y and H) when they end in %.
+func ParseShape(spec string, size image.Point) (Shape, error) {
+	kind, rest, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return nil, fmt.Errorf("shape %q must look like rect:X,Y,W,H", spec)
+	}
+	var values []float64
+	for i, field := range strings.Split(rest, ",") {
+		extent := size.X
+		if i%2 == 1 {
+			extent = size.Y
+		}
+		v, err := parseLength(strings.TrimSpace(field), extent)
+		if err != nil {
+			return nil, fmt.Errorf("shape %q: %w", spec, err)
+		}
+		values = append(values, v)
+	}
+	switch strings.ToLower(kind) {
+	case "rect", "ellipse":
+		if len(values) != 4 {
+			return nil, fmt.Errorf("shape %q needs X,Y,W,H", spec)
+		}
+		if values[2] <= 0 || values[3] <= 0 {
+			return nil, fmt.Errorf("shape %q must have a positive width and height", spec)
+		}
+		if strings.ToLower(kind) == "rect" {
+			return Rect{X: values[0], Y: values[1], W: values[2], H: values[3]}, nil
+		}
+		return Ellipse{X: values[0], Y: values[1], W: values[2], H: values[3]}, nil
+	case "polygon":
+		if len(values) < 6 || len(values)%2 != 0 {
+			return nil, fmt.Errorf("shape %q needs at least three X,Y points", spec)
+		}
+		poly := make(Polygon, 0, len(values)/2)
+		for i := 0; i < len(values); i += 2 {
+			poly = append(poly, image.Pt(int(values[i]+0.5), int(values[i+1]+0.5)))
+		}
+		return poly, nil
+	default:
+		return nil, fmt.Errorf("unknown shape %q (use rect, ellipse or polygon)", kind)
+	}
+}
+
+// parseLength parses a pixel count or a percentage of extent.
+func parseLength(field string, extent int) (float64, error) {
+	if pct, ok := strings.CutSuffix(field, "%"); ok {
+		v, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage %q", field)
+		}
+		return v / 100 * float64(extent), nil
+	}
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", field)
+	}
+	return v, nil
+}
+
+// Mask renders an inpainting mask of the given size: black inside any of
+// the shapes, to be repainted, and white elsewhere.  With invert the colors
+// are swapped, for tools that expect white to mark the repainted area.
+func Mask(size image.Point, shapes []Shape, invert bool) *image.Gray {
+	return renderMask(size, invert, func(x, y int) bool {
+		for _, s := range shapes {
+			if s.Contains(x, y) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// AlphaMask renders an inpainting mask from an image's alpha channel: its
+// transparent pixels are repainted and its opaque ones kept.  invert is as
+// for Mask.
+func AlphaMask(img image.Image, invert bool) *image.Gray {
+	b := img.Bounds()
+	return renderMask(b.Size(), invert, func(x, y int) bool {
+		_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+		return a < alphaThreshold
+	})
+}
+
+// renderMask paints every pixel for which repaint holds black, or white
+// when inverted, and the rest the other color.
+func renderMask(size image.Point, invert bool, repaint func(x, y int) bool) *image.Gray {
+	in, out := maskRepaint, maskKeep
+	if invert {
+		in, out = out, in
+	}
+	mask := image.NewGray(image.Rect(0, 0, size.X, size.Y))
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if repaint(x, y) {
+				mask.SetGray(x, y, in)
+			} else {
+				mask.SetGray(x, y, out)
+			}
+		}
+	}
+	return mask
+}
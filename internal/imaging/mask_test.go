@@ -0,0 +1,90 @@
+package imaging_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"leonardo-cli/internal/imaging"
+)
+
+func TestParseShape_AcceptsPixelsAndPercentages(t *testing.T) {
+	shape, err := imaging.ParseShape("rect:25%,10,50%,50%", image.Pt(200, 100))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := imaging.Rect{X: 50, Y: 10, W: 100, H: 50}
+	if shape != want {
+		t.Fatalf("got %+v, want %+v", shape, want)
+	}
+}
+
+func TestParseShape_RejectsMalformedSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"10,10,5,5",
+		"circle:1,2,3,4",
+		"rect:1,2,3",
+		"rect:1,2,0,4",
+		"ellipse:a,b,c,d",
+		"polygon:0,0,10,10",
+		"polygon:0,0,10,10,5",
+	} {
+		if _, err := imaging.ParseShape(spec, image.Pt(100, 100)); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestMask_MarksShapesBlackOnWhite(t *testing.T) {
+	size := image.Pt(20, 20)
+	var shapes []imaging.Shape
+	for _, spec := range []string{"rect:0,0,5,5", "ellipse:10,10,10,10", "polygon:0,19,8,19,0,11"} {
+		s, err := imaging.ParseShape(spec, size)
+		if err != nil {
+			t.Fatalf("parse %q: %v", spec, err)
+		}
+		shapes = append(shapes, s)
+	}
+	mask := imaging.Mask(size, shapes, false)
+	if got := mask.Bounds().Size(); got != size {
+		t.Fatalf("mask size %v, want %v", got, size)
+	}
+	cases := []struct {
+		x, y int
+		want uint8
+	}{
+		{2, 2, 0},     // inside the rectangle
+		{15, 15, 0},   // ellipse centre
+		{10, 10, 255}, // ellipse bounding box corner
+		{1, 17, 0},    // inside the triangle
+		{7, 12, 255},  // beside the triangle's hypotenuse
+		{12, 3, 255},  // untouched
+	}
+	for _, c := range cases {
+		if got := mask.GrayAt(c.x, c.y).Y; got != c.want {
+			t.Errorf("pixel (%d,%d) = %d, want %d", c.x, c.y, got, c.want)
+		}
+	}
+	inverted := imaging.Mask(size, shapes, true)
+	if inverted.GrayAt(2, 2).Y != 255 || inverted.GrayAt(12, 3).Y != 0 {
+		t.Fatalf("invert did not swap the colors")
+	}
+}
+
+func TestAlphaMask_RepaintsTransparentPixels(t *testing.T) {
+	img := solid(4, 2, color.RGBA{R: 200, A: 255})
+	img.Set(1, 0, color.RGBA{})
+	img.Set(3, 1, color.NRGBA{G: 255, A: 40})
+	mask := imaging.AlphaMask(img, false)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			want := uint8(255)
+			if (x == 1 && y == 0) || (x == 3 && y == 1) {
+				want = 0
+			}
+			if got := mask.GrayAt(x, y).Y; got != want {
+				t.Errorf("pixel (%d,%d) = %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// maxTextChunk bounds the size of a text chunk read by PNGText, so a
+// corrupt length cannot exhaust memory.
+const maxTextChunk = 16 << 20
+
+// PNGText returns the text chunks (tEXt, zTXt and iTXt) of the PNG file at
+// path, keyed by keyword.  Tools such as AUTOMATIC1111 store generation
+// settings there.
+func PNGText(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening image: %w", err)
+	}
+	defer f.Close()
+	text, err := readPNGText(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return text, nil
+}
+
+// readPNGText walks the chunks of a PNG stream up to its end, collecting
+// the text chunks.
+func readPNGText(r io.Reader) (map[string]string, error) {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil || !bytes.Equal(signature, pngSignature) {
+		return nil, errors.New("not a PNG file")
+	}
+	text := map[string]string{}
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("truncated PNG: %w", err)
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		kind := string(header[4:])
+		if kind == "IEND" {
+			return text, nil
+		}
+		if kind != "tEXt" && kind != "zTXt" && kind != "iTXt" {
+			if _, err := io.CopyN(io.Discard, r, int64(length)+4); err != nil {
+				return nil, fmt.Errorf("truncated PNG: %w", err)
+			}
+			continue
+		}
+		if length > maxTextChunk {
+			return nil, fmt.Errorf("%s chunk of %d bytes is too large", kind, length)
+		}
+		data := make([]byte, length+4) // the chunk and its CRC
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("truncated PNG: %w", err)
+		}
+		keyword, value, err := decodeTextChunk(kind, data[:length])
+		if err != nil {
+			return nil, err
+		}
+		text[keyword] = value
+	}
+}
+
+// decodeTextChunk returns the keyword and text of a tEXt, zTXt or iTXt
+// chunk.
+func decodeTextChunk(kind string, data []byte) (string, string, error) {
+	keyword, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return "", "", fmt.Errorf("malformed %s chunk", kind)
+	}
+	switch kind {
+	case "tEXt":
+		return string(keyword), latin1(rest), nil
+	case "zTXt":
+		if len(rest) < 1 {
+			return "", "", fmt.Errorf("malformed %s chunk", kind)
+		}
+		value, err := inflate(rest[1:])
+		return string(keyword), latin1(value), err
+	}
+	// iTXt: compression flag and method, then language tag and translated
+	// keyword, each NUL-terminated, then UTF-8 text.
+	if len(rest) < 2 {
+		return "", "", fmt.Errorf("malformed %s chunk", kind)
+	}
+	compressed := rest[0] == 1
+	fields := bytes.SplitN(rest[2:], []byte{0}, 3)
+	if len(fields) != 3 {
+		return "", "", fmt.Errorf("malformed %s chunk", kind)
+	}
+	value := fields[2]
+	if compressed {
+		var err error
+		if value, err = inflate(value); err != nil {
+			return "", "", err
+		}
+	}
+	return string(keyword), string(value), nil
+}
+
+// inflate decompresses zlib data.
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing text chunk: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, maxTextChunk))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing text chunk: %w", err)
+	}
+	return out, nil
+}
+
+// latin1 converts ISO 8859-1 text, the encoding of tEXt and zTXt chunks,
+// to UTF-8.  Many tools write UTF-8 into these chunks anyway, so text that
+// is already valid UTF-8 is kept as it is.
+func latin1(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -0,0 +1,85 @@
+package imaging_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/imaging"
+)
+
+// withTextChunks encodes a small PNG and inserts the given chunks right
+// after its header chunk.
+func withTextChunks(t *testing.T, chunks ...[]byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solid(2, 2, color.White)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	headerEnd := 8 + 8 + 13 + 4 // signature, IHDR length and type, IHDR data, CRC
+	var out bytes.Buffer
+	out.Write(data[:headerEnd])
+	for _, c := range chunks {
+		out.Write(c)
+	}
+	out.Write(data[headerEnd:])
+	path := filepath.Join(t.TempDir(), "with-text.png")
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func chunk(kind string, data []byte) []byte {
+	var c bytes.Buffer
+	binary.Write(&c, binary.BigEndian, uint32(len(data)))
+	c.WriteString(kind)
+	c.Write(data)
+	binary.Write(&c, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+	return c.Bytes()
+}
+
+func TestPNGText_ReadsPlainCompressedAndInternationalChunks(t *testing.T) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write([]byte("compressed value"))
+	zw.Close()
+
+	path := withTextChunks(t,
+		chunk("tEXt", []byte("parameters\x00a red fox\nSteps: 20")),
+		chunk("zTXt", append([]byte("Comment\x00\x00"), z.Bytes()...)),
+		chunk("iTXt", []byte("Title\x00\x00\x00en\x00\x00Füchse")),
+	)
+
+	text, err := imaging.PNGText(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text["parameters"] != "a red fox\nSteps: 20" {
+		t.Errorf("expected the tEXt chunk, got %q", text["parameters"])
+	}
+	if text["Comment"] != "compressed value" {
+		t.Errorf("expected the zTXt chunk, got %q", text["Comment"])
+	}
+	if text["Title"] != "Füchse" {
+		t.Errorf("expected the iTXt chunk, got %q", text["Title"])
+	}
+	if _, err := imaging.Load(path); err != nil {
+		t.Errorf("expected the image to stay valid: %v", err)
+	}
+}
+
+func TestPNGText_RejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not.png")
+	os.WriteFile(path, []byte("GIF89a"), 0644)
+	if _, err := imaging.PNGText(path); err == nil {
+		t.Error("expected an error for a file that is not a PNG")
+	}
+}
@@ -0,0 +1,108 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseSize parses a WIDTHxHEIGHT size such as "512x512".
+func ParseSize(spec string) (image.Point, error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), "x")
+	if !ok {
+		return image.Point{}, fmt.Errorf("size %q must be WIDTHxHEIGHT, e.g. 512x512", spec)
+	}
+	width, werr := strconv.Atoi(w)
+	height, herr := strconv.Atoi(h)
+	if werr != nil || herr != nil || width < 1 || height < 1 {
+		return image.Point{}, fmt.Errorf("size %q must be two positive whole numbers, e.g. 512x512", spec)
+	}
+	return image.Pt(width, height), nil
+}
+
+// FitWithin returns size scaled down, keeping its aspect ratio, so that
+// neither side exceeds maxDimension.  Sizes that already fit are returned
+// unchanged; images are never enlarged.
+func FitWithin(size image.Point, maxDimension int) image.Point {
+	longest := size.X
+	if size.Y > longest {
+		longest = size.Y
+	}
+	if longest <= maxDimension || maxDimension < 1 {
+		return size
+	}
+	scale := float64(maxDimension) / float64(longest)
+	fit := image.Pt(int(float64(size.X)*scale+0.5), int(float64(size.Y)*scale+0.5))
+	if fit.X < 1 {
+		fit.X = 1
+	}
+	if fit.Y < 1 {
+		fit.Y = 1
+	}
+	return fit
+}
+
+// Resize scales img to size.  Each output pixel is the average of the
+// source pixels it covers, which keeps downscaled images smooth; when
+// enlarging, the nearest source pixel is used.
+func Resize(img image.Image, size image.Point) *image.RGBA {
+	src := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	sx := float64(src.Dx()) / float64(size.X)
+	sy := float64(src.Dy()) / float64(size.Y)
+	for y := 0; y < size.Y; y++ {
+		y0, y1 := span(y, sy)
+		for x := 0; x < size.X; x++ {
+			x0, x1 := span(x, sx)
+			var r, g, b, a, n uint64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					cr, cg, cb, ca := img.At(src.Min.X+px, src.Min.Y+py).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			out.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return out
+}
+
+// Thumbnail returns img scaled down so its longest side is at most
+// maxDimension pixels; smaller images are returned as they are.
+func Thumbnail(img image.Image, maxDimension int) image.Image {
+	size := FitWithin(img.Bounds().Size(), maxDimension)
+	if size == img.Bounds().Size() {
+		return img
+	}
+	return Resize(img, size)
+}
+
+// span returns the source pixels [from, to) covered by output pixel i at the
+// given scale, always at least one.
+func span(i int, scale float64) (int, int) {
+	from := int(float64(i) * scale)
+	to := int(float64(i+1) * scale)
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
+
+// SaveJPEG encodes img as a JPEG file at path with the given quality,
+// from 1 (smallest) to 100 (best).
+func SaveJPEG(path string, img image.Image, quality int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating image: %w", err)
+	}
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: quality}); err != nil {
+		f.Close()
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,50 @@
+package imaging_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"leonardo-cli/internal/imaging"
+)
+
+func TestParseSize_AcceptsWidthByHeight(t *testing.T) {
+	size, err := imaging.ParseSize("512x256")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size != image.Pt(512, 256) {
+		t.Errorf("expected 512x256, got %v", size)
+	}
+	for _, spec := range []string{"512", "0x10", "ax10", "10x-1"} {
+		if _, err := imaging.ParseSize(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestFitWithin_ShrinksTheLongestSideKeepingAspect(t *testing.T) {
+	if got := imaging.FitWithin(image.Pt(1024, 768), 512); got != image.Pt(512, 384) {
+		t.Errorf("expected 512x384, got %v", got)
+	}
+	if got := imaging.FitWithin(image.Pt(300, 200), 512); got != image.Pt(300, 200) {
+		t.Errorf("expected small images to be left alone, got %v", got)
+	}
+}
+
+func TestResize_AveragesThePixelsItCovers(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.White)
+	img.Set(1, 0, color.Black)
+	img.Set(0, 1, color.White)
+	img.Set(1, 1, color.Black)
+
+	out := imaging.Resize(img, image.Pt(1, 1))
+
+	if got := out.RGBAAt(0, 0); got.R < 120 || got.R > 135 {
+		t.Errorf("expected a mid grey, got %v", got)
+	}
+	if big := imaging.Resize(img, image.Pt(4, 4)); big.RGBAAt(3, 3) != (color.RGBA{A: 255}) {
+		t.Errorf("expected enlarging to repeat the nearest pixel, got %v", big.RGBAAt(3, 3))
+	}
+}
@@ -0,0 +1,228 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Protocols for showing an image inline in a terminal.
+const (
+	ProtocolITerm  = "iterm"  // iTerm2 and WezTerm inline images
+	ProtocolKitty  = "kitty"  // kitty graphics protocol
+	ProtocolSixel  = "sixel"  // DEC sixel graphics, e.g. xterm -ti vt340, foot, mlterm
+	ProtocolBlocks = "blocks" // colored half-block characters, works in any 24-bit color terminal
+	ProtocolASCII  = "ascii"  // plain characters by brightness, for terminals without color
+)
+
+// Protocols lists the supported terminal image protocols.
+var Protocols = []string{ProtocolITerm, ProtocolKitty, ProtocolSixel, ProtocolBlocks, ProtocolASCII}
+
+// CellWidth is the assumed width in pixels of a terminal cell, used to size
+// sixel images, which are drawn at their pixel size.
+const CellWidth = 8
+
+// kittyChunk is the largest base64 payload the kitty protocol accepts in
+// one escape sequence.
+const kittyChunk = 4096
+
+// asciiRamp orders characters from darkest to brightest.
+const asciiRamp = " .:-=+*#%@"
+
+// RenderTerminal writes img to w with the given protocol, about columns
+// terminal cells wide.
+func RenderTerminal(w io.Writer, img image.Image, protocol string, columns int) error {
+	if columns < 1 {
+		columns = 1
+	}
+	switch protocol {
+	case ProtocolITerm:
+		return renderITerm(w, img, columns)
+	case ProtocolKitty:
+		return renderKitty(w, img, columns)
+	case ProtocolSixel:
+		return renderSixel(w, scaleToWidth(img, columns*CellWidth, 1))
+	case ProtocolBlocks:
+		return renderBlocks(w, scaleToWidth(img, columns, 1))
+	case ProtocolASCII:
+		// Cells are about twice as tall as wide, so every other row is
+		// dropped to keep the proportions.
+		return renderASCII(w, scaleToWidth(img, columns, 2))
+	}
+	return fmt.Errorf("unknown terminal image protocol %q (want one of %s)", protocol, strings.Join(Protocols, ", "))
+}
+
+// scaleToWidth resizes img to width pixels, keeping its aspect ratio with
+// the height divided by squash.
+func scaleToWidth(img image.Image, width, squash int) image.Image {
+	size := img.Bounds().Size()
+	height := size.Y * width / size.X / squash
+	if height < 1 {
+		height = 1
+	}
+	return Resize(img, image.Pt(width, height))
+}
+
+// encodedPNG returns img as base64-encoded PNG.
+func encodedPNG(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("encoding preview: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// renderITerm sends the image with iTerm2's OSC 1337 sequence; the terminal
+// scales it to the requested width.
+func renderITerm(w io.Writer, img image.Image, columns int) error {
+	data, err := encodedPNG(img)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\x1b]1337;File=inline=1;width=%d;preserveAspectRatio=1:%s\a\n", columns, data)
+	return err
+}
+
+// renderKitty sends the image with the kitty graphics protocol, split into
+// chunks as the protocol requires; the terminal scales it to the requested
+// width.
+func renderKitty(w io.Writer, img image.Image, columns int) error {
+	data, err := encodedPNG(img)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for first := true; first || data != ""; first = false {
+		chunk := data
+		if len(chunk) > kittyChunk {
+			chunk = chunk[:kittyChunk]
+		}
+		data = data[len(chunk):]
+		more := 0
+		if data != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(bw, "\x1b_Ga=T,f=100,c=%d,m=%d;%s\x1b\\", columns, more, chunk)
+		} else {
+			fmt.Fprintf(bw, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+// sixelLevel maps a 16-bit channel onto the six levels of the color cube
+// used as the sixel palette.
+func sixelLevel(v uint32) int {
+	return int(v * 5 / 0xffff)
+}
+
+// renderSixel draws the image in DEC sixel graphics using a 6×6×6 color
+// cube.  Each band of six pixel rows is drawn once per color it uses.
+func renderSixel(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for i := 0; i < 216; i++ {
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, i/36*20, i/6%6*20, i%6*20)
+	}
+	index := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			index[y*width+x] = sixelLevel(r)*36 + sixelLevel(g)*6 + sixelLevel(bl)
+		}
+	}
+	row := make([]byte, width)
+	for top := 0; top < height; top += 6 {
+		var used [216]bool
+		for y := top; y < top+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				used[index[y*width+x]] = true
+			}
+		}
+		first := true
+		for c := range used {
+			if !used[c] {
+				continue
+			}
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if index[(top+dy)*width+x] == c {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = '?' + bits
+			}
+			if !first {
+				bw.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(bw, "#%d", c)
+			writeSixelRow(bw, row)
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\\n")
+	return bw.Flush()
+}
+
+// writeSixelRow writes a row of sixel characters, run-length encoding
+// repeats.
+func writeSixelRow(w *bufio.Writer, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, row[i])
+		} else {
+			w.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+// renderBlocks draws two pixel rows per line with the upper half-block
+// character, the top pixel as its foreground and the bottom as background.
+func renderBlocks(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	bw := bufio.NewWriter(w)
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			bottom := color.RGBA{}
+			if y+1 < b.Max.Y {
+				bottom = color.RGBAModel.Convert(img.At(x, y+1)).(color.RGBA)
+			}
+			fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// renderASCII draws one character per pixel, brighter pixels with denser
+// characters.
+func renderASCII(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	bw := bufio.NewWriter(w)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			bw.WriteByte(asciiRamp[int(gray.Y)*(len(asciiRamp)-1)/255])
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,67 @@
+package imaging_test
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"leonardo-cli/internal/imaging"
+)
+
+func TestRenderTerminal_BlocksDrawTwoPixelRowsPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	if err := imaging.RenderTerminal(&buf, solid(8, 8, color.RGBA{R: 255, A: 255}), imaging.ProtocolBlocks, 4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines for a square image 4 columns wide, got %d", len(lines))
+	}
+	if strings.Count(lines[0], "▀") != 4 || !strings.Contains(lines[0], "\x1b[38;2;255;0;0m") {
+		t.Errorf("expected 4 red half-blocks, got %q", lines[0])
+	}
+}
+
+func TestRenderTerminal_KittySplitsLargePayloadsIntoChunks(t *testing.T) {
+	noisy := solid(128, 128, color.Black)
+	seed := uint32(1)
+	for i := range noisy.Pix {
+		seed = seed*1664525 + 1013904223
+		noisy.Pix[i] = byte(seed >> 24)
+	}
+	var buf bytes.Buffer
+	if err := imaging.RenderTerminal(&buf, noisy, imaging.ProtocolKitty, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "\x1b_Ga=T,f=100,c=10,m=1;") {
+		t.Errorf("expected a first chunk announcing more data, got %q", out[:40])
+	}
+	if !strings.Contains(out, "\x1b_Gm=0;") {
+		t.Error("expected a final chunk")
+	}
+}
+
+func TestRenderTerminal_SixelDrawsOneBandPerSixRows(t *testing.T) {
+	var buf bytes.Buffer
+	if err := imaging.RenderTerminal(&buf, solid(2, 1, color.White), imaging.ProtocolSixel, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "\x1bP0;1;0q\"1;1;16;8") || !strings.HasSuffix(out, "\x1b\\\n") {
+		t.Errorf("expected a 16x8 sixel image, got %q", out[:30])
+	}
+	if bands := strings.Count(out, "-"); bands != 2 {
+		t.Errorf("expected 2 bands for 8 rows, got %d", bands)
+	}
+	if !strings.Contains(out, "#215!16~") {
+		t.Errorf("expected a run-length encoded white band, got %q", out)
+	}
+}
+
+func TestRenderTerminal_RejectsUnknownProtocols(t *testing.T) {
+	if err := imaging.RenderTerminal(&bytes.Buffer{}, solid(1, 1, color.White), "vt100", 1); err == nil {
+		t.Error("expected an error for an unknown protocol")
+	}
+}
@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// Corners a watermark can be placed in.
+const (
+	CornerTopLeft     = "top-left"
+	CornerTopRight    = "top-right"
+	CornerBottomLeft  = "bottom-left"
+	CornerBottomRight = "bottom-right"
+	CornerCenter      = "center"
+)
+
+// Corners lists the supported watermark positions.
+var Corners = []string{CornerTopLeft, CornerTopRight, CornerBottomLeft, CornerBottomRight, CornerCenter}
+
+// watermarkBacking is drawn behind watermark text so it reads on light and
+// dark images alike.
+var watermarkBacking = color.RGBA{R: 0, G: 0, B: 0, A: 200}
+
+// Watermark marks an image as a draft: Text in the blocky caption font on a
+// dark backing, or an Image overlay, drawn in Corner at Opacity (0 to 1).
+type Watermark struct {
+	Text    string
+	Image   image.Image
+	Corner  string
+	Opacity float64
+}
+
+// Validate checks the watermark has something to draw, a known corner and
+// an opacity between 0 and 1.
+func (w Watermark) Validate() error {
+	if strings.TrimSpace(w.Text) == "" && w.Image == nil {
+		return fmt.Errorf("watermark needs text or an image")
+	}
+	if !validCorner(w.Corner) {
+		return fmt.Errorf("unknown watermark corner %q (use %s)", w.Corner, strings.Join(Corners, ", "))
+	}
+	if w.Opacity <= 0 || w.Opacity > 1 {
+		return fmt.Errorf("watermark opacity must be above 0 and at most 1")
+	}
+	return nil
+}
+
+func validCorner(corner string) bool {
+	for _, c := range Corners {
+		if c == corner {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyWatermark returns a copy of img with w drawn over it.  Text is
+// scaled to about a twentieth of the image's shorter side and an image
+// overlay to at most a quarter of it, both inset from the edges.
+func ApplyWatermark(img image.Image, w Watermark) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	short := min(b.Dx(), b.Dy())
+	var mark image.Image
+	if w.Image != nil {
+		mark = Thumbnail(w.Image, max(short/4, 1))
+	} else {
+		mark = renderWatermarkText(w.Text, short, b.Dx())
+	}
+	at := place(out.Bounds().Size(), mark.Bounds().Size(), w.Corner, max(short/40, 1))
+	opacity := &image.Uniform{C: color.Alpha{A: uint8(w.Opacity*255 + 0.5)}}
+	mb := mark.Bounds()
+	draw.DrawMask(out, image.Rectangle{Min: at, Max: at.Add(mb.Size())}, mark, mb.Min, opacity, image.Point{}, draw.Over)
+	return out
+}
+
+// renderWatermarkText draws text on its backing, sized for an image whose
+// shorter side is short and which is width pixels wide.
+func renderWatermarkText(text string, short, width int) *image.RGBA {
+	text = strings.TrimSpace(text)
+	scale := max(short/100, 1)
+	for scale > 1 && textWidth(text, scale)+2*scale > width {
+		scale--
+	}
+	layer := image.NewRGBA(image.Rect(0, 0, textWidth(text, scale)+2*scale, 7*scale))
+	draw.Draw(layer, layer.Bounds(), &image.Uniform{C: watermarkBacking}, image.Point{}, draw.Src)
+	drawText(layer, text, image.Pt(scale, scale), scale, captionColor)
+	return layer
+}
+
+// place returns the top left point of a mark of size mark in corner of an
+// image of size size, margin pixels from its edges.
+func place(size, mark image.Point, corner string, margin int) image.Point {
+	left, top := margin, margin
+	right, bottom := size.X-mark.X-margin, size.Y-mark.Y-margin
+	switch corner {
+	case CornerTopLeft:
+		return image.Pt(left, top)
+	case CornerTopRight:
+		return image.Pt(right, top)
+	case CornerBottomLeft:
+		return image.Pt(left, bottom)
+	case CornerCenter:
+		return image.Pt((size.X-mark.X)/2, (size.Y-mark.Y)/2)
+	default:
+		return image.Pt(right, bottom)
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
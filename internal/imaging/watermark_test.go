@@ -0,0 +1,68 @@
+package imaging_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"leonardo-cli/internal/imaging"
+)
+
+// changedArea returns the bounding box of the pixels that differ between a
+// and b.
+func changedArea(a, b *image.RGBA) image.Rectangle {
+	var area image.Rectangle
+	for y := a.Bounds().Min.Y; y < a.Bounds().Max.Y; y++ {
+		for x := a.Bounds().Min.X; x < a.Bounds().Max.X; x++ {
+			if a.RGBAAt(x, y) != b.RGBAAt(x, y) {
+				area = area.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return area
+}
+
+func TestApplyWatermark_DrawsTextInTheChosenCornerAndKeepsTheOriginal(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	img := solid(400, 200, red)
+
+	out := imaging.ApplyWatermark(img, imaging.Watermark{Text: "Draft", Corner: imaging.CornerBottomRight, Opacity: 0.5})
+
+	area := changedArea(img, out)
+	if area.Empty() {
+		t.Fatal("expected the watermark to change some pixels")
+	}
+	if area.Min.X < 200 || area.Min.Y < 100 || area.Max.X > 400-5 || area.Max.Y > 200-5 {
+		t.Errorf("expected the mark inset in the bottom right corner, got %v", area)
+	}
+	if c := out.RGBAAt(area.Min.X, area.Min.Y); c.R == 0 || c.R == 255 {
+		t.Errorf("expected the mark blended with the image at half opacity, got %v", c)
+	}
+	if img.RGBAAt(399, 199) != red || img.RGBAAt(area.Min.X, area.Min.Y) != red {
+		t.Error("expected the original image to be left untouched")
+	}
+}
+
+func TestApplyWatermark_ScalesAnImageOverlayToAQuarterOfTheShorterSide(t *testing.T) {
+	img := solid(400, 200, color.White)
+	logo := solid(100, 100, color.Black)
+
+	out := imaging.ApplyWatermark(img, imaging.Watermark{Image: logo, Corner: imaging.CornerTopLeft, Opacity: 1})
+
+	if area := changedArea(img, out); area != image.Rect(5, 5, 55, 55) {
+		t.Errorf("expected a 50px logo 5px from the top left, got %v", area)
+	}
+}
+
+func TestWatermarkValidate_RejectsIncompleteWatermarks(t *testing.T) {
+	for _, w := range []imaging.Watermark{
+		{Corner: imaging.CornerCenter, Opacity: 0.5},
+		{Text: "x", Corner: "middle", Opacity: 0.5},
+		{Text: "x", Corner: imaging.CornerCenter, Opacity: 0},
+		{Text: "x", Corner: imaging.CornerCenter, Opacity: 1.5},
+	} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", w)
+		}
+	}
+}
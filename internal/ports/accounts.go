@@ -0,0 +1,21 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// AccountStore defines the port used to persist named API credentials so
+// several Leonardo accounts can be used from the same machine.
+type AccountStore interface {
+	// Save stores an account, replacing any existing account with the same
+	// name.
+	Save(account domain.Account) error
+	// List returns every stored account in the order they were saved.
+	List() ([]domain.Account, error)
+	// Remove deletes the account with the given name, if present.
+	Remove(name string) error
+	// Default returns the name of the default account, or an empty string
+	// when none is set.
+	Default() (string, error)
+	// SetDefault records the name of the default account.  An empty name
+	// clears it.
+	SetDefault(name string) error
+}
@@ -0,0 +1,15 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// CharacterStore defines the port used to keep the named subject
+// references reused across generations.
+type CharacterStore interface {
+	// Save stores a character, replacing any existing one with the same
+	// name.
+	Save(character domain.Character) error
+	// List returns every stored character sorted by name.
+	List() ([]domain.Character, error)
+	// Remove deletes the character with the given name, if present.
+	Remove(name string) error
+}
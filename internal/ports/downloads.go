@@ -0,0 +1,19 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// DownloadStore defines the port used to remember the content of downloaded
+// images, so identical downloads can share their data on disk.
+type DownloadStore interface {
+	// Save stores a file, replacing any existing record for its path.
+	Save(image domain.StoredImage) error
+	// Record saves image like Save and returns the other files recorded
+	// with the same content, in the order they were saved.  The lookup and
+	// the save happen as one step, so concurrent downloads of the same
+	// image find each other.
+	Record(image domain.StoredImage) ([]domain.StoredImage, error)
+	// List returns every stored file in the order they were saved.
+	List() ([]domain.StoredImage, error)
+	// Remove deletes the record for path, if present.
+	Remove(path string) error
+}
@@ -0,0 +1,11 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// History defines the port used to keep a log of CLI invocations.
+type History interface {
+	// Append adds an invocation to the end of the log.
+	Append(entry domain.HistoryEntry) error
+	// List returns every recorded invocation, oldest first.
+	List() ([]domain.HistoryEntry, error)
+}
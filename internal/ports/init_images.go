@@ -0,0 +1,26 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// InitImageClient defines the port used to manage init images, the
+// reference images generations can start from.
+type InitImageClient interface {
+	// UploadInitImage uploads the image file at path and returns the new
+	// init image.
+	UploadInitImage(path string) (domain.InitImage, error)
+	// GetInitImage retrieves an uploaded init image by its ID.
+	GetInitImage(id string) (domain.InitImage, error)
+	// DeleteInitImage removes an uploaded init image by its ID.
+	DeleteInitImage(id string) (domain.DeleteResponse, error)
+}
+
+// InitImageStore defines the port used to remember the init images uploaded
+// from this machine, since the API offers no way to list them.
+type InitImageStore interface {
+	// Save stores an init image, replacing any existing one with the same ID.
+	Save(image domain.InitImage) error
+	// List returns every stored init image in the order they were saved.
+	List() ([]domain.InitImage, error)
+	// Remove deletes the init image with the given ID, if present.
+	Remove(id string) error
+}
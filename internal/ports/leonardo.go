@@ -1,6 +1,11 @@
 package ports
 
-import "leonardo-cli/internal/domain"
+import (
+	"context"
+	"io"
+
+	"leonardo-cli/internal/domain"
+)
 
 // LeonardoClient defines the hexagonal port used by the application layer to
 // interact with the Leonardo.Ai API.  Implementations of this interface may
@@ -12,6 +17,13 @@ type LeonardoClient interface {
 	// GetGenerationStatus retrieves the status of a previously created generation
 	// by its generation ID.  It returns the status string and any image URLs.
 	GetGenerationStatus(id string) (domain.GenerationStatus, error)
+	// WaitForCompletion checks a generation's status, backing off between
+	// checks, until it is complete or has failed, ctx is done or the
+	// options' timeout elapses.  It returns the last status seen.
+	WaitForCompletion(ctx context.Context, id string, opts domain.WaitOptions) (domain.GenerationStatus, error)
+	// GetGeneration retrieves the complete record of a generation, including
+	// its parameters, elements and per-image data.
+	GetGeneration(id string) (domain.GenerationDetail, error)
 	// DeleteGeneration removes a generation by its ID.
 	DeleteGeneration(id string) (domain.DeleteResponse, error)
 	// GetUserInfo retrieves the authenticated user's account information.
@@ -20,7 +32,20 @@ type LeonardoClient interface {
 	ListGenerations(userID string, offset, limit int) (domain.GenerationListResponse, error)
 	// DownloadImage downloads an image from the given URL and saves it to destPath.
 	DownloadImage(url, destPath string) error
+	// StreamImage downloads an image from the given URL and copies its bytes
+	// to w as they arrive.
+	StreamImage(url string, w io.Writer) error
 	// ListPlatformModels retrieves the list of public platform models available
 	// for use with generations.
 	ListPlatformModels() (domain.PlatformModelResponse, error)
+	// UpscaleImage starts an upscale of a generated image by its image ID.
+	UpscaleImage(imageID string) (domain.VariationJob, error)
+	// UniversalUpscale starts an upscale of a generated image by its image
+	// ID with the universal upscaler, which scales by multiplier.
+	UniversalUpscale(imageID string, multiplier float64) (domain.VariationJob, error)
+	// CreateVariation starts a variation of the given kind, such as a
+	// background removal, of a generated image by its image ID.
+	CreateVariation(kind domain.VariationKind, imageID string) (domain.VariationJob, error)
+	// GetVariation retrieves a variation, such as an upscale, by its job ID.
+	GetVariation(id string) (domain.ImageVariation, error)
 }
@@ -0,0 +1,16 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// Library defines the port used to persist and query the local record of
+// generations created by the CLI.  Implementations may store entries in a
+// file, a database or memory.
+type Library interface {
+	// Save stores an entry, replacing any existing entry with the same
+	// generation ID.
+	Save(entry domain.LibraryEntry) error
+	// List returns every stored entry in the order they were saved.
+	List() ([]domain.LibraryEntry, error)
+	// Remove deletes the entry with the given generation ID, if present.
+	Remove(id string) error
+}
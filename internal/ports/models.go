@@ -0,0 +1,14 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// ModelCache defines the port used to keep a local copy of the platform
+// model list, so requests can be checked against model capabilities without
+// listing the models before every generation.
+type ModelCache interface {
+	// Load returns the cached catalog.  An empty catalog means nothing has
+	// been cached yet.
+	Load() (domain.ModelCatalog, error)
+	// Save replaces the cached catalog.
+	Save(catalog domain.ModelCatalog) error
+}
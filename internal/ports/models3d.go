@@ -0,0 +1,11 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// Model3DClient defines the port used to upload 3D models that texture
+// generations can be run against.
+type Model3DClient interface {
+	// UploadModel3D uploads the model file at path under the given name and
+	// returns the new model.
+	UploadModel3D(path, name string) (domain.Model3D, error)
+}
@@ -0,0 +1,10 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// PricingClient defines the port used to ask Leonardo what a generation
+// would cost before submitting it.
+type PricingClient interface {
+	// CalculateCost returns the API token cost of a generation configuration.
+	CalculateCost(query domain.PriceQuery) (int, error)
+}
@@ -0,0 +1,14 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// ProjectManifest defines the port used to record which generations produced
+// the asset files of a project.
+type ProjectManifest interface {
+	// Create starts an empty manifest.  It fails when one already exists.
+	Create() error
+	// List returns every recorded asset ordered by path.
+	List() ([]domain.ProjectAsset, error)
+	// Save records an asset, replacing any existing record for its path.
+	Save(asset domain.ProjectAsset) error
+}
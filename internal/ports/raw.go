@@ -0,0 +1,11 @@
+package ports
+
+import "leonardo-cli/internal/domain"
+
+// RawClient defines the port used to send arbitrary, already built requests
+// to the Leonardo API with the client's credentials.
+type RawClient interface {
+	// SendRaw sends the request and returns the response.  Non-2xx
+	// responses are returned along with a *domain.APIError.
+	SendRaw(req domain.RawRequest) (domain.RawResponse, error)
+}
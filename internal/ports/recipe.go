@@ -0,0 +1,8 @@
+package ports
+
+// RecipeSource defines the port used to fetch recipes shared at a URL.
+type RecipeSource interface {
+	// FetchRecipe returns the body found at url, without sending any
+	// credentials.
+	FetchRecipe(url string) ([]byte, error)
+}
@@ -0,0 +1,9 @@
+package ports
+
+// Wordlists defines the port used to read the wordlists that prompt
+// wildcards are resolved from.
+type Wordlists interface {
+	// Lines returns the entries of the named wordlist.  A missing wordlist
+	// is reported as a *domain.UnknownWildcardError.
+	Lines(name string) ([]string, error)
+}
@@ -2,11 +2,20 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"leonardo-cli/internal/domain"
@@ -19,6 +28,10 @@ type APIClient struct {
 	apiKey string
 	// HTTP client is configurable to allow overriding timeouts in tests.
 	httpClient *http.Client
+	// observer, when set, is told about every HTTP request the client makes.
+	observer func(domain.CallMetric)
+	// ctx bounds every request; cancelling it aborts requests in flight.
+	ctx context.Context
 }
 
 // NewAPIClient constructs a new APIClient.  The apiKey must be a valid
@@ -28,7 +41,152 @@ func NewAPIClient(apiKey string, httpClient *http.Client) *APIClient {
 	if httpClient == nil {
 		httpClient = &http.Client{Timeout: 60 * time.Second}
 	}
-	return &APIClient{apiKey: apiKey, httpClient: httpClient}
+	return &APIClient{apiKey: apiKey, httpClient: httpClient, ctx: context.Background()}
+}
+
+// requestIDHeaders lists the response headers that carry a request
+// identifier, in order of preference.
+var requestIDHeaders = []string{"X-Request-Id", "X-Amzn-Requestid", "X-Amz-Cf-Id", "Cf-Ray"}
+
+// SetObserver registers fn to be called after every HTTP request with the
+// request's latency, status and request ID.  fn may be called concurrently
+// when the client is shared between goroutines.
+func (c *APIClient) SetObserver(fn func(domain.CallMetric)) {
+	c.observer = fn
+}
+
+// SetContext makes every later request run under ctx, so cancelling ctx,
+// for example on Ctrl-C, aborts the requests in flight.
+func (c *APIClient) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// newRequest builds an authenticated request against the Leonardo API.
+// A non-nil payload is sent as a JSON body.
+func (c *APIClient) newRequest(method, url string, payload []byte) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(c.ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	return httpReq, nil
+}
+
+// send executes an API request and returns the response body.  Non-2xx
+// responses are returned as an *domain.APIError along with the body, which
+// callers keep in the Raw field of their result.
+func (c *APIClient) send(httpReq *http.Request) ([]byte, error) {
+	body, _, err := c.sendWithHeader(httpReq)
+	return body, err
+}
+
+// sendWithHeader is send that also returns the response headers, or nil
+// when no response arrived.
+func (c *APIClient) sendWithHeader(httpReq *http.Request) ([]byte, http.Header, error) {
+	_, body, header, err := c.do(httpReq)
+	return body, header, err
+}
+
+// do is sendWithHeader that also returns the status code, or zero when no
+// response arrived.
+func (c *APIClient) do(httpReq *http.Request) (int, []byte, http.Header, error) {
+	start := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.observe(httpReq, 0, "", domain.RateLimit{}, time.Since(start))
+		return 0, nil, nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	requestID := requestIDFrom(resp.Header)
+	c.observe(httpReq, resp.StatusCode, requestID, rateLimitFrom(resp.Header, time.Now()), time.Since(start))
+	if err != nil {
+		return resp.StatusCode, nil, resp.Header, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, bodyBytes, resp.Header, &domain.APIError{StatusCode: resp.StatusCode, Body: bodyBytes, RequestID: requestID}
+	}
+	return resp.StatusCode, bodyBytes, resp.Header, nil
+}
+
+// retryAfter returns the delay a Retry-After header asks for, given in
+// seconds or as an HTTP date, or zero when there is none.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// observe reports a finished request to the observer, if any.
+func (c *APIClient) observe(httpReq *http.Request, statusCode int, requestID string, limit domain.RateLimit, elapsed time.Duration) {
+	if c.observer == nil {
+		return
+	}
+	c.observer(domain.CallMetric{
+		Method:     httpReq.Method,
+		Path:       httpReq.URL.Path,
+		StatusCode: statusCode,
+		RequestID:  requestID,
+		Duration:   elapsed,
+		RateLimit:  limit,
+	})
+}
+
+// rateLimitPrefixes are the header families carrying rate limits: the
+// common X-RateLimit-* and the standardised RateLimit-*.
+var rateLimitPrefixes = []string{"X-Ratelimit-", "Ratelimit-"}
+
+// rateLimitFrom reads the request allowance from a response's
+// Limit/Remaining/Reset headers.  Reset is taken as seconds from now, or as
+// a Unix time when it is too large to be a delay.
+func rateLimitFrom(header http.Header, now time.Time) domain.RateLimit {
+	for _, prefix := range rateLimitPrefixes {
+		limit, err := strconv.Atoi(strings.TrimSpace(header.Get(prefix + "Limit")))
+		if err != nil || limit <= 0 {
+			continue
+		}
+		remaining, err := strconv.Atoi(strings.TrimSpace(header.Get(prefix + "Remaining")))
+		if err != nil {
+			continue
+		}
+		rl := domain.RateLimit{Limit: limit, Remaining: remaining}
+		if reset, err := strconv.ParseInt(strings.TrimSpace(header.Get(prefix+"Reset")), 10, 64); err == nil && reset >= 0 {
+			if reset > 1e9 {
+				rl.Reset = time.Unix(reset, 0)
+			} else {
+				rl.Reset = now.Add(time.Duration(reset) * time.Second)
+			}
+		}
+		return rl
+	}
+	return domain.RateLimit{}
+}
+
+// requestIDFrom returns the request identifier found in a response's
+// headers, or an empty string when there is none.
+func requestIDFrom(header http.Header) string {
+	for _, name := range requestIDHeaders {
+		if id := strings.TrimSpace(header.Get(name)); id != "" {
+			return id
+		}
+	}
+	return ""
 }
 
 // CreateGeneration implements the LeonardoClient interface.  It builds a JSON
@@ -36,6 +194,32 @@ func NewAPIClient(apiKey string, httpClient *http.Client) *APIClient {
 // endpoint.  The response body is returned in the Raw field and the
 // generation ID (if any) is extracted.
 func (c *APIClient) CreateGeneration(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+	payload, err := GenerationPayload(req)
+	if err != nil {
+		return domain.GenerationResponse{}, err
+	}
+	httpReq, err := c.newRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/generations", payload)
+	if err != nil {
+		return domain.GenerationResponse{}, err
+	}
+	if req.IdempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", req.IdempotencyKey)
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.GenerationResponse{Raw: bodyBytes}, err
+	}
+	var decoded createGenerationResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.GenerationResponse{Raw: bodyBytes}, err
+	}
+	genID := decoded.SDGenerationJob.GenerationID
+	return domain.GenerationResponse{GenerationID: genID, Cost: decoded.SDGenerationJob.APICreditCost, Raw: bodyBytes}, nil
+}
+
+// GenerationPayload returns the JSON body CreateGeneration sends for req,
+// so that it can be shown without submitting it.
+func GenerationPayload(req domain.GenerationRequest) ([]byte, error) {
 	metadata := req.Metadata
 	bodyMap := map[string]interface{}{
 		"prompt":     metadata.Prompt,
@@ -74,40 +258,46 @@ func (c *APIClient) CreateGeneration(req domain.GenerationRequest) (domain.Gener
 	if metadata.HasSeed() {
 		bodyMap["seed"] = metadata.Seed
 	}
-	// Marshal payload
-	payload, err := json.Marshal(bodyMap)
-	if err != nil {
-		return domain.GenerationResponse{}, fmt.Errorf("encoding request body: %w", err)
+	if metadata.HasHighResolution() {
+		bodyMap["highResolution"] = true
 	}
-	httpReq, err := http.NewRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/generations", bytes.NewBuffer(payload))
-	if err != nil {
-		return domain.GenerationResponse{}, fmt.Errorf("creating request: %w", err)
+	if metadata.HasContrastRatio() {
+		bodyMap["contrastRatio"] = metadata.ContrastRatio
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return domain.GenerationResponse{}, fmt.Errorf("executing request: %w", err)
+	if metadata.HasExpandedDomain() {
+		bodyMap["expandedDomain"] = true
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return domain.GenerationResponse{}, fmt.Errorf("reading response: %w", err)
+	if metadata.HasHighContrast() {
+		bodyMap["highContrast"] = true
 	}
-	if resp.StatusCode >= 300 {
-		return domain.GenerationResponse{Raw: bodyBytes}, fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
-	var decoded map[string]interface{}
-	genID := ""
-	if err := json.Unmarshal(bodyBytes, &decoded); err == nil {
-		if job, ok := decoded["sdGenerationJob"].(map[string]interface{}); ok {
-			if id, ok := job["generationId"].(string); ok {
-				genID = id
-			}
+	if metadata.HasInitImageID() {
+		bodyMap["init_image_id"] = metadata.InitImageID
+	}
+	if metadata.HasInitStrength() {
+		bodyMap["init_strength"] = metadata.InitStrength
+	}
+	if metadata.HasElements() {
+		elements := make([]map[string]interface{}, len(metadata.Elements))
+		for i, e := range metadata.Elements {
+			elements[i] = map[string]interface{}{"akUUID": e.ID, "weight": e.Weight}
 		}
+		bodyMap["elements"] = elements
+	}
+	if metadata.HasPhotoReal() {
+		bodyMap["photoReal"] = true
+		// PhotoReal v2 runs on top of the chosen model; v1 ignores modelId.
+		if metadata.HasModelID() {
+			bodyMap["photoRealVersion"] = "v2"
+		}
+	}
+	for key, value := range req.Params {
+		bodyMap[key] = value
 	}
-	return domain.GenerationResponse{GenerationID: genID, Raw: bodyBytes}, nil
+	payload, err := json.Marshal(bodyMap)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request body: %w", err)
+	}
+	return payload, nil
 }
 
 // GetGenerationStatus implements the LeonardoClient interface.  It issues a
@@ -115,45 +305,120 @@ func (c *APIClient) CreateGeneration(req domain.GenerationRequest) (domain.Gener
 // status and image URLs.  The raw JSON is always included in the returned
 // GenerationStatus.
 func (c *APIClient) GetGenerationStatus(id string) (domain.GenerationStatus, error) {
+	status, _, err := c.generationStatus(context.Background(), id)
+	return status, err
+}
+
+// generationStatus fetches the status of a generation under ctx, along with
+// any delay the server asked for before the next request.
+func (c *APIClient) generationStatus(ctx context.Context, id string) (domain.GenerationStatus, time.Duration, error) {
 	url := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/generations/%s", id)
-	httpReq, err := http.NewRequest("GET", url, nil)
+	httpReq, err := c.newRequest("GET", url, nil)
 	if err != nil {
-		return domain.GenerationStatus{}, fmt.Errorf("creating request: %w", err)
+		return domain.GenerationStatus{}, 0, err
 	}
-	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	resp, err := c.httpClient.Do(httpReq)
+	bodyBytes, header, err := c.sendWithHeader(httpReq.WithContext(ctx))
+	advised := retryAfter(header, time.Now())
 	if err != nil {
-		return domain.GenerationStatus{}, fmt.Errorf("executing request: %w", err)
+		return domain.GenerationStatus{Raw: bodyBytes}, advised, err
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return domain.GenerationStatus{}, fmt.Errorf("reading response: %w", err)
+	var decoded generationResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.GenerationStatus{Raw: bodyBytes}, advised, err
 	}
-	if resp.StatusCode >= 300 {
-		return domain.GenerationStatus{Raw: bodyBytes}, fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
-	status := domain.GenerationStatus{Raw: bodyBytes}
-	var decoded map[string]interface{}
-	if err := json.Unmarshal(bodyBytes, &decoded); err == nil {
-		// Newer API responses structure the generation under generations_by_pk
-		if gen, ok := decoded["generations_by_pk"].(map[string]interface{}); ok {
-			if s, ok := gen["status"].(string); ok {
-				status.Status = s
+	status := domain.GenerationStatus{}
+	// Newer API responses structure the generation under generations_by_pk
+	if decoded.Generation != nil {
+		status = decoded.Generation.toStatus()
+	}
+	status.Raw = bodyBytes
+	return status, advised, nil
+}
+
+// WaitForCompletion implements the LeonardoClient interface.  It checks the
+// generation's status until it is complete or has failed, starting
+// opts.Interval apart and backing off with jitter, and waits longer when
+// the server sends a Retry-After header.  Up to domain.WaitMaxErrors
+// retryable errors in a row, such as rate limits, are ridden out.  The wait
+// ends early when ctx is done or opts.Timeout elapses, returning the last
+// status seen with an error.
+func (c *APIClient) WaitForCompletion(ctx context.Context, id string, opts domain.WaitOptions) (domain.GenerationStatus, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	var (
+		last     domain.GenerationStatus
+		interval time.Duration
+		polls    int
+		failures int
+	)
+	for {
+		status, advised, err := c.generationStatus(ctx, id)
+		switch {
+		case ctx.Err() != nil:
+			return last, waitEnded(ctx, id, opts, last)
+		case err != nil:
+			failures++
+			class := domain.ClassifyFailure(err)
+			if failures >= domain.WaitMaxErrors || (class != domain.FailureTransient && class != domain.FailureRateLimit) {
+				return last, err
+			}
+		default:
+			failures = 0
+			polls++
+			last = status
+			if opts.OnPoll != nil {
+				opts.OnPoll(status, polls)
 			}
-			if imgs, ok := gen["generated_images"].([]interface{}); ok {
-				for _, item := range imgs {
-					if im, ok := item.(map[string]interface{}); ok {
-						if url, ok := im["url"].(string); ok {
-							status.Images = append(status.Images, url)
-						}
-					}
-				}
+			if status.Finished() {
+				return status, nil
 			}
 		}
+		interval = opts.NextInterval(interval)
+		timer := time.NewTimer(opts.Delay(interval, advised, rand.Float64()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, waitEnded(ctx, id, opts, last)
+		case <-timer.C:
+		}
+	}
+}
+
+// waitEnded explains why a wait for a generation stopped before it
+// finished.
+func waitEnded(ctx context.Context, id string, opts domain.WaitOptions, last domain.GenerationStatus) error {
+	if opts.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s waiting for generation %s (status %s)", opts.Timeout, id, last.Status)
+	}
+	return fmt.Errorf("waiting for generation %s: %w", id, ctx.Err())
+}
+
+// GetGeneration implements the LeonardoClient interface.  It issues a GET
+// request to the /generations/{id} endpoint and decodes the full generation
+// record.  The raw JSON is always included in the returned GenerationDetail.
+func (c *APIClient) GetGeneration(id string) (domain.GenerationDetail, error) {
+	url := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/generations/%s", id)
+	httpReq, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return domain.GenerationDetail{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.GenerationDetail{Raw: bodyBytes}, err
+	}
+	var decoded generationResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.GenerationDetail{Raw: bodyBytes}, err
+	}
+	detail := domain.GenerationDetail{}
+	if decoded.Generation != nil {
+		detail = decoded.Generation.toDetail()
 	}
-	return status, nil
+	detail.Raw = bodyBytes
+	return detail, nil
 }
 
 // DeleteGeneration implements the LeonardoClient interface.  It issues a
@@ -161,32 +426,21 @@ func (c *APIClient) GetGenerationStatus(id string) (domain.GenerationStatus, err
 // included in the returned DeleteResponse.
 func (c *APIClient) DeleteGeneration(id string) (domain.DeleteResponse, error) {
 	url := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/generations/%s", id)
-	httpReq, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return domain.DeleteResponse{}, fmt.Errorf("creating request: %w", err)
-	}
-	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	resp, err := c.httpClient.Do(httpReq)
+	httpReq, err := c.newRequest("DELETE", url, nil)
 	if err != nil {
-		return domain.DeleteResponse{}, fmt.Errorf("executing request: %w", err)
+		return domain.DeleteResponse{}, err
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	bodyBytes, err := c.send(httpReq)
 	if err != nil {
-		return domain.DeleteResponse{}, fmt.Errorf("reading response: %w", err)
+		return domain.DeleteResponse{Raw: bodyBytes}, err
 	}
-	if resp.StatusCode >= 300 {
-		return domain.DeleteResponse{Raw: bodyBytes}, fmt.Errorf("API returned status %d", resp.StatusCode)
+	var decoded deleteGenerationResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.DeleteResponse{Raw: bodyBytes}, err
 	}
 	result := domain.DeleteResponse{Raw: bodyBytes}
-	var decoded map[string]interface{}
-	if err := json.Unmarshal(bodyBytes, &decoded); err == nil {
-		if del, ok := decoded["delete_generations_by_pk"].(map[string]interface{}); ok {
-			if delID, ok := del["id"].(string); ok {
-				result.ID = delID
-			}
-		}
+	if decoded.Deleted != nil {
+		result.ID = decoded.Deleted.ID
 	}
 	return result, nil
 }
@@ -195,48 +449,26 @@ func (c *APIClient) DeleteGeneration(id string) (domain.DeleteResponse, error) {
 // request to the /me endpoint to retrieve the authenticated user's account
 // information including token balances.
 func (c *APIClient) GetUserInfo() (domain.UserInfo, error) {
-	httpReq, err := http.NewRequest("GET", "https://cloud.leonardo.ai/api/rest/v1/me", nil)
+	httpReq, err := c.newRequest("GET", "https://cloud.leonardo.ai/api/rest/v1/me", nil)
 	if err != nil {
-		return domain.UserInfo{}, fmt.Errorf("creating request: %w", err)
+		return domain.UserInfo{}, err
 	}
-	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	resp, err := c.httpClient.Do(httpReq)
+	bodyBytes, err := c.send(httpReq)
 	if err != nil {
-		return domain.UserInfo{}, fmt.Errorf("executing request: %w", err)
+		return domain.UserInfo{Raw: bodyBytes}, err
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return domain.UserInfo{}, fmt.Errorf("reading response: %w", err)
-	}
-	if resp.StatusCode >= 300 {
-		return domain.UserInfo{Raw: bodyBytes}, fmt.Errorf("API returned status %d", resp.StatusCode)
+	var decoded userInfoResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.UserInfo{Raw: bodyBytes}, err
 	}
 	info := domain.UserInfo{Raw: bodyBytes}
-	var decoded map[string]interface{}
-	if err := json.Unmarshal(bodyBytes, &decoded); err == nil {
-		if details, ok := decoded["user_details"].([]interface{}); ok && len(details) > 0 {
-			if detail, ok := details[0].(map[string]interface{}); ok {
-				if user, ok := detail["user"].(map[string]interface{}); ok {
-					if id, ok := user["id"].(string); ok {
-						info.UserID = id
-					}
-					if name, ok := user["username"].(string); ok {
-						info.Username = name
-					}
-				}
-				if tokens, ok := detail["apiSubscriptionTokens"].(float64); ok {
-					info.APISubscriptionTokens = int(tokens)
-				}
-				if tokens, ok := detail["apiPaidTokens"].(float64); ok {
-					info.APIPaidTokens = int(tokens)
-				}
-				if date, ok := detail["apiPlanTokenRenewalDate"].(string); ok {
-					info.TokenRenewalDate = date
-				}
-			}
-		}
+	if len(decoded.UserDetails) > 0 {
+		detail := decoded.UserDetails[0]
+		info.UserID = detail.User.ID
+		info.Username = detail.User.Username
+		info.APISubscriptionTokens = detail.APISubscriptionTokens
+		info.APIPaidTokens = detail.APIPaidTokens
+		info.TokenRenewalDate = detail.APIPlanTokenRenewalDate
 	}
 	return info, nil
 }
@@ -246,56 +478,21 @@ func (c *APIClient) GetUserInfo() (domain.UserInfo, error) {
 // parameters.  The raw JSON is always included in the returned response.
 func (c *APIClient) ListGenerations(userID string, offset, limit int) (domain.GenerationListResponse, error) {
 	url := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/generations/user/%s?offset=%d&limit=%d", userID, offset, limit)
-	httpReq, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return domain.GenerationListResponse{}, fmt.Errorf("creating request: %w", err)
-	}
-	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	resp, err := c.httpClient.Do(httpReq)
+	httpReq, err := c.newRequest("GET", url, nil)
 	if err != nil {
-		return domain.GenerationListResponse{}, fmt.Errorf("executing request: %w", err)
+		return domain.GenerationListResponse{}, err
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	bodyBytes, err := c.send(httpReq)
 	if err != nil {
-		return domain.GenerationListResponse{}, fmt.Errorf("reading response: %w", err)
+		return domain.GenerationListResponse{Raw: bodyBytes}, err
 	}
-	if resp.StatusCode >= 300 {
-		return domain.GenerationListResponse{Raw: bodyBytes}, fmt.Errorf("API returned status %d", resp.StatusCode)
+	var decoded generationListResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.GenerationListResponse{Raw: bodyBytes}, err
 	}
 	result := domain.GenerationListResponse{Raw: bodyBytes}
-	var decoded map[string]interface{}
-	if err := json.Unmarshal(bodyBytes, &decoded); err == nil {
-		if gens, ok := decoded["generations"].([]interface{}); ok {
-			for _, g := range gens {
-				if gen, ok := g.(map[string]interface{}); ok {
-					item := domain.GenerationListItem{}
-					if id, ok := gen["id"].(string); ok {
-						item.ID = id
-					}
-					if s, ok := gen["status"].(string); ok {
-						item.Status = s
-					}
-					if ca, ok := gen["createdAt"].(string); ok {
-						item.CreatedAt = ca
-					}
-					if p, ok := gen["prompt"].(string); ok {
-						item.Prompt = p
-					}
-					if imgs, ok := gen["generated_images"].([]interface{}); ok {
-						for _, img := range imgs {
-							if im, ok := img.(map[string]interface{}); ok {
-								if u, ok := im["url"].(string); ok {
-									item.Images = append(item.Images, u)
-								}
-							}
-						}
-					}
-					result.Generations = append(result.Generations, item)
-				}
-			}
-		}
+	for _, gen := range decoded.Generations {
+		result.Generations = append(result.Generations, gen.toListItem())
 	}
 	return result, nil
 }
@@ -305,73 +502,582 @@ func (c *APIClient) ListGenerations(userID string, offset, limit int) (domain.Ge
 // response body to destPath.  No Authorization header is sent because the
 // URL is a public CDN link, not a Leonardo API endpoint.
 func (c *APIClient) DownloadImage(url, destPath string) error {
-	httpReq, err := http.NewRequest("GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(c.ctx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
+	start := time.Now()
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
+		c.observe(httpReq, 0, "", domain.RateLimit{}, time.Since(start))
 		return fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
+		c.observe(httpReq, resp.StatusCode, requestIDFrom(resp.Header), domain.RateLimit{}, time.Since(start))
 		return fmt.Errorf("download returned status %d", resp.StatusCode)
 	}
 	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	c.observe(httpReq, resp.StatusCode, requestIDFrom(resp.Header), domain.RateLimit{}, time.Since(start))
 	if err != nil {
 		return fmt.Errorf("reading response: %w", err)
 	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
 	if err := os.WriteFile(destPath, bodyBytes, 0644); err != nil {
 		return fmt.Errorf("writing file: %w", err)
 	}
 	return nil
 }
 
+// StreamImage implements the LeonardoClient interface.  Like DownloadImage
+// it sends no Authorization header, but it copies the response body to w as
+// it arrives instead of buffering it, so nothing touches the disk.  When the
+// copy fails part of the image may already have been written.
+func (c *APIClient) StreamImage(url string, w io.Writer) error {
+	httpReq, err := http.NewRequestWithContext(c.ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	start := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.observe(httpReq, 0, "", domain.RateLimit{}, time.Since(start))
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		c.observe(httpReq, resp.StatusCode, requestIDFrom(resp.Header), domain.RateLimit{}, time.Since(start))
+		return fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+	_, err = io.Copy(w, resp.Body)
+	c.observe(httpReq, resp.StatusCode, requestIDFrom(resp.Header), domain.RateLimit{}, time.Since(start))
+	if err != nil {
+		return fmt.Errorf("streaming image: %w", err)
+	}
+	return nil
+}
+
+// FetchRecipe implements the RecipeSource interface.  Like DownloadImage
+// it sends no Authorization header: the URL is wherever someone shared the
+// recipe, and the token must never leave for a third party.  Bodies larger
+// than domain.MaxRecipeSize are refused.
+func (c *APIClient) FetchRecipe(url string) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(c.ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	start := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.observe(httpReq, 0, "", domain.RateLimit{}, time.Since(start))
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, domain.MaxRecipeSize+1))
+	c.observe(httpReq, resp.StatusCode, requestIDFrom(resp.Header), domain.RateLimit{}, time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("fetching recipe returned status %d", resp.StatusCode)
+	}
+	if len(body) > domain.MaxRecipeSize {
+		return nil, fmt.Errorf("recipe is larger than %d bytes", domain.MaxRecipeSize)
+	}
+	return body, nil
+}
+
 // ListPlatformModels implements the LeonardoClient interface.  It issues a
 // GET request to the /platformModels endpoint to retrieve the list of public
 // platform models available for image generation.
 func (c *APIClient) ListPlatformModels() (domain.PlatformModelResponse, error) {
-	httpReq, err := http.NewRequest("GET", "https://cloud.leonardo.ai/api/rest/v1/platformModels", nil)
+	httpReq, err := c.newRequest("GET", "https://cloud.leonardo.ai/api/rest/v1/platformModels", nil)
 	if err != nil {
-		return domain.PlatformModelResponse{}, fmt.Errorf("creating request: %w", err)
+		return domain.PlatformModelResponse{}, err
 	}
-	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.PlatformModelResponse{Raw: bodyBytes}, err
+	}
+	var decoded platformModelsResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.PlatformModelResponse{Raw: bodyBytes}, err
+	}
+	result := domain.PlatformModelResponse{Raw: bodyBytes}
+	for _, model := range decoded.CustomModels {
+		result.Models = append(result.Models, domain.PlatformModel{
+			ID:          model.ID,
+			Name:        model.Name,
+			Description: model.Description,
+			SDVersion:   model.SDVersion,
+		})
+	}
+	return result, nil
+}
+
+// UpscaleImage implements the LeonardoClient interface.  It issues a POST to
+// the /variations/upscale endpoint for a generated image and returns the ID
+// of the upscale job.  The raw JSON is always included in the result.
+func (c *APIClient) UpscaleImage(imageID string) (domain.VariationJob, error) {
+	payload, err := json.Marshal(map[string]string{"id": imageID})
+	if err != nil {
+		return domain.VariationJob{}, fmt.Errorf("marshalling request: %w", err)
+	}
+	httpReq, err := c.newRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/variations/upscale", payload)
+	if err != nil {
+		return domain.VariationJob{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.VariationJob{Raw: bodyBytes}, err
+	}
+	var decoded upscaleResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.VariationJob{Raw: bodyBytes}, err
+	}
+	return domain.VariationJob{ID: decoded.SDUpscaleJob.ID, Raw: bodyBytes}, nil
+}
+
+// UniversalUpscale implements the LeonardoClient interface.  It issues a
+// POST to the /variations/universal-upscaler endpoint for a generated image
+// and returns the ID of the upscale job.  The raw JSON is always included in
+// the result.
+func (c *APIClient) UniversalUpscale(imageID string, multiplier float64) (domain.VariationJob, error) {
+	payload, err := json.Marshal(map[string]interface{}{"generatedImageId": imageID, "upscaleMultiplier": multiplier})
+	if err != nil {
+		return domain.VariationJob{}, fmt.Errorf("marshalling request: %w", err)
+	}
+	httpReq, err := c.newRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/variations/universal-upscaler", payload)
+	if err != nil {
+		return domain.VariationJob{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.VariationJob{Raw: bodyBytes}, err
+	}
+	var decoded universalUpscalerResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.VariationJob{Raw: bodyBytes}, err
+	}
+	if decoded.UniversalUpscaler.ID == "" {
+		return domain.VariationJob{Raw: bodyBytes}, fmt.Errorf("response holds no universal upscaler job")
+	}
+	return domain.VariationJob{ID: decoded.UniversalUpscaler.ID, Raw: bodyBytes}, nil
+}
+
+// CreateVariation implements the LeonardoClient interface.  It issues a POST
+// to the /variations/{kind} endpoint for a generated image and returns the
+// ID of the job.  The raw JSON is always included in the result.
+func (c *APIClient) CreateVariation(kind domain.VariationKind, imageID string) (domain.VariationJob, error) {
+	body := map[string]interface{}{"id": imageID}
+	if kind == domain.VariationUnzoom {
+		body["isVariation"] = false
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return domain.VariationJob{}, fmt.Errorf("marshalling request: %w", err)
+	}
+	httpReq, err := c.newRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/variations/"+string(kind), payload)
+	if err != nil {
+		return domain.VariationJob{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.VariationJob{Raw: bodyBytes}, err
+	}
+	var decoded variationJobResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.VariationJob{Raw: bodyBytes}, err
+	}
+	for _, job := range []*variationJobRecord{decoded.SDUpscaleJob, decoded.SDNobgJob, decoded.SDUnzoomJob} {
+		if job != nil && job.ID != "" {
+			return domain.VariationJob{ID: job.ID, Raw: bodyBytes}, nil
+		}
+	}
+	return domain.VariationJob{Raw: bodyBytes}, fmt.Errorf("response holds no %s job", kind)
+}
+
+// GetVariation implements the LeonardoClient interface.  It issues a GET
+// request to the /variations/{id} endpoint and returns the variation, which
+// carries a URL once its status is COMPLETE.
+func (c *APIClient) GetVariation(id string) (domain.ImageVariation, error) {
+	url := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/variations/%s", id)
+	httpReq, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return domain.ImageVariation{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.ImageVariation{}, err
+	}
+	var decoded variationResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.ImageVariation{}, err
+	}
+	if len(decoded.Variations) == 0 {
+		return domain.ImageVariation{ID: id}, nil
+	}
+	v := decoded.Variations[0]
+	return domain.ImageVariation{ID: v.ID, URL: v.URL, Status: v.Status, TransformType: v.TransformType}, nil
+}
+
+// initImageCDN is the base URL init images and 3D models are served from
+// once uploaded.
+const initImageCDN = "https://cdn.leonardo.ai/"
+
+// UploadInitImage implements the InitImageClient interface.  It asks the API
+// for a presigned upload with a POST to /init-image, then posts the file to
+// the returned URL as multipart form data along with the presigned fields.
+// The raw JSON of the first call is included in the returned InitImage.
+func (c *APIClient) UploadInitImage(path string) (domain.InitImage, error) {
+	ext, err := domain.InitImageExtension(path)
+	if err != nil {
+		return domain.InitImage{}, err
+	}
+	payload, err := json.Marshal(map[string]string{"extension": ext})
+	if err != nil {
+		return domain.InitImage{}, fmt.Errorf("marshalling request: %w", err)
+	}
+	httpReq, err := c.newRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/init-image", payload)
+	if err != nil {
+		return domain.InitImage{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.InitImage{Raw: bodyBytes}, err
+	}
+	var decoded uploadInitImageResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.InitImage{Raw: bodyBytes}, err
+	}
+	if decoded.Upload == nil || decoded.Upload.ID == "" || decoded.Upload.URL == "" {
+		return domain.InitImage{Raw: bodyBytes}, fmt.Errorf("decoding response: no upload returned")
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(decoded.Upload.Fields), &fields); err != nil {
+		return domain.InitImage{Raw: bodyBytes}, fmt.Errorf("decoding upload fields: %w", err)
+	}
+	if err := c.uploadForm(decoded.Upload.URL, fields, path); err != nil {
+		return domain.InitImage{Raw: bodyBytes}, err
+	}
+	return domain.InitImage{
+		ID:       decoded.Upload.ID,
+		URL:      initImageCDN + decoded.Upload.Key,
+		FileName: filepath.Base(path),
+		Raw:      bodyBytes,
+	}, nil
+}
+
+// uploadForm posts the file at path to a presigned storage URL.  The
+// storage service authenticates the upload through the form fields, so no
+// Authorization header is sent.
+func (c *APIClient) uploadForm(url string, fields map[string]string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := form.WriteField(k, fields[k]); err != nil {
+			return fmt.Errorf("building upload: %w", err)
+		}
+	}
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("building upload: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return fmt.Errorf("building upload: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(c.ctx, "POST", url, &body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", form.FormDataContentType())
+	start := time.Now()
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return domain.PlatformModelResponse{}, fmt.Errorf("executing request: %w", err)
+		c.observe(httpReq, 0, "", domain.RateLimit{}, time.Since(start))
+		return fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	c.observe(httpReq, resp.StatusCode, requestIDFrom(resp.Header), domain.RateLimit{}, time.Since(start))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("upload returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetInitImage implements the InitImageClient interface.  It issues a GET
+// request to the /init-image/{id} endpoint.  The raw JSON is always included
+// in the returned InitImage.
+func (c *APIClient) GetInitImage(id string) (domain.InitImage, error) {
+	url := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/init-image/%s", id)
+	httpReq, err := c.newRequest("GET", url, nil)
 	if err != nil {
-		return domain.PlatformModelResponse{}, fmt.Errorf("reading response: %w", err)
+		return domain.InitImage{}, err
 	}
-	if resp.StatusCode >= 300 {
-		return domain.PlatformModelResponse{Raw: bodyBytes}, fmt.Errorf("API returned status %d", resp.StatusCode)
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.InitImage{Raw: bodyBytes}, err
 	}
-	result := domain.PlatformModelResponse{Raw: bodyBytes}
-	var decoded map[string]interface{}
-	if err := json.Unmarshal(bodyBytes, &decoded); err == nil {
-		if models, ok := decoded["custom_models"].([]interface{}); ok {
-			for _, m := range models {
-				if model, ok := m.(map[string]interface{}); ok {
-					item := domain.PlatformModel{}
-					if id, ok := model["id"].(string); ok {
-						item.ID = id
-					}
-					if name, ok := model["name"].(string); ok {
-						item.Name = name
-					}
-					if desc, ok := model["description"].(string); ok {
-						item.Description = desc
-					}
-					result.Models = append(result.Models, item)
-				}
-			}
-		}
+	var decoded initImageResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.InitImage{Raw: bodyBytes}, err
+	}
+	image := domain.InitImage{Raw: bodyBytes}
+	if decoded.InitImage != nil {
+		image.ID = decoded.InitImage.ID
+		image.URL = decoded.InitImage.URL
+		image.CreatedAt = parseTimestamp(decoded.InitImage.CreatedAt)
+	}
+	return image, nil
+}
+
+// DeleteInitImage implements the InitImageClient interface.  It issues a
+// DELETE request to the /init-image/{id} endpoint.  The raw JSON is always
+// included in the returned DeleteResponse.
+func (c *APIClient) DeleteInitImage(id string) (domain.DeleteResponse, error) {
+	url := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/init-image/%s", id)
+	httpReq, err := c.newRequest("DELETE", url, nil)
+	if err != nil {
+		return domain.DeleteResponse{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.DeleteResponse{Raw: bodyBytes}, err
+	}
+	var decoded deleteInitImageResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.DeleteResponse{Raw: bodyBytes}, err
+	}
+	result := domain.DeleteResponse{Raw: bodyBytes}
+	if decoded.Deleted != nil {
+		result.ID = decoded.Deleted.ID
 	}
 	return result, nil
 }
 
-// Ensure APIClient satisfies the LeonardoClient interface at compile time.
-var _ ports.LeonardoClient = (*APIClient)(nil)
+// UploadModel3D implements the Model3DClient interface.  Like
+// UploadInitImage it asks for a presigned upload, with a POST to
+// /models-3d/upload, then posts the file to the returned URL.  The raw JSON
+// of the first call is included in the returned Model3D.
+func (c *APIClient) UploadModel3D(path, name string) (domain.Model3D, error) {
+	ext, err := domain.Model3DExtension(path)
+	if err != nil {
+		return domain.Model3D{}, err
+	}
+	payload, err := json.Marshal(map[string]string{"name": name, "modelExtension": ext})
+	if err != nil {
+		return domain.Model3D{}, fmt.Errorf("marshalling request: %w", err)
+	}
+	httpReq, err := c.newRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/models-3d/upload", payload)
+	if err != nil {
+		return domain.Model3D{}, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return domain.Model3D{Raw: bodyBytes}, err
+	}
+	var decoded uploadModel3DResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return domain.Model3D{Raw: bodyBytes}, err
+	}
+	if decoded.Upload == nil || decoded.Upload.ID == "" || decoded.Upload.URL == "" {
+		return domain.Model3D{Raw: bodyBytes}, fmt.Errorf("decoding response: no upload returned")
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(decoded.Upload.Fields), &fields); err != nil {
+		return domain.Model3D{Raw: bodyBytes}, fmt.Errorf("decoding upload fields: %w", err)
+	}
+	if err := c.uploadForm(decoded.Upload.URL, fields, path); err != nil {
+		return domain.Model3D{Raw: bodyBytes}, err
+	}
+	return domain.Model3D{
+		ID:       decoded.Upload.ID,
+		Name:     name,
+		URL:      initImageCDN + decoded.Upload.Key,
+		FileName: filepath.Base(path),
+		Raw:      bodyBytes,
+	}, nil
+}
+
+// CalculateCost implements the PricingClient interface.  It asks the
+// /pricing-calculator endpoint for the cost of an image generation with the
+// query's settings.  Nothing is generated or charged.
+func (c *APIClient) CalculateCost(query domain.PriceQuery) (int, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"service": "IMAGE_GENERATION",
+		"serviceParams": map[string]interface{}{
+			"IMAGE_GENERATION": map[string]interface{}{
+				"imageWidth":    query.Width,
+				"imageHeight":   query.Height,
+				"numImages":     query.NumImages,
+				"alchemyMode":   query.Alchemy,
+				"isModelCustom": query.CustomModel,
+				"isSDXL":        query.SDXL(),
+				"isPhoenix":     query.Phoenix(),
+			},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("marshalling request: %w", err)
+	}
+	httpReq, err := c.newRequest("POST", "https://cloud.leonardo.ai/api/rest/v1/pricing-calculator", payload)
+	if err != nil {
+		return 0, err
+	}
+	bodyBytes, err := c.send(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	var decoded pricingResponse
+	if err := decodeResponse(bodyBytes, &decoded); err != nil {
+		return 0, err
+	}
+	if decoded.Cost == nil || decoded.Cost.Cost == nil {
+		return 0, fmt.Errorf("decoding response: no cost returned")
+	}
+	return *decoded.Cost.Cost, nil
+}
+
+// SendRaw implements the RawClient interface.  It sends req to the API
+// with the client's credentials and context.  The path must be relative to
+// APIBaseURL, or a full URL under it; the token is never sent to another
+// host.
+func (c *APIClient) SendRaw(req domain.RawRequest) (domain.RawResponse, error) {
+	url, err := rawURL(req.Path)
+	if err != nil {
+		return domain.RawResponse{}, err
+	}
+	httpReq, err := c.newRequest(strings.ToUpper(req.Method), url, req.Body)
+	if err != nil {
+		return domain.RawResponse{}, err
+	}
+	status, body, header, err := c.do(httpReq)
+	return domain.RawResponse{
+		StatusCode: status,
+		Header:     header,
+		Body:       body,
+		RetryAfter: retryAfter(header, time.Now()),
+	}, err
+}
+
+// rawURL returns the URL of an API path given relative to APIBaseURL, with
+// or without its leading slash, or as a full URL under APIBaseURL.
+func rawURL(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, APIBaseURL+"/") || strings.HasPrefix(path, APIBaseURL+"?") {
+		return path, nil
+	}
+	if strings.Contains(path, "://") {
+		return "", fmt.Errorf("%q is not under %s; give the path relative to it, e.g. /me", path, APIBaseURL)
+	}
+	if path == "" || path == "/" {
+		return "", fmt.Errorf("an API path such as /me is required")
+	}
+	return APIBaseURL + "/" + strings.TrimPrefix(path, "/"), nil
+}
+
+// APIVersion is the version of the Leonardo.Ai REST API the client
+// targets, and APIBaseURL the root every endpoint is relative to.
+const (
+	APIVersion = "v1"
+	APIBaseURL = "https://cloud.leonardo.ai/api/rest/" + APIVersion
+)
+
+// Endpoints lists the API endpoints the client calls, relative to
+// APIBaseURL.
+var Endpoints = []string{
+	"POST /generations",
+	"GET /generations/{id}",
+	"DELETE /generations/{id}",
+	"GET /generations/user/{userId}",
+	"GET /me",
+	"GET /platformModels",
+	"POST /variations/upscale",
+	"POST /variations/universal-upscaler",
+	"POST /variations/{unzoom,nobg}",
+	"GET /variations/{id}",
+	"POST /init-image",
+	"GET /init-image/{id}",
+	"DELETE /init-image/{id}",
+	"POST /models-3d/upload",
+	"POST /pricing-calculator",
+}
+
+// CheckCompatibility probes read-only endpoints and confirms each answers
+// with the fields the client decodes, so an API change shows up as a
+// failed check instead of as odd results later.  Nothing is created and
+// no tokens are spent.
+func (c *APIClient) CheckCompatibility() []domain.EndpointCheck {
+	var checks []domain.EndpointCheck
+	me, body := c.probe("GET /me", APIBaseURL+"/me", "user_details")
+	checks = append(checks, me)
+	var user userInfoResponse
+	if me.OK() {
+		if err := decodeResponse(body, &user); err != nil {
+			checks[0].Err = err
+		} else if len(user.UserDetails) == 0 || user.UserDetails[0].User.ID == "" {
+			checks[0].Err = fmt.Errorf("response has no user ID")
+		}
+	}
+	models, _ := c.probe("GET /platformModels", APIBaseURL+"/platformModels", "custom_models")
+	checks = append(checks, models)
+	list := domain.EndpointCheck{Endpoint: "GET /generations/user/{userId}", Err: fmt.Errorf("skipped: no user ID from /me")}
+	if checks[0].OK() {
+		list, _ = c.probe(list.Endpoint, fmt.Sprintf("%s/generations/user/%s?offset=0&limit=1", APIBaseURL, user.UserDetails[0].User.ID), "generations")
+	}
+	return append(checks, list)
+}
+
+// probe GETs url and checks that the JSON response has the given
+// top-level fields, returning the body for further checks.
+func (c *APIClient) probe(endpoint, url string, fields ...string) (domain.EndpointCheck, []byte) {
+	check := domain.EndpointCheck{Endpoint: endpoint}
+	start := time.Now()
+	httpReq, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		check.Err = err
+		return check, nil
+	}
+	body, err := c.send(httpReq)
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Err = err
+		return check, body
+	}
+	var top map[string]json.RawMessage
+	if err := decodeResponse(body, &top); err != nil {
+		check.Err = err
+		return check, body
+	}
+	for _, field := range fields {
+		if _, ok := top[field]; !ok {
+			check.Err = fmt.Errorf("response has no %q field", field)
+		}
+	}
+	return check, body
+}
+
+// Ensure APIClient satisfies the client ports at compile time.
+var (
+	_ ports.LeonardoClient  = (*APIClient)(nil)
+	_ ports.InitImageClient = (*APIClient)(nil)
+	_ ports.Model3DClient   = (*APIClient)(nil)
+	_ ports.PricingClient   = (*APIClient)(nil)
+	_ ports.RawClient       = (*APIClient)(nil)
+	_ ports.RecipeSource    = (*APIClient)(nil)
+)
@@ -1,7 +1,10 @@
 package provider_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"leonardo-cli/internal/domain"
 	"leonardo-cli/internal/provider"
@@ -133,7 +137,7 @@ func TestAPIClient_CreateGeneration_OmitsZeroValueOptionalFields(t *testing.T) {
 	}
 
 	// These optional fields should NOT be present in the payload
-	for _, key := range []string{"modelId", "negative_prompt", "width", "height", "public", "alchemy", "ultra", "styleUUID", "contrast", "guidance_scale", "seed"} {
+	for _, key := range []string{"modelId", "negative_prompt", "width", "height", "public", "alchemy", "ultra", "styleUUID", "contrast", "guidance_scale", "seed", "highResolution", "contrastRatio", "expandedDomain", "highContrast"} {
 		if _, exists := receivedBody[key]; exists {
 			t.Errorf("expected optional field %q to be omitted, but it was present with value %v", key, receivedBody[key])
 		}
@@ -167,6 +171,115 @@ func TestAPIClient_CreateGeneration_ReturnsErrorOnNon2xxStatus(t *testing.T) {
 	}
 }
 
+func TestAPIClient_CreateGeneration_ReturnsClassifiableAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"rate limit exceeded"}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+
+	_, err := client.CreateGeneration(domain.GenerationRequest{
+		Metadata: domain.GenerationMetadata{Prompt: "test"},
+	})
+	var apiErr *domain.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *domain.APIError, got %T (%v)", err, err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, apiErr.StatusCode)
+	}
+	if got := domain.ClassifyFailure(err); got != domain.FailureRateLimit {
+		t.Errorf("expected failure class %q, got %q", domain.FailureRateLimit, got)
+	}
+}
+
+func TestAPIClient_APIErrorCarriesRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"bad request"}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+
+	_, err := client.GetUserInfo()
+	var apiErr *domain.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *domain.APIError, got %T (%v)", err, err)
+	}
+	if apiErr.RequestID != "req-123" {
+		t.Errorf("expected request ID %q, got %q", "req-123", apiErr.RequestID)
+	}
+	if !strings.Contains(err.Error(), "request ID req-123") {
+		t.Errorf("expected error to mention the request ID, got %q", err.Error())
+	}
+}
+
+func TestAPIClient_ReportsCallMetricsToObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Requestid", "amzn-456")
+		w.Write([]byte(`{"generations":[]}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+	var metrics []domain.CallMetric
+	client.SetObserver(func(m domain.CallMetric) { metrics = append(metrics, m) })
+
+	if _, err := client.ListGenerations("user-1", 0, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metrics) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(metrics))
+	}
+	m := metrics[0]
+	if m.Method != "GET" || m.Path != "/api/rest/v1/generations/user/user-1" {
+		t.Errorf("unexpected call %s %s", m.Method, m.Path)
+	}
+	if m.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", m.StatusCode)
+	}
+	if m.RequestID != "amzn-456" {
+		t.Errorf("expected request ID %q, got %q", "amzn-456", m.RequestID)
+	}
+	if m.Duration <= 0 {
+		t.Errorf("expected a positive duration, got %s", m.Duration)
+	}
+}
+
+func TestAPIClient_GetUserInfo_RejectedTokensAreClassified(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected domain.TokenState
+	}{
+		{"invalid", http.StatusUnauthorized, `{"error":"Invalid token"}`, domain.TokenInvalid},
+		{"expired", http.StatusUnauthorized, `{"error":"token has expired"}`, domain.TokenExpired},
+		{"forbidden", http.StatusForbidden, `{"error":"forbidden"}`, domain.TokenInvalid},
+		{"server error", http.StatusInternalServerError, `{"error":"token service down"}`, domain.TokenUnverified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := newClientWithBaseURL("key", server.URL)
+
+			_, err := client.GetUserInfo()
+			if got := domain.ClassifyTokenError(err); got != tt.expected {
+				t.Errorf("expected token state %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestAPIClient_CreateGeneration_IncludesAllOptionalFields(t *testing.T) {
 	var receivedBody map[string]interface{}
 
@@ -194,12 +307,32 @@ func TestAPIClient_CreateGeneration_IncludesAllOptionalFields(t *testing.T) {
 			StyleUUID:      "style-123",
 			Contrast:       2.5,
 			GuidanceScale:  8.0,
+			HighResolution: true,
+			ContrastRatio:  0.4,
+			ExpandedDomain: true,
+			HighContrast:   true,
+			PhotoReal:      true,
+			Elements:       []domain.GenerationElement{{ID: "element-1", Weight: 0.6}},
 		},
 	}
 	_, err := client.CreateGeneration(req)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if elements, _ := json.Marshal(receivedBody["elements"]); string(elements) != `[{"akUUID":"element-1","weight":0.6}]` {
+		t.Errorf("expected the Element with its weight, got %s", elements)
+	}
+	if receivedBody["photoRealVersion"] != "v2" {
+		t.Errorf("expected photoRealVersion v2 with a model, got %v", receivedBody["photoRealVersion"])
+	}
+	for _, key := range []string{"highResolution", "expandedDomain", "highContrast", "photoReal"} {
+		if receivedBody[key] != true {
+			t.Errorf("expected %s true, got %v", key, receivedBody[key])
+		}
+	}
+	if receivedBody["contrastRatio"] != 0.4 {
+		t.Errorf("expected contrastRatio 0.4, got %v", receivedBody["contrastRatio"])
+	}
 
 	if receivedBody["ultra"] != true {
 		t.Errorf("expected ultra true, got %v", receivedBody["ultra"])
@@ -339,6 +472,46 @@ func TestAPIClient_GetGenerationStatus_ReturnsErrorOnNon2xxStatus(t *testing.T)
 	}
 }
 
+func TestAPIClient_GetGenerationStatus_ReportsUnexpectedResponseShape(t *testing.T) {
+	body := `{"generations_by_pk":{"status":"COMPLETE","generated_images":"not-a-list"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+
+	status, err := client.GetGenerationStatus("gen-odd")
+	if err == nil {
+		t.Fatal("expected error for unexpected response shape, got nil")
+	}
+	if !strings.Contains(err.Error(), "generated_images") {
+		t.Errorf("expected error to name the offending field, got %q", err.Error())
+	}
+	if string(status.Raw) != body {
+		t.Errorf("expected raw body to be preserved, got %q", string(status.Raw))
+	}
+}
+
+func TestAPIClient_GetGenerationStatus_ToleratesMissingGeneration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"generations_by_pk":null}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+
+	status, err := client.GetGenerationStatus("gen-gone")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Status != "" || len(status.Images) != 0 {
+		t.Errorf("expected empty status, got %+v", status)
+	}
+}
+
 func TestAPIClient_GetGenerationStatus_ReturnsRawResponseAlways(t *testing.T) {
 	expectedJSON := `{"generations_by_pk":{"status":"COMPLETE","custom_field":"extra"}}`
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -360,6 +533,91 @@ func TestAPIClient_GetGenerationStatus_ReturnsRawResponseAlways(t *testing.T) {
 
 // --- Behavior: Deleting a generation via HTTP ---
 
+// --- Behavior: Getting a full generation record via HTTP ---
+
+func TestAPIClient_GetGeneration_DecodesFullRecord(t *testing.T) {
+	var receivedMethod, receivedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedMethod = r.Method
+		receivedPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"generations_by_pk":{
+			"id":"gen-full","status":"COMPLETE","createdAt":"2026-02-26T10:00:00.000",
+			"prompt":"a castle","negativePrompt":"blurry","modelId":"model-1",
+			"scheduler":"LEONARDO","presetStyle":"CINEMATIC","sdVersion":"SDXL_0_9",
+			"imageWidth":1024,"imageHeight":768,"inferenceSteps":30,"seed":42,
+			"guidanceScale":7,"initStrength":0.4,"initImageId":"init-1",
+			"public":false,"photoReal":true,"ultra":true,"promptMagic":false,
+			"generation_elements":[{"id":5,"weightApplied":0.8,"lora":{"akUUID":"el-1","name":"Crystal"}}],
+			"generated_images":[{"id":"img-1","url":"https://cdn.leonardo.ai/1.png","nsfw":true,"likeCount":3,
+				"generated_image_variation_generics":[{"id":"var-1","url":"https://cdn.leonardo.ai/1-up.png","status":"COMPLETE","transformType":"UPSCALE"}]}]
+		}}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+
+	detail, err := client.GetGeneration("gen-full")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receivedMethod != "GET" || receivedPath != "/api/rest/v1/generations/gen-full" {
+		t.Errorf("expected GET /api/rest/v1/generations/gen-full, got %s %s", receivedMethod, receivedPath)
+	}
+	if detail.ID != "gen-full" || detail.Status != "COMPLETE" || detail.Prompt != "a castle" {
+		t.Errorf("unexpected identity fields: %+v", detail)
+	}
+	if detail.Scheduler != "LEONARDO" || detail.PresetStyle != "CINEMATIC" || detail.SDVersion != "SDXL_0_9" {
+		t.Errorf("unexpected model settings: scheduler %q, preset %q, sd %q", detail.Scheduler, detail.PresetStyle, detail.SDVersion)
+	}
+	if detail.Width != 1024 || detail.Height != 768 || detail.InferenceSteps != 30 || detail.Seed != 42 {
+		t.Errorf("unexpected numeric fields: %dx%d steps %d seed %d", detail.Width, detail.Height, detail.InferenceSteps, detail.Seed)
+	}
+	if detail.InitImageID != "init-1" || detail.InitStrength != 0.4 {
+		t.Errorf("unexpected init image: %q at %v", detail.InitImageID, detail.InitStrength)
+	}
+	if !detail.PhotoReal || !detail.Ultra || detail.Public {
+		t.Errorf("unexpected flags: photoReal %v ultra %v public %v", detail.PhotoReal, detail.Ultra, detail.Public)
+	}
+	if detail.CreatedAt.IsZero() {
+		t.Error("expected createdAt to be parsed")
+	}
+	if len(detail.Elements) != 1 || detail.Elements[0].Name != "Crystal" || detail.Elements[0].Weight != 0.8 {
+		t.Errorf("unexpected elements: %+v", detail.Elements)
+	}
+	if len(detail.Images) != 1 {
+		t.Fatalf("expected 1 image, got %d", len(detail.Images))
+	}
+	img := detail.Images[0]
+	if img.ID != "img-1" || !img.NSFW || img.LikeCount != 3 {
+		t.Errorf("unexpected image: %+v", img)
+	}
+	if len(img.Variations) != 1 || img.Variations[0].TransformType != "UPSCALE" {
+		t.Errorf("unexpected variations: %+v", img.Variations)
+	}
+}
+
+func TestAPIClient_GetGeneration_ReturnsErrorOnNon2xxStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+
+	detail, err := client.GetGeneration("missing")
+	if err == nil {
+		t.Fatal("expected error for 404 status, got nil")
+	}
+	if !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected error to mention status 404, got %q", err.Error())
+	}
+	if string(detail.Raw) != `{"error":"not found"}` {
+		t.Errorf("expected raw body to be preserved, got %q", string(detail.Raw))
+	}
+}
+
 func TestAPIClient_DeleteGeneration_SendsCorrectHTTPRequest(t *testing.T) {
 	var receivedMethod, receivedPath string
 	var receivedHeaders http.Header
@@ -604,6 +862,39 @@ func TestAPIClient_ListGenerations_ParsesMultipleGenerations(t *testing.T) {
 	}
 }
 
+func TestAPIClient_ListGenerations_ParsesCreatedAtTimestamps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"generations":[
+				{"id":"gen-1","createdAt":"2026-02-26T10:00:00.000Z"},
+				{"id":"gen-2","createdAt":"2026-02-26T11:30:15.123"},
+				{"id":"gen-3","createdAt":"not a date"}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+
+	resp, err := client.ListGenerations("user-1", 0, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Generations) != 3 {
+		t.Fatalf("expected 3 generations, got %d", len(resp.Generations))
+	}
+	if want := time.Date(2026, 2, 26, 10, 0, 0, 0, time.UTC); !resp.Generations[0].CreatedAt.Equal(want) {
+		t.Errorf("expected createdAt %v, got %v", want, resp.Generations[0].CreatedAt)
+	}
+	if want := time.Date(2026, 2, 26, 11, 30, 15, 123000000, time.UTC); !resp.Generations[1].CreatedAt.Equal(want) {
+		t.Errorf("expected zone-less createdAt to be read as UTC %v, got %v", want, resp.Generations[1].CreatedAt)
+	}
+	if !resp.Generations[2].CreatedAt.IsZero() {
+		t.Errorf("expected unparseable createdAt to be zero, got %v", resp.Generations[2].CreatedAt)
+	}
+}
+
 func TestAPIClient_ListGenerations_ReturnsErrorOnNon2xxStatus(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusForbidden)
@@ -747,6 +1038,47 @@ func TestAPIClient_DownloadImage_UsesGETMethod(t *testing.T) {
 	}
 }
 
+func TestAPIClient_StreamImage_CopiesTheBodyWithoutTheAuthHeader(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte("image-bytes"))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("secret-api-key", server.URL)
+	var out strings.Builder
+
+	if err := client.StreamImage(server.URL+"/img.png", &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "image-bytes" {
+		t.Errorf("expected the image bytes, got %q", out.String())
+	}
+	if auth != "" {
+		t.Errorf("expected no Authorization header for image download, got %q", auth)
+	}
+}
+
+func TestAPIClient_StreamImage_WritesNothingOnNon2xxStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+	defer server.Close()
+
+	client := newClientWithBaseURL("key", server.URL)
+	var out strings.Builder
+
+	err := client.StreamImage(server.URL+"/missing.png", &out)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected error mentioning status 404, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing written, got %q", out.String())
+	}
+}
+
 // --- Behavior: Listing platform models via HTTP ---
 
 func TestAPIClient_ListPlatformModels_SendsCorrectHTTPRequest(t *testing.T) {
@@ -758,7 +1090,7 @@ func TestAPIClient_ListPlatformModels_SendsCorrectHTTPRequest(t *testing.T) {
 		receivedPath = r.URL.Path
 		receivedHeaders = r.Header
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"custom_models":[{"id":"model-1","name":"Leonardo Diffusion","description":"General purpose model"}]}`))
+		w.Write([]byte(`{"custom_models":[{"id":"model-1","name":"Leonardo Diffusion","description":"General purpose model","sdVersion":"SDXL_1_0"}]}`))
 	}))
 	defer server.Close()
 
@@ -790,6 +1122,9 @@ func TestAPIClient_ListPlatformModels_SendsCorrectHTTPRequest(t *testing.T) {
 	if resp.Models[0].Description != "General purpose model" {
 		t.Errorf("expected model description %q, got %q", "General purpose model", resp.Models[0].Description)
 	}
+	if resp.Models[0].SDVersion != "SDXL_1_0" {
+		t.Errorf("expected SD version %q, got %q", "SDXL_1_0", resp.Models[0].SDVersion)
+	}
 }
 
 func TestAPIClient_ListPlatformModels_ParsesMultipleModels(t *testing.T) {
@@ -879,6 +1214,304 @@ func TestAPIClient_UsesDefaultHTTPClientWhenNilProvided(t *testing.T) {
 	}
 }
 
+// --- Behavior: Waiting for a generation ---
+
+func TestAPIClient_WaitForCompletion_BacksOffUntilComplete(t *testing.T) {
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		status := "PENDING"
+		if len(times) == 4 {
+			status = "COMPLETE"
+		}
+		w.Write([]byte(`{"generations_by_pk":{"status":"` + status + `"}}`))
+	}))
+	defer server.Close()
+	var polled []string
+
+	status, err := newClientWithBaseURL("test-key", server.URL).WaitForCompletion(context.Background(), "gen-1", domain.WaitOptions{
+		Interval: 40 * time.Millisecond,
+		OnPoll: func(s domain.GenerationStatus, attempt int) {
+			polled = append(polled, fmt.Sprintf("%d:%s", attempt, s.Status))
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Status != "COMPLETE" || strings.Join(polled, ",") != "1:PENDING,2:PENDING,3:PENDING,4:COMPLETE" {
+		t.Errorf("unexpected status %q after polls %v", status.Status, polled)
+	}
+	if first, third := times[1].Sub(times[0]), times[3].Sub(times[2]); third <= first {
+		t.Errorf("expected checks to slow down, got gaps %s then %s", first, third)
+	}
+}
+
+func TestAPIClient_WaitForCompletion_HonoursRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"generations_by_pk":{"status":"COMPLETE"}}`))
+	}))
+	defer server.Close()
+	start := time.Now()
+
+	status, err := newClientWithBaseURL("test-key", server.URL).WaitForCompletion(context.Background(), "gen-1", domain.WaitOptions{Interval: time.Millisecond})
+
+	if err != nil || status.Status != "COMPLETE" {
+		t.Fatalf("expected the rate limit to be ridden out, got %q, %v", status.Status, err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait the advised second, waited %s", elapsed)
+	}
+}
+
+func TestAPIClient_WaitForCompletion_StopsOnTimeoutCancelAndPermanentErrors(t *testing.T) {
+	pending := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"generations_by_pk":{"status":"PENDING"}}`))
+	}))
+	defer pending.Close()
+	client := newClientWithBaseURL("test-key", pending.URL)
+
+	status, err := client.WaitForCompletion(context.Background(), "gen-slow", domain.WaitOptions{Interval: 5 * time.Millisecond, Timeout: 30 * time.Millisecond})
+	if err == nil || status.Status != "PENDING" || !strings.Contains(err.Error(), "timed out after 30ms waiting for generation gen-slow (status PENDING)") {
+		t.Errorf("expected a timeout with the last status, got %q, %v", status.Status, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := client.WaitForCompletion(ctx, "gen-slow", domain.WaitOptions{Interval: 5 * time.Millisecond}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+
+	calls := 0
+	forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer forbidden.Close()
+	_, err = newClientWithBaseURL("test-key", forbidden.URL).WaitForCompletion(context.Background(), "gen-1", domain.WaitOptions{Interval: time.Millisecond})
+	var apiErr *domain.APIError
+	if !errors.As(err, &apiErr) || calls != 1 {
+		t.Errorf("expected the 403 to end the wait at once, got %v after %d calls", err, calls)
+	}
+}
+
+// --- Behavior: Checking API compatibility ---
+
+func TestAPIClient_CheckCompatibility_PassesWhenEndpointsAnswerAsExpected(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/api/rest/v1/me":
+			w.Write([]byte(`{"user_details":[{"user":{"id":"user-1"}}]}`))
+		case "/api/rest/v1/platformModels":
+			w.Write([]byte(`{"custom_models":[]}`))
+		default:
+			w.Write([]byte(`{"generations":[]}`))
+		}
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("test-key", server.URL)
+
+	checks := client.CheckCompatibility()
+
+	if !domain.Compatible(checks) {
+		t.Fatalf("expected every check to pass, got %+v", checks)
+	}
+	want := "GET /api/rest/v1/me,GET /api/rest/v1/platformModels,GET /api/rest/v1/generations/user/user-1?offset=0&limit=1"
+	if got := strings.Join(paths, ","); got != want {
+		t.Errorf("expected probes %q, got %q", want, got)
+	}
+}
+
+func TestAPIClient_CheckCompatibility_FailsOnMissingFieldsAndErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/rest/v1/me":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`{"models":[]}`))
+		}
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("bad-key", server.URL)
+
+	checks := client.CheckCompatibility()
+
+	if domain.Compatible(checks) || len(checks) != 3 {
+		t.Fatalf("expected three failed checks, got %+v", checks)
+	}
+	for i, want := range []string{"status 401", `no "custom_models" field`, "skipped"} {
+		if checks[i].OK() || !strings.Contains(checks[i].Err.Error(), want) {
+			t.Errorf("expected check %d to fail with %q, got %v", i, want, checks[i].Err)
+		}
+	}
+}
+
+// --- Behavior: Sending raw requests ---
+
+func TestAPIClient_SendRaw_SendsSignedRequestUnderBaseURL(t *testing.T) {
+	var got, auth, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Method + " " + r.URL.RequestURI()
+		auth = r.Header.Get("Authorization")
+		contentType = r.Header.Get("Content-Type")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("test-key", server.URL)
+
+	resp, err := client.SendRaw(domain.RawRequest{Method: "post", Path: "generations?x=1", Body: []byte(`{"prompt":"cat"}`)})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "POST /api/rest/v1/generations?x=1" {
+		t.Errorf("expected POST /api/rest/v1/generations?x=1, got %q", got)
+	}
+	if auth != "Bearer test-key" || contentType != "application/json" || body != `{"prompt":"cat"}` {
+		t.Errorf("unexpected request: auth %q, content type %q, body %q", auth, contentType, body)
+	}
+	if resp.StatusCode != http.StatusCreated || string(resp.Body) != `{"ok":true}` {
+		t.Errorf("unexpected response %d %s", resp.StatusCode, resp.Body)
+	}
+}
+
+func TestAPIClient_SendRaw_ReturnsErrorResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"slow down"}`))
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("test-key", server.URL)
+
+	resp, err := client.SendRaw(domain.RawRequest{Method: "GET", Path: provider.APIBaseURL + "/me"})
+
+	var apiErr *domain.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected a 429 APIError, got %v", err)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests || string(resp.Body) != `{"error":"slow down"}` || resp.RetryAfter != 7*time.Second {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestAPIClient_SendRaw_RefusesOtherHosts(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("test-key", server.URL)
+
+	for _, path := range []string{"https://example.com/me", "https://cloud.leonardo.ai/api/rest/v2/me", ""} {
+		if _, err := client.SendRaw(domain.RawRequest{Method: "GET", Path: path}); err == nil {
+			t.Errorf("expected %q to be refused", path)
+		}
+	}
+	if called {
+		t.Error("expected no request to be sent")
+	}
+}
+
+func TestAPIClient_FetchRecipe_SendsNoCredentials(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"prompt":"a fox"}`))
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("secret-key", server.URL)
+
+	body, err := client.FetchRecipe(server.URL + "/recipes/fox.json")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != `{"prompt":"a fox"}` {
+		t.Errorf("unexpected body %q", body)
+	}
+	if auth != "" {
+		t.Errorf("expected no Authorization header, got %q", auth)
+	}
+}
+
+func TestAPIClient_FetchRecipe_RefusesOversizedBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", domain.MaxRecipeSize+1)))
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("key", server.URL)
+
+	if _, err := client.FetchRecipe(server.URL + "/huge.json"); err == nil {
+		t.Fatal("expected an error for a recipe larger than the limit")
+	}
+}
+
+func TestGenerationPayload_MatchesTheBodyCreateGenerationSends(t *testing.T) {
+	var sent []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"sdGenerationJob":{"generationId":"gen-1"}}`))
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("key", server.URL)
+	req := domain.GenerationRequest{NumImages: 2, Private: true, Metadata: domain.GenerationMetadata{
+		Prompt:   "a fox",
+		ModelID:  "m-1",
+		Width:    512,
+		Height:   768,
+		Alchemy:  true,
+		Elements: []domain.GenerationElement{{ID: "el-1", Weight: 0.5}},
+	}}
+
+	payload, err := provider.GenerationPayload(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.CreateGeneration(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(payload) != string(sent) {
+		t.Errorf("payload %s differs from the body sent %s", payload, sent)
+	}
+}
+
+func TestGenerationPayload_AddsParamsOverridingBuiltInFields(t *testing.T) {
+	req := domain.GenerationRequest{
+		Metadata: domain.GenerationMetadata{Prompt: "a fox", Width: 512},
+		Params: map[string]json.RawMessage{
+			"transparency": json.RawMessage(`"foreground_only"`),
+			"width":        json.RawMessage(`640`),
+		},
+	}
+
+	payload, err := provider.GenerationPayload(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if body["transparency"] != "foreground_only" || body["width"] != float64(640) || body["prompt"] != "a fox" {
+		t.Errorf("unexpected payload %s", payload)
+	}
+}
+
 // newClientWithBaseURL creates an APIClient that targets a test server instead
 // of the real Leonardo API. It does this by using a custom http.Transport that
 // rewrites request URLs to point at the test server.
@@ -903,3 +1536,298 @@ func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	req.URL.Host = host
 	return http.DefaultTransport.RoundTrip(req)
 }
+
+func TestAPIClient_UploadInitImage_RequestsPresignedUploadAndPostsFile(t *testing.T) {
+	var uploaded, key, extension string
+	var uploadAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/rest/v1/init-image":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			extension = body["extension"]
+			w.Write([]byte(`{"uploadInitImage":{"id":"init-1","fields":"{\"key\":\"u/init-1.png\",\"policy\":\"p\"}","key":"u/init-1.png","url":"https://uploads.example/bucket"}}`))
+		case "/bucket":
+			uploadAuth = r.Header.Get("Authorization")
+			key = r.FormValue("key")
+			file, _, err := r.FormFile("file")
+			if err == nil {
+				data, _ := ioutil.ReadAll(file)
+				uploaded = string(data)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "ref.PNG")
+	os.WriteFile(path, []byte("png-bytes"), 0644)
+
+	image, err := newClientWithBaseURL("test-key", server.URL).UploadInitImage(path)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if extension != "png" {
+		t.Errorf("expected extension png, got %q", extension)
+	}
+	if key != "u/init-1.png" || uploaded != "png-bytes" {
+		t.Errorf("expected presigned fields and file in the upload, got key %q and body %q", key, uploaded)
+	}
+	if uploadAuth != "" {
+		t.Errorf("expected no Authorization header on the upload, got %q", uploadAuth)
+	}
+	if image.ID != "init-1" || image.URL != "https://cdn.leonardo.ai/u/init-1.png" || image.FileName != "ref.PNG" {
+		t.Errorf("unexpected init image: %+v", image)
+	}
+}
+
+func TestAPIClient_UploadModel3D_RequestsPresignedUploadAndPostsFile(t *testing.T) {
+	var request map[string]string
+	var key, uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/rest/v1/models-3d/upload":
+			json.NewDecoder(r.Body).Decode(&request)
+			w.Write([]byte(`{"uploadModelAsset":{"modelId":"model-1","modelFields":"{\"key\":\"u/model-1.obj\"}","modelKey":"u/model-1.obj","modelUrl":"https://uploads.example/bucket"}}`))
+		case "/bucket":
+			key = r.FormValue("key")
+			file, _, err := r.FormFile("file")
+			if err == nil {
+				data, _ := ioutil.ReadAll(file)
+				uploaded = string(data)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "chest.obj")
+	os.WriteFile(path, []byte("v 0 0 0"), 0644)
+
+	model, err := newClientWithBaseURL("test-key", server.URL).UploadModel3D(path, "Chest")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request["name"] != "Chest" || request["modelExtension"] != "obj" {
+		t.Errorf("unexpected upload request %v", request)
+	}
+	if key != "u/model-1.obj" || uploaded != "v 0 0 0" {
+		t.Errorf("expected presigned fields and file in the upload, got key %q and body %q", key, uploaded)
+	}
+	if model.ID != "model-1" || model.Name != "Chest" || model.FileName != "chest.obj" {
+		t.Errorf("unexpected model: %+v", model)
+	}
+}
+
+func TestAPIClient_CalculateCost_SendsImageGenerationParams(t *testing.T) {
+	var body struct {
+		Service       string                            `json:"service"`
+		ServiceParams map[string]map[string]interface{} `json:"serviceParams"`
+	}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"calculateProductionApiServiceCost":{"cost":24}}`))
+	}))
+	defer server.Close()
+	query := domain.PriceQuery{Width: 1024, Height: 768, NumImages: 4, Alchemy: true, SDVersion: "SDXL_1_0"}
+
+	cost, err := newClientWithBaseURL("test-key", server.URL).CalculateCost(query)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cost != 24 {
+		t.Errorf("expected cost 24, got %d", cost)
+	}
+	params := body.ServiceParams["IMAGE_GENERATION"]
+	if path != "/api/rest/v1/pricing-calculator" || body.Service != "IMAGE_GENERATION" {
+		t.Errorf("unexpected request to %s for %q", path, body.Service)
+	}
+	if params["imageWidth"] != 1024.0 || params["imageHeight"] != 768.0 || params["numImages"] != 4.0 ||
+		params["alchemyMode"] != true || params["isSDXL"] != true || params["isPhoenix"] != false {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestAPIClient_CalculateCost_RejectsResponseWithoutCost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"calculateProductionApiServiceCost":{}}`))
+	}))
+	defer server.Close()
+
+	if _, err := newClientWithBaseURL("test-key", server.URL).CalculateCost(domain.PriceQuery{Width: 512, Height: 512, NumImages: 1}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestAPIClient_DeleteInitImage_ReturnsDeletedID(t *testing.T) {
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Write([]byte(`{"delete_init_images_by_pk":{"id":"init-1"}}`))
+	}))
+	defer server.Close()
+
+	res, err := newClientWithBaseURL("test-key", server.URL).DeleteInitImage("init-1")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != "DELETE" || path != "/api/rest/v1/init-image/init-1" {
+		t.Errorf("expected DELETE /api/rest/v1/init-image/init-1, got %s %s", method, path)
+	}
+	if res.ID != "init-1" {
+		t.Errorf("expected deleted ID init-1, got %q", res.ID)
+	}
+}
+
+func TestAPIClient_UpscaleImage_PostsImageIDAndReturnsJob(t *testing.T) {
+	var body map[string]string
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"sdUpscaleJob":{"id":"job-1","apiCreditCost":5}}`))
+	}))
+	defer server.Close()
+
+	job, err := newClientWithBaseURL("test-key", server.URL).UpscaleImage("img-1")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/api/rest/v1/variations/upscale" || body["id"] != "img-1" {
+		t.Errorf("unexpected request to %s with %v", path, body)
+	}
+	if job.ID != "job-1" {
+		t.Errorf("expected job ID job-1, got %q", job.ID)
+	}
+}
+
+func TestAPIClient_UniversalUpscale_PostsImageIDAndMultiplier(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"universalUpscaler":{"id":"job-u","apiCreditCost":10}}`))
+	}))
+	defer server.Close()
+
+	job, err := newClientWithBaseURL("test-key", server.URL).UniversalUpscale("img-1", 1.5)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/api/rest/v1/variations/universal-upscaler" || body["generatedImageId"] != "img-1" || body["upscaleMultiplier"] != 1.5 {
+		t.Errorf("unexpected request to %s with %v", path, body)
+	}
+	if job.ID != "job-u" {
+		t.Errorf("expected job ID job-u, got %q", job.ID)
+	}
+}
+
+func TestAPIClient_CreateVariation_PostsToKindEndpointAndReturnsJob(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		switch path {
+		case "/api/rest/v1/variations/nobg":
+			w.Write([]byte(`{"sdNobgJob":{"id":"job-nobg","apiCreditCost":2}}`))
+		case "/api/rest/v1/variations/unzoom":
+			w.Write([]byte(`{"sdUnzoomJob":{"id":"job-unzoom"}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	client := newClientWithBaseURL("test-key", server.URL)
+
+	job, err := client.CreateVariation(domain.VariationNoBackground, "img-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.ID != "job-nobg" || body["id"] != "img-1" {
+		t.Errorf("unexpected job %+v for request %v", job, body)
+	}
+	job, err = client.CreateVariation(domain.VariationUnzoom, "img-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.ID != "job-unzoom" || body["isVariation"] != false {
+		t.Errorf("unexpected job %+v for request %v", job, body)
+	}
+	if _, err := client.CreateVariation(domain.VariationUpscale, "img-3"); err == nil {
+		t.Error("expected an error when the response holds no job")
+	}
+}
+
+func TestAPIClient_GetVariation_DecodesStatusAndURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/rest/v1/variations/job-1" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"generated_image_variation_generic":[{"id":"job-1","status":"COMPLETE","url":"https://cdn/up.png","transformType":"UPSCALE"}]}`))
+	}))
+	defer server.Close()
+
+	variation, err := newClientWithBaseURL("test-key", server.URL).GetVariation("job-1")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if variation.Status != "COMPLETE" || variation.URL != "https://cdn/up.png" || variation.TransformType != "UPSCALE" {
+		t.Errorf("unexpected variation: %+v", variation)
+	}
+}
+
+func TestAPIClient_ReportsRateLimitHeadersToObserver(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    domain.RateLimit
+		reset   bool
+	}{
+		{"x-ratelimit with delay", map[string]string{"X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "7", "X-RateLimit-Reset": "30"}, domain.RateLimit{Limit: 100, Remaining: 7}, true},
+		{"standard with unix time", map[string]string{"RateLimit-Limit": "50", "RateLimit-Remaining": "49", "RateLimit-Reset": "1893456000"}, domain.RateLimit{Limit: 50, Remaining: 49, Reset: time.Unix(1893456000, 0)}, false},
+		{"none", map[string]string{}, domain.RateLimit{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				w.Write([]byte(`{"user_details":[]}`))
+			}))
+			defer server.Close()
+			client := newClientWithBaseURL("key", server.URL)
+			var got domain.RateLimit
+			client.SetObserver(func(m domain.CallMetric) { got = m.RateLimit })
+
+			before := time.Now()
+			if _, err := client.GetUserInfo(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Limit != tt.want.Limit || got.Remaining != tt.want.Remaining {
+				t.Errorf("expected %d of %d, got %d of %d", tt.want.Remaining, tt.want.Limit, got.Remaining, got.Limit)
+			}
+			if tt.reset {
+				if wait := got.Reset.Sub(before); wait < 29*time.Second || wait > 31*time.Second {
+					t.Errorf("expected a reset about 30s away, got %s", wait)
+				}
+			} else if !got.Reset.Equal(tt.want.Reset) {
+				t.Errorf("expected reset %v, got %v", tt.want.Reset, got.Reset)
+			}
+		})
+	}
+}
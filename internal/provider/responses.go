@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// The types in this file mirror the JSON documents returned by the Leonardo
+// REST API.  They are decoded with encoding/json and then converted into
+// domain types, so the rest of the application never sees API field names.
+
+// createGenerationResponse is returned by POST /generations.
+type createGenerationResponse struct {
+	SDGenerationJob struct {
+		GenerationID  string `json:"generationId"`
+		APICreditCost int    `json:"apiCreditCost"`
+	} `json:"sdGenerationJob"`
+}
+
+// generationResponse is returned by GET /generations/{id}.
+type generationResponse struct {
+	Generation *generationRecord `json:"generations_by_pk"`
+}
+
+// generationListResponse is returned by GET /generations/user/{userId}.
+type generationListResponse struct {
+	Generations []generationRecord `json:"generations"`
+}
+
+// generationRecord is a generation as stored by the API.  The same shape is
+// used by the single-generation and list endpoints.
+type generationRecord struct {
+	ID                   string                    `json:"id"`
+	Status               string                    `json:"status"`
+	CreatedAt            string                    `json:"createdAt"`
+	Prompt               string                    `json:"prompt"`
+	NegativePrompt       string                    `json:"negativePrompt"`
+	ModelID              string                    `json:"modelId"`
+	Scheduler            string                    `json:"scheduler"`
+	PresetStyle          string                    `json:"presetStyle"`
+	SDVersion            string                    `json:"sdVersion"`
+	ImageWidth           int                       `json:"imageWidth"`
+	ImageHeight          int                       `json:"imageHeight"`
+	InferenceSteps       int                       `json:"inferenceSteps"`
+	Seed                 int64                     `json:"seed"`
+	GuidanceScale        float64                   `json:"guidanceScale"`
+	InitStrength         float64                   `json:"initStrength"`
+	InitImageID          string                    `json:"initImageId"`
+	InitGeneratedImageID string                    `json:"initGeneratedImageId"`
+	Public               bool                      `json:"public"`
+	PhotoReal            bool                      `json:"photoReal"`
+	Alchemy              bool                      `json:"alchemy"`
+	Ultra                bool                      `json:"ultra"`
+	PromptMagic          bool                      `json:"promptMagic"`
+	GenerationElements   []generationElementRecord `json:"generation_elements"`
+	GeneratedImages      []generatedImageRecord    `json:"generated_images"`
+}
+
+type generationElementRecord struct {
+	WeightApplied float64 `json:"weightApplied"`
+	Lora          struct {
+		AkUUID string `json:"akUUID"`
+		Name   string `json:"name"`
+	} `json:"lora"`
+}
+
+type generatedImageRecord struct {
+	ID         string                 `json:"id"`
+	URL        string                 `json:"url"`
+	NSFW       bool                   `json:"nsfw"`
+	LikeCount  int                    `json:"likeCount"`
+	Variations []imageVariationRecord `json:"generated_image_variation_generics"`
+}
+
+type imageVariationRecord struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	Status        string `json:"status"`
+	TransformType string `json:"transformType"`
+}
+
+// deleteGenerationResponse is returned by DELETE /generations/{id}.
+type deleteGenerationResponse struct {
+	Deleted *struct {
+		ID string `json:"id"`
+	} `json:"delete_generations_by_pk"`
+}
+
+// userInfoResponse is returned by GET /me.
+type userInfoResponse struct {
+	UserDetails []struct {
+		User struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"user"`
+		APISubscriptionTokens   int    `json:"apiSubscriptionTokens"`
+		APIPaidTokens           int    `json:"apiPaidTokens"`
+		APIPlanTokenRenewalDate string `json:"apiPlanTokenRenewalDate"`
+	} `json:"user_details"`
+}
+
+// platformModelsResponse is returned by GET /platformModels.
+type platformModelsResponse struct {
+	CustomModels []struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		SDVersion   string `json:"sdVersion"`
+	} `json:"custom_models"`
+}
+
+// upscaleResponse is returned by POST /variations/upscale.
+type upscaleResponse struct {
+	SDUpscaleJob struct {
+		ID string `json:"id"`
+	} `json:"sdUpscaleJob"`
+}
+
+// universalUpscalerResponse is returned by POST
+// /variations/universal-upscaler.
+type universalUpscalerResponse struct {
+	UniversalUpscaler struct {
+		ID string `json:"id"`
+	} `json:"universalUpscaler"`
+}
+
+// variationJobResponse is returned by the POST /variations/{kind}
+// endpoints; only the job of the requested kind is set.
+type variationJobResponse struct {
+	SDUpscaleJob *variationJobRecord `json:"sdUpscaleJob"`
+	SDNobgJob    *variationJobRecord `json:"sdNobgJob"`
+	SDUnzoomJob  *variationJobRecord `json:"sdUnzoomJob"`
+}
+
+type variationJobRecord struct {
+	ID string `json:"id"`
+}
+
+// variationResponse is returned by GET /variations/{id}.
+type variationResponse struct {
+	Variations []imageVariationRecord `json:"generated_image_variation_generic"`
+}
+
+// uploadInitImageResponse is returned by POST /init-image.  Fields is a JSON
+// encoded object holding the form fields of the presigned upload.
+type uploadInitImageResponse struct {
+	Upload *struct {
+		ID     string `json:"id"`
+		Fields string `json:"fields"`
+		Key    string `json:"key"`
+		URL    string `json:"url"`
+	} `json:"uploadInitImage"`
+}
+
+// uploadModel3DResponse is returned by POST /models-3d/upload.  Fields is
+// a JSON encoded object holding the form fields of the presigned upload.
+type uploadModel3DResponse struct {
+	Upload *struct {
+		ID     string `json:"modelId"`
+		Fields string `json:"modelFields"`
+		Key    string `json:"modelKey"`
+		URL    string `json:"modelUrl"`
+	} `json:"uploadModelAsset"`
+}
+
+// pricingResponse is returned by POST /pricing-calculator.
+type pricingResponse struct {
+	Cost *struct {
+		Cost *int `json:"cost"`
+	} `json:"calculateProductionApiServiceCost"`
+}
+
+// initImageResponse is returned by GET /init-image/{id}.
+type initImageResponse struct {
+	InitImage *struct {
+		ID        string `json:"id"`
+		URL       string `json:"url"`
+		CreatedAt string `json:"createdAt"`
+	} `json:"init_images_by_pk"`
+}
+
+// deleteInitImageResponse is returned by DELETE /init-image/{id}.
+type deleteInitImageResponse struct {
+	Deleted *struct {
+		ID string `json:"id"`
+	} `json:"delete_init_images_by_pk"`
+}
+
+// decodeResponse decodes an API response body into v.  Failures mention the
+// offending field so changes in the API's response shape are easy to spot.
+func decodeResponse(body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("decoding response: field %q has unexpected type %s (expected %s)", typeErr.Field, typeErr.Value, typeErr.Type)
+		}
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// toStatus converts a generation record into a GenerationStatus.
+func (r generationRecord) toStatus() domain.GenerationStatus {
+	status := domain.GenerationStatus{Status: r.Status, CreatedAt: parseTimestamp(r.CreatedAt)}
+	for _, img := range r.GeneratedImages {
+		if img.URL != "" {
+			status.Images = append(status.Images, img.URL)
+		}
+	}
+	return status
+}
+
+// toListItem converts a generation record into a GenerationListItem.
+func (r generationRecord) toListItem() domain.GenerationListItem {
+	item := domain.GenerationListItem{
+		ID:        r.ID,
+		Status:    r.Status,
+		CreatedAt: parseTimestamp(r.CreatedAt),
+		Prompt:    r.Prompt,
+		ModelID:   r.ModelID,
+		Width:     r.ImageWidth,
+		Height:    r.ImageHeight,
+		Alchemy:   r.Alchemy,
+	}
+	for _, img := range r.GeneratedImages {
+		if img.URL != "" {
+			item.Images = append(item.Images, img.URL)
+		}
+	}
+	return item
+}
+
+// toDetail converts a generation record into a GenerationDetail.
+func (r generationRecord) toDetail() domain.GenerationDetail {
+	detail := domain.GenerationDetail{
+		ID:             r.ID,
+		Status:         r.Status,
+		CreatedAt:      parseTimestamp(r.CreatedAt),
+		Prompt:         r.Prompt,
+		NegativePrompt: r.NegativePrompt,
+		ModelID:        r.ModelID,
+		Scheduler:      r.Scheduler,
+		PresetStyle:    r.PresetStyle,
+		SDVersion:      r.SDVersion,
+		Width:          r.ImageWidth,
+		Height:         r.ImageHeight,
+		InferenceSteps: r.InferenceSteps,
+		Seed:           int(r.Seed),
+		GuidanceScale:  r.GuidanceScale,
+		InitStrength:   r.InitStrength,
+		InitImageID:    r.InitImageID,
+		Public:         r.Public,
+		PhotoReal:      r.PhotoReal,
+		Alchemy:        r.Alchemy,
+		Ultra:          r.Ultra,
+		PromptMagic:    r.PromptMagic,
+	}
+	if detail.InitImageID == "" {
+		detail.InitImageID = r.InitGeneratedImageID
+	}
+	for _, e := range r.GenerationElements {
+		detail.Elements = append(detail.Elements, domain.GenerationElement{
+			ID:     e.Lora.AkUUID,
+			Name:   e.Lora.Name,
+			Weight: e.WeightApplied,
+		})
+	}
+	for _, img := range r.GeneratedImages {
+		image := domain.GeneratedImage{ID: img.ID, URL: img.URL, NSFW: img.NSFW, LikeCount: img.LikeCount}
+		for _, v := range img.Variations {
+			image.Variations = append(image.Variations, domain.ImageVariation{
+				ID:            v.ID,
+				URL:           v.URL,
+				Status:        v.Status,
+				TransformType: v.TransformType,
+			})
+		}
+		detail.Images = append(detail.Images, image)
+	}
+	return detail
+}
+
+// timestampLayouts lists the timestamp formats seen in API responses.  The
+// API usually omits the zone designator; those timestamps are in UTC.
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+
+// parseTimestamp parses an API timestamp, returning the zero time when the
+// value is not in a known format.
+func parseTimestamp(value string) time.Time {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
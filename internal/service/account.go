@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// AccountService manages the named API credentials stored on this machine
+// and picks the one a command should use.
+type AccountService struct {
+	store ports.AccountStore
+}
+
+// NewAccountService constructs a new AccountService given an account store.
+func NewAccountService(store ports.AccountStore) *AccountService {
+	return &AccountService{store: store}
+}
+
+// Add stores a named credential.  The first account added becomes the
+// default.
+func (s *AccountService) Add(name, token string) error {
+	name = strings.TrimSpace(name)
+	token = strings.TrimSpace(token)
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("account name %q must be non-empty and contain no whitespace", name)
+	}
+	if token == "" {
+		return fmt.Errorf("token for account %q is empty", name)
+	}
+	if err := s.store.Save(domain.Account{Name: name, Token: token}); err != nil {
+		return err
+	}
+	current, err := s.store.Default()
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return s.store.SetDefault(name)
+	}
+	return nil
+}
+
+// Remove deletes a stored account, clearing the default if it pointed at it.
+func (s *AccountService) Remove(name string) error {
+	if _, err := s.find(name); err != nil {
+		return err
+	}
+	if err := s.store.Remove(name); err != nil {
+		return err
+	}
+	current, err := s.store.Default()
+	if err != nil {
+		return err
+	}
+	if current == name {
+		return s.store.SetDefault("")
+	}
+	return nil
+}
+
+// Use makes a stored account the default.
+func (s *AccountService) Use(name string) error {
+	if _, err := s.find(name); err != nil {
+		return err
+	}
+	return s.store.SetDefault(name)
+}
+
+// List returns the stored accounts and the name of the default one.
+func (s *AccountService) List() ([]domain.Account, string, error) {
+	accounts, err := s.store.List()
+	if err != nil {
+		return nil, "", err
+	}
+	current, err := s.store.Default()
+	if err != nil {
+		return nil, "", err
+	}
+	return accounts, current, nil
+}
+
+// Token returns the token of the named account, or of the default account
+// when name is empty.  It reports false when name is empty and no default
+// is set.
+func (s *AccountService) Token(name string) (string, bool, error) {
+	if name == "" {
+		current, err := s.store.Default()
+		if err != nil || current == "" {
+			return "", false, err
+		}
+		name = current
+	}
+	account, err := s.find(name)
+	if err != nil {
+		return "", false, err
+	}
+	return account.Token, true, nil
+}
+
+func (s *AccountService) find(name string) (domain.Account, error) {
+	accounts, err := s.store.List()
+	if err != nil {
+		return domain.Account{}, err
+	}
+	for _, a := range accounts {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return domain.Account{}, &domain.UnknownAccountError{Name: name}
+}
@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeAccountStore implements ports.AccountStore in memory.
+type fakeAccountStore struct {
+	accounts []domain.Account
+	current  string
+}
+
+func (f *fakeAccountStore) Save(account domain.Account) error {
+	for i := range f.accounts {
+		if f.accounts[i].Name == account.Name {
+			f.accounts[i] = account
+			return nil
+		}
+	}
+	f.accounts = append(f.accounts, account)
+	return nil
+}
+
+func (f *fakeAccountStore) List() ([]domain.Account, error) {
+	return append([]domain.Account(nil), f.accounts...), nil
+}
+
+func (f *fakeAccountStore) Remove(name string) error {
+	kept := f.accounts[:0]
+	for _, a := range f.accounts {
+		if a.Name != name {
+			kept = append(kept, a)
+		}
+	}
+	f.accounts = kept
+	return nil
+}
+
+func (f *fakeAccountStore) Default() (string, error) { return f.current, nil }
+
+func (f *fakeAccountStore) SetDefault(name string) error {
+	f.current = name
+	return nil
+}
+
+// --- Behavior: Managing stored accounts ---
+
+func TestAccountAdd_FirstAccountBecomesDefault(t *testing.T) {
+	store := &fakeAccountStore{}
+	svc := service.NewAccountService(store)
+
+	if err := svc.Add("work", "tok-work"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.Add("personal", "tok-personal"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	token, ok, err := svc.Token("")
+	if err != nil || !ok {
+		t.Fatalf("expected a default token, got ok=%v err=%v", ok, err)
+	}
+	if token != "tok-work" {
+		t.Errorf("expected %q, got %q", "tok-work", token)
+	}
+}
+
+func TestAccountAdd_RejectsEmptyTokenAndBadNames(t *testing.T) {
+	svc := service.NewAccountService(&fakeAccountStore{})
+
+	if err := svc.Add("work", "  "); err == nil {
+		t.Error("expected error for empty token")
+	}
+	if err := svc.Add("my work", "tok"); err == nil {
+		t.Error("expected error for name with whitespace")
+	}
+}
+
+func TestAccountToken_SelectsNamedAccount(t *testing.T) {
+	store := &fakeAccountStore{accounts: []domain.Account{{Name: "work", Token: "tok-work"}, {Name: "personal", Token: "tok-personal"}}, current: "work"}
+	svc := service.NewAccountService(store)
+
+	token, _, err := svc.Token("personal")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "tok-personal" {
+		t.Errorf("expected %q, got %q", "tok-personal", token)
+	}
+}
+
+func TestAccountToken_UnknownAccountIsAnError(t *testing.T) {
+	svc := service.NewAccountService(&fakeAccountStore{})
+
+	_, _, err := svc.Token("nope")
+	var unknown *domain.UnknownAccountError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownAccountError, got %v", err)
+	}
+}
+
+func TestAccountToken_ReportsNoDefault(t *testing.T) {
+	svc := service.NewAccountService(&fakeAccountStore{accounts: []domain.Account{{Name: "work", Token: "tok"}}})
+
+	_, ok, err := svc.Token("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected no default account")
+	}
+}
+
+func TestAccountRemove_ClearsDefault(t *testing.T) {
+	store := &fakeAccountStore{accounts: []domain.Account{{Name: "work", Token: "tok"}}, current: "work"}
+	svc := service.NewAccountService(store)
+
+	if err := svc.Remove("work"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.current != "" {
+		t.Errorf("expected default to be cleared, got %q", store.current)
+	}
+	if err := svc.Remove("work"); err == nil {
+		t.Error("expected error removing an unknown account")
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+
+	"leonardo-cli/internal/domain"
+)
+
+// DefaultBackfillScan is how many of the user's most recent generations
+// backfill searches for image file names it could not match otherwise.
+const DefaultBackfillScan = 500
+
+// BackfillService finds the generations that image files downloaded
+// without a sidecar came from.
+type BackfillService struct {
+	generations *GenerationService
+	library     *LibraryService
+}
+
+// NewBackfillService constructs a new BackfillService.
+func NewBackfillService(generations *GenerationService, library *LibraryService) *BackfillService {
+	return &BackfillService{generations: generations, library: library}
+}
+
+// Match pairs each file with its generation.  A file name starting with a
+// generation ID is matched by it, then names and ID prefixes are looked up
+// in the local library, and finally the remaining files are compared with
+// the file names of the images of the user's scan most recent generations.
+// A scan of zero skips that last step.  Files no heuristic matches are
+// returned with no generation ID.
+func (s *BackfillService) Match(files []string, userID string, scan int) ([]domain.BackfillMatch, error) {
+	matches := make([]domain.BackfillMatch, len(files))
+	unmatched := map[string][]int{}
+	for i, file := range files {
+		matches[i].File = file
+		ref := domain.BackfillReference(file)
+		switch {
+		case domain.IsFullGenerationID(ref):
+			matches[i].GenerationID, matches[i].By = ref, domain.MatchedByID
+			continue
+		case ref != "":
+			id, err := s.library.Resolve(ref)
+			if err != nil {
+				return nil, fmt.Errorf("matching %s: %w", file, err)
+			}
+			if id != ref && domain.IsFullGenerationID(id) {
+				matches[i].GenerationID, matches[i].By = id, domain.MatchedByName
+				continue
+			}
+		}
+		name := domain.RemoteImageName(file)
+		unmatched[name] = append(unmatched[name], i)
+	}
+	if len(unmatched) == 0 || scan <= 0 || userID == "" {
+		return matches, nil
+	}
+	seen := 0
+	err := s.generations.walkGenerations(s.generations.ctx, userID, DefaultListPageSize, 1, func(gen domain.GenerationListItem) error {
+		for _, url := range gen.Images {
+			name := domain.RemoteImageName(url)
+			for _, i := range unmatched[name] {
+				matches[i].GenerationID, matches[i].By = gen.ID, domain.MatchedByRemoteFile
+			}
+			delete(unmatched, name)
+		}
+		if seen++; seen >= scan || len(unmatched) == 0 {
+			return ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		return matches, fmt.Errorf("listing generations to match file names: %w", err)
+	}
+	return matches, nil
+}
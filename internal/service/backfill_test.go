@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// --- Behavior: Matching old downloads to their generations ---
+
+func TestBackfillMatch_TriesIDThenLibraryThenRemoteFileNames(t *testing.T) {
+	const (
+		byID     = "3fa2c1d0-5e4b-4f5a-9c1e-0123456789ab"
+		byName   = "7c1e0b2a-1111-4f5a-9c1e-0123456789ab"
+		byRemote = "9d0e1f2a-2222-4f5a-9c1e-0123456789ab"
+	)
+	fake := &fakeLeonardoClient{listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+		if offset > 0 {
+			return domain.GenerationListResponse{}, nil
+		}
+		return domain.GenerationListResponse{Generations: []domain.GenerationListItem{
+			{ID: "other", Images: []string{"https://cdn.leonardo.ai/users/u/generations/other/Cat_0.jpg"}},
+			{ID: byRemote, Images: []string{"https://cdn.leonardo.ai/users/u/generations/x/Leonardo_Phoenix_A_red_fox_0.jpg?w=1"}},
+		}}, nil
+	}}
+	lib := service.NewLibraryService(&fakeLibrary{entries: []domain.LibraryEntry{{GenerationID: byName, Name: "hero-banner"}}})
+	backfill := service.NewBackfillService(service.NewGenerationService(fake), lib)
+	files := []string{byID + "_1.png", byID + "_2_upscale.png", "hero-banner_1.png", "Leonardo_Phoenix_A_red_fox_0.jpg", "holiday.jpg"}
+
+	matches, err := backfill.Match(files, "user-1", 100)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []domain.BackfillMatch{
+		{File: files[0], GenerationID: byID, By: domain.MatchedByID},
+		{File: files[1], GenerationID: byID, By: domain.MatchedByID},
+		{File: files[2], GenerationID: byName, By: domain.MatchedByName},
+		{File: files[3], GenerationID: byRemote, By: domain.MatchedByRemoteFile},
+		{File: files[4]},
+	}
+	for i, m := range matches {
+		if m != want[i] {
+			t.Errorf("file %d: expected %+v, got %+v", i, want[i], m)
+		}
+	}
+}
+
+func TestBackfillMatch_SkipsRemoteSearchWithZeroScan(t *testing.T) {
+	fake := &fakeLeonardoClient{listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+		t.Fatal("unexpected listing with a zero scan")
+		return domain.GenerationListResponse{}, nil
+	}}
+	backfill := service.NewBackfillService(service.NewGenerationService(fake), service.NewLibraryService(&fakeLibrary{}))
+
+	matches, err := backfill.Match([]string{"unknown_1.png"}, "user-1", 0)
+
+	if err != nil || len(matches) != 1 || matches[0].GenerationID != "" {
+		t.Errorf("expected the file to stay unmatched, got %+v (%v)", matches, err)
+	}
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// statusFailed is the generation status reported by the API when a job
+// could not be completed on the server side.
+const statusFailed = domain.GenerationFailed
+
+// SubmitItem submits a single request as a new batch item and records the
+// outcome, for callers that stream requests rather than holding a batch.
+func (s *GenerationService) SubmitItem(req domain.GenerationRequest) domain.BatchItem {
+	return s.submitBatchItem(domain.BatchItem{Request: req})
+}
+
+// submitBatchItem submits the item's request and records the outcome on a
+// copy of the item.  Any previous failure is cleared on success.  Items
+// without an idempotency key are given one, and the key is sent with the
+// request.
+func (s *GenerationService) submitBatchItem(item domain.BatchItem) domain.BatchItem {
+	if item.IdempotencyKey == "" {
+		item.IdempotencyKey = domain.NewIdempotencyKey()
+	}
+	item.SubmittedAt = time.Now().UTC()
+	item.Attempts++
+	req := item.Request
+	req.IdempotencyKey = item.IdempotencyKey
+	res, err := s.Create(req)
+	if err != nil {
+		item.GenerationID = ""
+		item.Failure = domain.ClassifyFailure(err)
+		item.Error = err.Error()
+		return item
+	}
+	item.GenerationID = res.GenerationID
+	item.Failure = ""
+	item.Error = ""
+	return item
+}
+
+// SubmitBatch submits every request in order and returns a manifest recording
+// the outcome of each one.  A failed submission does not stop the batch; it
+// is recorded with its failure class so it can be retried later.  When report
+// is not nil it is called after each submission with the item's index.
+func (s *GenerationService) SubmitBatch(requests []domain.GenerationRequest, report func(index int, item domain.BatchItem)) domain.BatchManifest {
+	manifest, _ := s.RunBatch(domain.NewBatchManifest(requests), report, nil)
+	return manifest
+}
+
+// RunBatch submits the pending items of a manifest in order, resuming a run
+// that stopped part way.  Items whose create request was sent but whose
+// outcome was never recorded are first looked up among the user's recent
+// generations, so a generation created just before a crash is adopted
+// rather than created twice; those not found are submitted again.
+//
+// With SetBatchConcurrency several items are in flight at once, as many as
+// a domain.ConcurrencyLimit allows.  An item the API rate limits lowers the
+// limit, pauses new submissions for the cooldown and goes to the back of
+// the queue, until it has been tried domain.DefaultMaxAttempts times.  A
+// spread spaces the submissions evenly over its window instead.  A pending
+// cap holds submissions back while that many generations are unfinished,
+// polling them until one completes.
+//
+// When checkpoint is not nil it is given the manifest before every
+// submission, with the item marked as sent, and once more at the end, so
+// the manifest on disk always says which items may exist.  A checkpoint
+// error stops the run.  report is called as for SubmitBatch, including for
+// adopted items, once each item's outcome is final; with concurrency that
+// is not always in order.
+func (s *GenerationService) RunBatch(manifest domain.BatchManifest, report func(index int, item domain.BatchItem), checkpoint func(domain.BatchManifest) error) (domain.BatchManifest, error) {
+	items := append([]domain.BatchItem(nil), manifest.Items...)
+	manifest = domain.BatchManifest{Items: items}
+	save := func() error {
+		if checkpoint == nil {
+			return nil
+		}
+		return checkpoint(manifest)
+	}
+	recovered, err := s.reconcileInDoubt(items)
+	if err != nil {
+		return manifest, err
+	}
+	var queue []int
+	for i, item := range items {
+		switch {
+		case recovered[i]:
+			if report != nil {
+				report(i, item)
+			}
+		case item.Pending() || item.InDoubt():
+			queue = append(queue, i)
+		}
+	}
+
+	type outcome struct {
+		index int
+		item  domain.BatchItem
+	}
+	limit := domain.NewConcurrencyLimit(s.batch.Max)
+	done := make(chan outcome, len(items))
+	inFlight := 0
+	var spacing time.Duration
+	if s.batch.Spread > 0 && len(queue) > 0 {
+		spacing = s.batch.Spread / time.Duration(len(queue))
+	}
+	poll := s.batch.Poll
+	if poll <= 0 {
+		poll = domain.DefaultPendingPoll
+	}
+	var (
+		stopped   error // why no more items are submitted
+		saveErr   error
+		notBefore time.Time // when the next submission may start
+		held      bool      // whether the pending cap is holding items back
+	)
+	for {
+		for stopped == nil && saveErr == nil && len(queue) > 0 && inFlight < limit.Limit() && !time.Now().Before(notBefore) {
+			if pending := s.pending(inFlight); s.batch.MaxPending > 0 && pending >= s.batch.MaxPending {
+				if !held && s.batch.OnHold != nil {
+					s.batch.OnHold(pending)
+				}
+				held = true
+				break
+			}
+			held = false
+			if err := s.ctx.Err(); err != nil {
+				stopped = err
+				break
+			}
+			i := queue[0]
+			queue = queue[1:]
+			item := items[i]
+			if item.IdempotencyKey == "" {
+				item.IdempotencyKey = domain.NewIdempotencyKey()
+			}
+			item.SubmittedAt = time.Now().UTC()
+			item.Failure, item.Error = "", ""
+			items[i] = item
+			if saveErr = save(); saveErr != nil {
+				break
+			}
+			inFlight++
+			notBefore = item.SubmittedAt.Add(spacing)
+			go func(i int, item domain.BatchItem) {
+				done <- outcome{i, s.submitBatchItem(item)}
+			}(i, item)
+		}
+		if inFlight == 0 && (stopped != nil || saveErr != nil || len(queue) == 0) {
+			break
+		}
+		// Wait for a submission to finish, for the next one to be due,
+		// for pending generations to be checked again or for the run to
+		// be cancelled.
+		var wake <-chan time.Time
+		var timer *time.Timer
+		if len(queue) > 0 && (held || time.Now().Before(notBefore)) {
+			wait := time.Until(notBefore)
+			if held && (wait <= 0 || wait > poll) {
+				wait = poll
+			}
+			timer = time.NewTimer(wait)
+			wake = timer.C
+		}
+		var cancelled <-chan struct{}
+		if stopped == nil {
+			cancelled = s.ctx.Done()
+		}
+		var r outcome
+		finished := false
+		select {
+		case <-wake:
+		case <-cancelled:
+			stopped = s.ctx.Err()
+		case r = <-done:
+			finished = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if !finished {
+			if held && stopped == nil {
+				s.refreshUnfinished()
+			}
+			continue
+		}
+		inFlight--
+		i, submitted := r.index, r.item
+		if err := s.ctx.Err(); err != nil && submitted.Failed() {
+			// The request was cut off, so it may or may not have
+			// reached the API; leave the item in doubt for a resume.
+			items[i].Attempts = submitted.Attempts
+			stopped = err
+			continue
+		}
+		items[i] = submitted
+		if submitted.Failure == domain.FailureRateLimit {
+			if limit.RateLimited() && s.batch.OnChange != nil {
+				s.batch.OnChange(limit.Limit(), true)
+			}
+			if pause := time.Now().Add(s.batch.Cooldown); pause.After(notBefore) {
+				notBefore = pause
+			}
+			if submitted.Attempts < domain.DefaultMaxAttempts {
+				queue = append(queue, i)
+				continue
+			}
+		} else if limit.Succeeded() && s.batch.OnChange != nil {
+			s.batch.OnChange(limit.Limit(), false)
+		}
+		if report != nil {
+			report(i, submitted)
+		}
+	}
+	if saveErr != nil {
+		return manifest, saveErr
+	}
+	if stopped != nil {
+		return manifest, stopRun(save(), stopped)
+	}
+	return manifest, save()
+}
+
+// stopRun returns the error a run stopped with: a failure to save its last
+// checkpoint, which matters more, or else why it stopped.
+func stopRun(saveErr, err error) error {
+	if saveErr != nil {
+		return saveErr
+	}
+	return fmt.Errorf("batch stopped: %w", err)
+}
+
+// inDoubtLookupPages bounds how many pages' worth of recent generations are
+// searched for the generations of in-doubt items.
+const inDoubtLookupPages = 10
+
+// reconcileInDoubt looks up the generations of in-doubt items among the
+// user's recent generations and records the ones found in place.  It
+// returns the indexes of the items it recovered.  Items not found are left
+// in doubt.
+func (s *GenerationService) reconcileInDoubt(items []domain.BatchItem) (map[int]bool, error) {
+	var earliest time.Time
+	claimed := make(map[string]bool)
+	for _, item := range items {
+		if item.InDoubt() && (earliest.IsZero() || item.SubmittedAt.Before(earliest)) {
+			earliest = item.SubmittedAt
+		}
+		if item.GenerationID != "" {
+			claimed[item.GenerationID] = true
+		}
+	}
+	recovered := make(map[int]bool)
+	if earliest.IsZero() {
+		return recovered, nil
+	}
+	info, err := s.client.GetUserInfo()
+	if err != nil {
+		return nil, fmt.Errorf("looking up user to check interrupted submissions: %w", err)
+	}
+	// Generations are listed newest first, so the search ends at the first
+	// one created before any in-doubt item could have been submitted.
+	var recent []domain.GenerationListItem
+	cutoff := earliest.Add(-domain.InDoubtClockSkew)
+	err = s.walkGenerations(s.ctx, info.UserID, recentLookupLimit, 1, func(gen domain.GenerationListItem) error {
+		if gen.CreatedAt.Before(cutoff) || len(recent) >= inDoubtLookupPages*recentLookupLimit {
+			return ErrStopIteration
+		}
+		recent = append(recent, gen)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing recent generations to check interrupted submissions: %w", err)
+	}
+	for i, item := range items {
+		if !item.InDoubt() {
+			continue
+		}
+		if id, ok := item.MatchInDoubt(recent, claimed); ok {
+			claimed[id] = true
+			items[i].GenerationID = id
+			recovered[i] = true
+		}
+	}
+	return recovered, nil
+}
+
+// RetryFailed resubmits the retryable failures of a batch manifest.  Items
+// that were submitted successfully are checked against the API first so
+// generations that failed on the server are retried as well.  Items that
+// failed with a non-retryable class are skipped, and items that already
+// reached maxAttempts are counted as exhausted.  The updated manifest is
+// returned alongside a summary of what happened.
+func (s *GenerationService) RetryFailed(manifest domain.BatchManifest, maxAttempts int) (domain.BatchManifest, domain.BatchRetrySummary) {
+	if maxAttempts <= 0 {
+		maxAttempts = domain.DefaultMaxAttempts
+	}
+	var summary domain.BatchRetrySummary
+	updated := domain.BatchManifest{Items: make([]domain.BatchItem, len(manifest.Items))}
+	for i, item := range manifest.Items {
+		if !item.Failed() && item.GenerationID != "" {
+			if status, err := s.client.GetGenerationStatus(item.GenerationID); err == nil && status.Status == statusFailed {
+				item.Failure = domain.FailureTransient
+				item.Error = fmt.Sprintf("generation %s failed on the server", item.GenerationID)
+			}
+		}
+		switch {
+		case !item.Failed():
+		case !item.Failure.Retryable():
+			summary.Skipped++
+		case !item.CanRetry(maxAttempts):
+			summary.Exhausted++
+		default:
+			item = s.submitBatchItem(item)
+			summary.Retried++
+			if !item.Failed() {
+				summary.Succeeded++
+			}
+		}
+		updated.Items[i] = item
+	}
+	return updated, summary
+}
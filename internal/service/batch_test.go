@@ -0,0 +1,485 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// --- Behavior: Retrying failed batch items ---
+
+func TestRetryFailed_ResubmitsOnlyRetryableFailures(t *testing.T) {
+	var submitted []string
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			submitted = append(submitted, req.Metadata.Prompt)
+			return domain.GenerationResponse{GenerationID: "gen-" + req.Metadata.Prompt}, nil
+		},
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	manifest := domain.BatchManifest{Items: []domain.BatchItem{
+		{Request: prompt("ok"), GenerationID: "gen-ok", Attempts: 1},
+		{Request: prompt("limited"), Attempts: 1, Failure: domain.FailureRateLimit},
+		{Request: prompt("flaky"), Attempts: 1, Failure: domain.FailureTransient},
+		{Request: prompt("nsfw"), Attempts: 1, Failure: domain.FailureModeration},
+		{Request: prompt("invalid"), Attempts: 1, Failure: domain.FailurePermanent},
+	}}
+
+	updated, summary := svc.RetryFailed(manifest, 3)
+
+	if len(submitted) != 2 || submitted[0] != "limited" || submitted[1] != "flaky" {
+		t.Fatalf("expected only retryable items to be resubmitted, got %v", submitted)
+	}
+	if summary.Retried != 2 || summary.Succeeded != 2 {
+		t.Errorf("expected 2 retried and 2 succeeded, got %+v", summary)
+	}
+	if summary.Skipped != 2 {
+		t.Errorf("expected 2 skipped items, got %d", summary.Skipped)
+	}
+	if updated.Items[1].GenerationID != "gen-limited" {
+		t.Errorf("expected generation ID %q, got %q", "gen-limited", updated.Items[1].GenerationID)
+	}
+	if updated.Items[1].Failed() {
+		t.Errorf("expected failure to be cleared after success, got %q", updated.Items[1].Failure)
+	}
+	if updated.Items[1].Attempts != 2 {
+		t.Errorf("expected attempts to be incremented to 2, got %d", updated.Items[1].Attempts)
+	}
+}
+
+func TestRetryFailed_RespectsMaxAttempts(t *testing.T) {
+	calls := 0
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			calls++
+			return domain.GenerationResponse{GenerationID: "gen-new"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	manifest := domain.BatchManifest{Items: []domain.BatchItem{
+		{Request: prompt("tired"), Attempts: 3, Failure: domain.FailureTransient},
+	}}
+
+	updated, summary := svc.RetryFailed(manifest, 3)
+
+	if calls != 0 {
+		t.Errorf("expected no submissions for exhausted item, got %d", calls)
+	}
+	if summary.Exhausted != 1 {
+		t.Errorf("expected 1 exhausted item, got %d", summary.Exhausted)
+	}
+	if updated.Items[0].Failure != domain.FailureTransient {
+		t.Errorf("expected failure to be preserved, got %q", updated.Items[0].Failure)
+	}
+}
+
+func TestRetryFailed_ClassifiesNewFailures(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			return domain.GenerationResponse{}, &domain.APIError{StatusCode: 400, Body: []byte(`{"error":"Prompt flagged by content moderation"}`)}
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	manifest := domain.BatchManifest{Items: []domain.BatchItem{
+		{Request: prompt("again"), Attempts: 1, Failure: domain.FailureRateLimit},
+	}}
+
+	updated, summary := svc.RetryFailed(manifest, 3)
+
+	if summary.Succeeded != 0 {
+		t.Errorf("expected no successes, got %d", summary.Succeeded)
+	}
+	if updated.Items[0].Failure != domain.FailureModeration {
+		t.Errorf("expected failure %q, got %q", domain.FailureModeration, updated.Items[0].Failure)
+	}
+	if updated.Items[0].Error != "API returned status 400" {
+		t.Errorf("expected error %q, got %q", "API returned status 400", updated.Items[0].Error)
+	}
+}
+
+func TestRetryFailed_ResubmitsGenerationsThatFailedOnServer(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			return domain.GenerationResponse{GenerationID: "gen-second"}, nil
+		},
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "FAILED"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	manifest := domain.BatchManifest{Items: []domain.BatchItem{
+		{Request: prompt("broken"), GenerationID: "gen-first", Attempts: 1},
+	}}
+
+	updated, summary := svc.RetryFailed(manifest, 3)
+
+	if summary.Retried != 1 {
+		t.Errorf("expected 1 retried item, got %d", summary.Retried)
+	}
+	if updated.Items[0].GenerationID != "gen-second" {
+		t.Errorf("expected generation ID %q, got %q", "gen-second", updated.Items[0].GenerationID)
+	}
+}
+
+// prompt builds a minimal request for batch tests.
+func prompt(text string) domain.GenerationRequest {
+	return domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: text}}
+}
+
+// --- Behavior: Submitting a batch ---
+
+func TestSubmitBatch_RecordsEveryOutcomeInOrder(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			if req.Metadata.Prompt == "bad" {
+				return domain.GenerationResponse{}, &domain.APIError{StatusCode: 400, Body: []byte(`{"error":"invalid"}`)}
+			}
+			return domain.GenerationResponse{GenerationID: "gen-" + req.Metadata.Prompt}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	requests := []domain.GenerationRequest{
+		{Metadata: domain.GenerationMetadata{Prompt: "a"}},
+		{Metadata: domain.GenerationMetadata{Prompt: "bad"}},
+		{Metadata: domain.GenerationMetadata{Prompt: "c"}},
+	}
+
+	var reported []int
+	manifest := svc.SubmitBatch(requests, func(index int, item domain.BatchItem) {
+		reported = append(reported, index)
+	})
+
+	if len(manifest.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(manifest.Items))
+	}
+	if manifest.Items[0].GenerationID != "gen-a" || manifest.Items[2].GenerationID != "gen-c" {
+		t.Errorf("unexpected generation IDs: %q, %q", manifest.Items[0].GenerationID, manifest.Items[2].GenerationID)
+	}
+	if manifest.Items[1].Failure != domain.FailurePermanent || manifest.Items[1].Attempts != 1 {
+		t.Errorf("expected a permanent failure after 1 attempt, got %q after %d", manifest.Items[1].Failure, manifest.Items[1].Attempts)
+	}
+	if len(reported) != 3 || reported[0] != 0 || reported[2] != 2 {
+		t.Errorf("expected each item to be reported in order, got %v", reported)
+	}
+}
+
+func TestRunBatch_CheckpointsItemAsSentBeforeCreating(t *testing.T) {
+	var checkpoints []domain.BatchManifest
+	var keys []string
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			keys = append(keys, req.IdempotencyKey)
+			last := checkpoints[len(checkpoints)-1]
+			if item := last.Items[len(keys)-1]; !item.InDoubt() || item.IdempotencyKey != req.IdempotencyKey {
+				t.Errorf("expected item %d to be checkpointed as sent with its key, got %+v", len(keys), item)
+			}
+			return domain.GenerationResponse{GenerationID: "gen-" + req.Metadata.Prompt}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	manifest := domain.NewBatchManifest([]domain.GenerationRequest{
+		{Metadata: domain.GenerationMetadata{Prompt: "a"}},
+		{Metadata: domain.GenerationMetadata{Prompt: "b"}},
+	})
+
+	result, err := svc.RunBatch(manifest, nil, func(m domain.BatchManifest) error {
+		checkpoints = append(checkpoints, domain.BatchManifest{Items: append([]domain.BatchItem(nil), m.Items...)})
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(checkpoints) != 3 {
+		t.Errorf("expected a checkpoint per item and one at the end, got %d", len(checkpoints))
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] == keys[1] {
+		t.Errorf("expected a distinct idempotency key per item, got %q", keys)
+	}
+	final := checkpoints[len(checkpoints)-1]
+	if final.Items[1].GenerationID != "gen-b" || result.Items[0].GenerationID != "gen-a" {
+		t.Errorf("unexpected final manifest %+v", final.Items)
+	}
+}
+
+func TestRunBatch_AdaptsConcurrencyToRateLimits(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	limited := false
+	fake := &fakeLeonardoClient{createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		first := req.Metadata.Prompt == "p4" && !limited
+		if first {
+			limited = true
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		if first {
+			return domain.GenerationResponse{}, &domain.APIError{StatusCode: 429}
+		}
+		return domain.GenerationResponse{GenerationID: "gen-" + req.Metadata.Prompt}, nil
+	}}
+	svc := service.NewGenerationService(fake)
+	var changes []string
+	svc.SetBatchConcurrency(domain.BatchConcurrency{Max: 3, Cooldown: time.Millisecond, OnChange: func(limit int, rateLimited bool) {
+		changes = append(changes, fmt.Sprintf("%d:%t", limit, rateLimited))
+	}})
+	var requests []domain.GenerationRequest
+	for i := 0; i < 10; i++ {
+		requests = append(requests, domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: fmt.Sprintf("p%d", i)}})
+	}
+	reported := 0
+
+	result, err := svc.RunBatch(domain.NewBatchManifest(requests), func(int, domain.BatchItem) { reported++ }, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, item := range result.Items {
+		if item.Failed() || item.GenerationID != fmt.Sprintf("gen-p%d", i) {
+			t.Errorf("item %d: expected to be created, got %+v", i, item)
+		}
+	}
+	if result.Items[4].Attempts != 2 {
+		t.Errorf("expected the rate-limited item to be resubmitted once, got %d attempts", result.Items[4].Attempts)
+	}
+	if reported != 10 {
+		t.Errorf("expected each item reported once, got %d reports", reported)
+	}
+	if peak < 2 || peak > 3 {
+		t.Errorf("expected between 2 and 3 submissions in flight at the peak, got %d", peak)
+	}
+	if len(changes) < 2 || changes[0] != "2:false" {
+		t.Errorf("expected the limit to ramp up from 1, got %v", changes)
+	}
+}
+
+func TestRunBatch_HoldsSubmissionsAtThePendingCap(t *testing.T) {
+	var mu sync.Mutex
+	open := map[string]bool{}
+	polls := map[string]int{}
+	peak := 0
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			id := "gen-" + req.Metadata.Prompt
+			open[id] = true
+			if len(open) > peak {
+				peak = len(open)
+			}
+			return domain.GenerationResponse{GenerationID: id}, nil
+		},
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			polls[id]++
+			if polls[id] < 2 {
+				return domain.GenerationStatus{Status: "PENDING"}, nil
+			}
+			delete(open, id)
+			return domain.GenerationStatus{Status: domain.GenerationComplete}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	holds := 0
+	svc.SetBatchConcurrency(domain.BatchConcurrency{Max: 4, MaxPending: 2, Poll: time.Millisecond, OnHold: func(pending int) {
+		if pending != 2 {
+			t.Errorf("expected to be held at 2 pending, got %d", pending)
+		}
+		holds++
+	}})
+	var requests []domain.GenerationRequest
+	for i := 0; i < 5; i++ {
+		requests = append(requests, domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: fmt.Sprintf("p%d", i)}})
+	}
+
+	result, err := svc.RunBatch(domain.NewBatchManifest(requests), nil, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, item := range result.Items {
+		if item.Failed() || item.GenerationID == "" {
+			t.Errorf("item %d: expected to be created, got %+v", i, item)
+		}
+	}
+	if peak != 2 {
+		t.Errorf("expected at most 2 generations pending at once, got %d", peak)
+	}
+	if holds == 0 {
+		t.Error("expected the cap to hold submissions back")
+	}
+}
+
+func TestRunBatch_GivesUpOnItemStillRateLimitedAfterMaxAttempts(t *testing.T) {
+	fake := &fakeLeonardoClient{createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+		return domain.GenerationResponse{}, &domain.APIError{StatusCode: 429}
+	}}
+	svc := service.NewGenerationService(fake)
+	svc.SetBatchConcurrency(domain.BatchConcurrency{Max: 4, Cooldown: time.Millisecond})
+	var reported []domain.BatchItem
+
+	result, err := svc.RunBatch(domain.NewBatchManifest([]domain.GenerationRequest{{Metadata: domain.GenerationMetadata{Prompt: "a"}}}), func(_ int, item domain.BatchItem) {
+		reported = append(reported, item)
+	}, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item := result.Items[0]; item.Failure != domain.FailureRateLimit || item.Attempts != domain.DefaultMaxAttempts {
+		t.Errorf("expected a rate-limit failure after %d attempts, got %+v", domain.DefaultMaxAttempts, item)
+	}
+	if len(reported) != 1 {
+		t.Errorf("expected the item to be reported once, got %d", len(reported))
+	}
+}
+
+func TestRunBatch_SpreadsSubmissionsOverWindow(t *testing.T) {
+	var mu sync.Mutex
+	var sent []time.Time
+	fake := &fakeLeonardoClient{createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+		mu.Lock()
+		sent = append(sent, time.Now())
+		mu.Unlock()
+		return domain.GenerationResponse{GenerationID: "gen-" + req.Metadata.Prompt}, nil
+	}}
+	svc := service.NewGenerationService(fake)
+	svc.SetBatchConcurrency(domain.BatchConcurrency{Max: 4, Spread: 90 * time.Millisecond})
+	manifest := domain.NewBatchManifest([]domain.GenerationRequest{
+		{Metadata: domain.GenerationMetadata{Prompt: "a"}},
+		{Metadata: domain.GenerationMetadata{Prompt: "b"}},
+		{Metadata: domain.GenerationMetadata{Prompt: "c"}},
+	})
+
+	if _, err := svc.RunBatch(manifest, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sent) != 3 {
+		t.Fatalf("expected 3 submissions, got %d", len(sent))
+	}
+	for i := 1; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < 25*time.Millisecond {
+			t.Errorf("expected submissions about 30ms apart, got %s between %d and %d", gap, i-1, i)
+		}
+	}
+}
+
+// --- Behavior: Resuming an interrupted batch ---
+
+func TestRunBatch_AdoptsGenerationOfInterruptedSubmission(t *testing.T) {
+	sent := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	var created []string
+	fake := &fakeLeonardoClient{
+		userFn: func() (domain.UserInfo, error) { return domain.UserInfo{UserID: "user-1"}, nil },
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			return domain.GenerationListResponse{Generations: []domain.GenerationListItem{
+				{ID: "gen-late", Prompt: "fox", CreatedAt: sent.Add(time.Minute)},
+				{ID: "gen-fox", Prompt: "fox", CreatedAt: sent.Add(2 * time.Second)},
+				{ID: "gen-old", Prompt: "owl", CreatedAt: sent.Add(-time.Hour)},
+			}}, nil
+		},
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			created = append(created, req.Metadata.Prompt)
+			return domain.GenerationResponse{GenerationID: "gen-new-" + req.Metadata.Prompt}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	manifest := domain.BatchManifest{Items: []domain.BatchItem{
+		{Request: domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "done"}}, GenerationID: "gen-done", Attempts: 1},
+		{Request: domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "fox"}}, IdempotencyKey: "k2", SubmittedAt: sent, Attempts: 1},
+		{Request: domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "owl"}}, IdempotencyKey: "k3", SubmittedAt: sent, Attempts: 1},
+		{Request: domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "cat"}}, IdempotencyKey: "k4"},
+	}}
+	var reported []int
+
+	result, err := svc.RunBatch(manifest, func(i int, item domain.BatchItem) { reported = append(reported, i) }, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Items[1].GenerationID != "gen-fox" {
+		t.Errorf("expected the earliest matching generation to be adopted, got %q", result.Items[1].GenerationID)
+	}
+	if strings.Join(created, ",") != "owl,cat" {
+		t.Errorf("expected only the unmatched and pending items to be created, got %v", created)
+	}
+	if result.Items[2].GenerationID != "gen-new-owl" || result.Items[2].Attempts != 2 || result.Items[2].IdempotencyKey != "k3" {
+		t.Errorf("expected the owl item to be resubmitted with its key, got %+v", result.Items[2])
+	}
+	if len(reported) != 3 || reported[0] != 1 {
+		t.Errorf("expected items 1-3 to be reported, got %v", reported)
+	}
+}
+
+func TestRunBatch_StopsWhenCheckpointFails(t *testing.T) {
+	fake := &fakeLeonardoClient{createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+		t.Fatal("unexpected create after a failed checkpoint")
+		return domain.GenerationResponse{}, nil
+	}}
+	svc := service.NewGenerationService(fake)
+	manifest := domain.NewBatchManifest([]domain.GenerationRequest{{Metadata: domain.GenerationMetadata{Prompt: "a"}}})
+
+	_, err := svc.RunBatch(manifest, nil, func(domain.BatchManifest) error { return errors.New("disk full") })
+
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the checkpoint error, got %v", err)
+	}
+}
+
+func TestRunBatch_LeavesCutOffItemInDoubtWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	creates := 0
+	fake := &fakeLeonardoClient{createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+		creates++
+		cancel()
+		return domain.GenerationResponse{}, context.Canceled
+	}}
+	svc := service.NewGenerationService(fake)
+	svc.SetContext(ctx)
+	manifest := domain.NewBatchManifest([]domain.GenerationRequest{
+		{Metadata: domain.GenerationMetadata{Prompt: "a"}},
+		{Metadata: domain.GenerationMetadata{Prompt: "b"}},
+	})
+	var saved domain.BatchManifest
+
+	_, err := svc.RunBatch(manifest, nil, func(m domain.BatchManifest) error {
+		saved = domain.BatchManifest{Items: append([]domain.BatchItem(nil), m.Items...)}
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the run to stop with the cancellation, got %v", err)
+	}
+	if creates != 1 {
+		t.Errorf("expected no submissions after the cancellation, got %d", creates)
+	}
+	if !saved.Items[0].InDoubt() || saved.Items[0].Failed() {
+		t.Errorf("expected the cut-off item to be saved in doubt, got %+v", saved.Items[0])
+	}
+	if !saved.Items[1].Pending() || !saved.Items[1].SubmittedAt.IsZero() {
+		t.Errorf("expected the next item to stay pending, got %+v", saved.Items[1])
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// CharacterService keeps the named subject references that create
+// --character applies, so a series of generations shows the same subject.
+type CharacterService struct {
+	store ports.CharacterStore
+}
+
+// NewCharacterService constructs a new CharacterService given a store.
+func NewCharacterService(store ports.CharacterStore) *CharacterService {
+	return &CharacterService{store: store}
+}
+
+// Save validates and stores a character, replacing one with the same name.
+func (s *CharacterService) Save(character domain.Character) error {
+	if err := character.Validate(); err != nil {
+		return err
+	}
+	if character.CreatedAt.IsZero() {
+		character.CreatedAt = time.Now().UTC()
+	}
+	return s.store.Save(character)
+}
+
+// Get returns the character called name.
+func (s *CharacterService) Get(name string) (domain.Character, error) {
+	characters, err := s.store.List()
+	if err != nil {
+		return domain.Character{}, err
+	}
+	for _, c := range characters {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return domain.Character{}, fmt.Errorf("no character named %q (see character list)", name)
+}
+
+// List returns every character sorted by name.
+func (s *CharacterService) List() ([]domain.Character, error) {
+	return s.store.List()
+}
+
+// Remove deletes the character called name.
+func (s *CharacterService) Remove(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+	return s.store.Remove(name)
+}
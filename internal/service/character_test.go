@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeCharacterStore implements ports.CharacterStore in memory.
+type fakeCharacterStore struct {
+	characters map[string]domain.Character
+}
+
+func (f *fakeCharacterStore) Save(character domain.Character) error {
+	f.characters[character.Name] = character
+	return nil
+}
+
+func (f *fakeCharacterStore) List() ([]domain.Character, error) {
+	var list []domain.Character
+	for _, c := range f.characters {
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func (f *fakeCharacterStore) Remove(name string) error {
+	delete(f.characters, name)
+	return nil
+}
+
+func TestCharacterService_SavesValidCharactersOnly(t *testing.T) {
+	store := &fakeCharacterStore{characters: map[string]domain.Character{}}
+	characters := service.NewCharacterService(store)
+
+	var invalid *domain.InvalidRequestError
+	for _, c := range []domain.Character{
+		{Name: "mira"},
+		{Name: "two words", InitImageID: "init-1"},
+		{Name: "mira", StyleUUID: "style-1", InitStrength: 0.5},
+		{Name: "mira", InitImageID: "init-1", InitStrength: 1.5},
+	} {
+		if err := characters.Save(c); !errors.As(err, &invalid) {
+			t.Errorf("expected %+v to be rejected, got %v", c, err)
+		}
+	}
+
+	if err := characters.Save(domain.Character{Name: "mira", InitImageID: "init-1", InitStrength: 0.4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := characters.Get("mira")
+	if err != nil || got.InitImageID != "init-1" || got.CreatedAt.IsZero() {
+		t.Errorf("expected the saved character with a creation time, got %+v, %v", got, err)
+	}
+}
+
+func TestCharacterService_ReportsUnknownNames(t *testing.T) {
+	characters := service.NewCharacterService(&fakeCharacterStore{characters: map[string]domain.Character{}})
+
+	if _, err := characters.Get("ghost"); err == nil {
+		t.Error("expected an error for an unknown character")
+	}
+	if err := characters.Remove("ghost"); err == nil {
+		t.Error("expected removing an unknown character to fail")
+	}
+}
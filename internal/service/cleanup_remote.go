@@ -0,0 +1,43 @@
+package service
+
+import (
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// RemoteCleanupCandidates walks every generation of userID and returns the
+// ones filter selects at time now, newest first.
+func (s *GenerationService) RemoteCleanupCandidates(userID string, filter domain.RemoteCleanupFilter, now time.Time) ([]domain.GenerationListItem, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	var matches []domain.GenerationListItem
+	err := s.IterateGenerations(s.ctx, userID, func(item domain.GenerationListItem) error {
+		if filter.Matches(item, now) {
+			matches = append(matches, item)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// DeleteGenerations deletes each of ids in turn, passing every outcome to
+// report, and returns how many deletions failed.  A failure does not stop
+// the others; cancelling the service's context does.
+func (s *GenerationService) DeleteGenerations(ids []string, report func(id string, err error)) int {
+	failed := 0
+	for _, id := range ids {
+		if err := s.ctx.Err(); err != nil {
+			return failed
+		}
+		_, err := s.client.DeleteGeneration(id)
+		if err != nil {
+			failed++
+		}
+		if report != nil {
+			report(id, err)
+		}
+	}
+	return failed
+}
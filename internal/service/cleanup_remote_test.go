@@ -0,0 +1,92 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// --- Behavior: Cleaning up the remote generation history ---
+
+func TestRemoteCleanupCandidates_SelectsFailedAndStuckGenerations(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	history := []domain.GenerationListItem{
+		{ID: "running", Status: "PENDING", CreatedAt: now.Add(-5 * time.Minute)},
+		{ID: "failed", Status: "FAILED", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "done", Status: "COMPLETE", CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "stuck", Status: "PENDING", CreatedAt: now.Add(-4 * time.Hour)},
+	}
+	fake := &fakeLeonardoClient{
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			if offset > 0 {
+				return domain.GenerationListResponse{}, nil
+			}
+			return domain.GenerationListResponse{Generations: history}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	filter := domain.RemoteCleanupFilter{Statuses: []string{"FAILED", "PENDING"}, OlderThan: time.Hour}
+	got, err := svc.RemoteCleanupCandidates("user-1", filter, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, gen := range got {
+		ids = append(ids, gen.ID)
+	}
+	if strings.Join(ids, ",") != "failed,stuck" {
+		t.Errorf("expected failed and stuck, got %v", ids)
+	}
+}
+
+func TestRemoteCleanupCandidates_RefusesPendingWithoutAnAgeLimit(t *testing.T) {
+	svc := service.NewGenerationService(&fakeLeonardoClient{})
+
+	_, err := svc.RemoteCleanupCandidates("user-1", domain.RemoteCleanupFilter{Statuses: []string{"PENDING"}}, time.Now())
+
+	if err == nil || !strings.Contains(err.Error(), "PENDING") {
+		t.Errorf("expected an error about PENDING, got %v", err)
+	}
+}
+
+func TestRemoteCleanupCandidates_RefusesCompleteAndUnknownStatuses(t *testing.T) {
+	svc := service.NewGenerationService(&fakeLeonardoClient{})
+
+	for _, status := range []string{"COMPLETE", "complete", "DELETED"} {
+		filter := domain.RemoteCleanupFilter{Statuses: []string{"FAILED", status}, OlderThan: time.Hour}
+		if _, err := svc.RemoteCleanupCandidates("user-1", filter, time.Now()); err == nil || !strings.Contains(err.Error(), strings.ToUpper(status)) {
+			t.Errorf("expected %s to be refused, got %v", status, err)
+		}
+	}
+}
+
+func TestDeleteGenerations_KeepsGoingAfterAFailure(t *testing.T) {
+	var deleted []string
+	fake := &fakeLeonardoClient{
+		deleteFn: func(id string) (domain.DeleteResponse, error) {
+			if id == "b" {
+				return domain.DeleteResponse{}, errors.New("API returned status 500")
+			}
+			deleted = append(deleted, id)
+			return domain.DeleteResponse{ID: id}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	var reported []string
+	failed := svc.DeleteGenerations([]string{"a", "b", "c"}, func(id string, err error) {
+		reported = append(reported, id)
+	})
+
+	if failed != 1 {
+		t.Errorf("expected 1 failure, got %d", failed)
+	}
+	if strings.Join(deleted, ",") != "a,c" || len(reported) != 3 {
+		t.Errorf("expected a and c deleted and every outcome reported, got %v and %v", deleted, reported)
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// DedupeService keeps repeated downloads of the same image from taking the
+// space more than once: each downloaded file is recorded by its SHA-256,
+// and a file identical to one already recorded is replaced with a hard link
+// to it.  Linked files share their data, so an image edited in place
+// changes in every directory it was downloaded to.
+type DedupeService struct {
+	store ports.DownloadStore
+}
+
+// NewDedupeService constructs a new DedupeService recording downloads in
+// store.
+func NewDedupeService(store ports.DownloadStore) *DedupeService {
+	return &DedupeService{store: store}
+}
+
+// Add records the downloaded file at path and, when an identical file is
+// already recorded, replaces it with a hard link to that file and returns
+// the file's path.  It returns "" when the content is new or the link
+// cannot be made — across file systems, or where hard links are not
+// supported — in which case the copy is kept.
+func (s *DedupeService) Add(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	image, err := storedImage(path)
+	if err != nil {
+		return "", err
+	}
+	// Recording first, in one step with the lookup, lets a concurrent
+	// download of the same image find this file and link to it.
+	identical, err := s.store.Record(image)
+	if err != nil {
+		return "", fmt.Errorf("recording %s: %w", path, err)
+	}
+	linked := ""
+	for _, r := range identical {
+		shared, err := sameFile(r.Path, path)
+		if err != nil {
+			continue
+		}
+		if !shared {
+			// The recorded file may have been edited since.
+			if current, err := storedImage(r.Path); err != nil || current != r {
+				continue
+			}
+			if err := replaceWithLink(r.Path, path); err != nil {
+				break
+			}
+		}
+		linked = r.Path
+		break
+	}
+	return linked, nil
+}
+
+// GC checks every recorded download: the records of files that no longer
+// exist are forgotten, changed files are hashed again, and identical files
+// not yet sharing their data are hard-linked to the first one recorded.
+// With dryRun nothing is changed and the report tells what would be.
+func (s *DedupeService) GC(dryRun bool) (domain.DedupeReport, error) {
+	var report domain.DedupeReport
+	records, err := s.store.List()
+	if err != nil {
+		return report, err
+	}
+	var present []domain.StoredImage
+	for _, r := range records {
+		current, err := storedImage(r.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			report.Forgotten++
+			if !dryRun {
+				if err := s.store.Remove(r.Path); err != nil {
+					return report, err
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		if current != r && !dryRun {
+			if err := s.store.Save(current); err != nil {
+				return report, err
+			}
+		}
+		present = append(present, current)
+	}
+
+	report.Files = len(present)
+	first := map[string]string{}
+	for _, image := range present {
+		original, seen := first[image.SHA256]
+		if !seen {
+			first[image.SHA256] = image.Path
+			report.Distinct++
+			continue
+		}
+		shared, err := sameFile(original, image.Path)
+		if err != nil {
+			return report, err
+		}
+		if !shared {
+			if !dryRun {
+				if err := replaceWithLink(original, image.Path); err != nil {
+					// Stays a copy, e.g. on another file system.
+					continue
+				}
+			}
+			report.Linked++
+		}
+		report.SavedBytes += image.Size
+	}
+	return report, nil
+}
+
+// storedImage hashes the file at path.
+func storedImage(path string) (domain.StoredImage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return domain.StoredImage{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return domain.StoredImage{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return domain.StoredImage{Path: path, SHA256: hex.EncodeToString(h.Sum(nil)), Size: size}, nil
+}
+
+// sameFile reports whether a and b are links to the same data.
+func sameFile(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(infoA, infoB), nil
+}
+
+// replaceWithLink replaces the file at path with a hard link to target.
+// The link is made next to path first and renamed over it, so path is
+// never missing.
+func replaceWithLink(target, path string) error {
+	tmp := path + ".link"
+	os.Remove(tmp)
+	if err := os.Link(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeDownloadStore implements ports.DownloadStore in memory.
+type fakeDownloadStore struct {
+	images []domain.StoredImage
+}
+
+func (f *fakeDownloadStore) Save(image domain.StoredImage) error {
+	for i := range f.images {
+		if f.images[i].Path == image.Path {
+			f.images[i] = image
+			return nil
+		}
+	}
+	f.images = append(f.images, image)
+	return nil
+}
+
+func (f *fakeDownloadStore) Record(image domain.StoredImage) ([]domain.StoredImage, error) {
+	var identical []domain.StoredImage
+	for _, other := range f.images {
+		if other.Path != image.Path && other.SHA256 == image.SHA256 && other.Size == image.Size {
+			identical = append(identical, other)
+		}
+	}
+	return identical, f.Save(image)
+}
+
+func (f *fakeDownloadStore) List() ([]domain.StoredImage, error) {
+	return append([]domain.StoredImage(nil), f.images...), nil
+}
+
+func (f *fakeDownloadStore) Remove(path string) error {
+	kept := f.images[:0]
+	for _, image := range f.images {
+		if image.Path != path {
+			kept = append(kept, image)
+		}
+	}
+	f.images = kept
+	return nil
+}
+
+func writeFiles(t *testing.T, contents map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range contents {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func linked(t *testing.T, a, b string) bool {
+	t.Helper()
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		t.Fatalf("stat: %v, %v", errA, errB)
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+func TestDedupeAdd_LinksAnIdenticalDownloadToTheFirstCopy(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a/img.png": "pixels", "b/img.png": "pixels", "c/other.png": "different"})
+	store := &fakeDownloadStore{}
+	svc := service.NewDedupeService(store)
+	first, second, other := filepath.Join(dir, "a/img.png"), filepath.Join(dir, "b/img.png"), filepath.Join(dir, "c/other.png")
+
+	if to, err := svc.Add(first); err != nil || to != "" {
+		t.Fatalf("expected the first copy to be kept, got %q, %v", to, err)
+	}
+	to, err := svc.Add(second)
+	if err != nil || to != first {
+		t.Fatalf("expected the second copy to be linked to %s, got %q, %v", first, to, err)
+	}
+	if !linked(t, first, second) {
+		t.Error("expected the two copies to share their data")
+	}
+	if to, _ := svc.Add(other); to != "" {
+		t.Errorf("expected a different image to be kept, got a link to %q", to)
+	}
+	if len(store.images) != 3 {
+		t.Errorf("expected every download to be recorded, got %+v", store.images)
+	}
+}
+
+func TestDedupeGC_ForgetsMissingFilesAndLinksRemainingCopies(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.png": "pixels", "b.png": "pixels", "gone.png": "x"})
+	store := &fakeDownloadStore{}
+	svc := service.NewDedupeService(store)
+	for _, name := range []string{"a.png", "gone.png"} {
+		if _, err := svc.Add(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// b.png was recorded while it differed, so it was not linked then.
+	store.Save(domain.StoredImage{Path: filepath.Join(dir, "b.png"), SHA256: "stale", Size: 6})
+	os.Remove(filepath.Join(dir, "gone.png"))
+
+	preview, err := svc.GC(true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preview.Linked != 1 || preview.Forgotten != 1 || linked(t, filepath.Join(dir, "a.png"), filepath.Join(dir, "b.png")) {
+		t.Fatalf("expected a dry run to report without changing anything, got %+v", preview)
+	}
+
+	report, err := svc.GC(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := domain.DedupeReport{Files: 2, Distinct: 1, Linked: 1, Forgotten: 1, SavedBytes: 6}
+	if report != want {
+		t.Errorf("expected %+v, got %+v", want, report)
+	}
+	if !linked(t, filepath.Join(dir, "a.png"), filepath.Join(dir, "b.png")) {
+		t.Error("expected the copies to be linked")
+	}
+	if len(store.images) != 2 {
+		t.Errorf("expected the missing file to be forgotten, got %+v", store.images)
+	}
+	if again, _ := svc.GC(false); again.Linked != 0 || again.SavedBytes != 6 {
+		t.Errorf("expected a second run to find the link in place, got %+v", again)
+	}
+}
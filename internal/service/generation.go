@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"leonardo-cli/internal/domain"
 	"leonardo-cli/internal/ports"
@@ -12,17 +16,137 @@ import (
 // monitoring image generations.  It depends on a LeonardoClient port which
 // abstracts the underlying API.
 type GenerationService struct {
-	client ports.LeonardoClient
+	client   ports.LeonardoClient
+	progress func(domain.ProgressEvent)
+	ctx      context.Context
+	failFast bool
+	batch    domain.BatchConcurrency
+	fs       domain.FileSystem
+	upscale  domain.UpscaleTarget
+	// stuckAfter and stuckAlert warn about waits on a stuck generation.
+	stuckAfter time.Duration
+	stuckAlert func(id string, pending time.Duration)
+
+	mu         sync.Mutex
+	unfinished []string // created generations not yet seen to finish
 }
 
 // NewGenerationService constructs a new GenerationService given a client.
 func NewGenerationService(client ports.LeonardoClient) *GenerationService {
-	return &GenerationService{client: client}
+	return &GenerationService{client: client, ctx: context.Background()}
+}
+
+// SetContext makes waits and batch runs stop once ctx is done, for example
+// on Ctrl-C.
+func (s *GenerationService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// SetFailFast makes downloads stop at the first file that fails, skipping
+// the rest, instead of attempting every file and reporting each outcome.
+func (s *GenerationService) SetFailFast(failFast bool) {
+	s.failFast = failFast
+}
+
+// SetFileSystem makes the file names chosen by callers, such as those of
+// DownloadAs, valid on fs.  Without it they must be valid everywhere.
+func (s *GenerationService) SetFileSystem(fs domain.FileSystem) {
+	s.fs = fs
+}
+
+// SetUpscaleTarget makes upscale variations reach target, keeping each
+// image's aspect ratio, with the universal upscaler.  Without it they use
+// the standard upscaler's fixed factor.
+func (s *GenerationService) SetUpscaleTarget(target domain.UpscaleTarget) {
+	s.upscale = target
+}
+
+// SetStuckAlert makes waits call alert, once per wait, when the generation
+// has been pending for longer than after since it was submitted.  The wait
+// goes on; the alert lets the user decide whether to give up.
+func (s *GenerationService) SetStuckAlert(after time.Duration, alert func(id string, pending time.Duration)) {
+	s.stuckAfter, s.stuckAlert = after, alert
+}
+
+// SetBatchConcurrency lets batch runs submit several items at once, adapting
+// how many to the API's rate limits.  Without it items are submitted one at
+// a time.
+func (s *GenerationService) SetBatchConcurrency(c domain.BatchConcurrency) {
+	s.batch = c
+}
+
+// Unfinished returns the IDs of the generations this service created that
+// it has not seen finish, oldest first.
+func (s *GenerationService) Unfinished() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.unfinished...)
+}
+
+// refreshUnfinished checks the status of every generation not yet seen to
+// finish and forgets those that have.  Generations whose status cannot be
+// read are forgotten too, so a lookup error cannot hold a run back forever.
+func (s *GenerationService) refreshUnfinished() {
+	for _, id := range s.Unfinished() {
+		status, err := s.client.GetGenerationStatus(id)
+		s.trackFinished(id, err != nil || status.Finished())
+	}
+}
+
+// pending returns how many generations count against the pending cap of
+// batch runs: those not yet seen to finish and the inFlight submissions
+// that may be about to join them.
+func (s *GenerationService) pending(inFlight int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return inFlight + len(s.unfinished)
+}
+
+// trackFinished records whether a generation has finished.
+func (s *GenerationService) trackFinished(id string, finished bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, pending := range s.unfinished {
+		if pending == id {
+			if finished {
+				s.unfinished = append(s.unfinished[:i], s.unfinished[i+1:]...)
+			}
+			return
+		}
+	}
+	if !finished {
+		s.unfinished = append(s.unfinished, id)
+	}
+}
+
+// SetProgress registers fn to be called as generations are submitted,
+// polled and downloaded.  A nil fn disables progress reporting.
+func (s *GenerationService) SetProgress(fn func(domain.ProgressEvent)) {
+	s.progress = fn
+}
+
+// report passes ev to the progress callback, if any.
+func (s *GenerationService) report(ev domain.ProgressEvent) {
+	if s.progress != nil {
+		s.progress(ev)
+	}
 }
 
 // Create starts a new generation by delegating to the underlying client.
+// Requests with inconsistent parameters are rejected before any API call.
 func (s *GenerationService) Create(req domain.GenerationRequest) (domain.GenerationResponse, error) {
-	return s.client.CreateGeneration(req)
+	if err := req.Metadata.ValidateAlchemy(); err != nil {
+		return domain.GenerationResponse{}, err
+	}
+	if err := req.Metadata.ValidateInitImage(); err != nil {
+		return domain.GenerationResponse{}, err
+	}
+	res, err := s.client.CreateGeneration(req)
+	if err == nil {
+		s.trackFinished(res.GenerationID, false)
+		s.report(domain.ProgressEvent{Kind: domain.ProgressSubmitted, GenerationID: res.GenerationID})
+	}
+	return res, err
 }
 
 // Status retrieves the status of an existing generation by delegating to the client.
@@ -30,6 +154,11 @@ func (s *GenerationService) Status(id string) (domain.GenerationStatus, error) {
 	return s.client.GetGenerationStatus(id)
 }
 
+// Show retrieves the complete record of a generation by delegating to the client.
+func (s *GenerationService) Show(id string) (domain.GenerationDetail, error) {
+	return s.client.GetGeneration(id)
+}
+
 // Delete removes a generation by its ID by delegating to the client.
 func (s *GenerationService) Delete(id string) (domain.DeleteResponse, error) {
 	return s.client.DeleteGeneration(id)
@@ -45,30 +174,217 @@ func (s *GenerationService) ListGenerations(userID string, offset, limit int) (d
 	return s.client.ListGenerations(userID, offset, limit)
 }
 
+// recentLookupLimit is the number of most recent generations searched when
+// resolving an ID prefix against the API.
+const recentLookupLimit = 50
+
+// ResolveRecent expands a generation ID prefix by matching it against the
+// authenticated user's most recent generations.  Full IDs, prefixes that
+// are too short and prefixes that match nothing are returned unchanged.
+func (s *GenerationService) ResolveRecent(prefix string) (string, error) {
+	if domain.IsFullGenerationID(prefix) || len(prefix) < domain.MinIDPrefixLength {
+		return prefix, nil
+	}
+	info, err := s.client.GetUserInfo()
+	if err != nil {
+		return "", fmt.Errorf("looking up user for prefix resolution: %w", err)
+	}
+	list, err := s.client.ListGenerations(info.UserID, 0, recentLookupLimit)
+	if err != nil {
+		return "", fmt.Errorf("listing recent generations: %w", err)
+	}
+	ids := make([]string, 0, len(list.Generations))
+	for _, g := range list.Generations {
+		ids = append(ids, g.ID)
+	}
+	id, ok, err := domain.MatchIDPrefix(prefix, ids)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return prefix, nil
+	}
+	return id, nil
+}
+
+// statusComplete is the generation status reported by the API once all
+// images are ready.
+const statusComplete = domain.GenerationComplete
+
+// AwaitCompletion waits for a generation to complete or fail, returning its
+// final status.  Checks start interval apart and back off from there, as
+// implemented by the client's WaitForCompletion.  A generation pending for
+// too long is reported to the stuck alert (see SetStuckAlert).  A positive timeout bounds
+// the wait; when it elapses the last status seen is returned with an error.
+func (s *GenerationService) AwaitCompletion(id string, interval, timeout time.Duration) (domain.GenerationStatus, error) {
+	alerted := false
+	return s.client.WaitForCompletion(s.ctx, id, domain.WaitOptions{
+		Interval: interval,
+		Timeout:  timeout,
+		OnPoll: func(status domain.GenerationStatus, attempt int) {
+			if status.Finished() {
+				s.trackFinished(id, true)
+			}
+			if pending, stuck := domain.StuckFor(status.Status, status.CreatedAt, time.Now(), s.stuckAfter); stuck && s.stuckAlert != nil && !alerted {
+				alerted = true
+				s.stuckAlert(id, pending)
+			}
+			s.report(domain.ProgressEvent{Kind: domain.ProgressPolling, GenerationID: id, Status: status.Status, Attempt: attempt})
+		},
+	})
+}
+
 // Download fetches the status of a generation and downloads all generated
 // images to the specified output directory.  Files are named using the pattern
 // {generationID}_{index}.png.  It returns an error if the generation is not
-// complete or has no images.
+// complete or has no images.  A failed image does not stop the others: every
+// outcome is recorded in the result, and the error then summarizes the
+// failures (see SetFailFast).
 func (s *GenerationService) Download(id, outputDir string) (domain.DownloadResult, error) {
+	return s.DownloadAs(id, outputDir, id)
+}
+
+// DownloadAs downloads all images of a completed generation like Download,
+// naming the files {name}_{index}.png instead, with name made a valid file
+// name (see SetFileSystem).
+func (s *GenerationService) DownloadAs(id, outputDir, name string) (domain.DownloadResult, error) {
 	status, err := s.client.GetGenerationStatus(id)
 	if err != nil {
 		return domain.DownloadResult{}, err
 	}
-	if status.Status != "COMPLETE" {
+	if status.Status != statusComplete {
 		return domain.DownloadResult{}, fmt.Errorf("generation is not complete, current status: %s", status.Status)
 	}
 	if len(status.Images) == 0 {
 		return domain.DownloadResult{}, fmt.Errorf("no images available for generation %s", id)
 	}
-	var filePaths []string
+	name = s.fs.SanitizeFileName(name, len(fmt.Sprintf("_%d.png", len(status.Images))))
+	var result domain.DownloadResult
 	for i, imgURL := range status.Images {
-		destPath := filepath.Join(outputDir, fmt.Sprintf("%s_%d.png", id, i+1))
-		if err := s.client.DownloadImage(imgURL, destPath); err != nil {
-			return domain.DownloadResult{}, fmt.Errorf("downloading image %d: %w", i+1, err)
+		destPath := filepath.Join(outputDir, fmt.Sprintf("%s_%d.png", name, i+1))
+		outcome := s.downloadFile(&result, imgURL, domain.ImageDownload{Image: i + 1, Path: destPath})
+		if outcome.Outcome == domain.DownloadSaved {
+			result.FilePaths = append(result.FilePaths, destPath)
+			s.report(domain.ProgressEvent{Kind: domain.ProgressImageDownloaded, GenerationID: id, Image: i + 1, Images: len(status.Images), Path: destPath})
+		}
+	}
+	return result, result.Err()
+}
+
+// downloadFile downloads one file of a generation, records its outcome in
+// result and returns it.  Once a file has failed in fail-fast mode, the
+// remaining files are skipped without a request.
+func (s *GenerationService) downloadFile(result *domain.DownloadResult, url string, outcome domain.ImageDownload) domain.ImageDownload {
+	switch {
+	case s.failFast && result.Count(domain.DownloadFailed) > 0:
+		outcome.Outcome = domain.DownloadSkipped
+	default:
+		outcome.Outcome = domain.DownloadSaved
+		if err := s.client.DownloadImage(url, outcome.Path); err != nil {
+			what := fmt.Sprintf("image %d", outcome.Image)
+			if outcome.Variation != "" {
+				what = fmt.Sprintf("%s variation of image %d", outcome.Variation, outcome.Image)
+			}
+			outcome.Outcome = domain.DownloadFailed
+			outcome.Err = fmt.Errorf("downloading %s: %w", what, err)
 		}
-		filePaths = append(filePaths, destPath)
 	}
-	return domain.DownloadResult{FilePaths: filePaths}, nil
+	result.Outcomes = append(result.Outcomes, outcome)
+	return outcome
+}
+
+// DownloadWithVariations downloads the images of a completed generation like
+// Download, followed by every finished variation of each image (upscales,
+// background removals, ...).  Variations are named
+// {generationID}_{index}_{suffix}.png, with a counter appended when an image
+// has several variations of the same type.
+func (s *GenerationService) DownloadWithVariations(id, outputDir string) (domain.DownloadResult, error) {
+	result, err := s.Download(id, outputDir)
+	if len(result.Outcomes) == 0 {
+		return result, err
+	}
+	detail, err := s.client.GetGeneration(id)
+	if err != nil {
+		return result, fmt.Errorf("fetching variations: %w", err)
+	}
+	for i, img := range detail.Images {
+		seen := map[string]int{}
+		for _, v := range img.Variations {
+			if v.Status != statusComplete || v.URL == "" {
+				continue
+			}
+			suffix := v.FileSuffix()
+			seen[suffix]++
+			if seen[suffix] > 1 {
+				suffix = fmt.Sprintf("%s_%d", suffix, seen[suffix])
+			}
+			destPath := filepath.Join(outputDir, fmt.Sprintf("%s_%d_%s.png", id, i+1, suffix))
+			outcome := s.downloadFile(&result, v.URL, domain.ImageDownload{Image: i + 1, Variation: v.FileSuffix(), Path: destPath})
+			if outcome.Outcome == domain.DownloadSaved {
+				result.Variations = append(result.Variations, domain.DownloadedVariation{Image: i + 1, Variation: v, Path: destPath})
+			}
+		}
+	}
+	return result, result.Err()
+}
+
+// EstimateDownload returns the expected number of bytes needed to download
+// every image of the given generations, and their variations when
+// includeVariations is set, so callers can check for disk space up front.
+func (s *GenerationService) EstimateDownload(ids []string, includeVariations bool) (int64, error) {
+	var total int64
+	for _, id := range ids {
+		detail, err := s.client.GetGeneration(id)
+		if err != nil {
+			return 0, err
+		}
+		total += detail.EstimatedDownloadBytes(includeVariations)
+	}
+	return total, nil
+}
+
+// DownloadRepresentative downloads the first image of a completed generation
+// to outputDir, as {generationID}_1.png, and returns its path.  It is used
+// where one image stands for the whole generation, such as comparisons.
+func (s *GenerationService) DownloadRepresentative(id, outputDir string) (string, error) {
+	status, err := s.client.GetGenerationStatus(id)
+	if err != nil {
+		return "", err
+	}
+	if status.Status != statusComplete {
+		return "", fmt.Errorf("generation %s is not complete, current status: %s", id, status.Status)
+	}
+	if len(status.Images) == 0 {
+		return "", fmt.Errorf("no images available for generation %s", id)
+	}
+	destPath := filepath.Join(outputDir, fmt.Sprintf("%s_1.png", id))
+	if err := s.client.DownloadImage(status.Images[0], destPath); err != nil {
+		return "", fmt.Errorf("downloading image: %w", err)
+	}
+	return destPath, nil
+}
+
+// StreamImage writes the bytes of image number image (starting at 1) of a
+// completed generation to w, for piping them into another program without
+// saving a file.
+func (s *GenerationService) StreamImage(id string, image int, w io.Writer) error {
+	status, err := s.client.GetGenerationStatus(id)
+	if err != nil {
+		return err
+	}
+	if status.Status != statusComplete {
+		return fmt.Errorf("generation %s is not complete, current status: %s", id, status.Status)
+	}
+	if len(status.Images) == 0 {
+		return fmt.Errorf("no images available for generation %s", id)
+	}
+	if image < 1 || image > len(status.Images) {
+		return fmt.Errorf("generation %s has %d images, there is no image %d", id, len(status.Images), image)
+	}
+	if err := s.client.StreamImage(status.Images[image-1], w); err != nil {
+		return fmt.Errorf("downloading image %d: %w", image, err)
+	}
+	return nil
 }
 
 // ListPlatformModels retrieves the available platform models by delegating to the client.
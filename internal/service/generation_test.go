@@ -1,11 +1,15 @@
 package service_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"leonardo-cli/internal/domain"
 	"leonardo-cli/internal/service"
@@ -15,13 +19,19 @@ import (
 // layer at the port boundary. We stub only the port — never internal
 // collaborators — following Cooper's guidance on hexagonal testing.
 type fakeLeonardoClient struct {
-	createFn   func(req domain.GenerationRequest) (domain.GenerationResponse, error)
-	statusFn   func(id string) (domain.GenerationStatus, error)
-	deleteFn   func(id string) (domain.DeleteResponse, error)
-	userFn     func() (domain.UserInfo, error)
-	listFn     func(userID string, offset, limit int) (domain.GenerationListResponse, error)
-	downloadFn func(url, destPath string) error
-	modelsFn   func() (domain.PlatformModelResponse, error)
+	createFn    func(req domain.GenerationRequest) (domain.GenerationResponse, error)
+	statusFn    func(id string) (domain.GenerationStatus, error)
+	getFn       func(id string) (domain.GenerationDetail, error)
+	deleteFn    func(id string) (domain.DeleteResponse, error)
+	userFn      func() (domain.UserInfo, error)
+	listFn      func(userID string, offset, limit int) (domain.GenerationListResponse, error)
+	downloadFn  func(url, destPath string) error
+	streamFn    func(url string, w io.Writer) error
+	modelsFn    func() (domain.PlatformModelResponse, error)
+	upscaleFn   func(imageID string) (domain.VariationJob, error)
+	universalFn func(imageID string, multiplier float64) (domain.VariationJob, error)
+	variationFn func(id string) (domain.ImageVariation, error)
+	createVarFn func(kind domain.VariationKind, imageID string) (domain.VariationJob, error)
 }
 
 func (f *fakeLeonardoClient) CreateGeneration(req domain.GenerationRequest) (domain.GenerationResponse, error) {
@@ -32,6 +42,32 @@ func (f *fakeLeonardoClient) GetGenerationStatus(id string) (domain.GenerationSt
 	return f.statusFn(id)
 }
 
+// WaitForCompletion polls statusFn at a fixed interval; the real backoff is
+// covered by the provider's tests.
+func (f *fakeLeonardoClient) WaitForCompletion(ctx context.Context, id string, opts domain.WaitOptions) (domain.GenerationStatus, error) {
+	deadline := time.Now().Add(opts.Timeout)
+	for attempt := 1; ; attempt++ {
+		status, err := f.statusFn(id)
+		if err != nil {
+			return status, err
+		}
+		if opts.OnPoll != nil {
+			opts.OnPoll(status, attempt)
+		}
+		if status.Finished() {
+			return status, nil
+		}
+		if opts.Timeout > 0 && time.Now().Add(opts.Interval).After(deadline) {
+			return status, fmt.Errorf("timed out after %s waiting for generation %s (status %s)", opts.Timeout, id, status.Status)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
+
+func (f *fakeLeonardoClient) GetGeneration(id string) (domain.GenerationDetail, error) {
+	return f.getFn(id)
+}
+
 func (f *fakeLeonardoClient) DeleteGeneration(id string) (domain.DeleteResponse, error) {
 	return f.deleteFn(id)
 }
@@ -48,10 +84,30 @@ func (f *fakeLeonardoClient) DownloadImage(url, destPath string) error {
 	return f.downloadFn(url, destPath)
 }
 
+func (f *fakeLeonardoClient) StreamImage(url string, w io.Writer) error {
+	return f.streamFn(url, w)
+}
+
 func (f *fakeLeonardoClient) ListPlatformModels() (domain.PlatformModelResponse, error) {
 	return f.modelsFn()
 }
 
+func (f *fakeLeonardoClient) UpscaleImage(imageID string) (domain.VariationJob, error) {
+	return f.upscaleFn(imageID)
+}
+
+func (f *fakeLeonardoClient) UniversalUpscale(imageID string, multiplier float64) (domain.VariationJob, error) {
+	return f.universalFn(imageID, multiplier)
+}
+
+func (f *fakeLeonardoClient) GetVariation(id string) (domain.ImageVariation, error) {
+	return f.variationFn(id)
+}
+
+func (f *fakeLeonardoClient) CreateVariation(kind domain.VariationKind, imageID string) (domain.VariationJob, error) {
+	return f.createVarFn(kind, imageID)
+}
+
 // --- Behavior: Creating a generation ---
 
 func TestCreate_ReturnsGenerationIDAndRawResponse(t *testing.T) {
@@ -162,6 +218,35 @@ func TestCreate_PassesAllRequestFieldsToClient(t *testing.T) {
 	}
 }
 
+func TestCreate_RejectsAlchemyFieldsWithoutAlchemy(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			t.Fatal("request should not reach the client")
+			return domain.GenerationResponse{}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	_, err := svc.Create(domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "p", HighResolution: true, ContrastRatio: 0.5}})
+
+	if err == nil || !strings.Contains(err.Error(), "high resolution, contrast ratio require Alchemy") {
+		t.Errorf("expected alchemy validation error, got %v", err)
+	}
+	if domain.ClassifyFailure(err) != domain.FailurePermanent {
+		t.Errorf("expected validation errors to be permanent, got %q", domain.ClassifyFailure(err))
+	}
+}
+
+func TestCreate_RejectsContrastRatioOutOfRange(t *testing.T) {
+	svc := service.NewGenerationService(&fakeLeonardoClient{})
+
+	_, err := svc.Create(domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "p", Alchemy: true, ContrastRatio: 1.5}})
+
+	if err == nil || !strings.Contains(err.Error(), "between 0 and 1") {
+		t.Errorf("expected range error, got %v", err)
+	}
+}
+
 func TestCreate_PropagatesClientError(t *testing.T) {
 	fake := &fakeLeonardoClient{
 		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
@@ -275,6 +360,92 @@ func TestStatus_PropagatesClientError(t *testing.T) {
 	}
 }
 
+// --- Behavior: Showing a full generation record ---
+
+func TestShow_ReturnsGenerationDetailFromClient(t *testing.T) {
+	var capturedID string
+	fake := &fakeLeonardoClient{
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			capturedID = id
+			return domain.GenerationDetail{ID: id, Scheduler: "LEONARDO"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	detail, err := svc.Show("gen-detail")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if capturedID != "gen-detail" {
+		t.Errorf("expected ID %q passed to client, got %q", "gen-detail", capturedID)
+	}
+	if detail.Scheduler != "LEONARDO" {
+		t.Errorf("expected scheduler %q, got %q", "LEONARDO", detail.Scheduler)
+	}
+}
+
+func TestShow_PropagatesClientError(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{}, errors.New("API returned status 404")
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	_, err := svc.Show("missing")
+
+	if err == nil || err.Error() != "API returned status 404" {
+		t.Errorf("expected error %q, got %v", "API returned status 404", err)
+	}
+}
+
+// --- Behavior: Estimating download size ---
+
+func TestEstimateDownload_SumsImagesAndFinishedVariations(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{ID: id, Width: 512, Height: 512, Images: []domain.GeneratedImage{
+				{ID: "img-1", Variations: []domain.ImageVariation{
+					{TransformType: "UPSCALE", Status: "COMPLETE"},
+					{TransformType: "NOBG", Status: "PENDING"},
+				}},
+				{ID: "img-2"},
+			}}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	image := domain.EstimateImageBytes(512, 512)
+
+	plain, err := svc.EstimateDownload([]string{"gen-a", "gen-b"}, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if plain != 4*image {
+		t.Errorf("expected %d bytes without variations, got %d", 4*image, plain)
+	}
+	withVariations, err := svc.EstimateDownload([]string{"gen-a"}, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := 2*image + 4*image; withVariations != want {
+		t.Errorf("expected %d bytes with the upscale, got %d", want, withVariations)
+	}
+}
+
+func TestEstimateDownload_PropagatesClientError(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{}, errors.New("API returned status 404")
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	if _, err := svc.EstimateDownload([]string{"missing"}, false); err == nil {
+		t.Error("expected an error")
+	}
+}
+
 // --- Behavior: Deleting a generation ---
 
 func TestDelete_ReturnsDeletedIDAndRawResponse(t *testing.T) {
@@ -471,6 +642,142 @@ func TestListGenerations_PropagatesClientError(t *testing.T) {
 
 // --- Behavior: Downloading images for a generation ---
 
+func TestAwaitCompletion_PollsUntilComplete(t *testing.T) {
+	statuses := []string{"PENDING", "PENDING", "COMPLETE"}
+	calls := 0
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			status := statuses[calls]
+			calls++
+			return domain.GenerationStatus{Status: status}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	status, err := svc.AwaitCompletion("gen-1", time.Millisecond, time.Second)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Status != "COMPLETE" {
+		t.Errorf("expected status COMPLETE, got %q", status.Status)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 status checks, got %d", calls)
+	}
+}
+
+func TestAwaitCompletion_TimesOutWithLastStatus(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "PENDING"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	status, err := svc.AwaitCompletion("gen-slow", time.Millisecond, 5*time.Millisecond)
+
+	if err == nil {
+		t.Fatal("expected timeout error, got nil")
+	}
+	if status.Status != "PENDING" || !strings.Contains(err.Error(), "gen-slow") {
+		t.Errorf("expected last status and generation ID in error, got %q / %q", status.Status, err.Error())
+	}
+}
+
+func TestAwaitCompletion_AlertsOnceWhenTheGenerationIsStuck(t *testing.T) {
+	statuses := []string{"PENDING", "PENDING", "PENDING", "COMPLETE"}
+	calls := 0
+	submitted := time.Now().Add(-2 * time.Hour)
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			status := statuses[calls]
+			calls++
+			return domain.GenerationStatus{Status: status, CreatedAt: submitted}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	var alerts []string
+	svc.SetStuckAlert(time.Hour, func(id string, pending time.Duration) {
+		alerts = append(alerts, fmt.Sprintf("%s %s", id, pending.Round(time.Hour)))
+	})
+
+	if _, err := svc.AwaitCompletion("gen-1", time.Millisecond, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0] != "gen-1 2h0m0s" {
+		t.Errorf("expected one alert after 2h pending, got %v", alerts)
+	}
+}
+
+func TestUnfinished_ListsCreatedGenerationsUntilTheyFinish(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			return domain.GenerationResponse{GenerationID: "gen-" + req.Metadata.Prompt}, nil
+		},
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	for _, prompt := range []string{"a", "b", "c"} {
+		if _, err := svc.Create(domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: prompt}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := svc.AwaitCompletion("gen-b", time.Millisecond, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := strings.Join(svc.Unfinished(), ","); got != "gen-a,gen-c" {
+		t.Errorf("expected gen-a,gen-c to be unfinished, got %q", got)
+	}
+}
+
+func TestProgress_ReportsSubmitPollAndDownloadEvents(t *testing.T) {
+	polls := 0
+	fake := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			return domain.GenerationResponse{GenerationID: "gen-1"}, nil
+		},
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			polls++
+			if polls == 1 {
+				return domain.GenerationStatus{Status: "PENDING"}, nil
+			}
+			return domain.GenerationStatus{Status: "COMPLETE", Images: []string{"https://cdn/a.png", "https://cdn/b.png"}}, nil
+		},
+		downloadFn: func(url, destPath string) error { return nil },
+	}
+	svc := service.NewGenerationService(fake)
+	var events []string
+	svc.SetProgress(func(ev domain.ProgressEvent) {
+		events = append(events, fmt.Sprintf("%s %s %s %d %d/%d", ev.Kind, ev.GenerationID, ev.Status, ev.Attempt, ev.Image, ev.Images))
+	})
+
+	if _, err := svc.Create(domain.GenerationRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.AwaitCompletion("gen-1", time.Millisecond, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Download("gen-1", t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"submitted gen-1  0 0/0",
+		"polling gen-1 PENDING 1 0/0",
+		"polling gen-1 COMPLETE 2 0/0",
+		"image-downloaded gen-1  0 1/2",
+		"image-downloaded gen-1  0 2/2",
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected events:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(events, "\n"))
+	}
+}
+
 func TestDownload_DownloadsAllImagesAndReturnsFilePaths(t *testing.T) {
 	fake := &fakeLeonardoClient{
 		statusFn: func(id string) (domain.GenerationStatus, error) {
@@ -536,6 +843,26 @@ func TestDownload_UsesGenerationIDAndIndexInFilenames(t *testing.T) {
 	}
 }
 
+func TestDownloadAs_MakesTheNameValidOnTheTargetFileSystem(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE", Images: []string{"https://cdn.leonardo.ai/img1.png"}}, nil
+		},
+		downloadFn: func(url, destPath string) error { return nil },
+	}
+	svc := service.NewGenerationService(fake)
+	svc.SetFileSystem(domain.FileSystemWindows)
+
+	result, err := svc.DownloadAs("gen-xyz", "out", `draft: "v2"?`)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := filepath.Join("out", "draft_ _v2___1.png"); result.FilePaths[0] != want {
+		t.Errorf("expected file path %q, got %q", want, result.FilePaths[0])
+	}
+}
+
 func TestDownload_ReturnsErrorWhenGenerationNotComplete(t *testing.T) {
 	fake := &fakeLeonardoClient{
 		statusFn: func(id string) (domain.GenerationStatus, error) {
@@ -623,6 +950,73 @@ func TestDownload_PropagatesDownloadError(t *testing.T) {
 	}
 }
 
+func TestDownload_KeepsGoingPastAFailedImage(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{
+				Status: "COMPLETE",
+				Images: []string{"https://cdn.leonardo.ai/1.png", "https://cdn.leonardo.ai/2.png", "https://cdn.leonardo.ai/3.png"},
+			}, nil
+		},
+		downloadFn: func(url, destPath string) error {
+			if strings.HasSuffix(url, "/2.png") {
+				return errors.New("API returned status 403")
+			}
+			return nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	result, err := svc.Download("gen-1", t.TempDir())
+
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 files failed") || !strings.Contains(err.Error(), "image 2") {
+		t.Errorf("expected a summary of the failed image, got %v", err)
+	}
+	if len(result.FilePaths) != 2 {
+		t.Errorf("expected the other 2 images saved, got %v", result.FilePaths)
+	}
+	var outcomes []string
+	for _, o := range result.Outcomes {
+		outcomes = append(outcomes, string(o.Outcome))
+	}
+	if got := strings.Join(outcomes, ","); got != "saved,failed,saved" {
+		t.Errorf("expected outcomes saved,failed,saved, got %s", got)
+	}
+}
+
+func TestDownload_FailFastSkipsTheRemainingImages(t *testing.T) {
+	requests := 0
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{
+				Status: "COMPLETE",
+				Images: []string{"https://cdn.leonardo.ai/1.png", "https://cdn.leonardo.ai/2.png", "https://cdn.leonardo.ai/3.png"},
+			}, nil
+		},
+		downloadFn: func(url, destPath string) error {
+			requests++
+			if strings.HasSuffix(url, "/1.png") {
+				return errors.New("connection reset")
+			}
+			return nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	svc.SetFailFast(true)
+
+	result, err := svc.Download("gen-1", t.TempDir())
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if requests != 1 {
+		t.Errorf("expected downloads to stop after the failure, got %d requests", requests)
+	}
+	if result.Count(domain.DownloadFailed) != 1 || result.Count(domain.DownloadSkipped) != 2 {
+		t.Errorf("expected 1 failed and 2 skipped, got %+v", result.Outcomes)
+	}
+}
+
 func TestDownload_PassesCorrectURLsToClient(t *testing.T) {
 	var capturedURLs []string
 	fake := &fakeLeonardoClient{
@@ -661,6 +1055,85 @@ func TestDownload_PassesCorrectURLsToClient(t *testing.T) {
 
 // --- Behavior: Listing platform models ---
 
+func TestDownloadRepresentative_DownloadsOnlyTheFirstImage(t *testing.T) {
+	var downloaded []string
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE", Images: []string{"https://cdn/a.png", "https://cdn/b.png"}}, nil
+		},
+		downloadFn: func(url, destPath string) error {
+			downloaded = append(downloaded, url)
+			return nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	dir := t.TempDir()
+
+	path, err := svc.DownloadRepresentative("gen-1", dir)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, "gen-1_1.png") {
+		t.Errorf("unexpected path %q", path)
+	}
+	if len(downloaded) != 1 || downloaded[0] != "https://cdn/a.png" {
+		t.Errorf("expected only the first image to be downloaded, got %v", downloaded)
+	}
+}
+
+func TestDownloadRepresentative_RequiresCompleteGeneration(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "PENDING"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	if _, err := svc.DownloadRepresentative("gen-1", t.TempDir()); err == nil || !strings.Contains(err.Error(), "PENDING") {
+		t.Errorf("expected not-complete error, got %v", err)
+	}
+}
+
+func TestStreamImage_WritesTheRequestedImage(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE", Images: []string{"https://cdn/a.png", "https://cdn/b.png"}}, nil
+		},
+		streamFn: func(url string, w io.Writer) error {
+			_, err := io.WriteString(w, url)
+			return err
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	var out strings.Builder
+
+	if err := svc.StreamImage("gen-1", 2, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "https://cdn/b.png" {
+		t.Errorf("expected the second image, got %q", out.String())
+	}
+}
+
+func TestStreamImage_RejectsAnImageOutOfRange(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE", Images: []string{"https://cdn/a.png"}}, nil
+		},
+		streamFn: func(url string, w io.Writer) error {
+			t.Fatal("expected no download")
+			return nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	err := svc.StreamImage("gen-1", 2, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "no image 2") {
+		t.Errorf("expected out-of-range error, got %v", err)
+	}
+}
+
 func TestListPlatformModels_ReturnsModelsFromClient(t *testing.T) {
 	fake := &fakeLeonardoClient{
 		modelsFn: func() (domain.PlatformModelResponse, error) {
@@ -711,3 +1184,67 @@ func TestListPlatformModels_PropagatesClientError(t *testing.T) {
 		t.Errorf("expected error message %q, got %q", "API returned status 401", err.Error())
 	}
 }
+
+// --- Behavior: Resolving ID prefixes against recent generations ---
+
+func TestResolveRecent_ExpandsPrefixFromRecentGenerations(t *testing.T) {
+	var listedUser string
+	fake := &fakeLeonardoClient{
+		userFn: func() (domain.UserInfo, error) {
+			return domain.UserInfo{UserID: "user-1"}, nil
+		},
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			listedUser = userID
+			return domain.GenerationListResponse{Generations: []domain.GenerationListItem{
+				{ID: "3fa2c1d0-0000-0000-0000-000000000000"},
+				{ID: "9bb01234-0000-0000-0000-000000000000"},
+			}}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	id, err := svc.ResolveRecent("9bb0")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "9bb01234-0000-0000-0000-000000000000" {
+		t.Errorf("expected full ID, got %q", id)
+	}
+	if listedUser != "user-1" {
+		t.Errorf("expected generations of %q to be listed, got %q", "user-1", listedUser)
+	}
+}
+
+func TestResolveRecent_DoesNotCallAPIForFullIDs(t *testing.T) {
+	fake := &fakeLeonardoClient{}
+	svc := service.NewGenerationService(fake)
+
+	id, err := svc.ResolveRecent("3fa2c1d0-0000-0000-0000-000000000000")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "3fa2c1d0-0000-0000-0000-000000000000" {
+		t.Errorf("expected ID unchanged, got %q", id)
+	}
+}
+
+func TestResolveRecent_ReturnsPrefixUnchangedWhenNothingMatches(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		userFn: func() (domain.UserInfo, error) { return domain.UserInfo{UserID: "user-1"}, nil },
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			return domain.GenerationListResponse{}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	id, err := svc.ResolveRecent("abcd")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "abcd" {
+		t.Errorf("expected %q, got %q", "abcd", id)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// HistoryService keeps the log of CLI invocations behind the history
+// command.
+type HistoryService struct {
+	history ports.History
+}
+
+// NewHistoryService constructs a new HistoryService given a history log.
+func NewHistoryService(history ports.History) *HistoryService {
+	return &HistoryService{history: history}
+}
+
+// Record appends an invocation to the history.
+func (s *HistoryService) Record(entry domain.HistoryEntry) error {
+	return s.history.Append(entry)
+}
+
+// Recent returns the n most recent invocations, oldest first, or all of
+// them when n is not positive.
+func (s *HistoryService) Recent(n int) ([]domain.HistoryEntry, error) {
+	entries, err := s.history.List()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && n < len(entries) {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, nil
+}
+
+// Get returns invocation number n.
+func (s *HistoryService) Get(n int) (domain.HistoryEntry, error) {
+	entries, err := s.history.List()
+	if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+	for _, e := range entries {
+		if e.Number == n {
+			return e, nil
+		}
+	}
+	return domain.HistoryEntry{}, fmt.Errorf("no invocation %d in the history", n)
+}
@@ -0,0 +1,56 @@
+package service_test
+
+import (
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeHistory is an in-memory ports.History.
+type fakeHistory struct {
+	entries []domain.HistoryEntry
+}
+
+func (f *fakeHistory) Append(entry domain.HistoryEntry) error {
+	entry.Number = len(f.entries) + 1
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func (f *fakeHistory) List() ([]domain.HistoryEntry, error) {
+	return append([]domain.HistoryEntry(nil), f.entries...), nil
+}
+
+func TestHistory_RecentReturnsTheLastInvocationsOldestFirst(t *testing.T) {
+	svc := service.NewHistoryService(&fakeHistory{})
+	for _, cmd := range []string{"me", "list", "create", "status"} {
+		_ = svc.Record(domain.HistoryEntry{Args: []string{cmd}})
+	}
+
+	recent, err := svc.Recent(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Args[0] != "create" || recent[1].Number != 4 {
+		t.Errorf("expected invocations 3 and 4, got %+v", recent)
+	}
+	all, _ := svc.Recent(0)
+	if len(all) != 4 {
+		t.Errorf("expected every invocation with no limit, got %d", len(all))
+	}
+}
+
+func TestHistory_GetFindsInvocationByNumber(t *testing.T) {
+	svc := service.NewHistoryService(&fakeHistory{})
+	_ = svc.Record(domain.HistoryEntry{Args: []string{"me"}})
+	_ = svc.Record(domain.HistoryEntry{Args: []string{"list"}})
+
+	entry, err := svc.Get(2)
+	if err != nil || entry.Args[0] != "list" {
+		t.Errorf("expected invocation 2, got %+v, %v", entry, err)
+	}
+	if _, err := svc.Get(3); err == nil {
+		t.Error("expected an error for a missing invocation")
+	}
+}
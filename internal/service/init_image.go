@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// InitImageService manages the reference images uploaded to Leonardo and
+// remembers the ones uploaded from this machine so they can be listed and
+// reused.
+type InitImageService struct {
+	client ports.InitImageClient
+	store  ports.InitImageStore
+}
+
+// NewInitImageService constructs a new InitImageService given a client and
+// a store for the local record of uploads.
+func NewInitImageService(client ports.InitImageClient, store ports.InitImageStore) *InitImageService {
+	return &InitImageService{client: client, store: store}
+}
+
+// Upload checks that path is a usable image file, uploads it and records the
+// new init image locally.
+func (s *InitImageService) Upload(path string) (domain.InitImage, error) {
+	if _, err := domain.InitImageExtension(path); err != nil {
+		return domain.InitImage{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return domain.InitImage{}, fmt.Errorf("reading image: %w", err)
+	}
+	if info.IsDir() {
+		return domain.InitImage{}, fmt.Errorf("%s is a directory", path)
+	}
+	image, err := s.client.UploadInitImage(path)
+	if err != nil {
+		return image, err
+	}
+	if image.CreatedAt.IsZero() {
+		image.CreatedAt = time.Now().UTC()
+	}
+	if err := s.store.Save(image); err != nil {
+		return image, fmt.Errorf("init image %s was uploaded but could not be recorded: %w", image.ID, err)
+	}
+	return image, nil
+}
+
+// List returns the init images uploaded from this machine.
+func (s *InitImageService) List() ([]domain.InitImage, error) {
+	return s.store.List()
+}
+
+// Show retrieves an init image from the API by its ID.
+func (s *InitImageService) Show(id string) (domain.InitImage, error) {
+	return s.client.GetInitImage(id)
+}
+
+// Delete removes an init image from Leonardo and forgets it locally.
+func (s *InitImageService) Delete(id string) (domain.DeleteResponse, error) {
+	res, err := s.client.DeleteInitImage(id)
+	if err != nil {
+		return res, err
+	}
+	return res, s.store.Remove(id)
+}
@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeInitImageClient implements ports.InitImageClient for testing.
+type fakeInitImageClient struct {
+	uploadFn func(path string) (domain.InitImage, error)
+	getFn    func(id string) (domain.InitImage, error)
+	deleteFn func(id string) (domain.DeleteResponse, error)
+}
+
+func (f *fakeInitImageClient) UploadInitImage(path string) (domain.InitImage, error) {
+	return f.uploadFn(path)
+}
+
+func (f *fakeInitImageClient) GetInitImage(id string) (domain.InitImage, error) {
+	return f.getFn(id)
+}
+
+func (f *fakeInitImageClient) DeleteInitImage(id string) (domain.DeleteResponse, error) {
+	return f.deleteFn(id)
+}
+
+// fakeInitImageStore implements ports.InitImageStore in memory.
+type fakeInitImageStore struct {
+	images []domain.InitImage
+}
+
+func (f *fakeInitImageStore) Save(image domain.InitImage) error {
+	f.images = append(f.images, image)
+	return nil
+}
+
+func (f *fakeInitImageStore) List() ([]domain.InitImage, error) {
+	return append([]domain.InitImage(nil), f.images...), nil
+}
+
+func (f *fakeInitImageStore) Remove(id string) error {
+	kept := f.images[:0]
+	for _, image := range f.images {
+		if image.ID != id {
+			kept = append(kept, image)
+		}
+	}
+	f.images = kept
+	return nil
+}
+
+func TestInitImageUpload_RecordsUploadedImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ref.png")
+	os.WriteFile(path, []byte("png"), 0644)
+	client := &fakeInitImageClient{uploadFn: func(p string) (domain.InitImage, error) {
+		return domain.InitImage{ID: "init-1", FileName: "ref.png"}, nil
+	}}
+	store := &fakeInitImageStore{}
+	svc := service.NewInitImageService(client, store)
+
+	image, err := svc.Upload(path)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image.CreatedAt.IsZero() {
+		t.Error("expected upload time to be set")
+	}
+	if len(store.images) != 1 || store.images[0].ID != "init-1" {
+		t.Errorf("expected init-1 to be recorded, got %+v", store.images)
+	}
+}
+
+func TestInitImageUpload_RejectsUnsupportedFilesBeforeUploading(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("text"), 0644)
+	client := &fakeInitImageClient{uploadFn: func(p string) (domain.InitImage, error) {
+		t.Fatal("upload should not be attempted")
+		return domain.InitImage{}, nil
+	}}
+	svc := service.NewInitImageService(client, &fakeInitImageStore{})
+
+	if _, err := svc.Upload(path); err == nil {
+		t.Fatal("expected error for a text file, got nil")
+	}
+	if _, err := svc.Upload(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Fatal("expected error for a missing file, got nil")
+	}
+}
+
+func TestInitImageDelete_ForgetsOnlyAfterRemoteDelete(t *testing.T) {
+	store := &fakeInitImageStore{images: []domain.InitImage{{ID: "init-1"}, {ID: "init-2"}}}
+	failing := true
+	client := &fakeInitImageClient{deleteFn: func(id string) (domain.DeleteResponse, error) {
+		if failing {
+			return domain.DeleteResponse{}, errors.New("API returned status 500")
+		}
+		return domain.DeleteResponse{ID: id}, nil
+	}}
+	svc := service.NewInitImageService(client, store)
+
+	if _, err := svc.Delete("init-1"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(store.images) != 2 {
+		t.Fatalf("expected record to be kept after a failed delete, got %+v", store.images)
+	}
+	failing = false
+	if _, err := svc.Delete("init-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.images) != 1 || store.images[0].ID != "init-2" {
+		t.Errorf("expected only init-2 to remain, got %+v", store.images)
+	}
+}
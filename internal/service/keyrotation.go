@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// RotationKey is one API key of a KeyRotation: the stored account it
+// belongs to and a client authenticated with it.
+type RotationKey struct {
+	Account string
+	Client  ports.LeonardoClient
+}
+
+// KeyRotation is a LeonardoClient that spreads a run over several API keys.
+// Generations are submitted with the current key until the API rate limits
+// it or it runs out of tokens, then with the next one.  Requests about a
+// generation go to the key that created it, since only its account can see
+// it; everything else uses the current key.  It is safe for concurrent use.
+type KeyRotation struct {
+	keys []RotationKey
+
+	mu       sync.Mutex
+	current  int
+	usage    []domain.KeyUsage
+	owners   map[string]int
+	onRotate func(from, to, reason string)
+}
+
+// NewKeyRotation constructs a new KeyRotation starting with the first of
+// keys, which must not be empty.
+func NewKeyRotation(keys []RotationKey) *KeyRotation {
+	usage := make([]domain.KeyUsage, len(keys))
+	for i, k := range keys {
+		usage[i].Account = k.Account
+	}
+	return &KeyRotation{keys: keys, usage: usage, owners: make(map[string]int)}
+}
+
+// SetOnRotate registers fn to be told when submissions move from one
+// account's key to another's, and why.
+func (r *KeyRotation) SetOnRotate(fn func(from, to, reason string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRotate = fn
+}
+
+// Usage returns what each key was used for so far, in rotation order.
+func (r *KeyRotation) Usage() []domain.KeyUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.KeyUsage(nil), r.usage...)
+}
+
+// CreateGeneration implements ports.LeonardoClient.  A submission the API
+// rate limits or cannot charge is retried with each other key that still
+// has tokens, once, before its error is returned.
+func (r *KeyRotation) CreateGeneration(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+	var lastErr error
+	for tries := 0; tries < len(r.keys); tries++ {
+		k, ok := r.pick()
+		if !ok {
+			break
+		}
+		resp, err := r.keys[k].Client.CreateGeneration(req)
+		if !r.record(k, resp, err) {
+			return resp, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return domain.GenerationResponse{}, fmt.Errorf("every API key of the rotation is out of tokens")
+	}
+	return domain.GenerationResponse{}, lastErr
+}
+
+// pick returns the current key, skipping keys that ran out of tokens.
+func (r *KeyRotation) pick() (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := 0; i < len(r.keys); i++ {
+		k := (r.current + i) % len(r.keys)
+		if !r.usage[k].OutOfTokens {
+			r.current = k
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// record accounts for a submission made with key k and reports whether it
+// should be retried with another key, moving the rotation on if so.
+func (r *KeyRotation) record(k int, resp domain.GenerationResponse, err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usage := &r.usage[k]
+	usage.Requests++
+	if err == nil {
+		usage.Generations++
+		usage.Cost += resp.Cost
+		r.owners[resp.GenerationID] = k
+		return false
+	}
+	var reason string
+	switch {
+	case domain.IsOutOfTokens(err):
+		usage.OutOfTokens = true
+		reason = "out of tokens"
+	case domain.ClassifyFailure(err) == domain.FailureRateLimit:
+		usage.RateLimited++
+		reason = "rate limited"
+	default:
+		return false
+	}
+	if len(r.keys) == 1 {
+		return false
+	}
+	if r.current == k {
+		r.current = (k + 1) % len(r.keys)
+		if r.onRotate != nil {
+			r.onRotate(r.keys[k].Account, r.keys[r.current].Account, reason)
+		}
+	}
+	return true
+}
+
+// owner returns the client of the key that created generation id, or of
+// the current key when the generation was not created in this run.
+func (r *KeyRotation) owner(id string) ports.LeonardoClient {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if k, ok := r.owners[id]; ok {
+		return r.keys[k].Client
+	}
+	return r.keys[r.current].Client
+}
+
+// active returns the client of the current key.
+func (r *KeyRotation) active() ports.LeonardoClient {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.keys[r.current].Client
+}
+
+// GetGenerationStatus implements ports.LeonardoClient.
+func (r *KeyRotation) GetGenerationStatus(id string) (domain.GenerationStatus, error) {
+	return r.owner(id).GetGenerationStatus(id)
+}
+
+// WaitForCompletion implements ports.LeonardoClient.
+func (r *KeyRotation) WaitForCompletion(ctx context.Context, id string, opts domain.WaitOptions) (domain.GenerationStatus, error) {
+	return r.owner(id).WaitForCompletion(ctx, id, opts)
+}
+
+// GetGeneration implements ports.LeonardoClient.
+func (r *KeyRotation) GetGeneration(id string) (domain.GenerationDetail, error) {
+	return r.owner(id).GetGeneration(id)
+}
+
+// DeleteGeneration implements ports.LeonardoClient.
+func (r *KeyRotation) DeleteGeneration(id string) (domain.DeleteResponse, error) {
+	return r.owner(id).DeleteGeneration(id)
+}
+
+// GetUserInfo implements ports.LeonardoClient.  It reports the current
+// key's user with the token balances of every key still in the rotation
+// added up, which is what the run can spend; keys that cannot be queried
+// add nothing.
+func (r *KeyRotation) GetUserInfo() (domain.UserInfo, error) {
+	r.mu.Lock()
+	current, usage := r.current, append([]domain.KeyUsage(nil), r.usage...)
+	r.mu.Unlock()
+	info, err := r.keys[current].Client.GetUserInfo()
+	if err != nil {
+		return info, err
+	}
+	for k, key := range r.keys {
+		if k == current || usage[k].OutOfTokens {
+			continue
+		}
+		if other, err := key.Client.GetUserInfo(); err == nil {
+			info.APISubscriptionTokens += other.APISubscriptionTokens
+			info.APIPaidTokens += other.APIPaidTokens
+		}
+	}
+	return info, nil
+}
+
+// ListGenerations implements ports.LeonardoClient.
+func (r *KeyRotation) ListGenerations(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+	return r.active().ListGenerations(userID, offset, limit)
+}
+
+// DownloadImage implements ports.LeonardoClient.
+func (r *KeyRotation) DownloadImage(url, destPath string) error {
+	return r.active().DownloadImage(url, destPath)
+}
+
+// StreamImage implements ports.LeonardoClient.
+func (r *KeyRotation) StreamImage(url string, w io.Writer) error {
+	return r.active().StreamImage(url, w)
+}
+
+// ListPlatformModels implements ports.LeonardoClient.
+func (r *KeyRotation) ListPlatformModels() (domain.PlatformModelResponse, error) {
+	return r.active().ListPlatformModels()
+}
+
+// UpscaleImage implements ports.LeonardoClient.
+func (r *KeyRotation) UpscaleImage(imageID string) (domain.VariationJob, error) {
+	return r.active().UpscaleImage(imageID)
+}
+
+// UniversalUpscale implements ports.LeonardoClient.
+func (r *KeyRotation) UniversalUpscale(imageID string, multiplier float64) (domain.VariationJob, error) {
+	return r.active().UniversalUpscale(imageID, multiplier)
+}
+
+// CreateVariation implements ports.LeonardoClient.
+func (r *KeyRotation) CreateVariation(kind domain.VariationKind, imageID string) (domain.VariationJob, error) {
+	return r.active().CreateVariation(kind, imageID)
+}
+
+// GetVariation implements ports.LeonardoClient.
+func (r *KeyRotation) GetVariation(id string) (domain.ImageVariation, error) {
+	return r.active().GetVariation(id)
+}
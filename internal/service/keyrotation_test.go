@@ -0,0 +1,130 @@
+package service_test
+
+import (
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// --- Behavior: Rotating between API keys ---
+
+// keyClient is a fake client whose submissions all end with err, or create
+// a generation named after prefix when err is nil.
+func keyClient(prefix string, err error, submitted *int) *fakeLeonardoClient {
+	return &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			*submitted++
+			if err != nil {
+				return domain.GenerationResponse{}, err
+			}
+			return domain.GenerationResponse{GenerationID: prefix + "-gen", Cost: 8}, nil
+		},
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{ID: id, Prompt: prefix}, nil
+		},
+	}
+}
+
+func TestKeyRotation_MovesToTheNextKeyWhenRateLimitedAndRoutesFollowUps(t *testing.T) {
+	var workCalls, backupCalls int
+	rotation := service.NewKeyRotation([]service.RotationKey{
+		{Account: "work", Client: keyClient("work", &domain.APIError{StatusCode: 429}, &workCalls)},
+		{Account: "backup", Client: keyClient("backup", nil, &backupCalls)},
+	})
+	var rotated []string
+	rotation.SetOnRotate(func(from, to, reason string) {
+		rotated = append(rotated, from+">"+to+": "+reason)
+	})
+
+	resp, err := rotation.CreateGeneration(domain.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GenerationID != "backup-gen" {
+		t.Errorf("expected the backup key to create the generation, got %q", resp.GenerationID)
+	}
+	if _, err := rotation.CreateGeneration(domain.GenerationRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if workCalls != 1 || backupCalls != 2 {
+		t.Errorf("expected the rotation to stay on the backup key, got %d work and %d backup calls", workCalls, backupCalls)
+	}
+	if len(rotated) != 1 || rotated[0] != "work>backup: rate limited" {
+		t.Errorf("unexpected rotations: %v", rotated)
+	}
+	if detail, _ := rotation.GetGeneration("backup-gen"); detail.Prompt != "backup" {
+		t.Errorf("expected the generation to be looked up with the key that created it, got %q", detail.Prompt)
+	}
+	usage := rotation.Usage()
+	want := []domain.KeyUsage{
+		{Account: "work", Requests: 1, RateLimited: 1},
+		{Account: "backup", Requests: 2, Generations: 2, Cost: 16},
+	}
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("key %d: expected %+v, got %+v", i, want[i], usage[i])
+		}
+	}
+}
+
+func TestKeyRotation_RetiresKeysOutOfTokens(t *testing.T) {
+	var aCalls, bCalls int
+	broke := &domain.APIError{StatusCode: 400, Body: []byte(`{"error":"Not enough API tokens"}`)}
+	rotation := service.NewKeyRotation([]service.RotationKey{
+		{Account: "a", Client: keyClient("a", broke, &aCalls)},
+		{Account: "b", Client: keyClient("b", broke, &bCalls)},
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := rotation.CreateGeneration(domain.GenerationRequest{}); err == nil {
+			t.Fatal("expected an error once every key is out of tokens")
+		}
+	}
+
+	if aCalls != 1 || bCalls != 1 {
+		t.Errorf("expected each key to be tried once, got %d and %d", aCalls, bCalls)
+	}
+	for _, u := range rotation.Usage() {
+		if !u.OutOfTokens {
+			t.Errorf("expected %s to be marked out of tokens", u.Account)
+		}
+	}
+}
+
+func TestKeyRotation_ReturnsOtherFailuresWithoutRotating(t *testing.T) {
+	var aCalls, bCalls int
+	rotation := service.NewKeyRotation([]service.RotationKey{
+		{Account: "a", Client: keyClient("a", &domain.APIError{StatusCode: 400, Body: []byte("bad width")}, &aCalls)},
+		{Account: "b", Client: keyClient("b", nil, &bCalls)},
+	})
+
+	if _, err := rotation.CreateGeneration(domain.GenerationRequest{}); err == nil {
+		t.Fatal("expected the invalid request to fail")
+	}
+
+	if aCalls != 1 || bCalls != 0 {
+		t.Errorf("expected no retry with another key, got %d and %d calls", aCalls, bCalls)
+	}
+}
+
+func TestKeyRotation_AddsUpTheBalancesOfItsKeys(t *testing.T) {
+	balance := func(user string, tokens int) *fakeLeonardoClient {
+		return &fakeLeonardoClient{userFn: func() (domain.UserInfo, error) {
+			return domain.UserInfo{UserID: user, APISubscriptionTokens: tokens, APIPaidTokens: 1}, nil
+		}}
+	}
+	rotation := service.NewKeyRotation([]service.RotationKey{
+		{Account: "a", Client: balance("user-a", 100)},
+		{Account: "b", Client: balance("user-b", 50)},
+	})
+
+	info, err := rotation.GetUserInfo()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.UserID != "user-a" || info.APISubscriptionTokens != 150 || info.APIPaidTokens != 2 {
+		t.Errorf("expected user-a with pooled balances, got %+v", info)
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// LibraryService keeps the local record of created generations and resolves
+// the human-friendly references users type on the command line into
+// generation IDs.
+type LibraryService struct {
+	library ports.Library
+}
+
+// NewLibraryService constructs a new LibraryService given a library.
+func NewLibraryService(library ports.Library) *LibraryService {
+	return &LibraryService{library: library}
+}
+
+// Record stores a newly created generation in the library.
+func (s *LibraryService) Record(entry domain.LibraryEntry) error {
+	if strings.TrimSpace(entry.GenerationID) == "" {
+		return fmt.Errorf("generation ID is empty; cannot record in library")
+	}
+	if entry.Name != "" {
+		existing, err := s.findByName(entry.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.GenerationID != entry.GenerationID {
+			return fmt.Errorf("name %q is already used by generation %s", entry.Name, existing.GenerationID)
+		}
+	}
+	return s.library.Save(entry)
+}
+
+// CheckName returns an error when name is already used by a generation in
+// the library.  Callers check before creating a generation so a clash does
+// not surface only after credits were spent.
+func (s *LibraryService) CheckName(name string) error {
+	if name == "" {
+		return nil
+	}
+	existing, err := s.findByName(name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("name %q is already used by generation %s", name, existing.GenerationID)
+	}
+	return nil
+}
+
+// SetFavorite marks a generation in the library as a favorite, or clears the
+// mark.  Only generations recorded locally can be favorites.
+func (s *LibraryService) SetFavorite(id string, favorite bool) error {
+	entries, err := s.library.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.GenerationID == id {
+			e.Favorite = favorite
+			return s.library.Save(e)
+		}
+	}
+	return fmt.Errorf("generation %s is not in the local library", id)
+}
+
+// RecordDuration stores how long a generation in the library took to
+// complete, for later wait estimates.  Generations not recorded locally are
+// ignored.
+func (s *LibraryService) RecordDuration(id string, d time.Duration) error {
+	entries, err := s.library.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.GenerationID == id {
+			e.Duration = d
+			return s.library.Save(e)
+		}
+	}
+	return nil
+}
+
+// EstimateWait returns how long generations with the given model and size
+// usually take, based on the completion times recorded in the library.
+func (s *LibraryService) EstimateWait(modelID string, width, height int) (domain.WaitEstimate, bool) {
+	entries, err := s.library.List()
+	if err != nil {
+		return domain.WaitEstimate{}, false
+	}
+	return domain.EstimateWait(entries, modelID, width, height)
+}
+
+// Favorites returns the set of generation IDs marked as favorites.
+func (s *LibraryService) Favorites() (map[string]bool, error) {
+	entries, err := s.library.List()
+	if err != nil {
+		return nil, err
+	}
+	favorites := map[string]bool{}
+	for _, e := range entries {
+		if e.Favorite {
+			favorites[e.GenerationID] = true
+		}
+	}
+	return favorites, nil
+}
+
+// Costs returns the token cost recorded for each generation created with
+// a known cost.
+func (s *LibraryService) Costs() (map[string]int, error) {
+	entries, err := s.library.List()
+	if err != nil {
+		return nil, err
+	}
+	costs := map[string]int{}
+	for _, e := range entries {
+		if e.Cost > 0 {
+			costs[e.GenerationID] = e.Cost
+		}
+	}
+	return costs, nil
+}
+
+// Budget returns the recorded token spend per month and per tag or
+// project, as grouped by domain.Budget.  A non-empty month, as YYYY-MM,
+// keeps only that month's lines.
+func (s *LibraryService) Budget(by, month string, loc *time.Location) ([]domain.BudgetLine, error) {
+	entries, err := s.library.List()
+	if err != nil {
+		return nil, err
+	}
+	lines, err := domain.Budget(entries, by, loc)
+	if err != nil || month == "" {
+		return lines, err
+	}
+	var kept []domain.BudgetLine
+	for _, line := range lines {
+		if line.Month == month {
+			kept = append(kept, line)
+		}
+	}
+	return kept, nil
+}
+
+// Forget removes a generation from the library, typically after it was
+// deleted remotely.
+func (s *LibraryService) Forget(id string) error {
+	return s.library.Remove(id)
+}
+
+// Resolve turns a user-supplied reference into a generation ID.  A reference
+// matching the name or full ID of a library entry resolves to that entry's
+// ID, and a unique prefix of an entry's ID resolves to the full ID.  Any
+// other reference is returned unchanged so callers can try other sources.
+func (s *LibraryService) Resolve(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	entries, err := s.library.List()
+	if err != nil {
+		return "", err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Name == ref || e.GenerationID == ref {
+			return e.GenerationID, nil
+		}
+		ids = append(ids, e.GenerationID)
+	}
+	id, ok, err := domain.MatchIDPrefix(ref, ids)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return id, nil
+	}
+	return ref, nil
+}
+
+// Note returns the note recorded for generation id, empty when there is
+// none or the generation is not in the library.
+func (s *LibraryService) Note(id string) (string, error) {
+	entries, err := s.library.List()
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.GenerationID == id {
+			return e.Note, nil
+		}
+	}
+	return "", nil
+}
+
+// Last returns the IDs of the n most recently created generations in the
+// library, newest first.  It fails when the library holds no generations.
+func (s *LibraryService) Last(n int) ([]string, error) {
+	entries, err := s.library.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no generations recorded locally yet")
+	}
+	newestFirst(entries)
+	if n <= 0 || n > len(entries) {
+		n = len(entries)
+	}
+	ids := make([]string, 0, n)
+	for _, e := range entries[:n] {
+		ids = append(ids, e.GenerationID)
+	}
+	return ids, nil
+}
+
+// Search returns the library entries carrying tag whose note contains
+// text, newest first.  An empty tag or text matches every entry; both match
+// regardless of case.
+func (s *LibraryService) Search(tag, text string) ([]domain.LibraryEntry, error) {
+	entries, err := s.library.List()
+	if err != nil {
+		return nil, err
+	}
+	text = strings.ToLower(text)
+	var found []domain.LibraryEntry
+	for _, e := range entries {
+		if (tag == "" || e.HasTag(tag)) && strings.Contains(strings.ToLower(e.Note), text) {
+			found = append(found, e)
+		}
+	}
+	newestFirst(found)
+	return found, nil
+}
+
+// newestFirst sorts entries by creation time, newest first.  Entries are
+// stored in creation order; reversing them before a stable sort keeps
+// generations created within the same second newest first.
+func newestFirst(entries []domain.LibraryEntry) {
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+}
+
+// findByName returns the library entry with the given name, or nil when no
+// entry uses it.
+func (s *LibraryService) findByName(name string) (*domain.LibraryEntry, error) {
+	entries, err := s.library.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Name == name {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,363 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeLibrary implements ports.Library in memory for testing the library
+// service at its port boundary.
+type fakeLibrary struct {
+	entries []domain.LibraryEntry
+}
+
+func (f *fakeLibrary) Save(entry domain.LibraryEntry) error {
+	for i := range f.entries {
+		if f.entries[i].GenerationID == entry.GenerationID {
+			f.entries[i] = entry
+			return nil
+		}
+	}
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func (f *fakeLibrary) List() ([]domain.LibraryEntry, error) {
+	return append([]domain.LibraryEntry(nil), f.entries...), nil
+}
+
+func (f *fakeLibrary) Remove(id string) error {
+	kept := f.entries[:0]
+	for _, e := range f.entries {
+		if e.GenerationID != id {
+			kept = append(kept, e)
+		}
+	}
+	f.entries = kept
+	return nil
+}
+
+// --- Behavior: Resolving generation references ---
+
+func TestResolve_ReturnsIDForKnownName(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "3fa2c1d0-0000-0000-0000-000000000000", Name: "hero-banner-v3"},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	id, err := svc.Resolve("hero-banner-v3")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "3fa2c1d0-0000-0000-0000-000000000000" {
+		t.Errorf("expected resolved ID %q, got %q", "3fa2c1d0-0000-0000-0000-000000000000", id)
+	}
+}
+
+func TestResolve_ReturnsReferenceUnchangedWhenNotAName(t *testing.T) {
+	svc := service.NewLibraryService(&fakeLibrary{})
+
+	id, err := svc.Resolve("  some-generation-id ")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "some-generation-id" {
+		t.Errorf("expected %q, got %q", "some-generation-id", id)
+	}
+}
+
+// --- Behavior: Recording generations ---
+
+func TestRecord_RejectsNameUsedByAnotherGeneration(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{{GenerationID: "gen-1", Name: "hero"}}}
+	svc := service.NewLibraryService(lib)
+
+	err := svc.Record(domain.LibraryEntry{GenerationID: "gen-2", Name: "hero"})
+
+	if err == nil {
+		t.Fatal("expected error for duplicate name, got nil")
+	}
+	if !strings.Contains(err.Error(), "gen-1") {
+		t.Errorf("expected error to mention existing generation, got %q", err.Error())
+	}
+	if len(lib.entries) != 1 {
+		t.Errorf("expected library to be unchanged, got %d entries", len(lib.entries))
+	}
+}
+
+func TestRecord_RejectsEmptyGenerationID(t *testing.T) {
+	svc := service.NewLibraryService(&fakeLibrary{})
+
+	if err := svc.Record(domain.LibraryEntry{Name: "orphan"}); err == nil {
+		t.Fatal("expected error for empty generation ID, got nil")
+	}
+}
+
+func TestCheckName_AllowsUnusedAndEmptyNames(t *testing.T) {
+	svc := service.NewLibraryService(&fakeLibrary{entries: []domain.LibraryEntry{{GenerationID: "gen-1", Name: "hero"}}})
+
+	if err := svc.CheckName("villain"); err != nil {
+		t.Errorf("expected unused name to be accepted, got %v", err)
+	}
+	if err := svc.CheckName(""); err != nil {
+		t.Errorf("expected empty name to be accepted, got %v", err)
+	}
+	if err := svc.CheckName("hero"); err == nil {
+		t.Error("expected used name to be rejected, got nil")
+	}
+}
+
+func TestForget_RemovesGenerationFromLibrary(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{{GenerationID: "gen-1", Name: "hero"}}}
+	svc := service.NewLibraryService(lib)
+
+	if err := svc.Forget("gen-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	id, _ := svc.Resolve("hero")
+	if id != "hero" {
+		t.Errorf("expected forgotten name to no longer resolve, got %q", id)
+	}
+}
+
+func TestResolve_ExpandsUniqueIDPrefix(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "3fa2c1d0-0000-0000-0000-000000000000"},
+		{GenerationID: "9bb01234-0000-0000-0000-000000000000"},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	id, err := svc.Resolve("3fa2")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "3fa2c1d0-0000-0000-0000-000000000000" {
+		t.Errorf("expected full ID, got %q", id)
+	}
+}
+
+func TestResolve_ReturnsErrorForAmbiguousPrefix(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "3fa2c1d0-0000-0000-0000-000000000000"},
+		{GenerationID: "3fa2ffff-0000-0000-0000-000000000000"},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	_, err := svc.Resolve("3fa2")
+
+	var ambiguous *domain.AmbiguousReferenceError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected AmbiguousReferenceError, got %v", err)
+	}
+	if len(ambiguous.Matches) != 2 {
+		t.Errorf("expected 2 matches, got %d", len(ambiguous.Matches))
+	}
+}
+
+func TestResolve_IgnoresPrefixesShorterThanMinimum(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{{GenerationID: "3fa2c1d0-0000-0000-0000-000000000000"}}}
+	svc := service.NewLibraryService(lib)
+
+	id, _ := svc.Resolve("3fa")
+
+	if id != "3fa" {
+		t.Errorf("expected short prefix to be returned unchanged, got %q", id)
+	}
+}
+
+// --- Behavior: Resolving the most recent generations ---
+
+func TestLast_ReturnsMostRecentGenerationsNewestFirst(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "gen-old", CreatedAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-new", CreatedAt: time.Date(2026, 1, 3, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-mid", CreatedAt: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	ids, err := svc.Last(2)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "gen-new" || ids[1] != "gen-mid" {
+		t.Errorf("expected [gen-new gen-mid], got %v", ids)
+	}
+}
+
+func TestLast_PrefersLaterEntriesWithinTheSameSecond(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "gen-first", CreatedAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-second", CreatedAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	ids, _ := svc.Last(1)
+
+	if len(ids) != 1 || ids[0] != "gen-second" {
+		t.Errorf("expected [gen-second], got %v", ids)
+	}
+}
+
+func TestLast_ReturnsErrorForEmptyLibrary(t *testing.T) {
+	svc := service.NewLibraryService(&fakeLibrary{})
+
+	if _, err := svc.Last(1); err == nil {
+		t.Fatal("expected error for empty library, got nil")
+	}
+}
+
+func TestSetFavorite_MarksAndClearsLibraryEntries(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{{GenerationID: "gen-1"}, {GenerationID: "gen-2"}}}
+	svc := service.NewLibraryService(lib)
+
+	if err := svc.SetFavorite("gen-2", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	favorites, _ := svc.Favorites()
+	if len(favorites) != 1 || !favorites["gen-2"] {
+		t.Errorf("expected only gen-2 to be a favorite, got %v", favorites)
+	}
+
+	if err := svc.SetFavorite("gen-2", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if favorites, _ := svc.Favorites(); len(favorites) != 0 {
+		t.Errorf("expected no favorites, got %v", favorites)
+	}
+}
+
+func TestSetFavorite_RejectsUnknownGenerations(t *testing.T) {
+	svc := service.NewLibraryService(&fakeLibrary{})
+
+	if err := svc.SetFavorite("gen-missing", true); err == nil {
+		t.Fatal("expected error for a generation outside the library, got nil")
+	}
+}
+
+func TestSearch_ReturnsTaggedGenerationsNewestFirstIgnoringCase(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "gen-old", Tags: []string{"campaign-spring-2026"}, CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-other", Tags: []string{"portrait"}, CreatedAt: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-new", Tags: []string{"hero", "Campaign-Spring-2026"}, CreatedAt: time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	found, err := svc.Search("campaign-spring-2026", "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(found) != 2 || found[0].GenerationID != "gen-new" || found[1].GenerationID != "gen-old" {
+		t.Errorf("expected [gen-new gen-old], got %+v", found)
+	}
+}
+
+func TestSearch_MatchesNoteTextAndTag(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "gen-warm", Tags: []string{"acme"}, Note: "Client asked for warmer colors"},
+		{GenerationID: "gen-cool", Tags: []string{"acme"}, Note: "cooler, more contrast"},
+		{GenerationID: "gen-other", Note: "Warmer than v2"},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	found, err := svc.Search("acme", "WARMER")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(found) != 1 || found[0].GenerationID != "gen-warm" {
+		t.Errorf("expected [gen-warm], got %+v", found)
+	}
+	if found, _ := svc.Search("", "warmer"); len(found) != 2 {
+		t.Errorf("expected every note mentioning warmer without a tag, got %+v", found)
+	}
+	if note, _ := svc.Note("gen-cool"); note != "cooler, more contrast" {
+		t.Errorf("unexpected note %q", note)
+	}
+	if note, err := svc.Note("gen-missing"); note != "" || err != nil {
+		t.Errorf("expected no note for a generation outside the library, got %q, %v", note, err)
+	}
+}
+
+func TestEstimateWait_UsesMedianOfMatchingCompletedGenerations(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "gen-1", ModelID: "phoenix", Width: 1024, Height: 1024},
+		{GenerationID: "gen-2", ModelID: "phoenix", Width: 1024, Height: 1024, Duration: 30 * time.Second},
+		{GenerationID: "gen-3", ModelID: "phoenix", Width: 1024, Height: 1024, Duration: 90 * time.Second},
+		{GenerationID: "gen-4", ModelID: "phoenix", Width: 512, Height: 512, Duration: 5 * time.Second},
+		{GenerationID: "gen-5", ModelID: "flux", Width: 1024, Height: 1024, Duration: 5 * time.Second},
+	}}
+	svc := service.NewLibraryService(lib)
+	if err := svc.RecordDuration("gen-1", 40*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	estimate, ok := svc.EstimateWait("phoenix", 1024, 1024)
+
+	if !ok || estimate.Typical != 40*time.Second || estimate.Samples != 3 {
+		t.Fatalf("expected a 40s median from 3 samples, got %+v, %v", estimate, ok)
+	}
+	if got := estimate.Describe("Phoenix"); got != "usually ~40s for Phoenix 1024×1024" {
+		t.Errorf("unexpected description %q", got)
+	}
+	if _, ok := svc.EstimateWait("phoenix", 768, 768); ok {
+		t.Error("expected no estimate for a size never waited for")
+	}
+}
+
+func TestBudget_GroupsRecordedSpendByProjectPerMonth(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "gen-1", Cost: 20, Tags: []string{"hero", "project:acme"}, CreatedAt: time.Date(2026, 4, 30, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-2", Cost: 30, Tags: []string{"project:acme"}, CreatedAt: time.Date(2026, 5, 2, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-3", Tags: []string{"project:acme"}, CreatedAt: time.Date(2026, 5, 3, 10, 0, 0, 0, time.UTC)},
+		{GenerationID: "gen-4", Cost: 12, Tags: []string{"hero"}, CreatedAt: time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	lines, err := svc.Budget(domain.BudgetByProject, "2026-05", time.UTC)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []domain.BudgetLine{
+		{Month: "2026-05", Group: domain.BudgetUnassigned, Generations: 1, Tokens: 12},
+		{Month: "2026-05", Group: "acme", Generations: 2, Tokens: 30, Uncosted: 1},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, want[i], lines[i])
+		}
+	}
+}
+
+func TestBudget_CountsEveryTagWhenGroupingByTag(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{
+		{GenerationID: "gen-1", Cost: 20, Tags: []string{"hero", "spring"}, CreatedAt: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)},
+	}}
+	svc := service.NewLibraryService(lib)
+
+	lines, err := svc.Budget(domain.BudgetByTag, "", time.UTC)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(lines) != 2 || lines[0].Group != "hero" || lines[1].Group != "spring" || lines[1].Tokens != 20 {
+		t.Errorf("expected hero and spring each charged 20, got %+v", lines)
+	}
+	if _, err := svc.Budget("model", "", time.UTC); err == nil {
+		t.Error("expected an error for an unknown grouping")
+	}
+}
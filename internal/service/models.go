@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// DefaultModelCacheTTL is how long a cached model list is trusted before it
+// is fetched again.
+const DefaultModelCacheTTL = 24 * time.Hour
+
+// ModelService checks generation requests against the capabilities of the
+// chosen model, using a cached copy of the platform model list.
+type ModelService struct {
+	client ports.LeonardoClient
+	cache  ports.ModelCache
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewModelService constructs a new ModelService given a client and a cache
+// for the model list.
+func NewModelService(client ports.LeonardoClient, cache ports.ModelCache) *ModelService {
+	return &ModelService{client: client, cache: cache, ttl: DefaultModelCacheTTL, now: time.Now}
+}
+
+// Catalog returns the platform model list, fetching it when the cache is
+// empty, stale or refresh is set.  A cache that cannot be read or written
+// only costs an extra request.
+func (s *ModelService) Catalog(refresh bool) (domain.ModelCatalog, error) {
+	if !refresh {
+		if cached, err := s.cache.Load(); err == nil && cached.Fresh(s.now(), s.ttl) {
+			return cached, nil
+		}
+	}
+	resp, err := s.client.ListPlatformModels()
+	if err != nil {
+		return domain.ModelCatalog{}, err
+	}
+	catalog := domain.ModelCatalog{Models: resp.Models, FetchedAt: s.now().UTC()}
+	_ = s.cache.Save(catalog)
+	return catalog, nil
+}
+
+// Validate checks meta against the capabilities of its model.  Requests
+// without a model, or for a model missing from the platform list such as a
+// custom model, are not checked.  A model missing from a cached list causes
+// one refresh in case it was published since.  Unsupported settings are
+// reported as a domain.InvalidRequestError.
+func (s *ModelService) Validate(meta domain.GenerationMetadata) error {
+	if !meta.HasModelID() {
+		return nil
+	}
+	catalog, err := s.Catalog(false)
+	if err != nil {
+		return fmt.Errorf("fetching model capabilities: %w", err)
+	}
+	model, ok := catalog.Find(meta.ModelID)
+	if !ok && s.now().Sub(catalog.FetchedAt) > time.Minute {
+		if catalog, err = s.Catalog(true); err != nil {
+			return fmt.Errorf("fetching model capabilities: %w", err)
+		}
+		model, ok = catalog.Find(meta.ModelID)
+	}
+	if !ok {
+		return nil
+	}
+	return model.Validate(meta)
+}
+
+// PromptTokenLimit returns how many prompt tokens the model reads.  Requests
+// without a model, models missing from the list and failures to fetch the
+// list get domain.DefaultPromptTokenLimit, the limit of most models.
+func (s *ModelService) PromptTokenLimit(modelID string) int {
+	if modelID == "" {
+		return domain.DefaultPromptTokenLimit
+	}
+	catalog, err := s.Catalog(false)
+	if err != nil {
+		return domain.DefaultPromptTokenLimit
+	}
+	if model, ok := catalog.Find(modelID); ok {
+		return model.Capabilities().PromptTokens
+	}
+	return domain.DefaultPromptTokenLimit
+}
+
+// DefaultSize fills in the width and height meta omits with its model's
+// native resolution, rather than leaving the API to pick one silently.  It
+// returns the model and whether anything was filled in.  Requests without a
+// model, for models missing from the list or without a known native size,
+// and failures to fetch the list leave meta unchanged.
+func (s *ModelService) DefaultSize(meta domain.GenerationMetadata) (domain.GenerationMetadata, domain.PlatformModel, bool) {
+	if !meta.HasModelID() || (meta.HasWidth() && meta.HasHeight()) {
+		return meta, domain.PlatformModel{}, false
+	}
+	catalog, err := s.Catalog(false)
+	if err != nil {
+		return meta, domain.PlatformModel{}, false
+	}
+	model, ok := catalog.Find(meta.ModelID)
+	if !ok {
+		return meta, domain.PlatformModel{}, false
+	}
+	width, height, ok := model.NativeSize()
+	if !ok {
+		return meta, model, false
+	}
+	if !meta.HasWidth() {
+		meta.Width = width
+	}
+	if !meta.HasHeight() {
+		meta.Height = height
+	}
+	return meta, model, true
+}
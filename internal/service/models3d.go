@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// Model3DService uploads the 3D models texture generations run against.
+type Model3DService struct {
+	client ports.Model3DClient
+}
+
+// NewModel3DService constructs a new Model3DService given a client.
+func NewModel3DService(client ports.Model3DClient) *Model3DService {
+	return &Model3DService{client: client}
+}
+
+// Upload checks that path is an OBJ file and uploads it.  An empty name
+// defaults to the file name without its extension.
+func (s *Model3DService) Upload(path, name string) (domain.Model3D, error) {
+	if _, err := domain.Model3DExtension(path); err != nil {
+		return domain.Model3D{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return domain.Model3D{}, fmt.Errorf("reading model: %w", err)
+	}
+	if info.IsDir() {
+		return domain.Model3D{}, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() == 0 {
+		return domain.Model3D{}, fmt.Errorf("%s is empty", path)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = domain.Model3DName(path)
+	}
+	return s.client.UploadModel3D(path, name)
+}
@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeModel3DClient implements ports.Model3DClient for testing.
+type fakeModel3DClient struct {
+	uploadFn func(path, name string) (domain.Model3D, error)
+}
+
+func (f *fakeModel3DClient) UploadModel3D(path, name string) (domain.Model3D, error) {
+	return f.uploadFn(path, name)
+}
+
+func TestModel3DUpload_NamesModelAfterFileByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chest.OBJ")
+	os.WriteFile(path, []byte("v 0 0 0\n"), 0644)
+	var gotName string
+	client := &fakeModel3DClient{uploadFn: func(p, name string) (domain.Model3D, error) {
+		gotName = name
+		return domain.Model3D{ID: "model-1", Name: name}, nil
+	}}
+
+	model, err := service.NewModel3DService(client).Upload(path, "  ")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotName != "chest" || model.ID != "model-1" {
+		t.Errorf("expected model-1 named chest, got %q named %q", model.ID, gotName)
+	}
+}
+
+func TestModel3DUpload_RejectsUnusableFilesBeforeUploading(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.obj")
+	os.WriteFile(empty, nil, 0644)
+	fbx := filepath.Join(dir, "chest.fbx")
+	os.WriteFile(fbx, []byte("fbx"), 0644)
+	client := &fakeModel3DClient{uploadFn: func(p, name string) (domain.Model3D, error) {
+		t.Fatalf("unexpected upload of %s", p)
+		return domain.Model3D{}, nil
+	}}
+	svc := service.NewModel3DService(client)
+
+	for _, path := range []string{fbx, empty, filepath.Join(dir, "missing.obj")} {
+		if _, err := svc.Upload(path, ""); err == nil {
+			t.Errorf("expected an error for %s", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeModelCache is an in-memory ports.ModelCache.
+type fakeModelCache struct {
+	catalog domain.ModelCatalog
+	saves   int
+}
+
+func (f *fakeModelCache) Load() (domain.ModelCatalog, error) { return f.catalog, nil }
+
+func (f *fakeModelCache) Save(catalog domain.ModelCatalog) error {
+	f.catalog = catalog
+	f.saves++
+	return nil
+}
+
+func modelList(calls *int, models ...domain.PlatformModel) func() (domain.PlatformModelResponse, error) {
+	return func() (domain.PlatformModelResponse, error) {
+		*calls++
+		return domain.PlatformModelResponse{Models: models}, nil
+	}
+}
+
+func TestModelValidate_RejectsUnsupportedSettingsAndListsSupportedOnes(t *testing.T) {
+	calls := 0
+	client := &fakeLeonardoClient{modelsFn: modelList(&calls,
+		domain.PlatformModel{ID: "sd15", Name: "Dreamshaper", SDVersion: "v1_5"})}
+	svc := service.NewModelService(client, &fakeModelCache{})
+
+	err := svc.Validate(domain.GenerationMetadata{ModelID: "sd15", Width: 1536, Height: 1020, Alchemy: true, PhotoReal: true})
+	var invalid *domain.InvalidRequestError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected InvalidRequestError, got %v", err)
+	}
+	for _, want := range []string{"model Dreamshaper", "width 1536", "height 1020", "PhotoReal", "supported: width and height 32-1024 in steps of 8, Alchemy"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got %q", want, err.Error())
+		}
+	}
+	if err := svc.Validate(domain.GenerationMetadata{ModelID: "sd15", Width: 768, Height: 512, Alchemy: true}); err != nil {
+		t.Errorf("expected supported request to pass, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the model list to be fetched once, got %d", calls)
+	}
+}
+
+func TestModelValidate_UsesFreshCacheAndSkipsUnknownModels(t *testing.T) {
+	calls := 0
+	client := &fakeLeonardoClient{modelsFn: modelList(&calls)}
+	cache := &fakeModelCache{catalog: domain.ModelCatalog{
+		FetchedAt: time.Now().Add(-time.Hour),
+		Models:    []domain.PlatformModel{{ID: "flux", Name: "Flux Dev", SDVersion: "FLUX_DEV"}},
+	}}
+	svc := service.NewModelService(client, cache)
+
+	if err := svc.Validate(domain.GenerationMetadata{ModelID: "flux", Alchemy: true}); err == nil {
+		t.Error("expected Alchemy to be rejected for Flux")
+	}
+	if calls != 0 {
+		t.Errorf("expected a fresh cache to avoid fetching, got %d calls", calls)
+	}
+	// A model missing from the cache triggers one refresh; custom models
+	// never appear in the platform list and are not checked.
+	if err := svc.Validate(domain.GenerationMetadata{ModelID: "custom", Width: 4000}); err != nil {
+		t.Errorf("expected unknown model to pass, got %v", err)
+	}
+	if calls != 1 || cache.saves != 1 {
+		t.Errorf("expected one refresh saved to the cache, got %d calls and %d saves", calls, cache.saves)
+	}
+}
+
+func TestModelValidate_RefetchesStaleCacheAndReportsFetchErrors(t *testing.T) {
+	client := &fakeLeonardoClient{modelsFn: func() (domain.PlatformModelResponse, error) {
+		return domain.PlatformModelResponse{}, errors.New("offline")
+	}}
+	cache := &fakeModelCache{catalog: domain.ModelCatalog{FetchedAt: time.Now().Add(-48 * time.Hour)}}
+	svc := service.NewModelService(client, cache)
+
+	err := svc.Validate(domain.GenerationMetadata{ModelID: "m"})
+	var invalid *domain.InvalidRequestError
+	if err == nil || errors.As(err, &invalid) {
+		t.Fatalf("expected a fetch error, got %v", err)
+	}
+	if err := svc.Validate(domain.GenerationMetadata{Prompt: "no model"}); err != nil {
+		t.Errorf("expected requests without a model to pass, got %v", err)
+	}
+}
+
+func TestModelPromptTokenLimit_DependsOnTextEncoder(t *testing.T) {
+	calls := 0
+	client := &fakeLeonardoClient{modelsFn: modelList(&calls,
+		domain.PlatformModel{ID: "sdxl", SDVersion: "SDXL_1_0"},
+		domain.PlatformModel{ID: "flux", SDVersion: "FLUX_DEV"})}
+	svc := service.NewModelService(client, &fakeModelCache{})
+
+	for id, want := range map[string]int{"sdxl": 75, "flux": 512, "custom": 75, "": 75} {
+		if got := svc.PromptTokenLimit(id); got != want {
+			t.Errorf("model %q: expected limit %d, got %d", id, want, got)
+		}
+	}
+}
+
+func TestModelDefaultSize_FillsOmittedDimensionsWithTheNativeResolution(t *testing.T) {
+	calls := 0
+	client := &fakeLeonardoClient{modelsFn: modelList(&calls,
+		domain.PlatformModel{ID: "sd15", Name: "Dreamshaper", SDVersion: "v1_5"},
+		domain.PlatformModel{ID: "xl", SDVersion: "SDXL_1_0"},
+		domain.PlatformModel{ID: "new", SDVersion: "SOMETHING_NEW"})}
+	svc := service.NewModelService(client, &fakeModelCache{})
+
+	meta, model, filled := svc.DefaultSize(domain.GenerationMetadata{ModelID: "sd15"})
+	if !filled || meta.Width != 512 || meta.Height != 512 || model.Name != "Dreamshaper" {
+		t.Errorf("expected 512x512 from Dreamshaper, got %dx%d from %q (%v)", meta.Width, meta.Height, model.Name, filled)
+	}
+	if meta, _, _ := svc.DefaultSize(domain.GenerationMetadata{ModelID: "xl", Width: 1344}); meta.Width != 1344 || meta.Height != 1024 {
+		t.Errorf("expected only the omitted height filled in, got %dx%d", meta.Width, meta.Height)
+	}
+	for _, id := range []string{"new", "custom", ""} {
+		if meta, _, filled := svc.DefaultSize(domain.GenerationMetadata{ModelID: id}); filled || meta.Width != 0 || meta.Height != 0 {
+			t.Errorf("model %q: expected the size left to the API, got %dx%d", id, meta.Width, meta.Height)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"leonardo-cli/internal/domain"
+)
+
+// Defaults used when walking every page of a user's generations.
+const (
+	// DefaultListPageSize is the number of generations requested per page.
+	DefaultListPageSize = 50
+	// DefaultListConcurrency is the number of pages fetched at once.
+	DefaultListConcurrency = 4
+)
+
+// listPage is the outcome of fetching one page of generations.
+type listPage struct {
+	index int
+	items []domain.GenerationListItem
+	err   error
+}
+
+// ErrStopIteration can be returned by the callback of IterateGenerations to
+// stop walking pages early without reporting an error.
+var ErrStopIteration = errors.New("stop iteration")
+
+// IterateGenerations passes every generation of a user to fn, newest first,
+// requesting pages of DefaultListPageSize one at a time so callers never
+// deal with offsets themselves.  It stops at the last page, when ctx is
+// done, or when fn returns an error; that error is returned unless it is
+// ErrStopIteration.
+func (s *GenerationService) IterateGenerations(ctx context.Context, userID string, fn func(domain.GenerationListItem) error) error {
+	return s.walkGenerations(ctx, userID, DefaultListPageSize, 1, fn)
+}
+
+// ListAllGenerations walks every page of a user's generations, fetching up
+// to concurrency pages at a time, and passes each generation to emit as soon
+// as the pages before it have arrived.  Generations are emitted in the order
+// the API lists them, and emit is always called from the caller's goroutine.
+// It stops early once the service's context is done.
+func (s *GenerationService) ListAllGenerations(userID string, pageSize, concurrency int, emit func(domain.GenerationListItem)) error {
+	return s.walkGenerations(s.ctx, userID, pageSize, concurrency, func(item domain.GenerationListItem) error {
+		emit(item)
+		return nil
+	})
+}
+
+// walkGenerations is the page walker behind IterateGenerations and
+// ListAllGenerations.
+//
+// The API does not report how many generations exist, so pages are requested
+// speculatively until one comes back short; the few requests already in
+// flight past the end return empty pages and are discarded.
+func (s *GenerationService) walkGenerations(ctx context.Context, userID string, pageSize, concurrency int, fn func(domain.GenerationListItem) error) error {
+	if pageSize < 1 {
+		pageSize = DefaultListPageSize
+	}
+	if concurrency < 1 {
+		concurrency = DefaultListConcurrency
+	}
+
+	var (
+		mu       sync.Mutex
+		next     int
+		lastPage = -1 // index of the first short or failed page, once known
+		stopped  bool // set once fn has asked to stop
+	)
+	claim := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped || (lastPage >= 0 && next > lastPage) {
+			return 0, false
+		}
+		index := next
+		next++
+		return index, true
+	}
+	finish := func(index int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if lastPage < 0 || index < lastPage {
+			lastPage = index
+		}
+	}
+	stop := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+	}
+
+	results := make(chan listPage, concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				index, ok := claim()
+				if !ok {
+					return
+				}
+				var resp domain.GenerationListResponse
+				err := ctx.Err()
+				if err == nil {
+					resp, err = s.client.ListGenerations(userID, index*pageSize, pageSize)
+				}
+				if err != nil {
+					err = fmt.Errorf("listing generations at offset %d: %w", index*pageSize, err)
+				}
+				if err != nil || len(resp.Generations) < pageSize {
+					finish(index)
+				}
+				results <- listPage{index: index, items: resp.Generations, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Pages may arrive out of order; hold them until every earlier page has
+	// been passed to fn.  Results are drained to the end so no worker is left
+	// blocked, even after the last page or an error has been seen.
+	pending := map[int]listPage{}
+	want := 0
+	done := false
+	var firstErr error
+	for page := range results {
+		if done {
+			continue
+		}
+		pending[page.index] = page
+		for !done {
+			p, ok := pending[want]
+			if !ok {
+				break
+			}
+			delete(pending, want)
+			want++
+			if p.err != nil {
+				firstErr = p.err
+				done = true
+				break
+			}
+			for _, item := range p.items {
+				if err := fn(item); err != nil {
+					if !errors.Is(err, ErrStopIteration) {
+						firstErr = err
+					}
+					done = true
+					stop()
+					break
+				}
+			}
+			done = done || len(p.items) < pageSize
+		}
+	}
+	return firstErr
+}
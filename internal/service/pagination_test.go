@@ -0,0 +1,214 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// --- Behavior: Walking every page of generations ---
+
+// pagedGenerations returns a listFn serving total generations named gen-0,
+// gen-1, ... in pages, with later pages answering faster than earlier ones so
+// results arrive out of order.
+func pagedGenerations(total int, calls *int, mu *sync.Mutex) func(string, int, int) (domain.GenerationListResponse, error) {
+	return func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+		mu.Lock()
+		*calls++
+		mu.Unlock()
+		time.Sleep(time.Duration(10-offset/limit%10) * time.Millisecond)
+		var resp domain.GenerationListResponse
+		for i := offset; i < offset+limit && i < total; i++ {
+			resp.Generations = append(resp.Generations, domain.GenerationListItem{ID: fmt.Sprintf("gen-%d", i)})
+		}
+		return resp, nil
+	}
+}
+
+func TestListAllGenerations_EmitsEveryGenerationInOrder(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fake := &fakeLeonardoClient{listFn: pagedGenerations(23, &calls, &mu)}
+	svc := service.NewGenerationService(fake)
+
+	var ids []string
+	err := svc.ListAllGenerations("user-1", 5, 3, func(item domain.GenerationListItem) {
+		ids = append(ids, item.ID)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 23 {
+		t.Fatalf("expected 23 generations, got %d", len(ids))
+	}
+	for i, id := range ids {
+		if expected := fmt.Sprintf("gen-%d", i); id != expected {
+			t.Fatalf("expected %q at position %d, got %q", expected, i, id)
+		}
+	}
+	// Five pages are needed; at most concurrency-1 extra pages may be in
+	// flight when the short page is seen.
+	if calls < 5 || calls > 7 {
+		t.Errorf("expected between 5 and 7 page requests, got %d", calls)
+	}
+}
+
+func TestListAllGenerations_StopsAtExactPageBoundary(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fake := &fakeLeonardoClient{listFn: pagedGenerations(10, &calls, &mu)}
+	svc := service.NewGenerationService(fake)
+
+	count := 0
+	if err := svc.ListAllGenerations("user-1", 5, 2, func(domain.GenerationListItem) { count++ }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 10 {
+		t.Errorf("expected 10 generations, got %d", count)
+	}
+}
+
+func TestListAllGenerations_NeverExceedsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	fake := &fakeLeonardoClient{
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			mu.Lock()
+			inFlight++
+			if inFlight > peak {
+				peak = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(2 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			var resp domain.GenerationListResponse
+			if offset < 40 {
+				resp.Generations = make([]domain.GenerationListItem, limit)
+			}
+			return resp, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	if err := svc.ListAllGenerations("user-1", 2, 3, func(domain.GenerationListItem) {}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent requests, got %d", peak)
+	}
+}
+
+func TestListAllGenerations_ReturnsErrorAfterEmittingEarlierPages(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			if offset >= 10 {
+				return domain.GenerationListResponse{}, errors.New("API returned status 500")
+			}
+			return domain.GenerationListResponse{Generations: make([]domain.GenerationListItem, limit)}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	count := 0
+	err := svc.ListAllGenerations("user-1", 5, 4, func(domain.GenerationListItem) { count++ })
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "offset 10") {
+		t.Errorf("expected error to mention offset 10, got %q", err.Error())
+	}
+	if count != 10 {
+		t.Errorf("expected the 10 generations before the failure, got %d", count)
+	}
+}
+
+func TestIterateGenerations_PassesEveryGenerationInOrder(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fake := &fakeLeonardoClient{listFn: pagedGenerations(120, &calls, &mu)}
+	svc := service.NewGenerationService(fake)
+
+	var got []string
+	err := svc.IterateGenerations(context.Background(), "user-1", func(item domain.GenerationListItem) error {
+		got = append(got, item.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 120 {
+		t.Fatalf("expected 120 generations, got %d", len(got))
+	}
+	for i, id := range got {
+		if want := fmt.Sprintf("gen-%d", i); id != want {
+			t.Fatalf("position %d: expected %s, got %s", i, want, id)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 page requests, got %d", calls)
+	}
+}
+
+func TestIterateGenerations_StopsQuietlyOnErrStopIteration(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fake := &fakeLeonardoClient{listFn: pagedGenerations(500, &calls, &mu)}
+	svc := service.NewGenerationService(fake)
+
+	count := 0
+	err := svc.IterateGenerations(context.Background(), "user-1", func(domain.GenerationListItem) error {
+		count++
+		if count == 10 {
+			return service.ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected a clean stop, got %v", err)
+	}
+	if count != 10 {
+		t.Errorf("expected 10 generations before stopping, got %d", count)
+	}
+	if calls > 2 {
+		t.Errorf("expected paging to stop with the callback, got %d requests", calls)
+	}
+}
+
+func TestIterateGenerations_ReturnsCallbackError(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fake := &fakeLeonardoClient{listFn: pagedGenerations(120, &calls, &mu)}
+	svc := service.NewGenerationService(fake)
+
+	boom := errors.New("disk full")
+	err := svc.IterateGenerations(context.Background(), "user-1", func(domain.GenerationListItem) error { return boom })
+	if !errors.Is(err, boom) {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+}
+
+func TestIterateGenerations_StopsWhenContextIsCancelled(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			t.Fatal("expected no request after cancellation")
+			return domain.GenerationListResponse{}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := svc.IterateGenerations(ctx, "user-1", func(domain.GenerationListItem) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
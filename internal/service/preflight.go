@@ -0,0 +1,66 @@
+package service
+
+import (
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// PreflightService checks, before a batch run starts, that the token works
+// and the account can pay for the whole run, so an impossible run stops
+// up front rather than failing item after item.
+type PreflightService struct {
+	client  ports.LeonardoClient
+	pricing ports.PricingClient
+	models  *ModelService
+}
+
+// NewPreflightService constructs a new PreflightService given a client, a
+// pricing client and the model service used to look up each request's
+// model.
+func NewPreflightService(client ports.LeonardoClient, pricing ports.PricingClient, models *ModelService) *PreflightService {
+	return &PreflightService{client: client, pricing: pricing, models: models}
+}
+
+// Check verifies the token, reads the token balance and estimates the cost
+// of requests.  Identical configurations are priced once.  Requests that
+// cannot be priced, for example because the model list is unavailable, are
+// counted as unpriced rather than failing the check.  A report whose Err is
+// non-nil means the run cannot complete.
+func (s *PreflightService) Check(requests []domain.GenerationRequest) domain.PreflightReport {
+	report := domain.PreflightReport{Requests: len(requests)}
+	info, err := s.client.GetUserInfo()
+	report.Token = domain.ClassifyTokenError(err)
+	if err != nil {
+		report.TokenError = err
+		return report
+	}
+	report.Balance = info.APISubscriptionTokens + info.APIPaidTokens
+	catalog, err := s.models.Catalog(false)
+	if err != nil {
+		report.Unpriced = len(requests)
+		return report
+	}
+	type price struct {
+		cost int
+		err  error
+	}
+	prices := make(map[domain.PriceQuery]price)
+	for _, req := range requests {
+		model, found := catalog.Find(req.Metadata.ModelID)
+		if !found {
+			model = domain.PlatformModel{ID: req.Metadata.ModelID}
+		}
+		query := domain.PriceQueryFor(req, model, req.Metadata.HasModelID() && !found)
+		p, ok := prices[query]
+		if !ok {
+			p.cost, p.err = s.pricing.CalculateCost(query)
+			prices[query] = p
+		}
+		if p.err != nil {
+			report.Unpriced++
+			continue
+		}
+		report.EstimatedCost += p.cost
+	}
+	return report
+}
@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+func TestPreflightCheck_PricesIdenticalRequestsOnce(t *testing.T) {
+	calls := 0
+	client := &fakeLeonardoClient{
+		userFn: func() (domain.UserInfo, error) {
+			return domain.UserInfo{APISubscriptionTokens: 50, APIPaidTokens: 10}, nil
+		},
+		modelsFn: modelList(&calls, domain.PlatformModel{ID: "xl", SDVersion: "SDXL_1_0"}),
+	}
+	var queries []domain.PriceQuery
+	pricing := &fakePricingClient{costFn: func(q domain.PriceQuery) (int, error) {
+		queries = append(queries, q)
+		return 8 * q.NumImages, nil
+	}}
+	requests := []domain.GenerationRequest{
+		{NumImages: 2, Metadata: domain.GenerationMetadata{ModelID: "xl", Width: 512, Height: 512}},
+		{NumImages: 2, Metadata: domain.GenerationMetadata{ModelID: "xl", Width: 512, Height: 512}},
+		{Metadata: domain.GenerationMetadata{ModelID: "custom"}},
+	}
+
+	report := service.NewPreflightService(client, pricing, service.NewModelService(client, &fakeModelCache{})).Check(requests)
+
+	if err := report.Err(); err != nil {
+		t.Fatalf("unexpected preflight failure: %v", err)
+	}
+	if report.Balance != 60 || report.EstimatedCost != 16+16+8 || report.Unpriced != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 distinct configurations to be priced, got %+v", queries)
+	}
+	if !queries[0].SDXL() || queries[0].CustomModel {
+		t.Errorf("expected an SDXL platform model query, got %+v", queries[0])
+	}
+	if !queries[1].CustomModel || queries[1].Width != 1024 || queries[1].Height != 1024 || queries[1].NumImages != 1 {
+		t.Errorf("expected a default-sized custom model query, got %+v", queries[1])
+	}
+}
+
+func TestPreflightCheck_FailsWhenBalanceIsShort(t *testing.T) {
+	calls := 0
+	client := &fakeLeonardoClient{
+		userFn:   func() (domain.UserInfo, error) { return domain.UserInfo{APIPaidTokens: 20}, nil },
+		modelsFn: modelList(&calls),
+	}
+	pricing := &fakePricingClient{costFn: func(q domain.PriceQuery) (int, error) { return 15, nil }}
+	requests := make([]domain.GenerationRequest, 3)
+
+	report := service.NewPreflightService(client, pricing, service.NewModelService(client, &fakeModelCache{})).Check(requests)
+
+	var preflightErr *domain.PreflightError
+	err := report.Err()
+	if !errors.As(err, &preflightErr) {
+		t.Fatalf("expected a PreflightError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "short by 25") || !strings.Contains(err.Error(), "estimated cost 45 tokens") {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestPreflightCheck_StopsAtInvalidToken(t *testing.T) {
+	client := &fakeLeonardoClient{userFn: func() (domain.UserInfo, error) {
+		return domain.UserInfo{}, &domain.APIError{StatusCode: 401}
+	}}
+	pricing := &fakePricingClient{costFn: func(q domain.PriceQuery) (int, error) {
+		t.Fatal("unexpected pricing call")
+		return 0, nil
+	}}
+
+	report := service.NewPreflightService(client, pricing, service.NewModelService(client, &fakeModelCache{})).Check(make([]domain.GenerationRequest, 2))
+
+	if report.Token != domain.TokenInvalid || report.Err() == nil {
+		t.Errorf("expected an invalid token to fail the preflight, got %+v", report)
+	}
+}
+
+func TestPreflightCheck_CountsUnpricedRequests(t *testing.T) {
+	calls := 0
+	client := &fakeLeonardoClient{
+		userFn:   func() (domain.UserInfo, error) { return domain.UserInfo{APIPaidTokens: 5}, nil },
+		modelsFn: modelList(&calls),
+	}
+	pricing := &fakePricingClient{costFn: func(q domain.PriceQuery) (int, error) {
+		return 0, errors.New("calculator down")
+	}}
+
+	report := service.NewPreflightService(client, pricing, service.NewModelService(client, &fakeModelCache{})).Check(make([]domain.GenerationRequest, 2))
+
+	if report.Err() != nil || report.Unpriced != 2 || report.EstimatedCost != 0 {
+		t.Errorf("expected both requests to be unpriced without failing, got %+v", report)
+	}
+}
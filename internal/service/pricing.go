@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// DefaultPricingConcurrency is the number of configurations priced at once.
+const DefaultPricingConcurrency = 4
+
+// PricingService asks the pricing calculator what generations would cost.
+type PricingService struct {
+	client      ports.PricingClient
+	concurrency int
+}
+
+// NewPricingService constructs a new PricingService given a client.
+func NewPricingService(client ports.PricingClient) *PricingService {
+	return &PricingService{client: client, concurrency: DefaultPricingConcurrency}
+}
+
+// Quote returns the cost of a single configuration.
+func (s *PricingService) Quote(query domain.PriceQuery) (domain.PriceQuote, error) {
+	cost, err := s.client.CalculateCost(query)
+	if err != nil {
+		return domain.PriceQuote{}, err
+	}
+	return domain.PriceQuote{PriceQuery: query, Cost: cost}, nil
+}
+
+// Table prices every query, several at a time, and returns the quotes in
+// the order of the queries.  The first failure aborts the table.
+func (s *PricingService) Table(queries []domain.PriceQuery) ([]domain.PriceQuote, error) {
+	quotes := make([]domain.PriceQuote, len(queries))
+	errs := make([]error, len(queries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				quotes[i], errs[i] = s.Quote(queries[i])
+			}
+		}()
+	}
+	for i := range queries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			q := queries[i]
+			return nil, fmt.Errorf("pricing %dx%d with %d images: %w", q.Width, q.Height, q.NumImages, err)
+		}
+	}
+	return quotes, nil
+}
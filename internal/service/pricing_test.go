@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakePricingClient implements ports.PricingClient for testing.
+type fakePricingClient struct {
+	mu     sync.Mutex
+	calls  int
+	costFn func(query domain.PriceQuery) (int, error)
+}
+
+func (f *fakePricingClient) CalculateCost(query domain.PriceQuery) (int, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	return f.costFn(query)
+}
+
+func TestPricingTable_KeepsQueryOrder(t *testing.T) {
+	client := &fakePricingClient{costFn: func(q domain.PriceQuery) (int, error) {
+		return q.Width / 64 * q.NumImages, nil
+	}}
+	queries := []domain.PriceQuery{
+		{Width: 1024, Height: 1024, NumImages: 1},
+		{Width: 512, Height: 512, NumImages: 2},
+		{Width: 768, Height: 768, NumImages: 4},
+		{Width: 1536, Height: 1536, NumImages: 1},
+		{Width: 640, Height: 640, NumImages: 1},
+	}
+
+	quotes, err := service.NewPricingService(client).Table(queries)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.calls != len(queries) {
+		t.Errorf("expected %d calls, got %d", len(queries), client.calls)
+	}
+	want := []int{16, 16, 48, 24, 10}
+	for i, q := range quotes {
+		if q.PriceQuery != queries[i] || q.Cost != want[i] {
+			t.Errorf("quote %d = %+v, want cost %d for %+v", i, q, want[i], queries[i])
+		}
+	}
+}
+
+func TestPricingTable_ReportsFailingConfiguration(t *testing.T) {
+	client := &fakePricingClient{costFn: func(q domain.PriceQuery) (int, error) {
+		if q.Width == 768 {
+			return 0, errors.New("boom")
+		}
+		return 1, nil
+	}}
+	queries := []domain.PriceQuery{{Width: 512, Height: 512, NumImages: 1}, {Width: 768, Height: 768, NumImages: 2}}
+
+	_, err := service.NewPricingService(client).Table(queries)
+
+	if err == nil || err.Error() != "pricing 768x768 with 2 images: boom" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// ProjectService records the provenance of a project's asset files: which
+// generation, prompt and model produced each committed image.
+type ProjectService struct {
+	manifest ports.ProjectManifest
+	library  ports.Library
+	prompt   func(string) string
+}
+
+// NewProjectService constructs a new ProjectService given the project's
+// manifest and the local library used to look up generation details.
+func NewProjectService(manifest ports.ProjectManifest, library ports.Library) *ProjectService {
+	return &ProjectService{manifest: manifest, library: library}
+}
+
+// SetPromptFilter registers fn to transform prompts before they are
+// written to the manifest, e.g. to redact them.  A nil fn records prompts
+// unchanged.
+func (s *ProjectService) SetPromptFilter(fn func(string) string) {
+	s.prompt = fn
+}
+
+// Init starts an empty manifest for the project.
+func (s *ProjectService) Init() error {
+	return s.manifest.Create()
+}
+
+// Add records that asset was produced by its generation.  The prompt and
+// model are filled in from the local library when the generation was
+// created from this machine.
+func (s *ProjectService) Add(asset domain.ProjectAsset) (domain.ProjectAsset, error) {
+	if strings.TrimSpace(asset.GenerationID) == "" {
+		return asset, fmt.Errorf("generation ID is empty; cannot record asset")
+	}
+	cleaned, ok := domain.CleanAssetPath(asset.Path)
+	if !ok {
+		return asset, fmt.Errorf("%s is outside the project", asset.Path)
+	}
+	asset.Path = cleaned
+	entries, err := s.library.List()
+	if err != nil {
+		return asset, err
+	}
+	for _, e := range entries {
+		if e.GenerationID == asset.GenerationID {
+			if asset.Prompt == "" {
+				asset.Prompt = e.Prompt
+			}
+			if asset.ModelID == "" {
+				asset.ModelID = e.ModelID
+			}
+		}
+	}
+	if s.prompt != nil && asset.Prompt != "" {
+		asset.Prompt = s.prompt(asset.Prompt)
+	}
+	if asset.AddedAt.IsZero() {
+		asset.AddedAt = time.Now().UTC()
+	}
+	return asset, s.manifest.Save(asset)
+}
+
+// Assets returns the recorded assets ordered by path.
+func (s *ProjectService) Assets() ([]domain.ProjectAsset, error) {
+	return s.manifest.List()
+}
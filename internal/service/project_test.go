@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeProjectManifest implements ports.ProjectManifest in memory.
+type fakeProjectManifest struct {
+	assets []domain.ProjectAsset
+}
+
+func (f *fakeProjectManifest) Create() error { return nil }
+
+func (f *fakeProjectManifest) List() ([]domain.ProjectAsset, error) {
+	return append([]domain.ProjectAsset(nil), f.assets...), nil
+}
+
+func (f *fakeProjectManifest) Save(asset domain.ProjectAsset) error {
+	f.assets = append(f.assets, asset)
+	return nil
+}
+
+func TestProjectAdd_FillsDetailsFromLibrary(t *testing.T) {
+	lib := &fakeLibrary{entries: []domain.LibraryEntry{{GenerationID: "gen-1", Prompt: "a red fox", ModelID: "model-1"}}}
+	manifest := &fakeProjectManifest{}
+	svc := service.NewProjectService(manifest, lib)
+	svc.SetPromptFilter(strings.ToUpper)
+
+	asset, err := svc.Add(domain.ProjectAsset{Path: "art/./fox.png", GenerationID: "gen-1", SHA256: "abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if asset.Path != "art/fox.png" {
+		t.Errorf("expected cleaned path %q, got %q", "art/fox.png", asset.Path)
+	}
+	if asset.Prompt != "A RED FOX" || asset.ModelID != "model-1" {
+		t.Errorf("expected filtered prompt and model from the library, got %+v", asset)
+	}
+	if asset.AddedAt.IsZero() {
+		t.Error("expected AddedAt to be set")
+	}
+	if len(manifest.assets) != 1 || manifest.assets[0] != asset {
+		t.Errorf("expected the asset to be saved, got %+v", manifest.assets)
+	}
+}
+
+func TestProjectAdd_RejectsPathsOutsideTheProject(t *testing.T) {
+	svc := service.NewProjectService(&fakeProjectManifest{}, &fakeLibrary{})
+	for _, path := range []string{"../other/fox.png", "/abs/fox.png", "."} {
+		if _, err := svc.Add(domain.ProjectAsset{Path: path, GenerationID: "gen-1"}); err == nil {
+			t.Errorf("expected %q to be rejected", path)
+		}
+	}
+	if _, err := svc.Add(domain.ProjectAsset{Path: "fox.png"}); err == nil {
+		t.Error("expected a missing generation ID to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// DefaultRawRetryDelay is the wait before the first retry of a raw request
+// when the API does not say how long to wait; it doubles for each retry
+// after that.
+const DefaultRawRetryDelay = time.Second
+
+// RawService sends arbitrary requests to the API for endpoints the CLI does
+// not wrap, retrying failures that are safe to retry.
+type RawService struct {
+	client ports.RawClient
+	ctx    context.Context
+	delay  time.Duration
+}
+
+// NewRawService constructs a new RawService given a client.
+func NewRawService(client ports.RawClient) *RawService {
+	return &RawService{client: client, ctx: context.Background(), delay: DefaultRawRetryDelay}
+}
+
+// SetContext makes waits between retries stop once ctx is done, for
+// example on Ctrl-C.
+func (s *RawService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// SetRetryDelay sets the wait before the first retry when the API gives no
+// Retry-After.
+func (s *RawService) SetRetryDelay(delay time.Duration) {
+	s.delay = delay
+}
+
+// Send sends req, retrying up to retries times on rate limits and, for
+// idempotent requests, on transient failures such as server errors.  Each
+// retry waits as long as the API's Retry-After asks, or else backs off
+// exponentially.  The last response is returned with its error.
+func (s *RawService) Send(req domain.RawRequest, retries int) (domain.RawResponse, error) {
+	delay := s.delay
+	for attempt := 0; ; attempt++ {
+		resp, err := s.client.SendRaw(req)
+		if err == nil || attempt >= retries || !rawRetryable(req, err) {
+			return resp, err
+		}
+		wait := resp.RetryAfter
+		if wait <= 0 {
+			wait = delay
+		}
+		delay *= 2
+		timer := time.NewTimer(wait)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
+
+// rawRetryable reports whether req may be sent again after err.  Requests
+// that are not idempotent are retried only when rate limited, since a
+// transient failure may have happened after the API acted on them.
+func rawRetryable(req domain.RawRequest, err error) bool {
+	class := domain.ClassifyFailure(err)
+	if !class.Retryable() {
+		return false
+	}
+	return req.Idempotent() || class == domain.FailureRateLimit
+}
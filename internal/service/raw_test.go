@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeRawClient implements ports.RawClient for testing, answering with the
+// next of its statuses on each call.
+type fakeRawClient struct {
+	statuses []int
+	calls    int
+}
+
+func (f *fakeRawClient) SendRaw(req domain.RawRequest) (domain.RawResponse, error) {
+	status := f.statuses[f.calls]
+	f.calls++
+	resp := domain.RawResponse{StatusCode: status, Body: []byte(`{}`)}
+	if status >= 300 {
+		return resp, &domain.APIError{StatusCode: status}
+	}
+	return resp, nil
+}
+
+func newRawService(client *fakeRawClient) *service.RawService {
+	raw := service.NewRawService(client)
+	raw.SetRetryDelay(time.Millisecond)
+	return raw
+}
+
+func TestRawSend_RetriesIdempotentRequestsOnServerErrors(t *testing.T) {
+	client := &fakeRawClient{statuses: []int{503, 500, 200}}
+
+	resp, err := newRawService(client).Send(domain.RawRequest{Method: "GET", Path: "/me"}, 2)
+
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected success after retries, got %d, %v", resp.StatusCode, err)
+	}
+	if client.calls != 3 {
+		t.Errorf("expected 3 calls, got %d", client.calls)
+	}
+}
+
+func TestRawSend_RetriesPostOnlyOnRateLimits(t *testing.T) {
+	client := &fakeRawClient{statuses: []int{429, 502, 200}}
+
+	resp, err := newRawService(client).Send(domain.RawRequest{Method: "POST", Path: "/generations"}, 5)
+
+	var apiErr *domain.APIError
+	if !errors.As(err, &apiErr) || resp.StatusCode != 502 {
+		t.Fatalf("expected the 502 to be returned, got %d, %v", resp.StatusCode, err)
+	}
+	if client.calls != 2 {
+		t.Errorf("expected the rate limit to be retried once, got %d calls", client.calls)
+	}
+}
+
+func TestRawSend_StopsAtRetryLimitAndPermanentErrors(t *testing.T) {
+	client := &fakeRawClient{statuses: []int{500, 500, 500}}
+	if _, err := newRawService(client).Send(domain.RawRequest{Method: "GET", Path: "/me"}, 1); err == nil || client.calls != 2 {
+		t.Errorf("expected failure after 2 calls, got %d calls, %v", client.calls, err)
+	}
+
+	client = &fakeRawClient{statuses: []int{404, 200}}
+	if _, err := newRawService(client).Send(domain.RawRequest{Method: "GET", Path: "/nope"}, 3); err == nil || client.calls != 1 {
+		t.Errorf("expected a 404 not to be retried, got %d calls, %v", client.calls, err)
+	}
+}
+
+func TestRawSend_StopsWaitingWhenCancelled(t *testing.T) {
+	client := &fakeRawClient{statuses: []int{429, 200}}
+	raw := service.NewRawService(client)
+	raw.SetRetryDelay(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	raw.SetContext(ctx)
+
+	if _, err := raw.Send(domain.RawRequest{Method: "GET", Path: "/me"}, 3); err == nil || client.calls != 1 {
+		t.Errorf("expected the wait to be cut short, got %d calls, %v", client.calls, err)
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// RecipeService loads generation settings others shared at a URL, such as
+// a share bundle's generation.json or a sidecar.
+type RecipeService struct {
+	source ports.RecipeSource
+}
+
+// NewRecipeService constructs a new RecipeService given a recipe source.
+func NewRecipeService(source ports.RecipeSource) *RecipeService {
+	return &RecipeService{source: source}
+}
+
+// Load fetches the recipe at rawURL, which must be an http or https URL,
+// and parses it.
+func (s *RecipeService) Load(rawURL string) (domain.Recipe, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return domain.Recipe{}, fmt.Errorf("recipe URL %q must be an http or https URL", rawURL)
+	}
+	data, err := s.source.FetchRecipe(rawURL)
+	if err != nil {
+		return domain.Recipe{}, fmt.Errorf("fetching recipe: %w", err)
+	}
+	recipe, err := domain.ParseRecipe(data)
+	if err != nil {
+		return domain.Recipe{}, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	return recipe, nil
+}
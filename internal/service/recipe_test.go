@@ -0,0 +1,47 @@
+package service_test
+
+import (
+	"testing"
+
+	"leonardo-cli/internal/service"
+)
+
+// fakeRecipeSource implements ports.RecipeSource, recording the URLs asked
+// for.
+type fakeRecipeSource struct {
+	body    string
+	fetched []string
+}
+
+func (f *fakeRecipeSource) FetchRecipe(url string) ([]byte, error) {
+	f.fetched = append(f.fetched, url)
+	return []byte(f.body), nil
+}
+
+func TestRecipeService_LoadParsesFetchedRecipe(t *testing.T) {
+	source := &fakeRecipeSource{body: `{"prompt":"a fox","seed":42}`}
+	svc := service.NewRecipeService(source)
+
+	recipe, err := svc.Load("https://example.com/fox.json")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if recipe.Settings["prompt"] != "a fox" || recipe.Settings["seed"] != "42" {
+		t.Errorf("unexpected settings %v", recipe.Settings)
+	}
+}
+
+func TestRecipeService_LoadRefusesNonHTTPURLs(t *testing.T) {
+	source := &fakeRecipeSource{body: `{"prompt":"a fox"}`}
+	svc := service.NewRecipeService(source)
+
+	for _, url := range []string{"file:///etc/passwd", "fox.json", "https://"} {
+		if _, err := svc.Load(url); err == nil {
+			t.Errorf("expected an error for %q", url)
+		}
+	}
+	if len(source.fetched) != 0 {
+		t.Errorf("expected nothing fetched, got %v", source.fetched)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// RestyleResult describes one source image run through an image-to-image
+// preset.
+type RestyleResult struct {
+	InitImageID  string
+	GenerationID string
+	FilePaths    []string
+	// Cost is the API tokens the generation was charged, when the API
+	// reported it.
+	Cost int
+	// Duration is how long the generation took from submission to
+	// completion.
+	Duration time.Duration
+}
+
+// RestyleService runs local images through an image-to-image preset: each
+// image is uploaded as an init image and used as the starting point of a
+// generation whose other settings come from the preset.
+type RestyleService struct {
+	generations *GenerationService
+	initImages  *InitImageService
+}
+
+// NewRestyleService constructs a new RestyleService from the services that
+// create generations and upload init images.
+func NewRestyleService(generations *GenerationService, initImages *InitImageService) *RestyleService {
+	return &RestyleService{generations: generations, initImages: initImages}
+}
+
+// Restyle uploads the image at path, generates from it with preset, waits
+// for the generation and downloads the results to outputDir as
+// {source name}_{n}.png.  interval and timeout apply to the wait, as in
+// AwaitCompletion.  The result holds whatever was done before a failure.
+func (s *RestyleService) Restyle(path string, preset domain.GenerationRequest, outputDir string, interval, timeout time.Duration) (RestyleResult, error) {
+	var result RestyleResult
+	image, err := s.initImages.Upload(path)
+	if err != nil {
+		return result, fmt.Errorf("uploading init image: %w", err)
+	}
+	result.InitImageID = image.ID
+	req := preset
+	req.Metadata.InitImageID = image.ID
+	submitted := time.Now()
+	res, err := s.generations.Create(req)
+	if err != nil {
+		return result, fmt.Errorf("creating generation: %w", err)
+	}
+	result.GenerationID = res.GenerationID
+	result.Cost = res.Cost
+	status, err := s.generations.AwaitCompletion(res.GenerationID, interval, timeout)
+	if err != nil {
+		return result, err
+	}
+	if status.Status != statusComplete {
+		return result, fmt.Errorf("generation %s finished with status %s", res.GenerationID, status.Status)
+	}
+	result.Duration = time.Since(submitted)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	downloaded, err := s.generations.DownloadAs(res.GenerationID, outputDir, name)
+	result.FilePaths = downloaded.FilePaths
+	return result, err
+}
@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+func TestRestyle_UploadsGeneratesAndDownloadsNamedAfterSource(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "harbour.jpg")
+	os.WriteFile(src, []byte("jpg"), 0644)
+	out := t.TempDir()
+	var sent domain.GenerationRequest
+	var downloaded []string
+	client := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			sent = req
+			return domain.GenerationResponse{GenerationID: "gen-1"}, nil
+		},
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE", Images: []string{"u1", "u2"}}, nil
+		},
+		downloadFn: func(url, destPath string) error {
+			downloaded = append(downloaded, destPath)
+			return nil
+		},
+	}
+	images := service.NewInitImageService(&fakeInitImageClient{uploadFn: func(p string) (domain.InitImage, error) {
+		return domain.InitImage{ID: "init-9"}, nil
+	}}, &fakeInitImageStore{})
+	svc := service.NewRestyleService(service.NewGenerationService(client), images)
+
+	preset := domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "oil painting", InitStrength: 0.4}}
+	result, err := svc.Restyle(src, preset, out, 0, 0)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent.Metadata.InitImageID != "init-9" || sent.Metadata.InitStrength != 0.4 {
+		t.Errorf("expected the uploaded image to seed the preset, got %+v", sent.Metadata)
+	}
+	want := []string{filepath.Join(out, "harbour_1.png"), filepath.Join(out, "harbour_2.png")}
+	if len(downloaded) != 2 || downloaded[0] != want[0] || downloaded[1] != want[1] {
+		t.Errorf("expected downloads %v, got %v", want, downloaded)
+	}
+	if result.InitImageID != "init-9" || result.GenerationID != "gen-1" || len(result.FilePaths) != 2 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestRestyle_ReportsFailedGenerations(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a.png")
+	os.WriteFile(src, []byte("png"), 0644)
+	client := &fakeLeonardoClient{
+		createFn: func(req domain.GenerationRequest) (domain.GenerationResponse, error) {
+			return domain.GenerationResponse{GenerationID: "gen-2"}, nil
+		},
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "FAILED"}, nil
+		},
+	}
+	images := service.NewInitImageService(&fakeInitImageClient{uploadFn: func(p string) (domain.InitImage, error) {
+		return domain.InitImage{ID: "init-1"}, nil
+	}}, &fakeInitImageStore{})
+	svc := service.NewRestyleService(service.NewGenerationService(client), images)
+
+	result, err := svc.Restyle(src, domain.GenerationRequest{Metadata: domain.GenerationMetadata{Prompt: "p"}}, t.TempDir(), 0, 0)
+
+	if err == nil {
+		t.Fatal("expected an error for a failed generation")
+	}
+	if result.GenerationID != "gen-2" {
+		t.Errorf("expected the generation ID to be reported, got %q", result.GenerationID)
+	}
+}
+
+func TestCreate_RejectsInitStrengthWithoutInitImage(t *testing.T) {
+	svc := service.NewGenerationService(&fakeLeonardoClient{})
+	for _, meta := range []domain.GenerationMetadata{
+		{Prompt: "p", InitStrength: 0.5},
+		{Prompt: "p", InitImageID: "init-1", InitStrength: 0.95},
+	} {
+		_, err := svc.Create(domain.GenerationRequest{Metadata: meta})
+		var invalid *domain.InvalidRequestError
+		if !errors.As(err, &invalid) {
+			t.Errorf("expected InvalidRequestError for %+v, got %v", meta, err)
+		}
+	}
+}
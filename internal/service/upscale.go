@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// AutoUpscale waits for a generation to complete, starts an upscale of every
+// image it produced, waits for the upscales and downloads them to outputDir
+// as {generationID}_{n}_upscaled.png.  interval and timeout apply to each
+// wait, as in AwaitCompletion.
+func (s *GenerationService) AutoUpscale(id, outputDir string, interval, timeout time.Duration) (domain.DownloadResult, error) {
+	status, err := s.AwaitCompletion(id, interval, timeout)
+	if err != nil {
+		return domain.DownloadResult{}, err
+	}
+	if status.Status != statusComplete {
+		return domain.DownloadResult{}, fmt.Errorf("generation %s finished with status %s", id, status.Status)
+	}
+	detail, err := s.client.GetGeneration(id)
+	if err != nil {
+		return domain.DownloadResult{}, err
+	}
+	if len(detail.Images) == 0 {
+		return domain.DownloadResult{}, fmt.Errorf("no images available for generation %s", id)
+	}
+	jobs := make([]string, 0, len(detail.Images))
+	for i, img := range detail.Images {
+		job, err := s.client.UpscaleImage(img.ID)
+		if err != nil {
+			return domain.DownloadResult{}, fmt.Errorf("upscaling image %d: %w", i+1, err)
+		}
+		s.report(domain.ProgressEvent{Kind: domain.ProgressSubmitted, GenerationID: job.ID})
+		jobs = append(jobs, job.ID)
+	}
+	var filePaths []string
+	for i, jobID := range jobs {
+		variation, err := s.awaitVariation(jobID, interval, timeout)
+		if err != nil {
+			return domain.DownloadResult{FilePaths: filePaths}, fmt.Errorf("upscaling image %d: %w", i+1, err)
+		}
+		destPath := filepath.Join(outputDir, fmt.Sprintf("%s_%d_upscaled.png", id, i+1))
+		if err := s.client.DownloadImage(variation.URL, destPath); err != nil {
+			return domain.DownloadResult{FilePaths: filePaths}, fmt.Errorf("downloading upscaled image %d: %w", i+1, err)
+		}
+		filePaths = append(filePaths, destPath)
+		s.report(domain.ProgressEvent{Kind: domain.ProgressImageDownloaded, GenerationID: id, Image: i + 1, Images: len(jobs), Path: destPath})
+	}
+	return domain.DownloadResult{FilePaths: filePaths}, nil
+}
+
+// awaitVariation polls a variation job until it is complete, failing when
+// the job fails or the timeout elapses.
+func (s *GenerationService) awaitVariation(id string, interval, timeout time.Duration) (domain.ImageVariation, error) {
+	deadline := time.Now().Add(timeout)
+	for attempt := 1; ; attempt++ {
+		variation, err := s.client.GetVariation(id)
+		if err != nil {
+			return variation, err
+		}
+		s.report(domain.ProgressEvent{Kind: domain.ProgressPolling, GenerationID: id, Status: variation.Status, Attempt: attempt})
+		switch variation.Status {
+		case statusComplete:
+			if variation.URL == "" {
+				return variation, fmt.Errorf("variation %s completed without an image", id)
+			}
+			return variation, nil
+		case statusFailed:
+			return variation, fmt.Errorf("variation %s failed", id)
+		}
+		if timeout > 0 && time.Now().Add(interval).After(deadline) {
+			return variation, fmt.Errorf("timed out after %s waiting for variation %s (status %s)", timeout, id, variation.Status)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,144 @@
+package service_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+func TestAutoUpscale_UpscalesEveryImageAndDownloadsResults(t *testing.T) {
+	polls := map[string]int{}
+	var upscaled, downloaded []string
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE"}, nil
+		},
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{ID: id, Images: []domain.GeneratedImage{{ID: "img-1"}, {ID: "img-2"}}}, nil
+		},
+		upscaleFn: func(imageID string) (domain.VariationJob, error) {
+			upscaled = append(upscaled, imageID)
+			return domain.VariationJob{ID: "job-" + imageID}, nil
+		},
+		variationFn: func(id string) (domain.ImageVariation, error) {
+			polls[id]++
+			if polls[id] == 1 {
+				return domain.ImageVariation{ID: id, Status: "PENDING"}, nil
+			}
+			return domain.ImageVariation{ID: id, Status: "COMPLETE", URL: "https://cdn/" + id + ".png"}, nil
+		},
+		downloadFn: func(url, destPath string) error {
+			downloaded = append(downloaded, url)
+			return nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+	dir := t.TempDir()
+
+	result, err := svc.AutoUpscale("gen-1", dir, time.Millisecond, time.Second)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(upscaled, ",") != "img-1,img-2" {
+		t.Errorf("expected both images to be upscaled, got %v", upscaled)
+	}
+	if strings.Join(downloaded, ",") != "https://cdn/job-img-1.png,https://cdn/job-img-2.png" {
+		t.Errorf("unexpected downloads: %v", downloaded)
+	}
+	expected := []string{filepath.Join(dir, "gen-1_1_upscaled.png"), filepath.Join(dir, "gen-1_2_upscaled.png")}
+	if strings.Join(result.FilePaths, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected paths %v, got %v", expected, result.FilePaths)
+	}
+}
+
+func TestAutoUpscale_FailsWhenGenerationFails(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "FAILED"}, nil
+		},
+		upscaleFn: func(imageID string) (domain.VariationJob, error) {
+			t.Fatal("upscale should not be attempted")
+			return domain.VariationJob{}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	_, err := svc.AutoUpscale("gen-1", t.TempDir(), time.Millisecond, time.Second)
+
+	if err == nil || !strings.Contains(err.Error(), "FAILED") {
+		t.Errorf("expected failed generation error, got %v", err)
+	}
+}
+
+func TestAutoUpscale_ReportsFailedUpscales(t *testing.T) {
+	fake := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE"}, nil
+		},
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{Images: []domain.GeneratedImage{{ID: "img-1"}}}, nil
+		},
+		upscaleFn: func(imageID string) (domain.VariationJob, error) {
+			return domain.VariationJob{ID: "job-1"}, nil
+		},
+		variationFn: func(id string) (domain.ImageVariation, error) {
+			return domain.ImageVariation{ID: id, Status: "FAILED"}, nil
+		},
+	}
+	svc := service.NewGenerationService(fake)
+
+	_, err := svc.AutoUpscale("gen-1", t.TempDir(), time.Millisecond, time.Second)
+
+	if err == nil || !strings.Contains(err.Error(), "job-1") {
+		t.Errorf("expected error naming the failed variation, got %v", err)
+	}
+}
+
+func TestDownloadWithVariations_SavesFinishedVariationsWithSuffixes(t *testing.T) {
+	var saved []string
+	client := &fakeLeonardoClient{
+		statusFn: func(id string) (domain.GenerationStatus, error) {
+			return domain.GenerationStatus{Status: "COMPLETE", Images: []string{"u1", "u2"}}, nil
+		},
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{Images: []domain.GeneratedImage{
+				{ID: "img-1", Variations: []domain.ImageVariation{
+					{ID: "v1", URL: "up1", Status: "COMPLETE", TransformType: "UPSCALE"},
+					{ID: "v2", URL: "up2", Status: "COMPLETE", TransformType: "UPSCALE"},
+					{ID: "v3", URL: "", Status: "PENDING", TransformType: "NOBG"},
+				}},
+				{ID: "img-2", Variations: []domain.ImageVariation{
+					{ID: "v4", URL: "nobg", Status: "COMPLETE", TransformType: "NOBG"},
+				}},
+			}}, nil
+		},
+		downloadFn: func(url, destPath string) error {
+			saved = append(saved, destPath)
+			return nil
+		},
+	}
+	svc := service.NewGenerationService(client)
+
+	result, err := svc.DownloadWithVariations("gen", "out")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"gen_1.png", "gen_2.png", "gen_1_upscaled.png", "gen_1_upscaled_2.png", "gen_2_nobg.png"}
+	if len(saved) != len(want) {
+		t.Fatalf("expected %d downloads, got %v", len(want), saved)
+	}
+	for i, name := range want {
+		if saved[i] != filepath.Join("out", name) {
+			t.Errorf("download %d: expected %s, got %s", i, name, saved[i])
+		}
+	}
+	if len(result.Variations) != 3 || result.Variations[2].Image != 2 || result.Variations[2].Variation.ID != "v4" {
+		t.Errorf("unexpected variations: %+v", result.Variations)
+	}
+}
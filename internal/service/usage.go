@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// UsageService reports token consumption per day from the generation
+// history.  The API has no usage history endpoint, so consumption is
+// derived from each completed generation: the cost recorded when it was
+// created from this machine or, failing that, the pricing calculator's
+// price for its settings.
+type UsageService struct {
+	generations *GenerationService
+	pricing     ports.PricingClient
+	models      *ModelService
+}
+
+// NewUsageService constructs a new UsageService given the generation
+// service walking the history, a pricing client and the model service used
+// to look up each generation's model.
+func NewUsageService(generations *GenerationService, pricing ports.PricingClient, models *ModelService) *UsageService {
+	return &UsageService{generations: generations, pricing: pricing, models: models}
+}
+
+// Remote walks the user's generations created since since, newest first,
+// and totals the completed ones per day in loc.  recorded maps generation
+// IDs to the costs known locally.  Identical settings are priced once, and
+// generations that cannot be priced are counted as unpriced.
+func (s *UsageService) Remote(ctx context.Context, userID string, since time.Time, recorded map[string]int, loc *time.Location) (domain.UsageReport, error) {
+	var report domain.UsageReport
+	catalog, catalogErr := s.models.Catalog(false)
+	type price struct {
+		cost int
+		err  error
+	}
+	prices := make(map[domain.PriceQuery]price)
+	err := s.generations.IterateGenerations(ctx, userID, func(item domain.GenerationListItem) error {
+		if item.CreatedAt.Before(since) {
+			return ErrStopIteration
+		}
+		if item.Status != statusComplete {
+			return nil
+		}
+		date := item.CreatedAt.In(loc).Format("2006-01-02")
+		if cost, ok := recorded[item.ID]; ok && cost > 0 {
+			report.Add(date, len(item.Images), cost, false)
+			return nil
+		}
+		if catalogErr != nil {
+			report.Unpriced++
+			return nil
+		}
+		model, found := catalog.Find(item.ModelID)
+		if !found {
+			model = domain.PlatformModel{ID: item.ModelID}
+		}
+		req := domain.GenerationRequest{NumImages: len(item.Images), Metadata: domain.GenerationMetadata{
+			ModelID: item.ModelID,
+			Width:   item.Width,
+			Height:  item.Height,
+			Alchemy: item.Alchemy,
+		}}
+		query := domain.PriceQueryFor(req, model, item.ModelID != "" && !found)
+		p, ok := prices[query]
+		if !ok {
+			p.cost, p.err = s.pricing.CalculateCost(query)
+			prices[query] = p
+		}
+		if p.err != nil {
+			report.Unpriced++
+			return nil
+		}
+		report.Add(date, len(item.Images), p.cost, true)
+		return nil
+	})
+	return report, err
+}
@@ -0,0 +1,66 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+func TestUsageRemote_TotalsRecordedAndPricedCostsPerDay(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 5, d, h, 0, 0, 0, time.UTC) }
+	items := []domain.GenerationListItem{
+		{ID: "g5", Status: "PENDING", CreatedAt: day(3, 12)},
+		{ID: "g4", Status: "COMPLETE", CreatedAt: day(3, 9), Images: []string{"a", "b"}, ModelID: "xl", Width: 512, Height: 512},
+		{ID: "g3", Status: "COMPLETE", CreatedAt: day(2, 18), Images: []string{"a", "b"}, ModelID: "xl", Width: 512, Height: 512},
+		{ID: "g2", Status: "COMPLETE", CreatedAt: day(2, 8), Images: []string{"a"}, ModelID: "xl"},
+		{ID: "g1", Status: "FAILED", CreatedAt: day(2, 7)},
+		{ID: "g0", Status: "COMPLETE", CreatedAt: day(1, 23), Images: []string{"a"}},
+	}
+	calls := 0
+	client := &fakeLeonardoClient{
+		listFn: func(userID string, offset, limit int) (domain.GenerationListResponse, error) {
+			if offset >= len(items) {
+				return domain.GenerationListResponse{}, nil
+			}
+			end := offset + limit
+			if end > len(items) {
+				end = len(items)
+			}
+			return domain.GenerationListResponse{Generations: items[offset:end]}, nil
+		},
+		modelsFn: modelList(&calls, domain.PlatformModel{ID: "xl", SDVersion: "SDXL_1_0"}),
+	}
+	var priced []domain.PriceQuery
+	pricing := &fakePricingClient{costFn: func(q domain.PriceQuery) (int, error) {
+		priced = append(priced, q)
+		return 5 * q.NumImages, nil
+	}}
+	usage := service.NewUsageService(service.NewGenerationService(client), pricing, service.NewModelService(client, &fakeModelCache{}))
+
+	report, err := usage.Remote(context.Background(), "user-1", day(2, 0), map[string]int{"g2": 12}, time.UTC)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []domain.UsageDay{
+		{Date: "2026-05-02", Generations: 2, Images: 3, Tokens: 12 + 10, Estimated: 1},
+		{Date: "2026-05-03", Generations: 1, Images: 2, Tokens: 10, Estimated: 1},
+	}
+	if len(report.Days) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, report.Days)
+	}
+	for i := range want {
+		if report.Days[i] != want[i] {
+			t.Errorf("day %d: expected %+v, got %+v", i, want[i], report.Days[i])
+		}
+	}
+	if len(priced) != 1 || !priced[0].SDXL() {
+		t.Errorf("expected identical settings to be priced once, got %+v", priced)
+	}
+	if total := report.Total(); total.Tokens != 32 || total.Generations != 3 {
+		t.Errorf("unexpected total %+v", total)
+	}
+}
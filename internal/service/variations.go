@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"leonardo-cli/internal/domain"
+)
+
+// FanOutVariations requests every kind of variation for images of a
+// generation at once, waits for all of them and downloads the results to
+// {outputDir}/{generationID}/image-{n}/{suffix}.png, e.g. image-1/nobg.png.
+// image selects one image by its 1-based position; zero selects them all.
+// interval and timeout apply to each wait, as in AwaitCompletion.  Jobs run
+// concurrently and one failing does not stop the others; every outcome is
+// returned in image and kind order.  With SetUpscaleTarget the multiplier
+// of upscales is worked out from the generation's size first, and a target
+// out of the API's range fails the fan-out before any job starts.
+func (s *GenerationService) FanOutVariations(id string, image int, kinds []domain.VariationKind, outputDir string, interval, timeout time.Duration) ([]domain.VariationOutcome, error) {
+	detail, err := s.client.GetGeneration(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(detail.Images) == 0 {
+		return nil, fmt.Errorf("no images available for generation %s", id)
+	}
+	if image < 0 || image > len(detail.Images) {
+		return nil, fmt.Errorf("generation %s has %d images; there is no image %d", id, len(detail.Images), image)
+	}
+	var multiplier float64
+	if !s.upscale.IsZero() && domain.HasVariationKind(kinds, domain.VariationUpscale) {
+		if multiplier, err = s.upscale.Multiplier(detail.Width, detail.Height); err != nil {
+			return nil, fmt.Errorf("generation %s: %w", id, err)
+		}
+	}
+	var outcomes []domain.VariationOutcome
+	for i, img := range detail.Images {
+		if image != 0 && i+1 != image {
+			continue
+		}
+		for _, kind := range kinds {
+			outcomes = append(outcomes, domain.VariationOutcome{Image: i + 1, ImageID: img.ID, Kind: kind})
+		}
+	}
+	var wg sync.WaitGroup
+	for i := range outcomes {
+		wg.Add(1)
+		go func(o *domain.VariationOutcome) {
+			defer wg.Done()
+			dir := filepath.Join(outputDir, id, fmt.Sprintf("image-%d", o.Image))
+			o.JobID, o.Path, o.Err = s.runVariation(o.Kind, o.ImageID, dir, multiplier, interval, timeout)
+		}(&outcomes[i])
+	}
+	wg.Wait()
+	return outcomes, nil
+}
+
+// runVariation starts one variation job, waits for it and downloads the
+// result into dir, returning the job ID and the file written.  A positive
+// multiplier makes an upscale use the universal upscaler.
+func (s *GenerationService) runVariation(kind domain.VariationKind, imageID, dir string, multiplier float64, interval, timeout time.Duration) (string, string, error) {
+	var job domain.VariationJob
+	var err error
+	if kind == domain.VariationUpscale && multiplier > 0 {
+		job, err = s.client.UniversalUpscale(imageID, multiplier)
+	} else {
+		job, err = s.client.CreateVariation(kind, imageID)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("starting %s: %w", kind, err)
+	}
+	s.report(domain.ProgressEvent{Kind: domain.ProgressSubmitted, GenerationID: job.ID})
+	variation, err := s.awaitVariation(job.ID, interval, timeout)
+	if err != nil {
+		return job.ID, "", err
+	}
+	path := filepath.Join(dir, kind.FileSuffix()+".png")
+	if err := s.client.DownloadImage(variation.URL, path); err != nil {
+		return job.ID, "", fmt.Errorf("downloading %s: %w", kind, err)
+	}
+	s.report(domain.ProgressEvent{Kind: domain.ProgressImageDownloaded, GenerationID: job.ID, Path: path})
+	return job.ID, path, nil
+}
@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+func TestFanOutVariations_RunsEveryKindForEveryImage(t *testing.T) {
+	var mu sync.Mutex
+	started := map[string]bool{}
+	fake := &fakeLeonardoClient{
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{ID: id, Images: []domain.GeneratedImage{{ID: "img-1"}, {ID: "img-2"}}}, nil
+		},
+		createVarFn: func(kind domain.VariationKind, imageID string) (domain.VariationJob, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			started[string(kind)+"/"+imageID] = true
+			if kind == domain.VariationUnzoom && imageID == "img-2" {
+				return domain.VariationJob{}, errors.New("API returned status 400")
+			}
+			return domain.VariationJob{ID: string(kind) + "-" + imageID}, nil
+		},
+		variationFn: func(id string) (domain.ImageVariation, error) {
+			return domain.ImageVariation{ID: id, Status: "COMPLETE", URL: "https://cdn/" + id + ".png"}, nil
+		},
+		downloadFn: func(url, destPath string) error { return nil },
+	}
+	svc := service.NewGenerationService(fake)
+	kinds := []domain.VariationKind{domain.VariationNoBackground, domain.VariationUnzoom}
+
+	outcomes, err := svc.FanOutVariations("gen-1", 0, kinds, "out", time.Millisecond, time.Second)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outcomes) != 4 || len(started) != 4 {
+		t.Fatalf("expected 4 jobs, got %d outcomes and %d started", len(outcomes), len(started))
+	}
+	if o := outcomes[0]; o.Image != 1 || o.Kind != domain.VariationNoBackground || o.Path != filepath.Join("out", "gen-1", "image-1", "nobg.png") || o.Err != nil {
+		t.Errorf("unexpected first outcome %+v", o)
+	}
+	if o := outcomes[3]; o.Image != 2 || o.Kind != domain.VariationUnzoom || o.Err == nil {
+		t.Errorf("expected the failed unzoom of image 2 last, got %+v", o)
+	}
+	if o := outcomes[2]; o.Err != nil || o.Path != filepath.Join("out", "gen-1", "image-2", "nobg.png") {
+		t.Errorf("expected the other jobs to finish despite the failure, got %+v", o)
+	}
+}
+
+func TestFanOutVariations_SelectsOneImageAndRejectsMissingOnes(t *testing.T) {
+	var requested []string
+	fake := &fakeLeonardoClient{
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{ID: id, Images: []domain.GeneratedImage{{ID: "img-1"}, {ID: "img-2"}}}, nil
+		},
+		createVarFn: func(kind domain.VariationKind, imageID string) (domain.VariationJob, error) {
+			requested = append(requested, imageID)
+			return domain.VariationJob{ID: "job"}, nil
+		},
+		variationFn: func(id string) (domain.ImageVariation, error) {
+			return domain.ImageVariation{ID: id, Status: "COMPLETE", URL: "https://cdn/up.png"}, nil
+		},
+		downloadFn: func(url, destPath string) error { return nil },
+	}
+	svc := service.NewGenerationService(fake)
+	kinds := []domain.VariationKind{domain.VariationUpscale}
+
+	outcomes, err := svc.FanOutVariations("gen-1", 2, kinds, ".", time.Millisecond, time.Second)
+	if err != nil || len(outcomes) != 1 || len(requested) != 1 || requested[0] != "img-2" {
+		t.Errorf("expected only image 2, got %+v, %v, %v", outcomes, requested, err)
+	}
+	if _, err := svc.FanOutVariations("gen-1", 3, kinds, ".", time.Millisecond, time.Second); err == nil {
+		t.Error("expected an error for an image the generation does not have")
+	}
+}
+
+func TestFanOutVariations_UpscalesToATargetWithTheUniversalUpscaler(t *testing.T) {
+	var multipliers []float64
+	fake := &fakeLeonardoClient{
+		getFn: func(id string) (domain.GenerationDetail, error) {
+			return domain.GenerationDetail{ID: id, Width: 1024, Height: 768, Images: []domain.GeneratedImage{{ID: "img-1"}}}, nil
+		},
+		universalFn: func(imageID string, multiplier float64) (domain.VariationJob, error) {
+			multipliers = append(multipliers, multiplier)
+			return domain.VariationJob{ID: "job"}, nil
+		},
+		createVarFn: func(kind domain.VariationKind, imageID string) (domain.VariationJob, error) {
+			t.Errorf("expected no standard %s job", kind)
+			return domain.VariationJob{}, nil
+		},
+		variationFn: func(id string) (domain.ImageVariation, error) {
+			return domain.ImageVariation{ID: id, Status: "COMPLETE", URL: "https://cdn/up.png"}, nil
+		},
+		downloadFn: func(url, destPath string) error { return nil },
+	}
+	svc := service.NewGenerationService(fake)
+	svc.SetUpscaleTarget(domain.UpscaleTarget{Height: 1152})
+	kinds := []domain.VariationKind{domain.VariationUpscale}
+
+	if _, err := svc.FanOutVariations("gen-1", 0, kinds, ".", time.Millisecond, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(multipliers) != 1 || multipliers[0] != 1.5 {
+		t.Errorf("expected one 1.5x upscale, got %v", multipliers)
+	}
+
+	svc.SetUpscaleTarget(domain.UpscaleTarget{Width: 4096})
+	if _, err := svc.FanOutVariations("gen-1", 0, kinds, ".", time.Millisecond, time.Second); err == nil || !strings.Contains(err.Error(), "at most 2x") {
+		t.Errorf("expected a 4x target to be refused before any job, got %v", err)
+	}
+	svc.SetUpscaleTarget(domain.UpscaleTarget{Width: 2048, Height: 2048})
+	if _, err := svc.FanOutVariations("gen-1", 0, kinds, ".", time.Millisecond, time.Second); err == nil || !strings.Contains(err.Error(), "aspect ratio") {
+		t.Errorf("expected a target with another aspect ratio to be refused, got %v", err)
+	}
+	if len(multipliers) != 1 {
+		t.Errorf("expected no jobs for refused targets, got %v", multipliers)
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"math/rand"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// WildcardService resolves __wildcards__ in prompts to random lines of the
+// matching wordlists.
+type WildcardService struct {
+	lists ports.Wordlists
+	rng   *rand.Rand
+}
+
+// NewWildcardService constructs a new WildcardService reading wordlists from
+// lists.  The same seed picks the same lines.
+func NewWildcardService(lists ports.Wordlists, seed int64) *WildcardService {
+	return &WildcardService{lists: lists, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Resolve expands the wildcards in the prompt of meta.  When the prompt has
+// any, the original is kept as PromptTemplate so the sidecar records both.
+func (s *WildcardService) Resolve(meta domain.GenerationMetadata) (domain.GenerationMetadata, error) {
+	if !domain.HasWildcards(meta.Prompt) {
+		return meta, nil
+	}
+	prompt, err := domain.ExpandWildcards(meta.Prompt, s.lists.Lines, s.rng.Intn)
+	if err != nil {
+		return meta, err
+	}
+	meta.PromptTemplate, meta.Prompt = meta.Prompt, prompt
+	return meta, nil
+}
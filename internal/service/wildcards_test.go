@@ -0,0 +1,67 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/service"
+)
+
+// fakeWordlists is an in-memory ports.Wordlists.
+type fakeWordlists map[string][]string
+
+func (f fakeWordlists) Lines(name string) ([]string, error) {
+	lines, ok := f[name]
+	if !ok {
+		return nil, &domain.UnknownWildcardError{Name: name}
+	}
+	return lines, nil
+}
+
+func TestWildcardResolve_ExpandsNestedWildcardsAndKeepsTemplate(t *testing.T) {
+	lists := fakeWordlists{
+		"animal": {"fox", "owl"},
+		"scene":  {"a __animal__ in the snow"},
+	}
+	svc := service.NewWildcardService(lists, 1)
+
+	meta, err := svc.Resolve(domain.GenerationMetadata{Prompt: "__scene__, painted by __animal__s"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.PromptTemplate != "__scene__, painted by __animal__s" {
+		t.Errorf("expected the template to be kept, got %q", meta.PromptTemplate)
+	}
+	if domain.HasWildcards(meta.Prompt) || !strings.Contains(meta.Prompt, " in the snow, painted by ") {
+		t.Errorf("expected every wildcard resolved, got %q", meta.Prompt)
+	}
+	again, _ := service.NewWildcardService(lists, 1).Resolve(domain.GenerationMetadata{Prompt: "__scene__, painted by __animal__s"})
+	if again.Prompt != meta.Prompt {
+		t.Errorf("expected the same seed to pick the same lines, got %q and %q", meta.Prompt, again.Prompt)
+	}
+}
+
+func TestWildcardResolve_LeavesPlainPromptsAlone(t *testing.T) {
+	svc := service.NewWildcardService(fakeWordlists{}, 1)
+
+	meta, err := svc.Resolve(domain.GenerationMetadata{Prompt: "a snake_case __ name"})
+	if err != nil || meta.Prompt != "a snake_case __ name" || meta.HasPromptTemplate() {
+		t.Errorf("expected the prompt unchanged, got %+v, %v", meta, err)
+	}
+}
+
+func TestWildcardResolve_ReportsUnknownAndSelfReferencingWordlists(t *testing.T) {
+	svc := service.NewWildcardService(fakeWordlists{"loop": {"again __loop__"}, "empty": nil}, 1)
+
+	var unknown *domain.UnknownWildcardError
+	for _, prompt := range []string{"__missing__", "__empty__"} {
+		if _, err := svc.Resolve(domain.GenerationMetadata{Prompt: prompt}); !errors.As(err, &unknown) {
+			t.Errorf("%s: expected UnknownWildcardError, got %v", prompt, err)
+		}
+	}
+	if _, err := svc.Resolve(domain.GenerationMetadata{Prompt: "__loop__"}); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("expected a nesting error, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// FileAccountStore is an AccountStore adapter that keeps credentials in a
+// JSON file readable only by its owner.
+type FileAccountStore struct {
+	path string
+}
+
+// NewFileAccountStore constructs a FileAccountStore backed by the file at
+// path.  The file and its parent directory are created on the first change.
+func NewFileAccountStore(path string) *FileAccountStore {
+	return &FileAccountStore{path: path}
+}
+
+// accountsFile is the on-disk representation of the stored accounts.
+type accountsFile struct {
+	Default  string          `json:"default,omitempty"`
+	Accounts []accountRecord `json:"accounts"`
+}
+
+type accountRecord struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// Save implements the AccountStore interface.
+func (s *FileAccountStore) Save(account domain.Account) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	record := accountRecord{Name: account.Name, Token: account.Token}
+	replaced := false
+	for i := range file.Accounts {
+		if file.Accounts[i].Name == account.Name {
+			file.Accounts[i] = record
+			replaced = true
+		}
+	}
+	if !replaced {
+		file.Accounts = append(file.Accounts, record)
+	}
+	return s.write(file)
+}
+
+// List implements the AccountStore interface.  A missing file means no
+// accounts are stored.
+func (s *FileAccountStore) List() ([]domain.Account, error) {
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]domain.Account, 0, len(file.Accounts))
+	for _, r := range file.Accounts {
+		accounts = append(accounts, domain.Account{Name: r.Name, Token: r.Token})
+	}
+	return accounts, nil
+}
+
+// Remove implements the AccountStore interface.
+func (s *FileAccountStore) Remove(name string) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	kept := file.Accounts[:0]
+	for _, r := range file.Accounts {
+		if r.Name != name {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(file.Accounts) {
+		return nil
+	}
+	file.Accounts = kept
+	return s.write(file)
+}
+
+// Default implements the AccountStore interface.
+func (s *FileAccountStore) Default() (string, error) {
+	file, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	return file.Default, nil
+}
+
+// SetDefault implements the AccountStore interface.
+func (s *FileAccountStore) SetDefault(name string) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	file.Default = name
+	return s.write(file)
+}
+
+func (s *FileAccountStore) read() (accountsFile, error) {
+	var file accountsFile
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, fmt.Errorf("reading accounts: %w", err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("parsing accounts: %w", err)
+	}
+	return file, nil
+}
+
+// write replaces the accounts file atomically.  The file holds API tokens,
+// so it is created with owner-only permissions.
+func (s *FileAccountStore) write(file accountsFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding accounts: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("creating accounts directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing accounts: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing accounts: %w", err)
+	}
+	return nil
+}
+
+// Ensure FileAccountStore satisfies the AccountStore interface at compile time.
+var _ ports.AccountStore = (*FileAccountStore)(nil)
@@ -0,0 +1,73 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestFileAccountStore_PersistsAccountsAndDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "accounts.json")
+	first := storage.NewFileAccountStore(path)
+
+	if err := first.Save(domain.Account{Name: "work", Token: "tok-work"}); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	if err := first.Save(domain.Account{Name: "personal", Token: "tok-personal"}); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	if err := first.SetDefault("personal"); err != nil {
+		t.Fatalf("unexpected error setting default: %v", err)
+	}
+
+	second := storage.NewFileAccountStore(path)
+	accounts, err := second.List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].Name != "work" || accounts[1].Token != "tok-personal" {
+		t.Fatalf("unexpected accounts: %+v", accounts)
+	}
+	current, err := second.Default()
+	if err != nil {
+		t.Fatalf("unexpected error reading default: %v", err)
+	}
+	if current != "personal" {
+		t.Errorf("expected default %q, got %q", "personal", current)
+	}
+}
+
+func TestFileAccountStore_FileIsOwnerOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	store := storage.NewFileAccountStore(path)
+
+	if err := store.Save(domain.Account{Name: "work", Token: "tok-work"}); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected permissions 0600, got %o", perm)
+	}
+}
+
+func TestFileAccountStore_RemoveDeletesAccount(t *testing.T) {
+	store := storage.NewFileAccountStore(filepath.Join(t.TempDir(), "accounts.json"))
+	store.Save(domain.Account{Name: "work", Token: "tok-work"})
+	store.Save(domain.Account{Name: "personal", Token: "tok-personal"})
+
+	if err := store.Remove("work"); err != nil {
+		t.Fatalf("unexpected error removing: %v", err)
+	}
+
+	accounts, _ := store.List()
+	if len(accounts) != 1 || accounts[0].Name != "personal" {
+		t.Errorf("expected only the personal account, got %+v", accounts)
+	}
+}
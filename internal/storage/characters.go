@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// FileCharacterStore is a CharacterStore adapter that keeps the characters
+// in a single JSON file.
+type FileCharacterStore struct {
+	path string
+}
+
+// NewFileCharacterStore constructs a FileCharacterStore backed by the file
+// at path.  The file and its parent directory are created on the first Save.
+func NewFileCharacterStore(path string) *FileCharacterStore {
+	return &FileCharacterStore{path: path}
+}
+
+// charactersFile is the on-disk representation of the characters.
+type charactersFile struct {
+	Characters []characterRecord `json:"characters"`
+}
+
+type characterRecord struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InitImageID  string          `json:"init_image_id,omitempty"`
+	InitStrength float64         `json:"init_strength,omitempty"`
+	StyleUUID    string          `json:"style_uuid,omitempty"`
+	Seed         int             `json:"seed,omitempty"`
+	Elements     []elementRecord `json:"elements,omitempty"`
+	CreatedAt    string          `json:"created_at,omitempty"`
+}
+
+type elementRecord struct {
+	ID     string  `json:"id"`
+	Weight float64 `json:"weight"`
+}
+
+// Save implements the CharacterStore interface.
+func (s *FileCharacterStore) Save(character domain.Character) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	characters, err := s.List()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range characters {
+		if characters[i].Name == character.Name {
+			characters[i] = character
+			replaced = true
+		}
+	}
+	if !replaced {
+		characters = append(characters, character)
+	}
+	return s.write(characters)
+}
+
+// List implements the CharacterStore interface.  A missing file means no
+// characters were saved yet.
+func (s *FileCharacterStore) List() ([]domain.Character, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading characters: %w", err)
+	}
+	var file charactersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing characters: %w", err)
+	}
+	characters := make([]domain.Character, 0, len(file.Characters))
+	for _, r := range file.Characters {
+		c := domain.Character{
+			Name:         r.Name,
+			Description:  r.Description,
+			InitImageID:  r.InitImageID,
+			InitStrength: r.InitStrength,
+			StyleUUID:    r.StyleUUID,
+			Seed:         r.Seed,
+			CreatedAt:    parseCreatedAt(r.CreatedAt),
+		}
+		for _, e := range r.Elements {
+			c.Elements = append(c.Elements, domain.GenerationElement{ID: e.ID, Weight: e.Weight})
+		}
+		characters = append(characters, c)
+	}
+	sort.Slice(characters, func(i, j int) bool { return characters[i].Name < characters[j].Name })
+	return characters, nil
+}
+
+// Remove implements the CharacterStore interface.
+func (s *FileCharacterStore) Remove(name string) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	characters, err := s.List()
+	if err != nil {
+		return err
+	}
+	kept := characters[:0]
+	for _, c := range characters {
+		if c.Name != name {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == len(characters) {
+		return nil
+	}
+	return s.write(kept)
+}
+
+// write replaces the characters file atomically.
+func (s *FileCharacterStore) write(characters []domain.Character) error {
+	file := charactersFile{Characters: make([]characterRecord, 0, len(characters))}
+	for _, c := range characters {
+		r := characterRecord{
+			Name:         c.Name,
+			Description:  c.Description,
+			InitImageID:  c.InitImageID,
+			InitStrength: c.InitStrength,
+			StyleUUID:    c.StyleUUID,
+			Seed:         c.Seed,
+			CreatedAt:    formatCreatedAt(c.CreatedAt),
+		}
+		for _, e := range c.Elements {
+			r.Elements = append(r.Elements, elementRecord{ID: e.ID, Weight: e.Weight})
+		}
+		file.Characters = append(file.Characters, r)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding characters: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("creating characters directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing characters: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing characters: %w", err)
+	}
+	return nil
+}
+
+// Ensure FileCharacterStore satisfies the CharacterStore interface at
+// compile time.
+var _ ports.CharacterStore = (*FileCharacterStore)(nil)
@@ -0,0 +1,36 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestFileCharacterStore_PersistsReplacesAndRemovesCharacters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "characters.json")
+	store := storage.NewFileCharacterStore(path)
+	_ = store.Save(domain.Character{Name: "mira", InitImageID: "init-1", InitStrength: 0.4})
+	_ = store.Save(domain.Character{Name: "bolt", Elements: []domain.GenerationElement{{ID: "el-1", Weight: 0.6}}})
+	_ = store.Save(domain.Character{Name: "mira", InitImageID: "init-2", Seed: 42})
+
+	characters, err := storage.NewFileCharacterStore(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(characters) != 2 || characters[0].Name != "bolt" || characters[1].InitImageID != "init-2" || characters[1].InitStrength != 0 {
+		t.Fatalf("unexpected characters: %+v", characters)
+	}
+	if e := characters[0].Elements; len(e) != 1 || e[0].ID != "el-1" || e[0].Weight != 0.6 {
+		t.Errorf("expected the Element to round-trip, got %+v", e)
+	}
+
+	if err := store.Remove("mira"); err != nil {
+		t.Fatalf("unexpected error removing: %v", err)
+	}
+	characters, _ = store.List()
+	if len(characters) != 1 || characters[0].Name != "bolt" {
+		t.Errorf("expected only bolt to remain, got %+v", characters)
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// FileDownloadStore is a DownloadStore adapter that keeps the record of
+// downloaded files in a single JSON file.
+type FileDownloadStore struct {
+	path string
+}
+
+// NewFileDownloadStore constructs a FileDownloadStore backed by the file at
+// path.  The file and its parent directory are created on the first Save.
+func NewFileDownloadStore(path string) *FileDownloadStore {
+	return &FileDownloadStore{path: path}
+}
+
+// downloadsFile is the on-disk representation of the download records.
+type downloadsFile struct {
+	Files []downloadRecord `json:"files"`
+}
+
+type downloadRecord struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Save implements the DownloadStore interface.
+func (s *FileDownloadStore) Save(image domain.StoredImage) error {
+	_, err := s.Record(image)
+	return err
+}
+
+// Record implements the DownloadStore interface.  The lookup and the save
+// hold the same lock.
+func (s *FileDownloadStore) Record(image domain.StoredImage) ([]domain.StoredImage, error) {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	images, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var identical []domain.StoredImage
+	replaced := false
+	for i := range images {
+		switch {
+		case images[i].Path == image.Path:
+			images[i] = image
+			replaced = true
+		case images[i].SHA256 == image.SHA256 && images[i].Size == image.Size:
+			identical = append(identical, images[i])
+		}
+	}
+	if !replaced {
+		images = append(images, image)
+	}
+	return identical, s.write(images)
+}
+
+// List implements the DownloadStore interface.  A missing file means nothing
+// was recorded yet.
+func (s *FileDownloadStore) List() ([]domain.StoredImage, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading download records: %w", err)
+	}
+	var file downloadsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing download records: %w", err)
+	}
+	images := make([]domain.StoredImage, 0, len(file.Files))
+	for _, r := range file.Files {
+		images = append(images, domain.StoredImage{Path: r.Path, SHA256: r.SHA256, Size: r.Size})
+	}
+	return images, nil
+}
+
+// Remove implements the DownloadStore interface.
+func (s *FileDownloadStore) Remove(path string) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	images, err := s.List()
+	if err != nil {
+		return err
+	}
+	kept := images[:0]
+	for _, image := range images {
+		if image.Path != path {
+			kept = append(kept, image)
+		}
+	}
+	if len(kept) == len(images) {
+		return nil
+	}
+	return s.write(kept)
+}
+
+// write replaces the download records file atomically.
+func (s *FileDownloadStore) write(images []domain.StoredImage) error {
+	file := downloadsFile{Files: make([]downloadRecord, 0, len(images))}
+	for _, image := range images {
+		file.Files = append(file.Files, downloadRecord{Path: image.Path, SHA256: image.SHA256, Size: image.Size})
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding download records: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("creating download records directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing download records: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing download records: %w", err)
+	}
+	return nil
+}
+
+// Ensure FileDownloadStore satisfies the DownloadStore interface at compile
+// time.
+var _ ports.DownloadStore = (*FileDownloadStore)(nil)
@@ -0,0 +1,83 @@
+package storage_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestFileDownloadStore_PersistsReplacesAndRemovesFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "downloads.json")
+	store := storage.NewFileDownloadStore(path)
+	_ = store.Save(domain.StoredImage{Path: "/img/a.png", SHA256: "aaa", Size: 3})
+	_ = store.Save(domain.StoredImage{Path: "/img/b.png", SHA256: "bbb", Size: 4})
+	_ = store.Save(domain.StoredImage{Path: "/img/a.png", SHA256: "ccc", Size: 5})
+
+	images, err := storage.NewFileDownloadStore(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(images) != 2 || images[0].SHA256 != "ccc" || images[0].Size != 5 {
+		t.Fatalf("expected a.png to be replaced in place, got %+v", images)
+	}
+
+	if err := store.Remove("/img/a.png"); err != nil {
+		t.Fatalf("unexpected error removing: %v", err)
+	}
+	images, _ = store.List()
+	if len(images) != 1 || images[0].Path != "/img/b.png" {
+		t.Errorf("expected only b.png to remain, got %+v", images)
+	}
+}
+
+func TestFileDownloadStore_RecordReturnsIdenticalFilesAndSavesInOneStep(t *testing.T) {
+	store := storage.NewFileDownloadStore(filepath.Join(t.TempDir(), "downloads.json"))
+	_ = store.Save(domain.StoredImage{Path: "/a/img.png", SHA256: "aaa", Size: 3})
+	_ = store.Save(domain.StoredImage{Path: "/c/other.png", SHA256: "bbb", Size: 3})
+
+	identical, err := store.Record(domain.StoredImage{Path: "/b/img.png", SHA256: "aaa", Size: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(identical) != 1 || identical[0].Path != "/a/img.png" {
+		t.Errorf("expected only /a/img.png to match, got %+v", identical)
+	}
+	again, _ := store.Record(domain.StoredImage{Path: "/d/img.png", SHA256: "aaa", Size: 3})
+	if len(again) != 2 {
+		t.Errorf("expected the file recorded by the first call to match too, got %+v", again)
+	}
+}
+
+func TestFileDownloadStore_ConcurrentRecordsOfOneImageFindEachOther(t *testing.T) {
+	store := storage.NewFileDownloadStore(filepath.Join(t.TempDir(), "downloads.json"))
+	const n = 8
+	found := make(chan int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			identical, err := store.Record(domain.StoredImage{Path: fmt.Sprintf("/dir%d/img.png", i), SHA256: "aaa", Size: 3})
+			if err != nil {
+				t.Error(err)
+			}
+			found <- len(identical)
+		}(i)
+	}
+	wg.Wait()
+	close(found)
+	seen := map[int]bool{}
+	for count := range found {
+		seen[count] = true
+	}
+	// Recorded one at a time, the i-th record finds the i before it.
+	for i := 0; i < n; i++ {
+		if !seen[i] {
+			t.Errorf("expected one record to find %d earlier ones, got %v", i, seen)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// FileHistory is a History adapter that appends one JSON line per
+// invocation to a file.  Appending keeps recording cheap however long the
+// history grows, and concurrent invocations never rewrite each other's lines.
+type FileHistory struct {
+	path string
+}
+
+// NewFileHistory constructs a FileHistory backed by the file at path.  The
+// file and its parent directory are created on the first Append.
+func NewFileHistory(path string) *FileHistory {
+	return &FileHistory{path: path}
+}
+
+// historyRecord is the on-disk representation of an invocation.
+type historyRecord struct {
+	At           string   `json:"at"`
+	Dir          string   `json:"dir,omitempty"`
+	Args         []string `json:"args"`
+	ExitCode     int      `json:"exit_code"`
+	GenerationID string   `json:"generation_id,omitempty"`
+}
+
+// Append implements the History interface.
+func (h *FileHistory) Append(entry domain.HistoryEntry) error {
+	data, err := json.Marshal(historyRecord{
+		At:           formatCreatedAt(entry.At),
+		Dir:          entry.Dir,
+		Args:         entry.Args,
+		ExitCode:     entry.ExitCode,
+		GenerationID: entry.GenerationID,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return f.Close()
+}
+
+// List implements the History interface.  A missing file is treated as an
+// empty history, and lines that cannot be parsed, such as one cut short by
+// a crash, are skipped.  Entries are numbered by their position.
+func (h *FileHistory) List() ([]domain.HistoryEntry, error) {
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer f.Close()
+	var entries []domain.HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var r historyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		entries = append(entries, domain.HistoryEntry{
+			Number:       len(entries) + 1,
+			At:           parseCreatedAt(r.At),
+			Dir:          r.Dir,
+			Args:         r.Args,
+			ExitCode:     r.ExitCode,
+			GenerationID: r.GenerationID,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	return entries, nil
+}
+
+var _ ports.History = (*FileHistory)(nil)
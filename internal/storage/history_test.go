@@ -0,0 +1,52 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestFileHistory_AppendsAndNumbersInvocations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "history.jsonl")
+	history := storage.NewFileHistory(path)
+
+	empty, err := history.List()
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("expected an empty history, got %v, %v", empty, err)
+	}
+	at := time.Date(2026, 6, 1, 9, 30, 0, 0, time.UTC)
+	_ = history.Append(domain.HistoryEntry{At: at, Dir: "/work", Args: []string{"create", "--prompt", "a fox"}, GenerationID: "gen-1"})
+	_ = storage.NewFileHistory(path).Append(domain.HistoryEntry{At: at, Args: []string{"status", "--last"}, ExitCode: 1})
+
+	entries, err := storage.NewFileHistory(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	first, second := entries[0], entries[1]
+	if first.Number != 1 || !first.At.Equal(at) || first.Dir != "/work" || first.GenerationID != "gen-1" || first.Args[2] != "a fox" {
+		t.Errorf("unexpected first entry %+v", first)
+	}
+	if second.Number != 2 || second.ExitCode != 1 || second.Args[0] != "status" {
+		t.Errorf("unexpected second entry %+v", second)
+	}
+}
+
+func TestFileHistory_SkipsTruncatedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	os.WriteFile(path, []byte(`{"args":["me"],"exit_code":0}`+"\n"+`{"args":["cre`+"\n"), 0600)
+
+	entries, err := storage.NewFileHistory(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Args[0] != "me" {
+		t.Errorf("expected only the complete line, got %+v", entries)
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// FileInitImageStore is an InitImageStore adapter that keeps the init images
+// uploaded from this machine in a single JSON file.
+type FileInitImageStore struct {
+	path string
+}
+
+// NewFileInitImageStore constructs a FileInitImageStore backed by the file
+// at path.  The file and its parent directory are created on the first Save.
+func NewFileInitImageStore(path string) *FileInitImageStore {
+	return &FileInitImageStore{path: path}
+}
+
+// initImagesFile is the on-disk representation of the init image records.
+type initImagesFile struct {
+	InitImages []initImageRecord `json:"init_images"`
+}
+
+type initImageRecord struct {
+	ID        string `json:"id"`
+	URL       string `json:"url,omitempty"`
+	FileName  string `json:"file,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// Save implements the InitImageStore interface.
+func (s *FileInitImageStore) Save(image domain.InitImage) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	images, err := s.List()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range images {
+		if images[i].ID == image.ID {
+			images[i] = image
+			replaced = true
+		}
+	}
+	if !replaced {
+		images = append(images, image)
+	}
+	return s.write(images)
+}
+
+// List implements the InitImageStore interface.  A missing file means no
+// init images were uploaded yet.
+func (s *FileInitImageStore) List() ([]domain.InitImage, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading init images: %w", err)
+	}
+	var file initImagesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing init images: %w", err)
+	}
+	images := make([]domain.InitImage, 0, len(file.InitImages))
+	for _, r := range file.InitImages {
+		images = append(images, domain.InitImage{
+			ID:        r.ID,
+			URL:       r.URL,
+			FileName:  r.FileName,
+			CreatedAt: parseCreatedAt(r.CreatedAt),
+		})
+	}
+	return images, nil
+}
+
+// Remove implements the InitImageStore interface.
+func (s *FileInitImageStore) Remove(id string) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	images, err := s.List()
+	if err != nil {
+		return err
+	}
+	kept := images[:0]
+	for _, image := range images {
+		if image.ID != id {
+			kept = append(kept, image)
+		}
+	}
+	if len(kept) == len(images) {
+		return nil
+	}
+	return s.write(kept)
+}
+
+// write replaces the init images file atomically.
+func (s *FileInitImageStore) write(images []domain.InitImage) error {
+	file := initImagesFile{InitImages: make([]initImageRecord, 0, len(images))}
+	for _, image := range images {
+		file.InitImages = append(file.InitImages, initImageRecord{
+			ID:        image.ID,
+			URL:       image.URL,
+			FileName:  image.FileName,
+			CreatedAt: formatCreatedAt(image.CreatedAt),
+		})
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding init images: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("creating init images directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing init images: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing init images: %w", err)
+	}
+	return nil
+}
+
+// Ensure FileInitImageStore satisfies the InitImageStore interface at
+// compile time.
+var _ ports.InitImageStore = (*FileInitImageStore)(nil)
@@ -0,0 +1,34 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestFileInitImageStore_PersistsAndRemovesImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "init-images.json")
+	uploaded := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	store := storage.NewFileInitImageStore(path)
+	_ = store.Save(domain.InitImage{ID: "init-1", URL: "https://cdn/1.png", FileName: "a.png", CreatedAt: uploaded})
+	_ = store.Save(domain.InitImage{ID: "init-2", FileName: "b.png"})
+
+	images, err := storage.NewFileInitImageStore(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(images) != 2 || images[0].FileName != "a.png" || !images[0].CreatedAt.Equal(uploaded) {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+
+	if err := store.Remove("init-1"); err != nil {
+		t.Fatalf("unexpected error removing: %v", err)
+	}
+	images, _ = store.List()
+	if len(images) != 1 || images[0].ID != "init-2" {
+		t.Errorf("expected only init-2 to remain, got %+v", images)
+	}
+}
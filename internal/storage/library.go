@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// FileLibrary is a Library adapter that keeps every entry in a single JSON
+// file.  The whole file is rewritten on each change, which is fine for the
+// few thousand entries a single user accumulates.  Changes hold a lock file
+// so concurrent invocations do not lose each other's entries.
+type FileLibrary struct {
+	path string
+}
+
+// NewFileLibrary constructs a FileLibrary backed by the file at path.  The
+// file and its parent directory are created on the first Save.
+func NewFileLibrary(path string) *FileLibrary {
+	return &FileLibrary{path: path}
+}
+
+// libraryFile is the on-disk representation of the library.
+type libraryFile struct {
+	Generations []libraryRecord `json:"generations"`
+}
+
+type libraryRecord struct {
+	GenerationID string   `json:"generation_id"`
+	Name         string   `json:"name,omitempty"`
+	Prompt       string   `json:"prompt,omitempty"`
+	ModelID      string   `json:"model_id,omitempty"`
+	CreatedAt    string   `json:"created_at,omitempty"`
+	SidecarPath  string   `json:"sidecar,omitempty"`
+	Favorite     bool     `json:"favorite,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Width        int      `json:"width,omitempty"`
+	Height       int      `json:"height,omitempty"`
+	// DurationSeconds is how long the generation took to complete.
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Cost            int     `json:"cost,omitempty"`
+	Note            string  `json:"note,omitempty"`
+}
+
+// Save implements the Library interface.
+func (l *FileLibrary) Save(entry domain.LibraryEntry) error {
+	unlock, err := lockFile(l.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := l.List()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range entries {
+		if entries[i].GenerationID == entry.GenerationID {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+	return l.write(entries)
+}
+
+// List implements the Library interface.  A missing file is treated as an
+// empty library.
+func (l *FileLibrary) List() ([]domain.LibraryEntry, error) {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading library: %w", err)
+	}
+	var file libraryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing library: %w", err)
+	}
+	entries := make([]domain.LibraryEntry, 0, len(file.Generations))
+	for _, r := range file.Generations {
+		entries = append(entries, domain.LibraryEntry{
+			GenerationID: r.GenerationID,
+			Name:         r.Name,
+			Prompt:       r.Prompt,
+			ModelID:      r.ModelID,
+			CreatedAt:    parseCreatedAt(r.CreatedAt),
+			SidecarPath:  r.SidecarPath,
+			Favorite:     r.Favorite,
+			Tags:         r.Tags,
+			Width:        r.Width,
+			Height:       r.Height,
+			Duration:     time.Duration(r.DurationSeconds * float64(time.Second)),
+			Cost:         r.Cost,
+			Note:         r.Note,
+		})
+	}
+	return entries, nil
+}
+
+// Remove implements the Library interface.
+func (l *FileLibrary) Remove(id string) error {
+	unlock, err := lockFile(l.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := l.List()
+	if err != nil {
+		return err
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.GenerationID != id {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(entries) {
+		return nil
+	}
+	return l.write(kept)
+}
+
+// write replaces the library file atomically so an interrupted write never
+// leaves a truncated index behind.
+func (l *FileLibrary) write(entries []domain.LibraryEntry) error {
+	file := libraryFile{Generations: make([]libraryRecord, 0, len(entries))}
+	for _, e := range entries {
+		file.Generations = append(file.Generations, libraryRecord{
+			GenerationID:    e.GenerationID,
+			Name:            e.Name,
+			Prompt:          e.Prompt,
+			ModelID:         e.ModelID,
+			CreatedAt:       formatCreatedAt(e.CreatedAt),
+			SidecarPath:     e.SidecarPath,
+			Favorite:        e.Favorite,
+			Tags:            e.Tags,
+			Width:           e.Width,
+			Height:          e.Height,
+			DurationSeconds: e.Duration.Round(time.Millisecond).Seconds(),
+			Cost:            e.Cost,
+			Note:            e.Note,
+		})
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding library: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("creating library directory: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing library: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("writing library: %w", err)
+	}
+	return nil
+}
+
+// formatCreatedAt renders a creation time for the library file, leaving
+// unknown times out.
+func formatCreatedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseCreatedAt reads a creation time written by formatCreatedAt.
+func parseCreatedAt(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Ensure FileLibrary satisfies the Library interface at compile time.
+var _ ports.Library = (*FileLibrary)(nil)
@@ -0,0 +1,178 @@
+package storage_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+// These tests exercise the FileLibrary adapter against a real temporary
+// directory — the file system is its boundary.
+
+func TestFileLibrary_ListReturnsEmptyWhenFileMissing(t *testing.T) {
+	lib := storage.NewFileLibrary(filepath.Join(t.TempDir(), "missing", "library.json"))
+
+	entries, err := lib.List()
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected empty library, got %d entries", len(entries))
+	}
+}
+
+func TestFileLibrary_SavePersistsEntriesAcrossInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "library.json")
+	first := storage.NewFileLibrary(path)
+
+	if err := first.Save(domain.LibraryEntry{GenerationID: "gen-1", Name: "hero", Prompt: "a hero"}); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	if err := first.Save(domain.LibraryEntry{GenerationID: "gen-2", Prompt: "a villain"}); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	entries, err := storage.NewFileLibrary(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].GenerationID != "gen-1" || entries[0].Name != "hero" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Prompt != "a villain" {
+		t.Errorf("expected prompt %q, got %q", "a villain", entries[1].Prompt)
+	}
+}
+
+func TestFileLibrary_SaveReplacesEntryWithSameID(t *testing.T) {
+	lib := storage.NewFileLibrary(filepath.Join(t.TempDir(), "library.json"))
+	_ = lib.Save(domain.LibraryEntry{GenerationID: "gen-1", Name: "old"})
+
+	if err := lib.Save(domain.LibraryEntry{GenerationID: "gen-1", Name: "new"}); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	entries, _ := lib.List()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Name != "new" {
+		t.Errorf("expected name %q, got %q", "new", entries[0].Name)
+	}
+}
+
+func TestFileLibrary_RemoveDropsEntry(t *testing.T) {
+	lib := storage.NewFileLibrary(filepath.Join(t.TempDir(), "library.json"))
+	_ = lib.Save(domain.LibraryEntry{GenerationID: "gen-1"})
+	_ = lib.Save(domain.LibraryEntry{GenerationID: "gen-2"})
+
+	if err := lib.Remove("gen-1"); err != nil {
+		t.Fatalf("unexpected error removing: %v", err)
+	}
+
+	entries, _ := lib.List()
+	if len(entries) != 1 || entries[0].GenerationID != "gen-2" {
+		t.Errorf("expected only gen-2 to remain, got %+v", entries)
+	}
+}
+
+func TestFileLibrary_PersistsFavorites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.json")
+	_ = storage.NewFileLibrary(path).Save(domain.LibraryEntry{GenerationID: "gen-1", Favorite: true})
+
+	entries, err := storage.NewFileLibrary(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Favorite {
+		t.Errorf("expected favorite to survive a round trip, got %+v", entries)
+	}
+}
+
+func TestFileLibrary_ListReturnsErrorForCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.json")
+	if err := os.WriteFile(path, []byte("not-json"), 0644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	_, err := storage.NewFileLibrary(path).List()
+
+	if err == nil {
+		t.Fatal("expected error for corrupt library, got nil")
+	}
+}
+
+func TestFileLibrary_PersistsTagsSizeAndDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.json")
+	_ = storage.NewFileLibrary(path).Save(domain.LibraryEntry{GenerationID: "gen-1", Tags: []string{"hero", "campaign-spring-2026"}, Width: 768, Height: 512, Duration: 34500 * time.Millisecond})
+
+	entries, err := storage.NewFileLibrary(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(entries) != 1 || strings.Join(entries[0].Tags, ",") != "hero,campaign-spring-2026" {
+		t.Errorf("expected tags to survive a round trip, got %+v", entries)
+	}
+	if e := entries[0]; e.Width != 768 || e.Height != 512 || e.Duration != 34500*time.Millisecond {
+		t.Errorf("expected size and duration to survive a round trip, got %+v", e)
+	}
+}
+
+func TestFileLibrary_ConcurrentSavesKeepEveryEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.json")
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate instances, like separate invocations.
+			errs <- storage.NewFileLibrary(path).Save(domain.LibraryEntry{GenerationID: fmt.Sprintf("gen-%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error saving: %v", err)
+		}
+	}
+
+	entries, err := storage.NewFileLibrary(path).List()
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 20 {
+		t.Errorf("expected 20 entries, got %d", len(entries))
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file to be released, got %v", err)
+	}
+}
+
+func TestFileLibrary_SaveBreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.json")
+	if err := os.WriteFile(path+".lock", []byte("12345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.NewFileLibrary(path).Save(domain.LibraryEntry{GenerationID: "gen-1"}); err != nil {
+		t.Fatalf("expected a stale lock to be broken, got %v", err)
+	}
+}
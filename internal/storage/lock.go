@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Lock timings.  A writer waits up to lockTimeout for another invocation to
+// finish, trying again every lockRetry.  A lock file older than
+// staleLockAge was left by an invocation that crashed or was killed, since
+// no store holds its lock for more than a read and a rewrite.
+var (
+	lockTimeout  = 10 * time.Second
+	lockRetry    = 20 * time.Millisecond
+	staleLockAge = 30 * time.Second
+)
+
+// lockFile takes the lock guarding the file at path, a path+".lock" file
+// created exclusively, so that concurrent invocations such as parallel cron
+// jobs read, change and rewrite the file one at a time instead of
+// overwriting each other's changes.  A lock file is portable where flock is
+// not, and works on network file systems.  The returned function releases
+// the lock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	lock := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("locking %s: %w", path, err)
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another leonardo invocation; if none is running, delete %s", path, lock)
+		}
+		time.Sleep(lockRetry)
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// FileModelCache is a ModelCache adapter that keeps the platform model list
+// in a single JSON file.
+type FileModelCache struct {
+	path string
+}
+
+// NewFileModelCache constructs a FileModelCache backed by the file at path.
+// The file and its parent directory are created on the first Save.
+func NewFileModelCache(path string) *FileModelCache {
+	return &FileModelCache{path: path}
+}
+
+// modelCacheFile is the on-disk representation of the cached catalog.
+type modelCacheFile struct {
+	FetchedAt string        `json:"fetched_at"`
+	Models    []modelRecord `json:"models"`
+}
+
+type modelRecord struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	SDVersion   string `json:"sd_version,omitempty"`
+}
+
+// Load implements the ModelCache interface.  A missing file yields an empty
+// catalog.
+func (c *FileModelCache) Load() (domain.ModelCatalog, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.ModelCatalog{}, nil
+	}
+	if err != nil {
+		return domain.ModelCatalog{}, fmt.Errorf("reading model cache: %w", err)
+	}
+	var file modelCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return domain.ModelCatalog{}, fmt.Errorf("parsing model cache: %w", err)
+	}
+	catalog := domain.ModelCatalog{FetchedAt: parseCreatedAt(file.FetchedAt)}
+	for _, r := range file.Models {
+		catalog.Models = append(catalog.Models, domain.PlatformModel{
+			ID:          r.ID,
+			Name:        r.Name,
+			Description: r.Description,
+			SDVersion:   r.SDVersion,
+		})
+	}
+	return catalog, nil
+}
+
+// Save implements the ModelCache interface.
+func (c *FileModelCache) Save(catalog domain.ModelCatalog) error {
+	unlock, err := lockFile(c.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file := modelCacheFile{FetchedAt: formatCreatedAt(catalog.FetchedAt), Models: make([]modelRecord, 0, len(catalog.Models))}
+	for _, m := range catalog.Models {
+		file.Models = append(file.Models, modelRecord{
+			ID:          m.ID,
+			Name:        m.Name,
+			Description: m.Description,
+			SDVersion:   m.SDVersion,
+		})
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding model cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("creating model cache directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing model cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("writing model cache: %w", err)
+	}
+	return nil
+}
+
+// Ensure FileModelCache satisfies the ModelCache interface at compile time.
+var _ ports.ModelCache = (*FileModelCache)(nil)
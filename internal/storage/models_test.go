@@ -0,0 +1,42 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestFileModelCache_RoundTripsCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "models.json")
+	cache := storage.NewFileModelCache(path)
+
+	empty, err := cache.Load()
+	if err != nil {
+		t.Fatalf("unexpected error loading missing cache: %v", err)
+	}
+	if len(empty.Models) != 0 || !empty.FetchedAt.IsZero() {
+		t.Errorf("expected empty catalog, got %+v", empty)
+	}
+
+	fetched := time.Date(2026, 5, 2, 8, 0, 0, 0, time.UTC)
+	want := domain.ModelCatalog{
+		FetchedAt: fetched,
+		Models:    []domain.PlatformModel{{ID: "m-1", Name: "Phoenix", SDVersion: "PHOENIX"}},
+	}
+	if err := cache.Save(want); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	got, err := storage.NewFileModelCache(path).Load()
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if !got.FetchedAt.Equal(fetched) {
+		t.Errorf("expected fetched at %v, got %v", fetched, got.FetchedAt)
+	}
+	if len(got.Models) != 1 || got.Models[0] != want.Models[0] {
+		t.Errorf("expected %+v, got %+v", want.Models, got.Models)
+	}
+}
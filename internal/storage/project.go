@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// projectManifestVersion is written to every manifest so the format can
+// change later without breaking older files.
+const projectManifestVersion = 1
+
+// FileProjectManifest is a ProjectManifest adapter backed by a leonardo.lock
+// file.  Assets are kept sorted by path and written with a trailing newline
+// so the file produces small, stable diffs under version control.
+type FileProjectManifest struct {
+	path string
+}
+
+// NewFileProjectManifest constructs a FileProjectManifest backed by the file
+// at path.
+func NewFileProjectManifest(path string) *FileProjectManifest {
+	return &FileProjectManifest{path: path}
+}
+
+// projectManifestFile is the on-disk representation of the manifest.
+type projectManifestFile struct {
+	Version int                  `json:"version"`
+	Assets  []projectAssetRecord `json:"assets"`
+}
+
+type projectAssetRecord struct {
+	Path         string `json:"path"`
+	GenerationID string `json:"generation_id"`
+	Prompt       string `json:"prompt,omitempty"`
+	ModelID      string `json:"model_id,omitempty"`
+	SHA256       string `json:"sha256"`
+	AddedAt      string `json:"added_at,omitempty"`
+}
+
+// Create implements the ProjectManifest interface.
+func (m *FileProjectManifest) Create() error {
+	unlock, err := lockFile(m.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(m.path); err == nil {
+		return fmt.Errorf("%s already exists", m.path)
+	}
+	return m.write(nil)
+}
+
+// List implements the ProjectManifest interface.  Unlike the other stores a
+// missing file is an error, since a project has to be initialised first.
+func (m *FileProjectManifest) List() ([]domain.ProjectAsset, error) {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s not found; run project init first", m.path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading project manifest: %w", err)
+	}
+	var file projectManifestFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing project manifest: %w", err)
+	}
+	if file.Version > projectManifestVersion {
+		return nil, fmt.Errorf("project manifest version %d is newer than this CLI supports", file.Version)
+	}
+	assets := make([]domain.ProjectAsset, 0, len(file.Assets))
+	for _, r := range file.Assets {
+		assets = append(assets, domain.ProjectAsset{
+			Path:         r.Path,
+			GenerationID: r.GenerationID,
+			Prompt:       r.Prompt,
+			ModelID:      r.ModelID,
+			SHA256:       r.SHA256,
+			AddedAt:      parseCreatedAt(r.AddedAt),
+		})
+	}
+	return assets, nil
+}
+
+// Save implements the ProjectManifest interface.
+func (m *FileProjectManifest) Save(asset domain.ProjectAsset) error {
+	unlock, err := lockFile(m.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	assets, err := m.List()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range assets {
+		if assets[i].Path == asset.Path {
+			assets[i] = asset
+			replaced = true
+		}
+	}
+	if !replaced {
+		assets = append(assets, asset)
+	}
+	return m.write(assets)
+}
+
+func (m *FileProjectManifest) write(assets []domain.ProjectAsset) error {
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Path < assets[j].Path })
+	file := projectManifestFile{Version: projectManifestVersion, Assets: make([]projectAssetRecord, 0, len(assets))}
+	for _, a := range assets {
+		file.Assets = append(file.Assets, projectAssetRecord{
+			Path:         a.Path,
+			GenerationID: a.GenerationID,
+			Prompt:       a.Prompt,
+			ModelID:      a.ModelID,
+			SHA256:       a.SHA256,
+			AddedAt:      formatCreatedAt(a.AddedAt),
+		})
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding project manifest: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing project manifest: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("writing project manifest: %w", err)
+	}
+	return nil
+}
+
+// Ensure FileProjectManifest satisfies the ProjectManifest interface at
+// compile time.
+var _ ports.ProjectManifest = (*FileProjectManifest)(nil)
@@ -0,0 +1,47 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestFileProjectManifest_RequiresInitAndKeepsAssetsSorted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), domain.ProjectManifestName)
+	manifest := storage.NewFileProjectManifest(path)
+
+	if err := manifest.Save(domain.ProjectAsset{Path: "a.png", GenerationID: "gen-1"}); err == nil {
+		t.Fatal("expected saving before init to fail")
+	}
+	if err := manifest.Create(); err != nil {
+		t.Fatalf("unexpected error creating: %v", err)
+	}
+	if err := manifest.Create(); err == nil {
+		t.Error("expected creating twice to fail")
+	}
+
+	added := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	_ = manifest.Save(domain.ProjectAsset{Path: "z/hero.png", GenerationID: "gen-2", SHA256: "old"})
+	_ = manifest.Save(domain.ProjectAsset{Path: "b/fox.png", GenerationID: "gen-1", SHA256: "f0x", AddedAt: added})
+	_ = manifest.Save(domain.ProjectAsset{Path: "z/hero.png", GenerationID: "gen-3", SHA256: "new"})
+
+	assets, err := storage.NewFileProjectManifest(path).List()
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+	if len(assets) != 2 || assets[0].Path != "b/fox.png" || assets[1].GenerationID != "gen-3" {
+		t.Fatalf("expected two assets sorted by path with hero replaced, got %+v", assets)
+	}
+	if !assets[0].AddedAt.Equal(added) || assets[0].SHA256 != "f0x" {
+		t.Errorf("unexpected fox asset: %+v", assets[0])
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasSuffix(string(data), "}\n") || !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("expected a versioned manifest ending in a newline, got:\n%s", data)
+	}
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/ports"
+)
+
+// DirWordlists is a Wordlists adapter reading plain text files, one entry
+// per line, named after the wildcard: __artists__ reads artists.txt.  The
+// directories are searched in order and the first file found is used.
+type DirWordlists struct {
+	dirs  []string
+	cache map[string][]string
+}
+
+// NewDirWordlists constructs a DirWordlists searching dirs in order.
+func NewDirWordlists(dirs ...string) *DirWordlists {
+	return &DirWordlists{dirs: dirs, cache: map[string][]string{}}
+}
+
+// Lines implements the Wordlists interface.  Blank lines and lines starting
+// with # are skipped.
+func (w *DirWordlists) Lines(name string) ([]string, error) {
+	if lines, ok := w.cache[name]; ok {
+		return lines, nil
+	}
+	for _, dir := range w.dirs {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)+".txt"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading wordlist %s: %w", name, err)
+		}
+		var lines []string
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading wordlist %s: %w", name, err)
+		}
+		w.cache[name] = lines
+		return lines, nil
+	}
+	return nil, &domain.UnknownWildcardError{Name: name}
+}
+
+var _ ports.Wordlists = (*DirWordlists)(nil)
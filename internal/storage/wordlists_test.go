@@ -0,0 +1,40 @@
+package storage_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"leonardo-cli/internal/domain"
+	"leonardo-cli/internal/storage"
+)
+
+func TestDirWordlists_ReadsFirstMatchingFileSkippingCommentsAndBlanks(t *testing.T) {
+	project, home := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(project, "artists.txt"), []byte("# painters\nMonet\n\n  Turner  \n"), 0644)
+	os.WriteFile(filepath.Join(home, "artists.txt"), []byte("Hokusai\n"), 0644)
+	os.MkdirAll(filepath.Join(home, "styles"), 0755)
+	os.WriteFile(filepath.Join(home, "styles", "lighting.txt"), []byte("golden hour\r\nblue hour\r\n"), 0644)
+	lists := storage.NewDirWordlists(project, home)
+
+	artists, err := lists.Lines("artists")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"Monet", "Turner"}; !reflect.DeepEqual(artists, want) {
+		t.Errorf("expected %v, got %v", want, artists)
+	}
+	lighting, err := lists.Lines("styles/lighting")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"golden hour", "blue hour"}; !reflect.DeepEqual(lighting, want) {
+		t.Errorf("expected %v, got %v", want, lighting)
+	}
+	var unknown *domain.UnknownWildcardError
+	if _, err := lists.Lines("colors"); !errors.As(err, &unknown) || unknown.Name != "colors" {
+		t.Errorf("expected UnknownWildcardError for a missing wordlist, got %v", err)
+	}
+}